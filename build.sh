@@ -36,6 +36,14 @@ BUILD_DIR="build"
 RELEASE_DIR="release"
 MODULE_PATH=$(go list -m)
 VERSION_VAR_PATH="${MODULE_PATH}/cmd.version"
+# Release signing (optional): CITADEL_RELEASE_PUBLIC_KEY is the minisign public
+# key baked into the binary to verify self-updates; CITADEL_MINISIGN_KEY is the
+# secret key file used to sign the archives below.
+SIGNING_KEYS_VAR_PATH="${MODULE_PATH}/internal/update.ReleaseSigningKeys"
+LDFLAGS="-X '${VERSION_VAR_PATH}=${VERSION}'"
+if [[ -n "${CITADEL_RELEASE_PUBLIC_KEY:-}" ]]; then
+    LDFLAGS="$LDFLAGS -X '${SIGNING_KEYS_VAR_PATH}=${CITADEL_RELEASE_PUBLIC_KEY}'"
+fi
 
 # --- Man Page Generation ---
 MAN_DIR="docs/man"
@@ -104,7 +112,7 @@ for OS in "${PLATFORMS[@]}"; do
 
         # 1. Build the binary
         echo "Building binary..."
-        CGO_ENABLED=0 GOOS=$OS GOARCH=$ARCH go build -ldflags="-X '${VERSION_VAR_PATH}=${VERSION}'" -o "$BINARY_PATH" ./cmd/citadel
+        CGO_ENABLED=0 GOOS=$OS GOARCH=$ARCH go build -ldflags="$LDFLAGS" -o "$BINARY_PATH" ./cmd/citadel
 
         # 2. Copy man page if available (not for Windows)
         if [[ "$OS" != "windows" ]] && [[ -f "$MAN_DIR/citadel.1" ]]; then
@@ -151,6 +159,16 @@ else
     (cd "$RELEASE_DIR" && shasum -a 256 *.tar.gz *.zip 2>/dev/null > checksums.txt || shasum -a 256 *.tar.gz > checksums.txt)
 fi
 
+# --- Sign Archives ---
+if [[ -n "${CITADEL_MINISIGN_KEY:-}" ]]; then
+    echo ""
+    echo "--- Signing Archives ---"
+    for archive in "$RELEASE_DIR"/*.tar.gz "$RELEASE_DIR"/*.zip; do
+        [[ -f "$archive" ]] || continue
+        minisign -S -s "$CITADEL_MINISIGN_KEY" -m "$archive"
+    done
+fi
+
 echo "✅ Build and packaging complete."
 echo ""
 echo "Binaries for local use are in: './$BUILD_DIR'"
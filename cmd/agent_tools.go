@@ -18,6 +18,7 @@ import (
 	"time"
 
 	"github.com/aceteam-ai/citadel-cli/internal/catalog"
+	"github.com/aceteam-ai/citadel-cli/internal/events"
 	"github.com/aceteam-ai/citadel-cli/internal/network"
 	"github.com/aceteam-ai/citadel-cli/internal/platform"
 	"github.com/aceteam-ai/citadel-cli/internal/status"
@@ -87,11 +88,15 @@ func buildAgentProviders(ctx context.Context, d agentProviderDeps) *status.Agent
 				TTLSeconds: spec.TTLSeconds,
 				Creator:    spec.Creator,
 				Epoch:      spec.Epoch,
+				StorageKey: spec.StorageKey,
 			})
 		},
 		Unexpose: func(name string) (any, error) {
 			return liveExposeOps{}.Unexpose(ctx, name)
 		},
+		Events: func(patterns []string) (<-chan events.Event, func()) {
+			return events.Default.Subscribe(patterns...)
+		},
 	}
 }
 
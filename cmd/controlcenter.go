@@ -307,6 +307,7 @@ func runControlCenter() {
 		FullscreenEnabled: config.LoadRendering(platform.ConfigDir()).Fullscreen,
 		WhatsApp:          buildWhatsAppCallbacks(),
 		ModuleInstall:     buildModuleInstallCallbacks(),
+		Jobs:              buildJobsCallbacks(),
 	}
 
 	cc := controlcenter.New(cfg)
@@ -2064,21 +2065,34 @@ func runTUIWorker(ctx context.Context, activityFn func(level, msg string)) error
 		WorkflowExec:              ccWfExec,
 		HandlerLog:                func(format string, args ...any) { activity("info", fmt.Sprintf(format, args...)) },
 		PinnedServices:            ccPinnedServices,
+		Ctx:                       ctx,
+		Source:                    source,
 	}
 	handlers := buildNodeJobHandlers(nodeJobOpts)
 
-	// Create runner with TUI callbacks
+	// Create runner with TUI callbacks. The state tracks jobs for the Jobs page.
+	state := worker.NewWorkerState()
+	energySchedule, jobWindowFn := resolveEnergySchedule(nodeName, func(format string, args ...any) {
+		activity("warning", fmt.Sprintf(format, args...))
+	})
 	runner := worker.NewRunner(source, handlers, worker.RunnerConfig{
 		WorkerID:     workerID,
 		NodeID:       headscaleNodeID,
 		AgentVersion: Version,
 		Verbose:      false,
 		ActivityFn:   activity, // Route logs through TUI
+		State:        state,
 		JobRecordFn: func(record usage.UsageRecord) {
 			// Job recording callback - could be extended to pass to TUI
 			// For now, the activity log covers job status
 			_ = record
 		},
+		EnergySchedule: energySchedule,
+		JobWindowFn:    jobWindowFn,
+		// Held deferrable jobs wait here, off the queue (energy_defer.go). Its
+		// own file, so a node also running `citadel work` never resumes the
+		// same hold in both workers.
+		DeferSpoolPath: filepath.Join(platform.ConfigDir(), "deferred-jobs-controlcenter.json"),
 	})
 
 	if streamFactory != nil {
@@ -2090,6 +2104,15 @@ func runTUIWorker(ctx context.Context, activityFn func(level, msg string)) error
 	// a control-center-only node.
 	registerPrivilegedNodeJobHandlers(runner, nodeJobOpts)
 
+	ccWorkerMu.Lock()
+	ccWorkerRunner, ccWorkerState = runner, state
+	ccWorkerMu.Unlock()
+	defer func() {
+		ccWorkerMu.Lock()
+		ccWorkerRunner = nil
+		ccWorkerMu.Unlock()
+	}()
+
 	activity("success", "Worker started, listening for jobs...")
 
 	// Run the worker (blocks until context is cancelled)
@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/aceteam-ai/citadel-cli/internal/platform"
+	"github.com/aceteam-ai/citadel-cli/internal/tui/controlcenter"
+	"github.com/aceteam-ai/citadel-cli/internal/usage"
+	"github.com/aceteam-ai/citadel-cli/internal/worker"
+)
+
+// ccJobHistoryLimit is how many usage records the Jobs page shows when no
+// worker runs in this process.
+const ccJobHistoryLimit = 50
+
+// In-process worker handles for the Jobs page, guarded by ccWorkerMu. The
+// state outlives a stopped worker so its history stays on screen; the runner
+// is cleared on stop because a re-run needs a live run loop.
+var (
+	ccWorkerRunner *worker.Runner
+	ccWorkerState  *worker.WorkerState
+)
+
+// ccUsageDBPath locates the usage store `citadel work` writes. A package var
+// so tests can point it at a temp dir.
+var ccUsageDBPath = func() (string, error) {
+	nodeDir, err := platform.DefaultNodeDir("")
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(nodeDir, "usage.db"), nil
+}
+
+// ccUsageHistory lazily opens the usage store read side for the Jobs page.
+var ccUsageHistory struct {
+	once  sync.Once
+	store *usage.Store
+}
+
+// buildJobsCallbacks wires the Jobs page. With the worker running in the
+// control center it shows that worker's live jobs, payloads and results, and
+// can cancel or re-run them. Otherwise (the worker runs as `citadel work`) it
+// shows the node's usage history, which has no payloads and no controls.
+func buildJobsCallbacks() controlcenter.JobsCallbacks {
+	return controlcenter.JobsCallbacks{
+		InFlight: func() []controlcenter.JobEntry {
+			_, state := ccWorkerHandles()
+			return jobEntriesFromViews(state.InFlightJobs())
+		},
+		Recent: func() []controlcenter.JobEntry {
+			if _, state := ccWorkerHandles(); state != nil {
+				return jobEntriesFromViews(state.RecentJobs())
+			}
+			return ccUsageJobEntries()
+		},
+		Cancel: func(id string) error {
+			_, state := ccWorkerHandles()
+			return state.CancelJob(id)
+		},
+		Rerun: func(id string) (string, error) {
+			runner, _ := ccWorkerHandles()
+			if runner == nil {
+				return "", fmt.Errorf("worker is not running; start it from the Dashboard")
+			}
+			return runner.RerunJob(id)
+		},
+	}
+}
+
+// ccWorkerHandles returns the in-process worker's runner and state; either
+// may be nil.
+func ccWorkerHandles() (*worker.Runner, *worker.WorkerState) {
+	ccWorkerMu.Lock()
+	defer ccWorkerMu.Unlock()
+	return ccWorkerRunner, ccWorkerState
+}
+
+// ccUsageJobEntries reads recent jobs from the usage store, without creating
+// it on a node that has never run `citadel work`.
+func ccUsageJobEntries() []controlcenter.JobEntry {
+	ccUsageHistory.once.Do(func() {
+		path, err := ccUsageDBPath()
+		if err != nil {
+			return
+		}
+		if _, err := os.Stat(path); err != nil {
+			return
+		}
+		store, err := usage.OpenStore(path)
+		if err != nil {
+			Debug("jobs page: usage store: %v", err)
+			return
+		}
+		ccUsageHistory.store = store
+	})
+	if ccUsageHistory.store == nil {
+		return nil
+	}
+	records, err := ccUsageHistory.store.Recent(ccJobHistoryLimit)
+	if err != nil {
+		Debug("jobs page: usage history: %v", err)
+		return nil
+	}
+	entries := make([]controlcenter.JobEntry, 0, len(records))
+	for _, r := range records {
+		entries = append(entries, jobEntryFromUsage(r))
+	}
+	return entries
+}
+
+func jobEntriesFromViews(views []worker.JobView) []controlcenter.JobEntry {
+	entries := make([]controlcenter.JobEntry, 0, len(views))
+	for _, v := range views {
+		entries = append(entries, controlcenter.JobEntry{
+			JobRecord: controlcenter.JobRecord{
+				ID:          v.ID,
+				Type:        v.Type,
+				Status:      v.Status,
+				StartedAt:   v.StartedAt,
+				CompletedAt: v.CompletedAt,
+				Error:       v.Error,
+			},
+			Source:  v.Source,
+			Queue:   v.Queue,
+			Chunks:  v.Chunks,
+			Payload: v.Payload,
+			Result:  v.Result,
+		})
+	}
+	return entries
+}
+
+func jobEntryFromUsage(r usage.UsageRecord) controlcenter.JobEntry {
+	return controlcenter.JobEntry{
+		JobRecord: controlcenter.JobRecord{
+			ID:          r.JobID,
+			Type:        r.JobType,
+			Status:      r.Status,
+			StartedAt:   r.StartedAt,
+			CompletedAt: r.CompletedAt,
+			Duration:    time.Duration(r.DurationMs) * time.Millisecond,
+			Error:       r.ErrorMessage,
+		},
+	}
+}
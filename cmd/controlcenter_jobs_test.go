@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aceteam-ai/citadel-cli/internal/usage"
+)
+
+func TestJobsCallbacksWithoutWorkerShowUsageHistory(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "usage.db")
+	store, err := usage.OpenStore(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now().UTC().Truncate(time.Second)
+	store.Insert(usage.UsageRecord{JobID: "job-1", JobType: "LLM_INFERENCE", Status: "success", StartedAt: now, CompletedAt: now, DurationMs: 1500})
+	store.Insert(usage.UsageRecord{JobID: "job-2", JobType: "SHELL_COMMAND", Status: "failed", ErrorMessage: "exit 1", StartedAt: now, CompletedAt: now})
+	store.Close()
+
+	origPath := ccUsageDBPath
+	ccUsageDBPath = func() (string, error) { return dbPath, nil }
+	ccUsageHistory.once, ccUsageHistory.store = sync.Once{}, nil
+	t.Cleanup(func() {
+		ccUsageDBPath = origPath
+		if ccUsageHistory.store != nil {
+			ccUsageHistory.store.Close()
+		}
+		ccUsageHistory.once, ccUsageHistory.store = sync.Once{}, nil
+	})
+
+	cb := buildJobsCallbacks()
+	if got := cb.InFlight(); len(got) != 0 {
+		t.Errorf("in flight = %+v, want none without a worker", got)
+	}
+	recent := cb.Recent()
+	if len(recent) != 2 || recent[0].ID != "job-2" || recent[0].Error != "exit 1" || recent[1].Duration != 1500*time.Millisecond {
+		t.Errorf("recent = %+v, want the usage history newest first", recent)
+	}
+	if err := cb.Cancel("job-2"); err == nil {
+		t.Error("cancel succeeded without a worker")
+	}
+	if _, err := cb.Rerun("job-2"); err == nil {
+		t.Error("re-run succeeded without a worker")
+	}
+}
@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/aceteam-ai/citadel-cli/internal/config"
+	"github.com/aceteam-ai/citadel-cli/internal/events"
+	"github.com/aceteam-ai/citadel-cli/internal/platform"
+)
+
+// startEventWebhooks stamps the node's event bus with its name and, when
+// webhooks.yaml lists receivers, starts delivering matching events to them
+// from a persisted outbox, including deliveries an earlier run (or the run
+// an auto-update replaced) left undelivered. A bad webhooks file or outbox
+// is a warning, not a fatal error: webhooks are an integration, not the
+// node's job.
+func startEventWebhooks(ctx context.Context, nodeName string) {
+	events.Default.SetNode(nodeName)
+
+	hooks, err := config.LoadWebhooks(platform.ConfigDir())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "   - Warning: %v; webhooks disabled\n", err)
+		return
+	}
+	if len(hooks) == 0 {
+		return
+	}
+	nodeDir, err := platform.DefaultNodeDir("")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "   - Warning: no node directory for the webhook outbox (%v); webhooks disabled\n", err)
+		return
+	}
+	outbox, err := events.OpenOutbox(events.DefaultOutboxDir(nodeDir), events.DefaultOutboxSize)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "   - Warning: %v; webhooks disabled\n", err)
+		return
+	}
+	dispatcher := events.NewDispatcher(events.DispatcherConfig{
+		Webhooks:  hooks,
+		Outbox:    outbox,
+		UserAgent: "citadel/" + Version,
+		Logf:      func(format string, args ...any) { Log(format, args...) },
+	})
+	removeSink := events.Default.AddSink(dispatcher.Enqueue)
+	go func() {
+		defer removeSink()
+		dispatcher.Run(ctx)
+	}()
+	if n := outbox.Len(); n > 0 {
+		fmt.Printf("   - Webhooks: %d receiver(s), %d delivery(ies) pending from the last run\n", len(hooks), n)
+	} else {
+		fmt.Printf("   - Webhooks: %d receiver(s)\n", len(hooks))
+	}
+}
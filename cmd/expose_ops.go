@@ -13,11 +13,13 @@ import (
 	"context"
 	"fmt"
 	"net"
+	"net/http"
 	"time"
 
 	"github.com/aceteam-ai/citadel-cli/internal/config"
 	"github.com/aceteam-ai/citadel-cli/internal/gateway"
 	"github.com/aceteam-ai/citadel-cli/internal/platform"
+	"github.com/aceteam-ai/citadel-cli/internal/storage"
 	"github.com/aceteam-ai/citadel-cli/internal/worker"
 )
 
@@ -40,10 +42,15 @@ func (liveExposeOps) Expose(_ context.Context, req worker.ExposeRequest) (*worke
 		return nil, fmt.Errorf("no in-process gateway (expose requires the node gateway to be running)")
 	}
 
+	rewrite, err := storageExposeRewrite(req.StorageKey)
+	if err != nil {
+		return nil, err
+	}
 	policy := &gateway.ExposePolicy{
 		Visibility: gateway.Visibility(req.Visibility),
 		Creator:    req.Creator,
 		TokenEpoch: req.Epoch,
+		Rewrite:    rewrite,
 	}
 	addr := fmt.Sprintf("127.0.0.1:%d", req.Port)
 	if err := ref.gw.Expose(req.Name, addr, policy); err != nil {
@@ -62,6 +69,7 @@ func (liveExposeOps) Expose(_ context.Context, req worker.ExposeRequest) (*worke
 		Visibility: req.Visibility,
 		Creator:    req.Creator,
 		TokenEpoch: req.Epoch,
+		StorageKey: req.StorageKey,
 	}); err != nil {
 		Log("warning: exposure %q is live but was not persisted (it will not survive a restart): %v", req.Name, err)
 	}
@@ -147,10 +155,16 @@ func restoreExposures(gw *gateway.Server) {
 
 	restored := 0
 	for _, r := range recs {
+		rewrite, err := storageExposeRewrite(r.StorageKey)
+		if err != nil {
+			Log("warning: skipping persisted exposure %q: %v", r.Name, err)
+			continue
+		}
 		policy := &gateway.ExposePolicy{
 			Visibility: gateway.Visibility(r.Visibility),
 			Creator:    r.Creator,
 			TokenEpoch: r.TokenEpoch,
+			Rewrite:    rewrite,
 		}
 		addr := fmt.Sprintf("127.0.0.1:%d", r.Port)
 		if err := gw.Expose(r.Name, addr, policy); err != nil {
@@ -172,6 +186,20 @@ func restoreExposures(gw *gateway.Server) {
 	}
 }
 
+// storageExposeRewrite resolves an exposure's storage key to the hook that
+// re-signs proxied S3 requests with it (nil for an ordinary exposure). An
+// unknown key is an error: exposing storage unsigned would only ever 403.
+func storageExposeRewrite(accessKey string) (func(*http.Request), error) {
+	if accessKey == "" {
+		return nil, nil
+	}
+	key, err := storage.LookupKey(accessKey)
+	if err != nil {
+		return nil, err
+	}
+	return storage.ExposeRewrite(*key), nil
+}
+
 // localPortListening reports whether something accepts TCP on the loopback port.
 // Short timeout: this runs once per exposure on the startup path, and a slow or
 // filtered probe must never delay the gateway coming up.
@@ -40,8 +40,11 @@ The node-level row is recorded under the service name "_node" and carries
 host CPU/RAM plus total GPU utilisation and VRAM used. When energy sampling is
 enabled (CITADEL_ENERGY_SAMPLING=1 or energy.yaml; default off), the node row
 also carries a per-interval energy estimate: power_w, energy_wh, and
-power_source (measured when read from a GPU power sensor, else estimated from
-utilisation and a TDP budget).`,
+power_source (measured when read from a GPU power sensor, measured_gpu_rapl
+when that is summed with the CPU's RAPL package and DRAM energy counters,
+measured_rapl when read from RAPL alone, else estimated from utilisation and a
+TDP budget). With RAPL readable, service rows carry their
+cgroup CPU share of the measured CPU power, labelled measured_rapl.`,
 	Example: `  # Summarize all services over the last hour
   citadel footprints --since 1h
 
@@ -2,6 +2,8 @@ package cmd
 
 import (
 	"context"
+	"net/url"
+	"os"
 	"sync"
 	"time"
 
@@ -12,7 +14,8 @@ import (
 // gateway_chat.go wires the gateway's model->engine chat router (issue #581,
 // node-side complement of aceteam #6236) to this node's live engine discovery.
 // Shared by `citadel work --gateway` (cmd/work.go) and `citadel serve`
-// (cmd/serve.go) so both gateways expose /v1/chat/completions identically.
+// (cmd/serve.go) so both gateways expose /v1/chat/completions identically. The
+// embedding router's TEI discovery lives here for the same reason.
 
 // chatListerTTL bounds how long a discovered engine->model map is reused before
 // a fresh probe. status.DiscoverLocalEngines runs `docker inspect` + an engine
@@ -53,3 +56,86 @@ func newLocalChatLister() gateway.ChatModelLister {
 		return out
 	}
 }
+
+// embeddingListerTTL bounds how long probed TEI servers are reused. A TEI
+// container serves one model for its lifetime, so this only has to notice a
+// server starting, stopping or being swapped; the probe includes an /embed
+// call to measure dimensions, too much to repeat on every request.
+// embeddingListerRetry is the shorter reuse of a probe that found nothing, so
+// a TEI that finishes loading its model is picked up promptly.
+const (
+	embeddingListerTTL   = 30 * time.Second
+	embeddingListerRetry = 5 * time.Second
+)
+
+// newLocalEmbeddingLister builds a gateway.EmbeddingModelLister that probes the
+// TEI servers at addresses (host:port) with a TTL cache. A server that does not
+// answer, e.g. one still downloading its model, is left out until a later
+// probe finds it ready.
+//
+// Probes run in the background, starting at construction, and callers always
+// get the last result without waiting: a probe can take seconds per server,
+// and /v1/embeddings, /v1/rerank and /v1/models must not stall behind it. The
+// gateway's embedding fallback covers the window before the first probe.
+func newLocalEmbeddingLister(addresses ...string) gateway.EmbeddingModelLister {
+	var (
+		mu         sync.Mutex
+		cached     []gateway.EmbeddingUpstream
+		expiry     time.Time
+		refreshing = true
+	)
+	refresh := func() {
+		out := probeEmbedders(addresses)
+		ttl := embeddingListerTTL
+		if len(out) == 0 {
+			ttl = embeddingListerRetry
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		cached, expiry, refreshing = out, time.Now().Add(ttl), false
+	}
+	go refresh()
+	return func() []gateway.EmbeddingUpstream {
+		mu.Lock()
+		defer mu.Unlock()
+		if !refreshing && !time.Now().Before(expiry) {
+			refreshing = true
+			go refresh()
+		}
+		return cached
+	}
+}
+
+// probeEmbedders runs ProbeTEI against each distinct address, keeping the
+// servers that answer.
+func probeEmbedders(addresses []string) []gateway.EmbeddingUpstream {
+	out := []gateway.EmbeddingUpstream{}
+	seen := map[string]bool{}
+	for _, addr := range addresses {
+		if addr == "" || seen[addr] {
+			continue
+		}
+		seen[addr] = true
+		// Longer than model discovery: the dimension probe runs a forward
+		// pass, which takes a moment on a CPU-only TEI.
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		up, err := gateway.ProbeTEI(ctx, addr)
+		cancel()
+		if err == nil {
+			out = append(out, up)
+		}
+	}
+	return out
+}
+
+// rerankAddr returns host:port of the TEI reranker named by CITADEL_RERANK_URL
+// (the same variable FILE_SEMANTIC_SEARCH reads), or "" when unset. The
+// reranker is a different model from the embedder, so it normally runs in a
+// second TEI container.
+func rerankAddr() string {
+	u, err := url.Parse(os.Getenv("CITADEL_RERANK_URL"))
+	if err != nil {
+		return ""
+	}
+	return u.Host
+}
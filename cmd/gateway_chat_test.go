@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestLocalEmbeddingListerDoesNotBlockOnProbe checks that a slow TEI probe
+// never stalls a caller: the lister answers from its cache at once and picks
+// the server up when the background probe completes.
+func TestLocalEmbeddingListerDoesNotBlockOnProbe(t *testing.T) {
+	release := make(chan struct{})
+	tei := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/info":
+			json.NewEncoder(w).Encode(map[string]any{
+				"model_id":   "BAAI/bge-m3",
+				"model_type": map[string]any{"embedding": map[string]any{}},
+			})
+		case "/embed":
+			json.NewEncoder(w).Encode([][]float32{{0, 0, 0}})
+		}
+	}))
+	defer tei.Close()
+	defer close(release)
+
+	lister := newLocalEmbeddingLister(strings.TrimPrefix(tei.URL, "http://"))
+	start := time.Now()
+	if got := lister(); len(got) != 0 {
+		t.Fatalf("before the probe: %v, want none", got)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Fatalf("lister blocked %v on the probe", d)
+	}
+
+	release <- struct{}{} // /info
+	release <- struct{}{} // /embed
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		got := lister()
+		if len(got) == 1 && got[0].Model == "BAAI/bge-m3" && got[0].Dimensions == 3 {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("probe result never cached: %v", got)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -158,9 +158,13 @@ func (c *swapController) ObserveSwap(rec worker.SwapRecord, stats worker.SwapSta
 	if len(rec.Evicted) > 0 {
 		evicted = strings.Join(rec.Evicted, ",")
 	}
-	c.logf("[hotswap] swap %s model=%s outcome=%s evicted=%s wait=%s "+
+	kind := "swap"
+	if rec.Prewarm {
+		kind = "prewarm swap"
+	}
+	c.logf("[hotswap] %s %s model=%s outcome=%s evicted=%s wait=%s "+
 		"(swaps this hour: %d, evicting: %d/%d)",
-		rec.Backend, rec.Model, rec.Outcome, evicted, rec.Wait.Round(time.Second),
+		kind, rec.Backend, rec.Model, rec.Outcome, evicted, rec.Wait.Round(time.Second),
 		stats.SwapsPerHour, stats.EvictingSwapsPerHour, stats.MaxEvictingPerHour)
 }
 
@@ -198,6 +202,24 @@ func newModelSwapManager(configDir, workspaceDir string, pinned []string, logf f
 	return worker.NewSwapManager(newSwapController(configDir, workspaceDir, pinned, logf))
 }
 
+// newModelPrewarmer starts the demand predictor that prewarms engines through
+// swapper, or returns nil when prewarming is off or there is no context to
+// bound its loop. A source that can peek its queues feeds it the backlog.
+func newModelPrewarmer(ctx context.Context, swapper *worker.SwapManager, source worker.JobSource, logf func(string, ...any)) *worker.Prewarmer {
+	if ctx == nil || !status.ModelPrewarmEnabled() {
+		return nil
+	}
+	if logf != nil {
+		logf("[prewarm] speculative model prewarming ENABLED (CITADEL_MODEL_PREWARM)")
+	}
+	p := worker.NewPrewarmer(swapper, logf)
+	if b, ok := source.(worker.BacklogPeeker); ok {
+		p.WithBacklog(b)
+	}
+	go p.Run(ctx)
+	return p
+}
+
 // hotswapConfigDir returns configDir when model hotswap is enabled, else "".
 // The heartbeat collector runs with ConfigDir="" by default (engines are probed,
 // not read from the manifest); hotswap needs the config dir to enumerate
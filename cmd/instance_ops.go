@@ -18,30 +18,41 @@ import (
 // picked up without a worker restart.
 func newInstanceProviderFactory(configDir string, log func(format string, args ...any)) func() (worker.InstanceProvider, error) {
 	return func() (worker.InstanceProvider, error) {
-		if configDir == "" {
-			return nil, fmt.Errorf("no config directory: this node has no proxmox configuration")
-		}
-		cfg, err := proxmox.LoadConfig(configDir)
+		p, err := loadInstanceProvisioner(configDir, log)
 		if err != nil {
 			return nil, err
 		}
-		if cfg == nil || cfg.BaseURL == "" {
-			return nil, fmt.Errorf("proxmox is not configured on this node (missing %s)", proxmox.ConfigPath(configDir))
-		}
-		if cfg.Provisioning == nil || !cfg.Provisioning.Enabled {
-			return nil, fmt.Errorf("instance provisioning is not enabled in %s (set provisioning.enabled)", proxmox.ConfigPath(configDir))
-		}
+		return p, nil
+	}
+}
 
-		client := proxmox.NewClient(proxmox.ClientConfig{
-			BaseURL:     cfg.BaseURL,
-			TokenID:     cfg.TokenID,
-			TokenSecret: cfg.TokenSecret,
-		})
+// loadInstanceProvisioner builds the Provisioner from configDir/proxmox.json.
+// The `citadel proxmox` snapshot/backup subcommands share it with the job
+// handler so both apply the same retention and backup defaults.
+func loadInstanceProvisioner(configDir string, log func(format string, args ...any)) (*proxmox.Provisioner, error) {
+	if configDir == "" {
+		return nil, fmt.Errorf("no config directory: this node has no proxmox configuration")
+	}
+	cfg, err := proxmox.LoadConfig(configDir)
+	if err != nil {
+		return nil, err
+	}
+	if cfg == nil || cfg.BaseURL == "" {
+		return nil, fmt.Errorf("proxmox is not configured on this node (missing %s)", proxmox.ConfigPath(configDir))
+	}
+	if cfg.Provisioning == nil || !cfg.Provisioning.Enabled {
+		return nil, fmt.Errorf("instance provisioning is not enabled in %s (set provisioning.enabled)", proxmox.ConfigPath(configDir))
+	}
 
-		pcfg := *cfg.Provisioning
-		if pcfg.PVENode == "" {
-			pcfg.PVENode = cfg.NodeName
-		}
-		return proxmox.NewProvisioner(client, pcfg, nil, log)
+	client := proxmox.NewClient(proxmox.ClientConfig{
+		BaseURL:     cfg.BaseURL,
+		TokenID:     cfg.TokenID,
+		TokenSecret: cfg.TokenSecret,
+	})
+
+	pcfg := *cfg.Provisioning
+	if pcfg.PVENode == "" {
+		pcfg.PVENode = cfg.NodeName
 	}
+	return proxmox.NewProvisioner(client, pcfg, nil, log)
 }
@@ -273,6 +273,13 @@ func pruneUnreferenced() error {
 // composeUpDetached starts/recreates a service's container non-interactively. It
 // is intentionally minimal (no prompts) so `module update` is scriptable.
 func composeUpDetached(name, composePath string) error {
+	return composeUp(name, composePath)
+}
+
+// composeUp runs `compose up -d` for a service's compose file, with any extra
+// up flags (the service supervisor passes --force-recreate to restart a
+// container that is running but unhealthy).
+func composeUp(name, composePath string, extra ...string) error {
 	if _, err := os.Stat(composePath); err != nil {
 		return fmt.Errorf("compose file not found: %s", composePath)
 	}
@@ -287,6 +294,7 @@ func composeUpDetached(name, composePath string) error {
 	args := []string{"compose"}
 	args = append(args, composeFileArgs(composePath, composePath)...)
 	args = append(args, "up", "-d")
+	args = append(args, extra...)
 	// Preflight (citadel #767 follow-up, #781): this module-restart-on-update
 	// path execs "docker" directly with no prior check. Refuse ONLY when the
 	// CLI is missing (that exec would fail immediately anyway) and report a
@@ -51,6 +51,13 @@ type nodeJobHandlerOpts struct {
 	// by the model-hotswap swap manager (#632) so a pinned engine is never
 	// evicted to swap in another. Optional.
 	PinnedServices []string
+	// Ctx bounds background loops the handler set runs (the model prewarm
+	// predictor). Optional: nil starts none.
+	Ctx context.Context
+	// Source is the runner's job source. When it can peek its queues (direct
+	// Redis), the prewarm predictor also watches the waiting tag-queue backlog.
+	// Optional.
+	Source worker.JobSource
 }
 
 // buildNodeJobHandlers returns the base node-job handler set: the legacy Nexus
@@ -89,8 +96,24 @@ func buildNodeJobHandlers(opts nodeJobHandlerOpts) []worker.JobHandler {
 	// case the handler is byte-for-byte the pre-#632 one.
 	if swapper := newModelSwapManager(opts.ConfigDir, opts.WorkspaceDir, opts.PinnedServices, opts.HandlerLog); swapper != nil {
 		llmHandler = llmHandler.WithSwapper(swapper)
+		// Speculative prewarming (opt-in, CITADEL_MODEL_PREWARM): learn demand
+		// from the queue backlog and the jobs this handler sees, and load the
+		// likely-next engine into free VRAM through the same swap manager.
+		if prewarmer := newModelPrewarmer(opts.Ctx, swapper, opts.Source, opts.HandlerLog); prewarmer != nil {
+			llmHandler = llmHandler.WithDemandRecorder(prewarmer)
+		}
 	}
 	handlers = append(handlers, llmHandler)
+	// LLM_BATCH: OpenAI Batch-style JSONL inference through the same engine
+	// routing, readiness and hotswap as llm_inference. Its input and result files
+	// live in the workspace, so it is registered only when there is one.
+	if opts.WorkspaceDir != "" {
+		handlers = append(handlers, worker.NewLLMBatchHandler(worker.LLMBatchConfig{
+			Roots:     []string{opts.WorkspaceDir},
+			Inference: llmHandler,
+			Log:       opts.HandlerLog,
+		}))
+	}
 	// document_rasterize (issue #675): render selected PDF pages to images so a
 	// scanned document can reach an OCR model, which only accepts raster images.
 	// Registered unconditionally alongside llm_inference: it needs no workspace
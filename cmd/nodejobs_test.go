@@ -41,4 +41,7 @@ func TestNodeJobHandlersCoverPrivilegedTypes(t *testing.T) {
 	if !runner.CanHandle("WORKFLOW_RUN") {
 		t.Errorf("node-job handler set missing WORKFLOW_RUN")
 	}
+	if !runner.CanHandle(worker.JobTypeLLMBatch) {
+		t.Errorf("node-job handler set missing LLM_BATCH")
+	}
 }
@@ -0,0 +1,296 @@
+// cmd/nvr.go
+//
+// `citadel nvr events` queries the Frigate event index, and startNVRWatcher
+// wires the event watcher (internal/nvr) into `citadel work`: when the nvr
+// module is installed, the worker subscribes to Frigate's events on the
+// module's node-local broker, indexes them, and pushes rule matches to the org
+// through the notify client.
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aceteam-ai/citadel-cli/internal/catalog"
+	"github.com/aceteam-ai/citadel-cli/internal/compose"
+	"github.com/aceteam-ai/citadel-cli/internal/config"
+	"github.com/aceteam-ai/citadel-cli/internal/gateway"
+	"github.com/aceteam-ai/citadel-cli/internal/notify"
+	"github.com/aceteam-ai/citadel-cli/internal/nvr"
+	"github.com/aceteam-ai/citadel-cli/internal/platform"
+	"github.com/spf13/cobra"
+)
+
+// nvrBrokerContainer is the nvr module's mosquitto container. It publishes no
+// host port (see services/nvr-service/compose.yml), so the watcher dials its
+// compose-network address, which the host can route to.
+const nvrBrokerContainer = "citadel-nvr-mosquitto"
+
+var (
+	nvrEventsCamera   string
+	nvrEventsLabel    string
+	nvrEventsSince    string
+	nvrEventsLimit    int
+	nvrEventsNotified bool
+	nvrEventsLinks    bool
+)
+
+var nvrCmd = &cobra.Command{
+	Use:   "nvr",
+	Short: "Camera NVR (Frigate) events and notifications",
+}
+
+var nvrEventsCmd = &cobra.Command{
+	Use:   "events",
+	Short: "List indexed Frigate events",
+	Long: `List the Frigate events recorded by the event watcher, newest first.
+
+` + "`citadel work`" + ` subscribes to the nvr module's MQTT broker and indexes every
+detection in ~/.citadel-cli/nvr/events.db. Events matching a rule in
+~/.citadel-cli/nvr/rules.yaml are also pushed to your org's phones with the
+event snapshot and a link to the clip, e.g.:
+
+  exposure: frigate        # gateway exposure links go through
+  rules:
+    - name: night-person
+      cameras: [front-door]
+      labels: [person]
+      min_score: 0.7
+      from: "22:00"        # node local time; wraps midnight
+      to: "06:00"
+      cooldown: 5m
+
+Links require the Frigate UI to be exposed with org visibility
+(citadel service expose frigate --port 8212 --visibility org).`,
+	Example: `  # Last 50 events
+  citadel nvr events
+
+  # People on one camera over the last day, with clip links
+  citadel nvr events --camera front-door --label person --since 24h --links
+
+  # Only events that sent a notification
+  citadel nvr events --notified`,
+	RunE: runNVREvents,
+}
+
+func init() {
+	nvrEventsCmd.Flags().StringVar(&nvrEventsCamera, "camera", "", "Only events from this camera")
+	nvrEventsCmd.Flags().StringVar(&nvrEventsLabel, "label", "", "Only events with this label (person, car, ...)")
+	nvrEventsCmd.Flags().StringVar(&nvrEventsSince, "since", "", "Only events newer than this duration (e.g. 30m, 24h)")
+	nvrEventsCmd.Flags().IntVar(&nvrEventsLimit, "limit", 50, "Maximum number of events to list")
+	nvrEventsCmd.Flags().BoolVar(&nvrEventsNotified, "notified", false, "Only events that triggered a notification")
+	nvrEventsCmd.Flags().BoolVar(&nvrEventsLinks, "links", false, "Print snapshot and clip links through the gateway exposure")
+	nvrCmd.AddCommand(nvrEventsCmd)
+	rootCmd.AddCommand(nvrCmd)
+}
+
+func runNVREvents(cmd *cobra.Command, args []string) error {
+	q := nvr.EventQuery{
+		Camera:       nvrEventsCamera,
+		Label:        nvrEventsLabel,
+		NotifiedOnly: nvrEventsNotified,
+		Limit:        nvrEventsLimit,
+	}
+	if nvrEventsSince != "" {
+		since, err := time.ParseDuration(nvrEventsSince)
+		if err != nil {
+			return fmt.Errorf("invalid --since %q (use e.g. 30m, 24h): %w", nvrEventsSince, err)
+		}
+		q.Since = time.Now().Add(-since)
+	}
+
+	path, err := nvr.IndexPath()
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		fmt.Printf("No event index at %s\n", path)
+		fmt.Println("The event watcher runs under `citadel work` once the nvr module is installed.")
+		return nil
+	}
+	idx, err := nvr.OpenIndex(path)
+	if err != nil {
+		return err
+	}
+	defer idx.Close()
+
+	events, err := idx.Query(q)
+	if err != nil {
+		return err
+	}
+	if len(events) == 0 {
+		fmt.Println("No matching events.")
+		return nil
+	}
+
+	var base string
+	if nvrEventsLinks {
+		exposure := nvr.DefaultExposureName
+		if rulesPath, err := nvr.RulesPath(); err == nil {
+			if rs, err := nvr.LoadRules(rulesPath); err == nil {
+				exposure = rs.Exposure
+			}
+		}
+		if base = nvrExposureBaseURL(exposure); base == "" {
+			fmt.Fprintf(os.Stderr, "Warning: no org-visible %q exposure; links omitted\n", exposure)
+		}
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "STARTED\tCAMERA\tLABEL\tSCORE\tDURATION\tZONES\tNOTIFIED\tID")
+	for _, e := range events {
+		dur := "ongoing"
+		if !e.End.IsZero() {
+			dur = e.End.Sub(e.Start).Round(time.Second).String()
+		}
+		label := e.Label
+		if e.SubLabel != "" {
+			label += " (" + e.SubLabel + ")"
+		}
+		if e.FalsePositive {
+			label += " [fp]"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%.0f%%\t%s\t%s\t%s\t%s\n",
+			e.Start.Local().Format("2006-01-02 15:04:05"), e.Camera, label, e.Score*100, dur,
+			dashIfEmpty(strings.Join(e.Zones, ",")), dashIfEmpty(strings.Join(e.Notified, ",")), e.ID)
+		if base != "" {
+			snap, clip := nvr.EventLinks(base, e.ID)
+			if e.HasSnapshot {
+				fmt.Fprintf(w, "\t  snapshot: %s\n", snap)
+			}
+			if e.HasClip {
+				fmt.Fprintf(w, "\t  clip:     %s\n", clip)
+			}
+		}
+	}
+	return w.Flush()
+}
+
+func dashIfEmpty(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// nvrExposureBaseURL returns the gateway URL of the named exposure, or "" when
+// it is not exposed with org visibility. `link` exposures need a per-link
+// token and `private` ones a creator identity, so neither yields a URL an
+// org member's phone can open from a push.
+func nvrExposureBaseURL(name string) string {
+	recs, err := config.LoadExposures(platform.ConfigDir())
+	if err != nil {
+		return ""
+	}
+	for _, r := range recs {
+		if r.Name != name {
+			continue
+		}
+		if gateway.Visibility(r.Visibility) != gateway.VisibilityOrg {
+			return ""
+		}
+		facts := gatewayFactsForURL()
+		ip := meshIPv4()
+		if ip == "" {
+			ip = facts.MeshIP
+		}
+		if ip == "" {
+			return ""
+		}
+		scheme := "https"
+		if !facts.UseTLS {
+			scheme = "http"
+		}
+		return fmt.Sprintf("%s://%s%s", scheme, net.JoinHostPort(ip, strconv.Itoa(facts.Port)), gateway.ExposeRoutePath(name))
+	}
+	return ""
+}
+
+// startNVRWatcher launches the Frigate event watcher when the nvr module is
+// installed with MQTT enabled. Pushes need a device API token; without one the
+// watcher still indexes events.
+func startNVRWatcher(ctx context.Context) {
+	_, configDir, err := findAndReadManifest()
+	if err != nil {
+		return
+	}
+	envPath := filepath.Join(configDir, "services", "nvr.env")
+	password, ok := compose.ReadEnvVar(envPath, "NVR_MQTT_PASSWORD")
+	if !ok || password == "" {
+		return
+	}
+	if v, ok := compose.ReadEnvVar(envPath, "NVR_MQTT"); ok && strings.EqualFold(v, "false") {
+		Debug("nvr event watcher disabled (NVR_MQTT=false)")
+		return
+	}
+	user, _ := compose.ReadEnvVar(envPath, "NVR_MQTT_USER")
+	if user == "" {
+		user = "frigate"
+	}
+
+	indexPath, err := nvr.IndexPath()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "   - Warning: nvr event watcher: %v\n", err)
+		return
+	}
+	idx, err := nvr.OpenIndex(indexPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "   - Warning: nvr event watcher: %v\n", err)
+		return
+	}
+	rulesPath, _ := nvr.RulesPath()
+
+	cfg := nvr.WatcherConfig{
+		BrokerAddr: nvrBrokerAddr,
+		User:       user,
+		Password:   password,
+		Index:      idx,
+		RulesPath:  rulesPath,
+		LinkBase:   nvrExposureBaseURL,
+		Logf:       func(format string, args ...any) { Log(format, args...) },
+	}
+	if dc := getDeviceConfigFromFile(); dc != nil && dc.DeviceAPIToken != "" {
+		apiBaseURL := dc.APIBaseURL
+		if apiBaseURL == "" {
+			apiBaseURL = authServiceURL
+		}
+		cfg.Notifier = notify.NewClient(notify.Config{BaseURL: apiBaseURL, Token: dc.DeviceAPIToken})
+	}
+	w, err := nvr.NewWatcher(cfg)
+	if err != nil {
+		idx.Close()
+		fmt.Fprintf(os.Stderr, "   - Warning: nvr event watcher: %v\n", err)
+		return
+	}
+	go func() {
+		defer idx.Close()
+		w.Run(ctx)
+	}()
+	pushes := "on"
+	if cfg.Notifier == nil {
+		pushes = "off (no device API token)"
+	}
+	fmt.Printf("   - NVR event watcher: indexing to %s, pushes %s, rules %s\n", indexPath, pushes, rulesPath)
+}
+
+// nvrBrokerAddr resolves the mosquitto container's compose-network address.
+func nvrBrokerAddr(ctx context.Context) (string, error) {
+	rt := catalog.SelectContainerRuntime()
+	out, err := exec.CommandContext(ctx, rt.EngineBin, "inspect", "--format",
+		"{{range .NetworkSettings.Networks}}{{.IPAddress}} {{end}}", nvrBrokerContainer).Output()
+	if err != nil {
+		return "", fmt.Errorf("broker container %s not found (is the nvr module running?)", nvrBrokerContainer)
+	}
+	if ips := strings.Fields(string(out)); len(ips) > 0 {
+		return net.JoinHostPort(ips[0], strconv.Itoa(nvr.DefaultMQTTPort)), nil
+	}
+	return "", fmt.Errorf("broker container %s has no network address", nvrBrokerContainer)
+}
@@ -11,6 +11,8 @@ import (
 
 	"github.com/aceteam-ai/citadel-cli/internal/platform"
 	"github.com/aceteam-ai/citadel-cli/internal/provision"
+	"github.com/aceteam-ai/citadel-cli/internal/proxmox"
+	"github.com/aceteam-ai/citadel-cli/internal/worker"
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
 )
@@ -31,6 +33,9 @@ Examples:
   # Create a container
   citadel provision create --name my-app --image nginx:latest --port 8080:80
 
+  # Create an LXC system container
+  citadel provision create --name sandbox --type lxc --image ubuntu:24.04
+
   # Check status
   citadel provision status <id>
 
@@ -50,7 +55,7 @@ var provisionListCmd = &cobra.Command{
 }
 
 func runProvisionList(_ *cobra.Command, _ []string) {
-	mgr, err := newProvisionManager()
+	mgr, err := newProvisionManager(false)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
@@ -63,22 +68,65 @@ func runProvisionList(_ *cobra.Command, _ []string) {
 	}
 
 	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
-	fmt.Fprintln(tw, "ID\tNAME\tTYPE\tSTATUS\tIMAGE\tCONTAINER")
+	fmt.Fprintln(tw, "ID\tNAME\tTYPE\tSTATUS\tIMAGE\tCONTAINER\tORG\tCPUS\tMEMORY\tGPUS")
 	for _, r := range resources {
 		cid := r.ContainerID
 		if len(cid) > 12 {
 			cid = cid[:12]
 		}
-		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n",
-			shortID(r.ID), r.Spec.Name, r.Spec.Type, r.Status, r.Spec.Image, cid)
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			shortID(r.ID), r.Spec.Name, r.Spec.Type, r.Status, r.Spec.Image, cid,
+			dashIfEmpty(r.Spec.OrgID), dashIfEmpty(r.Spec.CPUs), formatReservedMemory(r.Spec.MemoryMB), formatReservedGPUs(r))
 	}
 	tw.Flush()
+
+	printReservationSummary(mgr)
+}
+
+// printReservationSummary prints the node's reserved capacity under the
+// resource table.
+func printReservationSummary(mgr *provision.Manager) {
+	sum := mgr.Reservations()
+	capacity, err := mgr.Capacity(context.Background())
+	fmt.Println()
+	if err != nil {
+		fmt.Printf("Reserved: %g CPUs, %d MB memory, %d GPUs (capacity unknown: %v)\n",
+			sum.Reserved.CPUs, sum.Reserved.MemoryMB, sum.Reserved.GPUs, err)
+	} else {
+		fmt.Printf("Reserved: %g/%g CPUs, %d/%d MB memory, %d/%d GPUs (inference engines hold %d MB)\n",
+			sum.Reserved.CPUs, capacity.CPUs, sum.Reserved.MemoryMB, capacity.MemoryMB,
+			sum.Reserved.GPUs, capacity.GPUs, capacity.EngineMemoryMB)
+	}
+	if sum.Queued > 0 {
+		fmt.Printf("Queued:   %d resource(s) waiting for capacity\n", sum.Queued)
+	}
+}
+
+func formatReservedMemory(mb int) string {
+	if mb == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%dM", mb)
+}
+
+// formatReservedGPUs shows pinned devices, or the unpinned request.
+func formatReservedGPUs(r *provision.Resource) string {
+	if len(r.GPUDevices) > 0 {
+		parts := make([]string, len(r.GPUDevices))
+		for i, d := range r.GPUDevices {
+			parts[i] = fmt.Sprintf("%d", d)
+		}
+		return strings.Join(parts, ",")
+	}
+	return dashIfEmpty(r.Spec.GPUs)
 }
 
 // --- provision create ---
 
 var (
 	provisionCreateName    string
+	provisionCreateType    string
+	provisionCreateOrg     string
 	provisionCreateImage   string
 	provisionCreatePorts   []string
 	provisionCreateEnv     []string
@@ -92,12 +140,29 @@ var (
 var provisionCreateCmd = &cobra.Command{
 	Use:   "create",
 	Short: "Create a new provisioned resource",
-	Long: `Create a Docker container from a spec.
+	Long: `Create a Docker container, LXC container or VM from a spec.
+
+--type selects the backend:
+  docker  --image is a container image reference (default)
+  lxc     --image is a distribution for the LXC download template,
+          dist:release[:arch]; the container gets its own address, so
+          --port and --gpus are not supported
+  vm      --image is the Proxmox template VMID or name, defaulting to
+          provisioning.template_vmid in proxmox.json; only --cpus and
+          --memory apply
+
+Admission control checks every spec against the node's free capacity
+(host CPUs and memory, less what inference engines hold) and its org's
+quota from provision-policy.json in the config directory. GPU requests are
+pinned to specific devices. A spec that does not fit is rejected, or queued
+when the policy sets "queue": true.
 
 Examples:
   citadel provision create --name my-app --image nginx:latest
   citadel provision create --name db --image postgres:16 --port 5432:5432 --env POSTGRES_PASSWORD=secret
-  citadel provision create --name gpu-worker --image pytorch/pytorch --gpus all`,
+  citadel provision create --name gpu-worker --image pytorch/pytorch --gpus all
+  citadel provision create --name sandbox --type lxc --image ubuntu:24.04 --memory 2048
+  citadel provision create --name build-vm --type vm --image ubuntu-cloud --cpus 4 --memory 8192`,
 	Run: runProvisionCreate,
 }
 
@@ -106,14 +171,16 @@ func runProvisionCreate(_ *cobra.Command, _ []string) {
 		fmt.Fprintln(os.Stderr, "Error: --name is required")
 		os.Exit(1)
 	}
-	if provisionCreateImage == "" {
+	resourceType := provision.ResourceType(provisionCreateType)
+	if provisionCreateImage == "" && resourceType != provision.ResourceTypeVM {
 		fmt.Fprintln(os.Stderr, "Error: --image is required")
 		os.Exit(1)
 	}
 
 	spec := &provision.ResourceSpec{
 		Name:     provisionCreateName,
-		Type:     provision.ResourceTypeDocker,
+		Type:     resourceType,
+		OrgID:    provisionCreateOrg,
 		Image:    provisionCreateImage,
 		CPUs:     provisionCreateCPUs,
 		MemoryMB: provisionCreateMemory,
@@ -155,7 +222,7 @@ func runProvisionCreate(_ *cobra.Command, _ []string) {
 		spec.Volumes = append(spec.Volumes, vm)
 	}
 
-	mgr, err := newProvisionManager()
+	mgr, err := newProvisionManager(true)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
@@ -169,6 +236,8 @@ func runProvisionCreate(_ *cobra.Command, _ []string) {
 
 	if result.Reused {
 		color.Yellow("Resource %q already exists (ID: %s)\n", spec.Name, shortID(result.Resource.ID))
+	} else if result.Queued {
+		color.Yellow("Resource %q queued until capacity frees up (ID: %s): %s\n", spec.Name, shortID(result.Resource.ID), result.Resource.Error)
 	} else {
 		color.Green("Resource %q created (ID: %s)\n", spec.Name, shortID(result.Resource.ID))
 	}
@@ -186,7 +255,7 @@ var provisionStatusCmd = &cobra.Command{
 }
 
 func runProvisionStatus(_ *cobra.Command, args []string) {
-	mgr, err := newProvisionManager()
+	mgr, err := newProvisionManager(false)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
@@ -213,7 +282,7 @@ var provisionDestroyCmd = &cobra.Command{
 }
 
 func runProvisionDestroy(_ *cobra.Command, args []string) {
-	mgr, err := newProvisionManager()
+	mgr, err := newProvisionManager(false)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
@@ -241,7 +310,7 @@ var provisionLogsCmd = &cobra.Command{
 }
 
 func runProvisionLogs(_ *cobra.Command, args []string) {
-	mgr, err := newProvisionManager()
+	mgr, err := newProvisionManager(false)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
@@ -260,7 +329,14 @@ func runProvisionLogs(_ *cobra.Command, args []string) {
 
 // --- Helpers ---
 
-func newProvisionManager() (*provision.Manager, error) {
+// newProvisionManager opens the resource store for a CLI command. Only create
+// enables admission control, for its quota and capacity checks; the other
+// commands only read and reconcile, and never start queued resources, which
+// is left to the daemon (see initProvisionManager). The CLI's GPU tracker
+// starts empty; the manager fills it from the resource store it shares with
+// the daemon before pinning, so neither process pins a device the other's
+// resources hold.
+func newProvisionManager(admission bool) (*provision.Manager, error) {
 	configDir := platform.ConfigDir()
 
 	store, err := provision.NewStore(configDir)
@@ -268,21 +344,97 @@ func newProvisionManager() (*provision.Manager, error) {
 		return nil, fmt.Errorf("initializing resource store: %w", err)
 	}
 
-	docker, err := provision.NewDockerBackend()
+	backends, err := provisionBackends(configDir)
 	if err != nil {
-		return nil, fmt.Errorf("initializing Docker backend: %w", err)
-	}
-
-	backends := map[provision.ResourceType]provision.Backend{
-		provision.ResourceTypeDocker: docker,
+		return nil, err
 	}
 
 	mgr := provision.NewManager(store, backends)
+	if admission {
+		policy, err := provision.LoadPolicy(configDir)
+		if err != nil {
+			return nil, err
+		}
+		mgr.EnableAdmission(provision.AdmissionConfig{
+			Policy: policy,
+			GPUs:   provisionGPUs(worker.NewGPUTracker(platform.GetGPUCountSimple())),
+		})
+	}
 	mgr.ReconcileAll(context.Background())
 
 	return mgr, nil
 }
 
+// provisionGPUs adapts the node's GPU tracker for admission control. A node
+// without GPUs gets an empty tracker, so GPU requests are rejected rather
+// than passed through unpinned.
+func provisionGPUs(t *worker.GPUTracker) provision.GPUAllocator {
+	if t == nil {
+		return worker.NewGPUTracker(0)
+	}
+	return t
+}
+
+// provisionBackends returns a backend for every resource type this node can
+// host: Docker and LXC when their CLIs are installed, and VMs when a Proxmox
+// hypervisor is configured in proxmox.json. It fails only when none is
+// available.
+func provisionBackends(configDir string) (map[provision.ResourceType]provision.Backend, error) {
+	backends := make(map[provision.ResourceType]provision.Backend)
+	var missing []string
+
+	if docker, err := provision.NewDockerBackend(); err == nil {
+		backends[provision.ResourceTypeDocker] = docker
+	} else {
+		missing = append(missing, err.Error())
+	}
+
+	if lxc, err := provision.NewLXCBackend(); err == nil {
+		backends[provision.ResourceTypeLXC] = lxc
+	} else {
+		missing = append(missing, err.Error())
+	}
+
+	if vm, err := newVMBackend(configDir); err == nil {
+		backends[provision.ResourceTypeVM] = vm
+	} else {
+		missing = append(missing, err.Error())
+	}
+
+	if len(backends) == 0 {
+		return nil, fmt.Errorf("no provisioning backend available: %s", strings.Join(missing, "; "))
+	}
+	return backends, nil
+}
+
+// newVMBackend builds the VM backend from configDir/proxmox.json. The default
+// template and clone storage come from its provisioning section when present.
+func newVMBackend(configDir string) (*provision.VMBackend, error) {
+	cfg, err := proxmox.LoadConfig(configDir)
+	if err != nil {
+		return nil, err
+	}
+	if cfg == nil || cfg.BaseURL == "" {
+		return nil, fmt.Errorf("no hypervisor configured (missing %s)", proxmox.ConfigPath(configDir))
+	}
+
+	vmCfg := provision.VMConfig{Node: cfg.NodeName}
+	if p := cfg.Provisioning; p != nil {
+		if p.PVENode != "" {
+			vmCfg.Node = p.PVENode
+		}
+		vmCfg.TemplateVMID = p.TemplateVMID
+		vmCfg.Storage = p.Storage
+	}
+
+	client := proxmox.NewClient(proxmox.ClientConfig{
+		BaseURL:     cfg.BaseURL,
+		TokenID:     cfg.TokenID,
+		TokenSecret: cfg.TokenSecret,
+	})
+	return provision.NewVMBackend(client, vmCfg)
+}
+
 func resolveResourceID(mgr *provision.Manager, input string) string {
 	if r := mgr.Get(input); r != nil {
 		return input
@@ -357,13 +509,15 @@ func init() {
 	provisionCmd.AddCommand(provisionLogsCmd)
 
 	provisionCreateCmd.Flags().StringVar(&provisionCreateName, "name", "", "Resource name (required)")
-	provisionCreateCmd.Flags().StringVar(&provisionCreateImage, "image", "", "Container image (required)")
+	provisionCreateCmd.Flags().StringVar(&provisionCreateType, "type", string(provision.ResourceTypeDocker), "Resource type: docker, lxc or vm")
+	provisionCreateCmd.Flags().StringVar(&provisionCreateOrg, "org", "", "Org the resource is provisioned for (quota accounting)")
+	provisionCreateCmd.Flags().StringVar(&provisionCreateImage, "image", "", "Container image, LXC distribution or VM template (required except for vm)")
 	provisionCreateCmd.Flags().StringSliceVarP(&provisionCreatePorts, "port", "p", nil, "Port mapping (host:container[/protocol])")
 	provisionCreateCmd.Flags().StringSliceVarP(&provisionCreateEnv, "env", "e", nil, "Environment variable (KEY=VALUE)")
 	provisionCreateCmd.Flags().StringSliceVarP(&provisionCreateVolumes, "volume", "v", nil, "Volume mount (host:container[:ro])")
 	provisionCreateCmd.Flags().StringVar(&provisionCreateCPUs, "cpus", "", "CPU limit (e.g., 0.5)")
 	provisionCreateCmd.Flags().IntVar(&provisionCreateMemory, "memory", 0, "Memory limit in MB")
-	provisionCreateCmd.Flags().StringVar(&provisionCreateGPUs, "gpus", "", "GPU access (e.g., all, 1)")
+	provisionCreateCmd.Flags().StringVar(&provisionCreateGPUs, "gpus", "", "GPU access (e.g., all, 1, device=0,2)")
 	provisionCreateCmd.Flags().StringSliceVar(&provisionCreateCommand, "cmd", nil, "Override container command")
 
 	provisionLogsCmd.Flags().IntVar(&provisionLogsTail, "tail", 100, "Number of log lines to show")
@@ -0,0 +1,271 @@
+// cmd/proxmox_snapshot.go
+//
+// `citadel proxmox snapshot|rollback|backup` are the operator-side mirror of
+// the INSTANCE_SNAPSHOT / INSTANCE_ROLLBACK / INSTANCE_BACKUP jobs. They go
+// through the same proxmox.Provisioner as the job handler, so snapshot
+// retention and backup defaults come from the "provisioning" section of
+// proxmox.json either way. `snapshot promote` turns a snapshot into a new
+// cloud-init template for future provisioning.
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aceteam-ai/citadel-cli/internal/platform"
+	pmx "github.com/aceteam-ai/citadel-cli/internal/proxmox"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var (
+	proxmoxSnapNode        string
+	proxmoxSnapName        string
+	proxmoxSnapDescription string
+	proxmoxSnapIncludeRAM  bool
+	proxmoxTemplateName    string
+	proxmoxRollbackYes     bool
+	proxmoxBackupStorage   string
+	proxmoxBackupMode      string
+)
+
+var proxmoxSnapshotCmd = &cobra.Command{
+	Use:   "snapshot",
+	Short: "Manage instance VM snapshots",
+	Long: `Create, list, delete and promote snapshots of instance VMs.
+
+Taking a snapshot prunes the instance's oldest snapshots down to the retention
+configured for its resource pool (provisioning.snapshot_retention, overridden
+per pool by provisioning.pool_snapshot_retention in proxmox.json).
+
+Examples:
+  citadel proxmox snapshot create 105 --name before-upgrade
+  citadel proxmox snapshot list 105
+  citadel proxmox snapshot promote 105 golden --template-name ubuntu-golden`,
+}
+
+var proxmoxSnapshotCreateCmd = &cobra.Command{
+	Use:   "create <vmid>",
+	Short: "Snapshot an instance VM",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runProxmoxSnapshotCreate,
+}
+
+var proxmoxSnapshotListCmd = &cobra.Command{
+	Use:   "list <vmid>",
+	Short: "List an instance VM's snapshots",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runProxmoxSnapshotList,
+}
+
+var proxmoxSnapshotDeleteCmd = &cobra.Command{
+	Use:   "delete <vmid> <snapshot>",
+	Short: "Delete an instance VM snapshot",
+	Args:  cobra.ExactArgs(2),
+	RunE:  runProxmoxSnapshotDelete,
+}
+
+var proxmoxSnapshotPromoteCmd = &cobra.Command{
+	Use:   "promote <vmid> <snapshot>",
+	Short: "Promote a snapshot into a new cloud-init template",
+	Long: `Full-clone an instance VM as it was at a snapshot and convert the clone
+into a template. The instance's cloud-init user-data (which carries its mesh
+authkey) is stripped from the template.
+
+The template keeps the snapshot's disk as-is: promote a snapshot taken after
+the guest was generalized (cloud-init clean, tailscale logout), or every VM
+cloned from it boots with the source instance's identity.
+
+Point provisioning.template_vmid at the new VMID to provision from it.`,
+	Args: cobra.ExactArgs(2),
+	RunE: runProxmoxSnapshotPromote,
+}
+
+var proxmoxRollbackCmd = &cobra.Command{
+	Use:   "rollback <vmid> <snapshot>",
+	Short: "Roll an instance VM back to a snapshot",
+	Long: `Revert an instance VM to a snapshot. Everything written since the
+snapshot is lost. An instance that was running is started again afterwards.
+
+Use --yes to skip the confirmation prompt.`,
+	Args: cobra.ExactArgs(2),
+	RunE: runProxmoxRollback,
+}
+
+var proxmoxBackupCmd = &cobra.Command{
+	Use:   "backup <vmid>",
+	Short: "Back up an instance VM with vzdump",
+	Long: `Write a vzdump archive of an instance VM. Storage and mode default to
+provisioning.backup_storage ("local") and provisioning.backup_mode
+("snapshot", a live backup).`,
+	Args: cobra.ExactArgs(1),
+	RunE: runProxmoxBackup,
+}
+
+func init() {
+	for _, c := range []*cobra.Command{proxmoxSnapshotCreateCmd, proxmoxSnapshotListCmd,
+		proxmoxSnapshotDeleteCmd, proxmoxSnapshotPromoteCmd, proxmoxRollbackCmd, proxmoxBackupCmd} {
+		c.Flags().StringVar(&proxmoxSnapNode, "node", "", "PVE node the VM runs on (default: provisioning.pve_node)")
+	}
+	proxmoxSnapshotCreateCmd.Flags().StringVar(&proxmoxSnapName, "name", "", "Snapshot name (default: aceteam-<UTC time>; the aceteam- prefix is reserved)")
+	proxmoxSnapshotCreateCmd.Flags().StringVar(&proxmoxSnapDescription, "description", "", "Snapshot description")
+	proxmoxSnapshotCreateCmd.Flags().BoolVar(&proxmoxSnapIncludeRAM, "include-ram", false, "Save the VM's memory so a rollback resumes it")
+	proxmoxSnapshotPromoteCmd.Flags().StringVar(&proxmoxTemplateName, "template-name", "", "Name of the new template (default: template-<vmid>-<snapshot>)")
+	proxmoxRollbackCmd.Flags().BoolVar(&proxmoxRollbackYes, "yes", false, "Skip the confirmation prompt.")
+	proxmoxBackupCmd.Flags().StringVar(&proxmoxBackupStorage, "storage", "", "Target storage (default: provisioning.backup_storage)")
+	proxmoxBackupCmd.Flags().StringVar(&proxmoxBackupMode, "mode", "", "vzdump mode: snapshot, suspend or stop (default: provisioning.backup_mode)")
+
+	proxmoxSnapshotCmd.AddCommand(proxmoxSnapshotCreateCmd)
+	proxmoxSnapshotCmd.AddCommand(proxmoxSnapshotListCmd)
+	proxmoxSnapshotCmd.AddCommand(proxmoxSnapshotDeleteCmd)
+	proxmoxSnapshotCmd.AddCommand(proxmoxSnapshotPromoteCmd)
+	proxmoxCmd.AddCommand(proxmoxSnapshotCmd)
+	proxmoxCmd.AddCommand(proxmoxRollbackCmd)
+	proxmoxCmd.AddCommand(proxmoxBackupCmd)
+}
+
+// proxmoxInstanceRef loads the provisioner and parses the <vmid> argument.
+func proxmoxInstanceRef(arg string) (*pmx.Provisioner, pmx.InstanceRef, error) {
+	vmid, err := strconv.Atoi(arg)
+	if err != nil || vmid <= 0 {
+		return nil, pmx.InstanceRef{}, fmt.Errorf("invalid vmid %q", arg)
+	}
+	p, err := loadInstanceProvisioner(platform.ConfigDir(), nil)
+	if err != nil {
+		return nil, pmx.InstanceRef{}, err
+	}
+	return p, pmx.InstanceRef{VMID: vmid, PVENode: proxmoxSnapNode}, nil
+}
+
+func runProxmoxSnapshotCreate(cmd *cobra.Command, args []string) error {
+	p, ref, err := proxmoxInstanceRef(args[0])
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Minute)
+	defer cancel()
+
+	fmt.Printf("Snapshotting VM %d...\n", ref.VMID)
+	res, err := p.Snapshot(ctx, ref, pmx.SnapshotRequest{
+		Name:        proxmoxSnapName,
+		Description: proxmoxSnapDescription,
+		IncludeRAM:  proxmoxSnapIncludeRAM,
+	})
+	if err != nil {
+		return err
+	}
+	color.New(color.FgGreen).Printf("Created snapshot %s\n", res.Name)
+	if len(res.Pruned) > 0 {
+		fmt.Printf("Pruned per retention for pool %s: %s\n", res.Pool, strings.Join(res.Pruned, ", "))
+	}
+	return nil
+}
+
+func runProxmoxSnapshotList(cmd *cobra.Command, args []string) error {
+	p, ref, err := proxmoxInstanceRef(args[0])
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	snaps, err := p.Snapshots(ctx, ref)
+	if err != nil {
+		return fmt.Errorf("listing snapshots: %w", err)
+	}
+	if len(snaps) == 0 {
+		fmt.Printf("VM %d has no snapshots.\n", ref.VMID)
+		return nil
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tTAKEN\tRAM\tDESCRIPTION")
+	for _, s := range snaps {
+		taken := "-"
+		if s.SnapTime > 0 {
+			taken = time.Unix(s.SnapTime, 0).Local().Format("2006-01-02 15:04")
+		}
+		ram := "no"
+		if s.VMState == 1 {
+			ram = "yes"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", s.Name, taken, ram, strings.TrimSpace(s.Description))
+	}
+	return w.Flush()
+}
+
+func runProxmoxSnapshotDelete(cmd *cobra.Command, args []string) error {
+	p, ref, err := proxmoxInstanceRef(args[0])
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Minute)
+	defer cancel()
+
+	if err := p.DeleteSnapshot(ctx, ref, args[1]); err != nil {
+		return err
+	}
+	color.New(color.FgGreen).Printf("Deleted snapshot %s of VM %d\n", args[1], ref.VMID)
+	return nil
+}
+
+func runProxmoxSnapshotPromote(cmd *cobra.Command, args []string) error {
+	p, ref, err := proxmoxInstanceRef(args[0])
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	fmt.Printf("Promoting snapshot %s of VM %d to a template...\n", args[1], ref.VMID)
+	res, err := p.PromoteSnapshot(ctx, ref, args[1], proxmoxTemplateName)
+	if err != nil {
+		return err
+	}
+	color.New(color.FgGreen).Printf("Created template %d (%s)\n", res.VMID, res.Name)
+	fmt.Printf("To provision from it, set provisioning.template_vmid to %d in %s\n",
+		res.VMID, pmx.ConfigPath(platform.ConfigDir()))
+	return nil
+}
+
+func runProxmoxRollback(cmd *cobra.Command, args []string) error {
+	p, ref, err := proxmoxInstanceRef(args[0])
+	if err != nil {
+		return err
+	}
+	if !proxmoxRollbackYes && !proxmoxConfirm(fmt.Sprintf("Roll VM %d back to %s? Changes since the snapshot are lost.", ref.VMID, args[1])) {
+		fmt.Println("Aborted.")
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Minute)
+	defer cancel()
+
+	if err := p.Rollback(ctx, ref, args[1]); err != nil {
+		return err
+	}
+	color.New(color.FgGreen).Printf("Rolled VM %d back to %s\n", ref.VMID, args[1])
+	return nil
+}
+
+func runProxmoxBackup(cmd *cobra.Command, args []string) error {
+	p, ref, err := proxmoxInstanceRef(args[0])
+	if err != nil {
+		return err
+	}
+	// vzdump duration is dominated by disk size; rely on Ctrl-C rather than
+	// a short CLI timeout.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	fmt.Printf("Backing up VM %d...\n", ref.VMID)
+	res, err := p.Backup(ctx, ref, pmx.BackupOptions{Storage: proxmoxBackupStorage, Mode: proxmoxBackupMode})
+	if err != nil {
+		return err
+	}
+	color.New(color.FgGreen).Printf("Backup of VM %d written to %s (%s mode)\n", ref.VMID, res.Storage, res.Mode)
+	return nil
+}
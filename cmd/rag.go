@@ -11,6 +11,7 @@ import (
 	"github.com/aceteam-ai/citadel-cli/internal/rag"
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 var (
@@ -18,6 +19,8 @@ var (
 	ragFilePattern string
 	ragTopK        int
 	ragJSON        bool
+	ragOCR         bool
+	ragQueryOpts   rag.QueryOptions
 )
 
 var ragCmd = &cobra.Command{
@@ -44,10 +47,15 @@ Start it with 'citadel module install tei' if it is not yet up.`,
 var ragIndexCmd = &cobra.Command{
 	Use:   "index <path>",
 	Short: "Index a directory (or file) into the node-local semantic index",
-	Long: `Chunk, embed, and store the text files under <path> into the node-local index.
+	Long: `Chunk, embed, and store the documents under <path> into the node-local index.
+
+Text files are indexed as-is. PDFs (via poppler's pdftotext), DOCX, XLSX,
+PPTX and HTML are converted to text first, and each chunk remembers its page
+and section so query results can cite them. With --ocr, images and scanned
+PDF pages are read by the local unlimited-ocr service.
 
 Incremental and idempotent: unchanged files are skipped by content hash, and
-files deleted on disk are pruned on re-index. Binary files and noise dirs
+files deleted on disk are pruned on re-index. Other binary files and noise dirs
 (.git, node_modules, .venv, ...) are skipped automatically.`,
 	Args:         cobra.ExactArgs(1),
 	SilenceUsage: true,
@@ -55,8 +63,15 @@ files deleted on disk are pruned on re-index. Binary files and noise dirs
 }
 
 var ragQueryCmd = &cobra.Command{
-	Use:          "query <text>",
-	Short:        "Semantic-search the node-local index and show results with provenance",
+	Use:   "query <text>",
+	Short: "Semantic-search the node-local index and show results with provenance",
+	Long: `Search the node-local index and show the best-matching chunks.
+
+By default the search is hybrid: the embedding match is fused with an exact
+full-text match, so identifiers, error codes and names are found even when the
+embedding model blurs them. --mode vector gives pure semantic search, and
+--mode lexical pure keyword search (which needs no TEI). --rerank reorders the
+results with the cross-encoder served at TEI's /rerank (CITADEL_RERANK_URL).`,
 	Args:         cobra.MinimumNArgs(1),
 	SilenceUsage: true,
 	RunE:         runRAGQuery,
@@ -73,7 +88,9 @@ func init() {
 	ragCmd.PersistentFlags().StringVar(&ragModel, "model", "", "Embedding model override (default: gte-multilingual-base)")
 	ragCmd.PersistentFlags().BoolVar(&ragJSON, "json", false, "Emit machine-readable JSON")
 	ragIndexCmd.Flags().StringVar(&ragFilePattern, "pattern", "", "Restrict indexed filenames (glob, e.g. \"*.md\")")
+	ragIndexCmd.Flags().BoolVar(&ragOCR, "ocr", false, "OCR images and scanned PDF pages via the unlimited-ocr service")
 	ragQueryCmd.Flags().IntVar(&ragTopK, "top-k", 10, "Max results to return")
+	addQueryOptionFlags(ragQueryCmd.Flags(), &ragQueryOpts)
 
 	ragCmd.AddCommand(ragIndexCmd)
 	ragCmd.AddCommand(ragQueryCmd)
@@ -91,6 +108,9 @@ func newRAGService() *rag.Service {
 
 func runRAGIndex(cmd *cobra.Command, args []string) error {
 	svc := newRAGService()
+	if ragOCR {
+		svc.EnableOCR()
+	}
 	fmt.Printf("Indexing %s via %s ...\n", args[0], svc.Model())
 	res, err := svc.Index(cmd.Context(), args[0], ragFilePattern)
 	if err != nil {
@@ -107,7 +127,11 @@ func runRAGIndex(cmd *cobra.Command, args []string) error {
 func runRAGQuery(cmd *cobra.Command, args []string) error {
 	query := strings.Join(args, " ")
 	svc := newRAGService()
-	res, err := svc.Query(cmd.Context(), query, ragTopK)
+	opts, err := resolveQueryOptions(ragQueryOpts, ragTopK)
+	if err != nil {
+		return err
+	}
+	res, err := svc.QueryWithOptions(cmd.Context(), query, opts)
 	if err != nil {
 		return ragEmbedError(err)
 	}
@@ -120,13 +144,37 @@ func runRAGQuery(cmd *cobra.Command, args []string) error {
 	}
 	fmt.Printf("%s\n", color.New(color.Faint).Sprintf("%s", res.Provenance))
 	for i, h := range res.Hits {
-		loc := fmt.Sprintf("%s#%d", filepath.Base(h.Path), h.ChunkIndex)
-		fmt.Printf("\n%s  %s  %s\n", color.CyanString("%d.", i+1), color.New(color.Bold).Sprint(loc), color.New(color.Faint).Sprintf("(score %.3f)", h.Score))
+		fmt.Printf("\n%s  %s  %s\n", color.CyanString("%d.", i+1), color.New(color.Bold).Sprint(h.Citation()), color.New(color.Faint).Sprintf("(score %.3f)", h.Score))
 		fmt.Printf("   %s\n", h.Text)
 	}
 	return nil
 }
 
+// addQueryOptionFlags binds the retrieval mode, rerank and filter flags shared
+// by `citadel rag query` and `citadel search`.
+func addQueryOptionFlags(fs *pflag.FlagSet, o *rag.QueryOptions) {
+	fs.StringVar(&o.Mode, "mode", "hybrid", "Retrieval mode: hybrid, vector, or lexical")
+	fs.BoolVar(&o.Rerank, "rerank", false, "Rerank results with the TEI cross-encoder (/rerank)")
+	fs.StringVar(&o.PathPrefix, "path-prefix", "", "Only files at or under this path")
+	fs.StringSliceVar(&o.Extensions, "ext", nil, "Only files with these extensions (e.g. pdf,md)")
+	fs.StringVar(&o.ModifiedAfter, "modified-after", "", "Only files modified at or after this time (YYYY-MM-DD or RFC 3339)")
+	fs.StringVar(&o.ModifiedBefore, "modified-before", "", "Only files modified at or before this time (YYYY-MM-DD or RFC 3339)")
+}
+
+// resolveQueryOptions applies --top-k and makes --path-prefix absolute, since
+// the index stores absolute paths.
+func resolveQueryOptions(o rag.QueryOptions, topK int) (rag.QueryOptions, error) {
+	o.TopK = topK
+	if o.PathPrefix != "" {
+		abs, err := filepath.Abs(o.PathPrefix)
+		if err != nil {
+			return o, fmt.Errorf("resolve --path-prefix: %w", err)
+		}
+		o.PathPrefix = abs
+	}
+	return o, nil
+}
+
 func runRAGStatus(cmd *cobra.Command, args []string) error {
 	svc := newRAGService()
 	st, err := svc.Status()
@@ -18,6 +18,7 @@ var (
 	searchTopK  int
 	searchRoot  string
 	searchModel string
+	searchOpts  rag.QueryOptions
 )
 
 // searchResultJSON is ONE result in the `citadel search --json` output. This is
@@ -28,22 +29,30 @@ var (
 //	path        absolute file path of the matched chunk's source file
 //	chunk_index 0-based index of the chunk within that file
 //	snippet     the matched chunk text, truncated to ~500 bytes
-//	score       cosine similarity in [-1, 1], higher is more relevant
+//	score       relevance, higher is better: cosine similarity in [-1, 1] with
+//	            --mode vector, a reciprocal-rank-fusion score with the default
+//	            hybrid mode, the reranker's score with --rerank
+//	page        1-based page/slide of the chunk; omitted when unknown
+//	section     heading, slide title or sheet name; omitted when unknown
 type searchResultJSON struct {
 	Path       string  `json:"path"`
 	ChunkIndex int     `json:"chunk_index"`
 	Snippet    string  `json:"snippet"`
 	Score      float64 `json:"score"`
+	Page       int     `json:"page,omitempty"`
+	Section    string  `json:"section,omitempty"`
 }
 
 // searchOutputJSON is the top-level `citadel search --json` document. count may
 // be LESS than --top-k when hits were filtered out by the authorized-roots
-// check.
+// check. mode and reranked say how the scores were produced.
 type searchOutputJSON struct {
-	Query   string             `json:"query"`
-	Count   int                `json:"count"`
-	Model   string             `json:"model"`
-	Results []searchResultJSON `json:"results"`
+	Query    string             `json:"query"`
+	Count    int                `json:"count"`
+	Model    string             `json:"model"`
+	Mode     string             `json:"mode"`
+	Reranked bool               `json:"reranked"`
+	Results  []searchResultJSON `json:"results"`
 }
 
 var searchCmd = &cobra.Command{
@@ -58,6 +67,7 @@ Nothing is searchable until you authorize a root directory:
   citadel search index                       # index all authorized roots
   citadel search "quarterly revenue"         # semantic search
   citadel search "quarterly revenue" --json  # machine-readable output
+  citadel search ERR_CONN_RESET --ext log    # exact identifiers, filtered
 
 The authorized-roots allowlist is the security boundary: only files under an
 authorized root are ever indexed or returned. Indexing and searching require the
@@ -116,6 +126,7 @@ func init() {
 	searchCmd.Flags().BoolVar(&searchJSON, "json", false, "Emit machine-readable JSON (stable wrapper contract)")
 	searchCmd.Flags().IntVar(&searchTopK, "top-k", 10, "Max results to return")
 	searchCmd.Flags().StringVar(&searchRoot, "root", "", "Restrict the search to this authorized root")
+	addQueryOptionFlags(searchCmd.Flags(), &searchOpts)
 
 	searchRootsCmd.AddCommand(searchRootsListCmd)
 	searchRootsCmd.AddCommand(searchRootsAddCmd)
@@ -160,15 +171,19 @@ func runSearch(cmd *cobra.Command, args []string) error {
 		return err
 	}
 	query := strings.Join(args, " ")
-	res, err := svc.Query(cmd.Context(), query, searchTopK)
+	opts, err := resolveQueryOptions(searchOpts, searchTopK)
+	if err != nil {
+		return err
+	}
+	res, err := svc.QueryWithOptions(cmd.Context(), query, opts)
 	if err != nil {
 		return ragEmbedError(err)
 	}
 
 	if searchJSON {
-		out := searchOutputJSON{Query: query, Count: len(res.Hits), Model: res.Model, Results: make([]searchResultJSON, 0, len(res.Hits))}
+		out := searchOutputJSON{Query: query, Count: len(res.Hits), Model: res.Model, Mode: res.Mode, Reranked: res.Reranked, Results: make([]searchResultJSON, 0, len(res.Hits))}
 		for _, h := range res.Hits {
-			out.Results = append(out.Results, searchResultJSON{Path: h.Path, ChunkIndex: h.ChunkIndex, Snippet: h.Text, Score: h.Score})
+			out.Results = append(out.Results, searchResultJSON{Path: h.Path, ChunkIndex: h.ChunkIndex, Snippet: h.Text, Score: h.Score, Page: h.Page, Section: h.Section})
 		}
 		return printJSON(out)
 	}
@@ -179,7 +194,14 @@ func runSearch(cmd *cobra.Command, args []string) error {
 	}
 	fmt.Printf("%s\n", color.New(color.Faint).Sprint(res.Provenance))
 	for i, h := range res.Hits {
-		fmt.Printf("\n%s  %s  %s\n", color.CyanString("%d.", i+1), color.New(color.Bold).Sprint(h.Path), color.New(color.Faint).Sprintf("(chunk %d, score %.3f)", h.ChunkIndex, h.Score))
+		where := fmt.Sprintf("chunk %d", h.ChunkIndex)
+		if h.Page > 0 {
+			where = fmt.Sprintf("p.%d", h.Page)
+		}
+		if h.Section != "" {
+			where += " · " + h.Section
+		}
+		fmt.Printf("\n%s  %s  %s\n", color.CyanString("%d.", i+1), color.New(color.Bold).Sprint(h.Path), color.New(color.Faint).Sprintf("(%s, score %.3f)", where, h.Score))
 		fmt.Printf("   %s\n", h.Text)
 	}
 	return nil
@@ -13,6 +13,7 @@ func TestSearchJSONShape(t *testing.T) {
 		Query: "refund policy",
 		Count: 1,
 		Model: "gte-multilingual-base",
+		Mode:  "hybrid",
 		Results: []searchResultJSON{
 			{Path: "/home/u/docs/policy.md", ChunkIndex: 2, Snippet: "Refunds are issued within 30 days.", Score: 0.8123},
 		},
@@ -27,7 +28,7 @@ func TestSearchJSONShape(t *testing.T) {
 	if err := json.Unmarshal(b, &top); err != nil {
 		t.Fatalf("unmarshal: %v", err)
 	}
-	for _, k := range []string{"query", "count", "model", "results"} {
+	for _, k := range []string{"query", "count", "model", "mode", "reranked", "results"} {
 		if _, ok := top[k]; !ok {
 			t.Errorf("top-level JSON missing required key %q; got %v", k, top)
 		}
@@ -12,6 +12,7 @@ import (
 
 	"github.com/aceteam-ai/citadel-cli/internal/config"
 	"github.com/aceteam-ai/citadel-cli/internal/gateway"
+	"github.com/aceteam-ai/citadel-cli/internal/jobs"
 	"github.com/aceteam-ai/citadel-cli/internal/network"
 	"github.com/aceteam-ai/citadel-cli/internal/platform"
 	"github.com/aceteam-ai/citadel-cli/internal/tlscert"
@@ -27,6 +28,7 @@ var (
 	serveNoTLS         bool
 	serveCertDir       string
 	serveBind          string
+	serveOTLPEndpoint  string
 )
 
 var serveCmd = &cobra.Command{
@@ -190,6 +192,11 @@ func runServe(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	// Trace gateway requests when an OTLP collector is configured (off by
+	// default). Each request continues the caller's traceparent into the
+	// upstream it is proxied to.
+	defer startTracing(serveOTLPEndpoint, nodeName)()
+
 	// Create gateway
 	gw := gateway.NewServer(gateway.Config{
 		Port:          servePort,
@@ -230,17 +237,22 @@ func runServe(cmd *cobra.Command, args []string) error {
 	gw.AddUpstream("/ssh/authorized-keys", &gateway.Upstream{Address: statusAddr})
 	gw.AddUpstream("/provision", &gateway.Upstream{Address: statusAddr})
 
-	// Embedding upstream (issue #351): route the already-metered
-	// /v1/embeddings path to the local TEI service (default 127.0.0.1:8102).
-	// TEI exposes the OpenAI-compatible endpoint at the same path, so no prefix
-	// stripping is needed.
-	gw.AddUpstream("/v1/embeddings", &gateway.Upstream{Address: embeddingAddr})
+	// Embeddings and rerank: route /v1/embeddings and /v1/rerank by model to the
+	// local TEI embedder (default 127.0.0.1:8102) or reranker (CITADEL_RERANK_URL),
+	// falling back to a chat engine serving the embedding model, and to the TEI
+	// port itself while no embedder has been probed (issue #351's static route).
+	gw.SetEmbeddingRouter(newLocalEmbeddingLister(embeddingAddr, rerankAddr()))
+	gw.SetEmbeddingFallback(embeddingAddr)
 
 	// Chat routing (issue #581): expose /v1/chat/completions (+ /v1/completions
 	// and /v1/models) with model->engine resolution so mesh-direct chat to this
 	// node reaches whichever local engine serves the requested model.
 	gw.SetChatRouter(newLocalChatLister())
 
+	// OpenAI audio: /v1/audio/transcriptions and /v1/audio/speech on the whisper
+	// and kokoro sidecars that back TRANSCRIBE_AUDIO and SYNTHESIZE_SPEECH.
+	gw.SetAudioRouter(jobs.NewTranscribeAudioHandler(resolveWorkspaceDir()), jobs.NewSynthesizeSpeechHandler())
+
 	// VNC WebSocket proxy (requires websockify running on vnc-port)
 	gw.AddUpstream("/vnc", &gateway.Upstream{
 		Address:     vncAddr,
@@ -271,7 +283,8 @@ func runServe(cmd *cobra.Command, args []string) error {
 	fmt.Printf("     /health, /status, /worker, /ping -> %s (status server)\n", statusAddr)
 	fmt.Printf("     /api/screenshot, /api/actions -> %s\n", statusAddr)
 	fmt.Printf("     /ssh/authorized-keys     -> %s (SSH key deploy)\n", statusAddr)
-	fmt.Printf("     /v1/embeddings           -> %s (TEI embeddings)\n", embeddingAddr)
+	fmt.Printf("     /v1/embeddings           -> %s or local engine by model\n", embeddingAddr)
+	fmt.Printf("     /v1/rerank               -> TEI reranker by model\n")
 	fmt.Printf("     /v1/chat/completions     -> local engine by model (#581)\n")
 	fmt.Printf("     /vnc/...                 -> %s (websockify)\n", vncAddr)
 	fmt.Printf("     /terminal/...            -> %s (terminal)\n", termAddr)
@@ -306,6 +319,7 @@ func init() {
 	serveCmd.Flags().IntVar(&serveTermPort, "terminal-port", 7860, "Port of the local terminal server")
 	serveCmd.Flags().IntVar(&serveVNCPort, "vnc-port", 6080, "Port of websockify (VNC WebSocket bridge)")
 	serveCmd.Flags().IntVar(&serveEmbeddingPort, "embedding-port", 8102, "Port of the local TEI embedding service (/v1/embeddings upstream)")
+	serveCmd.Flags().StringVar(&serveOTLPEndpoint, "otlp-endpoint", "", "OTLP/HTTP collector URL for request traces, e.g. http://collector:4318 (default: off, or set OTEL_EXPORTER_OTLP_ENDPOINT)")
 
 	// Mark --no-tls as hidden (not recommended for production)
 	serveCmd.Flags().MarkHidden("no-tls")
@@ -11,6 +11,7 @@ package cmd
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -61,19 +62,7 @@ Requires the node gateway to be running (citadel work).`,
 			return fmt.Errorf("--visibility private needs a creator identity only the AceTeam backend can supply; use org or link from the CLI")
 		}
 
-		// meshIPv4() only answers inside the process that runs the network stack
-		// (`citadel work`); from a separate CLI process it is empty, so fall back
-		// to the facts that process persisted.
-		facts := gatewayFactsForURL()
-		ip := meshIPv4()
-		if ip == "" {
-			ip = facts.MeshIP
-		}
-		if ip == "" {
-			return fmt.Errorf("cannot determine this node's AceTeam Network IP; is `citadel work` running?")
-		}
-
-		body, err := json.Marshal(status.ExposeSpec{
+		out, err := postAgentExpose(cmd.Context(), status.ExposeSpec{
 			Name:       name,
 			Port:       exposeSvcPort,
 			Visibility: string(vis),
@@ -83,36 +72,6 @@ Requires the node gateway to be running (citadel work).`,
 			return err
 		}
 
-		url := fmt.Sprintf("http://%s:%d/agent/expose", ip, statusPortFrom(facts))
-		req, err := http.NewRequestWithContext(cmd.Context(), http.MethodPost, url, bytes.NewReader(body))
-		if err != nil {
-			return err
-		}
-		req.Header.Set("Content-Type", "application/json")
-
-		resp, err := (&http.Client{Timeout: 20 * time.Second}).Do(req)
-		if err != nil {
-			return fmt.Errorf("reach the node agent at %s: %w (is `citadel work` running?)", url, err)
-		}
-		defer resp.Body.Close()
-
-		var out struct {
-			URL       string `json:"url"`
-			Token     string `json:"token"`
-			ExpiresAt string `json:"expires_at"`
-			Error     string `json:"error"`
-		}
-		if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
-			return fmt.Errorf("decode agent response (HTTP %d): %w", resp.StatusCode, err)
-		}
-		if resp.StatusCode != http.StatusOK || out.Error != "" {
-			msg := out.Error
-			if msg == "" {
-				msg = fmt.Sprintf("HTTP %d", resp.StatusCode)
-			}
-			return fmt.Errorf("expose failed: %s", msg)
-		}
-
 		fmt.Printf("\n✅ Exposed %q -> 127.0.0.1:%d\n", name, exposeSvcPort)
 		fmt.Printf("   URL:    %s\n", out.URL)
 		fmt.Printf("   Access: %s\n", vis)
@@ -125,6 +84,61 @@ Requires the node gateway to be running (citadel work).`,
 	},
 }
 
+// agentExposeResponse is the /agent/expose reply (worker.ExposeResult, or an
+// error).
+type agentExposeResponse struct {
+	URL       string `json:"url"`
+	Token     string `json:"token"`
+	ExpiresAt string `json:"expires_at"`
+	Error     string `json:"error"`
+}
+
+// postAgentExpose sends spec to the running worker's /agent/expose endpoint.
+func postAgentExpose(ctx context.Context, spec status.ExposeSpec) (*agentExposeResponse, error) {
+	// meshIPv4() only answers inside the process that runs the network stack
+	// (`citadel work`); from a separate CLI process it is empty, so fall back
+	// to the facts that process persisted.
+	facts := gatewayFactsForURL()
+	ip := meshIPv4()
+	if ip == "" {
+		ip = facts.MeshIP
+	}
+	if ip == "" {
+		return nil, fmt.Errorf("cannot determine this node's AceTeam Network IP; is `citadel work` running?")
+	}
+
+	body, err := json.Marshal(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	url := fmt.Sprintf("http://%s:%d/agent/expose", ip, statusPortFrom(facts))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := (&http.Client{Timeout: 20 * time.Second}).Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("reach the node agent at %s: %w (is `citadel work` running?)", url, err)
+	}
+	defer resp.Body.Close()
+
+	var out agentExposeResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, fmt.Errorf("decode agent response (HTTP %d): %w", resp.StatusCode, err)
+	}
+	if resp.StatusCode != http.StatusOK || out.Error != "" {
+		msg := out.Error
+		if msg == "" {
+			msg = fmt.Sprintf("HTTP %d", resp.StatusCode)
+		}
+		return nil, fmt.Errorf("expose failed: %s", msg)
+	}
+	return &out, nil
+}
+
 // statusPortFrom returns the port the agent control surface listens on, taking
 // the running gateway's own recorded port when available.
 func statusPortFrom(f gatewayFacts) int {
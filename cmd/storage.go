@@ -5,6 +5,8 @@ package cmd
 
 import (
 	"fmt"
+	"os"
+	"text/tabwriter"
 
 	"github.com/aceteam-ai/citadel-cli/internal/storage"
 	"github.com/fatih/color"
@@ -19,7 +21,11 @@ var storageCmd = &cobra.Command{
 Objects are stored under ~/.citadel/storage/data and survive restarts. The
 gateway is published on 127.0.0.1 only (never 0.0.0.0). Root credentials are
 minted once on first start and reused on every restart, so stored objects and
-presigned URLs remain valid.`,
+presigned URLs remain valid.
+
+Give consumers their own scoped keys (storage keys), cap buckets (storage
+quota), and reach the store from other nodes through the gateway (storage
+expose).`,
 }
 
 var storageStartCmd = &cobra.Command{
@@ -91,23 +97,26 @@ func runStorageStatus(cmd *cobra.Command, args []string) error {
 	fmt.Printf("  Mesh IP:  %s\n", meshStr)
 	fmt.Printf("  Port:     %d\n", st.Port)
 	fmt.Printf("  Buckets:  %d\n", st.BucketCount)
-	fmt.Printf("  Used:     %s (approx)\n", humanBytes(st.BytesUsed))
-	return nil
-}
+	fmt.Printf("  Used:     %s (approx)\n", storage.FormatSize(st.BytesUsed))
+	fmt.Printf("  Keys:     %d issued\n", st.KeyCount)
 
-// humanBytes renders a byte count in a compact human-readable form (e.g.
-// "1.5 MiB"). Kept local to avoid promoting the go-humanize indirect dependency.
-func humanBytes(n int64) string {
-	const unit = 1024
-	if n < unit {
-		return fmt.Sprintf("%d B", n)
-	}
-	div, exp := int64(unit), 0
-	for x := n / unit; x >= unit; x /= unit {
-		div *= unit
-		exp++
+	if len(st.Buckets) > 0 {
+		fmt.Println()
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "BUCKET\tUSED\tQUOTA\t")
+		for _, b := range st.Buckets {
+			quota, flag := "-", ""
+			if b.QuotaBytes > 0 {
+				quota = fmt.Sprintf("%s (%.0f%%)", storage.FormatSize(b.QuotaBytes), 100*float64(b.BytesUsed)/float64(b.QuotaBytes))
+			}
+			if b.OverQuota {
+				flag = color.New(color.FgRed).Sprint("over quota: writes blocked")
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", b.Name, storage.FormatSize(b.BytesUsed), quota, flag)
+		}
+		w.Flush()
 	}
-	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+	return nil
 }
 
 func runStorageStop(cmd *cobra.Command, args []string) error {
@@ -0,0 +1,239 @@
+// cmd/storage_access.go
+//
+// Access control for the on-node object store: issued access keys
+// (`citadel storage keys`), per-bucket quotas (`citadel storage quota`) and
+// exposure through the node gateway (`citadel storage expose`). The mechanics
+// live in internal/storage; this file is the CLI surface.
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aceteam-ai/citadel-cli/internal/gateway"
+	"github.com/aceteam-ai/citadel-cli/internal/status"
+	"github.com/aceteam-ai/citadel-cli/internal/storage"
+	"github.com/spf13/cobra"
+)
+
+var (
+	storageKeyName     string
+	storageKeyBuckets  []string
+	storageKeyReadOnly bool
+
+	storageExposeKey        string
+	storageExposeName       string
+	storageExposeVisibility string
+	storageExposeTTL        time.Duration
+)
+
+var storageKeysCmd = &cobra.Command{
+	Use:   "keys",
+	Short: "Manage access keys for the object storage gateway",
+	Long: `Issue scoped S3 access keys instead of handing out the root credential.
+
+Each key can be limited to a set of buckets (--bucket, repeatable; default all
+buckets) and to read-only access. Revoking a key takes effect immediately.`,
+}
+
+var storageKeysCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Issue a new access key",
+	Example: `  # Read-write key for one bucket
+  citadel storage keys create --name backups --bucket backups
+
+  # Read-only key for two buckets
+  citadel storage keys create --name viewer --bucket media --bucket clips --read-only`,
+	Args: cobra.NoArgs,
+	RunE: runStorageKeysCreate,
+}
+
+var storageKeysRevokeCmd = &cobra.Command{
+	Use:   "revoke <access-key>",
+	Short: "Revoke an access key",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := storage.RevokeKey(args[0]); err != nil {
+			return err
+		}
+		fmt.Printf("Revoked %s.\n", args[0])
+		return nil
+	},
+}
+
+var storageKeysListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List issued access keys",
+	Args:  cobra.NoArgs,
+	RunE:  runStorageKeysList,
+}
+
+var storageQuotaCmd = &cobra.Command{
+	Use:   "quota",
+	Short: "Manage per-bucket size quotas",
+	Long: `Limit how much a bucket may hold.
+
+Usage is measured about once a minute by ` + "`citadel work`" + `. Once a bucket
+reaches its quota, issued keys can still read and delete in it but can no
+longer write, until it is back under the limit. The root credential is not
+limited.`,
+}
+
+var storageQuotaSetCmd = &cobra.Command{
+	Use:     "set <bucket> <size>",
+	Short:   "Set a bucket's quota (e.g. 500M, 10G)",
+	Example: `  citadel storage quota set media 50G`,
+	Args:    cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		size, err := storage.ParseSize(args[1])
+		if err != nil {
+			return err
+		}
+		if err := storage.SetQuota(args[0], size); err != nil {
+			return err
+		}
+		fmt.Printf("Quota for %s set to %s.\n", args[0], storage.FormatSize(size))
+		return nil
+	},
+}
+
+var storageQuotaClearCmd = &cobra.Command{
+	Use:   "clear <bucket>",
+	Short: "Remove a bucket's quota",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := storage.ClearQuota(args[0]); err != nil {
+			return err
+		}
+		fmt.Printf("Quota for %s removed.\n", args[0])
+		return nil
+	},
+}
+
+var storageExposeCmd = &cobra.Command{
+	Use:   "expose",
+	Short: "Expose the object storage gateway on the AceTeam Network gateway",
+	Long: `Serves the object store on this node's gateway at /expose/<name>/.
+
+Callers are admitted by visibility (org members, or holders of a link token)
+and every request is then re-signed with the issued key given by --key, so
+that key's bucket scope and read-only mode bound what the exposure can reach.
+Clients sign with any placeholder credential and use path-style addressing.
+
+Requires the node gateway to be running (citadel work).`,
+	Example: `  # Read-only key for the org
+  citadel storage keys create --name org-media --bucket media --read-only
+  citadel storage expose --key <access-key> --visibility org
+
+  # Then, from another node on the network
+  aws --endpoint-url https://<node-ip>:8443/expose/storage s3 ls s3://media`,
+	Args: cobra.NoArgs,
+	RunE: runStorageExpose,
+}
+
+func init() {
+	storageKeysCreateCmd.Flags().StringVar(&storageKeyName, "name", "", "Label for the key")
+	storageKeysCreateCmd.Flags().StringArrayVar(&storageKeyBuckets, "bucket", nil, "Bucket the key may access (repeatable; default all)")
+	storageKeysCreateCmd.Flags().BoolVar(&storageKeyReadOnly, "read-only", false, "Only allow listing and reading objects")
+	storageKeysCmd.AddCommand(storageKeysCreateCmd, storageKeysRevokeCmd, storageKeysListCmd)
+
+	storageQuotaCmd.AddCommand(storageQuotaSetCmd, storageQuotaClearCmd)
+
+	storageExposeCmd.Flags().StringVar(&storageExposeKey, "key", "", "Issued access key to sign requests with (required)")
+	storageExposeCmd.Flags().StringVar(&storageExposeName, "name", "storage", "Exposure name (the <name> in /expose/<name>/)")
+	storageExposeCmd.Flags().StringVar(&storageExposeVisibility, "visibility", "org", "Who may reach it: org or link")
+	storageExposeCmd.Flags().DurationVar(&storageExposeTTL, "ttl", 24*time.Hour, "Lifetime of a --visibility link token")
+
+	storageCmd.AddCommand(storageKeysCmd, storageQuotaCmd, storageExposeCmd)
+}
+
+func runStorageKeysCreate(cmd *cobra.Command, args []string) error {
+	key, err := storage.CreateKey(storage.KeyRequest{
+		Name:     storageKeyName,
+		Buckets:  storageKeyBuckets,
+		ReadOnly: storageKeyReadOnly,
+	})
+	if key == nil {
+		return err
+	}
+	fmt.Printf("Access key: %s\n", key.AccessKey)
+	fmt.Printf("Secret key: %s\n", key.SecretKey)
+	fmt.Printf("Buckets:    %s\n", keyBucketsString(*key))
+	fmt.Printf("Access:     %s\n", keyAccessString(*key))
+	fmt.Println("\nStore the secret now; `keys list` does not show it.")
+	// A partial failure (key issued, policies not yet applied) is still
+	// reported after the key, which the operator needs either way.
+	return err
+}
+
+func runStorageKeysList(cmd *cobra.Command, args []string) error {
+	keys, err := storage.ListKeys()
+	if err != nil {
+		return err
+	}
+	if len(keys) == 0 {
+		fmt.Println("No access keys issued. Create one with `citadel storage keys create`.")
+		return nil
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ACCESS KEY\tNAME\tBUCKETS\tACCESS\tCREATED")
+	for _, k := range keys {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", k.AccessKey, dashIfEmpty(k.Name), keyBucketsString(k),
+			keyAccessString(k), k.Created.Local().Format("2006-01-02 15:04"))
+	}
+	return w.Flush()
+}
+
+func keyBucketsString(k storage.AccessKey) string {
+	if len(k.Buckets) == 0 {
+		return "(all)"
+	}
+	return strings.Join(k.Buckets, ",")
+}
+
+func keyAccessString(k storage.AccessKey) string {
+	if k.ReadOnly {
+		return "read-only"
+	}
+	return "read-write"
+}
+
+func runStorageExpose(cmd *cobra.Command, args []string) error {
+	if storageExposeKey == "" {
+		return fmt.Errorf("--key is required (see `citadel storage keys list`)")
+	}
+	// Resolve locally first for a clear error; the worker looks it up again.
+	key, err := storage.LookupKey(storageExposeKey)
+	if err != nil {
+		return err
+	}
+	vis := gateway.Visibility(storageExposeVisibility)
+	if vis != gateway.VisibilityOrg && vis != gateway.VisibilityLink {
+		return fmt.Errorf("invalid --visibility %q (want org or link)", storageExposeVisibility)
+	}
+
+	out, err := postAgentExpose(cmd.Context(), status.ExposeSpec{
+		Name:       storageExposeName,
+		Port:       storage.HostPort(),
+		Visibility: string(vis),
+		TTLSeconds: int(storageExposeTTL.Seconds()),
+		StorageKey: key.AccessKey,
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("\n✅ Exposed object storage as %q\n", storageExposeName)
+	fmt.Printf("   Endpoint: %s\n", out.URL)
+	fmt.Printf("   Access:   %s, signed as %s (%s, buckets %s)\n", vis, key.AccessKey, keyAccessString(*key), keyBucketsString(*key))
+	if out.Token != "" {
+		fmt.Printf("   Token:    %s\n", out.Token)
+		fmt.Printf("   Expires:  %s\n", out.ExpiresAt)
+		fmt.Println("   Send the token as the X-Citadel-Access header, or append ?access_token=<token> to object URLs.")
+	}
+	fmt.Println()
+	return nil
+}
@@ -0,0 +1,218 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aceteam-ai/citadel-cli/internal/catalog"
+	"github.com/aceteam-ai/citadel-cli/internal/compose"
+	"github.com/aceteam-ai/citadel-cli/internal/events"
+	"github.com/aceteam-ai/citadel-cli/internal/notify"
+	"github.com/aceteam-ai/citadel-cli/internal/status"
+	"github.com/aceteam-ai/citadel-cli/internal/supervisor"
+)
+
+// Seams for the service supervisor, swapped in tests so no docker is needed.
+var (
+	// supervisorPS lists a compose file's project containers, stopped ones
+	// included: a crashed container only shows up with -a.
+	supervisorPS = func(composePath string) ([]byte, error) {
+		args := append(composeFileArgs(composePath, composePath), "ps", "-a", "--format", "json")
+		return composeCommand(args...).Output()
+	}
+	// supervisorOOMKilled reports whether the kernel's OOM killer ended a
+	// container; false when docker cannot say.
+	supervisorOOMKilled = func(containerID string) bool {
+		rt := catalog.SelectContainerRuntime()
+		out, err := exec.Command(rt.EngineBin, "inspect", "--format", "{{.State.OOMKilled}}", containerID).Output()
+		return err == nil && strings.TrimSpace(string(out)) == "true"
+	}
+	// supervisorRestart recreates a service's containers. --force-recreate
+	// because `up -d` leaves a running-but-unhealthy container alone.
+	supervisorRestart = func(name, composePath string) error {
+		return composeUp(name, composePath, "--force-recreate")
+	}
+	// supervisorHealthCheck finds a service's declared health check: from the
+	// installed module checkout when the lockfile has it, else the catalog.
+	supervisorHealthCheck = func(name string, modules map[string]catalog.LockEntry) catalog.HealthCheck {
+		var manifest *catalog.ServiceManifest
+		var err error
+		if entry, ok := modules[name]; ok {
+			manifest, err = catalog.InstalledManifest(entry)
+		} else {
+			manifest, err = catalog.LoadServiceManifest(name)
+		}
+		if err != nil || manifest == nil {
+			return catalog.HealthCheck{}
+		}
+		return manifest.HealthCheck
+	}
+)
+
+// startServiceSupervisor launches the background health supervisor: every
+// interval it probes each managed service and module, restarts one that stays
+// unhealthy (backing off exponentially), and marks it crash-looping -- with a
+// push to the org -- once restarts stop helping. Disabled by
+// CITADEL_SUPERVISE_INTERVAL<=0. Returns nil when disabled.
+func startServiceSupervisor(ctx context.Context, nodeName string) *supervisor.Supervisor {
+	interval, enabled := supervisor.IntervalFromEnv()
+	if !enabled {
+		Debug("service supervisor disabled (%s<=0)", supervisor.IntervalEnvVar)
+		return nil
+	}
+
+	var notifier *notify.Client
+	if dc := getDeviceConfigFromFile(); dc != nil && dc.DeviceAPIToken != "" {
+		apiBaseURL := dc.APIBaseURL
+		if apiBaseURL == "" {
+			apiBaseURL = authServiceURL
+		}
+		notifier = notify.NewClient(notify.Config{BaseURL: apiBaseURL, Token: dc.DeviceAPIToken})
+	}
+
+	sup := supervisor.New(supervisor.Config{
+		Targets:  supervisedTargets,
+		Interval: interval,
+		OnGiveUp: func(rec supervisor.Record) {
+			fmt.Fprintf(os.Stderr, "   - Warning: %s is crash-looping; stopped restarting it (%s)\n", rec.Name, rec.LastFailure)
+			if notifier != nil {
+				notifyCrashLoop(ctx, notifier, nodeName, rec)
+			}
+		},
+		OnStateChange: func(rec supervisor.Record, state string) {
+			events.Publish(events.TypeServiceStateChanged, map[string]any{
+				"service":       rec.Name,
+				"state":         state,
+				"reason":        rec.LastFailure,
+				"restart_count": rec.RestartCount,
+			})
+		},
+		Logf: func(format string, args ...any) { Log(format, args...) },
+	})
+	go sup.Run(ctx)
+	fmt.Printf("   - Service supervisor: probing every %s\n", interval)
+	return sup
+}
+
+// supervisedTargets lists the services to supervise from the current
+// manifest. It is re-read on every pass so a service the operator stopped
+// (desired_status: stopped) or removed is left alone from the next pass on.
+func supervisedTargets() []supervisor.Target {
+	manifest, configDir, err := findAndReadManifest()
+	if err != nil {
+		return nil
+	}
+	modules := make(map[string]catalog.LockEntry)
+	if lf, err := catalog.LoadLockfile(); err == nil {
+		for _, e := range lf.Modules {
+			modules[e.Name] = e
+		}
+	}
+	return supervisedTargetsFrom(manifest, configDir, modules)
+}
+
+// supervisedTargetsFrom builds one target per compose-managed service. Native
+// services have no container to judge and are skipped.
+func supervisedTargetsFrom(manifest *CitadelManifest, configDir string, modules map[string]catalog.LockEntry) []supervisor.Target {
+	var targets []supervisor.Target
+	for _, svc := range manifest.Services {
+		if serviceStartDisabled(svc) || svc.Type == "native" || svc.ComposeFile == "" {
+			continue
+		}
+		name := svc.Name
+		composePath := filepath.Join(configDir, svc.ComposeFile)
+		hc := supervisorHealthCheck(name, modules)
+		targets = append(targets, supervisor.Target{
+			Name:             name,
+			FailureThreshold: hc.Retries,
+			Probe: func(ctx context.Context) supervisor.Check {
+				return probeSupervisedService(composePath, hc)
+			},
+			Restart: func(ctx context.Context) error {
+				return supervisorRestart(name, composePath)
+			},
+		})
+	}
+	return targets
+}
+
+// probeSupervisedService judges a service by its declared containers and, when
+// they look healthy, by its declared health endpoint. Anything it cannot read
+// (docker down, an unparseable compose file) is unknown, never unhealthy: the
+// supervisor must not restart what it cannot see.
+func probeSupervisedService(composePath string, hc catalog.HealthCheck) supervisor.Check {
+	declared := compose.DeclaredServices(composePath)
+	if len(declared) == 0 {
+		return supervisor.Check{Health: supervisor.HealthUnknown}
+	}
+	out, err := supervisorPS(composePath)
+	if err != nil {
+		return supervisor.Check{Health: supervisor.HealthUnknown}
+	}
+	containers := compose.FilterPS(compose.ParsePS(out), declared)
+	for i := range containers {
+		// A 137 is `docker stop` or the OOM killer; only inspect can tell.
+		if c := &containers[i]; c.ID != "" && strings.Contains(c.Status, "(137)") {
+			c.OOMKilled = supervisorOOMKilled(c.ID)
+		}
+	}
+	check := supervisor.ClassifyService(containers)
+	if check.Health != supervisor.HealthHealthy || !catalog.HasHealthProbe(hc) {
+		return check
+	}
+	if catalog.ProbeHealth(hc) == catalog.ProbeUnhealthy {
+		return supervisor.Check{
+			Health: supervisor.HealthUnhealthy,
+			Reason: fmt.Sprintf("health check %s on port %d returned a server error", hc.Endpoint, hc.Port),
+		}
+	}
+	return check
+}
+
+// notifyCrashLoop pushes a crash-looping service to the org. Best-effort: a
+// failed push is logged, the supervisor carries on.
+func notifyCrashLoop(ctx context.Context, notifier *notify.Client, nodeName string, rec supervisor.Record) {
+	ctx, cancel := context.WithTimeout(ctx, 20*time.Second)
+	defer cancel()
+	_, err := notifier.Send(ctx, notify.Notification{
+		Title:  fmt.Sprintf("%s is crash-looping on %s", rec.Name, nodeName),
+		Body:   fmt.Sprintf("Restarted %d times without recovering; automatic restarts stopped. Last failure: %s", rec.RestartCount, rec.LastFailure),
+		Target: notify.TargetNodes,
+	})
+	if err != nil {
+		Log("supervisor: crash-loop notification for %s: %v", rec.Name, err)
+	}
+}
+
+// supervisionStates adapts the supervisor's records for the status collector.
+// Services that never failed are left out so their heartbeat entry is
+// unchanged. Returns nil for a nil supervisor (disabled).
+func supervisionStates(sup *supervisor.Supervisor) func() map[string]status.SupervisionState {
+	if sup == nil {
+		return nil
+	}
+	return func() map[string]status.SupervisionState {
+		states := make(map[string]status.SupervisionState)
+		for _, rec := range sup.Records() {
+			if rec.RestartCount == 0 && rec.LastFailure == "" && !rec.CrashLooping {
+				continue
+			}
+			st := status.SupervisionState{
+				RestartCount: rec.RestartCount,
+				LastFailure:  rec.LastFailure,
+				CrashLooping: rec.CrashLooping,
+			}
+			if !rec.LastFailureAt.IsZero() {
+				at := rec.LastFailureAt
+				st.LastFailureAt = &at
+			}
+			states[rec.Name] = st
+		}
+		return states
+	}
+}
@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aceteam-ai/citadel-cli/internal/catalog"
+	"github.com/aceteam-ai/citadel-cli/internal/supervisor"
+)
+
+func TestSupervisedTargetsProbeAndRestartComposeServices(t *testing.T) {
+	configDir := t.TempDir()
+	servicesDir := filepath.Join(configDir, "services")
+	if err := os.MkdirAll(servicesDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"vllm", "kokoro"} {
+		compose := "services:\n  " + name + ":\n    image: example/" + name + "\n"
+		if err := os.WriteFile(filepath.Join(servicesDir, name+".yml"), []byte(compose), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	origPS, origRestart, origHC := supervisorPS, supervisorRestart, supervisorHealthCheck
+	t.Cleanup(func() { supervisorPS, supervisorRestart, supervisorHealthCheck = origPS, origRestart, origHC })
+	// Project-wide ps output: another service's crash must not count against vllm.
+	supervisorPS = func(string) ([]byte, error) {
+		return []byte(`{"Service":"vllm","State":"running","Status":"Up 2 hours"}
+{"Service":"kokoro","State":"exited","Status":"Exited (139) 1 minute ago"}`), nil
+	}
+	var restarted []string
+	supervisorRestart = func(name, _ string) error { restarted = append(restarted, name); return nil }
+	supervisorHealthCheck = func(name string, _ map[string]catalog.LockEntry) catalog.HealthCheck {
+		if name == "kokoro" {
+			return catalog.HealthCheck{Retries: 5}
+		}
+		return catalog.HealthCheck{}
+	}
+
+	manifest := &CitadelManifest{Services: []Service{
+		{Name: "vllm", ComposeFile: "services/vllm.yml"},
+		{Name: "kokoro", ComposeFile: "services/kokoro.yml"},
+		{Name: "comfyui", ComposeFile: "services/comfyui.yml", DesiredStatus: "stopped"},
+		{Name: "ollama", Type: "native", Port: 11434},
+	}}
+	targets := supervisedTargetsFrom(manifest, configDir, nil)
+	if len(targets) != 2 {
+		t.Fatalf("targets = %d, want vllm and kokoro only (stopped and native skipped)", len(targets))
+	}
+
+	ctx := context.Background()
+	byName := map[string]supervisor.Target{}
+	for _, tgt := range targets {
+		byName[tgt.Name] = tgt
+	}
+	if got := byName["vllm"].Probe(ctx); got.Health != supervisor.HealthHealthy {
+		t.Errorf("vllm probe = %+v, want healthy", got)
+	}
+	kokoro := byName["kokoro"]
+	if got := kokoro.Probe(ctx); got.Health != supervisor.HealthUnhealthy || got.Reason != "container exited with code 139" {
+		t.Errorf("kokoro probe = %+v, want the crash", got)
+	}
+	if kokoro.FailureThreshold != 5 {
+		t.Errorf("kokoro threshold = %d, want its health check retries", kokoro.FailureThreshold)
+	}
+	if err := kokoro.Restart(ctx); err != nil || len(restarted) != 1 || restarted[0] != "kokoro" {
+		t.Errorf("restart err=%v restarted=%v", err, restarted)
+	}
+}
+
+func TestProbeSupervisedServiceTellsOOMKillFromStop(t *testing.T) {
+	composePath := filepath.Join(t.TempDir(), "vllm.yml")
+	if err := os.WriteFile(composePath, []byte("services:\n  vllm:\n    image: example/vllm\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	origPS, origOOM := supervisorPS, supervisorOOMKilled
+	t.Cleanup(func() { supervisorPS, supervisorOOMKilled = origPS, origOOM })
+	supervisorPS = func(string) ([]byte, error) {
+		return []byte(`{"ID":"abc123","Service":"vllm","State":"exited","Status":"Exited (137) 1 minute ago"}`), nil
+	}
+
+	for _, oom := range []bool{false, true} {
+		var inspected []string
+		supervisorOOMKilled = func(id string) bool { inspected = append(inspected, id); return oom }
+		got := probeSupervisedService(composePath, catalog.HealthCheck{})
+		want := supervisor.HealthUnknown
+		if oom {
+			want = supervisor.HealthUnhealthy
+		}
+		if got.Health != want {
+			t.Errorf("OOMKilled=%v: probe = %+v, want %v", oom, got, want)
+		}
+		if len(inspected) != 1 || inspected[0] != "abc123" {
+			t.Errorf("OOMKilled=%v: inspected %v, want the exited container", oom, inspected)
+		}
+	}
+}
+
+func TestSupervisionStatesOmitsServicesThatNeverFailed(t *testing.T) {
+	if supervisionStates(nil) != nil {
+		t.Error("a disabled supervisor should not hand the collector a func")
+	}
+	sup := supervisor.New(supervisor.Config{Targets: func() []supervisor.Target { return nil }})
+	if got := supervisionStates(sup)(); len(got) != 0 {
+		t.Errorf("states = %+v, want none", got)
+	}
+}
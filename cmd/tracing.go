@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/aceteam-ai/citadel-cli/internal/tracing"
+)
+
+// tracingFlushTimeout bounds the span flush on shutdown so an unreachable
+// collector cannot hold up exit.
+const tracingFlushTimeout = 5 * time.Second
+
+// resolveTracingConfig builds the span-export config. The --otlp-endpoint flag
+// wins over the standard OTEL_EXPORTER_OTLP_* variables; with neither set the
+// endpoint is empty and tracing stays off.
+func resolveTracingConfig(flagEndpoint, nodeName string) tracing.Config {
+	cfg := tracing.ConfigFromEnv()
+	if flagEndpoint != "" {
+		cfg.Endpoint = flagEndpoint
+	}
+	cfg.ServiceVersion = Version
+	cfg.NodeName = nodeName
+	cfg.Logf = func(format string, args ...any) { Log(format, args...) }
+	return cfg
+}
+
+// startTracing turns on OTLP/HTTP span export for job and gateway traces
+// when an endpoint is configured, and returns the flush to run on shutdown.
+// A bad endpoint is a warning, not a fatal error: tracing is diagnostics.
+func startTracing(flagEndpoint, nodeName string) (flush func()) {
+	cfg := resolveTracingConfig(flagEndpoint, nodeName)
+	shutdown, err := tracing.Setup(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "   - Warning: %v; tracing disabled\n", err)
+		return func() {}
+	}
+	if tracing.Enabled() {
+		fmt.Printf("   - Tracing: OTLP/HTTP → %s\n", cfg.Endpoint)
+	}
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), tracingFlushTimeout)
+		defer cancel()
+		shutdown(ctx)
+	}
+}
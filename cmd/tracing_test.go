@@ -0,0 +1,19 @@
+package cmd
+
+import "testing"
+
+func TestResolveTracingConfigFlagWinsOverEnv(t *testing.T) {
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "http://env-collector:4318")
+
+	if cfg := resolveTracingConfig("", "gpu-1"); cfg.Endpoint != "http://env-collector:4318/v1/traces" || cfg.NodeName != "gpu-1" {
+		t.Errorf("env only: cfg = %+v", cfg)
+	}
+	if cfg := resolveTracingConfig("http://flag-collector:4318", "gpu-1"); cfg.Endpoint != "http://flag-collector:4318" {
+		t.Errorf("flag: endpoint = %q, want the flag's", cfg.Endpoint)
+	}
+
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "")
+	if cfg := resolveTracingConfig("", "gpu-1"); cfg.Endpoint != "" {
+		t.Errorf("unset: endpoint = %q, want tracing off", cfg.Endpoint)
+	}
+}
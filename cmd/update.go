@@ -28,7 +28,11 @@ Examples:
   citadel update status     # Show update status and versions
   citadel update rollback   # Restore the previous version
   citadel update enable     # Enable auto-update checks
-  citadel update disable    # Disable auto-update checks`,
+  citadel update disable    # Disable auto-update checks
+  citadel update signature require  # Refuse releases without a valid signature
+  citadel update ring early         # Join the "early" rollout ring
+  citadel update source /srv/citadel-releases  # Update from a mirror
+  citadel update mirror /srv/citadel-releases  # Populate a mirror from GitHub`,
 	Run: func(cmd *cobra.Command, args []string) {
 		// Default behavior: show status
 		showUpdateStatus()
@@ -97,6 +101,110 @@ agent within one check interval, without a restart.`,
 	},
 }
 
+var updateSignatureCmd = &cobra.Command{
+	Use:   "signature [off|warn|require]",
+	Short: "Show or set the release signature policy",
+	Long: `Shows or sets how updates treat release signatures.
+
+Every update verifies the archive checksum. Releases may also carry a
+detached minisign signature that is checked against a key built into this
+binary, so a tampered release page cannot pass.
+
+  off      skip signature checks
+  warn     install unsigned releases with a warning (default)
+  require  refuse releases without a valid signature
+
+A signature that does not verify is refused under warn and require. The
+CITADEL_UPDATE_SIGNATURE environment variable overrides the saved setting.`,
+	Args:      cobra.MaximumNArgs(1),
+	ValidArgs: []string{"off", "warn", "require"},
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) == 0 {
+			fmt.Printf("Signature policy: %s\n", update.ResolveSignaturePolicy())
+			return
+		}
+		setSignaturePolicy(args[0])
+	},
+}
+
+var updateRingCmd = &cobra.Command{
+	Use:   "ring [canary|early|broad|none]",
+	Short: "Show or set this node's rollout ring",
+	Long: `Shows or sets the rollout ring automatic updates follow.
+
+A node in a ring installs a new release only after it has soaked for the
+ring's delay, at a slot within the ring's window derived from the node ID:
+
+  canary  immediately
+  early   1 to 2 days after the release
+  broad   3 to 5 days after the release
+  none    immediately (default)
+
+Rings only gate automatic updates; 'citadel update install' is immediate.
+The CITADEL_UPDATE_RING environment variable overrides the saved setting.`,
+	Args:      cobra.MaximumNArgs(1),
+	ValidArgs: []string{"canary", "early", "broad", "none"},
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) == 0 {
+			fmt.Printf("Rollout ring: %s\n", updateRingLabel(update.ResolveRing()))
+			return
+		}
+		ring, err := update.ParseRing(args[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		saveUpdateSetting(func(s *update.State) { s.Ring = string(ring) }, "CITADEL_UPDATE_RING",
+			fmt.Sprintf("Rollout ring set to %s.", updateRingLabel(ring)))
+	},
+}
+
+var updateSourceCmd = &cobra.Command{
+	Use:   "source [url|directory|github]",
+	Short: "Show or set where updates are downloaded from",
+	Long: `Shows or sets the release source: GitHub Releases (default), an HTTP
+mirror, or a local directory laid out by 'citadel update mirror'.
+
+Mirrored releases pass the same checksum and signature checks as GitHub
+ones. The CITADEL_UPDATE_SOURCE environment variable overrides the saved
+setting.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) == 0 {
+			src, err := update.ResolveReleaseSource()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Printf("Release source: %s\n", updateSourceLabel(src))
+			return
+		}
+		src, err := update.NormalizeSource(args[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		saveUpdateSetting(func(s *update.State) { s.Source = src }, "CITADEL_UPDATE_SOURCE",
+			fmt.Sprintf("Release source set to %s.", updateSourceLabel(src)))
+	},
+}
+
+var updateMirrorTag string
+
+var updateMirrorCmd = &cobra.Command{
+	Use:   "mirror <directory>",
+	Short: "Copy a release from GitHub into a mirror directory",
+	Long: `Downloads every asset of a release (all platforms, checksums and
+signatures) into <directory>/<tag>/ and writes the release JSON, so the
+directory can be served over HTTP or copied to an air-gapped site and used
+with 'citadel update source'. Without --tag the latest release is mirrored
+and becomes the mirror's latest.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		mirrorRelease(args[0])
+	},
+}
+
 func init() {
 	rootCmd.AddCommand(updateCmd)
 	updateCmd.AddCommand(updateCheckCmd)
@@ -105,6 +213,11 @@ func init() {
 	updateCmd.AddCommand(updateStatusCmd)
 	updateCmd.AddCommand(updateEnableCmd)
 	updateCmd.AddCommand(updateDisableCmd)
+	updateCmd.AddCommand(updateSignatureCmd)
+	updateCmd.AddCommand(updateRingCmd)
+	updateCmd.AddCommand(updateSourceCmd)
+	updateMirrorCmd.Flags().StringVar(&updateMirrorTag, "tag", "", "Release tag to mirror (default: latest)")
+	updateCmd.AddCommand(updateMirrorCmd)
 }
 
 func checkForUpdate() {
@@ -178,6 +291,9 @@ func installUpdate() {
 	// Update state
 	state, _ := update.LoadState()
 	update.RecordUpdate(state, Version, release.TagName)
+	// A manual install is the operator choosing this release, even one that
+	// previously failed verification and was rolled back.
+	update.ForgetFailedVersion(state, release.TagName)
 	update.UpdateLastCheck(state)
 	_ = update.SaveState(state)
 
@@ -227,6 +343,8 @@ func rollbackUpdate() {
 		state.CurrentVersion, state.PreviousVersion = state.PreviousVersion, state.CurrentVersion
 		_ = update.SaveState(state)
 	}
+	// A manual rollback settles any pending post-update verification.
+	_ = update.ClearPendingVerification()
 
 	fmt.Println("\nRollback complete.")
 	fmt.Println("Run 'citadel version' to verify.")
@@ -261,6 +379,27 @@ func showUpdateStatus() {
 
 	fmt.Printf("Auto-update:      %v\n", state.AutoUpdate)
 	fmt.Printf("Channel:          %s\n", state.Channel)
+	fmt.Printf("Signatures:       %s\n", update.ResolveSignaturePolicy())
+	fmt.Printf("Rollout ring:     %s\n", updateRingLabel(update.ResolveRing()))
+	if src, err := update.ResolveReleaseSource(); err != nil {
+		fmt.Printf("Release source:   invalid (%v)\n", err)
+	} else {
+		fmt.Printf("Release source:   %s\n", updateSourceLabel(src))
+	}
+
+	if p, err := update.LoadPendingVerification(); err == nil && p != nil {
+		if d := p.Deadline(); !d.IsZero() {
+			fmt.Printf("Verifying:        %s (must pass health checks by %s)\n", p.ToVersion, d.Format(time.RFC3339))
+		} else {
+			fmt.Printf("Verifying:        %s (pending restart)\n", p.ToVersion)
+		}
+	}
+	if len(state.FailedUpdates) > 0 {
+		fmt.Println("Failed updates (skipped by auto-update):")
+		for _, f := range state.FailedUpdates {
+			fmt.Printf("  %s  %s  %s\n", f.Version, f.At.Format(time.RFC3339), f.Reason)
+		}
+	}
 
 	// Check for available update
 	fmt.Println("\nChecking for updates...")
@@ -320,3 +459,72 @@ func CheckForUpdateInBackground() {
 	// Notify user (don't auto-install)
 	fmt.Printf("   - Update available: %s (run 'citadel update install')\n", release.TagName)
 }
+
+func setSignaturePolicy(raw string) {
+	policy, err := update.ParseSignaturePolicy(raw)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	saveUpdateSetting(func(s *update.State) { s.SignaturePolicy = string(policy) }, "CITADEL_UPDATE_SIGNATURE",
+		fmt.Sprintf("Signature policy set to %s.", policy))
+}
+
+// saveUpdateSetting applies set to the persisted update state and prints
+// done, noting when envVar overrides the saved value in this environment.
+func saveUpdateSetting(set func(*update.State), envVar, done string) {
+	state, err := update.LoadState()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading update state: %v\n", err)
+		os.Exit(1)
+	}
+	set(state)
+	if err := update.SaveState(state); err != nil {
+		fmt.Fprintf(os.Stderr, "Error saving update state: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println(done)
+	if env := os.Getenv(envVar); env != "" {
+		fmt.Printf("Note: %s=%s overrides it in this environment.\n", envVar, env)
+	}
+}
+
+func updateRingLabel(r update.Ring) string {
+	if r == update.RingNone {
+		return "none"
+	}
+	return string(r)
+}
+
+func updateSourceLabel(src string) string {
+	if src == "" {
+		return "GitHub Releases"
+	}
+	return src
+}
+
+func mirrorRelease(dir string) {
+	client := update.NewClient(Version).WithSource(update.SourceGitHub)
+	var (
+		release *update.Release
+		err     error
+	)
+	if updateMirrorTag != "" {
+		release, err = client.GetReleaseByTag(updateMirrorTag)
+	} else {
+		release, err = client.GetLatestRelease()
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error fetching release: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Mirroring %s into %s\n", release.TagName, dir)
+	err = client.MirrorRelease(release, dir, updateMirrorTag == "", func(format string, args ...any) {
+		fmt.Printf("   - "+format+"\n", args...)
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("\nMirrored %s. Point nodes at it with:\n  citadel update source %s\n", release.TagName, dir)
+}
@@ -0,0 +1,89 @@
+// cmd/update_health.go
+//
+// The health bar a freshly self-updated `citadel work` must reach before its
+// update is considered verified (see internal/update/verify.go). Each check is
+// only included when it applies to how this worker was started, so a node that
+// never joined the mesh or runs without a status server is not failed for it.
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/aceteam-ai/citadel-cli/internal/network"
+	"github.com/aceteam-ai/citadel-cli/internal/status"
+	"github.com/aceteam-ai/citadel-cli/internal/update"
+	"github.com/aceteam-ai/citadel-cli/internal/worker"
+)
+
+// updateHealthChecks builds the verification health bar: network up (when the
+// node had mesh state at startup), job source connected, status server serving
+// (when enabled) and a first successful poll.
+func updateHealthChecks(state *worker.WorkerState, expectNetwork bool, statusPort int) []update.HealthCheck {
+	var checks []update.HealthCheck
+	if expectNetwork {
+		checks = append(checks, update.HealthCheck{Name: "network", Check: func() error {
+			if !network.IsGlobalConnected() {
+				return errors.New("not connected to the AceTeam Network")
+			}
+			return nil
+		}})
+	}
+	checks = append(checks,
+		update.HealthCheck{Name: "job source", Check: func() error {
+			snap := state.Snapshot()
+			if snap.Source == "" || len(snap.Queues) == 0 {
+				return errors.New("not connected")
+			}
+			return nil
+		}},
+		update.HealthCheck{Name: "first poll", Check: func() error {
+			snap := state.Snapshot()
+			if snap.LastPollAt == nil {
+				return errors.New("no poll completed yet")
+			}
+			if snap.LastConsumeError != "" {
+				return fmt.Errorf("last poll failed: %s", snap.LastConsumeError)
+			}
+			return nil
+		}},
+	)
+	if statusPort > 0 {
+		url := fmt.Sprintf("http://127.0.0.1:%d/ping", statusPort)
+		client := &http.Client{Timeout: 3 * time.Second}
+		checks = append(checks, update.HealthCheck{Name: "status server", Check: func() error {
+			resp, err := client.Get(url)
+			if err != nil {
+				return err
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				return fmt.Errorf("%s returned %d", url, resp.StatusCode)
+			}
+			return nil
+		}})
+	}
+	return checks
+}
+
+// lastFailedUpdate reports the last rolled-back self-update for the heartbeat,
+// read fresh each time so a rollback made by this process shows up without a
+// restart.
+func lastFailedUpdate() *status.FailedUpdate {
+	state, err := update.LoadState()
+	if err != nil {
+		return nil
+	}
+	f := update.LatestFailedUpdate(state)
+	if f == nil {
+		return nil
+	}
+	out := &status.FailedUpdate{Version: f.Version, Reason: f.Reason}
+	if !f.At.IsZero() {
+		at := f.At
+		out.At = &at
+	}
+	return out
+}
@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aceteam-ai/citadel-cli/internal/update"
+	"github.com/aceteam-ai/citadel-cli/internal/worker"
+)
+
+func TestUpdateHealthChecks(t *testing.T) {
+	state := worker.NewWorkerState()
+	checks := updateHealthChecks(state, false, 0)
+	if len(checks) != 2 {
+		t.Fatalf("got %d checks without network/status server, want 2", len(checks))
+	}
+	for _, c := range checks {
+		if c.Check() == nil {
+			t.Errorf("%s passed before the worker connected", c.Name)
+		}
+	}
+
+	state.SetIdentity("w1", "api", "", "", "")
+	state.SetQueues([]string{"jobs:v1:org"})
+	state.RecordPoll()
+	state.RecordConsumeStatus(200, "")
+	for _, c := range checks {
+		if err := c.Check(); err != nil {
+			t.Errorf("%s: %v", c.Name, err)
+		}
+	}
+
+	state.RecordConsumeStatus(503, "unavailable")
+	if checks[1].Check() == nil {
+		t.Error("first poll passed with a consume error")
+	}
+
+	if got := len(updateHealthChecks(state, true, 8080)); got != 4 {
+		t.Errorf("got %d checks with network and status server, want 4", got)
+	}
+}
+
+func TestLastFailedUpdate(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	if got := lastFailedUpdate(); got != nil {
+		t.Fatalf("no failures recorded, got %+v", got)
+	}
+
+	state, err := update.LoadState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	update.RecordFailedUpdate(state, "v1.1.0", "network: not connected", time.Now())
+	update.RecordFailedUpdate(state, "v1.2.0", "first poll: no poll completed yet", time.Now())
+	if err := update.SaveState(state); err != nil {
+		t.Fatal(err)
+	}
+	got := lastFailedUpdate()
+	if got == nil || got.Version != "v1.2.0" || got.Reason != "first poll: no poll completed yet" || got.At == nil {
+		t.Errorf("lastFailedUpdate = %+v, want v1.2.0 with its reason and time", got)
+	}
+}
@@ -41,6 +41,7 @@ import (
 	"github.com/aceteam-ai/citadel-cli/internal/service"
 	internalServices "github.com/aceteam-ai/citadel-cli/internal/services"
 	"github.com/aceteam-ai/citadel-cli/internal/status"
+	"github.com/aceteam-ai/citadel-cli/internal/storage"
 	"github.com/aceteam-ai/citadel-cli/internal/terminal"
 	"github.com/aceteam-ai/citadel-cli/internal/tlscert"
 	"github.com/aceteam-ai/citadel-cli/internal/tmux"
@@ -97,6 +98,9 @@ var (
 	// Footprint sampler flag
 	workNoFootprint bool
 
+	// OTLP/HTTP trace collector for job traces (off when empty)
+	workOTLPEndpoint string
+
 	// Single-instance guard flag (issues #443 / #435): when true, skip the
 	// per-node worker lock so a second worker may run intentionally (e.g. a debug
 	// direct-Redis worker alongside the API-mode one in development).
@@ -388,6 +392,33 @@ func runWork(cmd *cobra.Command, args []string) {
 		}
 	}
 
+	// Post-update verification: if this start is a freshly self-updated binary,
+	// count the boot and decide whether it is still within its window to reach
+	// the health bar (watched once the runner exists, below) or has already
+	// failed (crash loop / window elapsed across restarts) and must roll back
+	// before doing anything else.
+	pendingUpdate, verifyAction, verifyErr := update.BeginVerification(Version, time.Now())
+	if verifyErr != nil {
+		Log("update verification: %v", verifyErr)
+	}
+	if verifyAction == update.VerifyRollback {
+		reason := fmt.Sprintf("health bar not reached by %s", pendingUpdate.Deadline().Local().Format(time.RFC3339))
+		if pendingUpdate.Boots > update.MaxVerifyBoots {
+			reason = fmt.Sprintf("restarted %d times without reaching the health bar", pendingUpdate.Boots-1)
+		}
+		update.RollBackFailedUpdate(pendingUpdate, reason, nil, func(format string, args ...any) {
+			fmt.Printf("   - "+format+"\n", args...)
+		})
+		if err := update.RestartProcess(); err != nil {
+			fmt.Fprintf(os.Stderr, "   - Warning: restart onto %s failed: %v; continuing on %s\n",
+				pendingUpdate.FromVersion, err, Version)
+		}
+		verifyAction = update.VerifyNone
+	}
+	// Whether the node had mesh state when it started decides if "network up"
+	// is part of the health bar.
+	hadNetworkState := network.HasState()
+
 	// Managed services this worker started (populated by the async startup
 	// goroutine below, read by the shutdown hook). Guarded by a mutex because
 	// the signal handler may fire while the startup goroutine is still filling
@@ -1037,6 +1068,34 @@ func runWork(cmd *cobra.Command, args []string) {
 	// per-service. Disabled by --no-footprint or CITADEL_FOOTPRINT_INTERVAL<=0.
 	startFootprintSampler(ctx, nodeName, workManifest)
 
+	// Publish node events (jobs, swaps, service health, updates) with this
+	// node's name, and deliver them to the HMAC-signed webhooks configured in
+	// webhooks.yaml. Local scripts can also follow them on /agent/events.
+	startEventWebhooks(ctx, nodeName)
+
+	// Supervise managed services and modules: restart one whose container
+	// crashed or whose health check fails, with exponential backoff, and mark
+	// it crash-looping (pushing a notification to the org) once restarts stop
+	// helping. Restart counts and the last failure ride the heartbeat.
+	// Disabled by CITADEL_SUPERVISE_INTERVAL<=0.
+	svcSupervisor := startServiceSupervisor(ctx, nodeName)
+
+	// Export job traces when an OTLP collector is configured (off by default).
+	// Each job's spans -- fetch, claim, swap, readiness, execute, publish -- are
+	// parented on its RayID, and the traceparent rides on into the gateway and
+	// the engine HTTP calls, so a slow fabric request can be attributed to
+	// queueing, hotswap, warm-up or generation.
+	defer startTracing(workOTLPEndpoint, nodeName)()
+
+	// Start the Frigate event watcher when the nvr module is installed: it
+	// indexes detections from the module's broker (queryable via `citadel nvr
+	// events`) and pushes rule matches to the org with a snapshot and clip link.
+	startNVRWatcher(ctx)
+
+	// Keep object-storage bucket policies in step with issued keys and
+	// quotas (`citadel storage keys|quota`). Idle unless keys or quotas exist.
+	go storage.RunQuotaEnforcer(ctx, storage.DefaultQuotaInterval, func(format string, args ...any) { Log(format, args...) })
+
 	// Start the background node-key renewer (epic #4583). While the node is
 	// healthy and online, it refreshes the Headscale node key before it expires,
 	// re-authorizing in place with the node's own device token — so a long-lived
@@ -1255,6 +1314,8 @@ func runWork(cmd *cobra.Command, args []string) {
 			WorkerLiveness: workerLivenessFn,
 			PinnedServices: manifestPinnedServices(workManifest),
 			ModelHotswap:   status.ModelHotswapEnabled(),
+			Supervision:    supervisionStates(svcSupervisor),
+			FailedUpdate:   lastFailedUpdate,
 		})
 	}
 
@@ -1263,6 +1324,15 @@ func runWork(cmd *cobra.Command, args []string) {
 	// status server starts (its AddRouteRegistrar closure references it).
 	wsDir := resolveWorkspaceDir()
 
+	// GPU slot tracker, shared by the job runner and provisioning admission
+	// control so a GPU-pinned container and a GPU job never land on the same
+	// device. Created before the status server, which hosts provisioning.
+	var gpuTracker *worker.GPUTracker
+	gpuCount := platform.GetGPUCountSimple()
+	if gpuCount > 0 {
+		gpuTracker = worker.NewGPUTracker(gpuCount)
+	}
+
 	// Workflow executor for WORKFLOW_RUN jobs (#105). Created here so the status
 	// server's AddRouteRegistrar closure and the job handler share a single instance.
 	wfExec := workflow.NewExecutor(workflow.ExecutorConfig{
@@ -1409,9 +1479,12 @@ func runWork(cmd *cobra.Command, args []string) {
 
 		statusServer := status.NewServer(serverCfg, collector)
 
-		// Register provisioning API routes on the status server
-		if provisionHandler := initProvisionHandler(); provisionHandler != nil {
-			statusServer.AddRouteRegistrar(provisionHandler.RegisterRoutes)
+		// Register provisioning API routes on the status server, and report
+		// their reservations on /resources.
+		if provisionMgr := initProvisionManager(gpuTracker); provisionMgr != nil {
+			statusServer.AddRouteRegistrar(provision.NewHandler(provisionMgr).RegisterRoutes)
+			statusServer.SetReservationsProvider(func() any { return provisionMgr.Reservations() })
+			go runProvisionQueue(ctx, provisionMgr)
 		}
 
 		// Register workflow API routes, gated by requireVPNOrAuth (#259).
@@ -1543,6 +1616,8 @@ func runWork(cmd *cobra.Command, args []string) {
 				WorkerLiveness: workerLivenessFn,
 				PinnedServices: manifestPinnedServices(workManifest),
 				ModelHotswap:   status.ModelHotswapEnabled(),
+				Supervision:    supervisionStates(svcSupervisor),
+				FailedUpdate:   lastFailedUpdate,
 			})
 		}
 
@@ -1993,15 +2068,22 @@ func runWork(cmd *cobra.Command, args []string) {
 		gw.AddUpstream("/provision", &gateway.Upstream{Address: statusAddr})
 		gw.AddUpstream("/workflow", &gateway.Upstream{Address: statusAddr})
 
-		// Embedding upstream (issue #351): route the already-metered
-		// /v1/embeddings path to the local TEI service (default 127.0.0.1:8102).
-		gw.AddUpstream("/v1/embeddings", &gateway.Upstream{Address: embeddingAddr})
+		// Embeddings and rerank: route /v1/embeddings and /v1/rerank by model to the
+		// local TEI embedder (default 127.0.0.1:8102) or reranker (CITADEL_RERANK_URL),
+		// falling back to a chat engine serving the embedding model, and to the TEI
+		// port itself while no embedder has been probed (issue #351's static route).
+		gw.SetEmbeddingRouter(newLocalEmbeddingLister(embeddingAddr, rerankAddr()))
+		gw.SetEmbeddingFallback(embeddingAddr)
 
 		// Chat routing (issue #581): expose /v1/chat/completions (+ /v1/completions
 		// and /v1/models) with model->engine resolution so mesh-direct chat to this
 		// node reaches whichever local engine serves the requested model.
 		gw.SetChatRouter(newLocalChatLister())
 
+		// OpenAI audio: /v1/audio/transcriptions and /v1/audio/speech on the whisper
+		// and kokoro sidecars that back TRANSCRIBE_AUDIO and SYNTHESIZE_SPEECH.
+		gw.SetAudioRouter(jobs.NewTranscribeAudioHandler(resolveWorkspaceDir()), jobs.NewSynthesizeSpeechHandler())
+
 		gw.AddUpstream("/vnc", &gateway.Upstream{
 			Address:     vncAddr,
 			StripPrefix: true,
@@ -2082,7 +2164,8 @@ func runWork(cmd *cobra.Command, args []string) {
 		fmt.Printf("     /api/screenshot, /api/actions -> %s\n", statusAddr)
 		fmt.Printf("     /ssh/authorized-keys     -> %s (SSH key deploy)\n", statusAddr)
 		fmt.Printf("     /workflow/...             -> %s (workflow API)\n", statusAddr)
-		fmt.Printf("     /v1/embeddings           -> %s (TEI embeddings)\n", embeddingAddr)
+		fmt.Printf("     /v1/embeddings           -> %s or local engine by model\n", embeddingAddr)
+		fmt.Printf("     /v1/rerank               -> TEI reranker by model\n")
 		fmt.Printf("     /v1/chat/completions     -> local engine by model (#581)\n")
 		fmt.Printf("     /vnc/...                 -> %s (websockify)\n", vncAddr)
 		fmt.Printf("     /terminal/...            -> %s (terminal)\n", termAddr)
@@ -2142,6 +2225,8 @@ func runWork(cmd *cobra.Command, args []string) {
 		WorkflowExec:              wfExec,
 		HandlerLog:                func(format string, args ...any) { Log(format, args...) },
 		PinnedServices:            manifestPinnedServices(workManifest),
+		Ctx:                       ctx,
+		Source:                    source,
 	}
 	handlers := buildNodeJobHandlers(nodeJobOpts)
 
@@ -2158,13 +2243,8 @@ func runWork(cmd *cobra.Command, args []string) {
 
 	// Resolve concurrency from flag or auto-detect from GPU count
 	maxConcurrency := workMaxConcurrency
-	var gpuTracker *worker.GPUTracker
-	gpuCount := platform.GetGPUCountSimple()
-	if gpuCount > 0 {
-		gpuTracker = worker.NewGPUTracker(gpuCount)
-		if maxConcurrency == 0 {
-			maxConcurrency = gpuCount
-		}
+	if gpuCount > 0 && maxConcurrency == 0 {
+		maxConcurrency = gpuCount
 	}
 	if maxConcurrency == 0 {
 		maxConcurrency = 1 // Default: sequential
@@ -2178,6 +2258,10 @@ func runWork(cmd *cobra.Command, args []string) {
 	// (issue #3924/#236); #234 already routed the source's LogFn the same way.
 	// State threads the shared introspection metrics so the /agent/* endpoints
 	// can report consume/job activity.
+	energySchedule, jobWindowFn := resolveEnergySchedule(nodeName, func(format string, args ...any) { Log(format, args...) })
+	if energySchedule != nil {
+		fmt.Printf("   - Energy schedule: %s; deferrable jobs wait for cheap windows\n", energySchedule.Metric)
+	}
 	runner := worker.NewRunner(source, handlers, worker.RunnerConfig{
 		WorkerID:       workerID,
 		NodeID:         headscaleNodeID,
@@ -2188,6 +2272,10 @@ func runWork(cmd *cobra.Command, args []string) {
 		MaxConcurrency: maxConcurrency,
 		GPUTracker:     gpuTracker,
 		State:          workerState,
+		EnergySchedule: energySchedule,
+		JobWindowFn:    jobWindowFn,
+		// Held deferrable jobs wait here, off the queue (energy_defer.go).
+		DeferSpoolPath: filepath.Join(platform.ConfigDir(), "deferred-jobs.json"),
 	})
 
 	// Add stream writer factory if available
@@ -2213,8 +2301,16 @@ func runWork(cmd *cobra.Command, args []string) {
 	if interval, err := update.ParseInterval(resolveAutoUpdateInterval()); err != nil {
 		fmt.Fprintf(os.Stderr, "   - Warning: %v; auto-update disabled\n", err)
 	} else {
+		// The rollout ring slot is keyed to the mesh identity so it survives
+		// hostname changes; the node name covers a node not yet on the mesh.
+		rolloutNodeID := headscaleNodeID
+		if rolloutNodeID == "" {
+			rolloutNodeID = nodeName
+		}
 		updater := update.NewAutoUpdater(update.AutoUpdaterConfig{
-			Checker:    update.NewClientWithTimeout(Version, 30*time.Second),
+			Checker: update.NewClientWithTimeout(Version, 30*time.Second).WithWarnf(func(format string, args ...any) {
+				fmt.Printf("   - auto-update: warning: "+format+"\n", args...)
+			}),
 			Interval:   interval,
 			Enabled:    resolveAutoUpdateEnabled,
 			ActiveJobs: runner.ActiveJobs,
@@ -2222,10 +2318,26 @@ func runWork(cmd *cobra.Command, args []string) {
 			Log: func(format string, args ...any) {
 				fmt.Printf("   - "+format+"\n", args...)
 			},
+			CurrentVersion: Version,
+			NodeID:         rolloutNodeID,
 		})
 		go updater.Run(ctx)
 	}
 
+	// Watch the health bar for a freshly-updated binary (see BeginVerification
+	// above). Fails back to the previous binary if it is not reached in time.
+	if verifyAction == update.VerifyWatch {
+		go update.RunVerifier(ctx, update.VerifierConfig{
+			Pending:    pendingUpdate,
+			Checks:     updateHealthChecks(workerState, hadNetworkState, workStatusPort),
+			ActiveJobs: runner.ActiveJobs,
+			Drain:      func() { runner.Drain() },
+			Log: func(format string, args ...any) {
+				fmt.Printf("   - "+format+"\n", args...)
+			},
+		})
+	}
+
 	// Start the self-heal liveness monitor (issue #548). It is the backstop for a
 	// consumption-wedged worker that the per-job watchdog can't catch (a wedge
 	// outside a handler, or a build with the watchdog disabled): it watches the
@@ -2402,6 +2514,57 @@ func resolveEnergySampling() bool {
 	return config.LoadEnergy(platform.ConfigDir()).SamplingEnabled
 }
 
+// resolveEnergySchedule loads the node's energy-schedule.yaml, which makes the
+// worker hold deferrable jobs for cheap tariff or low-carbon windows, and
+// returns the JobWindowFn that records each finished job's window in the
+// footprint job log (footprints/jobs/). Both are nil without a schedule; a
+// malformed schedule is logged and ignored rather than holding jobs through
+// the wrong hours.
+func resolveEnergySchedule(nodeName string, logf func(format string, args ...any)) (*config.EnergySchedule, func(worker.JobWindow)) {
+	sched, err := config.LoadEnergySchedule(platform.ConfigDir())
+	if err != nil {
+		logf("energy schedule ignored: %v", err)
+		return nil, nil
+	}
+	if sched == nil {
+		return nil, nil
+	}
+	nodeDir, err := platform.DefaultNodeDir("")
+	if err != nil {
+		logf("footprint job log disabled (no node dir): %v", err)
+		return sched, nil
+	}
+	jobLog, err := footprint.NewJobLog(footprint.JobLogDir(footprint.DefaultDir(nodeDir)))
+	if err != nil {
+		logf("footprint job log disabled: %v", err)
+		return sched, nil
+	}
+	retentionDays := footprint.RetentionFromEnv()
+	return sched, func(jw worker.JobWindow) {
+		err := jobLog.Append(footprint.JobWindow{
+			StartedAt:    jw.Record.StartedAt,
+			CompletedAt:  jw.Record.CompletedAt,
+			NodeID:       nodeName,
+			JobID:        jw.Record.JobID,
+			JobType:      jw.Record.JobType,
+			Status:       jw.Record.Status,
+			Deferred:     jw.Deferred,
+			Held:         jw.Held,
+			Window:       jw.Run.Label(),
+			Metric:       sched.Metric,
+			Unit:         sched.Unit,
+			ArrivalValue: jw.Arrival.Value,
+			RunValue:     jw.Run.Value,
+		})
+		if err != nil {
+			logf("footprint job log: %v", err)
+		}
+		if _, err := jobLog.Prune(time.Now(), retentionDays); err != nil {
+			logf("footprint job log prune: %v", err)
+		}
+	}
+}
+
 // sensitiveCapabilityPasscodeWarning returns a single warning line when a
 // passcode-gated remote-access surface (console/desktop/files/shell,
 // aceteam#6524) is enabled but no node passcode is configured, or "" when
@@ -2986,6 +3149,7 @@ func init() {
 	// Update check flags (deprecated - update check now runs on all commands via root.go)
 	workCmd.Flags().BoolVar(&workNoUpdate, "no-update", false, "(Deprecated) No longer has any effect - use 'citadel update disable' instead")
 	workCmd.Flags().BoolVar(&workNoFootprint, "no-footprint", false, "Disable the background resource-footprint sampler")
+	workCmd.Flags().StringVar(&workOTLPEndpoint, "otlp-endpoint", "", "OTLP/HTTP collector URL for job traces, e.g. http://collector:4318 (default: off, or set OTEL_EXPORTER_OTLP_ENDPOINT)")
 	workCmd.Flags().BoolVar(&workNoSingleInstance, "no-single-instance", false, "Allow a second worker to run for this node (skips the single-instance lock)")
 	workCmd.Flags().BoolVar(&workAttach, "attach", false, "When a worker is already running, print its full status banner and exit 0 (default: banner on an interactive terminal, a concise no-op notice otherwise)")
 	workCmd.Flags().BoolVar(&workNoAttach, "no-attach", false, "When a worker is already running, refuse with exit 1 instead of the exit-0 no-op notice/banner")
@@ -3023,30 +3187,63 @@ func init() {
 	workCmd.Flags().MarkHidden("gateway-no-tls")
 }
 
-// initProvisionHandler creates a provision.Handler backed by the Docker
-// backend with state persisted in the Citadel config directory.
-// Returns nil if initialization fails (provisioning API will be unavailable).
-func initProvisionHandler() *provision.Handler {
+// initProvisionManager creates a provision.Manager backed by every backend
+// this node supports (Docker, LXC, Proxmox VMs) with state persisted in the
+// Citadel config directory and admission control sharing the runner's GPU
+// tracker. Returns nil if initialization fails (provisioning API will be
+// unavailable).
+func initProvisionManager(gpuTracker *worker.GPUTracker) *provision.Manager {
 	configDir := platform.ConfigDir()
 	store, err := provision.NewStore(configDir)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "   - provision store init failed: %v\n", err)
 		return nil
 	}
-	docker, err := provision.NewDockerBackend()
+	backends, err := provisionBackends(configDir)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "   - provision docker backend init failed: %v\n", err)
+		fmt.Fprintf(os.Stderr, "   - provision backend init failed: %v\n", err)
 		return nil
 	}
-	backends := map[provision.ResourceType]provision.Backend{
-		provision.ResourceTypeDocker: docker,
+	policy, err := provision.LoadPolicy(configDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "   - provision policy load failed: %v\n", err)
+		return nil
 	}
 	mgr := provision.NewManager(store, backends)
-	// Reconcile persisted resources against actual Docker state on startup.
-	// This detects containers that crashed or were removed while the daemon
-	// was down and updates their status accordingly.
+	mgr.EnableAdmission(provision.AdmissionConfig{
+		Policy: policy,
+		GPUs:   provisionGPUs(gpuTracker),
+	})
+	// Reconcile persisted resources against actual backend state on startup.
+	// This detects containers and VMs that crashed or were removed while the
+	// daemon was down and updates their status accordingly, then start the
+	// queued resources that now fit. Only the daemon admits: its GPU tracker
+	// is the one GPU jobs share.
 	mgr.ReconcileAll(context.Background())
-	return provision.NewHandler(mgr)
+	mgr.AdmitQueued(context.Background())
+	return mgr
+}
+
+// provisionQueueInterval is how often the daemon retries queued provisioning
+// specs. Capacity also frees up outside the manager's view (GPU jobs finish,
+// engines unload), so destroy-triggered admission alone is not enough.
+const provisionQueueInterval = 30 * time.Second
+
+// runProvisionQueue periodically starts queued resources that now fit,
+// until ctx is cancelled.
+func runProvisionQueue(ctx context.Context, mgr *provision.Manager) {
+	ticker := time.NewTicker(provisionQueueInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if n := mgr.AdmitQueued(ctx); n > 0 {
+				Log("provision: started %d queued resource(s)", n)
+			}
+		}
+	}
 }
 
 // splitAndTrim splits a comma-separated env value into non-empty, trimmed items.
@@ -34,8 +34,9 @@ type Capability struct {
 type GPUDevice struct {
 	Name    string `json:"name" yaml:"name"`
 	VRAMMb  int    `json:"vram_mb" yaml:"vram_mb"`
-	Tag     string `json:"tag" yaml:"tag"`           // normalized tag e.g. "rtx3090"
-	VRAMTag string `json:"vram_tag" yaml:"vram_tag"` // e.g. "24gb"
+	Tag     string `json:"tag" yaml:"tag"`                           // normalized tag e.g. "rtx3090"
+	VRAMTag string `json:"vram_tag" yaml:"vram_tag"`                 // e.g. "24gb"
+	Vendor  string `json:"vendor,omitempty" yaml:"vendor,omitempty"` // "nvidia" or "amd"
 }
 
 // GPUCapabilities holds the full GPU capability summary for a node.
@@ -53,17 +54,20 @@ type NodeCapabilities struct {
 	Tags    []string         `json:"tags,omitempty" yaml:"tags,omitempty"`       // all capability tags
 }
 
-// DetectGPUCapabilities runs nvidia-smi and returns structured GPU information.
-// When nvidia-smi fails but lspci detects NVIDIA hardware, a GPUCapabilities
-// is still returned with the hardware name but empty Tag/VRAMTag fields so
-// the GPU is visible in status displays without producing routing tags.
-// Returns nil only if no NVIDIA hardware is detected at all.
+// DetectGPUCapabilities runs nvidia-smi and returns structured GPU information,
+// falling back to rocm-smi on AMD nodes. When both fail but lspci detects
+// NVIDIA hardware, a GPUCapabilities is still returned with the hardware name
+// but empty Tag/VRAMTag fields so the GPU is visible in status displays without
+// producing routing tags. Returns nil only if no GPU is detected at all.
 func DetectGPUCapabilities() *GPUCapabilities {
 	ctx, cancel := context.WithTimeout(context.Background(), detectionTimeout)
 	defer cancel()
 	cmd := exec.CommandContext(ctx, "nvidia-smi", "--query-gpu=name,memory.total,count", "--format=csv,noheader,nounits")
 	output, err := cmd.Output()
 	if err != nil {
+		if gpus, rerr := platform.QueryROCmGPUs(ctx); rerr == nil {
+			return rocmGPUCapabilities(gpus)
+		}
 		// nvidia-smi failed — check if hardware is physically present via lspci
 		hwName := platform.DetectNvidiaHardware()
 		if hwName == "" {
@@ -122,6 +126,7 @@ func DetectGPUCapabilities() *GPUCapabilities {
 			VRAMMb:  vramMB,
 			Tag:     gpuTag,
 			VRAMTag: vramTag,
+			Vendor:  platform.GPUVendorNVIDIA,
 		})
 	}
 
@@ -140,6 +145,26 @@ func DetectGPUCapabilities() *GPUCapabilities {
 	}
 }
 
+// rocmGPUCapabilities builds the capability summary for AMD GPUs read through
+// rocm-smi, with the same tag normalization as the NVIDIA path so an MI210
+// advertises gpu:mi210 and vram:64gb.
+func rocmGPUCapabilities(gpus []platform.ROCmGPU) *GPUCapabilities {
+	caps := &GPUCapabilities{Count: len(gpus), DriverStatus: "ok"}
+	for _, g := range gpus {
+		dev := GPUDevice{
+			Name:   g.Name,
+			VRAMMb: g.VRAMTotalMB,
+			Tag:    NormalizeGPUName(g.Name),
+			Vendor: platform.GPUVendorAMD,
+		}
+		if gb := NormalizeVRAM(strconv.Itoa(g.VRAMTotalMB)); gb != "" {
+			dev.VRAMTag = gb + "gb"
+		}
+		caps.Devices = append(caps.Devices, dev)
+	}
+	return caps
+}
+
 // DetectNodeCapabilities returns the full node capabilities including GPU and running engines.
 func DetectNodeCapabilities() *NodeCapabilities {
 	caps := &NodeCapabilities{}
@@ -424,7 +449,15 @@ func detectGPU() []Capability {
 	cmd := exec.CommandContext(ctx, "nvidia-smi", "--query-gpu=name,memory.total", "--format=csv,noheader,nounits")
 	output, err := cmd.Output()
 	if err != nil {
-		return nil
+		gpus, rerr := platform.QueryROCmGPUs(ctx)
+		if rerr != nil {
+			return nil
+		}
+		var caps []Capability
+		for idx, g := range gpus {
+			caps = append(caps, gpuTagCapabilities(idx, g.Name, strconv.Itoa(g.VRAMTotalMB))...)
+		}
+		return caps
 	}
 	var caps []Capability
 	for idx, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
@@ -436,29 +469,35 @@ func detectGPU() []Capability {
 		if len(parts) < 2 {
 			continue
 		}
-		gpuName := strings.TrimSpace(parts[0])
-		memoryMB := strings.TrimSpace(parts[1])
-		gpuTag := NormalizeGPUName(gpuName)
-		vramGB := NormalizeVRAM(memoryMB)
+		caps = append(caps, gpuTagCapabilities(idx, strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1]))...)
+	}
+	return caps
+}
 
-		// Aggregate tags (e.g., gpu:rtx4090, vram:24gb)
-		if gpuTag != "" {
-			caps = append(caps, Capability{Tag: fmt.Sprintf("gpu:%s", gpuTag), Category: "gpu", Description: gpuName})
-		}
-		if vramGB != "" {
-			caps = append(caps, Capability{Tag: fmt.Sprintf("vram:%sgb", vramGB), Category: "vram", Description: fmt.Sprintf("%s MB VRAM", memoryMB)})
-		}
-
-		// Indexed tags for per-GPU targeting (e.g., gpu:0:rtx4090:24gb)
-		if gpuTag != "" && vramGB != "" {
-			indexedTag := fmt.Sprintf("gpu:%d:%s:%sgb", idx, gpuTag, vramGB)
-			if ValidateTag(indexedTag) {
-				caps = append(caps, Capability{
-					Tag:         indexedTag,
-					Category:    "gpu",
-					Description: fmt.Sprintf("GPU %d: %s (%s MB VRAM)", idx, gpuName, memoryMB),
-				})
-			}
+// gpuTagCapabilities returns the gpu:/vram: tags for one GPU, given its name
+// and total memory in MB.
+func gpuTagCapabilities(idx int, gpuName, memoryMB string) []Capability {
+	var caps []Capability
+	gpuTag := NormalizeGPUName(gpuName)
+	vramGB := NormalizeVRAM(memoryMB)
+
+	// Aggregate tags (e.g., gpu:rtx4090, vram:24gb)
+	if gpuTag != "" {
+		caps = append(caps, Capability{Tag: fmt.Sprintf("gpu:%s", gpuTag), Category: "gpu", Description: gpuName})
+	}
+	if vramGB != "" {
+		caps = append(caps, Capability{Tag: fmt.Sprintf("vram:%sgb", vramGB), Category: "vram", Description: fmt.Sprintf("%s MB VRAM", memoryMB)})
+	}
+
+	// Indexed tags for per-GPU targeting (e.g., gpu:0:rtx4090:24gb)
+	if gpuTag != "" && vramGB != "" {
+		indexedTag := fmt.Sprintf("gpu:%d:%s:%sgb", idx, gpuTag, vramGB)
+		if ValidateTag(indexedTag) {
+			caps = append(caps, Capability{
+				Tag:         indexedTag,
+				Category:    "gpu",
+				Description: fmt.Sprintf("GPU %d: %s (%s MB VRAM)", idx, gpuName, memoryMB),
+			})
 		}
 	}
 	return caps
@@ -595,6 +634,10 @@ func NormalizeGPUName(name string) string {
 	}{
 		{"rtx", regexp.MustCompile(`rtx\s*(\d{4}\s*(?:ti|super)?)`)},
 		{"gtx", regexp.MustCompile(`gtx\s*(\d{3,4}\s*(?:ti|super)?)`)},
+		// AMD: Instinct MI210/MI300X, Radeon RX 7900 XTX, Radeon Pro W7900.
+		{"", regexp.MustCompile(`\b(mi\d{3}[a-z]?)\b`)},
+		{"rx", regexp.MustCompile(`\brx\s*(\d{4}\s*(?:xtx|xt|gre)?)`)},
+		{"", regexp.MustCompile(`\bpro\s+(w\d{4})\b`)},
 		{"", regexp.MustCompile(`\b([ahvl]\d{2,3})\b`)},
 	}
 	for _, p := range patterns {
@@ -4,7 +4,10 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
+
+	"github.com/aceteam-ai/citadel-cli/internal/platform"
 )
 
 func TestValidateTag(t *testing.T) {
@@ -43,6 +46,10 @@ func TestNormalizeGPUName(t *testing.T) {
 		{"Tesla A100", "a100"},
 		{"NVIDIA A100-SXM4-80GB", "a100"},
 		{"NVIDIA H100", "h100"},
+		{"AMD Instinct MI210", "mi210"},
+		{"AMD Instinct MI300X", "mi300x"},
+		{"Radeon RX 7900 XTX", "rx7900xtx"},
+		{"AMD Radeon PRO W7900", "w7900"},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -54,6 +61,34 @@ func TestNormalizeGPUName(t *testing.T) {
 	}
 }
 
+func TestROCmGPUCapabilities(t *testing.T) {
+	data, err := os.ReadFile("../platform/testdata/rocm-smi-mi210.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gpus, err := platform.ParseROCmSMI(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caps := rocmGPUCapabilities(gpus)
+	if caps.Count != 2 || caps.DriverStatus != "ok" || len(caps.Devices) != 2 {
+		t.Fatalf("caps = %+v", caps)
+	}
+	dev := caps.Devices[0]
+	if dev.Tag != "mi210" || dev.VRAMTag != "64gb" || dev.VRAMMb != 65520 || dev.Vendor != platform.GPUVendorAMD {
+		t.Errorf("device = %+v, want mi210/64gb from AMD", dev)
+	}
+
+	tags := gpuTagCapabilities(1, dev.Name, "65520")
+	var got []string
+	for _, c := range tags {
+		got = append(got, c.Tag)
+	}
+	if want := []string{"gpu:mi210", "vram:64gb", "gpu:1:mi210:64gb"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("tags = %v, want %v", got, want)
+	}
+}
+
 func TestNormalizeVRAM(t *testing.T) {
 	tests := []struct {
 		mb   string
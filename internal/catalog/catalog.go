@@ -3,6 +3,7 @@
 package catalog
 
 import (
+	"context"
 	"fmt"
 	"net"
 	"os"
@@ -485,12 +486,22 @@ func Search(query string) ([]RegistryEntry, error) {
 	return results, nil
 }
 
-// CheckGPU checks whether an NVIDIA GPU is available and returns VRAM in GB.
+// CheckGPU checks whether an NVIDIA or AMD GPU is available and returns VRAM
+// in GB.
 func CheckGPU() (hasGPU bool, vramGB float64, err error) {
 	cmd := exec.Command("nvidia-smi", "--query-gpu=memory.total", "--format=csv,noheader,nounits")
 	output, err := cmd.Output()
 	if err != nil {
-		return false, 0, nil // nvidia-smi not found or failed -- no GPU
+		// nvidia-smi not found or failed: try rocm-smi, else no GPU.
+		gpus, rerr := platform.QueryROCmGPUs(context.Background())
+		if rerr != nil {
+			return false, 0, nil
+		}
+		var totalMB int
+		for _, g := range gpus {
+			totalMB += g.VRAMTotalMB
+		}
+		return totalMB > 0, float64(totalMB) / 1024.0, nil
 	}
 
 	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
@@ -1,8 +1,10 @@
 package catalog
 
 import (
+	"os"
 	"strings"
 
+	"github.com/aceteam-ai/citadel-cli/internal/platform"
 	"gopkg.in/yaml.v3"
 )
 
@@ -21,6 +23,8 @@ import (
 //     compose.StripGPUDevices' navigation).
 //   - a top-level `gpus:` shorthand (e.g. `gpus: all`).
 //   - `runtime: nvidia` (legacy nvidia-docker runtime selection).
+//   - a `devices:` mapping of /dev/kfd (the ROCm compute device an AMD GPU
+//     service needs; see AdaptGPUStanzas).
 func serviceRequestsGPU(svc map[string]any) bool {
 	if svc == nil {
 		return false
@@ -36,10 +40,162 @@ func serviceRequestsGPU(svc map[string]any) bool {
 		return true
 	}
 
+	// `devices: [/dev/kfd, ...]` (ROCm).
+	if mapsROCmDevice(svc) {
+		return true
+	}
+
 	// deploy.resources.reservations.devices[*] (driver: nvidia or capabilities: [gpu]).
 	return deployReservesGPU(svc)
 }
 
+// rocmDevices are the host devices a ROCm container needs: the compute
+// interface and the render nodes.
+var rocmDevices = []string{"/dev/kfd", "/dev/dri"}
+
+// rocmGroups are the groups owning those devices on the host.
+var rocmGroups = []string{"video", "render"}
+
+// mapsROCmDevice reports whether a service maps /dev/kfd, in either the short
+// ("/dev/kfd" or "/dev/kfd:/dev/kfd") or long (source: /dev/kfd) form.
+func mapsROCmDevice(svc map[string]any) bool {
+	devices, ok := svc["devices"].([]any)
+	if !ok {
+		return false
+	}
+	for _, d := range devices {
+		switch dev := d.(type) {
+		case string:
+			if host, _, _ := strings.Cut(dev, ":"); strings.TrimSpace(host) == "/dev/kfd" {
+				return true
+			}
+		case map[string]any:
+			if src, ok := dev["source"].(string); ok && strings.TrimSpace(src) == "/dev/kfd" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// AdaptGPUStanzas rewrites a compose document's GPU requests for the host's
+// GPU vendor. Compose files are written for NVIDIA (a deploy device
+// reservation, `gpus:` or `runtime: nvidia`), which Docker cannot satisfy on
+// an AMD node, so for platform.GPUVendorAMD each NVIDIA request is replaced
+// with the ROCm equivalent: /dev/kfd and /dev/dri mapped into the container,
+// and the video/render groups added. Other vendors, and documents with no
+// NVIDIA request, are returned unchanged with changed=false. The service's
+// image must itself be a ROCm build; this only fixes the device plumbing.
+func AdaptGPUStanzas(composeYAML []byte, vendor string) (out []byte, changed bool, err error) {
+	if vendor != platform.GPUVendorAMD {
+		return composeYAML, false, nil
+	}
+	var doc map[string]any
+	if err := yaml.Unmarshal(composeYAML, &doc); err != nil {
+		return nil, false, err
+	}
+	services, ok := doc["services"].(map[string]any)
+	if !ok {
+		return composeYAML, false, nil
+	}
+	for _, raw := range services {
+		svc, ok := raw.(map[string]any)
+		if !ok || !stripNvidiaRequest(svc) {
+			continue
+		}
+		svc["devices"] = appendMissing(svc["devices"], rocmDevices)
+		svc["group_add"] = appendMissing(svc["group_add"], rocmGroups)
+		changed = true
+	}
+	if !changed {
+		return composeYAML, false, nil
+	}
+	out, err = yaml.Marshal(doc)
+	return out, err == nil, err
+}
+
+// hostGPUVendor detects the host's GPU vendor for AdaptGPUStanzas at install
+// time. A package var so tests need no GPU.
+var hostGPUVendor = platform.DetectGPUVendor
+
+// adaptComposeGPU applies AdaptGPUStanzas to a compose file in place, leaving
+// it byte-identical when nothing changes.
+func adaptComposeGPU(composePath, vendor string) error {
+	if vendor != platform.GPUVendorAMD {
+		return nil
+	}
+	data, err := os.ReadFile(composePath)
+	if err != nil {
+		return err
+	}
+	out, changed, err := AdaptGPUStanzas(data, vendor)
+	if err != nil || !changed {
+		return err
+	}
+	return os.WriteFile(composePath, out, 0644)
+}
+
+// stripNvidiaRequest removes a service's NVIDIA GPU request (`gpus:`,
+// `runtime: nvidia`, and GPU entries of deploy.resources.reservations.devices,
+// pruning parents left empty) and reports whether it had one.
+func stripNvidiaRequest(svc map[string]any) bool {
+	found := false
+	if _, ok := svc["gpus"]; ok {
+		delete(svc, "gpus")
+		found = true
+	}
+	if rt, ok := svc["runtime"].(string); ok && strings.EqualFold(strings.TrimSpace(rt), "nvidia") {
+		delete(svc, "runtime")
+		found = true
+	}
+	if !deployReservesGPU(svc) {
+		return found
+	}
+	deploy := svc["deploy"].(map[string]any)
+	resources := deploy["resources"].(map[string]any)
+	reservations := resources["reservations"].(map[string]any)
+	var kept []any
+	for _, d := range reservations["devices"].([]any) {
+		if dev, ok := d.(map[string]any); ok && deviceIsGPU(dev) {
+			continue
+		}
+		kept = append(kept, d)
+	}
+	if len(kept) > 0 {
+		reservations["devices"] = kept
+	} else {
+		delete(reservations, "devices")
+	}
+	if len(reservations) == 0 {
+		delete(resources, "reservations")
+	}
+	if len(resources) == 0 {
+		delete(deploy, "resources")
+	}
+	if len(deploy) == 0 {
+		delete(svc, "deploy")
+	}
+	return true
+}
+
+// appendMissing appends each of want not already in the (possibly absent)
+// compose list existing.
+func appendMissing(existing any, want []string) []any {
+	list, _ := existing.([]any)
+	have := make(map[string]bool, len(list))
+	for _, v := range list {
+		if s, ok := v.(string); ok {
+			have[s] = true
+		}
+	}
+	for _, w := range want {
+		if !have[w] {
+			list = append(list, w)
+		}
+	}
+	return list
+}
+
 // deployReservesGPU walks deploy.resources.reservations.devices looking for a GPU
 // device entry. The navigation mirrors compose.StripGPUDevices so the two stay
 // in lock-step on what counts as a GPU reservation.
@@ -61,18 +217,23 @@ func deployReservesGPU(svc map[string]any) bool {
 		return false
 	}
 	for _, d := range devices {
-		dev, ok := d.(map[string]any)
-		if !ok {
-			continue
-		}
-		if drv, ok := dev["driver"].(string); ok && strings.EqualFold(strings.TrimSpace(drv), "nvidia") {
+		if dev, ok := d.(map[string]any); ok && deviceIsGPU(dev) {
 			return true
 		}
-		if caps, ok := dev["capabilities"].([]any); ok {
-			for _, c := range caps {
-				if s, ok := c.(string); ok && strings.EqualFold(strings.TrimSpace(s), "gpu") {
-					return true
-				}
+	}
+	return false
+}
+
+// deviceIsGPU reports whether one deploy device reservation is a GPU: an
+// NVIDIA driver entry or a "gpu" capability.
+func deviceIsGPU(dev map[string]any) bool {
+	if drv, ok := dev["driver"].(string); ok && strings.EqualFold(strings.TrimSpace(drv), "nvidia") {
+		return true
+	}
+	if caps, ok := dev["capabilities"].([]any); ok {
+		for _, c := range caps {
+			if s, ok := c.(string); ok && strings.EqualFold(strings.TrimSpace(s), "gpu") {
+				return true
 			}
 		}
 	}
@@ -1,6 +1,11 @@
 package catalog
 
-import "testing"
+import (
+	"testing"
+
+	"github.com/aceteam-ai/citadel-cli/internal/platform"
+	"gopkg.in/yaml.v3"
+)
 
 func TestServiceRequestsGPU(t *testing.T) {
 	tests := []struct {
@@ -60,6 +65,21 @@ func TestServiceRequestsGPU(t *testing.T) {
 `,
 			want: true,
 		},
+		{
+			name: "rocm kfd device mapping",
+			yaml: "services:\n  svc:\n    image: x\n    devices:\n      - /dev/kfd\n      - /dev/dri\n",
+			want: true,
+		},
+		{
+			name: "rocm kfd long form",
+			yaml: "services:\n  svc:\n    image: x\n    devices:\n      - source: /dev/kfd\n        target: /dev/kfd\n",
+			want: true,
+		},
+		{
+			name: "non-gpu device mapping is not gpu",
+			yaml: "services:\n  svc:\n    image: x\n    devices:\n      - /dev/ttyUSB0:/dev/ttyUSB0\n",
+			want: false,
+		},
 		{
 			name: "deploy without gpu device is not gpu",
 			yaml: `services:
@@ -127,3 +147,79 @@ func TestDecodeComposeServices(t *testing.T) {
 		t.Error("expected an error for malformed YAML")
 	}
 }
+
+func TestAdaptGPUStanzas(t *testing.T) {
+	compose := `services:
+  llm:
+    image: x
+    devices:
+      - /dev/dri
+    deploy:
+      resources:
+        reservations:
+          devices:
+            - driver: nvidia
+              count: all
+              capabilities: [gpu]
+  legacy:
+    image: y
+    runtime: nvidia
+  web:
+    image: nginx
+`
+	out, changed, err := AdaptGPUStanzas([]byte(compose), platform.GPUVendorAMD)
+	if err != nil || !changed {
+		t.Fatalf("AdaptGPUStanzas = changed %v, err %v", changed, err)
+	}
+	svcs, err := decodeComposeServices(string(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	llm := svcs["llm"]
+	if _, ok := llm["deploy"]; ok {
+		t.Errorf("llm: empty deploy block left behind: %v", llm["deploy"])
+	}
+	if got, want := llm["devices"], []any{"/dev/dri", "/dev/kfd"}; !equalYAML(got, want) {
+		t.Errorf("llm devices = %v, want %v", got, want)
+	}
+	if got, want := llm["group_add"], []any{"video", "render"}; !equalYAML(got, want) {
+		t.Errorf("llm group_add = %v, want %v", got, want)
+	}
+	if !serviceRequestsGPU(llm) {
+		t.Error("adapted service must still count as a GPU service")
+	}
+
+	legacy := svcs["legacy"]
+	if _, ok := legacy["runtime"]; ok {
+		t.Error("legacy: runtime: nvidia not removed")
+	}
+	if !mapsROCmDevice(legacy) {
+		t.Error("legacy: /dev/kfd not mapped")
+	}
+
+	if _, ok := svcs["web"]["devices"]; ok {
+		t.Error("web: a non-GPU service must not gain devices")
+	}
+}
+
+func TestAdaptGPUStanzas_Unchanged(t *testing.T) {
+	gpu := []byte("services:\n  svc:\n    image: x\n    gpus: all\n")
+	for _, vendor := range []string{platform.GPUVendorNVIDIA, ""} {
+		out, changed, err := AdaptGPUStanzas(gpu, vendor)
+		if err != nil || changed || string(out) != string(gpu) {
+			t.Errorf("vendor %q: out %q changed %v err %v, want input unchanged", vendor, out, changed, err)
+		}
+	}
+	cpu := []byte("services:\n  svc:\n    image: nginx\n")
+	if out, changed, err := AdaptGPUStanzas(cpu, platform.GPUVendorAMD); err != nil || changed || string(out) != string(cpu) {
+		t.Errorf("cpu-only compose: out %q changed %v err %v, want input unchanged", out, changed, err)
+	}
+}
+
+// equalYAML compares two decoded YAML values by re-encoding them.
+func equalYAML(a, b any) bool {
+	x, _ := yaml.Marshal(a)
+	y, _ := yaml.Marshal(b)
+	return string(x) == string(y)
+}
@@ -230,6 +230,13 @@ func InstallFromManifest(manifest *ServiceManifest, composeSrcPath, servicesDir
 		ComposeDestPath: composeDest,
 	}
 
+	// 6a. GPU vendor: compose files request NVIDIA GPUs, which Docker cannot
+	// satisfy on an AMD node, so rewrite those requests to the ROCm device
+	// mappings. A no-op on NVIDIA and GPU-less hosts.
+	if err := adaptComposeGPU(composeDest, hostGPUVendor()); err != nil {
+		return nil, fmt.Errorf("failed to adapt GPU requests in compose file: %w", err)
+	}
+
 	// 6b. Least-privilege sandbox (untrusted/Tier-2 only, unless --no-harden).
 	// Generate a hardening override from the manifest's declared needs and write
 	// it next to the compose as <name>.sandbox.yml; the run path includes it
@@ -188,3 +188,23 @@ func isSafeCacheName(name string) bool {
 	}
 	return true
 }
+
+// InstalledManifest loads an installed module's ServiceManifest from the cache
+// checkout its lockfile entry maps to (the same dir ReferencedCacheDirs keeps),
+// or from the catalog for a catalog-sourced entry. Unlike ResolveSource it
+// never fetches, so it is cheap enough for the service supervisor to call on
+// every pass.
+func InstalledManifest(entry LockEntry) (*ServiceManifest, error) {
+	src, err := ParseSource(entry.Source)
+	if err != nil {
+		return nil, err
+	}
+	if src.Kind == KindCatalog {
+		return LoadServiceManifest(entry.Name)
+	}
+	if entry.ResolvedRef != "" {
+		src.Ref = entry.ResolvedRef
+	}
+	manifest, _, err := loadModuleManifest(ExpectedCacheDir(src))
+	return manifest, err
+}
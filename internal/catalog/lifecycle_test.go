@@ -1,6 +1,8 @@
 package catalog
 
 import (
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 )
@@ -165,3 +167,36 @@ func TestProbeHealthNotProbeable(t *testing.T) {
 		t.Errorf("ProbeHealth(unused port) = %v, must not be ProbeUnhealthy", got)
 	}
 }
+
+func TestInstalledManifestReadsCacheCheckout(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	entry := LockEntry{Name: "whisper", Source: "acme/whisper@^1.0", ResolvedRef: "v1.2.0"}
+	src, err := ParseSource(entry.Source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src.Ref = entry.ResolvedRef
+	dir := filepath.Join(ExpectedCacheDir(src), "citadel")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	manifest := "name: whisper\nhealth_check:\n  endpoint: /health\n  port: 9000\n  retries: 4\n"
+	if err := os.WriteFile(filepath.Join(dir, "service.yaml"), []byte(manifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "compose.yml"), []byte("services: {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := InstalledManifest(entry)
+	if err != nil {
+		t.Fatalf("InstalledManifest: %v", err)
+	}
+	if got.HealthCheck.Port != 9000 || got.HealthCheck.Retries != 4 {
+		t.Errorf("health check = %+v", got.HealthCheck)
+	}
+
+	if _, err := InstalledManifest(LockEntry{Name: "gone", Source: "acme/gone"}); err == nil {
+		t.Error("expected an error for a module with no cache checkout")
+	}
+}
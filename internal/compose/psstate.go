@@ -49,6 +49,10 @@ type PSContainer struct {
 	State   string `json:"State"`
 	Status  string `json:"Status"`
 	Ports   string `json:"Ports"`
+	// OOMKilled is not in the ps record: ps cannot tell the kernel's OOM kill
+	// from `docker stop`'s SIGKILL (both exit 137), so a caller that needs to
+	// fills it from `docker inspect`'s .State.OOMKilled.
+	OOMKilled bool `json:"-"`
 }
 
 // Running reports whether the container's state reads as up.
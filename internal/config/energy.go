@@ -1,9 +1,12 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -62,3 +65,157 @@ func SaveEnergy(configDir string, e *Energy) error {
 
 	return os.WriteFile(filepath.Join(configDir, energyFile), data, 0644)
 }
+
+// EnergySchedule is the node-local electricity schedule that deferrable jobs
+// wait on (energy-schedule.yaml). It holds one value per hour of the day,
+// either a tariff (price per kWh) or a grid carbon intensity (gCO2/kWh), and
+// an hour whose value is at or below the cheap threshold is a cheap window.
+// The worker holds jobs marked deferrable until a cheap window opens or their
+// deadline arrives, and the footprint job log records the window each job ran
+// in so savings can be reported against the hour it arrived in.
+//
+//	metric: tariff
+//	unit: USD/kWh
+//	timezone: Europe/Berlin
+//	cheap_at: 0.18
+//	hours: [0.14, 0.13, ..., 0.31, 0.22]   # 24 values, 00:00 first
+//	weekend: [0.14, ...]                   # optional, Saturday and Sunday
+type EnergySchedule struct {
+	// Metric is what the hourly values measure: EnergyMetricTariff or
+	// EnergyMetricCarbon.
+	Metric string `yaml:"metric" json:"metric"`
+	// Unit labels the values in reports (e.g. "USD/kWh", "gCO2/kWh").
+	Unit string `yaml:"unit,omitempty" json:"unit,omitempty"`
+	// Timezone is the IANA zone the hours are in. Empty means the node's local
+	// time zone.
+	Timezone string `yaml:"timezone,omitempty" json:"timezone,omitempty"`
+	// Hours holds the 24 hourly values, index 0 covering 00:00-01:00.
+	Hours []float64 `yaml:"hours" json:"hours"`
+	// Weekend, when set, replaces Hours on Saturday and Sunday.
+	Weekend []float64 `yaml:"weekend,omitempty" json:"weekend,omitempty"`
+	// CheapAt is the value at or below which an hour is cheap. Zero means the
+	// cheapest third of the weekday hours.
+	CheapAt float64 `yaml:"cheap_at,omitempty" json:"cheap_at,omitempty"`
+
+	loc *time.Location
+}
+
+// Energy schedule metrics.
+const (
+	EnergyMetricTariff = "tariff"
+	EnergyMetricCarbon = "carbon"
+)
+
+const energyScheduleFile = "energy-schedule.yaml"
+
+// EnergyWindow is the schedule hour containing a given time.
+type EnergyWindow struct {
+	Start time.Time
+	End   time.Time
+	// Value is the hour's tariff or carbon intensity.
+	Value float64
+	// Cheap reports whether Value is at or below the schedule's threshold.
+	Cheap bool
+}
+
+// Label is "cheap" or "peak", the window column of the footprint job log.
+func (w EnergyWindow) Label() string {
+	if w.Cheap {
+		return "cheap"
+	}
+	return "peak"
+}
+
+// LoadEnergySchedule reads energy-schedule.yaml from the config directory. A
+// missing file returns (nil, nil): without a schedule no job is deferred. A
+// malformed schedule is an error rather than a silent default, since guessing
+// would hold jobs through the wrong hours.
+func LoadEnergySchedule(configDir string) (*EnergySchedule, error) {
+	data, err := os.ReadFile(filepath.Join(configDir, energyScheduleFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read energy schedule: %w", err)
+	}
+	var s EnergySchedule
+	if err := yaml.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("parse energy schedule: %w", err)
+	}
+	if err := s.init(); err != nil {
+		return nil, fmt.Errorf("energy schedule: %w", err)
+	}
+	return &s, nil
+}
+
+// init validates the schedule and resolves its time zone and threshold.
+func (s *EnergySchedule) init() error {
+	switch s.Metric {
+	case EnergyMetricTariff, EnergyMetricCarbon:
+	default:
+		return fmt.Errorf("metric must be %q or %q, got %q", EnergyMetricTariff, EnergyMetricCarbon, s.Metric)
+	}
+	if len(s.Hours) != 24 {
+		return fmt.Errorf("hours must have 24 values, got %d", len(s.Hours))
+	}
+	if len(s.Weekend) != 0 && len(s.Weekend) != 24 {
+		return fmt.Errorf("weekend must have 24 values, got %d", len(s.Weekend))
+	}
+	for _, v := range append(append([]float64{}, s.Hours...), s.Weekend...) {
+		if v < 0 {
+			return fmt.Errorf("hourly values must not be negative, got %v", v)
+		}
+	}
+	if s.CheapAt < 0 {
+		return fmt.Errorf("cheap_at must not be negative, got %v", s.CheapAt)
+	}
+	s.loc = time.Local
+	if s.Timezone != "" {
+		loc, err := time.LoadLocation(s.Timezone)
+		if err != nil {
+			return fmt.Errorf("timezone: %w", err)
+		}
+		s.loc = loc
+	}
+	if s.CheapAt == 0 {
+		sorted := append([]float64{}, s.Hours...)
+		sort.Float64s(sorted)
+		s.CheapAt = sorted[len(sorted)/3-1]
+	}
+	return nil
+}
+
+// WindowAt returns the schedule hour containing t.
+func (s *EnergySchedule) WindowAt(t time.Time) EnergyWindow {
+	local := t.In(s.location())
+	start := time.Date(local.Year(), local.Month(), local.Day(), local.Hour(), 0, 0, 0, s.location())
+	hours := s.Hours
+	if wd := local.Weekday(); len(s.Weekend) == 24 && (wd == time.Saturday || wd == time.Sunday) {
+		hours = s.Weekend
+	}
+	v := hours[local.Hour()]
+	return EnergyWindow{Start: start, End: start.Add(time.Hour), Value: v, Cheap: v <= s.CheapAt}
+}
+
+// NextCheap returns the start of the first cheap window at or after t, looking
+// a week ahead. ok is false when the schedule has no cheap hour at all.
+func (s *EnergySchedule) NextCheap(t time.Time) (time.Time, bool) {
+	w := s.WindowAt(t)
+	if w.Cheap {
+		return t, true
+	}
+	for i := 0; i < 7*24; i++ {
+		w = s.WindowAt(w.End)
+		if w.Cheap {
+			return w.Start, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func (s *EnergySchedule) location() *time.Location {
+	if s.loc == nil {
+		return time.Local
+	}
+	return s.loc
+}
@@ -3,7 +3,9 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestLoadEnergyDefaultsOff(t *testing.T) {
@@ -38,3 +40,94 @@ func TestLoadEnergyPartialFileKeepsDefault(t *testing.T) {
 		t.Fatal("absent key should keep default disabled")
 	}
 }
+
+func writeEnergySchedule(t *testing.T, dir, body string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, energyScheduleFile), []byte(body), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadEnergyScheduleMissingIsNil(t *testing.T) {
+	s, err := LoadEnergySchedule(t.TempDir())
+	if err != nil || s != nil {
+		t.Fatalf("missing schedule = %+v, %v; want nil, nil", s, err)
+	}
+}
+
+func TestEnergyScheduleWindows(t *testing.T) {
+	dir := t.TempDir()
+	// Cheap overnight (0.10 from 00:00 to 06:00), peak otherwise; weekends flat cheap.
+	writeEnergySchedule(t, dir, `metric: tariff
+unit: USD/kWh
+timezone: UTC
+cheap_at: 0.12
+hours: [0.10, 0.10, 0.10, 0.10, 0.10, 0.10, 0.25, 0.25, 0.25, 0.25, 0.25, 0.25,
+        0.25, 0.25, 0.25, 0.25, 0.25, 0.25, 0.30, 0.30, 0.30, 0.25, 0.25, 0.25]
+weekend: [0.10, 0.10, 0.10, 0.10, 0.10, 0.10, 0.10, 0.10, 0.10, 0.10, 0.10, 0.10,
+          0.10, 0.10, 0.10, 0.10, 0.10, 0.10, 0.10, 0.10, 0.10, 0.10, 0.10, 0.10]
+`)
+	s, err := LoadEnergySchedule(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Wednesday 19:30 UTC is peak.
+	wed := time.Date(2026, 10, 14, 19, 30, 0, 0, time.UTC)
+	w := s.WindowAt(wed)
+	if w.Cheap || w.Value != 0.30 || w.Label() != "peak" || !w.Start.Equal(time.Date(2026, 10, 14, 19, 0, 0, 0, time.UTC)) {
+		t.Errorf("WindowAt(wed 19:30) = %+v", w)
+	}
+	next, ok := s.NextCheap(wed)
+	if !ok || !next.Equal(time.Date(2026, 10, 15, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("NextCheap(wed 19:30) = %v, %v; want thursday 00:00", next, ok)
+	}
+	// Already cheap: now.
+	if next, ok := s.NextCheap(wed.Add(-15 * time.Hour)); !ok || !next.Equal(wed.Add(-15*time.Hour)) {
+		t.Errorf("NextCheap inside a cheap window = %v, %v; want now", next, ok)
+	}
+	// Saturday afternoon uses the weekend profile.
+	if w := s.WindowAt(time.Date(2026, 10, 17, 15, 0, 0, 0, time.UTC)); !w.Cheap {
+		t.Errorf("saturday 15:00 = %+v, want cheap weekend rate", w)
+	}
+}
+
+func TestEnergyScheduleDefaultThreshold(t *testing.T) {
+	dir := t.TempDir()
+	writeEnergySchedule(t, dir, `metric: carbon
+timezone: UTC
+hours: [1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20, 21, 22, 23, 24]
+`)
+	s, err := LoadEnergySchedule(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The cheapest third: hours 00:00-08:00.
+	if s.CheapAt != 8 {
+		t.Errorf("CheapAt = %v, want 8", s.CheapAt)
+	}
+	day := time.Date(2026, 10, 14, 0, 0, 0, 0, time.UTC)
+	if !s.WindowAt(day.Add(7*time.Hour)).Cheap || s.WindowAt(day.Add(8*time.Hour)).Cheap {
+		t.Error("cheap window should end at 08:00")
+	}
+}
+
+func TestLoadEnergyScheduleRejectsMalformed(t *testing.T) {
+	cases := map[string]string{
+		"unknown metric":   "metric: price\nhours: [" + strings.Repeat("1, ", 23) + "1]\n",
+		"short day":        "metric: tariff\nhours: [1, 2, 3]\n",
+		"short weekend":    "metric: tariff\nhours: [" + strings.Repeat("1, ", 23) + "1]\nweekend: [1]\n",
+		"negative value":   "metric: tariff\nhours: [-1, " + strings.Repeat("1, ", 22) + "1]\n",
+		"unknown timezone": "metric: tariff\ntimezone: Mars/Olympus\nhours: [" + strings.Repeat("1, ", 23) + "1]\n",
+		"not yaml":         "metric: [",
+	}
+	for name, body := range cases {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeEnergySchedule(t, dir, body)
+			if s, err := LoadEnergySchedule(dir); err == nil {
+				t.Errorf("expected an error, got %+v", s)
+			}
+		})
+	}
+}
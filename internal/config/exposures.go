@@ -51,6 +51,11 @@ type ExposureRecord struct {
 	// would stop verifying (breaking live links), and a later bump could not
 	// revoke anything it had already handed out.
 	TokenEpoch int `json:"token_epoch,omitempty"`
+	// StorageKey is the issued storage access key (id) an object-storage
+	// exposure signs with. Only the id is persisted; the secret stays in the
+	// storage state and is looked up again on restore, so a key revoked while
+	// the node was down does not come back.
+	StorageKey string `json:"storage_key,omitempty"`
 }
 
 // LoadExposures returns the persisted exposure set, or an empty slice when
@@ -0,0 +1,70 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Webhook is one outbound subscription to the node's event bus
+// (internal/events): every matching event is POSTed to URL, signed with
+// Secret, and retried until the receiver accepts it.
+//
+//	webhooks:
+//	  - url: https://ci.example.internal/citadel
+//	    secret: s3cret
+//	    events: [job.finished, job.failed, service.*]
+type Webhook struct {
+	URL string `yaml:"url"`
+	// Secret is the HMAC-SHA256 key deliveries are signed with, so the
+	// receiver can tell they came from this node. Required.
+	Secret string `yaml:"secret"`
+	// Events lists the event types to deliver: exact types, subject
+	// wildcards ("job.*") or "*". Empty delivers every event.
+	Events []string `yaml:"events,omitempty"`
+}
+
+// webhooksFile holds the outbound webhooks. It carries secrets, so it should
+// be readable only by the node's user.
+const webhooksFile = "webhooks.yaml"
+
+// LoadWebhooks reads webhooks.yaml from the config directory. A missing file
+// means no webhooks (nil, nil); a malformed one, or a webhook without an
+// http(s) URL or a secret or with a repeated URL, is an error so a typo is not
+// silently ignored.
+func LoadWebhooks(configDir string) ([]Webhook, error) {
+	data, err := os.ReadFile(filepath.Join(configDir, webhooksFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read webhooks: %w", err)
+	}
+	var file struct {
+		Webhooks []Webhook `yaml:"webhooks"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parse webhooks: %w", err)
+	}
+	seen := make(map[string]bool, len(file.Webhooks))
+	for i, w := range file.Webhooks {
+		u, err := url.Parse(w.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("webhook %d: url %q is not an http(s) URL", i+1, w.URL)
+		}
+		if w.Secret == "" {
+			return nil, fmt.Errorf("webhook %d (%s): secret is required", i+1, w.URL)
+		}
+		// Pending deliveries are keyed by URL, so two entries for one URL
+		// would be indistinguishable; list both event sets on one entry.
+		if seen[w.URL] {
+			return nil, fmt.Errorf("webhook %d: %s is listed twice", i+1, w.URL)
+		}
+		seen[w.URL] = true
+	}
+	return file.Webhooks, nil
+}
@@ -0,0 +1,52 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadWebhooksMissingIsNil(t *testing.T) {
+	hooks, err := LoadWebhooks(t.TempDir())
+	if err != nil || hooks != nil {
+		t.Fatalf("missing webhooks = %+v, %v; want nil, nil", hooks, err)
+	}
+}
+
+func TestLoadWebhooks(t *testing.T) {
+	dir := t.TempDir()
+	body := "webhooks:\n" +
+		"  - url: https://ci.example.internal/citadel\n" +
+		"    secret: s3cret\n" +
+		"    events: [job.finished, service.*]\n" +
+		"  - url: http://127.0.0.1:9000/hook\n" +
+		"    secret: other\n"
+	if err := os.WriteFile(filepath.Join(dir, webhooksFile), []byte(body), 0600); err != nil {
+		t.Fatal(err)
+	}
+	hooks, err := LoadWebhooks(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hooks) != 2 || hooks[0].Secret != "s3cret" || len(hooks[0].Events) != 2 || hooks[1].Events != nil {
+		t.Errorf("hooks = %+v", hooks)
+	}
+}
+
+func TestLoadWebhooksRejectsInvalid(t *testing.T) {
+	for name, body := range map[string]string{
+		"no secret":  "webhooks:\n  - url: https://example.com/hook\n",
+		"bad scheme": "webhooks:\n  - url: ftp://example.com/hook\n    secret: x\n",
+		"no host":    "webhooks:\n  - url: /hook\n    secret: x\n",
+		"malformed":  "webhooks: [\n",
+		"duplicate":  "webhooks:\n  - url: https://example.com/hook\n    secret: x\n  - url: https://example.com/hook\n    secret: y\n",
+	} {
+		dir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(dir, webhooksFile), []byte(body), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadWebhooks(dir); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -4,8 +4,10 @@
 // listeners attached via AddListener, with no application-layer auth (the tsnet
 // mesh is the trust boundary, exactly like internal/desktop's VNCServer).
 //
-// Input (mouse/keyboard) is unchanged and stays on the existing action path;
-// this package is VIDEO-ONLY.
+// The same WebSocket carries input back from the viewer: pointer, keyboard and
+// text events are executed as desktop actions, clipboard text is synced with
+// the node clipboard, and viewer receive stats drive the encoder's bitrate and
+// output resolution (see input.go and ratecontrol.go).
 package deskstream
 
 // H.264 in Annex-B format is a sequence of NAL (Network Abstraction Layer)
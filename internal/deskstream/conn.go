@@ -2,10 +2,13 @@ package deskstream
 
 import (
 	"context"
+	"encoding/json"
 	"net/http"
+	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/aceteam-ai/citadel-cli/internal/desktop"
 	"github.com/gorilla/websocket"
 )
 
@@ -26,6 +29,12 @@ const (
 //  3. The client MAY send a TEXT frame {"type":"requestKeyframe"} to force an
 //     immediate IDR; the server restarts its encoder to honor it (a fresh
 //     ffmpeg process always begins with SPS+PPS+IDR).
+//  4. The client MAY send BINARY frames carrying pointer, keyboard, text,
+//     clipboard and receive-stats messages (see input.go). Input is executed
+//     through desktop actions; stats drive the adaptive rate controller,
+//     which announces changes with a TEXT reconfigure message.
+//  5. The client MAY send {"type":"subscribeClipboard"} to receive TEXT
+//     clipboard messages when the node clipboard changes.
 //
 // A fresh connection is treated as an implicit keyframe request: the encoder is
 // started from scratch, so the first BINARY frame already carries SPS+PPS+IDR
@@ -82,17 +91,52 @@ func (s *Server) handleStream(w http.ResponseWriter, r *http.Request) {
 	// callback (a different goroutine) only ENQUEUES; it never writes directly.
 	payloads := make(chan []byte, 64)
 	restart := make(chan struct{}, 1)
+	stats := make(chan ViewerStats, 4)
+	clipboardOut := make(chan string, 1)
 
 	ctx, cancel := context.WithCancel(r.Context())
 	defer cancel()
 
-	runner := newEncoderRunner(cfg, enc, func(p []byte) {
+	// Input is executed off the reader goroutine so a slow xdotool spawn
+	// never stalls reads (and with them pong handling).
+	inputQueue := make(chan []desktop.Action, inputQueueSize)
+	inputDone := make(chan struct{})
+	go func() {
+		runInputExecutor(ctx, inputQueue, s.logger)
+		close(inputDone)
+	}()
+	mapper := newInputMapper(geom.Width, geom.Height)
+	var mapperMu sync.Mutex
+	// At teardown, once the executor has stopped, release whatever keys and
+	// buttons the viewer still held so nothing stays pressed on the node.
+	defer func() {
+		cancel()
+		<-inputDone
+		mapperMu.Lock()
+		held := mapper.release()
+		mapperMu.Unlock()
+		if len(held) == 0 {
+			return
+		}
+		rctx, rcancel := context.WithTimeout(context.Background(), inputReleaseTimeout)
+		defer rcancel()
+		if err := executeActions(rctx, held); err != nil {
+			s.logger.Printf("client %s: releasing held input: %v", remote, err)
+		}
+	}()
+	clip := &clipboardWatcher{}
+	var clipboardOnce sync.Once
+	clipboardIn := make(chan string, 1)
+	go runClipboardWriter(ctx, clip, clipboardIn, s.logger)
+
+	enqueue := func(p []byte) {
 		// Drop frames if the client cannot keep up rather than block ffmpeg.
 		select {
 		case payloads <- p:
 		default:
 		}
-	})
+	}
+	runner := newEncoderRunner(cfg, enc, enqueue)
 	if err := runner.Start(ctx); err != nil {
 		s.logger.Printf("encoder start failed: %v", err)
 		return
@@ -103,10 +147,24 @@ func (s *Server) handleStream(w http.ResponseWriter, r *http.Request) {
 	// reassigned it — otherwise the restarted ffmpeg would never be reaped.
 	defer func() { runner.Stop() }()
 
-	// Reader goroutine: handles client TEXT frames (requestKeyframe) and detects
-	// disconnects. It never writes to the socket.
+	// restartEncoder replaces the runner with a fresh ffmpeg for cfg. Drain any
+	// stale queued payloads first so the client does not briefly receive
+	// pre-restart P-frames. A late onPayload from the old runner's read
+	// goroutine can still slip one stale P-frame past drain before the new
+	// keyframe arrives; the decoder self-corrects within a frame, which is
+	// acceptable here.
+	restartEncoder := func() error {
+		runner.Stop()
+		drain(payloads)
+		runner = newEncoderRunner(cfg, enc, enqueue)
+		return runner.Start(ctx)
+	}
+
+	// Reader goroutine: handles client TEXT frames (requestKeyframe,
+	// subscribeClipboard) and BINARY input frames, and detects disconnects. It
+	// never writes to the socket.
 	go func() {
-		conn.SetReadLimit(4096)
+		conn.SetReadLimit(maxClipboardBytes + 1024)
 		_ = conn.SetReadDeadline(time.Now().Add(pongWait))
 		conn.SetPongHandler(func(string) error {
 			return conn.SetReadDeadline(time.Now().Add(pongWait))
@@ -117,15 +175,58 @@ func (s *Server) handleStream(w http.ResponseWriter, r *http.Request) {
 				cancel()
 				return
 			}
-			if mt == websocket.TextMessage && parseClientMessage(data) == ClientMsgRequestKeyframe {
+			if mt == websocket.TextMessage {
+				switch parseClientMessage(data) {
+				case ClientMsgRequestKeyframe:
+					select {
+					case restart <- struct{}{}:
+					default:
+					}
+				case ClientMsgSubscribeClipboard:
+					clipboardOnce.Do(func() { go watchClipboard(ctx, clip, clipboardOut) })
+				}
+				continue
+			}
+
+			ev, perr := parseBinaryMessage(data)
+			if perr != nil {
+				s.logger.Printf("client %s: %v", remote, perr)
+				continue
+			}
+			switch ev.Kind {
+			case MsgStats:
+				select {
+				case stats <- ev.Stats:
+				default:
+				}
+			case MsgClipboard:
+				// Only the latest paste matters: replace one still waiting.
+				select {
+				case <-clipboardIn:
+				default:
+				}
+				clipboardIn <- ev.Text
+			default:
+				mapperMu.Lock()
+				actions := mapper.actions(ev)
+				mapperMu.Unlock()
+				if len(actions) == 0 {
+					continue
+				}
 				select {
-				case restart <- struct{}{}:
+				case inputQueue <- actions:
 				default:
+					s.logger.Printf("input queue full, dropping %d action(s)", len(actions))
 				}
 			}
 		}
 	}()
 
+	// The rate controller starts on the first stats report; clients that never
+	// send one keep the encoder defaults.
+	var rc *rateController
+	sentBytes := 0
+
 	ping := time.NewTicker(pingPeriod)
 	defer ping.Stop()
 
@@ -135,28 +236,48 @@ func (s *Server) handleStream(w http.ResponseWriter, r *http.Request) {
 			return
 		case <-restart:
 			// On-demand keyframe: restart the encoder so the next frames begin
-			// with SPS+PPS+IDR. Drain any stale queued payloads first so the
-			// client does not briefly receive pre-restart P-frames. A late
-			// onPayload from the old runner's read goroutine can still slip one
-			// stale P-frame past drain before the new keyframe arrives; the
-			// decoder self-corrects within a frame, which is acceptable here.
-			runner.Stop()
-			drain(payloads)
-			runner = newEncoderRunner(cfg, enc, func(p []byte) {
-				select {
-				case payloads <- p:
-				default:
-				}
-			})
-			if err := runner.Start(ctx); err != nil {
+			// with SPS+PPS+IDR.
+			if err := restartEncoder(); err != nil {
+				s.logger.Printf("encoder restart failed: %v", err)
+				return
+			}
+		case st := <-stats:
+			if rc == nil {
+				rc = newRateController(geom.Width, geom.Height, s.fps)
+			}
+			changed := rc.observe(st, sentBytes)
+			sentBytes = 0
+			if !changed {
+				continue
+			}
+			outW, outH := rc.streamSize()
+			msg, _ := json.Marshal(NewReconfigureMessage(outW, outH, rc.bitrateKbps))
+			_ = conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := conn.WriteMessage(websocket.TextMessage, msg); err != nil {
+				return
+			}
+			s.logger.Printf("client %s: stream now %dx%d @ %d kbit/s", remote, outW, outH, rc.bitrateKbps)
+			cfg.BitrateKbps = rc.bitrateKbps
+			cfg.OutputWidth, cfg.OutputHeight = outW, outH
+			mapperMu.Lock()
+			mapper.setStreamSize(outW, outH)
+			mapperMu.Unlock()
+			if err := restartEncoder(); err != nil {
 				s.logger.Printf("encoder restart failed: %v", err)
 				return
 			}
+		case text := <-clipboardOut:
+			msg, _ := json.Marshal(ClipboardMessage{Type: "clipboard", Text: text})
+			_ = conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := conn.WriteMessage(websocket.TextMessage, msg); err != nil {
+				return
+			}
 		case p := <-payloads:
 			_ = conn.SetWriteDeadline(time.Now().Add(writeWait))
 			if err := conn.WriteMessage(websocket.BinaryMessage, p); err != nil {
 				return
 			}
+			sentBytes += len(p)
 		case <-ping.C:
 			_ = conn.SetWriteDeadline(time.Now().Add(writeWait))
 			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
@@ -166,6 +287,30 @@ func (s *Server) handleStream(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// watchClipboard polls the node clipboard until ctx ends, forwarding changes
+// to out. A change is not sent while the previous one has not been written
+// yet; a later poll retries it (or the newer text).
+func watchClipboard(ctx context.Context, clip *clipboardWatcher, out chan<- string) {
+	t := time.NewTicker(clipboardPollInterval)
+	defer t.Stop()
+	send := func(text string) bool {
+		select {
+		case out <- text:
+			return true
+		default:
+			return false
+		}
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			clip.poll(ctx, send)
+		}
+	}
+}
+
 // drain empties a payload channel without blocking.
 func drain(ch chan []byte) {
 	for {
@@ -35,6 +35,20 @@ type EncodeConfig struct {
 	Height           int    // capture height in pixels
 	FPS              int    // target frame rate
 	KeyframeInterval int    // forced IDR interval in frames (g)
+
+	// BitrateKbps caps the encoder bitrate; 0 leaves the encoder default.
+	// Set by the adaptive rate controller once a viewer reports receive stats.
+	BitrateKbps int
+	// OutputWidth/OutputHeight scale the stream below the capture size; 0
+	// (or equal to the capture size) sends the capture unscaled.
+	OutputWidth  int
+	OutputHeight int
+}
+
+// scaled reports whether the output is downscaled from the capture size.
+func (cfg EncodeConfig) scaled() bool {
+	return cfg.OutputWidth > 0 && cfg.OutputHeight > 0 &&
+		(cfg.OutputWidth != cfg.Width || cfg.OutputHeight != cfg.Height)
 }
 
 // selectEncoder picks the best available H.264 encoder, preferring hardware
@@ -124,16 +138,24 @@ func buildFFmpegArgs(cfg EncodeConfig, enc EncoderKind) []string {
 	switch enc {
 	case EncoderVAAPI:
 		// VA-API needs the frames uploaded to the GPU; nv12 is the standard
-		// surface format. The device defaults to /dev/dri/renderD128.
+		// surface format. The device defaults to /dev/dri/renderD128. Scaling
+		// happens on the GPU after upload.
+		vf := "format=nv12,hwupload"
+		if cfg.scaled() {
+			vf += fmt.Sprintf(",scale_vaapi=w=%d:h=%d", cfg.OutputWidth, cfg.OutputHeight)
+		}
 		args = append(args,
 			"-vaapi_device", "/dev/dri/renderD128",
-			"-vf", "format=nv12,hwupload",
+			"-vf", vf,
 			"-c:v", "h264_vaapi",
 			"-g", strconv.Itoa(gop),
 			"-force_key_frames", forceKey,
 			"-bf", "0",
 		)
 	case EncoderNVENC:
+		if cfg.scaled() {
+			args = append(args, "-vf", fmt.Sprintf("scale=%d:%d", cfg.OutputWidth, cfg.OutputHeight))
+		}
 		args = append(args,
 			"-c:v", "h264_nvenc",
 			"-preset", "p1", // fastest / low-latency
@@ -143,6 +165,9 @@ func buildFFmpegArgs(cfg EncodeConfig, enc EncoderKind) []string {
 			"-bf", "0",
 		)
 	default: // software
+		if cfg.scaled() {
+			args = append(args, "-vf", fmt.Sprintf("scale=%d:%d", cfg.OutputWidth, cfg.OutputHeight))
+		}
 		args = append(args,
 			"-c:v", "libx264",
 			"-preset", "ultrafast",
@@ -155,6 +180,17 @@ func buildFFmpegArgs(cfg EncodeConfig, enc EncoderKind) []string {
 		)
 	}
 
+	// Constrained bitrate with a half-second VBV buffer: small enough that a
+	// rate change takes effect quickly, large enough to absorb a keyframe.
+	if cfg.BitrateKbps > 0 {
+		rate := fmt.Sprintf("%dk", cfg.BitrateKbps)
+		args = append(args,
+			"-b:v", rate,
+			"-maxrate", rate,
+			"-bufsize", fmt.Sprintf("%dk", cfg.BitrateKbps/2),
+		)
+	}
+
 	// Raw Annex-B H.264 on stdout.
 	args = append(args, "-f", "h264", "pipe:1")
 	return args
@@ -100,3 +100,26 @@ func TestBuildFFmpegArgs_NVENC(t *testing.T) {
 		}
 	}
 }
+
+func TestBuildFFmpegArgs_BitrateAndScale(t *testing.T) {
+	cfg := EncodeConfig{Display: ":0", Width: 1920, Height: 1080, FPS: 30,
+		BitrateKbps: 2500, OutputWidth: 1440, OutputHeight: 810}
+
+	sw := strings.Join(buildFFmpegArgs(cfg, EncoderSoftware), " ")
+	for _, want := range []string{"-video_size 1920x1080", "-vf scale=1440:810", "-b:v 2500k", "-maxrate 2500k", "-bufsize 1250k"} {
+		if !strings.Contains(sw, want) {
+			t.Errorf("software args missing %q\ngot: %s", want, sw)
+		}
+	}
+
+	vaapi := strings.Join(buildFFmpegArgs(cfg, EncoderVAAPI), " ")
+	if !strings.Contains(vaapi, "-vf format=nv12,hwupload,scale_vaapi=w=1440:h=810") {
+		t.Errorf("VA-API should scale on the GPU after upload\ngot: %s", vaapi)
+	}
+
+	// Defaults: no bitrate cap and no scaling filter.
+	plain := strings.Join(buildFFmpegArgs(EncodeConfig{Display: ":0", Width: 1920, Height: 1080, FPS: 30}, EncoderSoftware), " ")
+	if strings.Contains(plain, "-b:v") || strings.Contains(plain, "scale=") {
+		t.Errorf("default args should not set bitrate or scale\ngot: %s", plain)
+	}
+}
@@ -0,0 +1,418 @@
+package deskstream
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/aceteam-ai/citadel-cli/internal/desktop"
+	"github.com/aceteam-ai/citadel-cli/internal/platform"
+)
+
+// Client BINARY frames carry input, clipboard and receive statistics. The
+// first byte is the message type; all integers are big-endian. Server BINARY
+// frames remain H.264 payloads only, so a video-only client that never sends
+// these is unaffected.
+//
+//	0x01 pointer    x u16, y u16, buttons u8, wheel s8
+//	0x02 key        down u8, keysym u32
+//	0x03 text       UTF-8 text to type, at most maxTypeBytes
+//	0x04 clipboard  UTF-8 text to place on the node clipboard
+//	0x05 stats      intervalMs u32, bytes u32, decoded u16, dropped u16, decodeMs u16
+//
+// Pointer coordinates are in stream pixels (the size in the latest init or
+// reconfigure message). buttons uses the RFB bit layout: bit 0 left, bit 1
+// middle, bit 2 right. wheel is positive for scroll up. keysym is an X11
+// keysym, the same value an RFB KeyEvent carries.
+const (
+	MsgPointer   byte = 0x01
+	MsgKey       byte = 0x02
+	MsgText      byte = 0x03
+	MsgClipboard byte = 0x04
+	MsgStats     byte = 0x05
+)
+
+// maxClipboardBytes caps clipboard text accepted from (and sent to) a viewer.
+const maxClipboardBytes = 1 << 20
+
+// maxTypeChunk matches the per-action text limit enforced by desktop.Action.
+const maxTypeChunk = 1000
+
+// maxTypeBytes caps one text message, so it expands into at most a handful of
+// type actions (about a minute of xdotool typing). Longer pastes belong on the
+// clipboard message.
+const maxTypeBytes = 4 * maxTypeChunk
+
+// ViewerStats is a viewer's receive report for one reporting interval.
+type ViewerStats struct {
+	Interval      time.Duration
+	BytesReceived int
+	FramesDecoded int
+	FramesDropped int
+	// DecodeTime is the mean decode time per frame.
+	DecodeTime time.Duration
+}
+
+// ClientEvent is a decoded client BINARY frame. Only the fields for Kind are
+// set.
+type ClientEvent struct {
+	Kind    byte
+	X, Y    int
+	Buttons byte
+	Wheel   int
+	Down    bool
+	Keysym  uint32
+	Text    string
+	Stats   ViewerStats
+}
+
+// parseBinaryMessage decodes a client BINARY frame.
+func parseBinaryMessage(data []byte) (ClientEvent, error) {
+	if len(data) == 0 {
+		return ClientEvent{}, fmt.Errorf("empty message")
+	}
+	ev := ClientEvent{Kind: data[0]}
+	body := data[1:]
+	switch ev.Kind {
+	case MsgPointer:
+		if len(body) != 6 {
+			return ClientEvent{}, fmt.Errorf("pointer message: %d bytes, want 6", len(body))
+		}
+		ev.X = int(binary.BigEndian.Uint16(body[0:2]))
+		ev.Y = int(binary.BigEndian.Uint16(body[2:4]))
+		ev.Buttons = body[4]
+		ev.Wheel = int(int8(body[5]))
+	case MsgKey:
+		if len(body) != 5 {
+			return ClientEvent{}, fmt.Errorf("key message: %d bytes, want 5", len(body))
+		}
+		ev.Down = body[0] != 0
+		ev.Keysym = binary.BigEndian.Uint32(body[1:5])
+	case MsgText, MsgClipboard:
+		limit := maxClipboardBytes
+		if ev.Kind == MsgText {
+			limit = maxTypeBytes
+		}
+		if len(body) > limit {
+			return ClientEvent{}, fmt.Errorf("text message too large (%d bytes, limit %d)", len(body), limit)
+		}
+		if !utf8.Valid(body) {
+			return ClientEvent{}, fmt.Errorf("text message is not valid UTF-8")
+		}
+		ev.Text = string(body)
+	case MsgStats:
+		if len(body) != 14 {
+			return ClientEvent{}, fmt.Errorf("stats message: %d bytes, want 14", len(body))
+		}
+		ev.Stats = ViewerStats{
+			Interval:      time.Duration(binary.BigEndian.Uint32(body[0:4])) * time.Millisecond,
+			BytesReceived: int(binary.BigEndian.Uint32(body[4:8])),
+			FramesDecoded: int(binary.BigEndian.Uint16(body[8:10])),
+			FramesDropped: int(binary.BigEndian.Uint16(body[10:12])),
+			DecodeTime:    time.Duration(binary.BigEndian.Uint16(body[12:14])) * time.Millisecond,
+		}
+	default:
+		return ClientEvent{}, fmt.Errorf("unknown message type %#x", ev.Kind)
+	}
+	return ev, nil
+}
+
+// rfbButtons maps RFB button-mask bits to xdotool button numbers.
+var rfbButtons = [...]struct {
+	bit    byte
+	button int
+}{
+	{0x01, 1}, // left
+	{0x02, 2}, // middle
+	{0x04, 3}, // right
+}
+
+// inputMapper turns pointer and key events into desktop actions. It tracks
+// the pointer position and held buttons so a pointer event only produces the
+// transitions it represents, and scales stream coordinates back to the
+// captured screen when the stream is downscaled. It also tracks held keys, so
+// whatever the viewer still holds when it goes away can be released.
+type inputMapper struct {
+	captureW, captureH int
+	streamW, streamH   int
+
+	lastX, lastY int
+	havePos      bool
+	buttons      byte
+	keys         map[uint32]bool
+	released     bool
+}
+
+func newInputMapper(captureW, captureH int) *inputMapper {
+	return &inputMapper{captureW: captureW, captureH: captureH, streamW: captureW, streamH: captureH, keys: make(map[uint32]bool)}
+}
+
+// setStreamSize records the size of the stream the viewer is now decoding.
+func (m *inputMapper) setStreamSize(w, h int) {
+	m.streamW, m.streamH = w, h
+}
+
+// toScreen scales a stream coordinate to the captured screen.
+func (m *inputMapper) toScreen(x, y int) (int, int) {
+	if m.streamW > 0 && m.streamH > 0 && (m.streamW != m.captureW || m.streamH != m.captureH) {
+		x = x * m.captureW / m.streamW
+		y = y * m.captureH / m.streamH
+	}
+	return clampCoord(x), clampCoord(y)
+}
+
+func clampCoord(v int) int {
+	if v < 0 {
+		return 0
+	}
+	if v > 32767 {
+		return 32767
+	}
+	return v
+}
+
+// actions maps a pointer, key or text event onto desktop actions. It maps
+// nothing once release has run.
+func (m *inputMapper) actions(ev ClientEvent) []desktop.Action {
+	if m.released {
+		return nil
+	}
+	var out []desktop.Action
+	switch ev.Kind {
+	case MsgPointer:
+		x, y := m.toScreen(ev.X, ev.Y)
+		if !m.havePos || x != m.lastX || y != m.lastY {
+			out = append(out, desktop.Action{Type: "move", X: intPtr(x), Y: intPtr(y)})
+			m.lastX, m.lastY, m.havePos = x, y, true
+		}
+		for _, b := range rfbButtons {
+			was, now := m.buttons&b.bit != 0, ev.Buttons&b.bit != 0
+			switch {
+			case now && !was:
+				out = append(out, desktop.Action{Type: "mousedown", Button: intPtr(b.button)})
+			case was && !now:
+				out = append(out, desktop.Action{Type: "mouseup", Button: intPtr(b.button)})
+			}
+		}
+		m.buttons = ev.Buttons
+		if ev.Wheel != 0 {
+			delta := ev.Wheel
+			if delta > 100 {
+				delta = 100
+			} else if delta < -100 {
+				delta = -100
+			}
+			out = append(out, desktop.Action{Type: "scroll", Delta: intPtr(delta)})
+		}
+	case MsgKey:
+		typ := "keyup"
+		if ev.Down {
+			typ = "keydown"
+			m.keys[ev.Keysym] = true
+		} else {
+			delete(m.keys, ev.Keysym)
+		}
+		out = append(out, desktop.Action{Type: typ, Key: keysymKey(ev.Keysym)})
+	case MsgText:
+		for _, chunk := range splitText(ev.Text, maxTypeChunk) {
+			out = append(out, desktop.Action{Type: "type", Text: chunk})
+		}
+	}
+
+	return validActions(out)
+}
+
+// release returns the key-up and button-up actions for everything the viewer
+// still holds, so a disconnect mid-drag or with a modifier down does not
+// leave it pressed on the node, and stops mapping further events.
+func (m *inputMapper) release() []desktop.Action {
+	m.released = true
+	var out []desktop.Action
+	syms := make([]uint32, 0, len(m.keys))
+	for sym := range m.keys {
+		syms = append(syms, sym)
+	}
+	slices.Sort(syms)
+	for _, sym := range syms {
+		out = append(out, desktop.Action{Type: "keyup", Key: keysymKey(sym)})
+	}
+	for _, b := range rfbButtons {
+		if m.buttons&b.bit != 0 {
+			out = append(out, desktop.Action{Type: "mouseup", Button: intPtr(b.button)})
+		}
+	}
+	m.keys, m.buttons = map[uint32]bool{}, 0
+	return validActions(out)
+}
+
+// validActions drops anything the action validator would reject (e.g.
+// control characters in typed text) rather than fail the whole batch.
+func validActions(actions []desktop.Action) []desktop.Action {
+	valid := actions[:0]
+	for _, a := range actions {
+		if desktop.ValidateAction(a) == nil {
+			valid = append(valid, a)
+		}
+	}
+	return valid
+}
+
+// keysymKey renders an X11 keysym as an xdotool key name.
+func keysymKey(sym uint32) string {
+	return fmt.Sprintf("0x%x", sym)
+}
+
+// splitText splits s into chunks of at most n bytes on rune boundaries.
+func splitText(s string, n int) []string {
+	var out []string
+	for len(s) > n {
+		cut := n
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		out = append(out, s[:cut])
+		s = s[cut:]
+	}
+	if s != "" {
+		out = append(out, s)
+	}
+	return out
+}
+
+func intPtr(v int) *int { return &v }
+
+// coalesceMoves drops every absolute move that is immediately followed by
+// another absolute move. Pointer motion arrives far faster than xdotool can
+// be spawned, and only the final position of a run matters.
+func coalesceMoves(actions []desktop.Action) []desktop.Action {
+	out := actions[:0:0]
+	for i, a := range actions {
+		if a.Type == "move" && a.Mode == "" && i+1 < len(actions) &&
+			actions[i+1].Type == "move" && actions[i+1].Mode == "" {
+			continue
+		}
+		out = append(out, a)
+	}
+	return out
+}
+
+// executeActions, readClipboard and writeClipboard are variables so tests can
+// observe input and clipboard traffic without an X display.
+var (
+	executeActions = desktop.ExecuteActions
+	readClipboard  = platform.ReadClipboardContext
+	writeClipboard = platform.CopyToClipboardContext
+)
+
+// inputQueueSize bounds the action batches waiting for the executor.
+const inputQueueSize = 256
+
+// runInputExecutor executes queued action batches in order until ctx ends.
+// Batches that piled up while the previous one ran are merged so a burst of
+// pointer motion collapses to its final position.
+func runInputExecutor(ctx context.Context, queue <-chan []desktop.Action, logger Logger) {
+	for {
+		var batch []desktop.Action
+		select {
+		case <-ctx.Done():
+			return
+		case batch = <-queue:
+		}
+	drain:
+		for {
+			select {
+			case more := <-queue:
+				batch = append(batch, more...)
+			default:
+				break drain
+			}
+		}
+		batch = coalesceMoves(batch)
+		if len(batch) == 0 {
+			continue
+		}
+		if err := executeActions(ctx, batch); err != nil && ctx.Err() == nil {
+			logger.Printf("input: %v", err)
+		}
+	}
+}
+
+// inputReleaseTimeout bounds releasing held input when a stream ends.
+const inputReleaseTimeout = 5 * time.Second
+
+// clipboardTimeout bounds one read or write of the node clipboard. The tool
+// is killed past it, e.g. when xclip cannot reach the display.
+const clipboardTimeout = 5 * time.Second
+
+// runClipboardWriter writes viewer clipboard text to the node until ctx ends.
+// It runs off the reader goroutine so a slow clipboard tool never stalls
+// reads; the reader keeps only the latest pending text in queue.
+func runClipboardWriter(ctx context.Context, clip *clipboardWatcher, queue <-chan string, logger Logger) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case text := <-queue:
+			if err := clip.set(ctx, text); err != nil && ctx.Err() == nil {
+				logger.Printf("clipboard: %v", err)
+			}
+		}
+	}
+}
+
+// clipboardPollInterval is how often the node clipboard is checked for
+// changes to forward to a subscribed viewer.
+const clipboardPollInterval = time.Second
+
+// clipboardWatcher forwards node clipboard changes to a viewer. It remembers
+// the last text either side set so a viewer's own paste is not echoed back.
+type clipboardWatcher struct {
+	mu   sync.Mutex
+	last string
+}
+
+// set writes text to the node clipboard on behalf of the viewer, giving up
+// after clipboardTimeout.
+func (w *clipboardWatcher) set(ctx context.Context, text string) error {
+	w.mu.Lock()
+	w.last = text
+	w.mu.Unlock()
+	ctx, cancel := context.WithTimeout(ctx, clipboardTimeout)
+	defer cancel()
+	return writeClipboard(ctx, text)
+}
+
+// poll hands the clipboard text to deliver when it changed since the last
+// delivery, giving up on the read after clipboardTimeout. The text only
+// counts as sent once deliver accepts it, so a change dropped because the
+// viewer is behind is offered again on the next poll. deliver must not
+// block; it runs under the watcher's lock so a viewer paste cannot slip in
+// between the send and the bookkeeping.
+func (w *clipboardWatcher) poll(ctx context.Context, deliver func(text string) bool) bool {
+	ctx, cancel := context.WithTimeout(ctx, clipboardTimeout)
+	defer cancel()
+	text, err := readClipboard(ctx)
+	if err != nil {
+		return false
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if text == w.last {
+		return false
+	}
+	if len(text) > maxClipboardBytes || strings.TrimSpace(text) == "" {
+		// Never sendable: remember it so it is not re-checked every tick.
+		w.last = text
+		return false
+	}
+	if !deliver(text) {
+		return false
+	}
+	w.last = text
+	return true
+}
@@ -0,0 +1,311 @@
+package deskstream
+
+import (
+	"context"
+	"encoding/binary"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aceteam-ai/citadel-cli/internal/desktop"
+)
+
+func pointerMsg(x, y int, buttons byte, wheel int8) []byte {
+	b := []byte{MsgPointer, 0, 0, 0, 0, buttons, byte(wheel)}
+	binary.BigEndian.PutUint16(b[1:3], uint16(x))
+	binary.BigEndian.PutUint16(b[3:5], uint16(y))
+	return b
+}
+
+func keyMsg(down bool, keysym uint32) []byte {
+	b := []byte{MsgKey, 0, 0, 0, 0, 0}
+	if down {
+		b[1] = 1
+	}
+	binary.BigEndian.PutUint32(b[2:6], keysym)
+	return b
+}
+
+func TestParseBinaryMessage(t *testing.T) {
+	ev, err := parseBinaryMessage(pointerMsg(640, 360, 0x01, -3))
+	if err != nil {
+		t.Fatalf("pointer: %v", err)
+	}
+	if ev.X != 640 || ev.Y != 360 || ev.Buttons != 0x01 || ev.Wheel != -3 {
+		t.Errorf("pointer = %+v", ev)
+	}
+
+	ev, err = parseBinaryMessage(keyMsg(true, 0xff0d))
+	if err != nil {
+		t.Fatalf("key: %v", err)
+	}
+	if !ev.Down || ev.Keysym != 0xff0d {
+		t.Errorf("key = %+v", ev)
+	}
+
+	stats := make([]byte, 15)
+	stats[0] = MsgStats
+	binary.BigEndian.PutUint32(stats[1:5], 1000)
+	binary.BigEndian.PutUint32(stats[5:9], 500000)
+	binary.BigEndian.PutUint16(stats[9:11], 28)
+	binary.BigEndian.PutUint16(stats[11:13], 2)
+	binary.BigEndian.PutUint16(stats[13:15], 7)
+	ev, err = parseBinaryMessage(stats)
+	if err != nil {
+		t.Fatalf("stats: %v", err)
+	}
+	want := ViewerStats{Interval: time.Second, BytesReceived: 500000, FramesDecoded: 28, FramesDropped: 2, DecodeTime: 7 * time.Millisecond}
+	if ev.Stats != want {
+		t.Errorf("stats = %+v, want %+v", ev.Stats, want)
+	}
+
+	for name, bad := range map[string][]byte{
+		"empty":         {},
+		"unknown":       {0x7f},
+		"short pointer": {MsgPointer, 1, 2},
+		"bad utf8":      {MsgText, 0xff, 0xfe},
+		"long text":     append([]byte{MsgText}, strings.Repeat("a", maxTypeBytes+1)...),
+	} {
+		if _, err := parseBinaryMessage(bad); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+	// The same length is a fine clipboard paste.
+	if _, err := parseBinaryMessage(append([]byte{MsgClipboard}, strings.Repeat("a", maxTypeBytes+1)...)); err != nil {
+		t.Errorf("clipboard: %v", err)
+	}
+}
+
+func TestInputMapperPointerTransitions(t *testing.T) {
+	m := newInputMapper(1920, 1080)
+
+	// Press left at (100, 200): move then mousedown.
+	ev, _ := parseBinaryMessage(pointerMsg(100, 200, 0x01, 0))
+	got := m.actions(ev)
+	if len(got) != 2 || got[0].Type != "move" || got[1].Type != "mousedown" || *got[1].Button != 1 {
+		t.Fatalf("press = %+v", got)
+	}
+
+	// Same position, release: only mouseup.
+	ev, _ = parseBinaryMessage(pointerMsg(100, 200, 0x00, 0))
+	got = m.actions(ev)
+	if len(got) != 1 || got[0].Type != "mouseup" {
+		t.Fatalf("release = %+v", got)
+	}
+
+	// Wheel beyond the action range is clamped, not dropped.
+	ev, _ = parseBinaryMessage(pointerMsg(100, 200, 0x00, 127))
+	got = m.actions(ev)
+	if len(got) != 1 || got[0].Type != "scroll" || *got[0].Delta != 100 {
+		t.Fatalf("scroll = %+v", got)
+	}
+}
+
+func TestInputMapperScalesToCapture(t *testing.T) {
+	m := newInputMapper(1920, 1080)
+	m.setStreamSize(960, 540)
+	ev, _ := parseBinaryMessage(pointerMsg(480, 270, 0, 0))
+	got := m.actions(ev)
+	if len(got) != 1 || *got[0].X != 960 || *got[0].Y != 540 {
+		t.Fatalf("scaled move = %+v, want (960, 540)", got)
+	}
+}
+
+func TestInputMapperKeysAndText(t *testing.T) {
+	m := newInputMapper(1280, 720)
+	ev, _ := parseBinaryMessage(keyMsg(true, 0xffe1))
+	got := m.actions(ev)
+	if len(got) != 1 || got[0].Type != "keydown" || got[0].Key != "0xffe1" {
+		t.Fatalf("keydown = %+v", got)
+	}
+
+	long := strings.Repeat("é", maxTypeChunk) // 2 bytes per rune
+	got = m.actions(ClientEvent{Kind: MsgText, Text: long})
+	if len(got) != 2 {
+		t.Fatalf("long text split into %d actions, want 2", len(got))
+	}
+	for _, a := range got {
+		if len(a.Text) > maxTypeChunk {
+			t.Errorf("chunk of %d bytes exceeds %d", len(a.Text), maxTypeChunk)
+		}
+	}
+
+	// Control characters fail validation and are dropped.
+	if got := m.actions(ClientEvent{Kind: MsgText, Text: "\x01"}); len(got) != 0 {
+		t.Errorf("control text produced %+v", got)
+	}
+}
+
+func TestInputMapperReleasesHeldInput(t *testing.T) {
+	m := newInputMapper(1280, 720)
+	for _, msg := range [][]byte{
+		keyMsg(true, 0xffe3),        // Ctrl down
+		keyMsg(true, 0xffe1),        // Shift down
+		keyMsg(true, 0x61),          // a down
+		keyMsg(false, 0x61),         // a up
+		pointerMsg(10, 10, 0x01, 0), // left button down: a drag in progress
+	} {
+		ev, _ := parseBinaryMessage(msg)
+		m.actions(ev)
+	}
+
+	got := m.release()
+	want := []desktop.Action{
+		{Type: "keyup", Key: "0xffe1"},
+		{Type: "keyup", Key: "0xffe3"},
+		{Type: "mouseup", Button: intPtr(1)},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("release = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i].Type != want[i].Type || got[i].Key != want[i].Key ||
+			(want[i].Button != nil && (got[i].Button == nil || *got[i].Button != *want[i].Button)) {
+			t.Errorf("release[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	ev, _ := parseBinaryMessage(keyMsg(true, 0xffe9))
+	if got := m.actions(ev); len(got) != 0 {
+		t.Errorf("event after release mapped to %+v", got)
+	}
+	if got := m.release(); len(got) != 0 {
+		t.Errorf("second release = %+v, want nothing held", got)
+	}
+}
+
+func TestCoalesceMoves(t *testing.T) {
+	in := []desktop.Action{
+		{Type: "move", X: intPtr(1), Y: intPtr(1)},
+		{Type: "move", X: intPtr(2), Y: intPtr(2)},
+		{Type: "mousedown"},
+		{Type: "move", X: intPtr(3), Y: intPtr(3)},
+		{Type: "move", X: intPtr(4), Y: intPtr(4)},
+		{Type: "mouseup"},
+	}
+	out := coalesceMoves(in)
+	if len(out) != 4 {
+		t.Fatalf("got %d actions, want 4: %+v", len(out), out)
+	}
+	if *out[0].X != 2 || *out[2].X != 4 {
+		t.Errorf("kept moves at x=%d and x=%d, want the last of each run (2, 4)", *out[0].X, *out[2].X)
+	}
+}
+
+func TestRunInputExecutor(t *testing.T) {
+	var mu sync.Mutex
+	var executed []desktop.Action
+	orig := executeActions
+	executeActions = func(_ context.Context, a []desktop.Action) error {
+		mu.Lock()
+		executed = append(executed, a...)
+		mu.Unlock()
+		return nil
+	}
+	defer func() { executeActions = orig }()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	queue := make(chan []desktop.Action, 4)
+	queue <- []desktop.Action{{Type: "key", Key: "a"}}
+	go runInputExecutor(ctx, queue, &noOpLogger{})
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		mu.Lock()
+		n := len(executed)
+		mu.Unlock()
+		if n == 1 {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("queued action was not executed")
+}
+
+func TestClipboardWatcherSuppressesEcho(t *testing.T) {
+	var board string
+	origRead, origWrite := readClipboard, writeClipboard
+	readClipboard = func(context.Context) (string, error) { return board, nil }
+	writeClipboard = func(_ context.Context, s string) error { board = s; return nil }
+	defer func() { readClipboard, writeClipboard = origRead, origWrite }()
+
+	var sent []string
+	deliver := func(text string) bool { sent = append(sent, text); return true }
+	w := &clipboardWatcher{}
+	if err := w.set(context.Background(), "from viewer"); err != nil {
+		t.Fatalf("set: %v", err)
+	}
+	if w.poll(context.Background(), deliver) {
+		t.Error("viewer's own paste should not be echoed back")
+	}
+	board = "copied on node"
+	if !w.poll(context.Background(), deliver) || len(sent) != 1 || sent[0] != "copied on node" {
+		t.Errorf("sent %q, want the node change", sent)
+	}
+	if w.poll(context.Background(), deliver) {
+		t.Error("unchanged clipboard should not be re-sent")
+	}
+}
+
+func TestClipboardWatcherRetriesDroppedChange(t *testing.T) {
+	orig := readClipboard
+	readClipboard = func(context.Context) (string, error) { return "copied on node", nil }
+	defer func() { readClipboard = orig }()
+
+	w := &clipboardWatcher{}
+	if w.poll(context.Background(), func(string) bool { return false }) {
+		t.Fatal("a refused send reported delivery")
+	}
+	var got string
+	if !w.poll(context.Background(), func(text string) bool { got = text; return true }) || got != "copied on node" {
+		t.Errorf("retry sent %q, want the dropped change", got)
+	}
+}
+
+func TestClipboardWatcherBoundsReads(t *testing.T) {
+	orig := readClipboard
+	readClipboard = func(ctx context.Context) (string, error) {
+		deadline, ok := ctx.Deadline()
+		if !ok || time.Until(deadline) > clipboardTimeout {
+			t.Errorf("clipboard read deadline = %v, %v; want within %s", deadline, ok, clipboardTimeout)
+		}
+		return "", ctx.Err()
+	}
+	defer func() { readClipboard = orig }()
+
+	if (&clipboardWatcher{}).poll(context.Background(), func(string) bool { return true }) {
+		t.Error("a failed read reported a change")
+	}
+}
+
+func TestRunClipboardWriterBoundsSlowWrites(t *testing.T) {
+	written := make(chan string, 2)
+	orig := writeClipboard
+	writeClipboard = func(ctx context.Context, s string) error {
+		if s == "stuck" {
+			<-ctx.Done() // a clipboard tool that never returns
+			return ctx.Err()
+		}
+		written <- s
+		return nil
+	}
+	defer func() { writeClipboard = orig }()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	queue := make(chan string, 1)
+	go runClipboardWriter(ctx, &clipboardWatcher{}, queue, &noOpLogger{})
+
+	queue <- "stuck"
+	queue <- "next"
+	select {
+	case got := <-written:
+		if got != "next" {
+			t.Errorf("wrote %q, want next", got)
+		}
+	case <-time.After(clipboardTimeout + 2*time.Second):
+		t.Fatal("a stuck write was not abandoned at the timeout")
+	}
+}
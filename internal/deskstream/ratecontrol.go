@@ -0,0 +1,177 @@
+package deskstream
+
+import "time"
+
+const (
+	// minBitrateKbps / maxBitrateKbps bound the adaptive encoder bitrate.
+	minBitrateKbps = 300
+	maxBitrateKbps = 20000
+
+	// rateChangeCooldown is the minimum time between encoder reconfigurations.
+	// Every change restarts ffmpeg (a keyframe and a brief stall), so the
+	// controller must not oscillate faster than a viewer can notice the gain.
+	rateChangeCooldown = 3 * time.Second
+
+	// healthyReportsToRaise is how many consecutive clean receive reports are
+	// needed before the controller probes upward.
+	healthyReportsToRaise = 5
+
+	// minSentForBacklog is the smallest per-interval send volume at which a
+	// shortfall in received bytes is treated as a backlog rather than noise.
+	minSentForBacklog = 64 * 1024
+)
+
+// streamScales are the output resolutions the controller steps through, as a
+// fraction of the captured screen. Bitrate is adjusted first; resolution only
+// drops once the bitrate floor for the current scale is reached.
+var streamScales = []float64{1, 0.75, 0.5}
+
+// defaultBitrateKbps estimates a bitrate that gives clean desktop content at
+// the given size and frame rate (about 4 Mbit/s for 1080p30).
+func defaultBitrateKbps(width, height, fps int) int {
+	if fps <= 0 {
+		fps = 15
+	}
+	return clampBitrate(width * height * fps / 15000)
+}
+
+func clampBitrate(kbps int) int {
+	if kbps < minBitrateKbps {
+		return minBitrateKbps
+	}
+	if kbps > maxBitrateKbps {
+		return maxBitrateKbps
+	}
+	return kbps
+}
+
+// rateController turns viewer receive reports into encoder bitrate and output
+// resolution. It backs off multiplicatively when the viewer drops frames,
+// falls behind the bytes sent, or cannot decode at the frame rate, and probes
+// upward slowly after a run of clean reports.
+type rateController struct {
+	captureW, captureH int
+	fps                int
+
+	bitrateKbps int
+	scaleIdx    int
+	healthy     int
+	lastChange  time.Time
+
+	now func() time.Time
+}
+
+func newRateController(captureW, captureH, fps int) *rateController {
+	return &rateController{
+		captureW:    captureW,
+		captureH:    captureH,
+		fps:         fps,
+		bitrateKbps: defaultBitrateKbps(captureW, captureH, fps),
+		now:         time.Now,
+	}
+}
+
+// streamSize returns the output resolution at the current scale, rounded
+// down to even dimensions as H.264 4:2:0 requires.
+func (c *rateController) streamSize() (int, int) {
+	s := streamScales[c.scaleIdx]
+	w := int(float64(c.captureW)*s) &^ 1
+	h := int(float64(c.captureH)*s) &^ 1
+	return w, h
+}
+
+// bitrateBounds returns the bitrate range that suits the current scale.
+func (c *rateController) bitrateBounds() (floor, ceiling int) {
+	w, h := c.streamSize()
+	base := defaultBitrateKbps(w, h, c.fps)
+	return clampBitrate(base / 4), clampBitrate(base * 3 / 2)
+}
+
+// observe feeds one receive report; sentBytes is what the server wrote during
+// roughly the same interval. It reports whether the target changed, in which
+// case the caller reconfigures the encoder.
+func (c *rateController) observe(st ViewerStats, sentBytes int) bool {
+	frames := st.FramesDecoded + st.FramesDropped
+	dropping := frames > 0 && st.FramesDropped*10 > frames
+	backlog := sentBytes >= minSentForBacklog && st.BytesReceived*5 < sentBytes*4
+	frameBudget := time.Second / time.Duration(max(c.fps, 1))
+	slowDecode := st.DecodeTime > frameBudget
+
+	if !dropping && !backlog && !slowDecode {
+		c.healthy++
+		if c.healthy < healthyReportsToRaise || !c.cooledDown() {
+			return false
+		}
+		return c.raise()
+	}
+
+	c.healthy = 0
+	if !c.cooledDown() {
+		return false
+	}
+	if slowDecode {
+		// The viewer's decoder, not the link, is the bottleneck: fewer bits
+		// at the same size would not help, fewer pixels will.
+		return c.stepScale(1)
+	}
+	return c.lower()
+}
+
+func (c *rateController) cooledDown() bool {
+	return c.lastChange.IsZero() || c.now().Sub(c.lastChange) >= rateChangeCooldown
+}
+
+func (c *rateController) lower() bool {
+	floor, _ := c.bitrateBounds()
+	next := c.bitrateKbps * 7 / 10
+	if next < floor {
+		if c.stepScale(1) {
+			return true
+		}
+		next = floor
+	}
+	if next == c.bitrateKbps {
+		return false
+	}
+	c.bitrateKbps = next
+	c.changed()
+	return true
+}
+
+func (c *rateController) raise() bool {
+	_, ceiling := c.bitrateBounds()
+	if c.bitrateKbps >= ceiling {
+		return c.stepScale(-1)
+	}
+	next := c.bitrateKbps * 115 / 100
+	if next > ceiling {
+		next = ceiling
+	}
+	c.bitrateKbps = next
+	c.changed()
+	return true
+}
+
+// stepScale moves one step along streamScales (+1 smaller, -1 larger) and
+// carries the bitrate into the new scale's range.
+func (c *rateController) stepScale(dir int) bool {
+	idx := c.scaleIdx + dir
+	if idx < 0 || idx >= len(streamScales) {
+		return false
+	}
+	c.scaleIdx = idx
+	floor, ceiling := c.bitrateBounds()
+	if c.bitrateKbps < floor {
+		c.bitrateKbps = floor
+	}
+	if c.bitrateKbps > ceiling {
+		c.bitrateKbps = ceiling
+	}
+	c.changed()
+	return true
+}
+
+func (c *rateController) changed() {
+	c.healthy = 0
+	c.lastChange = c.now()
+}
@@ -0,0 +1,105 @@
+package deskstream
+
+import (
+	"testing"
+	"time"
+)
+
+// fakeClock lets tests step past the reconfiguration cooldown.
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time { return c.t }
+
+func newTestController() (*rateController, *fakeClock) {
+	clock := &fakeClock{t: time.Unix(1_700_000_000, 0)}
+	rc := newRateController(1920, 1080, 30)
+	rc.now = clock.now
+	return rc, clock
+}
+
+var cleanReport = ViewerStats{Interval: time.Second, BytesReceived: 500000, FramesDecoded: 30, DecodeTime: 5 * time.Millisecond}
+
+func TestDefaultBitrateKbps(t *testing.T) {
+	if got := defaultBitrateKbps(1920, 1080, 30); got < 3500 || got > 4500 {
+		t.Errorf("1080p30 = %d kbit/s, want ~4000", got)
+	}
+	if got := defaultBitrateKbps(16, 16, 1); got != minBitrateKbps {
+		t.Errorf("tiny stream = %d, want floor %d", got, minBitrateKbps)
+	}
+	if got := defaultBitrateKbps(7680, 4320, 60); got != maxBitrateKbps {
+		t.Errorf("8K60 = %d, want ceiling %d", got, maxBitrateKbps)
+	}
+}
+
+func TestRateControllerBacksOffOnDrops(t *testing.T) {
+	rc, _ := newTestController()
+	start := rc.bitrateKbps
+	lossy := ViewerStats{Interval: time.Second, FramesDecoded: 20, FramesDropped: 10}
+	if !rc.observe(lossy, 0) {
+		t.Fatal("dropping frames should lower the target")
+	}
+	if rc.bitrateKbps >= start {
+		t.Errorf("bitrate %d, want below %d", rc.bitrateKbps, start)
+	}
+
+	// A second bad report inside the cooldown changes nothing.
+	before := rc.bitrateKbps
+	if rc.observe(lossy, 0) || rc.bitrateKbps != before {
+		t.Error("changes inside the cooldown should be suppressed")
+	}
+}
+
+func TestRateControllerBacklog(t *testing.T) {
+	rc, _ := newTestController()
+	// Viewer received half of what was sent.
+	if !rc.observe(ViewerStats{Interval: time.Second, BytesReceived: 100000, FramesDecoded: 30}, 200000) {
+		t.Error("a receive backlog should lower the target")
+	}
+}
+
+func TestRateControllerSlowDecodeDropsResolution(t *testing.T) {
+	rc, _ := newTestController()
+	slow := ViewerStats{Interval: time.Second, FramesDecoded: 30, DecodeTime: 80 * time.Millisecond}
+	if !rc.observe(slow, 0) {
+		t.Fatal("slow decode should reconfigure")
+	}
+	if w, h := rc.streamSize(); w != 1440 || h != 810 {
+		t.Errorf("stream = %dx%d, want 1440x810", w, h)
+	}
+}
+
+func TestRateControllerStepsDownScaleAtFloor(t *testing.T) {
+	rc, clock := newTestController()
+	lossy := ViewerStats{Interval: time.Second, FramesDecoded: 10, FramesDropped: 10}
+	for i := 0; i < 20 && rc.scaleIdx == 0; i++ {
+		clock.t = clock.t.Add(rateChangeCooldown)
+		rc.observe(lossy, 0)
+	}
+	if rc.scaleIdx == 0 {
+		t.Fatal("persistent loss should eventually lower the resolution")
+	}
+	floor, ceiling := rc.bitrateBounds()
+	if rc.bitrateKbps < floor || rc.bitrateKbps > ceiling {
+		t.Errorf("bitrate %d outside the new scale's range [%d, %d]", rc.bitrateKbps, floor, ceiling)
+	}
+}
+
+func TestRateControllerRaisesAfterCleanRun(t *testing.T) {
+	rc, clock := newTestController()
+	rc.scaleIdx = 1
+	floor, _ := rc.bitrateBounds()
+	rc.bitrateKbps = floor
+
+	for i := 0; i < healthyReportsToRaise-1; i++ {
+		if rc.observe(cleanReport, 0) {
+			t.Fatalf("raised after only %d clean reports", i+1)
+		}
+	}
+	clock.t = clock.t.Add(rateChangeCooldown)
+	if !rc.observe(cleanReport, 0) {
+		t.Fatal("expected a raise after a clean run")
+	}
+	if rc.bitrateKbps <= floor {
+		t.Errorf("bitrate %d, want above %d", rc.bitrateKbps, floor)
+	}
+}
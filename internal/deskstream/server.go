@@ -17,12 +17,14 @@ import (
 // Server serves the node desktop as an H.264 stream over a binary WebSocket
 // (citadel-cli#338). It mirrors the VNC server's mesh exposure: it listens on
 // localhost and on any tsnet VPN listeners attached via AddListener, with no
-// application-layer auth (the tsnet mesh is the trust boundary). Input is
-// unchanged and not handled here; this is VIDEO-ONLY.
+// application-layer auth (the tsnet mesh is the trust boundary). Viewers send
+// pointer, keyboard and clipboard input back on the same socket, so a single
+// stream is a complete remote desktop.
 //
 // Each connection runs its OWN ffmpeg encoder so that a new viewer always
-// starts at an IDR and an on-demand keyframe (requestKeyframe) can be served by
-// restarting that viewer's encoder without disturbing others.
+// starts at an IDR, an on-demand keyframe (requestKeyframe) can be served by
+// restarting that viewer's encoder without disturbing others, and each
+// viewer's bitrate and resolution adapt to its own link.
 type Server struct {
 	host string
 	port int
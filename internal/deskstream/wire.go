@@ -39,17 +39,45 @@ func NewInitMessage(width, height, fps, keyframeInterval int) InitMessage {
 // Marshal returns the JSON bytes for the init TEXT frame.
 func (m InitMessage) Marshal() ([]byte, error) { return json.Marshal(m) }
 
-// ClientMessage is a TEXT frame sent by a client to the server. The only
-// recognized type is "requestKeyframe", which forces the server to emit an IDR
-// (with SPS+PPS) as soon as possible.
+// ReconfigureMessage is a TEXT frame the server sends when the adaptive rate
+// controller changes the stream. It is only sent to viewers that report
+// receive stats, so video-only clients never see one. A change of size takes
+// effect at the next keyframe, which follows immediately.
+type ReconfigureMessage struct {
+	Type        string `json:"type"`    // always "reconfigure"
+	Width       int    `json:"width"`   // stream width in pixels
+	Height      int    `json:"height"`  // stream height in pixels
+	BitrateKbps int    `json:"bitrate"` // target bitrate in kbit/s
+}
+
+// NewReconfigureMessage builds a reconfigure message.
+func NewReconfigureMessage(width, height, bitrateKbps int) ReconfigureMessage {
+	return ReconfigureMessage{Type: "reconfigure", Width: width, Height: height, BitrateKbps: bitrateKbps}
+}
+
+// ClipboardMessage is a TEXT frame carrying the node clipboard to a viewer
+// that subscribed with {"type":"subscribeClipboard"}.
+type ClipboardMessage struct {
+	Type string `json:"type"` // always "clipboard"
+	Text string `json:"text"`
+}
+
+// ClientMessage is a TEXT frame sent by a client to the server. Recognized
+// types are "requestKeyframe", which forces the server to emit an IDR (with
+// SPS+PPS) as soon as possible, and "subscribeClipboard", which opts in to
+// ClipboardMessage frames. Input travels in BINARY frames (see input.go).
 type ClientMessage struct {
-	Type string `json:"type"` // "requestKeyframe"
+	Type string `json:"type"` // "requestKeyframe" or "subscribeClipboard"
 }
 
 // ClientMsgRequestKeyframe is the value of ClientMessage.Type that requests an
 // immediate keyframe.
 const ClientMsgRequestKeyframe = "requestKeyframe"
 
+// ClientMsgSubscribeClipboard is the value of ClientMessage.Type that asks the
+// server to forward node clipboard changes.
+const ClientMsgSubscribeClipboard = "subscribeClipboard"
+
 // parseClientMessage decodes a client TEXT frame. It returns the message type,
 // or "" if the frame is not valid JSON or carries no type.
 func parseClientMessage(data []byte) string {
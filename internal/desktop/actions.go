@@ -45,6 +45,8 @@ var allowedActionTypes = map[string]bool{
 	"scroll":    true,
 	"mousedown": true, // press a button without releasing (drag start; issue #4180)
 	"mouseup":   true, // release a held button (drag end; issue #4180)
+	"keydown":   true, // press a key without releasing (streamed keyboard input)
+	"keyup":     true, // release a held key
 }
 
 var safeKeyPattern = regexp.MustCompile(`^[a-zA-Z0-9_+\- ]+$`)
@@ -63,16 +65,18 @@ func ParseActions(data []byte) ([]Action, error) {
 		return nil, fmt.Errorf("too many actions (max 100, got %d)", len(actions))
 	}
 	for i, a := range actions {
-		if err := validateAction(a); err != nil {
+		if err := ValidateAction(a); err != nil {
 			return nil, fmt.Errorf("action[%d]: %w", i, err)
 		}
 	}
 	return actions, nil
 }
 
-func validateAction(a Action) error {
+// ValidateAction checks a single action against the same rules ParseActions
+// applies, for callers that build actions directly rather than from JSON.
+func ValidateAction(a Action) error {
 	if !allowedActionTypes[a.Type] {
-		return fmt.Errorf("unknown action type %q (allowed: move, click, type, key, scroll, mousedown, mouseup)", a.Type)
+		return fmt.Errorf("unknown action type %q (allowed: move, click, type, key, keydown, keyup, scroll, mousedown, mouseup)", a.Type)
 	}
 
 	switch a.Type {
@@ -115,7 +119,7 @@ func validateAction(a Action) error {
 		if !safeTextPattern.MatchString(a.Text) {
 			return fmt.Errorf("text contains invalid characters")
 		}
-	case "key":
+	case "key", "keydown", "keyup":
 		if a.Key == "" {
 			return fmt.Errorf("key requires non-empty key name")
 		}
@@ -174,6 +178,12 @@ func ActionToXdotoolArgs(a Action) (string, []string, error) {
 		return "type", []string{"--clearmodifiers", "--", a.Text}, nil
 	case "key":
 		return "key", []string{"--clearmodifiers", a.Key}, nil
+	case "keydown":
+		// No --clearmodifiers: a streamed key press must compose with the
+		// modifiers the viewer is already holding.
+		return "keydown", []string{a.Key}, nil
+	case "keyup":
+		return "keyup", []string{a.Key}, nil
 	case "scroll":
 		button := 5
 		clicks := -*a.Delta
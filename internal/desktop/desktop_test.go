@@ -134,6 +134,8 @@ func TestParseActions(t *testing.T) {
 		{"scroll", `[{"type":"scroll","delta":-3}]`, 1, false},
 		{"mousedown", `[{"type":"mousedown","button":1}]`, 1, false},
 		{"mouseup", `[{"type":"mouseup","button":1}]`, 1, false},
+		{"keydown", `[{"type":"keydown","key":"0xffe1"},{"type":"keyup","key":"0xffe1"}]`, 2, false},
+		{"keyup missing key", `[{"type":"keyup"}]`, 0, true},
 		{"mousedown no button", `[{"type":"mousedown"}]`, 1, false},
 		{"drag sequence", `[{"type":"move","x":10,"y":20},{"type":"mousedown","button":1},{"type":"move","x":90,"y":80},{"type":"mouseup","button":1}]`, 4, false},
 		{"mousedown invalid button", `[{"type":"mousedown","button":9}]`, 0, true},
@@ -258,6 +260,18 @@ func TestActionToXdotoolArgs(t *testing.T) {
 			wantCmd:  "mouseup",
 			wantArgs: []string{"1"},
 		},
+		{
+			name:     "keydown keeps modifiers",
+			action:   Action{Type: "keydown", Key: "0xffe1"},
+			wantCmd:  "keydown",
+			wantArgs: []string{"0xffe1"},
+		},
+		{
+			name:     "keyup",
+			action:   Action{Type: "keyup", Key: "a"},
+			wantCmd:  "keyup",
+			wantArgs: []string{"a"},
+		},
 	}
 
 	for _, tt := range tests {
@@ -0,0 +1,59 @@
+package desktop
+
+import "time"
+
+// bandwidthMinSample is the smallest update used to measure throughput.
+// Smaller updates are dominated by round-trip latency and client decode time
+// and would make a fast link look slow.
+const bandwidthMinSample = 16 * 1024
+
+// bandwidthEWMAAlpha weights each new sample in the running estimate.
+const bandwidthEWMAAlpha = 0.3
+
+// bandwidthQualityTiers caps the Tight JPEG quality level by the estimated
+// link throughput (bytes per second), checked top to bottom.
+var bandwidthQualityTiers = []struct {
+	minBytesPerSec float64
+	level          int
+}{
+	{25e6 / 8, 9},
+	{10e6 / 8, 7},
+	{4e6 / 8, 5},
+	{1.5e6 / 8, 3},
+	{0, 1},
+}
+
+// bandwidthEstimator tracks per-connection throughput. RFB is pull-based: a
+// client asks for the next update only after it has received and decoded the
+// previous one, so the time from writing an update to the next
+// FramebufferUpdateRequest bounds how fast the link drains.
+type bandwidthEstimator struct {
+	bytesPerSec float64 // 0 until the first sample
+}
+
+// observe records that n bytes took d to deliver.
+func (b *bandwidthEstimator) observe(n int, d time.Duration) {
+	if n < bandwidthMinSample || d <= 0 {
+		return
+	}
+	rate := float64(n) / d.Seconds()
+	if b.bytesPerSec == 0 {
+		b.bytesPerSec = rate
+		return
+	}
+	b.bytesPerSec = bandwidthEWMAAlpha*rate + (1-bandwidthEWMAAlpha)*b.bytesPerSec
+}
+
+// qualityCap returns the highest JPEG quality level the link sustains. With
+// no samples yet it returns 9, leaving the client's requested level in force.
+func (b *bandwidthEstimator) qualityCap() int {
+	if b.bytesPerSec == 0 {
+		return 9
+	}
+	for _, t := range bandwidthQualityTiers {
+		if b.bytesPerSec >= t.minBytesPerSec {
+			return t.level
+		}
+	}
+	return 0
+}
@@ -0,0 +1,129 @@
+package desktop
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/maphash"
+	"image"
+)
+
+// scrollMinRows is the smallest run of shifted rows worth sending as a
+// CopyRect. Shorter matches are usually coincidences (repeated toolbar rows,
+// blank lines) and are cheaper to leave to the dirty-tile path.
+const scrollMinRows = 32
+
+// copyRect is a CopyRect rectangle: the client copies the area of its own
+// framebuffer at (srcX, srcY) into dst.
+type copyRect struct {
+	dst        image.Rectangle
+	srcX, srcY int
+}
+
+// encodeCopyRect appends a CopyRect rectangle to buf.
+func encodeCopyRect(buf *bytes.Buffer, c copyRect) {
+	writeRawRectHeader(buf, c.dst.Min.X, c.dst.Min.Y, c.dst.Dx(), c.dst.Dy(), encodingCopyRect)
+	var src [4]byte
+	binary.BigEndian.PutUint16(src[0:2], uint16(c.srcX))
+	binary.BigEndian.PutUint16(src[2:4], uint16(c.srcY))
+	buf.Write(src[:])
+}
+
+// rowHashSeed is shared by every connection so row hashes of the same frame
+// are comparable; it only needs to be stable within the process.
+var rowHashSeed = maphash.MakeSeed()
+
+// rowHashes returns one hash per framebuffer row.
+func rowHashes(pix []byte, frameW, frameH int) []uint64 {
+	stride := frameW * 4
+	out := make([]uint64, frameH)
+	for y := 0; y < frameH; y++ {
+		out[y] = maphash.Bytes(rowHashSeed, pix[y*stride:(y+1)*stride])
+	}
+	return out
+}
+
+// detectVerticalScroll looks for a full-width band of cur that appears in prev
+// shifted vertically, which is what scrolling a maximised window or a terminal
+// produces. It returns the CopyRect that reproduces the band on the client.
+//
+// Candidate shifts are voted on by changed rows whose content exists
+// elsewhere in prev; the winning shift is then extended to the longest
+// contiguous run of matching rows. Only full-width bands are detected: a
+// scroll inside a smaller window falls through to the dirty-tile path.
+func detectVerticalScroll(prev, cur []byte, frameW, frameH int) (copyRect, bool) {
+	if prev == nil || len(prev) != len(cur) || frameH < scrollMinRows {
+		return copyRect{}, false
+	}
+	ph := rowHashes(prev, frameW, frameH)
+	ch := rowHashes(cur, frameW, frameH)
+
+	// Index prev rows by hash. Repeated rows (blank space) keep the first
+	// index, which at worst costs a vote.
+	index := make(map[uint64]int, frameH)
+	for y, h := range ph {
+		if _, ok := index[h]; !ok {
+			index[h] = y
+		}
+	}
+
+	votes := make(map[int]int)
+	for y, h := range ch {
+		if h == ph[y] {
+			continue // unchanged row, says nothing about a shift
+		}
+		if py, ok := index[h]; ok && py != y {
+			votes[y-py]++
+		}
+	}
+	dy, best := 0, 0
+	for d, v := range votes {
+		if v > best || (v == best && d < dy) {
+			dy, best = d, v
+		}
+	}
+	if best < scrollMinRows {
+		return copyRect{}, false
+	}
+
+	// Longest run of rows y where cur[y] == prev[y-dy].
+	runStart, runLen := 0, 0
+	start := -1
+	for y := 0; y <= frameH; y++ {
+		match := y < frameH && y-dy >= 0 && y-dy < frameH && ch[y] == ph[y-dy]
+		if match {
+			if start < 0 {
+				start = y
+			}
+			continue
+		}
+		if start >= 0 {
+			if y-start > runLen {
+				runStart, runLen = start, y-start
+			}
+			start = -1
+		}
+	}
+	if runLen < scrollMinRows {
+		return copyRect{}, false
+	}
+	return copyRect{
+		dst:  image.Rect(0, runStart, frameW, runStart+runLen),
+		srcX: 0,
+		srcY: runStart - dy,
+	}, true
+}
+
+// applyCopyRect returns a copy of prev with c applied, i.e. the framebuffer
+// the client holds once it has processed the CopyRect. Diffing the next frame
+// against this (rather than prev) leaves only the newly exposed rows dirty.
+func applyCopyRect(prev []byte, frameW int, c copyRect) []byte {
+	out := append([]byte(nil), prev...)
+	stride := frameW * 4
+	rowBytes := c.dst.Dx() * 4
+	for row := 0; row < c.dst.Dy(); row++ {
+		src := (c.srcY+row)*stride + c.srcX*4
+		dst := (c.dst.Min.Y+row)*stride + c.dst.Min.X*4
+		copy(out[dst:dst+rowBytes], prev[src:src+rowBytes])
+	}
+	return out
+}
@@ -0,0 +1,110 @@
+package desktop
+
+import (
+	"bytes"
+	"sync"
+)
+
+// CursorShape is a pointer image sent to clients that negotiate the Cursor
+// pseudo-encoding, letting them draw the pointer locally instead of waiting
+// for it to appear in captured frames (GDI capture does not include it).
+type CursorShape struct {
+	Width, Height int
+	// HotX and HotY are the hotspot within the image.
+	HotX, HotY int
+	// Pix holds Width*Height pixels in BGRX byte order, like CaptureResult.
+	Pix []byte
+	// Mask is a 1bpp transparency bitmask, most significant bit first, each
+	// row padded to a whole byte. A set bit marks an opaque pixel.
+	Mask []byte
+}
+
+// Equal reports whether two shapes would encode identically.
+func (c *CursorShape) Equal(o *CursorShape) bool {
+	if c == nil || o == nil {
+		return c == o
+	}
+	return c.Width == o.Width && c.Height == o.Height &&
+		c.HotX == o.HotX && c.HotY == o.HotY &&
+		bytes.Equal(c.Pix, o.Pix) && bytes.Equal(c.Mask, o.Mask)
+}
+
+// CursorCapturer is optionally implemented by a Capturer that can report the
+// current pointer shape. The VNC server re-sends the shape whenever it
+// changes. Capturers without it get DefaultCursor, sent once.
+type CursorCapturer interface {
+	CaptureCursor() (*CursorShape, error)
+}
+
+// defaultCursorArt is a standard arrow: 'X' black, '.' white, ' ' transparent.
+var defaultCursorArt = []string{
+	"X           ",
+	"XX          ",
+	"X.X         ",
+	"X..X        ",
+	"X...X       ",
+	"X....X      ",
+	"X.....X     ",
+	"X......X    ",
+	"X.......X   ",
+	"X........X  ",
+	"X.....XXXXX ",
+	"X..X..X     ",
+	"X.X X..X    ",
+	"XX  X..X    ",
+	"X    X..X   ",
+	"     X..X   ",
+	"      XX    ",
+}
+
+var (
+	defaultCursorOnce  sync.Once
+	defaultCursorShape *CursorShape
+)
+
+// DefaultCursor returns the built-in arrow cursor.
+func DefaultCursor() *CursorShape {
+	defaultCursorOnce.Do(func() {
+		defaultCursorShape = cursorFromArt(defaultCursorArt)
+	})
+	return defaultCursorShape
+}
+
+// cursorFromArt builds a cursor with its hotspot at the top-left corner.
+func cursorFromArt(art []string) *CursorShape {
+	h := len(art)
+	w := len(art[0])
+	maskStride := (w + 7) / 8
+	c := &CursorShape{
+		Width:  w,
+		Height: h,
+		Pix:    make([]byte, w*h*4),
+		Mask:   make([]byte, maskStride*h),
+	}
+	for y, line := range art {
+		for x := 0; x < w && x < len(line); x++ {
+			var v byte
+			switch line[x] {
+			case 'X':
+				v = 0x00
+			case '.':
+				v = 0xff
+			default:
+				continue
+			}
+			p := (y*w + x) * 4
+			c.Pix[p], c.Pix[p+1], c.Pix[p+2] = v, v, v
+			c.Mask[y*maskStride+x/8] |= 0x80 >> uint(x%8)
+		}
+	}
+	return c
+}
+
+// encodeCursorRect appends a Cursor pseudo-encoding rectangle to buf: the
+// header carries the hotspot as x/y, followed by the pixels in the negotiated
+// RGBX format and then the bitmask.
+func encodeCursorRect(buf *bytes.Buffer, c *CursorShape) {
+	writeRawRectHeader(buf, c.HotX, c.HotY, c.Width, c.Height, encodingPseudoCursor)
+	buf.Write(extractRect(c.Pix, c.Width, 0, 0, c.Width, c.Height))
+	buf.Write(c.Mask)
+}
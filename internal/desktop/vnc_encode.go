@@ -10,8 +10,23 @@ import (
 
 // RFB encoding numbers we care about.
 const (
-	encodingRaw  int32 = 0
-	encodingZRLE int32 = 16
+	encodingRaw      int32 = 0
+	encodingCopyRect int32 = 1
+	encodingTight    int32 = 7
+	encodingZRLE     int32 = 16
+
+	// Pseudo-encodings. These never describe pixel data; a client lists them
+	// in SetEncodings to opt in to an extension.
+	encodingPseudoDesktopSize int32 = -223
+	encodingPseudoCursor      int32 = -239
+
+	// Tight JPEG quality levels run from -32 (level 0, lowest quality) to
+	// -23 (level 9, highest). Tight zlib compression levels run from -256
+	// (level 0) to -247 (level 9).
+	encodingPseudoQualityLevel0  int32 = -32
+	encodingPseudoQualityLevel9  int32 = -23
+	encodingPseudoCompressLevel0 int32 = -256
+	encodingPseudoCompressLevel9 int32 = -247
 )
 
 // zrleTileSize is the fixed tile dimension used by the ZRLE encoding (RFC 6143
@@ -218,57 +233,196 @@ func tileChanged(prev, cur []byte, frameW, tx, ty, tw, th int) bool {
 	return false
 }
 
-// writeFramebufferUpdateRects writes a complete FramebufferUpdate message
-// containing the given rectangles, encoded with the chosen encoding (ZRLE when
-// zEnc is non-nil and the client negotiated it, else Raw). The pixel source is
-// the native-BGRX frame; extraction handles the byte-order swap.
-//
-// rects must already be clamped to the frame bounds.
-func writeFramebufferUpdateRects(w io.Writer, pix []byte, frameW int, rects []image.Rectangle, zEnc *zrleEncoder) error {
-	var buf bytes.Buffer
+// encodingPrefs is the result of parsing a client's SetEncodings list.
+type encodingPrefs struct {
+	// preferred is the first pixel encoding in the client's list that this
+	// server can produce (Tight, ZRLE or Raw). Clients list encodings in
+	// order of preference, so the first match wins. Raw when none match.
+	preferred int32
+	// copyRect, cursor and desktopSize record which extensions the client
+	// opted in to.
+	copyRect    bool
+	cursor      bool
+	desktopSize bool
+	// quality is the requested Tight JPEG quality level (0-9), or -1 when
+	// the client sent no quality pseudo-encoding. Tight stays lossless
+	// without one, which is what the Tight spec requires.
+	quality int
+	// compress is the requested Tight zlib level (0-9), or -1 for default.
+	compress int
+}
 
-	// Drop any degenerate rectangles up front so the number-of-rects header
-	// always matches the rectangles actually written (a mismatch desyncs the
-	// RFB stream).
-	valid := rects[:0:0]
-	for _, r := range rects {
-		if r.Dx() > 0 && r.Dy() > 0 {
-			valid = append(valid, r)
+// parseEncodings scans a SetEncodings list (big-endian s32 values) and
+// returns the negotiated preferences. Unknown encodings are ignored.
+func parseEncodings(encodings []byte) encodingPrefs {
+	p := encodingPrefs{preferred: encodingRaw, quality: -1, compress: -1}
+	havePreferred := false
+	for i := 0; i+4 <= len(encodings); i += 4 {
+		enc := int32(binary.BigEndian.Uint32(encodings[i : i+4]))
+		switch {
+		case enc == encodingTight || enc == encodingZRLE || enc == encodingRaw:
+			if !havePreferred {
+				p.preferred = enc
+				havePreferred = true
+			}
+		case enc == encodingCopyRect:
+			p.copyRect = true
+		case enc == encodingPseudoCursor:
+			p.cursor = true
+		case enc == encodingPseudoDesktopSize:
+			p.desktopSize = true
+		case enc >= encodingPseudoQualityLevel0 && enc <= encodingPseudoQualityLevel9:
+			p.quality = int(enc - encodingPseudoQualityLevel0)
+		case enc >= encodingPseudoCompressLevel0 && enc <= encodingPseudoCompressLevel9:
+			p.compress = int(enc - encodingPseudoCompressLevel0)
 		}
 	}
+	return p
+}
 
-	// FramebufferUpdate header: type(0) + padding(1) + number-of-rects(u16).
-	buf.WriteByte(0)
-	buf.WriteByte(0)
-	var nrects [2]byte
-	binary.BigEndian.PutUint16(nrects[:], uint16(len(valid)))
-	buf.Write(nrects[:])
+// rfbEncoder holds all per-connection encoding state: the negotiated
+// preferences, the continuous zlib streams of ZRLE and Tight (created lazily
+// on first use, then kept for the life of the connection), and the bandwidth
+// estimate that caps Tight JPEG quality.
+type rfbEncoder struct {
+	prefs encodingPrefs
+	zrle  *zrleEncoder
+	tight *tightEncoder
+	bw    bandwidthEstimator
+}
+
+func newRFBEncoder() *rfbEncoder {
+	return &rfbEncoder{prefs: encodingPrefs{preferred: encodingRaw, quality: -1, compress: -1}}
+}
+
+// Close releases the zlib streams.
+func (e *rfbEncoder) Close() {
+	if e.zrle != nil {
+		e.zrle.Close()
+	}
+	if e.tight != nil {
+		e.tight.Close()
+	}
+}
+
+// jpegQuality returns the Tight JPEG quality level to use for the next
+// update: the client's requested level, capped by what the measured link
+// bandwidth can sustain. -1 means JPEG is disabled.
+func (e *rfbEncoder) jpegQuality() int {
+	if e.prefs.quality < 0 {
+		return -1
+	}
+	if limit := e.bw.qualityCap(); limit < e.prefs.quality {
+		return limit
+	}
+	return e.prefs.quality
+}
+
+// frameUpdate is the content of one FramebufferUpdate message. Rectangles
+// are written in field order: CopyRects first (they reference the client's
+// framebuffer as it stood before this update), then the cursor shape, then
+// the pixel rectangles.
+type frameUpdate struct {
+	pix    []byte
+	frameW int
+	copies []copyRect
+	cursor *CursorShape
+	rects  []image.Rectangle
+}
+
+// writeUpdate encodes u with the negotiated encoding and writes it as a
+// single FramebufferUpdate message. It returns the number of bytes written,
+// which feeds the bandwidth estimate.
+func (e *rfbEncoder) writeUpdate(w io.Writer, u frameUpdate) (int, error) {
+	var body bytes.Buffer
+	n := 0
 
-	for _, r := range valid {
+	for _, c := range u.copies {
+		if c.dst.Dx() <= 0 || c.dst.Dy() <= 0 {
+			continue
+		}
+		encodeCopyRect(&body, c)
+		n++
+	}
+	if u.cursor != nil {
+		encodeCursorRect(&body, u.cursor)
+		n++
+	}
+
+	quality := e.jpegQuality()
+	for _, r := range u.rects {
+		// Drop degenerate rectangles so the number-of-rects header always
+		// matches the rectangles actually written (a mismatch desyncs the
+		// RFB stream).
+		if r.Dx() <= 0 || r.Dy() <= 0 {
+			continue
+		}
 		x, y := r.Min.X, r.Min.Y
 		rw, rh := r.Dx(), r.Dy()
-		if zEnc != nil {
-			if err := zEnc.encodeZRLERect(&buf, pix, frameW, x, y, rw, rh); err != nil {
-				return err
+		switch e.prefs.preferred {
+		case encodingTight:
+			if e.tight == nil {
+				e.tight = newTightEncoder(e.prefs.compress)
 			}
-		} else {
-			encodeRawRect(&buf, pix, frameW, x, y, rw, rh)
+			// Tight decoders cap rectangle width, so split wide bands; split
+			// tall ones too so each payload fits a compact length.
+			for sx := 0; sx < rw; sx += tightMaxRectWidth {
+				sw := min(tightMaxRectWidth, rw-sx)
+				rows := max(1, tightMaxRectPixels/sw)
+				for sy := 0; sy < rh; sy += rows {
+					sh := min(rows, rh-sy)
+					if err := e.tight.encodeTightRect(&body, u.pix, u.frameW, x+sx, y+sy, sw, sh, quality); err != nil {
+						return 0, err
+					}
+					n++
+				}
+			}
+		case encodingZRLE:
+			if e.zrle == nil {
+				e.zrle = newZRLEEncoder()
+			}
+			if err := e.zrle.encodeZRLERect(&body, u.pix, u.frameW, x, y, rw, rh); err != nil {
+				return 0, err
+			}
+			n++
+		default:
+			encodeRawRect(&body, u.pix, u.frameW, x, y, rw, rh)
+			n++
 		}
 	}
 
+	// FramebufferUpdate header: type(0) + padding(1) + number-of-rects(u16).
+	var buf bytes.Buffer
+	buf.Grow(4 + body.Len())
+	buf.WriteByte(0)
+	buf.WriteByte(0)
+	var nrects [2]byte
+	binary.BigEndian.PutUint16(nrects[:], uint16(n))
+	buf.Write(nrects[:])
+	buf.Write(body.Bytes())
+
+	return w.Write(buf.Bytes())
+}
+
+// writeDesktopSize sends a FramebufferUpdate carrying only a DesktopSize
+// pseudo-rectangle, telling the client the framebuffer is now width x height.
+// The framebuffer contents after a resize are undefined, so the caller must
+// follow it with a full (non-incremental) update.
+func writeDesktopSize(w io.Writer, width, height int) error {
+	var buf bytes.Buffer
+	buf.Write([]byte{0, 0, 0, 1})
+	writeRawRectHeader(&buf, 0, 0, width, height, encodingPseudoDesktopSize)
 	_, err := w.Write(buf.Bytes())
 	return err
 }
 
-// clientSupportsZRLE scans a SetEncodings list (big-endian s32 values) and
-// reports whether the client advertised ZRLE (16). Pseudo-encodings (negative
-// values) are ignored.
-func clientSupportsZRLE(encodings []byte) bool {
-	for i := 0; i+4 <= len(encodings); i += 4 {
-		enc := int32(binary.BigEndian.Uint32(encodings[i : i+4]))
-		if enc == encodingZRLE {
-			return true
+// clipRects intersects every rectangle with bounds, dropping empty results.
+func clipRects(rects []image.Rectangle, bounds image.Rectangle) []image.Rectangle {
+	out := rects[:0:0]
+	for _, r := range rects {
+		if c := r.Intersect(bounds); !c.Empty() {
+			out = append(out, c)
 		}
 	}
-	return false
+	return out
 }
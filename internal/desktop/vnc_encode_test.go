@@ -191,28 +191,22 @@ func TestZRLEContinuousStream(t *testing.T) {
 	}
 }
 
-func TestClientSupportsZRLE(t *testing.T) {
-	// Build a SetEncodings list: [Tight(7), ZRLE(16), Cursor pseudo(-239)].
-	list := make([]byte, 0, 12)
-	for _, e := range []int32{7, encodingZRLE, -239} {
-		var b [4]byte
-		binary.BigEndian.PutUint32(b[:], uint32(e))
-		list = append(list, b[:]...)
+func TestParseEncodingsZRLE(t *testing.T) {
+	// A pseudo-encoding listed first does not hide the pixel encoding.
+	p := parseEncodings(encodingList(encodingPseudoCursor, encodingZRLE, encodingRaw))
+	if p.preferred != encodingZRLE {
+		t.Errorf("preferred = %d, want ZRLE", p.preferred)
 	}
-	if !clientSupportsZRLE(list) {
-		t.Error("expected ZRLE to be detected")
+	if p := parseEncodings(encodingList(encodingRaw)); p.preferred != encodingRaw {
+		t.Errorf("Raw-only list: preferred = %d, want Raw", p.preferred)
 	}
 
-	// List without ZRLE.
-	var raw [4]byte
-	binary.BigEndian.PutUint32(raw[:], 0)
-	if clientSupportsZRLE(raw[:]) {
-		t.Error("did not expect ZRLE in a Raw-only list")
+	// Truncated trailing bytes must not panic or select ZRLE.
+	if p := parseEncodings([]byte{0, 0, 0, 16, 0, 0}); p.preferred != encodingZRLE {
+		t.Errorf("complete entry before a truncated one: preferred = %d, want ZRLE", p.preferred)
 	}
-
-	// Truncated trailing bytes must not panic or false-positive.
-	if clientSupportsZRLE([]byte{0, 0, 0}) {
-		t.Error("truncated list should not report ZRLE")
+	if p := parseEncodings([]byte{0, 0, 0}); p.preferred != encodingRaw {
+		t.Errorf("truncated list: preferred = %d, want Raw", p.preferred)
 	}
 }
 
@@ -286,22 +280,31 @@ func TestDirtyTilesCoalescesRowRun(t *testing.T) {
 	}
 }
 
-func TestWriteFramebufferUpdateRectsRawNrects(t *testing.T) {
+func TestWriteUpdateNrects(t *testing.T) {
 	w, h := 64, 64
 	pix := makeBGRXFrame(w, h)
 	rects := []image.Rectangle{
 		image.Rect(0, 0, 32, 32),
 		image.Rect(32, 0, 64, 32),
-	}
-	var buf bytes.Buffer
-	if err := writeFramebufferUpdateRects(&buf, pix, w, rects, nil); err != nil {
-		t.Fatalf("write: %v", err)
-	}
-	data := buf.Bytes()
-	if data[0] != 0 {
-		t.Errorf("message type = %d, want 0", data[0])
-	}
-	if n := binary.BigEndian.Uint16(data[2:4]); n != 2 {
-		t.Errorf("nrects = %d, want 2", n)
+		image.Rect(0, 32, 0, 64), // degenerate: dropped, not counted
+	}
+	for _, preferred := range []int32{encodingRaw, encodingZRLE} {
+		enc := newRFBEncoder()
+		enc.prefs = parseEncodings(encodingList(preferred))
+		var buf bytes.Buffer
+		if _, err := enc.writeUpdate(&buf, frameUpdate{pix: pix, frameW: w, rects: rects}); err != nil {
+			t.Fatalf("encoding %d: write: %v", preferred, err)
+		}
+		enc.Close()
+		data := buf.Bytes()
+		if data[0] != 0 {
+			t.Errorf("encoding %d: message type = %d, want 0", preferred, data[0])
+		}
+		if n := binary.BigEndian.Uint16(data[2:4]); n != 2 {
+			t.Errorf("encoding %d: nrects = %d, want 2", preferred, n)
+		}
+		if got := int32(binary.BigEndian.Uint32(data[4+8 : 4+12])); got != preferred {
+			t.Errorf("first rect encoding = %d, want %d", got, preferred)
+		}
 	}
 }
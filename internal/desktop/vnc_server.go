@@ -15,7 +15,10 @@ import (
 )
 
 // VNCServer is a minimal RFB 3.8 server that captures the local screen and
-// serves it over the VNC protocol (Raw encoding, No authentication).
+// serves it over the VNC protocol (No authentication). It encodes with Tight
+// (JPEG when the client asks for a quality level), ZRLE or Raw, whichever the
+// client prefers, and supports the CopyRect, Cursor and DesktopSize
+// extensions.
 //
 // It listens on localhost and optionally on additional listeners (e.g. tsnet
 // VPN) added via AddListener, mirroring the terminal server pattern.
@@ -232,62 +235,64 @@ func (s *VNCServer) handleConn(conn net.Conn) {
 	s.logger.Printf("client connected: %s", remote)
 	defer s.logger.Printf("client disconnected: %s", remote)
 
-	if err := s.rfbHandshake(conn); err != nil {
+	fbW, fbH, err := s.rfbHandshake(conn)
+	if err != nil {
 		s.logger.Printf("handshake failed (%s): %v", remote, err)
 		return
 	}
 
-	s.rfbSession(conn)
+	s.rfbSession(conn, fbW, fbH)
 }
 
-// rfbHandshake performs the RFB 3.8 protocol handshake (version, security, init).
-func (s *VNCServer) rfbHandshake(conn net.Conn) error {
+// rfbHandshake performs the RFB 3.8 protocol handshake (version, security,
+// init) and returns the framebuffer size announced in ServerInit.
+func (s *VNCServer) rfbHandshake(conn net.Conn) (int, int, error) {
 	// 1. Server sends protocol version
 	if _, err := conn.Write([]byte("RFB 003.008\n")); err != nil {
-		return fmt.Errorf("write version: %w", err)
+		return 0, 0, fmt.Errorf("write version: %w", err)
 	}
 
 	// 2. Client responds with version
 	var clientVersion [12]byte
 	if _, err := io.ReadFull(conn, clientVersion[:]); err != nil {
-		return fmt.Errorf("read client version: %w", err)
+		return 0, 0, fmt.Errorf("read client version: %w", err)
 	}
 
 	// 3. Server sends security types: 1 type, type=1 (None)
 	if _, err := conn.Write([]byte{1, 1}); err != nil {
-		return fmt.Errorf("write security types: %w", err)
+		return 0, 0, fmt.Errorf("write security types: %w", err)
 	}
 
 	// 4. Client selects security type
 	var secType [1]byte
 	if _, err := io.ReadFull(conn, secType[:]); err != nil {
-		return fmt.Errorf("read security type: %w", err)
+		return 0, 0, fmt.Errorf("read security type: %w", err)
 	}
 	if secType[0] != 1 {
-		return fmt.Errorf("client selected unsupported security type %d", secType[0])
+		return 0, 0, fmt.Errorf("client selected unsupported security type %d", secType[0])
 	}
 
 	// 5. RFB 3.8: send SecurityResult (u32 0 = OK) for None auth
 	if err := binary.Write(conn, binary.BigEndian, uint32(0)); err != nil {
-		return fmt.Errorf("write security result: %w", err)
+		return 0, 0, fmt.Errorf("write security result: %w", err)
 	}
 
 	// 6. Client sends ClientInit (shared-flag byte)
 	var clientInit [1]byte
 	if _, err := io.ReadFull(conn, clientInit[:]); err != nil {
-		return fmt.Errorf("read ClientInit: %w", err)
+		return 0, 0, fmt.Errorf("read ClientInit: %w", err)
 	}
 
 	// 7. Server sends ServerInit
 	frame, err := s.capturer.Capture()
 	if err != nil {
-		return fmt.Errorf("initial capture: %w", err)
+		return 0, 0, fmt.Errorf("initial capture: %w", err)
 	}
 	if err := s.writeServerInit(conn, frame.Width(), frame.Height()); err != nil {
-		return fmt.Errorf("write ServerInit: %w", err)
+		return 0, 0, fmt.Errorf("write ServerInit: %w", err)
 	}
 
-	return nil
+	return frame.Width(), frame.Height(), nil
 }
 
 // ServerInit pixel format: 32bpp RGBX, matching what noVNC requests.
@@ -337,7 +342,8 @@ func (s *VNCServer) writeServerInit(w io.Writer, width, height int) error {
 }
 
 // rfbSession runs the main client message loop and frame-sending goroutine.
-func (s *VNCServer) rfbSession(conn net.Conn) {
+// fbW and fbH are the framebuffer dimensions announced in ServerInit.
+func (s *VNCServer) rfbSession(conn net.Conn, fbW, fbH int) {
 	var writeMu sync.Mutex
 	// updateRequested carries the incremental flag of a FramebufferUpdateRequest
 	// (true = incremental, false = full). Buffered to 1; a pending full request
@@ -345,34 +351,42 @@ func (s *VNCServer) rfbSession(conn net.Conn) {
 	updateRequested := make(chan bool, 1)
 	done := make(chan struct{})
 
-	// Per-connection encoding negotiation state. zrleEnabled is set when the
-	// client advertises ZRLE (16) via SetEncodings. Protected by encMu because
-	// it is written by the reader loop and read by the sender goroutine.
+	// Per-connection encoding negotiation state, set from SetEncodings.
+	// Protected by encMu because it is written by the reader loop and read by
+	// the sender goroutine.
 	var encMu sync.Mutex
-	zrleEnabled := false
+	prefs := encodingPrefs{preferred: encodingRaw, quality: -1, compress: -1}
+
+	cursors, _ := s.capturer.(CursorCapturer)
 
 	// Frame sender goroutine
 	go func() {
 		defer close(done)
-		interval := time.Duration(float64(time.Second) / float64(s.fps))
-		ticker := time.NewTicker(interval)
-		defer ticker.Stop()
 
 		// Per-connection state: the previous captured frame (native BGRX) for
-		// dirty-rectangle diffing, and the continuous ZRLE zlib stream.
+		// dirty-rectangle diffing, the encoder with its continuous zlib
+		// streams and bandwidth estimate, and the last cursor sent.
 		var prevFrame []byte
-		var zEnc *zrleEncoder
-		defer func() {
-			if zEnc != nil {
-				zEnc.Close()
-			}
-		}()
+		enc := newRFBEncoder()
+		defer enc.Close()
+		var sentCursor *CursorShape
+
+		// The previous update's size and send time. The next request arrives
+		// once the client has received and decoded it, which gives the
+		// bandwidth estimator one sample per update.
+		var lastBytes int
+		var lastSent time.Time
 
 		for {
 			select {
 			case <-s.stopCh:
 				return
 			case incremental := <-updateRequested:
+				if lastBytes > 0 {
+					enc.bw.observe(lastBytes, time.Since(lastSent))
+					lastBytes = 0
+				}
+
 				frame, err := s.capturer.Capture()
 				if err != nil {
 					s.logger.Printf("capture error: %v", err)
@@ -380,38 +394,73 @@ func (s *VNCServer) rfbSession(conn net.Conn) {
 				}
 
 				encMu.Lock()
-				useZRLE := zrleEnabled
+				enc.prefs = prefs
 				encMu.Unlock()
 
-				if useZRLE && zEnc == nil {
-					zEnc = newZRLEEncoder()
+				frameW, frameH := frame.Width(), frame.Height()
+
+				// Resolution change: resize the client when it supports
+				// DesktopSize and resend everything. Otherwise keep serving the
+				// size announced in ServerInit, clipping to it below.
+				if (frameW != fbW || frameH != fbH) && enc.prefs.desktopSize {
+					writeMu.Lock()
+					err = writeDesktopSize(conn, frameW, frameH)
+					writeMu.Unlock()
+					if err != nil {
+						return
+					}
+					s.logger.Printf("desktop resized to %dx%d", frameW, frameH)
+					fbW, fbH = frameW, frameH
+					prevFrame = nil
 				}
 
 				// Determine the rectangles to send. A non-incremental request,
 				// or the first frame of a connection, sends the full frame.
-				// Otherwise diff against the previous frame on a 64x64 tile grid.
-				frameW, frameH := frame.Width(), frame.Height()
-				var rects []image.Rectangle
+				// Otherwise look for a scrolled band to send as a CopyRect, then
+				// diff against the previous frame on a 64x64 tile grid.
+				u := frameUpdate{pix: frame.Pix, frameW: frameW}
 				if !incremental || prevFrame == nil {
-					rects = []image.Rectangle{image.Rect(0, 0, frameW, frameH)}
+					u.rects = []image.Rectangle{image.Rect(0, 0, frameW, frameH)}
 				} else {
-					rects = dirtyTiles(prevFrame, frame.Pix, frameW, frameH)
+					base := prevFrame
+					if enc.prefs.copyRect {
+						if c, ok := detectVerticalScroll(prevFrame, frame.Pix, frameW, frameH); ok {
+							u.copies = []copyRect{c}
+							base = applyCopyRect(prevFrame, frameW, c)
+						}
+					}
+					u.rects = dirtyTiles(base, frame.Pix, frameW, frameH)
+				}
+				if frameW != fbW || frameH != fbH {
+					bounds := image.Rect(0, 0, fbW, fbH)
+					u.rects = clipRects(u.rects, bounds)
+					u.copies = nil
 				}
 
-				activeEnc := zEnc
-				if !useZRLE {
-					activeEnc = nil
+				if enc.prefs.cursor {
+					shape := DefaultCursor()
+					if cursors != nil {
+						if c, err := cursors.CaptureCursor(); err == nil && c != nil {
+							shape = c
+						}
+					}
+					if !shape.Equal(sentCursor) {
+						u.cursor = shape
+						sentCursor = shape
+					}
 				}
 
 				writeMu.Lock()
-				err = writeFramebufferUpdateRects(conn, frame.Pix, frameW, rects, activeEnc)
+				start := time.Now()
+				n, err := enc.writeUpdate(conn, u)
 				writeMu.Unlock()
 				if err != nil {
 					return // connection dead
 				}
+				lastBytes, lastSent = n, start
 
-				// Stash a copy of this frame as the diff baseline. Capture
-				// returns a fresh buffer each call, so we can retain it.
+				// Stash this frame as the diff baseline. Capture returns a
+				// fresh buffer each call, so we can retain it.
 				prevFrame = frame.Pix
 			}
 		}
@@ -456,14 +505,15 @@ func (s *VNCServer) rfbSession(conn net.Conn) {
 			if _, err := io.ReadFull(conn, encodings); err != nil {
 				return
 			}
-			// Negotiate ZRLE (16) if the client advertises it; otherwise we
-			// fall back to Raw (0), which every client supports.
-			if clientSupportsZRLE(encodings) {
-				encMu.Lock()
-				zrleEnabled = true
-				encMu.Unlock()
-				s.logger.Printf("client negotiated ZRLE encoding")
-			}
+			// Pick the client's preferred encoding among Tight, ZRLE and Raw
+			// (Raw, which every client supports, when it lists none) and
+			// record the extensions it opted in to.
+			p := parseEncodings(encodings)
+			encMu.Lock()
+			prefs = p
+			encMu.Unlock()
+			s.logger.Printf("client negotiated encoding %d (quality=%d copyrect=%t cursor=%t desktopsize=%t)",
+				p.preferred, p.quality, p.copyRect, p.cursor, p.desktopSize)
 
 		case 3: // FramebufferUpdateRequest
 			// type(1) + incremental(1) + x(2) + y(2) + w(2) + h(2) = 10, already read 1
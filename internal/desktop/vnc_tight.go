@@ -0,0 +1,208 @@
+package desktop
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+)
+
+// Tight compression-control byte values (high nibble). Tight has no RFC; the
+// layout follows the TightVNC protocol notes that noVNC and TigerVNC decode.
+const (
+	// tightBasic selects basic compression with the copy filter on zlib
+	// stream 0: TPIXELs, zlib-compressed when 12 bytes or longer.
+	tightBasic byte = 0x00
+	// tightFill is a solid-colour rectangle followed by a single TPIXEL.
+	tightFill byte = 0x80
+	// tightJPEG is a JPEG image preceded by its compact length.
+	tightJPEG byte = 0x90
+)
+
+// tightMaxRectWidth is the widest rectangle a Tight decoder is required to
+// accept. Wider dirty bands (a 4K desktop is 3840 wide) are split.
+const tightMaxRectWidth = 2048
+
+// tightMaxCompactLen is the largest payload a compact length can describe:
+// three bytes carrying 7 + 7 + 8 bits.
+const tightMaxCompactLen = 1<<22 - 1
+
+// tightMaxRectPixels bounds a rectangle's area so its payload fits a compact
+// length: 3 MiB of TPIXELs leaves ample room for zlib's worst-case expansion.
+// Taller rectangles are split by height as well as width.
+const tightMaxRectPixels = 1 << 20
+
+// errTightTooLarge reports a payload longer than a compact length can carry.
+var errTightTooLarge = errors.New("tight payload exceeds the compact length limit")
+
+// tightMinDataSize is the threshold below which basic-compression data is sent
+// uncompressed, as the Tight spec requires.
+const tightMinDataSize = 12
+
+// tightJPEGQuality maps Tight quality levels 0-9 onto libjpeg quality values.
+// This is the table TigerVNC uses, so a given level looks the same against
+// either server.
+var tightJPEGQuality = [10]int{15, 29, 41, 42, 62, 77, 79, 86, 92, 100}
+
+// tightEncoder holds the per-connection zlib stream used by Tight basic
+// compression. As with ZRLE, the client keeps one inflater per stream for the
+// life of the connection, so the deflater must be created once and reused.
+type tightEncoder struct {
+	zbuf bytes.Buffer
+	zw   *zlib.Writer
+}
+
+// newTightEncoder creates a Tight encoder. level is the client's requested
+// compression level (0-9), or -1 for the zlib default.
+func newTightEncoder(level int) *tightEncoder {
+	e := &tightEncoder{}
+	if level < 0 || level > 9 {
+		level = zlib.DefaultCompression
+	}
+	// NewWriterLevel only fails for out-of-range levels, excluded above.
+	e.zw, _ = zlib.NewWriterLevel(&e.zbuf, level)
+	return e
+}
+
+// Close releases the underlying zlib writer.
+func (e *tightEncoder) Close() {
+	if e.zw != nil {
+		_ = e.zw.Close()
+		e.zw = nil
+	}
+}
+
+// encodeTightRect appends a Tight-encoded rectangle for the sub-rectangle
+// (x, y, w, h) of the frame to buf.
+//
+// A solid-colour rectangle is sent as a fill. Otherwise, when quality is a
+// level in 0-9 the rectangle is sent as JPEG; when quality is -1 (the client
+// did not ask for lossy compression) it is sent losslessly with basic
+// compression on zlib stream 0.
+//
+// A JPEG too large for its compact length falls back to a Raw rectangle. Basic
+// compression cannot fall back: the data has already gone through the shared
+// zlib stream, so an oversized payload is an error (rectangles are kept to
+// tightMaxRectPixels so it does not happen).
+func (e *tightEncoder) encodeTightRect(buf *bytes.Buffer, pix []byte, frameW, x, y, w, h, quality int) error {
+	start := buf.Len()
+	writeRawRectHeader(buf, x, y, w, h, encodingTight)
+
+	if r, g, b, ok := solidColor(pix, frameW, x, y, w, h); ok {
+		buf.WriteByte(tightFill)
+		buf.Write([]byte{r, g, b})
+		return nil
+	}
+
+	if quality >= 0 && quality < len(tightJPEGQuality) {
+		err := encodeTightJPEG(buf, pix, frameW, x, y, w, h, tightJPEGQuality[quality])
+		if errors.Is(err, errTightTooLarge) {
+			buf.Truncate(start)
+			encodeRawRect(buf, pix, frameW, x, y, w, h)
+			return nil
+		}
+		return err
+	}
+
+	buf.WriteByte(tightBasic)
+	data := tightPixels(pix, frameW, x, y, w, h)
+	if len(data) < tightMinDataSize {
+		buf.Write(data)
+		return nil
+	}
+
+	e.zbuf.Reset()
+	if _, err := e.zw.Write(data); err != nil {
+		return err
+	}
+	if err := e.zw.Flush(); err != nil {
+		return err
+	}
+	if err := writeCompactLen(buf, e.zbuf.Len()); err != nil {
+		return err
+	}
+	buf.Write(e.zbuf.Bytes())
+	return nil
+}
+
+// encodeTightJPEG appends the JPEG body (control byte, compact length, JPEG
+// data) of a Tight rectangle whose header has already been written.
+func encodeTightJPEG(buf *bytes.Buffer, pix []byte, frameW, x, y, w, h, quality int) error {
+	img := &image.RGBA{
+		Pix:    extractRect(pix, frameW, x, y, w, h),
+		Stride: w * 4,
+		Rect:   image.Rect(0, 0, w, h),
+	}
+	// The padding byte of RGBX is 0, which image.RGBA would treat as fully
+	// transparent; JPEG ignores alpha, but set it so the image is well formed.
+	for i := 3; i < len(img.Pix); i += 4 {
+		img.Pix[i] = 0xff
+	}
+
+	var jb bytes.Buffer
+	if err := jpeg.Encode(&jb, img, &jpeg.Options{Quality: quality}); err != nil {
+		return err
+	}
+	if jb.Len() > tightMaxCompactLen {
+		return errTightTooLarge
+	}
+	buf.WriteByte(tightJPEG)
+	_ = writeCompactLen(buf, jb.Len()) // in range: checked above
+	buf.Write(jb.Bytes())
+	return nil
+}
+
+// tightPixels returns the TPIXELs of the sub-rectangle: for our 32bpp,
+// depth-24 pixel format a TPIXEL is 3 bytes in R, G, B order.
+func tightPixels(pix []byte, frameW, x, y, w, h int) []byte {
+	out := make([]byte, 0, w*h*3)
+	for row := 0; row < h; row++ {
+		rowStart := ((y+row)*frameW + x) * 4
+		for col := 0; col < w; col++ {
+			p := rowStart + col*4
+			// Source is native BGRX.
+			out = append(out, pix[p+2], pix[p+1], pix[p])
+		}
+	}
+	return out
+}
+
+// solidColor reports whether every pixel of the sub-rectangle has the same
+// colour and, if so, returns it as R, G, B.
+func solidColor(pix []byte, frameW, x, y, w, h int) (r, g, b byte, ok bool) {
+	first := (y*frameW + x) * 4
+	b, g, r = pix[first], pix[first+1], pix[first+2]
+	for row := 0; row < h; row++ {
+		rowStart := ((y+row)*frameW + x) * 4
+		for col := 0; col < w; col++ {
+			p := rowStart + col*4
+			if pix[p] != b || pix[p+1] != g || pix[p+2] != r {
+				return 0, 0, 0, false
+			}
+		}
+	}
+	return r, g, b, true
+}
+
+// writeCompactLen writes a Tight compact length: 1-3 bytes, 7 bits each (8 in
+// the third), least significant first, with the high bit set on every byte but
+// the last. A length over tightMaxCompactLen would be silently truncated and
+// desync the stream, so it is refused and nothing is written.
+func writeCompactLen(buf *bytes.Buffer, n int) error {
+	if n < 0 || n > tightMaxCompactLen {
+		return fmt.Errorf("%w: %d bytes", errTightTooLarge, n)
+	}
+	b := byte(n & 0x7f)
+	if n > 0x7f {
+		buf.WriteByte(b | 0x80)
+		b = byte((n >> 7) & 0x7f)
+		if n > 0x3fff {
+			buf.WriteByte(b | 0x80)
+			b = byte((n >> 14) & 0xff)
+		}
+	}
+	buf.WriteByte(b)
+	return nil
+}
@@ -0,0 +1,356 @@
+package desktop
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"image"
+	"image/jpeg"
+	"io"
+	"testing"
+	"time"
+)
+
+// encodingList builds a SetEncodings payload from s32 encoding numbers.
+func encodingList(encs ...int32) []byte {
+	out := make([]byte, 0, len(encs)*4)
+	for _, e := range encs {
+		var b [4]byte
+		binary.BigEndian.PutUint32(b[:], uint32(e))
+		out = append(out, b[:]...)
+	}
+	return out
+}
+
+// readCompactLen decodes a Tight compact length, returning it and the number
+// of bytes consumed.
+func readCompactLen(b []byte) (int, int) {
+	n := int(b[0] & 0x7f)
+	if b[0]&0x80 == 0 {
+		return n, 1
+	}
+	n |= int(b[1]&0x7f) << 7
+	if b[1]&0x80 == 0 {
+		return n, 2
+	}
+	n |= int(b[2]) << 14
+	return n, 3
+}
+
+func TestParseEncodingsPreferenceOrder(t *testing.T) {
+	// noVNC's default list: Tight first, then ZRLE, then pseudo-encodings.
+	p := parseEncodings(encodingList(encodingTight, encodingZRLE, encodingRaw,
+		encodingCopyRect, encodingPseudoQualityLevel0+6, encodingPseudoCompressLevel0+2,
+		encodingPseudoCursor, encodingPseudoDesktopSize))
+	if p.preferred != encodingTight {
+		t.Errorf("preferred = %d, want Tight", p.preferred)
+	}
+	if !p.copyRect || !p.cursor || !p.desktopSize {
+		t.Errorf("extensions = %+v, want copyRect, cursor and desktopSize", p)
+	}
+	if p.quality != 6 {
+		t.Errorf("quality = %d, want 6", p.quality)
+	}
+	if p.compress != 2 {
+		t.Errorf("compress = %d, want 2", p.compress)
+	}
+
+	// ZRLE listed before Tight wins.
+	if p := parseEncodings(encodingList(encodingZRLE, encodingTight)); p.preferred != encodingZRLE {
+		t.Errorf("preferred = %d, want ZRLE", p.preferred)
+	}
+}
+
+func TestParseEncodingsDefaults(t *testing.T) {
+	p := parseEncodings(encodingList(5 /* Hextile, unsupported */))
+	if p.preferred != encodingRaw {
+		t.Errorf("preferred = %d, want Raw fallback", p.preferred)
+	}
+	if p.quality != -1 || p.compress != -1 {
+		t.Errorf("quality/compress = %d/%d, want -1/-1", p.quality, p.compress)
+	}
+	if p.copyRect || p.cursor || p.desktopSize {
+		t.Errorf("no extensions expected, got %+v", p)
+	}
+}
+
+func TestWriteCompactLen(t *testing.T) {
+	for _, n := range []int{0, 1, 127, 128, 16383, 16384, 4194303} {
+		var buf bytes.Buffer
+		if err := writeCompactLen(&buf, n); err != nil {
+			t.Fatalf("compact len %d: %v", n, err)
+		}
+		got, used := readCompactLen(buf.Bytes())
+		if got != n || used != buf.Len() {
+			t.Errorf("compact len %d: decoded %d using %d of %d bytes", n, got, used, buf.Len())
+		}
+	}
+}
+
+func TestWriteCompactLenRefusesOverflow(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeCompactLen(&buf, tightMaxCompactLen+1); err == nil || buf.Len() != 0 {
+		t.Errorf("err = %v, wrote %d bytes; want a refusal and nothing written", err, buf.Len())
+	}
+}
+
+func TestTightSolidRectIsFill(t *testing.T) {
+	w, h := 32, 32
+	pix := make([]byte, w*h*4)
+	for i := 0; i < len(pix); i += 4 {
+		pix[i], pix[i+1], pix[i+2] = 0x30, 0x20, 0x10 // BGRX
+	}
+	enc := newTightEncoder(-1)
+	defer enc.Close()
+
+	var buf bytes.Buffer
+	if err := enc.encodeTightRect(&buf, pix, w, 0, 0, w, h, 5); err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	data := buf.Bytes()
+	if e := int32(binary.BigEndian.Uint32(data[8:12])); e != encodingTight {
+		t.Errorf("encoding = %d, want Tight", e)
+	}
+	want := []byte{tightFill, 0x10, 0x20, 0x30}
+	if !bytes.Equal(data[12:], want) {
+		t.Errorf("fill body = %v, want %v (control, R, G, B)", data[12:], want)
+	}
+}
+
+func TestTightJPEGDecodes(t *testing.T) {
+	w, h := 40, 24
+	pix := makeBGRXFrame(w, h)
+	enc := newTightEncoder(-1)
+	defer enc.Close()
+
+	var buf bytes.Buffer
+	if err := enc.encodeTightRect(&buf, pix, w, 0, 0, w, h, 9); err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	data := buf.Bytes()[12:]
+	if data[0] != tightJPEG {
+		t.Fatalf("control = %#x, want JPEG", data[0])
+	}
+	n, used := readCompactLen(data[1:])
+	jpg := data[1+used:]
+	if len(jpg) != n {
+		t.Fatalf("compact length %d, but %d bytes follow", n, len(jpg))
+	}
+	img, err := jpeg.Decode(bytes.NewReader(jpg))
+	if err != nil {
+		t.Fatalf("decode JPEG: %v", err)
+	}
+	if b := img.Bounds(); b.Dx() != w || b.Dy() != h {
+		t.Errorf("JPEG size = %v, want %dx%d", b, w, h)
+	}
+}
+
+func TestTightBasicIsLosslessWithoutQuality(t *testing.T) {
+	w, h := 16, 8
+	pix := makeBGRXFrame(w, h)
+	enc := newTightEncoder(-1)
+	defer enc.Close()
+
+	var buf bytes.Buffer
+	if err := enc.encodeTightRect(&buf, pix, w, 0, 0, w, h, -1); err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	data := buf.Bytes()[12:]
+	if data[0] != tightBasic {
+		t.Fatalf("control = %#x, want basic", data[0])
+	}
+	n, used := readCompactLen(data[1:])
+	payload := data[1+used:]
+	if len(payload) != n {
+		t.Fatalf("compact length %d, but %d bytes follow", n, len(payload))
+	}
+	zr, err := zlib.NewReader(bytes.NewReader(payload))
+	if err != nil {
+		t.Fatalf("zlib.NewReader: %v", err)
+	}
+	got, err := io.ReadAll(zr)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		t.Fatalf("read zlib: %v", err)
+	}
+	if want := tightPixels(pix, w, 0, 0, w, h); !bytes.Equal(got, want) {
+		t.Errorf("decoded %d bytes, want %d TPIXEL bytes", len(got), len(want))
+	}
+}
+
+func TestWriteUpdateSplitsWideTightRects(t *testing.T) {
+	w, h := tightMaxRectWidth+100, 2
+	pix := makeBGRXFrame(w, h)
+	enc := newRFBEncoder()
+	defer enc.Close()
+	enc.prefs = parseEncodings(encodingList(encodingTight))
+
+	var buf bytes.Buffer
+	if _, err := enc.writeUpdate(&buf, frameUpdate{pix: pix, frameW: w, rects: []image.Rectangle{image.Rect(0, 0, w, h)}}); err != nil {
+		t.Fatalf("writeUpdate: %v", err)
+	}
+	if n := binary.BigEndian.Uint16(buf.Bytes()[2:4]); n != 2 {
+		t.Errorf("nrects = %d, want 2 (split at %d)", n, tightMaxRectWidth)
+	}
+}
+
+func TestWriteUpdateSplitsTallTightRects(t *testing.T) {
+	w, h := tightMaxRectWidth, tightMaxRectPixels/tightMaxRectWidth+1
+	pix := makeBGRXFrame(w, h)
+	enc := newRFBEncoder()
+	defer enc.Close()
+	enc.prefs = parseEncodings(encodingList(encodingTight))
+
+	var buf bytes.Buffer
+	if _, err := enc.writeUpdate(&buf, frameUpdate{pix: pix, frameW: w, rects: []image.Rectangle{image.Rect(0, 0, w, h)}}); err != nil {
+		t.Fatalf("writeUpdate: %v", err)
+	}
+	if n := binary.BigEndian.Uint16(buf.Bytes()[2:4]); n != 2 {
+		t.Errorf("nrects = %d, want 2 (split at %d rows)", n, tightMaxRectPixels/tightMaxRectWidth)
+	}
+}
+
+func TestWriteUpdateOrdersCopyCursorPixels(t *testing.T) {
+	w, h := 64, 64
+	pix := makeBGRXFrame(w, h)
+	enc := newRFBEncoder()
+	defer enc.Close()
+
+	u := frameUpdate{
+		pix:    pix,
+		frameW: w,
+		copies: []copyRect{{dst: image.Rect(0, 10, w, 50), srcX: 0, srcY: 0}},
+		cursor: DefaultCursor(),
+		rects:  []image.Rectangle{image.Rect(0, 50, w, h)},
+	}
+	var buf bytes.Buffer
+	if _, err := enc.writeUpdate(&buf, u); err != nil {
+		t.Fatalf("writeUpdate: %v", err)
+	}
+	data := buf.Bytes()
+	if n := binary.BigEndian.Uint16(data[2:4]); n != 3 {
+		t.Fatalf("nrects = %d, want 3", n)
+	}
+	// First rect: CopyRect, with its source position after the header.
+	if e := int32(binary.BigEndian.Uint32(data[12:16])); e != encodingCopyRect {
+		t.Errorf("rect 0 encoding = %d, want CopyRect", e)
+	}
+	// Second rect: the cursor, right after the 12+4 byte CopyRect.
+	off := 4 + 16
+	if e := int32(binary.BigEndian.Uint32(data[off+8 : off+12])); e != encodingPseudoCursor {
+		t.Errorf("rect 1 encoding = %d, want Cursor", e)
+	}
+}
+
+func TestWriteDesktopSize(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeDesktopSize(&buf, 3840, 2160); err != nil {
+		t.Fatalf("writeDesktopSize: %v", err)
+	}
+	data := buf.Bytes()
+	if len(data) != 16 {
+		t.Fatalf("len = %d, want 16", len(data))
+	}
+	if rw, rh := binary.BigEndian.Uint16(data[8:10]), binary.BigEndian.Uint16(data[10:12]); rw != 3840 || rh != 2160 {
+		t.Errorf("size = %dx%d, want 3840x2160", rw, rh)
+	}
+	if e := int32(binary.BigEndian.Uint32(data[12:16])); e != encodingPseudoDesktopSize {
+		t.Errorf("encoding = %d, want DesktopSize", e)
+	}
+}
+
+// scrollFrame returns a frame whose row y has content derived from y+offset,
+// so scrollFrame(w, h, k) is scrollFrame(w, h, 0) scrolled up by k rows.
+func scrollFrame(w, h, offset int) []byte {
+	pix := make([]byte, w*h*4)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			p := (y*w + x) * 4
+			pix[p] = byte(y + offset)
+			pix[p+1] = byte((y + offset) >> 8)
+			pix[p+2] = byte(x)
+		}
+	}
+	return pix
+}
+
+func TestDetectVerticalScroll(t *testing.T) {
+	w, h, dy := 80, 200, 24
+	prev := scrollFrame(w, h, 0)
+	cur := scrollFrame(w, h, dy)
+
+	c, ok := detectVerticalScroll(prev, cur, w, h)
+	if !ok {
+		t.Fatal("expected a scroll to be detected")
+	}
+	// Rows [0, h-dy) of cur are rows [dy, h) of prev.
+	if c.dst != image.Rect(0, 0, w, h-dy) || c.srcY != dy {
+		t.Errorf("copy = %+v, want dst [0,%d) from srcY %d", c, h-dy, dy)
+	}
+
+	// After the copy only the newly exposed bottom rows differ.
+	base := applyCopyRect(prev, w, c)
+	for _, r := range dirtyTiles(base, cur, w, h) {
+		if r.Max.Y <= h-dy-zrleTileSize {
+			t.Errorf("dirty rect %v lies inside the copied band", r)
+		}
+	}
+}
+
+func TestDetectVerticalScrollNoMatch(t *testing.T) {
+	w, h := 80, 200
+	frame := scrollFrame(w, h, 0)
+	if _, ok := detectVerticalScroll(frame, append([]byte(nil), frame...), w, h); ok {
+		t.Error("identical frames should not report a scroll")
+	}
+	if _, ok := detectVerticalScroll(nil, frame, w, h); ok {
+		t.Error("nil prev should not report a scroll")
+	}
+	if _, ok := detectVerticalScroll(frame, makeBGRXFrame(w, h), w, h); ok {
+		t.Error("unrelated frames should not report a scroll")
+	}
+}
+
+func TestBandwidthEstimatorCapsQuality(t *testing.T) {
+	var b bandwidthEstimator
+	if got := b.qualityCap(); got != 9 {
+		t.Errorf("no samples: cap = %d, want 9", got)
+	}
+	// Small updates are ignored.
+	b.observe(100, time.Second)
+	if got := b.qualityCap(); got != 9 {
+		t.Errorf("tiny sample: cap = %d, want 9", got)
+	}
+	// ~1 Mbit/s: 128 KiB per second.
+	b.observe(128*1024, time.Second)
+	if got := b.qualityCap(); got != 1 {
+		t.Errorf("1 Mbit/s: cap = %d, want 1", got)
+	}
+
+	enc := newRFBEncoder()
+	enc.prefs.quality = 8
+	enc.bw = b
+	if got := enc.jpegQuality(); got != 1 {
+		t.Errorf("jpegQuality = %d, want bandwidth cap 1", got)
+	}
+	enc.prefs.quality = -1
+	if got := enc.jpegQuality(); got != -1 {
+		t.Errorf("jpegQuality without client level = %d, want -1", got)
+	}
+}
+
+func TestDefaultCursorShape(t *testing.T) {
+	c := DefaultCursor()
+	if len(c.Pix) != c.Width*c.Height*4 {
+		t.Errorf("pix len = %d, want %d", len(c.Pix), c.Width*c.Height*4)
+	}
+	if len(c.Mask) != (c.Width+7)/8*c.Height {
+		t.Errorf("mask len = %d, want %d", len(c.Mask), (c.Width+7)/8*c.Height)
+	}
+	// The hotspot pixel (tip of the arrow) is opaque.
+	if c.Mask[0]&0x80 == 0 {
+		t.Error("hotspot pixel should be opaque")
+	}
+	if !c.Equal(DefaultCursor()) {
+		t.Error("DefaultCursor should equal itself")
+	}
+}
@@ -0,0 +1,167 @@
+// Package events is the node-local event bus. The worker, the swap manager,
+// the service supervisor and the auto-updater publish what happens on the node
+// (a job claimed or finished, a model swap, a service restarted, an update
+// applied) and local consumers subscribe: the /agent/events SSE stream on the
+// status server and the outbound webhook dispatcher (webhook.go).
+//
+// Publishing never blocks on a slow consumer. A subscriber whose buffer is
+// full misses events (an SSE client is expected to reconnect and re-read
+// state); a sink, which must not miss any, runs inline in Publish and is kept
+// cheap: the webhook dispatcher only writes the delivery to its outbox there.
+//
+// Like tracing, the bus is process-wide (Default) so call sites publish
+// without a handle and without branching on whether anyone listens.
+package events
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Event types. The prefix before the dot is the subject, so a subscription to
+// "job.*" follows every job event.
+const (
+	TypeJobClaimed          = "job.claimed"
+	TypeJobFinished         = "job.finished"
+	TypeJobFailed           = "job.failed"
+	TypeSwapStarted         = "swap.started"
+	TypeSwapFinished        = "swap.finished"
+	TypeServiceStateChanged = "service.state_changed"
+	TypeUpdateApplied       = "update.applied"
+)
+
+// subscriberBuffer is how many events a subscriber may fall behind before it
+// starts missing them.
+const subscriberBuffer = 64
+
+// Event is one thing that happened on the node.
+type Event struct {
+	// ID is unique per event; webhook receivers use it to deduplicate retries.
+	ID   string    `json:"id"`
+	Type string    `json:"type"`
+	Time time.Time `json:"time"`
+	// Node is the publishing node's name, so one receiver can serve a fleet.
+	Node string         `json:"node,omitempty"`
+	Data map[string]any `json:"data,omitempty"`
+}
+
+// Bus fans published events out to subscribers and sinks.
+type Bus struct {
+	node atomic.Pointer[string]
+
+	mu     sync.RWMutex
+	nextID int
+	subs   map[int]*subscriber
+	sinks  map[int]func(Event)
+}
+
+type subscriber struct {
+	patterns []string
+	ch       chan Event
+}
+
+// NewBus returns an empty bus.
+func NewBus() *Bus {
+	return &Bus{subs: make(map[int]*subscriber), sinks: make(map[int]func(Event))}
+}
+
+// Default is the process-wide bus the package-level functions use.
+var Default = NewBus()
+
+// Publish publishes an event of type typ on the Default bus.
+func Publish(typ string, data map[string]any) {
+	Default.Publish(typ, data)
+}
+
+// SetNode stamps every event published from now on with the node's name.
+func (b *Bus) SetNode(name string) {
+	b.node.Store(&name)
+}
+
+// Publish stamps and delivers an event of type typ: to every sink inline, then
+// to every matching subscriber that has buffer room.
+func (b *Bus) Publish(typ string, data map[string]any) {
+	ev := Event{ID: newID(), Type: typ, Time: time.Now().UTC(), Data: data}
+	if node := b.node.Load(); node != nil {
+		ev.Node = *node
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, sink := range b.sinks {
+		sink(ev)
+	}
+	for _, sub := range b.subs {
+		if !Matches(sub.patterns, typ) {
+			continue
+		}
+		select {
+		case sub.ch <- ev:
+		default:
+		}
+	}
+}
+
+// Subscribe returns a channel of the events matching patterns (see Matches)
+// and the function that unsubscribes and closes it.
+func (b *Bus) Subscribe(patterns ...string) (<-chan Event, func()) {
+	sub := &subscriber{patterns: patterns, ch: make(chan Event, subscriberBuffer)}
+	b.mu.Lock()
+	id := b.nextID
+	b.nextID++
+	b.subs[id] = sub
+	b.mu.Unlock()
+
+	var once sync.Once
+	return sub.ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subs, id)
+			b.mu.Unlock()
+			close(sub.ch)
+		})
+	}
+}
+
+// AddSink registers fn to be called inline for every event, and returns the
+// function that removes it. fn must be quick and must not publish.
+func (b *Bus) AddSink(fn func(Event)) func() {
+	b.mu.Lock()
+	id := b.nextID
+	b.nextID++
+	b.sinks[id] = fn
+	b.mu.Unlock()
+	return func() {
+		b.mu.Lock()
+		delete(b.sinks, id)
+		b.mu.Unlock()
+	}
+}
+
+// Matches reports whether typ matches any of patterns. A pattern is an exact
+// type, a subject wildcard ("job.*") or "*". No patterns match everything.
+func Matches(patterns []string, typ string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, p := range patterns {
+		switch {
+		case p == "*" || p == typ:
+			return true
+		case strings.HasSuffix(p, ".*") && strings.HasPrefix(typ, strings.TrimSuffix(p, "*")):
+			return true
+		}
+	}
+	return false
+}
+
+// newID returns a random 128-bit hex ID.
+func newID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
@@ -0,0 +1,73 @@
+package events
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMatches(t *testing.T) {
+	cases := []struct {
+		patterns []string
+		typ      string
+		want     bool
+	}{
+		{nil, TypeJobClaimed, true},
+		{[]string{"*"}, TypeUpdateApplied, true},
+		{[]string{TypeJobFailed}, TypeJobFailed, true},
+		{[]string{TypeJobFailed}, TypeJobFinished, false},
+		{[]string{"job.*"}, TypeJobFinished, true},
+		{[]string{"job.*"}, TypeSwapStarted, false},
+		{[]string{"jo*"}, TypeJobFinished, false},
+		{[]string{"swap.*", "service.*"}, TypeServiceStateChanged, true},
+	}
+	for _, c := range cases {
+		if got := Matches(c.patterns, c.typ); got != c.want {
+			t.Errorf("Matches(%v, %q) = %v, want %v", c.patterns, c.typ, got, c.want)
+		}
+	}
+}
+
+func TestBusSubscribeFiltersAndStamps(t *testing.T) {
+	b := NewBus()
+	b.SetNode("gpu-box")
+	jobs, cancel := b.Subscribe("job.*")
+	defer cancel()
+
+	b.Publish(TypeSwapStarted, nil)
+	b.Publish(TypeJobClaimed, map[string]any{"job_id": "j1"})
+
+	select {
+	case ev := <-jobs:
+		if ev.Type != TypeJobClaimed || ev.Node != "gpu-box" || ev.ID == "" || ev.Time.IsZero() || ev.Data["job_id"] != "j1" {
+			t.Errorf("event = %+v", ev)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no event delivered")
+	}
+	select {
+	case ev := <-jobs:
+		t.Errorf("unexpected event %+v", ev)
+	default:
+	}
+}
+
+func TestBusSlowSubscriberDoesNotBlock(t *testing.T) {
+	b := NewBus()
+	ch, cancel := b.Subscribe()
+	var sunk int
+	b.AddSink(func(Event) { sunk++ })
+
+	for i := 0; i < subscriberBuffer*2; i++ {
+		b.Publish(TypeJobFinished, nil)
+	}
+	if len(ch) != subscriberBuffer {
+		t.Errorf("buffered %d events, want %d", len(ch), subscriberBuffer)
+	}
+	if sunk != subscriberBuffer*2 {
+		t.Errorf("sink saw %d events, want every one (%d)", sunk, subscriberBuffer*2)
+	}
+
+	cancel()
+	cancel()
+	b.Publish(TypeJobFinished, nil)
+}
@@ -0,0 +1,240 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultOutboxSize bounds the deliveries kept for unreachable receivers.
+// Past it the oldest is dropped: the outbox is there to ride out a receiver
+// restart or a node reboot, not to buffer a days-long outage.
+const DefaultOutboxSize = 500
+
+// DefaultOutboxPerURL bounds the deliveries kept for one receiver, so a dead
+// receiver cannot fill the outbox on its own.
+const DefaultOutboxPerURL = 200
+
+// Delivery is one event on its way to one webhook.
+type Delivery struct {
+	ID    string `json:"id"`
+	URL   string `json:"url"`
+	Event Event  `json:"event"`
+	// Attempts counts the failed sends so far.
+	Attempts    int       `json:"attempts"`
+	NextAttempt time.Time `json:"next_attempt"`
+	LastError   string    `json:"last_error,omitempty"`
+}
+
+// Outbox persists pending deliveries, one JSON file each, so a delivery
+// survives a restart of the node (an auto-update restarts the process right
+// after publishing update.applied). Webhook secrets are never written here.
+type Outbox struct {
+	dir    string
+	max    int
+	perURL int
+
+	mu      sync.Mutex
+	pending map[string]Delivery
+}
+
+// DefaultOutboxDir returns the outbox directory under the node directory.
+func DefaultOutboxDir(nodeDir string) string {
+	return filepath.Join(nodeDir, "webhook-outbox")
+}
+
+// OpenOutbox opens (creating if needed) the outbox in dir, holding at most max
+// deliveries (DefaultOutboxSize when max <= 0) and at most
+// DefaultOutboxPerURL of them for one receiver, and loads what an earlier run
+// left behind. A file that no longer parses is discarded.
+func OpenOutbox(dir string, max int) (*Outbox, error) {
+	if max <= 0 {
+		max = DefaultOutboxSize
+	}
+	perURL := min(max, DefaultOutboxPerURL)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("events: create outbox dir: %w", err)
+	}
+	o := &Outbox{dir: dir, max: max, perURL: perURL, pending: make(map[string]Delivery)}
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("events: list outbox: %w", err)
+	}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		var d Delivery
+		if err := json.Unmarshal(data, &d); err != nil || d.ID == "" || d.ID+".json" != filepath.Base(path) {
+			os.Remove(path)
+			continue
+		}
+		o.pending[d.ID] = d
+	}
+	return o, nil
+}
+
+// Add stores a new delivery, first making room: when its receiver already has
+// its share the receiver's oldest delivery is dropped, and when the outbox is
+// full the oldest delivery of the receiver with the longest backlog is. A
+// receiver that stopped answering thus loses its own backlog, not the other
+// receivers'. It returns the IDs it dropped.
+func (o *Outbox) Add(d Delivery) ([]string, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	var dropped []string
+	for o.countLocked(d.URL) >= o.perURL {
+		oldest := o.oldestLocked(d.URL)
+		o.removeLocked(oldest)
+		dropped = append(dropped, oldest)
+	}
+	for len(o.pending) >= o.max {
+		oldest := o.oldestLocked(o.longestBacklogLocked())
+		o.removeLocked(oldest)
+		dropped = append(dropped, oldest)
+	}
+	return dropped, o.writeLocked(d)
+}
+
+// Update rewrites a delivery after a failed attempt. A delivery removed in the
+// meantime (dropped for room) stays removed.
+func (o *Outbox) Update(d Delivery) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if _, ok := o.pending[d.ID]; !ok {
+		return nil
+	}
+	return o.writeLocked(d)
+}
+
+// Remove forgets a delivery that was sent or given up on.
+func (o *Outbox) Remove(id string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.removeLocked(id)
+}
+
+// Due returns the deliveries whose next attempt is at or before now, in event
+// order. Each receiver's deliveries queue behind its oldest: one waiting out a
+// backoff holds back the later ones, so a receiver still sees events in the
+// order they happened.
+func (o *Outbox) Due(now time.Time) []Delivery {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	var due []Delivery
+	for _, queue := range o.queuesLocked() {
+		for _, d := range queue {
+			if d.NextAttempt.After(now) {
+				break
+			}
+			due = append(due, d)
+		}
+	}
+	sortByEvent(due)
+	return due
+}
+
+// NextAttempt returns the earliest time a receiver's oldest delivery is due;
+// ok is false when the outbox is empty.
+func (o *Outbox) NextAttempt() (next time.Time, ok bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for _, queue := range o.queuesLocked() {
+		if head := queue[0]; !ok || head.NextAttempt.Before(next) {
+			next, ok = head.NextAttempt, true
+		}
+	}
+	return next, ok
+}
+
+// Len returns the number of pending deliveries.
+func (o *Outbox) Len() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return len(o.pending)
+}
+
+// queuesLocked returns the pending deliveries per receiver URL, each in event
+// order.
+func (o *Outbox) queuesLocked() map[string][]Delivery {
+	queues := make(map[string][]Delivery)
+	for _, d := range o.pending {
+		queues[d.URL] = append(queues[d.URL], d)
+	}
+	for _, queue := range queues {
+		sortByEvent(queue)
+	}
+	return queues
+}
+
+func (o *Outbox) countLocked(url string) int {
+	n := 0
+	for _, d := range o.pending {
+		if d.URL == url {
+			n++
+		}
+	}
+	return n
+}
+
+// longestBacklogLocked returns the URL with the most pending deliveries.
+func (o *Outbox) longestBacklogLocked() string {
+	counts := make(map[string]int)
+	longest := ""
+	for _, d := range o.pending {
+		counts[d.URL]++
+		if c := counts[d.URL]; c > counts[longest] || (c == counts[longest] && d.URL < longest) {
+			longest = d.URL
+		}
+	}
+	return longest
+}
+
+// oldestLocked returns the ID of url's oldest pending delivery.
+func (o *Outbox) oldestLocked(url string) string {
+	var oldest Delivery
+	for _, d := range o.pending {
+		if d.URL == url && (oldest.ID == "" || d.Event.Time.Before(oldest.Event.Time)) {
+			oldest = d
+		}
+	}
+	return oldest.ID
+}
+
+func sortByEvent(ds []Delivery) {
+	sort.SliceStable(ds, func(i, j int) bool { return ds[i].Event.Time.Before(ds[j].Event.Time) })
+}
+
+// writeLocked writes d through a temp file and rename so a crash mid-write
+// never leaves a torn delivery behind.
+func (o *Outbox) writeLocked(d Delivery) error {
+	if d.ID == "" || strings.ContainsAny(d.ID, `/\`) {
+		return fmt.Errorf("events: invalid delivery id %q", d.ID)
+	}
+	data, err := json.Marshal(d)
+	if err != nil {
+		return fmt.Errorf("events: marshal delivery: %w", err)
+	}
+	path := filepath.Join(o.dir, d.ID+".json")
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("events: write delivery: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("events: write delivery: %w", err)
+	}
+	o.pending[d.ID] = d
+	return nil
+}
+
+func (o *Outbox) removeLocked(id string) {
+	delete(o.pending, id)
+	os.Remove(filepath.Join(o.dir, id+".json"))
+}
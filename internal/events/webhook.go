@@ -0,0 +1,261 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aceteam-ai/citadel-cli/internal/config"
+)
+
+// Webhook request headers. The signature is the hex HMAC-SHA256, keyed with
+// the webhook's secret, of "<timestamp>.<body>": binding the timestamp lets a
+// receiver reject a replayed delivery by its age.
+const (
+	HeaderEvent     = "X-Citadel-Event"
+	HeaderDelivery  = "X-Citadel-Delivery"
+	HeaderTimestamp = "X-Citadel-Timestamp"
+	HeaderSignature = "X-Citadel-Signature"
+)
+
+// Defaults for DispatcherConfig fields left zero.
+const (
+	DefaultMaxAttempts = 10
+	DefaultBackoffBase = 10 * time.Second
+	DefaultBackoffMax  = time.Hour
+	deliveryTimeout    = 10 * time.Second
+)
+
+// Sign returns the HeaderSignature value for body sent at timestamp (Unix
+// seconds) to a webhook with secret.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// DispatcherConfig configures a Dispatcher.
+type DispatcherConfig struct {
+	// Webhooks are the receivers. Each is keyed by its URL: a pending delivery
+	// whose URL is no longer configured is dropped instead of sent.
+	Webhooks []config.Webhook
+	// Outbox persists pending deliveries. Required.
+	Outbox *Outbox
+	// Client sends the deliveries. Defaults to a client with a 10s timeout.
+	Client *http.Client
+	// UserAgent is sent with every delivery (e.g. "citadel/v2.50.0").
+	UserAgent string
+	// MaxAttempts is how many failed sends a delivery gets before it is
+	// dropped.
+	MaxAttempts int
+	// BackoffBase is the wait after the first failed send; it doubles with
+	// every further failure up to BackoffMax.
+	BackoffBase time.Duration
+	BackoffMax  time.Duration
+	// Logf is an optional log sink.
+	Logf func(format string, args ...any)
+}
+
+// Dispatcher delivers bus events to the configured webhooks. Enqueue, the bus
+// sink, writes a delivery per matching webhook to the outbox; Run sends them,
+// retrying failures with exponential backoff.
+type Dispatcher struct {
+	cfg   DispatcherConfig
+	hooks map[string]config.Webhook
+	wake  chan struct{}
+	now   func() time.Time
+}
+
+// NewDispatcher builds a Dispatcher, filling zero config fields with defaults.
+func NewDispatcher(cfg DispatcherConfig) *Dispatcher {
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: deliveryTimeout}
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = DefaultMaxAttempts
+	}
+	if cfg.BackoffBase <= 0 {
+		cfg.BackoffBase = DefaultBackoffBase
+	}
+	if cfg.BackoffMax <= 0 {
+		cfg.BackoffMax = DefaultBackoffMax
+	}
+	if cfg.Logf == nil {
+		cfg.Logf = func(string, ...any) {}
+	}
+	hooks := make(map[string]config.Webhook, len(cfg.Webhooks))
+	for _, h := range cfg.Webhooks {
+		hooks[h.URL] = h
+	}
+	return &Dispatcher{cfg: cfg, hooks: hooks, wake: make(chan struct{}, 1), now: time.Now}
+}
+
+// Enqueue queues ev for every webhook subscribed to its type. It is the bus
+// sink, so it only writes to the outbox; Run does the sending.
+func (d *Dispatcher) Enqueue(ev Event) {
+	queued := false
+	for _, h := range d.cfg.Webhooks {
+		if !Matches(h.Events, ev.Type) {
+			continue
+		}
+		dropped, err := d.cfg.Outbox.Add(Delivery{ID: newID(), URL: h.URL, Event: ev, NextAttempt: d.now()})
+		if err != nil {
+			d.cfg.Logf("webhooks: could not queue %s for %s: %v", ev.Type, h.URL, err)
+			continue
+		}
+		if len(dropped) > 0 {
+			d.cfg.Logf("webhooks: outbox full; dropped %d undelivered event(s)", len(dropped))
+		}
+		queued = true
+	}
+	if queued {
+		select {
+		case d.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Run sends due deliveries until ctx is cancelled, including the ones an
+// earlier run left in the outbox. Each receiver has its own queue, worked
+// through one delivery at a time in event order so it sees a job's events in
+// the order they happened. Once a delivery to a receiver fails, the rest of
+// its queue waits for the next pass, and receivers are sent to side by side,
+// so one that hangs or keeps failing does not hold up the others.
+func (d *Dispatcher) Run(ctx context.Context) {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		case <-d.wake:
+		}
+		queues := make(map[string][]Delivery)
+		for _, del := range d.cfg.Outbox.Due(d.now()) {
+			queues[del.URL] = append(queues[del.URL], del)
+		}
+		var wg sync.WaitGroup
+		for _, queue := range queues {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for _, del := range queue {
+					if ctx.Err() != nil || !d.attempt(ctx, del) {
+						return
+					}
+				}
+			}()
+		}
+		wg.Wait()
+		if ctx.Err() != nil {
+			return
+		}
+		wait := time.Hour
+		if next, ok := d.cfg.Outbox.NextAttempt(); ok {
+			wait = max(next.Sub(d.now()), 0)
+		}
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(wait)
+	}
+}
+
+// attempt sends one delivery and settles it: removed when the receiver takes
+// it, refuses it for good or it runs out of attempts; otherwise rescheduled.
+// It reports whether the delivery was removed, i.e. whether the receiver's
+// next delivery may go out now without overtaking this one.
+func (d *Dispatcher) attempt(ctx context.Context, del Delivery) bool {
+	hook, ok := d.hooks[del.URL]
+	if !ok {
+		d.cfg.Outbox.Remove(del.ID)
+		return true
+	}
+	retry, err := d.send(ctx, hook, del)
+	if err == nil {
+		d.cfg.Outbox.Remove(del.ID)
+		return true
+	}
+	del.Attempts++
+	del.LastError = err.Error()
+	if !retry || del.Attempts >= d.cfg.MaxAttempts {
+		d.cfg.Outbox.Remove(del.ID)
+		d.cfg.Logf("webhooks: gave up delivering %s %s to %s after %d attempt(s): %v", del.Event.Type, del.Event.ID, del.URL, del.Attempts, err)
+		return true
+	}
+	del.NextAttempt = d.now().Add(d.backoff(del.Attempts))
+	if err := d.cfg.Outbox.Update(del); err != nil {
+		d.cfg.Logf("webhooks: %v", err)
+	}
+	if del.Attempts == 1 {
+		d.cfg.Logf("webhooks: delivery to %s failing, retrying: %v", del.URL, err)
+	}
+	return false
+}
+
+// send POSTs one delivery. retry reports whether a failure is worth retrying:
+// network errors, timeouts, 408, 429 and 5xx are; any other 4xx means the
+// receiver rejected the delivery itself.
+func (d *Dispatcher) send(ctx context.Context, hook config.Webhook, del Delivery) (retry bool, err error) {
+	body, err := json.Marshal(del.Event)
+	if err != nil {
+		return false, err
+	}
+	ts := d.now().Unix()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if d.cfg.UserAgent != "" {
+		req.Header.Set("User-Agent", d.cfg.UserAgent)
+	}
+	req.Header.Set(HeaderEvent, del.Event.Type)
+	req.Header.Set(HeaderDelivery, del.ID)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(ts, 10))
+	req.Header.Set(HeaderSignature, Sign(hook.Secret, ts, body))
+
+	resp, err := d.cfg.Client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode == http.StatusRequestTimeout, resp.StatusCode == http.StatusTooManyRequests, resp.StatusCode >= 500:
+		return true, fmt.Errorf("receiver answered %s", resp.Status)
+	default:
+		return false, fmt.Errorf("receiver rejected the delivery: %s", resp.Status)
+	}
+}
+
+// backoff is the wait after the n-th failed send: BackoffBase doubled n-1
+// times, capped at BackoffMax.
+func (d *Dispatcher) backoff(n int) time.Duration {
+	wait := d.cfg.BackoffBase
+	for i := 1; i < n; i++ {
+		wait *= 2
+		if wait >= d.cfg.BackoffMax {
+			return d.cfg.BackoffMax
+		}
+	}
+	return min(wait, d.cfg.BackoffMax)
+}
@@ -0,0 +1,250 @@
+package events
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aceteam-ai/citadel-cli/internal/config"
+)
+
+// receiver is a webhook endpoint answering with the queued status codes (200
+// once they run out) and recording what it was sent.
+type receiver struct {
+	mu       sync.Mutex
+	statuses []int
+	got      []*http.Request
+	bodies   [][]byte
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.got = append(rc.got, r)
+	rc.bodies = append(rc.bodies, body)
+	code := http.StatusOK
+	if len(rc.statuses) > 0 {
+		code, rc.statuses = rc.statuses[0], rc.statuses[1:]
+	}
+	w.WriteHeader(code)
+}
+
+func (rc *receiver) count() int {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return len(rc.got)
+}
+
+func newTestDispatcher(t *testing.T, url string, events ...string) (*Dispatcher, *Outbox) {
+	t.Helper()
+	outbox, err := OpenOutbox(t.TempDir(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := NewDispatcher(DispatcherConfig{
+		Webhooks:    []config.Webhook{{URL: url, Secret: "s3cret", Events: events}},
+		Outbox:      outbox,
+		MaxAttempts: 3,
+		BackoffBase: time.Millisecond,
+		BackoffMax:  5 * time.Millisecond,
+	})
+	return d, outbox
+}
+
+func runDispatcher(t *testing.T, d *Dispatcher) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		d.Run(ctx)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.After(3 * time.Second)
+	for !cond() {
+		select {
+		case <-deadline:
+			t.Fatalf("timed out waiting for %s", what)
+		case <-time.After(2 * time.Millisecond):
+		}
+	}
+}
+
+func TestDispatcherSignsAndRetries(t *testing.T) {
+	rc := &receiver{statuses: []int{http.StatusServiceUnavailable}}
+	srv := httptest.NewServer(rc)
+	defer srv.Close()
+	d, outbox := newTestDispatcher(t, srv.URL, "job.*")
+	runDispatcher(t, d)
+
+	b := NewBus()
+	b.AddSink(d.Enqueue)
+	b.Publish(TypeSwapStarted, nil)
+	b.Publish(TypeJobFinished, map[string]any{"job_id": "j1"})
+
+	waitFor(t, "the retried delivery", func() bool { return rc.count() == 2 && outbox.Len() == 0 })
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	first, second := rc.got[0], rc.got[1]
+	if first.Header.Get(HeaderDelivery) != second.Header.Get(HeaderDelivery) {
+		t.Error("a retry must reuse the delivery ID")
+	}
+	if second.Header.Get(HeaderEvent) != TypeJobFinished {
+		t.Errorf("event header = %q", second.Header.Get(HeaderEvent))
+	}
+	ts, err := strconv.ParseInt(second.Header.Get(HeaderTimestamp), 10, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := second.Header.Get(HeaderSignature), Sign("s3cret", ts, rc.bodies[1]); got != want {
+		t.Errorf("signature = %q, want %q", got, want)
+	}
+}
+
+func TestDispatcherDropsRejectedAndExhausted(t *testing.T) {
+	rc := &receiver{statuses: []int{http.StatusBadRequest, 500, 500, 500}}
+	srv := httptest.NewServer(rc)
+	defer srv.Close()
+	d, outbox := newTestDispatcher(t, srv.URL)
+
+	b := NewBus()
+	b.AddSink(d.Enqueue)
+	b.Publish(TypeJobFailed, nil)
+	b.Publish(TypeUpdateApplied, nil)
+	runDispatcher(t, d)
+
+	// One 400 (dropped at once) plus three 500s (MaxAttempts) for the other.
+	waitFor(t, "both deliveries to settle", func() bool { return outbox.Len() == 0 })
+	if n := rc.count(); n != 4 {
+		t.Errorf("receiver saw %d requests, want 4", n)
+	}
+}
+
+func TestOutboxSurvivesRestart(t *testing.T) {
+	dir := t.TempDir()
+	outbox, err := OpenOutbox(dir, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	for i, id := range []string{"a", "b", "c"} {
+		dropped, err := outbox.Add(Delivery{ID: id, URL: "http://x", Event: Event{ID: id, Time: base.Add(time.Duration(i) * time.Second)}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if id == "c" && (len(dropped) != 1 || dropped[0] != "a") {
+			t.Errorf("adding past the cap dropped %v, want [a]", dropped)
+		}
+	}
+
+	reopened, err := OpenOutbox(dir, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	due := reopened.Due(base.Add(time.Hour))
+	if len(due) != 2 || due[0].ID != "b" || due[1].ID != "c" {
+		t.Errorf("reopened outbox = %+v, want b then c", due)
+	}
+}
+
+func TestDispatcherDropsDeliveryForRemovedWebhook(t *testing.T) {
+	rc := &receiver{}
+	srv := httptest.NewServer(rc)
+	defer srv.Close()
+	d, outbox := newTestDispatcher(t, srv.URL)
+	if _, err := outbox.Add(Delivery{ID: "stale", URL: "http://removed.example/hook", Event: Event{Type: TypeJobClaimed}}); err != nil {
+		t.Fatal(err)
+	}
+	runDispatcher(t, d)
+	waitFor(t, "the stale delivery to be dropped", func() bool { return outbox.Len() == 0 })
+	if rc.count() != 0 {
+		t.Error("a delivery for an unconfigured URL must not be sent anywhere")
+	}
+}
+
+func TestDispatcherDeadReceiverDoesNotHoldUpOthers(t *testing.T) {
+	dead := &receiver{statuses: []int{500, 500, 500, 500, 500, 500}}
+	deadSrv := httptest.NewServer(dead)
+	defer deadSrv.Close()
+	healthy := &receiver{}
+	healthySrv := httptest.NewServer(healthy)
+	defer healthySrv.Close()
+	outbox, err := OpenOutbox(t.TempDir(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := NewDispatcher(DispatcherConfig{
+		Webhooks:    []config.Webhook{{URL: deadSrv.URL}, {URL: healthySrv.URL}},
+		Outbox:      outbox,
+		MaxAttempts: 10,
+		BackoffBase: time.Hour,
+	})
+
+	b := NewBus()
+	b.AddSink(d.Enqueue)
+	b.Publish(TypeJobClaimed, nil)
+	b.Publish(TypeSwapStarted, nil)
+	b.Publish(TypeJobFinished, nil)
+	runDispatcher(t, d)
+
+	waitFor(t, "the healthy receiver's deliveries", func() bool { return healthy.count() == 3 })
+	healthy.mu.Lock()
+	for i, want := range []string{TypeJobClaimed, TypeSwapStarted, TypeJobFinished} {
+		if got := healthy.got[i].Header.Get(HeaderEvent); got != want {
+			t.Errorf("delivery %d = %s, want %s", i, got, want)
+		}
+	}
+	healthy.mu.Unlock()
+
+	// The dead receiver's first delivery failed, so its later ones wait behind
+	// it rather than overtaking it.
+	if n := dead.count(); n != 1 {
+		t.Errorf("dead receiver saw %d requests, want 1", n)
+	}
+	if n := outbox.Len(); n != 3 {
+		t.Errorf("outbox holds %d deliveries, want the dead receiver's 3", n)
+	}
+}
+
+func TestOutboxEvictsFromTheLongestBacklog(t *testing.T) {
+	outbox, err := OpenOutbox(t.TempDir(), 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	add := func(id, url string, i int) []string {
+		t.Helper()
+		dropped, err := outbox.Add(Delivery{ID: id, URL: url, Event: Event{ID: id, Time: base.Add(time.Duration(i) * time.Second)}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return dropped
+	}
+	add("ok1", "http://healthy", 0)
+	add("dead1", "http://dead", 1)
+	add("dead2", "http://dead", 2)
+	add("dead3", "http://dead", 3)
+
+	// ok1 is the oldest, but the dead receiver has the longest backlog.
+	if dropped := add("ok2", "http://healthy", 4); len(dropped) != 1 || dropped[0] != "dead1" {
+		t.Errorf("adding to a full outbox dropped %v, want [dead1]", dropped)
+	}
+	due := outbox.Due(base.Add(time.Hour))
+	if len(due) != 4 || due[0].ID != "ok1" {
+		t.Errorf("due = %+v, want ok1 kept", due)
+	}
+}
@@ -12,9 +12,19 @@ import (
 type PowerSource string
 
 const (
-	// PowerSourceMeasured means the figure came from a hardware power sensor
-	// (nvidia-smi power.draw). This is the number the sovereignty receipt wants.
+	// PowerSourceMeasured means the figure came from the GPU's board power
+	// sensor (nvidia-smi power.draw) alone. This is the number the sovereignty
+	// receipt wants.
 	PowerSourceMeasured PowerSource = "measured"
+	// PowerSourceGPURAPL means the figure is the measured GPU board power plus
+	// the CPU package + DRAM power from the RAPL counters. Both are sensor
+	// readings, but the total covers more of the node than a GPU-only figure,
+	// so it is labelled apart to keep "measured" rows comparable.
+	PowerSourceGPURAPL PowerSource = "measured_gpu_rapl"
+	// PowerSourceRAPL means the figure is the CPU package + DRAM power measured
+	// from the RAPL energy counters, with no GPU term. On a service row it is
+	// that service's cgroup CPU share of the measured figure.
+	PowerSourceRAPL PowerSource = "measured_rapl"
 	// PowerSourceEstimated means the figure was modeled from utilisation and a
 	// thermal design / power-limit budget, not read from a sensor.
 	PowerSourceEstimated PowerSource = "estimated"
@@ -42,7 +52,7 @@ type PowerConfig struct {
 	// measured power.limit".
 	GPUTDPWattsOverride float64
 	// CPUTDPWatts is the CPU package power budget for the coarse CPU floor
-	// (waterfall tier 3). From CITADEL_CPU_TDP_WATTS, defaulting to
+	// (waterfall tier 4). From CITADEL_CPU_TDP_WATTS, defaulting to
 	// DefaultCPUTDPWatts. A value <= 0 disables the CPU floor entirely.
 	CPUTDPWatts float64
 }
@@ -91,8 +101,12 @@ type PowerInputs struct {
 	// CPUKnown / CPUPercent carry the host CPU utilisation (0-100).
 	CPUKnown   bool
 	CPUPercent float64
+	// RAPLMeasured / RAPLWatts carry the CPU package + DRAM power measured from
+	// the RAPL energy counters over the last interval.
+	RAPLMeasured bool
+	RAPLWatts    float64
 	// CPUTDPWatts is the CPU package power budget for the coarse floor. Zero
-	// disables tier 3.
+	// disables tier 4.
 	CPUTDPWatts float64
 }
 
@@ -112,19 +126,28 @@ type PowerEstimate struct {
 // means a real sensor reading and is never diluted by a modeled term.
 //
 //  1. Measured GPU board power (nvidia-smi power.draw)  -> measured
+//     plus RAPL CPU package + DRAM when readable        -> measured_gpu_rapl
 //  2. GPU utilisation x TDP (power.limit or override)   -> estimated
-//  3. CPU utilisation x CPU TDP (coarse floor)          -> estimated
-//  4. Nothing usable                                    -> unknown (blank)
+//  3. RAPL CPU package + DRAM energy counters           -> measured_rapl
+//  4. CPU utilisation x CPU TDP (coarse floor)          -> estimated
+//  5. Nothing usable                                    -> unknown (blank)
 //
-// Tier 3 is what gives Apple Silicon and CPU-only nodes a conservative floor
-// without powermetrics (which needs sudo): they have no power.draw, no NVIDIA
-// power.limit, and no GPU util, so they fall cleanly to the CPU model.
+// Tier 1 adds RAPL only because both terms are sensor readings; tier 2 never
+// does, so an estimate is never passed off as partly measured. Tier 3 replaces
+// the CPU model on Linux hosts whose RAPL counters are readable (root, or a
+// kernel without the CVE-2020-8694 restriction). Tier 4 is what gives Apple
+// Silicon and other CPU-only nodes a conservative floor without powermetrics
+// (which needs sudo): they have no power.draw, no NVIDIA power.limit, no GPU
+// util and no RAPL, so they fall cleanly to the CPU model.
 //
 // A fuller node model (GPU + CPU + PSU efficiency + idle baseline summed) is a
 // deliberate next increment; this first cut reports a single, clearly-labeled
 // dominant term.
 func EstimateNodePower(in PowerInputs) PowerEstimate {
 	if in.HasGPU && in.GPUPowerMeasured {
+		if in.RAPLMeasured {
+			return PowerEstimate{Watts: in.GPUPowerWatts + in.RAPLWatts, Source: PowerSourceGPURAPL, Known: true}
+		}
 		return PowerEstimate{Watts: in.GPUPowerWatts, Source: PowerSourceMeasured, Known: true}
 	}
 	if in.HasGPU && in.GPUUtilKnown && in.GPUTDPWatts > 0 {
@@ -134,6 +157,9 @@ func EstimateNodePower(in PowerInputs) PowerEstimate {
 			Known:  true,
 		}
 	}
+	if in.RAPLMeasured {
+		return PowerEstimate{Watts: in.RAPLWatts, Source: PowerSourceRAPL, Known: true}
+	}
 	if in.CPUKnown && in.CPUTDPWatts > 0 {
 		return PowerEstimate{
 			Watts:  wattsFromUtilTDP(in.CPUPercent, in.CPUTDPWatts),
@@ -2,8 +2,11 @@ package footprint
 
 import (
 	"math"
+	"os"
 	"testing"
 	"time"
+
+	"github.com/aceteam-ai/citadel-cli/internal/platform"
 )
 
 func approx(a, b float64) bool { return math.Abs(a-b) < 1e-9 }
@@ -72,6 +75,33 @@ func TestEstimateNodePowerWaterfall(t *testing.T) {
 			},
 			wantKnown: true, wantWatts: 142.5, wantSource: PowerSourceMeasured,
 		},
+		{
+			name: "tier1 adds measured RAPL to measured GPU draw",
+			in: PowerInputs{
+				HasGPU: true, GPUPowerMeasured: true, GPUPowerWatts: 142.5,
+				RAPLMeasured: true, RAPLWatts: 57.5,
+				CPUKnown: true, CPUPercent: 50, CPUTDPWatts: 65,
+			},
+			wantKnown: true, wantWatts: 200, wantSource: PowerSourceGPURAPL,
+		},
+		{
+			name: "tier2 GPU estimate never mixes in RAPL",
+			in: PowerInputs{
+				HasGPU: true, GPUPowerMeasured: false,
+				GPUUtilKnown: true, GPUUtilPercent: 40, GPUTDPWatts: 350,
+				RAPLMeasured: true, RAPLWatts: 57.5,
+			},
+			wantKnown: true, wantWatts: 140, wantSource: PowerSourceEstimated,
+		},
+		{
+			name: "tier3 RAPL replaces the CPU model on a CPU-only node",
+			in: PowerInputs{
+				HasGPU:       false,
+				RAPLMeasured: true, RAPLWatts: 48,
+				CPUKnown: true, CPUPercent: 25, CPUTDPWatts: 65,
+			},
+			wantKnown: true, wantWatts: 48, wantSource: PowerSourceRAPL,
+		},
 		{
 			name: "tier2 GPU util times TDP when no measured draw",
 			in: PowerInputs{
@@ -91,7 +121,7 @@ func TestEstimateNodePowerWaterfall(t *testing.T) {
 			wantKnown: true, wantWatts: 32.5, wantSource: PowerSourceEstimated,
 		},
 		{
-			name: "tier3 CPU floor (Apple Silicon / CPU-only node, no GPU)",
+			name: "tier4 CPU floor (Apple Silicon / CPU-only node, no GPU)",
 			in: PowerInputs{
 				HasGPU:   false,
 				CPUKnown: true, CPUPercent: 25, CPUTDPWatts: 60,
@@ -99,7 +129,7 @@ func TestEstimateNodePowerWaterfall(t *testing.T) {
 			wantKnown: true, wantWatts: 15, wantSource: PowerSourceEstimated,
 		},
 		{
-			name: "tier4 nothing usable yields unknown",
+			name: "tier5 nothing usable yields unknown",
 			in: PowerInputs{
 				HasGPU:   false,
 				CPUKnown: true, CPUPercent: 50, CPUTDPWatts: 0,
@@ -191,3 +221,21 @@ func TestParseWattsField(t *testing.T) {
 		}
 	}
 }
+
+func TestROCmPowerReading(t *testing.T) {
+	data, err := os.ReadFile("../platform/testdata/rocm-smi-mi210.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gpus, err := platform.ParseROCmSMI(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// card1 reports N/A power, so only card0's 97 W counts.
+	if watts, measured := rocmPowerReading(gpus); !measured || !approx(watts, 97) {
+		t.Errorf("rocmPowerReading = (%v, %v), want (97, true)", watts, measured)
+	}
+	if _, measured := rocmPowerReading(gpus[1:]); measured {
+		t.Error("a GPU without a power sensor must not count as measured")
+	}
+}
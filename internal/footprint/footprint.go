@@ -46,13 +46,15 @@ type Sample struct {
 	// NOT reimplement idle detection.
 	IdleSeconds *int
 
-	// PowerW is the estimated instantaneous node power in watts. Set only on the
-	// node-level row (per-service attribution is a deliberate next increment).
-	// Left empty when no defensible figure is available.
+	// PowerW is the estimated instantaneous power in watts. On the node-level row
+	// it is the whole node's figure; on a service row it is the service's cgroup
+	// CPU share of the RAPL-measured CPU package + DRAM power, set only when RAPL
+	// is readable. Left empty when no defensible figure is available.
 	PowerW *float64
 	// EnergyWh is the energy attributed to this sample's interval in watt-hours
 	// (PowerW times the sampling interval). Summing this column across node rows
-	// over a window yields the auditable energy total. Set only on the node row.
+	// over a window yields the auditable energy total; service rows are a
+	// breakdown of it and must not be added on top.
 	EnergyWh *float64
 	// PowerSource labels PowerW as measured (a real power sensor) or estimated (a
 	// modeled figure). Empty when no power figure was recorded.
@@ -0,0 +1,140 @@
+package footprint
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// The job log records the energy window each job ran in when the node has an
+// energy schedule (config.EnergySchedule), one row per finished job, so the
+// savings from holding deferrable jobs can be reported: arrival_value is the
+// tariff or carbon intensity of the hour the job reached the worker and
+// run_value that of the hour it started, so (arrival_value - run_value) x the
+// job's energy is what the deferral saved. It lives in a jobs/ subdirectory so
+// the footprints/*.csv glob the sample queries use never mixes the two schemas.
+
+// jobLogHeader is the fixed column order for job log CSVs.
+var jobLogHeader = []string{
+	"ts_start", "ts_end", "node_id", "job_id", "job_type", "status",
+	"deferred", "held_seconds", "window", "metric", "unit",
+	"arrival_value", "run_value",
+}
+
+// jobLogFilePattern matches jobs-YYYY-MM-DD.csv so pruning only touches the
+// log's own rotated files.
+var jobLogFilePattern = regexp.MustCompile(`^jobs-\d{4}-\d{2}-\d{2}\.csv$`)
+
+// JobWindow is one row of the job log.
+type JobWindow struct {
+	StartedAt   time.Time
+	CompletedAt time.Time
+	NodeID      string
+	JobID       string
+	JobType     string
+	// Status is the usage status the job finished with ("success", "failed", ...).
+	Status string
+	// Deferred reports whether the worker held the job for a cheap window.
+	Deferred bool
+	// Held is how long the job was held before it started.
+	Held time.Duration
+	// Window is "cheap" or "peak": the schedule window the job started in.
+	Window string
+	// Metric and Unit describe the values ("tariff", "USD/kWh").
+	Metric string
+	Unit   string
+	// ArrivalValue and RunValue are the schedule's values at arrival and start.
+	ArrivalValue float64
+	RunValue     float64
+}
+
+// JobLog appends JobWindow rows to a per-day CSV under dir. Unlike Store it is
+// written from the worker, whose jobs may finish concurrently, so Append is
+// serialised.
+type JobLog struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewJobLog returns a JobLog writing to dir, creating the directory if needed.
+func NewJobLog(dir string) (*JobLog, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("footprint: empty job log dir")
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("footprint: create job log dir: %w", err)
+	}
+	return &JobLog{dir: dir}, nil
+}
+
+// JobLogDir returns the job log directory under the footprints directory.
+func JobLogDir(footprintsDir string) string {
+	return filepath.Join(footprintsDir, "jobs")
+}
+
+// Append writes one row to the daily file for the job's start time, writing
+// the header when the file is new.
+func (l *JobLog) Append(jw JobWindow) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	path := filepath.Join(l.dir, fmt.Sprintf("jobs-%s.csv", jw.StartedAt.UTC().Format("2006-01-02")))
+	needHeader := false
+	if info, err := os.Stat(path); os.IsNotExist(err) || (err == nil && info.Size() == 0) {
+		needHeader = true
+	} else if err != nil {
+		return fmt.Errorf("footprint: stat %s: %w", path, err)
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("footprint: open %s: %w", path, err)
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(f)
+	if needHeader {
+		if err := w.Write(jobLogHeader); err != nil {
+			return fmt.Errorf("footprint: write header: %w", err)
+		}
+	}
+	if err := w.Write(jw.toRecord()); err != nil {
+		return fmt.Errorf("footprint: write row: %w", err)
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return fmt.Errorf("footprint: flush %s: %w", path, err)
+	}
+	return nil
+}
+
+// Prune deletes job log files older than retentionDays, as Store.Prune does.
+func (l *JobLog) Prune(now time.Time, retentionDays int) ([]string, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return pruneDailyFiles(l.dir, jobLogFilePattern, now, retentionDays)
+}
+
+// toRecord renders a row in jobLogHeader column order.
+func (jw JobWindow) toRecord() []string {
+	return []string{
+		jw.StartedAt.UTC().Format(time.RFC3339),
+		jw.CompletedAt.UTC().Format(time.RFC3339),
+		jw.NodeID,
+		jw.JobID,
+		jw.JobType,
+		jw.Status,
+		strconv.FormatBool(jw.Deferred),
+		strconv.Itoa(int(jw.Held.Seconds())),
+		jw.Window,
+		jw.Metric,
+		jw.Unit,
+		strconv.FormatFloat(jw.ArrivalValue, 'f', -1, 64),
+		strconv.FormatFloat(jw.RunValue, 'f', -1, 64),
+	}
+}
@@ -0,0 +1,69 @@
+package footprint
+
+import (
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestJobLogAppendAndPrune(t *testing.T) {
+	dir := JobLogDir(t.TempDir())
+	log, err := NewJobLog(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2026, 10, 14, 0, 5, 0, 0, time.UTC)
+	rows := []JobWindow{
+		{
+			StartedAt: start, CompletedAt: start.Add(90 * time.Second),
+			NodeID: "n", JobID: "j1", JobType: "FILE_INDEX", Status: "success",
+			Deferred: true, Held: 4*time.Hour + 30*time.Second, Window: "cheap",
+			Metric: "tariff", Unit: "USD/kWh", ArrivalValue: 0.3, RunValue: 0.1,
+		},
+		{
+			StartedAt: start.Add(time.Hour), CompletedAt: start.Add(time.Hour),
+			NodeID: "n", JobID: "j2", JobType: "SHELL_COMMAND", Status: "failed",
+			Window: "cheap", Metric: "tariff", Unit: "USD/kWh", ArrivalValue: 0.1, RunValue: 0.1,
+		},
+	}
+	for _, r := range rows {
+		if err := log.Append(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	f, err := os.Open(filepath.Join(dir, "jobs-2026-10-14.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	recs, err := csv.NewReader(f).ReadAll()
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recs) != 3 || !reflect.DeepEqual(recs[0], jobLogHeader) {
+		t.Fatalf("records = %v, want header + 2 rows", recs)
+	}
+	want := []string{
+		"2026-10-14T00:05:00Z", "2026-10-14T00:06:30Z", "n", "j1", "FILE_INDEX", "success",
+		"true", "14430", "cheap", "tariff", "USD/kWh", "0.3", "0.1",
+	}
+	if !reflect.DeepEqual(recs[1], want) {
+		t.Errorf("row = %v, want %v", recs[1], want)
+	}
+
+	// A week later the day's file is pruned; unrelated files are left alone.
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	pruned, err := log.Prune(start.AddDate(0, 0, 8), 7)
+	if err != nil || !reflect.DeepEqual(pruned, []string{"jobs-2026-10-14.csv"}) {
+		t.Errorf("Prune = %v, %v", pruned, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "notes.txt")); err != nil {
+		t.Error("unrelated file was pruned")
+	}
+}
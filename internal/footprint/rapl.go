@@ -0,0 +1,176 @@
+package footprint
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// raplBase is the powercap sysfs directory exposing the CPU's RAPL (Running
+// Average Power Limit) energy counters. Intel and AMD Zen both publish them
+// under intel-rapl. A package var (not a const) so tests can point the reader
+// at a fixture tree. On non-Linux hosts it simply does not exist and RAPL reads
+// report unavailable.
+var raplBase = "/sys/class/powercap"
+
+// raplZone is one RAPL counter we sum: a CPU package ("package-N") or its DRAM
+// subzone ("dram"). The core/uncore subzones are already inside the package
+// figure and psys spans the whole platform, so neither is read.
+type raplZone struct {
+	dir  string
+	kind string // "package" or "dram"
+}
+
+// raplCounter is one zone's cumulative energy reading.
+type raplCounter struct {
+	energyUJ uint64
+	// maxUJ is max_energy_range_uj, where the counter wraps back to zero.
+	maxUJ uint64
+}
+
+// RAPLReading is the CPU package and DRAM power averaged over the interval
+// between two counter reads.
+type RAPLReading struct {
+	PackageWatts float64
+	DRAMWatts    float64
+}
+
+// Watts is the package plus DRAM power.
+func (r RAPLReading) Watts() float64 { return r.PackageWatts + r.DRAMWatts }
+
+// raplReader turns RAPL's cumulative energy counters into average power. It
+// keeps the previous reading of each zone, so the first Read after
+// construction has nothing to diff against and reports ok=false.
+type raplReader struct {
+	base string
+
+	mu    sync.Mutex
+	zones []raplZone
+	prev  map[string]raplCounter
+	last  time.Time
+}
+
+// newRAPLReader returns a reader over the RAPL zones under base.
+func newRAPLReader(base string) *raplReader {
+	return &raplReader{base: base, zones: discoverRAPLZones(base)}
+}
+
+// Read samples every zone at now and returns the average power since the
+// previous Read. ok is false when there are no zones, a counter is unreadable
+// (energy_uj is root-only on kernels patched for CVE-2020-8694), or this is the
+// first read. Any failure is silent so the power waterfall falls through to the
+// CPU utilisation model.
+func (r *raplReader) Read(now time.Time) (RAPLReading, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.zones) == 0 {
+		return RAPLReading{}, false
+	}
+
+	cur := make(map[string]raplCounter, len(r.zones))
+	for _, z := range r.zones {
+		c, ok := readRAPLCounter(z.dir)
+		if !ok {
+			return RAPLReading{}, false
+		}
+		cur[z.dir] = c
+	}
+	prev, last := r.prev, r.last
+	r.prev, r.last = cur, now
+
+	elapsed := now.Sub(last).Seconds()
+	if prev == nil || elapsed <= 0 {
+		return RAPLReading{}, false
+	}
+	var reading RAPLReading
+	for _, z := range r.zones {
+		watts := float64(raplDeltaUJ(prev[z.dir], cur[z.dir])) / 1e6 / elapsed
+		if z.kind == "dram" {
+			reading.DRAMWatts += watts
+		} else {
+			reading.PackageWatts += watts
+		}
+	}
+	return reading, true
+}
+
+// raplDeltaUJ is the energy consumed between two readings of one counter. A
+// current value below the previous one means the counter wrapped at maxUJ in
+// between (a package counter wraps every few minutes to hours under load).
+// Without a known range, a wrap cannot be sized and counts as zero.
+func raplDeltaUJ(prev, cur raplCounter) uint64 {
+	if cur.energyUJ >= prev.energyUJ {
+		return cur.energyUJ - prev.energyUJ
+	}
+	if cur.maxUJ == 0 || prev.energyUJ > cur.maxUJ {
+		return 0
+	}
+	return cur.maxUJ - prev.energyUJ + cur.energyUJ
+}
+
+// discoverRAPLZones lists the package zones (intel-rapl:N) and their DRAM
+// subzones (intel-rapl:N:M named "dram") under base.
+func discoverRAPLZones(base string) []raplZone {
+	dirs, _ := filepath.Glob(filepath.Join(base, "intel-rapl:*"))
+	var zones []raplZone
+	for _, dir := range dirs {
+		name := readSysfsString(filepath.Join(dir, "name"))
+		switch {
+		case strings.HasPrefix(name, "package-"):
+			zones = append(zones, raplZone{dir: dir, kind: "package"})
+		case name == "dram":
+			zones = append(zones, raplZone{dir: dir, kind: "dram"})
+		}
+	}
+	return zones
+}
+
+// readRAPLCounter reads one zone's energy_uj and max_energy_range_uj.
+func readRAPLCounter(dir string) (raplCounter, bool) {
+	energy, err := strconv.ParseUint(readSysfsString(filepath.Join(dir, "energy_uj")), 10, 64)
+	if err != nil {
+		return raplCounter{}, false
+	}
+	maxRange, _ := strconv.ParseUint(readSysfsString(filepath.Join(dir, "max_energy_range_uj")), 10, 64)
+	return raplCounter{energyUJ: energy, maxUJ: maxRange}, true
+}
+
+func readSysfsString(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// cpuShares splits the node's CPU time across services by their container
+// (cgroup) CPU percentage. Container stats report CPU% per core (400% is four
+// cores busy) while the host figure is normalised to 0..100 across numCPU
+// cores, so a service's share is its CPU% over the host's busy core-percent.
+// Shares are scaled down when they sum past 1, which happens when the two
+// probes sample slightly different windows. Services without a CPU figure get
+// no share.
+func cpuShares(serviceCPU map[string]float64, hostCPUPercent float64, numCPU int) map[string]float64 {
+	busy := hostCPUPercent * float64(numCPU)
+	if busy <= 0 || len(serviceCPU) == 0 {
+		return nil
+	}
+	shares := make(map[string]float64, len(serviceCPU))
+	var total float64
+	for svc, pct := range serviceCPU {
+		if pct <= 0 {
+			continue
+		}
+		shares[svc] = pct / busy
+		total += shares[svc]
+	}
+	if total > 1 {
+		for svc := range shares {
+			shares[svc] /= total
+		}
+	}
+	return shares
+}
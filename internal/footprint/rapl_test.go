@@ -0,0 +1,123 @@
+package footprint
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeRAPLZone creates one powercap zone directory in a fixture tree.
+func writeRAPLZone(t *testing.T, base, zone, name string, energyUJ, maxUJ string) {
+	t.Helper()
+	dir := filepath.Join(base, zone)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for file, val := range map[string]string{"name": name, "energy_uj": energyUJ, "max_energy_range_uj": maxUJ} {
+		if err := os.WriteFile(filepath.Join(dir, file), []byte(val+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestRAPLReaderDeltas(t *testing.T) {
+	base := t.TempDir()
+	writeRAPLZone(t, base, "intel-rapl:0", "package-0", "1000000", "262143328850")
+	writeRAPLZone(t, base, "intel-rapl:0:0", "core", "500000", "262143328850")
+	writeRAPLZone(t, base, "intel-rapl:0:1", "dram", "200000", "65712999613")
+	writeRAPLZone(t, base, "intel-rapl:1", "psys", "9000000", "262143328850")
+
+	r := newRAPLReader(base)
+	if len(r.zones) != 2 {
+		t.Fatalf("zones = %+v, want package-0 and dram only", r.zones)
+	}
+	t0 := time.Unix(1000, 0)
+	if _, ok := r.Read(t0); ok {
+		t.Fatal("first read has no previous counters and must report ok=false")
+	}
+
+	// 10s later: package +600 J (60 W), dram +50 J (5 W). Core and psys move too
+	// but are not counted.
+	writeRAPLZone(t, base, "intel-rapl:0", "package-0", "601000000", "262143328850")
+	writeRAPLZone(t, base, "intel-rapl:0:0", "core", "400500000", "262143328850")
+	writeRAPLZone(t, base, "intel-rapl:0:1", "dram", "50200000", "65712999613")
+	writeRAPLZone(t, base, "intel-rapl:1", "psys", "999000000", "262143328850")
+	got, ok := r.Read(t0.Add(10 * time.Second))
+	if !ok {
+		t.Fatal("second read should report ok")
+	}
+	if !approx(got.PackageWatts, 60) || !approx(got.DRAMWatts, 5) || !approx(got.Watts(), 65) {
+		t.Errorf("reading = %+v, want 60 W package + 5 W dram", got)
+	}
+}
+
+func TestRAPLReaderWraparound(t *testing.T) {
+	base := t.TempDir()
+	writeRAPLZone(t, base, "intel-rapl:0", "package-0", "999000000", "1000000000")
+	r := newRAPLReader(base)
+	t0 := time.Unix(1000, 0)
+	r.Read(t0)
+
+	// Counter wrapped at 1000 J: 1 J to the wrap plus 99 J after it over 10s.
+	writeRAPLZone(t, base, "intel-rapl:0", "package-0", "99000000", "1000000000")
+	got, ok := r.Read(t0.Add(10 * time.Second))
+	if !ok || !approx(got.PackageWatts, 10) {
+		t.Errorf("wrapped reading = %+v (ok=%v), want 10 W", got, ok)
+	}
+}
+
+func TestRAPLReaderUnavailable(t *testing.T) {
+	if _, ok := newRAPLReader(t.TempDir()).Read(time.Now()); ok {
+		t.Error("no powercap zones must report ok=false")
+	}
+
+	// A root-only energy_uj reads as unparseable content here.
+	base := t.TempDir()
+	writeRAPLZone(t, base, "intel-rapl:0", "package-0", "", "262143328850")
+	r := newRAPLReader(base)
+	r.Read(time.Unix(1000, 0))
+	if _, ok := r.Read(time.Unix(1010, 0)); ok {
+		t.Error("an unreadable counter must report ok=false")
+	}
+}
+
+func TestRAPLDeltaUJ(t *testing.T) {
+	cases := []struct {
+		name      string
+		prev, cur raplCounter
+		want      uint64
+	}{
+		{"forward", raplCounter{energyUJ: 100, maxUJ: 1000}, raplCounter{energyUJ: 250, maxUJ: 1000}, 150},
+		{"wrapped", raplCounter{energyUJ: 900, maxUJ: 1000}, raplCounter{energyUJ: 50, maxUJ: 1000}, 150},
+		{"wrapped without range", raplCounter{energyUJ: 900}, raplCounter{energyUJ: 50}, 0},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := raplDeltaUJ(c.prev, c.cur); got != c.want {
+				t.Errorf("raplDeltaUJ = %d, want %d", got, c.want)
+			}
+		})
+	}
+}
+
+func TestCPUShares(t *testing.T) {
+	// 8 cores at 50% host CPU = 400 core-percent busy.
+	shares := cpuShares(map[string]float64{"vllm": 300, "tei": 60, "idle": 0}, 50, 8)
+	if !approx(shares["vllm"], 0.75) || !approx(shares["tei"], 0.15) {
+		t.Errorf("shares = %v, want vllm 0.75, tei 0.15", shares)
+	}
+	if _, ok := shares["idle"]; ok {
+		t.Error("a service with no CPU time must get no share")
+	}
+
+	// Probes sampled different windows: services claim more than the host.
+	shares = cpuShares(map[string]float64{"a": 300, "b": 100}, 25, 8)
+	if !approx(shares["a"]+shares["b"], 1) || !approx(shares["a"], 0.75) {
+		t.Errorf("over-claimed shares = %v, want normalised to 1", shares)
+	}
+
+	if cpuShares(map[string]float64{"a": 10}, 0, 8) != nil {
+		t.Error("an idle host must yield no shares")
+	}
+}
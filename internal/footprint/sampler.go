@@ -3,6 +3,7 @@ package footprint
 import (
 	"context"
 	"os/exec"
+	"runtime"
 	"strconv"
 	"strings"
 	"time"
@@ -47,6 +48,11 @@ type gpuFunc func() GPUSnapshot
 // path runs zero nvidia-smi power probes).
 type gpuPowerFunc func() (watts float64, measured bool, limitWatts float64)
 
+// raplFunc reads the CPU package + DRAM power averaged since its previous call.
+// Injected so the sampler is testable without RAPL counters, and called ONLY
+// when energy sampling is enabled.
+type raplFunc func(now time.Time) (RAPLReading, bool)
+
 // idleFunc returns the node's current idle-seconds signal and whether it is
 // available. Injected; the default returns (0, false) because #420's idle signal
 // is not wired into this branch and this package must NOT reimplement idle
@@ -69,16 +75,20 @@ type Sampler struct {
 	// interval is the sampling cadence, used to convert instantaneous power_w into
 	// per-interval energy_wh. Zero leaves energy_wh blank.
 	interval time.Duration
+	// numCPU is the host core count used to turn container CPU% into a share of
+	// the host's CPU time. Zero means runtime.NumCPU().
+	numCPU int
 
 	stats    statsFunc
 	gpu      gpuFunc
 	gpuPower gpuPowerFunc
+	rapl     raplFunc
 	idle     idleFunc
 }
 
 // NewSampler wires a Sampler to the real host probes. interval is the sampling
 // cadence (used for energy_wh); powerCfg carries the resolved TDP knobs; energy
-// turns the power estimate (and its nvidia-smi power probe) on. When energy is
+// turns the power estimate (and its nvidia-smi power and RAPL probes) on. When energy is
 // false the sampler behaves exactly as before the energy feature existed.
 func NewSampler(nodeID string, services []string, engineBin string, interval time.Duration, powerCfg PowerConfig, energy bool) *Sampler {
 	return &Sampler{
@@ -91,6 +101,7 @@ func NewSampler(nodeID string, services []string, engineBin string, interval tim
 		stats:     sampleContainerStats,
 		gpu:       sampleGPU,
 		gpuPower:  readGPUPowerReading,
+		rapl:      newRAPLReader(raplBase).Read,
 		idle:      func() (int, bool) { return 0, false },
 	}
 }
@@ -163,8 +174,9 @@ func (s *Sampler) Sample(ctx context.Context, ts time.Time) []Sample {
 	}
 
 	// Node-level energy estimate: opt-in (default OFF). Only when enabled do we run
-	// the GPU power probe and stamp power_w / energy_wh / power_source. When off,
-	// this is a no-op so the tick is byte-identical to the pre-energy footprint.
+	// the GPU power and RAPL probes and stamp power_w / energy_wh / power_source.
+	// When off, this is a no-op so the tick is byte-identical to the pre-energy
+	// footprint.
 	if s.energy {
 		if snap.HasGPU && s.gpuPower != nil {
 			watts, measured, limit := s.gpuPower()
@@ -172,7 +184,15 @@ func (s *Sampler) Sample(ctx context.Context, ts time.Time) []Sample {
 			snap.PowerMeasured = measured
 			snap.PowerLimitWatts = limit
 		}
-		s.fillNodePower(&node, snap, cpuPct, cpuOK)
+		var rapl RAPLReading
+		raplOK := false
+		if s.rapl != nil {
+			rapl, raplOK = s.rapl(ts)
+		}
+		s.fillNodePower(&node, snap, rapl, raplOK, cpuPct, cpuOK)
+		if raplOK && cpuOK {
+			s.fillServicePower(rows, rapl, cpuPct)
+		}
 	}
 
 	rows = append(rows, node)
@@ -182,7 +202,7 @@ func (s *Sampler) Sample(ctx context.Context, ts time.Time) []Sample {
 // fillNodePower runs the power waterfall for the node row and, when a figure is
 // available, sets power_w / energy_wh / power_source. It never fails: an absent
 // signal simply leaves the fields blank.
-func (s *Sampler) fillNodePower(node *Sample, snap GPUSnapshot, cpuPct float64, cpuOK bool) {
+func (s *Sampler) fillNodePower(node *Sample, snap GPUSnapshot, rapl RAPLReading, raplOK bool, cpuPct float64, cpuOK bool) {
 	gpuTDP := s.powerCfg.GPUTDPWattsOverride
 	if gpuTDP <= 0 {
 		gpuTDP = snap.PowerLimitWatts
@@ -194,6 +214,8 @@ func (s *Sampler) fillNodePower(node *Sample, snap GPUSnapshot, cpuPct float64,
 		GPUUtilKnown:     snap.HasGPU,
 		GPUUtilPercent:   snap.GPUUtilPercent,
 		GPUTDPWatts:      gpuTDP,
+		RAPLMeasured:     raplOK,
+		RAPLWatts:        rapl.Watts(),
 		CPUKnown:         cpuOK,
 		CPUPercent:       cpuPct,
 		CPUTDPWatts:      s.powerCfg.CPUTDPWatts,
@@ -209,6 +231,36 @@ func (s *Sampler) fillNodePower(node *Sample, snap GPUSnapshot, cpuPct float64,
 	}
 }
 
+// fillServicePower attributes the measured RAPL CPU package + DRAM power to the
+// running services by their cgroup CPU share, labelled measured_rapl. GPU power
+// is not attributed (nvidia-smi is not container-aware), so a service row's
+// power_w is its CPU-side draw only. Services with no CPU figure stay blank.
+func (s *Sampler) fillServicePower(rows []Sample, rapl RAPLReading, hostCPU float64) {
+	numCPU := s.numCPU
+	if numCPU <= 0 {
+		numCPU = runtime.NumCPU()
+	}
+	serviceCPU := make(map[string]float64, len(rows))
+	for _, r := range rows {
+		if r.Running && r.CPUPercent != nil {
+			serviceCPU[r.Service] = *r.CPUPercent
+		}
+	}
+	shares := cpuShares(serviceCPU, hostCPU, numCPU)
+	for i := range rows {
+		share, ok := shares[rows[i].Service]
+		if !ok {
+			continue
+		}
+		watts := share * rapl.Watts()
+		rows[i].PowerW = &watts
+		rows[i].PowerSource = PowerSourceRAPL
+		if wh := energyWh(watts, s.interval); wh > 0 {
+			rows[i].EnergyWh = &wh
+		}
+	}
+}
+
 // matchContainer returns the first stats row whose container name contains the
 // service name (case-insensitive). Compose containers are named like
 // "<project>-<service>-1", so a substring match reliably attributes them to the
@@ -250,10 +302,11 @@ func sampleGPU() GPUSnapshot {
 // readGPUPowerReading runs a single read-only nvidia-smi query for board power
 // draw and the enforced power limit, summed across GPUs. It is intentionally
 // footprint-local (not a change to the shared internal/platform GPU detector) so
-// this package stays self-contained per its package doc. Any failure is silent:
-// it returns measured=false and zero watts, so the estimator falls through to a
-// util or CPU model. This never prompts for privileges. It is called ONLY when
-// energy sampling is enabled.
+// this package stays self-contained per its package doc. On AMD nodes it falls
+// back to rocm-smi's package power, which reports no limit. Any failure is
+// silent: it returns measured=false and zero watts, so the estimator falls
+// through to a util or CPU model. This never prompts for privileges. It is
+// called ONLY when energy sampling is enabled.
 func readGPUPowerReading() (watts float64, measured bool, limitWatts float64) {
 	cmd := exec.Command(
 		"nvidia-smi",
@@ -262,7 +315,12 @@ func readGPUPowerReading() (watts float64, measured bool, limitWatts float64) {
 	)
 	out, err := cmd.Output()
 	if err != nil {
-		return 0, false, 0
+		gpus, rerr := platform.QueryROCmGPUs(context.Background())
+		if rerr != nil {
+			return 0, false, 0
+		}
+		watts, measured = rocmPowerReading(gpus)
+		return watts, measured, 0
 	}
 	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
 		parts := strings.Split(line, ",")
@@ -280,6 +338,18 @@ func readGPUPowerReading() (watts float64, measured bool, limitWatts float64) {
 	return watts, measured, limitWatts
 }
 
+// rocmPowerReading sums the package power rocm-smi reported across GPUs;
+// measured is false when no GPU reported one.
+func rocmPowerReading(gpus []platform.ROCmGPU) (watts float64, measured bool) {
+	for _, g := range gpus {
+		if g.PowerW >= 0 {
+			watts += g.PowerW
+			measured = true
+		}
+	}
+	return watts, measured
+}
+
 // parseWattsField parses an nvidia-smi power field (already "nounits", e.g.
 // "142.35"). It rejects the sentinel values nvidia-smi emits when a sensor is
 // absent ("[N/A]", "[Not Supported]", "[Insufficient Permissions]"), returning
@@ -244,6 +244,57 @@ func TestSamplerEnergyEnabledInvokesProbe(t *testing.T) {
 	}
 }
 
+// TestSamplerNodeRowAddsRAPLToGPUDraw verifies the RAPL probe runs with energy
+// sampling on and its measured CPU + DRAM power joins the measured GPU draw.
+func TestSamplerNodeRowAddsRAPLToGPUDraw(t *testing.T) {
+	s := &Sampler{
+		nodeID:    "n",
+		engineBin: "docker",
+		energy:    true,
+		interval:  time.Hour,
+		powerCfg:  PowerConfig{CPUTDPWatts: 65},
+		stats:     func(ctx context.Context, _ string) ([]containerStat, error) { return nil, nil },
+		gpu:       func() GPUSnapshot { return GPUSnapshot{HasGPU: true} },
+		gpuPower:  func() (float64, bool, float64) { return 200, true, 350 },
+		rapl: func(time.Time) (RAPLReading, bool) {
+			return RAPLReading{PackageWatts: 90, DRAMWatts: 10}, true
+		},
+		idle: func() (int, bool) { return 0, false },
+	}
+	node := s.Sample(context.Background(), time.Now())[0]
+	if node.PowerW == nil || !approx(*node.PowerW, 300) || node.PowerSource != PowerSourceGPURAPL {
+		t.Errorf("node power = %v (%q), want 300 W measured_gpu_rapl", node.PowerW, node.PowerSource)
+	}
+}
+
+// TestFillServicePowerSplitsByCPUShare verifies RAPL power is split across the
+// running service rows by their container CPU share of the host.
+func TestFillServicePowerSplitsByCPUShare(t *testing.T) {
+	s := &Sampler{interval: time.Hour, numCPU: 8}
+	vllmCPU, teiCPU := 300.0, 100.0
+	rows := []Sample{
+		{Service: "vllm", Running: true, CPUPercent: &vllmCPU},
+		{Service: "tei", Running: true, CPUPercent: &teiCPU},
+		{Service: "diffusers"},
+	}
+	// 8 cores at 50% = 400 core-percent busy: vllm holds 75%, tei 25%.
+	s.fillServicePower(rows, RAPLReading{PackageWatts: 90, DRAMWatts: 10}, 50)
+
+	vllm, tei := rows[0], rows[1]
+	if vllm.PowerW == nil || !approx(*vllm.PowerW, 75) || vllm.PowerSource != PowerSourceRAPL {
+		t.Errorf("vllm power = %v (%q), want 75 W measured_rapl", vllm.PowerW, vllm.PowerSource)
+	}
+	if tei.PowerW == nil || !approx(*tei.PowerW, 25) {
+		t.Errorf("tei power = %v, want 25 W", tei.PowerW)
+	}
+	if vllm.EnergyWh == nil || !approx(*vllm.EnergyWh, 75) {
+		t.Errorf("vllm energy_wh = %v, want 75 over one hour", vllm.EnergyWh)
+	}
+	if d := rows[2]; d.PowerW != nil || d.PowerSource != PowerSourceUnknown {
+		t.Errorf("a service that is not running must carry no power, got %+v", d)
+	}
+}
+
 func TestSamplerIdleSignalWiredThrough(t *testing.T) {
 	s := &Sampler{
 		nodeID:    "n",
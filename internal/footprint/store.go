@@ -7,6 +7,7 @@ import (
 	"path/filepath"
 	"regexp"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -168,10 +169,16 @@ func intField(v *int) string {
 // files in the directory are left untouched. A retentionDays <= 0 is treated as
 // "keep everything" (pruning disabled). Returns the names of pruned files.
 func (s *Store) Prune(now time.Time, retentionDays int) ([]string, error) {
+	return pruneDailyFiles(s.dir, dailyFilePattern, now, retentionDays)
+}
+
+// pruneDailyFiles is Store.Prune for any directory of <prefix>-YYYY-MM-DD.csv
+// files, considering only names matching pattern.
+func pruneDailyFiles(dir string, pattern *regexp.Regexp, now time.Time, retentionDays int) ([]string, error) {
 	if retentionDays <= 0 {
 		return nil, nil
 	}
-	entries, err := os.ReadDir(s.dir)
+	entries, err := os.ReadDir(dir)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return nil, nil
@@ -185,7 +192,7 @@ func (s *Store) Prune(now time.Time, retentionDays int) ([]string, error) {
 
 	var pruned []string
 	for _, e := range entries {
-		if e.IsDir() || !dailyFilePattern.MatchString(e.Name()) {
+		if e.IsDir() || !pattern.MatchString(e.Name()) {
 			continue
 		}
 		day, err := dayFromFileName(e.Name())
@@ -193,7 +200,7 @@ func (s *Store) Prune(now time.Time, retentionDays int) ([]string, error) {
 			continue // Malformed date despite pattern match: leave it alone.
 		}
 		if day.Before(cutoff) {
-			if err := os.Remove(filepath.Join(s.dir, e.Name())); err != nil {
+			if err := os.Remove(filepath.Join(dir, e.Name())); err != nil {
 				return pruned, fmt.Errorf("footprint: remove %s: %w", e.Name(), err)
 			}
 			pruned = append(pruned, e.Name())
@@ -202,9 +209,13 @@ func (s *Store) Prune(now time.Time, retentionDays int) ([]string, error) {
 	return pruned, nil
 }
 
-// dayFromFileName parses the YYYY-MM-DD date out of a footprints-*.csv name.
+// dayFromFileName parses the YYYY-MM-DD date out of a footprints-*.csv (or
+// jobs-*.csv) name.
 func dayFromFileName(name string) (time.Time, error) {
 	// footprints-2006-01-02.csv -> 2006-01-02
-	base := name[len("footprints-") : len(name)-len(".csv")]
-	return time.Parse("2006-01-02", base)
+	base := strings.TrimSuffix(name, ".csv")
+	if len(base) < len("2006-01-02") {
+		return time.Time{}, fmt.Errorf("footprint: no date in %q", name)
+	}
+	return time.Parse("2006-01-02", base[len(base)-len("2006-01-02"):])
 }
@@ -0,0 +1,375 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// audio_route.go serves OpenAI's audio endpoints from the node's speech
+// sidecars, the same ones that back the TRANSCRIBE_AUDIO (faster-whisper) and
+// SYNTHESIZE_SPEECH (kokoro) jobs:
+//
+//   - POST /v1/audio/transcriptions takes a multipart upload and answers in any
+//     of OpenAI's response formats (json, text, verbose_json, srt, vtt). The
+//     whisper sidecar only reads files from the node workspace and returns its
+//     own JSON, so the upload is staged there by the Transcriber and the
+//     response is rendered here.
+//   - POST /v1/audio/speech is reverse-proxied to kokoro, which already speaks
+//     the OpenAI request and returns raw audio.
+//
+// Both wait for their sidecar with the job handlers' readiness policy (patient
+// while a model loads, fast-fail when nothing listens) and answer 503 with an
+// OpenAI-shaped error when it never becomes ready. Neither response carries an
+// OpenAI usage object the MeteringMiddleware can parse, so the handlers report
+// usage in X-Citadel-Usage-* headers instead (see usageFromHeaders).
+
+const (
+	// maxAudioUploadBytes is OpenAI's transcription upload limit (25 MB). The
+	// multipart envelope gets a little headroom on top.
+	maxAudioUploadBytes = 25 << 20
+
+	// maxAudioFormMemory is how much of a multipart upload is held in memory;
+	// the remainder spills to a temp file until the Transcriber stages it.
+	maxAudioFormMemory = 8 << 20
+
+	// maxSpeechBody bounds a speech request body. kokoro caps the input at a
+	// few thousand characters itself; this only stops an unbounded read.
+	maxSpeechBody = 1 << 20
+
+	// audioRequestDeadline replaces the server's short read/write timeouts for
+	// audio requests: an upload can take longer than ReadTimeout to arrive, and
+	// a long recording takes far longer than WriteTimeout to transcribe.
+	audioRequestDeadline = time.Hour
+
+	// audioTokensPerSecond converts audio duration into the token-equivalents
+	// the ledger and ACET pricing are denominated in.
+	audioTokensPerSecond = 10
+
+	// transcriptionModel and speechModel label audio usage in the ledger. They
+	// name the serving sidecar, not the model the client asked for (OpenAI SDKs
+	// require one, e.g. "whisper-1" or "tts-1", and it is accepted as-is).
+	transcriptionModel = "faster-whisper"
+	speechModel        = "kokoro"
+
+	// defaultSpeechFormat is OpenAI's default speech format. kokoro's own
+	// default is opus, so requests that omit response_format get it set.
+	defaultSpeechFormat = "mp3"
+)
+
+// Transcriber is the local speech-to-text backend. cmd wires the
+// TRANSCRIBE_AUDIO job handler, so the gateway and jobs share one readiness
+// policy and one staging path into the whisper sidecar.
+type Transcriber interface {
+	// WaitReady blocks until the sidecar can transcribe, or returns why not.
+	WaitReady(ctx context.Context) error
+	// TranscribeUpload transcribes audio and returns the sidecar's JSON:
+	// {"text", "language", "segments": [{"start", "end", "text"}]}.
+	TranscribeUpload(ctx context.Context, audio io.Reader, filename, language string) ([]byte, error)
+}
+
+// SpeechSynthesizer is the local text-to-speech backend. cmd wires the
+// SYNTHESIZE_SPEECH job handler.
+type SpeechSynthesizer interface {
+	// WaitReady blocks until the sidecar can synthesize, or returns why not.
+	WaitReady() error
+	// BaseURL is the sidecar's OpenAI-compatible base URL.
+	BaseURL() string
+}
+
+// SetAudioRouter enables the OpenAI audio routes. Either backend may be nil,
+// in which case its route answers 404 like an unknown model. Must be called
+// before Start.
+func (s *Server) SetAudioRouter(stt Transcriber, tts SpeechSynthesizer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.transcriber = stt
+	s.synthesizer = tts
+}
+
+// registerAudioRoutes wires the audio handlers onto the mux. It is called from
+// Start (and directly from tests), as registerChatRoutes is.
+func (s *Server) registerAudioRoutes() {
+	s.mux.Handle("/v1/audio/transcriptions", http.HandlerFunc(s.handleTranscriptions))
+	s.mux.Handle("/v1/audio/speech", http.HandlerFunc(s.handleSpeech))
+}
+
+// whisperResult is the whisper sidecar's transcription JSON.
+type whisperResult struct {
+	Text     string `json:"text"`
+	Language string `json:"language"`
+	Segments []struct {
+		Start float64 `json:"start"`
+		End   float64 `json:"end"`
+		Text  string  `json:"text"`
+	} `json:"segments"`
+}
+
+// duration is the transcribed audio's length as far as speech was detected.
+// The sidecar does not report the file's duration, so trailing silence is not
+// counted.
+func (t *whisperResult) duration() float64 {
+	if n := len(t.Segments); n > 0 {
+		return t.Segments[n-1].End
+	}
+	return 0
+}
+
+// handleTranscriptions implements POST /v1/audio/transcriptions.
+func (s *Server) handleTranscriptions(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	stt := s.transcriber
+	s.mu.RUnlock()
+
+	if stt == nil {
+		writeChatError(w, http.StatusNotFound, "model_not_found", "transcription not enabled on this node")
+		return
+	}
+	if r.Method != http.MethodPost {
+		writeChatError(w, http.StatusMethodNotAllowed, "invalid_request_error", "use POST")
+		return
+	}
+	extendAudioDeadlines(w)
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxAudioUploadBytes+1<<20)
+	if err := r.ParseMultipartForm(maxAudioFormMemory); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeChatError(w, http.StatusRequestEntityTooLarge, "invalid_request_error",
+				fmt.Sprintf("audio file exceeds %d MB", maxAudioUploadBytes>>20))
+			return
+		}
+		writeChatError(w, http.StatusBadRequest, "invalid_request_error",
+			fmt.Sprintf("expected a multipart/form-data upload of at most %d MB: %v", maxAudioUploadBytes>>20, err))
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	format := r.FormValue("response_format")
+	if format == "" {
+		format = "json"
+	}
+	switch format {
+	case "json", "text", "verbose_json", "srt", "vtt":
+	default:
+		writeChatError(w, http.StatusBadRequest, "invalid_request_error",
+			fmt.Sprintf("unsupported response_format %q (want json, text, verbose_json, srt or vtt)", format))
+		return
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		writeChatError(w, http.StatusBadRequest, "invalid_request_error", "missing 'file' upload")
+		return
+	}
+	defer file.Close()
+	if header.Size > maxAudioUploadBytes {
+		writeChatError(w, http.StatusRequestEntityTooLarge, "invalid_request_error",
+			fmt.Sprintf("audio file exceeds %d MB", maxAudioUploadBytes>>20))
+		return
+	}
+
+	if err := stt.WaitReady(r.Context()); err != nil {
+		writeChatError(w, http.StatusServiceUnavailable, "service_unavailable", err.Error())
+		return
+	}
+	raw, err := stt.TranscribeUpload(r.Context(), file, header.Filename, strings.TrimSpace(r.FormValue("language")))
+	if err != nil {
+		log.Printf("[Gateway] transcription failed: %v (%s)", err, bytes.TrimSpace(raw))
+		writeChatError(w, http.StatusBadGateway, "upstream_error", "transcription failed: "+err.Error())
+		return
+	}
+	var result whisperResult
+	if err := json.Unmarshal(raw, &result); err != nil {
+		writeChatError(w, http.StatusBadGateway, "upstream_error", "transcription service returned invalid JSON")
+		return
+	}
+
+	setUsageHeaders(w.Header(), transcriptionModel, audioTokens(result.duration()), textTokens(result.Text))
+	writeTranscription(w, format, &result)
+}
+
+// writeTranscription renders a whisper result in an OpenAI response format.
+func writeTranscription(w http.ResponseWriter, format string, t *whisperResult) {
+	usage := map[string]any{"type": "duration", "seconds": math.Ceil(t.duration())}
+	switch format {
+	case "text":
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		fmt.Fprintln(w, t.Text)
+	case "srt", "vtt":
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		io.WriteString(w, formatSubtitles(format, t))
+	case "verbose_json":
+		type segment struct {
+			ID    int     `json:"id"`
+			Start float64 `json:"start"`
+			End   float64 `json:"end"`
+			Text  string  `json:"text"`
+		}
+		segments := make([]segment, len(t.Segments))
+		for i, s := range t.Segments {
+			segments[i] = segment{ID: i, Start: s.Start, End: s.End, Text: s.Text}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"task":     "transcribe",
+			"language": t.Language,
+			"duration": t.duration(),
+			"text":     t.Text,
+			"segments": segments,
+			"usage":    usage,
+		})
+	default:
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"text": t.Text, "usage": usage})
+	}
+}
+
+// formatSubtitles renders segments as SubRip ("srt") or WebVTT ("vtt") cues.
+func formatSubtitles(format string, t *whisperResult) string {
+	var b strings.Builder
+	sep := ","
+	if format == "vtt" {
+		b.WriteString("WEBVTT\n\n")
+		sep = "."
+	}
+	for i, s := range t.Segments {
+		if format == "srt" {
+			fmt.Fprintf(&b, "%d\n", i+1)
+		}
+		fmt.Fprintf(&b, "%s --> %s\n%s\n\n", subtitleTime(s.Start, sep), subtitleTime(s.End, sep), strings.TrimSpace(s.Text))
+	}
+	return b.String()
+}
+
+// subtitleTime formats seconds as HH:MM:SS<sep>mmm.
+func subtitleTime(seconds float64, sep string) string {
+	ms := int64(math.Round(seconds * 1000))
+	if ms < 0 {
+		ms = 0
+	}
+	return fmt.Sprintf("%02d:%02d:%02d%s%03d", ms/3600000, ms/60000%60, ms/1000%60, sep, ms%1000)
+}
+
+// handleSpeech implements POST /v1/audio/speech by proxying to kokoro.
+func (s *Server) handleSpeech(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	tts := s.synthesizer
+	nodeName := s.config.NodeName
+	s.mu.RUnlock()
+
+	if tts == nil {
+		writeChatError(w, http.StatusNotFound, "model_not_found", "speech synthesis not enabled on this node")
+		return
+	}
+	if r.Method != http.MethodPost {
+		writeChatError(w, http.StatusMethodNotAllowed, "invalid_request_error", "use POST")
+		return
+	}
+	extendAudioDeadlines(w)
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxSpeechBody))
+	_ = r.Body.Close()
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		writeChatError(w, http.StatusRequestEntityTooLarge, "invalid_request_error",
+			fmt.Sprintf("request body exceeds %d MB", maxSpeechBody>>20))
+		return
+	}
+	if err != nil {
+		writeChatError(w, http.StatusBadRequest, "invalid_request_error", "failed to read request body")
+		return
+	}
+	var req map[string]json.RawMessage
+	if err := json.Unmarshal(body, &req); err != nil {
+		writeChatError(w, http.StatusBadRequest, "invalid_request_error", "request body must be a JSON object")
+		return
+	}
+	var input string
+	_ = json.Unmarshal(req["input"], &input)
+	if strings.TrimSpace(input) == "" {
+		writeChatError(w, http.StatusBadRequest, "invalid_request_error", "'input' is required")
+		return
+	}
+	if _, ok := req["response_format"]; !ok {
+		req["response_format"], _ = json.Marshal(defaultSpeechFormat)
+		body, _ = json.Marshal(req)
+	}
+
+	if err := tts.WaitReady(); err != nil {
+		writeChatError(w, http.StatusServiceUnavailable, "service_unavailable", err.Error())
+		return
+	}
+	target, err := url.Parse(tts.BaseURL())
+	if err != nil {
+		writeChatError(w, http.StatusBadGateway, "upstream_error", "invalid speech service address")
+		return
+	}
+
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	r.ContentLength = int64(len(body))
+	proxy := &httputil.ReverseProxy{
+		Director: func(req *http.Request) {
+			req.URL.Scheme = target.Scheme
+			req.URL.Host = target.Host
+			req.Host = target.Host
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("X-Forwarded-For", req.RemoteAddr)
+			req.Header.Set("X-Forwarded-Proto", "https")
+			if nodeName != "" {
+				req.Header.Set("X-Citadel-Node", nodeName)
+			}
+		},
+		ModifyResponse: func(resp *http.Response) error {
+			if resp.StatusCode != http.StatusOK {
+				return nil
+			}
+			chars, err := strconv.Atoi(resp.Header.Get("X-TTS-Chars"))
+			if err != nil {
+				chars = utf8.RuneCountInString(input)
+			}
+			secs, _ := strconv.ParseFloat(resp.Header.Get("X-TTS-Duration-Seconds"), 64)
+			setUsageHeaders(resp.Header, speechModel, (chars+3)/4, audioTokens(secs))
+			return nil
+		},
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			log.Printf("[Gateway] speech proxy error -> %s: %v", target.Host, err)
+			writeChatError(w, http.StatusBadGateway, "upstream_error", "speech service unavailable")
+		},
+	}
+	proxy.ServeHTTP(w, r)
+}
+
+// extendAudioDeadlines lifts the server's read/write timeouts for one audio
+// request. Best effort: a writer that cannot set deadlines keeps the defaults.
+func extendAudioDeadlines(w http.ResponseWriter) {
+	rc := http.NewResponseController(w)
+	deadline := time.Now().Add(audioRequestDeadline)
+	_ = rc.SetReadDeadline(deadline)
+	_ = rc.SetWriteDeadline(deadline)
+}
+
+// audioTokens converts an audio duration to token-equivalents, rounding up.
+func audioTokens(seconds float64) int {
+	if seconds <= 0 {
+		return 0
+	}
+	return int(math.Ceil(seconds * audioTokensPerSecond))
+}
+
+// textTokens estimates the tokens in s at the usual four characters per token.
+func textTokens(s string) int {
+	return (utf8.RuneCountInString(s) + 3) / 4
+}
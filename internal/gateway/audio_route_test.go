@@ -0,0 +1,259 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// fakeTranscriber stands in for the TRANSCRIBE_AUDIO handler.
+type fakeTranscriber struct {
+	readyErr error
+	result   string
+	gotAudio string
+	gotName  string
+	gotLang  string
+}
+
+func (f *fakeTranscriber) WaitReady(context.Context) error { return f.readyErr }
+
+func (f *fakeTranscriber) TranscribeUpload(_ context.Context, audio io.Reader, filename, language string) ([]byte, error) {
+	b, _ := io.ReadAll(audio)
+	f.gotAudio, f.gotName, f.gotLang = string(b), filename, language
+	return []byte(f.result), nil
+}
+
+// fakeSynthesizer points the speech route at a stub kokoro server.
+type fakeSynthesizer struct {
+	readyErr error
+	url      string
+}
+
+func (f *fakeSynthesizer) WaitReady() error { return f.readyErr }
+func (f *fakeSynthesizer) BaseURL() string  { return f.url }
+
+const whisperJSON = `{"text":"hello there world","language":"en","segments":[` +
+	`{"start":0.0,"end":1.5,"text":"hello there"},{"start":1.5,"end":3.25,"text":"world"}]}`
+
+// newAudioGateway registers the audio routes through the same path Start uses.
+func newAudioGateway(stt Transcriber, tts SpeechSynthesizer) *Server {
+	gw := NewServer(Config{Port: 0, NodeName: "test-node"})
+	gw.SetAudioRouter(stt, tts)
+	gw.registerAudioRoutes()
+	return gw
+}
+
+// transcriptionRequest builds a multipart upload as the OpenAI SDKs send it.
+func transcriptionRequest(t *testing.T, fields map[string]string) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, err := mw.CreateFormFile("file", "clip.wav")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fw.Write([]byte("RIFFfakeaudio"))
+	for k, v := range fields {
+		mw.WriteField(k, v)
+	}
+	mw.Close()
+	req := httptest.NewRequest(http.MethodPost, "/v1/audio/transcriptions", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return req
+}
+
+func TestTranscriptionsResponseFormats(t *testing.T) {
+	tests := []struct {
+		format string
+		want   []string
+	}{
+		{"", []string{`"text":"hello there world"`, `"usage":{"seconds":4,"type":"duration"}`}},
+		{"text", []string{"hello there world\n"}},
+		{"verbose_json", []string{`"task":"transcribe"`, `"language":"en"`, `"duration":3.25`, `"id":1`}},
+		{"srt", []string{"1\n00:00:00,000 --> 00:00:01,500\nhello there\n\n2\n00:00:01,500 --> 00:00:03,250\nworld\n"}},
+		{"vtt", []string{"WEBVTT\n\n00:00:00.000 --> 00:00:01.500\nhello there\n"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			stt := &fakeTranscriber{result: whisperJSON}
+			gw := newAudioGateway(stt, nil)
+			fields := map[string]string{"model": "whisper-1", "language": "en"}
+			if tt.format != "" {
+				fields["response_format"] = tt.format
+			}
+			w := httptest.NewRecorder()
+			gw.mux.ServeHTTP(w, transcriptionRequest(t, fields))
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200; body=%s", w.Code, w.Body.String())
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(w.Body.String(), want) {
+					t.Errorf("body %q missing %q", w.Body.String(), want)
+				}
+			}
+			if stt.gotAudio != "RIFFfakeaudio" || stt.gotName != "clip.wav" || stt.gotLang != "en" {
+				t.Errorf("backend got audio=%q name=%q lang=%q", stt.gotAudio, stt.gotName, stt.gotLang)
+			}
+			// 3.25s of audio at 10/s = 33 in; "hello there world" = 5 out.
+			if got := w.Header().Get(usagePromptTokensHeader); got != "33" {
+				t.Errorf("prompt tokens header = %q, want 33", got)
+			}
+			if got := w.Header().Get(usageCompletionTokensHeader); got != "5" {
+				t.Errorf("completion tokens header = %q, want 5", got)
+			}
+		})
+	}
+}
+
+func TestTranscriptionsRejectsBadRequests(t *testing.T) {
+	gw := newAudioGateway(&fakeTranscriber{result: whisperJSON}, nil)
+
+	w := httptest.NewRecorder()
+	gw.mux.ServeHTTP(w, transcriptionRequest(t, map[string]string{"response_format": "docx"}))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("unsupported format: status = %d, want 400", w.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/audio/transcriptions", strings.NewReader(`{"file":"x"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	gw.mux.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("non-multipart body: status = %d, want 400", w.Code)
+	}
+
+	var big bytes.Buffer
+	mw := multipart.NewWriter(&big)
+	fw, _ := mw.CreateFormFile("file", "long.wav")
+	fw.Write(make([]byte, maxAudioUploadBytes+2<<20))
+	mw.Close()
+	req = httptest.NewRequest(http.MethodPost, "/v1/audio/transcriptions", &big)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	w = httptest.NewRecorder()
+	gw.mux.ServeHTTP(w, req)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("oversized upload: status = %d, want 413", w.Code)
+	}
+}
+
+func TestAudioRoutesUnavailableBackend503(t *testing.T) {
+	notReady := errors.New("transcription service unreachable at http://localhost:8101")
+	gw := newAudioGateway(&fakeTranscriber{readyErr: notReady}, &fakeSynthesizer{readyErr: notReady})
+
+	w := httptest.NewRecorder()
+	gw.mux.ServeHTTP(w, transcriptionRequest(t, nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("transcriptions: status = %d, want 503", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	gw.mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/audio/speech", strings.NewReader(`{"input":"hi"}`)))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("speech: status = %d, want 503", w.Code)
+	}
+	var resp struct {
+		Error struct{ Message, Type string } `json:"error"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || resp.Error.Type != "service_unavailable" {
+		t.Errorf("error body = %s, want an OpenAI-shaped service_unavailable error", w.Body.String())
+	}
+}
+
+func TestAudioRoutesNotEnabled404(t *testing.T) {
+	gw := newAudioGateway(nil, nil)
+	w := httptest.NewRecorder()
+	gw.mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/audio/speech", strings.NewReader(`{"input":"hi"}`)))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want 404", w.Code)
+	}
+}
+
+func TestSpeechProxiesToKokoro(t *testing.T) {
+	var got map[string]any
+	kokoro := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/audio/speech" {
+			t.Errorf("kokoro got path %q, want /v1/audio/speech", r.URL.Path)
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		w.Header().Set("Content-Type", "audio/mpeg")
+		w.Header().Set("X-TTS-Chars", "11")
+		w.Header().Set("X-TTS-Duration-Seconds", "1.05")
+		w.Write([]byte("ID3audio"))
+	}))
+	defer kokoro.Close()
+
+	gw := newAudioGateway(nil, &fakeSynthesizer{url: kokoro.URL})
+	w := httptest.NewRecorder()
+	gw.mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/audio/speech",
+		strings.NewReader(`{"model":"tts-1","input":"hello world","voice":"am_michael"}`)))
+
+	if w.Code != http.StatusOK || w.Body.String() != "ID3audio" {
+		t.Fatalf("status = %d body = %q, want the audio relayed", w.Code, w.Body.String())
+	}
+	if w.Header().Get("Content-Type") != "audio/mpeg" {
+		t.Errorf("Content-Type = %q, want audio/mpeg", w.Header().Get("Content-Type"))
+	}
+	if got["response_format"] != defaultSpeechFormat || got["voice"] != "am_michael" {
+		t.Errorf("kokoro request = %v, want voice kept and response_format defaulted to mp3", got)
+	}
+	// 11 chars = 3 in; 1.05s = 11 out.
+	if w.Header().Get(usagePromptTokensHeader) != "3" || w.Header().Get(usageCompletionTokensHeader) != "11" {
+		t.Errorf("usage headers = %q/%q, want 3/11",
+			w.Header().Get(usagePromptTokensHeader), w.Header().Get(usageCompletionTokensHeader))
+	}
+
+	w = httptest.NewRecorder()
+	gw.mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/audio/speech", strings.NewReader(`{"voice":"x"}`)))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("missing input: status = %d, want 400", w.Code)
+	}
+
+	long := `{"input":"` + strings.Repeat("a", maxSpeechBody) + `"}`
+	w = httptest.NewRecorder()
+	gw.mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/audio/speech", strings.NewReader(long)))
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("oversized body: status = %d, want 413", w.Code)
+	}
+}
+
+// TestMeteringMiddleware_Audio verifies audio requests are billed from the
+// usage headers and the multipart body reaches the handler intact.
+func TestMeteringMiddleware_Audio(t *testing.T) {
+	ledger := NewLedger(t.TempDir())
+	tier, _ := TierByName("small")
+	stt := &fakeTranscriber{result: whisperJSON}
+	gw := newAudioGateway(stt, nil)
+	middleware := NewMeteringMiddleware(gw.mux, ledger, nil, tier)
+
+	w := httptest.NewRecorder()
+	middleware.ServeHTTP(w, transcriptionRequest(t, map[string]string{"response_format": "srt"}))
+	if w.Code != http.StatusOK || stt.gotAudio != "RIFFfakeaudio" {
+		t.Fatalf("status = %d, backend audio = %q", w.Code, stt.gotAudio)
+	}
+
+	totalIn, totalOut, _, reqCount := middleware.InProcessStats()
+	if totalIn != 33 || totalOut != 5 || reqCount != 1 {
+		t.Errorf("stats = in %d out %d requests %d, want 33/5/1", totalIn, totalOut, reqCount)
+	}
+	recent, err := ledger.Recent(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recent) != 1 || recent[0].Model != transcriptionModel || recent[0].Path != "/v1/audio/transcriptions" {
+		t.Errorf("ledger = %+v, want one faster-whisper transcription record", recent)
+	}
+}
+
+func TestSubtitleTime(t *testing.T) {
+	if got := subtitleTime(3723.4567, ","); got != "01:02:03,457" {
+		t.Errorf("subtitleTime = %q, want 01:02:03,457", got)
+	}
+}
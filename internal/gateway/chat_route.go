@@ -161,21 +161,25 @@ func (s *Server) handleChatCompletions(w http.ResponseWriter, r *http.Request) {
 }
 
 // handleModels returns the OpenAI-compatible /v1/models listing aggregated from
-// the local serving engines. Duplicate model ids (same model on two engines) are
-// de-duplicated; the first engine wins the owned_by field.
+// the local serving engines and, when embedding routing is on, the embedding
+// and rerank servers (embedders with their vector dimensions). Duplicate model
+// ids (same model on two engines) are de-duplicated; the first engine wins the
+// owned_by field.
 func (s *Server) handleModels(w http.ResponseWriter, r *http.Request) {
 	s.mu.RLock()
 	lister := s.chatLister
+	embeddingLister := s.embeddingLister
 	s.mu.RUnlock()
 
 	type modelObj struct {
-		ID      string `json:"id"`
-		Object  string `json:"object"`
-		OwnedBy string `json:"owned_by"`
+		ID         string `json:"id"`
+		Object     string `json:"object"`
+		OwnedBy    string `json:"owned_by"`
+		Dimensions int    `json:"dimensions,omitempty"`
 	}
 	data := []modelObj{}
+	seen := map[string]bool{}
 	if lister != nil {
-		seen := map[string]bool{}
 		for _, e := range lister() {
 			for _, m := range e.Models {
 				m = strings.TrimSpace(m)
@@ -187,6 +191,16 @@ func (s *Server) handleModels(w http.ResponseWriter, r *http.Request) {
 			}
 		}
 	}
+	if embeddingLister != nil {
+		for _, e := range embeddingLister() {
+			m := strings.TrimSpace(e.Model)
+			if m == "" || seen[m] {
+				continue
+			}
+			seen[m] = true
+			data = append(data, modelObj{ID: m, Object: "model", OwnedBy: e.Engine, Dimensions: e.Dimensions})
+		}
+	}
 	w.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(w).Encode(map[string]any{"object": "list", "data": data})
 }
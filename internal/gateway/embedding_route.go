@@ -0,0 +1,575 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aceteam-ai/citadel-cli/internal/tracing"
+)
+
+// embedding_route.go routes OpenAI-compatible embeddings and Cohere/Jina-style
+// reranking by model, the way chat_route.go routes chat.
+//
+// Before this, /v1/embeddings was a static upstream pinned to the TEI port
+// (issue #351): a node serving its embedder from a second TEI container, or
+// from an engine (vLLM --task embed, ollama), was unreachable through the
+// gateway, and there was no rerank route at all, so RAG clients had to dial the
+// reranker's port directly. Now:
+//
+//   - POST /v1/embeddings resolves the body's "model" against the local TEI
+//     embedders, falling back to a chat engine that serves exactly that model
+//     (engines expose the same OpenAI path). Inputs beyond the upstream's batch
+//     limit are split into sub-requests and the responses merged, so clients
+//     can send up to maxEmbeddingInputs inputs regardless of how the server was
+//     started.
+//   - POST /v1/rerank takes {model, query, documents, top_n, return_documents}
+//     and scores the documents on a TEI reranker's /rerank, batched the same way.
+//   - /v1/models lists the embedders (with their vector dimensions) and
+//     rerankers alongside the chat models.
+//
+// Both report token usage in an OpenAI usage object, so MeteringMiddleware
+// records them in the ledger like chat.
+
+const (
+	// maxEmbeddingBody bounds an embeddings or rerank request body. Unlike chat
+	// the body is parsed, not just peeked, so an over-long body is rejected
+	// rather than truncated.
+	maxEmbeddingBody = 16 << 20 // 16 MiB
+
+	// maxEmbeddingInputs is OpenAI's per-request input limit for embeddings.
+	maxEmbeddingInputs = 2048
+
+	// maxRerankDocuments bounds the documents in one rerank request.
+	maxRerankDocuments = 1000
+
+	// EmbeddingTaskEmbed and EmbeddingTaskRerank are the EmbeddingUpstream tasks.
+	EmbeddingTaskEmbed  = "embed"
+	EmbeddingTaskRerank = "rerank"
+)
+
+// embeddingClient carries the gateway's own sub-requests (batched embeddings,
+// rerank). A large batch on a CPU-only TEI can take a while; a stall past this
+// means the server is not serving.
+var embeddingClient = &http.Client{Timeout: 2 * time.Minute}
+
+// EmbeddingUpstream is one local embedding or reranking server. Address is
+// host:port; Task is EmbeddingTaskEmbed or EmbeddingTaskRerank; Dimensions is
+// the embedder's vector size (0 if unknown); MaxBatch is the most inputs the
+// server takes per request (0 for no limit).
+type EmbeddingUpstream struct {
+	Engine     string
+	Address    string
+	Model      string
+	Task       string
+	Dimensions int
+	MaxBatch   int
+}
+
+// EmbeddingModelLister returns the local embedding and reranking servers. Like
+// ChatModelLister it is called per request; cmd wires a TTL-cached ProbeTEI.
+type EmbeddingModelLister func() []EmbeddingUpstream
+
+// SetEmbeddingRouter enables model-routed /v1/embeddings and /v1/rerank. It
+// replaces a static /v1/embeddings upstream; registering both panics at Start.
+// Must be called before Start.
+func (s *Server) SetEmbeddingRouter(lister EmbeddingModelLister) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.embeddingLister = lister
+}
+
+// SetEmbeddingFallback sets a TEI address that /v1/embeddings is proxied to,
+// verbatim, when the lister reports no embedder and no engine serves the model.
+// It keeps the static route the gateway had before model routing (issue #351),
+// so the node's TEI stays reachable while it is still loading or before the
+// first probe completes. Must be called before Start.
+func (s *Server) SetEmbeddingFallback(address string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.embeddingFallback = address
+}
+
+// registerEmbeddingRoutes wires the embedding handlers onto the mux. It is
+// called from Start (and directly from tests), as registerChatRoutes is, and
+// registers /v1/models itself only when chat routing has not.
+func (s *Server) registerEmbeddingRoutes() {
+	s.mux.Handle("/v1/embeddings", http.HandlerFunc(s.handleEmbeddings))
+	s.mux.Handle("/v1/rerank", http.HandlerFunc(s.handleRerank))
+	if s.chatLister == nil {
+		s.mux.Handle("/v1/models", http.HandlerFunc(s.handleModels))
+	}
+}
+
+// readEmbeddingBody reads a JSON request body, writing the error response and
+// returning ok=false if it is not a POST, too large, or not a JSON object.
+func readEmbeddingBody(w http.ResponseWriter, r *http.Request) (body []byte, fields map[string]json.RawMessage, ok bool) {
+	if r.Method != http.MethodPost {
+		writeChatError(w, http.StatusMethodNotAllowed, "invalid_request_error", "use POST")
+		return nil, nil, false
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxEmbeddingBody+1))
+	_ = r.Body.Close()
+	if err != nil {
+		writeChatError(w, http.StatusBadRequest, "invalid_request_error", "failed to read request body")
+		return nil, nil, false
+	}
+	if len(body) > maxEmbeddingBody {
+		writeChatError(w, http.StatusRequestEntityTooLarge, "invalid_request_error",
+			fmt.Sprintf("request body exceeds %d MB", maxEmbeddingBody>>20))
+		return nil, nil, false
+	}
+	if err := json.Unmarshal(body, &fields); err != nil || fields == nil {
+		writeChatError(w, http.StatusBadRequest, "invalid_request_error", "request body must be a JSON object")
+		return nil, nil, false
+	}
+	return body, fields, true
+}
+
+// handleEmbeddings implements POST /v1/embeddings.
+func (s *Server) handleEmbeddings(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	lister := s.embeddingLister
+	chatLister := s.chatLister
+	fallback := s.embeddingFallback
+	nodeName := s.config.NodeName
+	s.mu.RUnlock()
+
+	if lister == nil {
+		writeChatError(w, http.StatusNotFound, "model_not_found", "embedding routing not enabled on this node")
+		return
+	}
+	body, fields, ok := readEmbeddingBody(w, r)
+	if !ok {
+		return
+	}
+
+	inputs, batchable := splitEmbeddingInputs(fields["input"])
+	switch {
+	case len(inputs) == 0:
+		writeChatError(w, http.StatusBadRequest, "invalid_request_error", "'input' is required")
+		return
+	case len(inputs) > maxEmbeddingInputs:
+		writeChatError(w, http.StatusBadRequest, "invalid_request_error",
+			fmt.Sprintf("'input' has %d items; at most %d are allowed per request", len(inputs), maxEmbeddingInputs))
+		return
+	}
+
+	var model string
+	_ = json.Unmarshal(fields["model"], &model)
+	upstreams := lister()
+	up, ok := resolveEmbeddingModel(model, EmbeddingTaskEmbed, upstreams)
+	if !ok && chatLister != nil {
+		up, ok = engineEmbedder(model, chatLister())
+	}
+	if !ok && fallback != "" && !servesTask(upstreams, EmbeddingTaskEmbed) {
+		up, ok = EmbeddingUpstream{Engine: "tei", Address: fallback, Model: model, Task: EmbeddingTaskEmbed}, true
+	}
+	if !ok {
+		writeChatError(w, http.StatusNotFound, "model_not_found",
+			fmt.Sprintf("embedding model %q not served on this node", model))
+		return
+	}
+
+	if !batchable || up.MaxBatch <= 0 || len(inputs) <= up.MaxBatch {
+		// One upstream request: forward the body verbatim (encoding_format,
+		// dimensions, user and anything else the server understands).
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		r.ContentLength = int64(len(body))
+		proxyEmbedding(w, r, up, nodeName)
+		return
+	}
+
+	resp, status, errBody := embedBatched(r.Context(), up, fields, inputs)
+	if errBody != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_, _ = w.Write(errBody)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// splitEmbeddingInputs returns the items of an OpenAI "input": a string or a
+// token array is one item, an array of strings or token arrays is one per
+// element. batchable is false for the single-item forms.
+func splitEmbeddingInputs(raw json.RawMessage) (inputs []json.RawMessage, batchable bool) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+		return nil, false
+	}
+	if raw[0] != '[' {
+		return []json.RawMessage{raw}, false
+	}
+	var items []json.RawMessage
+	if err := json.Unmarshal(raw, &items); err != nil {
+		return []json.RawMessage{raw}, false
+	}
+	if len(items) > 0 {
+		if first := bytes.TrimSpace(items[0]); len(first) > 0 && first[0] != '"' && first[0] != '[' {
+			// A flat token array is a single input.
+			return []json.RawMessage{raw}, false
+		}
+	}
+	return items, true
+}
+
+// proxyEmbedding reverse-proxies r to the upstream's /v1/embeddings.
+func proxyEmbedding(w http.ResponseWriter, r *http.Request, up EmbeddingUpstream, nodeName string) {
+	target := &url.URL{Scheme: "http", Host: up.Address}
+	proxy := &httputil.ReverseProxy{
+		Director: func(req *http.Request) {
+			req.URL.Scheme = target.Scheme
+			req.URL.Host = target.Host
+			req.Host = target.Host
+			req.Header.Set("X-Forwarded-For", req.RemoteAddr)
+			req.Header.Set("X-Forwarded-Proto", "https")
+			if nodeName != "" {
+				req.Header.Set("X-Citadel-Node", nodeName)
+			}
+		},
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			log.Printf("[Gateway] embedding proxy error -> %s (engine=%s): %v", target.Host, up.Engine, err)
+			writeChatError(w, http.StatusBadGateway, "upstream_error", fmt.Sprintf("engine %q unavailable", up.Engine))
+		},
+	}
+	proxy.ServeHTTP(w, r)
+}
+
+// embeddingResponse is the OpenAI embeddings response. Vectors are kept raw so
+// float and base64 encodings both pass through untouched.
+type embeddingResponse struct {
+	Object string `json:"object"`
+	Data   []struct {
+		Object    string          `json:"object"`
+		Index     int             `json:"index"`
+		Embedding json.RawMessage `json:"embedding"`
+	} `json:"data"`
+	Model string      `json:"model"`
+	Usage openAIUsage `json:"usage"`
+}
+
+// embedBatched sends inputs to the upstream in batches of up.MaxBatch and
+// merges the responses, re-indexing each batch's data and summing usage. A
+// failed batch's status and body are returned as errBody for the caller to
+// relay.
+func embedBatched(ctx context.Context, up EmbeddingUpstream, fields map[string]json.RawMessage, inputs []json.RawMessage) (merged *embeddingResponse, status int, errBody []byte) {
+	merged = &embeddingResponse{Object: "list"}
+	for start := 0; start < len(inputs); start += up.MaxBatch {
+		end := min(start+up.MaxBatch, len(inputs))
+
+		req := make(map[string]json.RawMessage, len(fields))
+		for k, v := range fields {
+			req[k] = v
+		}
+		req["input"], _ = json.Marshal(inputs[start:end])
+		var part embeddingResponse
+		if status, errBody := postEmbeddingJSON(ctx, up, "/v1/embeddings", req, &part); errBody != nil {
+			return nil, status, errBody
+		}
+
+		for _, d := range part.Data {
+			d.Index += start
+			merged.Data = append(merged.Data, d)
+		}
+		merged.Model = part.Model
+		merged.Usage.PromptTokens += part.Usage.PromptTokens
+		merged.Usage.TotalTokens += part.Usage.TotalTokens
+	}
+	return merged, 0, nil
+}
+
+// postEmbeddingJSON POSTs req to the upstream and decodes a 200 response into
+// out. Any other outcome returns the status and an OpenAI-shaped error body
+// (the upstream's own body when it sent one).
+func postEmbeddingJSON(ctx context.Context, up EmbeddingUpstream, path string, req, out any) (status int, errBody []byte) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return http.StatusInternalServerError, chatErrorBody("internal_error", err.Error())
+	}
+	hreq, err := http.NewRequestWithContext(ctx, http.MethodPost, "http://"+up.Address+path, bytes.NewReader(body))
+	if err != nil {
+		return http.StatusInternalServerError, chatErrorBody("internal_error", err.Error())
+	}
+	hreq.Header.Set("Content-Type", "application/json")
+	tracing.Inject(ctx, hreq.Header)
+	resp, err := embeddingClient.Do(hreq)
+	if err != nil {
+		log.Printf("[Gateway] embedding request error -> %s%s (engine=%s): %v", up.Address, path, up.Engine, err)
+		return http.StatusBadGateway, chatErrorBody("upstream_error", fmt.Sprintf("engine %q unavailable", up.Engine))
+	}
+	defer resp.Body.Close()
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 256<<20))
+	if resp.StatusCode != http.StatusOK {
+		if !json.Valid(raw) {
+			raw = chatErrorBody("upstream_error", strings.TrimSpace(string(raw)))
+		}
+		return resp.StatusCode, raw
+	}
+	if err := json.Unmarshal(raw, out); err != nil {
+		return http.StatusBadGateway, chatErrorBody("upstream_error", fmt.Sprintf("engine %q returned invalid JSON", up.Engine))
+	}
+	return 0, nil
+}
+
+// rerankRequest is the Cohere/Jina rerank request.
+type rerankRequest struct {
+	Model           string   `json:"model"`
+	Query           string   `json:"query"`
+	Documents       []string `json:"documents"`
+	TopN            int      `json:"top_n"`
+	ReturnDocuments bool     `json:"return_documents"`
+}
+
+// rerankResult is one scored document in a rerank response.
+type rerankResult struct {
+	Index          int     `json:"index"`
+	RelevanceScore float64 `json:"relevance_score"`
+	Document       *struct {
+		Text string `json:"text"`
+	} `json:"document,omitempty"`
+}
+
+// handleRerank implements POST /v1/rerank on a TEI reranker.
+func (s *Server) handleRerank(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	lister := s.embeddingLister
+	s.mu.RUnlock()
+
+	if lister == nil {
+		writeChatError(w, http.StatusNotFound, "model_not_found", "rerank routing not enabled on this node")
+		return
+	}
+	body, _, ok := readEmbeddingBody(w, r)
+	if !ok {
+		return
+	}
+	var req rerankRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		writeChatError(w, http.StatusBadRequest, "invalid_request_error",
+			"expected {model, query, documents: [string], top_n, return_documents}")
+		return
+	}
+	switch {
+	case strings.TrimSpace(req.Query) == "":
+		writeChatError(w, http.StatusBadRequest, "invalid_request_error", "'query' is required")
+		return
+	case len(req.Documents) == 0:
+		writeChatError(w, http.StatusBadRequest, "invalid_request_error", "'documents' is required")
+		return
+	case len(req.Documents) > maxRerankDocuments:
+		writeChatError(w, http.StatusBadRequest, "invalid_request_error",
+			fmt.Sprintf("'documents' has %d items; at most %d are allowed per request", len(req.Documents), maxRerankDocuments))
+		return
+	}
+
+	up, ok := resolveEmbeddingModel(req.Model, EmbeddingTaskRerank, lister())
+	if !ok {
+		writeChatError(w, http.StatusNotFound, "model_not_found",
+			fmt.Sprintf("rerank model %q not served on this node", req.Model))
+		return
+	}
+
+	batch := up.MaxBatch
+	if batch <= 0 {
+		batch = len(req.Documents)
+	}
+	results := make([]rerankResult, 0, len(req.Documents))
+	tokens := 0
+	for start := 0; start < len(req.Documents); start += batch {
+		end := min(start+batch, len(req.Documents))
+		var scored []struct {
+			Index int     `json:"index"`
+			Score float64 `json:"score"`
+		}
+		teiReq := map[string]any{"query": req.Query, "texts": req.Documents[start:end], "truncate": true}
+		if status, errBody := postEmbeddingJSON(r.Context(), up, "/rerank", teiReq, &scored); errBody != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(status)
+			_, _ = w.Write(errBody)
+			return
+		}
+		for _, sc := range scored {
+			if sc.Index < 0 || sc.Index >= end-start {
+				writeChatError(w, http.StatusBadGateway, "upstream_error", "reranker returned an out-of-range index")
+				return
+			}
+			results = append(results, rerankResult{Index: start + sc.Index, RelevanceScore: sc.Score})
+		}
+	}
+	// TEI reports no usage for /rerank. Every document is scored as a
+	// (query, document) pair, so that is what is counted.
+	queryTokens := textTokens(req.Query)
+	for _, d := range req.Documents {
+		tokens += queryTokens + textTokens(d)
+	}
+
+	sort.SliceStable(results, func(i, j int) bool { return results[i].RelevanceScore > results[j].RelevanceScore })
+	if req.TopN > 0 && req.TopN < len(results) {
+		results = results[:req.TopN]
+	}
+	if req.ReturnDocuments {
+		for i := range results {
+			results[i].Document = &struct {
+				Text string `json:"text"`
+			}{Text: req.Documents[results[i].Index]}
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"object":  "list",
+		"model":   up.Model,
+		"results": results,
+		"usage":   openAIUsage{PromptTokens: tokens, TotalTokens: tokens},
+	})
+}
+
+// resolveEmbeddingModel picks the upstream for task serving model, with
+// resolveChatModel's matching: exact case-insensitive id first, then a
+// substring, and an empty model only when a single server does the task.
+func resolveEmbeddingModel(model, task string, upstreams []EmbeddingUpstream) (EmbeddingUpstream, bool) {
+	var cands []EmbeddingUpstream
+	for _, u := range upstreams {
+		if u.Task == task && u.Address != "" {
+			cands = append(cands, u)
+		}
+	}
+	if len(cands) == 0 {
+		return EmbeddingUpstream{}, false
+	}
+	sort.Slice(cands, func(i, j int) bool {
+		if cands[i].Model != cands[j].Model {
+			return cands[i].Model < cands[j].Model
+		}
+		return cands[i].Address < cands[j].Address
+	})
+
+	model = strings.TrimSpace(model)
+	if model == "" {
+		for _, c := range cands {
+			if c.Address != cands[0].Address {
+				return EmbeddingUpstream{}, false
+			}
+		}
+		return cands[0], true
+	}
+	for _, c := range cands {
+		if c.Model != "" && strings.EqualFold(c.Model, model) {
+			return c, true
+		}
+	}
+	needle := strings.ToLower(model)
+	for _, c := range cands {
+		if c.Model != "" && strings.Contains(strings.ToLower(c.Model), needle) {
+			return c, true
+		}
+	}
+	return EmbeddingUpstream{}, false
+}
+
+// servesTask reports whether any upstream does task.
+func servesTask(upstreams []EmbeddingUpstream, task string) bool {
+	for _, u := range upstreams {
+		if u.Task == task && u.Address != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// engineEmbedder finds a chat engine serving exactly model, for embedding
+// models loaded into vLLM or ollama. Only an exact id matches: a substring
+// could land an embedding request on an unrelated chat model.
+func engineEmbedder(model string, engines []ChatUpstream) (EmbeddingUpstream, bool) {
+	model = strings.TrimSpace(model)
+	if model == "" {
+		return EmbeddingUpstream{}, false
+	}
+	for _, e := range engines {
+		if e.Port <= 0 {
+			continue
+		}
+		for _, m := range e.Models {
+			if strings.EqualFold(strings.TrimSpace(m), model) {
+				return EmbeddingUpstream{
+					Engine:  e.Engine,
+					Address: net.JoinHostPort("127.0.0.1", strconv.Itoa(e.Port)),
+					Model:   m,
+					Task:    EmbeddingTaskEmbed,
+				}, true
+			}
+		}
+	}
+	return EmbeddingUpstream{}, false
+}
+
+// ProbeTEI asks the Text Embeddings Inference server at address (host:port)
+// what it serves: /info names the model, its type and batch limit, and for an
+// embedder one tiny /embed call measures the vector size, which /info does not
+// report. Classifier models, which the gateway does not route, are an error.
+func ProbeTEI(ctx context.Context, address string) (EmbeddingUpstream, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+address+"/info", nil)
+	if err != nil {
+		return EmbeddingUpstream{}, err
+	}
+	resp, err := embeddingClient.Do(req)
+	if err != nil {
+		return EmbeddingUpstream{}, fmt.Errorf("query TEI info: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return EmbeddingUpstream{}, fmt.Errorf("TEI info returned status %d", resp.StatusCode)
+	}
+	var info struct {
+		ModelID   string                     `json:"model_id"`
+		ModelType map[string]json.RawMessage `json:"model_type"`
+		MaxBatch  int                        `json:"max_client_batch_size"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return EmbeddingUpstream{}, fmt.Errorf("parse TEI info: %w", err)
+	}
+
+	up := EmbeddingUpstream{
+		Engine:   "tei",
+		Address:  address,
+		Model:    strings.TrimSpace(info.ModelID),
+		MaxBatch: info.MaxBatch,
+	}
+	switch {
+	case info.ModelType["reranker"] != nil:
+		up.Task = EmbeddingTaskRerank
+		return up, nil
+	case info.ModelType["embedding"] != nil:
+		up.Task = EmbeddingTaskEmbed
+	default:
+		return EmbeddingUpstream{}, fmt.Errorf("TEI at %s serves neither an embedding nor a reranker model", address)
+	}
+
+	var vectors [][]float32
+	if status, errBody := postEmbeddingJSON(ctx, up, "/embed", map[string]any{"inputs": "dimension probe"}, &vectors); errBody != nil {
+		return EmbeddingUpstream{}, fmt.Errorf("TEI embed probe returned status %d: %s", status, errBody)
+	}
+	if len(vectors) == 1 {
+		up.Dimensions = len(vectors[0])
+	}
+	return up, nil
+}
+
+// chatErrorBody is writeChatError's body, for relaying through a buffer.
+func chatErrorBody(typ, msg string) []byte {
+	b, _ := json.Marshal(map[string]any{"error": map[string]string{"message": msg, "type": typ}})
+	return b
+}
@@ -1,9 +1,13 @@
 package gateway
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"sync"
 	"testing"
 )
 
@@ -57,3 +61,329 @@ func TestEmbeddingUpstreamRouting(t *testing.T) {
 		t.Errorf("proxied path = %q, want /v1/embeddings (no strip)", resp["path"])
 	}
 }
+
+// fakeTEI is a Text Embeddings Inference server serving one embedding model
+// (4-dimensional vectors whose first component is the input's length) or, with
+// rerank set, a reranker scoring each text by its length. It records the batch
+// size of every request.
+type fakeTEI struct {
+	model    string
+	rerank   bool
+	maxBatch int
+
+	mu      sync.Mutex
+	batches []int
+}
+
+func (f *fakeTEI) serve(t *testing.T) string {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/info":
+			modelType := map[string]any{"embedding": map[string]string{"pooling": "cls"}}
+			if f.rerank {
+				modelType = map[string]any{"reranker": map[string]any{}}
+			}
+			json.NewEncoder(w).Encode(map[string]any{
+				"model_id": f.model, "model_type": modelType, "max_client_batch_size": f.maxBatch,
+			})
+		case "/embed":
+			json.NewEncoder(w).Encode([][]float32{{0, 0, 0, 0}})
+		case "/v1/embeddings":
+			var req struct {
+				Input []string `json:"input"`
+			}
+			json.NewDecoder(r.Body).Decode(&req)
+			f.record(len(req.Input))
+			type item struct {
+				Object    string    `json:"object"`
+				Index     int       `json:"index"`
+				Embedding []float64 `json:"embedding"`
+			}
+			data := make([]item, len(req.Input))
+			for i, in := range req.Input {
+				data[i] = item{Object: "embedding", Index: i, Embedding: []float64{float64(len(in)), 0, 0, 0}}
+			}
+			json.NewEncoder(w).Encode(map[string]any{
+				"object": "list", "data": data, "model": f.model,
+				"usage": map[string]int{"prompt_tokens": 3 * len(req.Input), "total_tokens": 3 * len(req.Input)},
+			})
+		case "/rerank":
+			var req struct {
+				Texts []string `json:"texts"`
+			}
+			json.NewDecoder(r.Body).Decode(&req)
+			f.record(len(req.Texts))
+			type scored struct {
+				Index int     `json:"index"`
+				Score float64 `json:"score"`
+			}
+			out := make([]scored, len(req.Texts))
+			for i, text := range req.Texts {
+				out[i] = scored{Index: i, Score: float64(len(text))}
+			}
+			json.NewEncoder(w).Encode(out)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return strings.TrimPrefix(srv.URL, "http://")
+}
+
+func (f *fakeTEI) record(n int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.batches = append(f.batches, n)
+}
+
+// newEmbeddingGateway serves an embedding-routed gateway in front of the given
+// TEI servers, wrapped in metering so usage lands in a ledger.
+func newEmbeddingGateway(t *testing.T, teis ...*fakeTEI) (*httptest.Server, *MeteringMiddleware) {
+	t.Helper()
+	var ups []EmbeddingUpstream
+	for _, f := range teis {
+		up, err := ProbeTEI(context.Background(), f.serve(t))
+		if err != nil {
+			t.Fatalf("ProbeTEI: %v", err)
+		}
+		ups = append(ups, up)
+	}
+	gw := NewServer(Config{NodeName: "test-node"})
+	gw.SetEmbeddingRouter(func() []EmbeddingUpstream { return ups })
+	gw.registerEmbeddingRoutes()
+
+	tier, _ := TierByName("small")
+	metering := NewMeteringMiddleware(gw.mux, NewLedger(t.TempDir()), nil, tier)
+	srv := httptest.NewServer(metering)
+	t.Cleanup(srv.Close)
+	return srv, metering
+}
+
+func postJSON(t *testing.T, url, body string, out any) int {
+	t.Helper()
+	resp, err := http.Post(url, "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if out != nil {
+		json.NewDecoder(resp.Body).Decode(out)
+	}
+	return resp.StatusCode
+}
+
+func TestProbeTEI(t *testing.T) {
+	embedder := &fakeTEI{model: "BAAI/bge-m3", maxBatch: 32}
+	up, err := ProbeTEI(context.Background(), embedder.serve(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if up.Model != "BAAI/bge-m3" || up.Task != EmbeddingTaskEmbed || up.Dimensions != 4 || up.MaxBatch != 32 {
+		t.Errorf("embedder = %+v", up)
+	}
+
+	reranker := &fakeTEI{model: "BAAI/bge-reranker-base", rerank: true}
+	up, err = ProbeTEI(context.Background(), reranker.serve(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if up.Task != EmbeddingTaskRerank || up.Dimensions != 0 {
+		t.Errorf("reranker = %+v", up)
+	}
+}
+
+func TestEmbeddingsBatchedAcrossUpstreamLimit(t *testing.T) {
+	tei := &fakeTEI{model: "BAAI/bge-m3", maxBatch: 2}
+	srv, metering := newEmbeddingGateway(t, tei)
+
+	var resp embeddingResponse
+	code := postJSON(t, srv.URL+"/v1/embeddings",
+		`{"model":"bge-m3","input":["a","bb","ccc","dddd","eeeee"]}`, &resp)
+	if code != http.StatusOK {
+		t.Fatalf("status = %d", code)
+	}
+	if fmt.Sprint(tei.batches) != "[2 2 1]" {
+		t.Errorf("upstream batches = %v, want [2 2 1]", tei.batches)
+	}
+	for i, d := range resp.Data {
+		var vec []float64
+		json.Unmarshal(d.Embedding, &vec)
+		if d.Index != i || vec[0] != float64(i+1) {
+			t.Errorf("data[%d] = index %d, vector %v; want the input's own vector in order", i, d.Index, vec)
+		}
+	}
+	if len(resp.Data) != 5 || resp.Usage.PromptTokens != 15 {
+		t.Errorf("got %d vectors, usage %+v; want 5 and 15 prompt tokens", len(resp.Data), resp.Usage)
+	}
+	if in, _, _, n := metering.InProcessStats(); in != 15 || n != 1 {
+		t.Errorf("metered %d tokens in %d requests, want 15 in 1", in, n)
+	}
+}
+
+func TestEmbeddingsRoutingAndLimits(t *testing.T) {
+	srv, _ := newEmbeddingGateway(t,
+		&fakeTEI{model: "BAAI/bge-m3", maxBatch: 2},
+		&fakeTEI{model: "BAAI/bge-reranker-base", rerank: true})
+
+	// A single string is one input, proxied verbatim; the reranker is never an
+	// embedding candidate, so an empty model is unambiguous.
+	var resp embeddingResponse
+	if code := postJSON(t, srv.URL+"/v1/embeddings", `{"input":"hello"}`, &resp); code != http.StatusOK {
+		t.Fatalf("status = %d", code)
+	}
+	if resp.Model != "BAAI/bge-m3" {
+		t.Errorf("model = %q", resp.Model)
+	}
+
+	inputs := make([]string, maxEmbeddingInputs+1)
+	for i := range inputs {
+		inputs[i] = `"x"`
+	}
+	for _, tc := range []struct {
+		body string
+		want int
+	}{
+		{`{"model":"nomic-embed-text","input":"hi"}`, http.StatusNotFound},
+		{`{"model":"bge-m3"}`, http.StatusBadRequest},
+		{`{"model":"bge-m3","input":[` + strings.Join(inputs, ",") + `]}`, http.StatusBadRequest},
+		{`not json`, http.StatusBadRequest},
+	} {
+		if code := postJSON(t, srv.URL+"/v1/embeddings", tc.body, nil); code != tc.want {
+			t.Errorf("%.40s: status %d, want %d", tc.body, code, tc.want)
+		}
+	}
+}
+
+func TestEmbeddingsFallBackToEngineByExactModel(t *testing.T) {
+	engine := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"backend": "ollama", "path": r.URL.Path})
+	}))
+	defer engine.Close()
+	var port int
+	fmt.Sscanf(engine.URL[strings.LastIndex(engine.URL, ":")+1:], "%d", &port)
+
+	gw := NewServer(Config{})
+	gw.SetChatRouter(func() []ChatUpstream {
+		return []ChatUpstream{{Engine: "ollama", Port: port, Models: []string{"nomic-embed-text", "llama3"}}}
+	})
+	gw.SetEmbeddingRouter(func() []EmbeddingUpstream { return nil })
+	gw.registerChatRoutes()
+	gw.registerEmbeddingRoutes()
+	srv := httptest.NewServer(gw.mux)
+	defer srv.Close()
+
+	var resp map[string]string
+	if code := postJSON(t, srv.URL+"/v1/embeddings", `{"model":"nomic-embed-text","input":"hi"}`, &resp); code != http.StatusOK {
+		t.Fatalf("status = %d", code)
+	}
+	if resp["backend"] != "ollama" || resp["path"] != "/v1/embeddings" {
+		t.Errorf("routed to %v", resp)
+	}
+	if code := postJSON(t, srv.URL+"/v1/embeddings", `{"model":"nomic","input":"hi"}`, nil); code != http.StatusNotFound {
+		t.Errorf("substring match on an engine: status %d, want 404", code)
+	}
+}
+
+func TestEmbeddingsFallBackToStaticTEI(t *testing.T) {
+	tei := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"backend": "tei", "path": r.URL.Path})
+	}))
+	defer tei.Close()
+
+	var listed []EmbeddingUpstream
+	gw := NewServer(Config{})
+	gw.SetEmbeddingRouter(func() []EmbeddingUpstream { return listed })
+	gw.SetEmbeddingFallback(strings.TrimPrefix(tei.URL, "http://"))
+	gw.registerEmbeddingRoutes()
+	srv := httptest.NewServer(gw.mux)
+	defer srv.Close()
+
+	// Nothing probed yet: any model goes to the static TEI.
+	var resp map[string]string
+	if code := postJSON(t, srv.URL+"/v1/embeddings", `{"model":"bge-m3","input":"hi"}`, &resp); code != http.StatusOK {
+		t.Fatalf("status = %d", code)
+	}
+	if resp["backend"] != "tei" || resp["path"] != "/v1/embeddings" {
+		t.Errorf("routed to %v", resp)
+	}
+
+	// Once an embedder is known, an unknown model is a 404 again.
+	listed = []EmbeddingUpstream{{Engine: "tei", Address: "127.0.0.1:1", Model: "BAAI/bge-m3", Task: EmbeddingTaskEmbed}}
+	if code := postJSON(t, srv.URL+"/v1/embeddings", `{"model":"nomic-embed-text","input":"hi"}`, nil); code != http.StatusNotFound {
+		t.Errorf("unknown model with an embedder listed: status %d, want 404", code)
+	}
+}
+
+func TestRerankBatchesSortsAndMeters(t *testing.T) {
+	tei := &fakeTEI{model: "BAAI/bge-reranker-base", rerank: true, maxBatch: 2}
+	srv, metering := newEmbeddingGateway(t, tei)
+
+	var resp struct {
+		Model   string         `json:"model"`
+		Results []rerankResult `json:"results"`
+		Usage   openAIUsage    `json:"usage"`
+	}
+	code := postJSON(t, srv.URL+"/v1/rerank",
+		`{"model":"bge-reranker","query":"q","documents":["aa","a","aaaa","aaa"],"top_n":3,"return_documents":true}`, &resp)
+	if code != http.StatusOK {
+		t.Fatalf("status = %d", code)
+	}
+	if fmt.Sprint(tei.batches) != "[2 2]" {
+		t.Errorf("upstream batches = %v, want [2 2]", tei.batches)
+	}
+	var order []string
+	for _, r := range resp.Results {
+		order = append(order, fmt.Sprintf("%d:%s", r.Index, r.Document.Text))
+	}
+	if fmt.Sprint(order) != "[2:aaaa 3:aaa 0:aa]" {
+		t.Errorf("results = %v, want the top 3 by score with original indices", order)
+	}
+	// Four (query, document) pairs of one token each side.
+	if resp.Usage.PromptTokens != 8 || resp.Model != "BAAI/bge-reranker-base" {
+		t.Errorf("model %q, usage %+v", resp.Model, resp.Usage)
+	}
+	if in, _, _, n := metering.InProcessStats(); in != 8 || n != 1 {
+		t.Errorf("metered %d tokens in %d requests, want 8 in 1", in, n)
+	}
+
+	for _, body := range []string{
+		`{"query":"q","documents":[]}`,
+		`{"documents":["a"]}`,
+		`{"query":"q","documents":"a"}`,
+	} {
+		if code := postJSON(t, srv.URL+"/v1/rerank", body, nil); code != http.StatusBadRequest {
+			t.Errorf("%s: status %d, want 400", body, code)
+		}
+	}
+}
+
+func TestModelsListsEmbeddingDimensions(t *testing.T) {
+	srv, _ := newEmbeddingGateway(t,
+		&fakeTEI{model: "BAAI/bge-m3"},
+		&fakeTEI{model: "BAAI/bge-reranker-base", rerank: true})
+
+	resp, err := http.Get(srv.URL + "/v1/models")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var list struct {
+		Data []struct {
+			ID         string `json:"id"`
+			OwnedBy    string `json:"owned_by"`
+			Dimensions int    `json:"dimensions"`
+		} `json:"data"`
+	}
+	json.NewDecoder(resp.Body).Decode(&list)
+	got := map[string]int{}
+	for _, m := range list.Data {
+		got[m.ID] = m.Dimensions
+	}
+	if len(got) != 2 || got["BAAI/bge-m3"] != 4 || got["BAAI/bge-reranker-base"] != 0 {
+		t.Errorf("models = %+v", list.Data)
+	}
+}
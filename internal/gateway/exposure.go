@@ -144,6 +144,12 @@ type ExposePolicy struct {
 	// (re-exposing with an incremented epoch) invalidates all previously issued
 	// link tokens for this service — the revoke-all primitive behind "revocable".
 	TokenEpoch int
+	// Rewrite, when non-nil, is applied to every proxied plain-HTTP request
+	// after the gateway has set the target and stripped the prefix, i.e. to the
+	// request exactly as the upstream will receive it. The storage exposure uses
+	// it to re-sign S3 requests, whose client signatures cover the original
+	// host and path. WebSocket upgrades bypass it.
+	Rewrite func(*http.Request)
 }
 
 // exposePrefixFromPath extracts the exposed-service name from a /expose/<name>
@@ -229,6 +235,7 @@ func (s *Server) Unexpose(name string) bool {
 	// Outside the lock: setAddr takes the Upstream's own mutex.
 	if up != nil {
 		up.setAddr("")
+		up.setRewrite(nil)
 		up.Address = ""
 	}
 	return existed
@@ -270,7 +277,7 @@ func (s *Server) Expose(name, address string, policy *ExposePolicy) error {
 	// emitted paths to include it. No app-side configuration is required. Apps
 	// that use relative paths work unchanged; an app that is neither
 	// ingress-aware nor relative still needs its own base-path setting.
-	s.wireExposeRoute(ExposeRoutePath(name), address)
+	s.wireExposeRoute(ExposeRoutePath(name), address, policy.Rewrite)
 	s.SetExposure(name, policy)
 	return nil
 }
@@ -280,7 +287,7 @@ func (s *Server) Expose(name, address string, policy *ExposePolicy) error {
 // re-wire-safe semantics (#449): a new route created after Start registers its
 // handler live; an existing route has its address mutated in place (never
 // replaced) so the running proxy keeps reading the current target.
-func (s *Server) wireExposeRoute(prefix, address string) {
+func (s *Server) wireExposeRoute(prefix, address string, rewrite func(*http.Request)) {
 	s.mu.Lock()
 	up, ok := s.config.Upstreams[prefix]
 	if !ok {
@@ -289,6 +296,7 @@ func (s *Server) wireExposeRoute(prefix, address string) {
 		// Upstream.IngressPath).
 		up = &Upstream{StripPrefix: true, WebSocket: true, IngressPath: prefix}
 		up.setAddr(address)
+		up.setRewrite(rewrite)
 		s.config.Upstreams[prefix] = up
 		if s.started {
 			s.registerProxy(prefix, up)
@@ -298,6 +306,7 @@ func (s *Server) wireExposeRoute(prefix, address string) {
 	}
 	s.mu.Unlock()
 	up.setAddr(address)
+	up.setRewrite(rewrite)
 }
 
 // explicitLinkToken extracts a `link` access token presented EXPLICITLY (header
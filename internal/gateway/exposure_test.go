@@ -536,3 +536,46 @@ func TestReExposeAfterUnexposeUsesTheNewBackend(t *testing.T) {
 		t.Errorf("old backend was reached again after re-Expose (hits=%d, want 1)", oldHits)
 	}
 }
+
+// The Rewrite hook must see the request as the upstream will receive it
+// (target host set, prefix stripped), and re-exposing without a hook must
+// drop it from the live route.
+func TestExposeRewriteSeesUpstreamRequest(t *testing.T) {
+	var gotSig string
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotSig = r.Header.Get("X-Test-Signed")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+	addr := backendAddr(t, backend)
+
+	gw := NewServer(Config{Port: 0, NodeName: "test-node"})
+	gw.SetPermissions(&config.Permissions{})
+	gw.SetMeshResolver(&MockMeshResolver{Identity: &MeshPeerIdentity{SameOwner: true}})
+	policy := &ExposePolicy{Visibility: VisibilityOrg, Rewrite: func(r *http.Request) {
+		r.Header.Set("X-Test-Signed", r.URL.Host+r.URL.Path)
+	}}
+	if err := gw.Expose("storage", addr, policy); err != nil {
+		t.Fatalf("Expose: %v", err)
+	}
+	for prefix, up := range gw.config.Upstreams {
+		gw.registerProxy(prefix, up)
+	}
+	h := gw.BuildHandler()
+
+	req := httptest.NewRequest(http.MethodGet, "/expose/storage/bucket/key.txt", nil)
+	req.Header.Set("X-Test-Signed", "client")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	if want := addr + "/bucket/key.txt"; gotSig != want {
+		t.Fatalf("rewrite saw %q, want %q", gotSig, want)
+	}
+
+	if err := gw.Expose("storage", addr, &ExposePolicy{Visibility: VisibilityOrg}); err != nil {
+		t.Fatalf("re-Expose: %v", err)
+	}
+	gotSig = ""
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/expose/storage/bucket/key.txt", nil))
+	if gotSig != "" {
+		t.Fatalf("rewrite survived re-expose without a hook: %q", gotSig)
+	}
+}
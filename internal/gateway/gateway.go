@@ -118,11 +118,14 @@ type Upstream struct {
 	//	with header:    src="/expose/frigate/assets/main-CosNs8ey.js"
 	IngressPath string
 
-	// mu guards dynAddr for upstreams whose target is set after registration.
+	// mu guards dynAddr and rewrite, which can change after registration.
 	mu sync.RWMutex
 	// dynAddr, when non-empty, overrides Address. It is set via
 	// Server.SetUpstreamAddress for dynamically-provisioned upstreams.
 	dynAddr string
+	// rewrite, when non-nil, is the last step of the proxy Director (see
+	// ExposePolicy.Rewrite).
+	rewrite func(*http.Request)
 }
 
 // addr returns the current backend address, preferring a dynamically-set
@@ -143,6 +146,21 @@ func (u *Upstream) setAddr(a string) {
 	u.dynAddr = a
 }
 
+// rewriter returns the current request rewrite hook, or nil. Safe for
+// concurrent use.
+func (u *Upstream) rewriter() func(*http.Request) {
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+	return u.rewrite
+}
+
+// setRewrite replaces the request rewrite hook. Safe for concurrent use.
+func (u *Upstream) setRewrite(fn func(*http.Request)) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.rewrite = fn
+}
+
 // Server is the HTTPS reverse proxy gateway.
 type Server struct {
 	config     Config
@@ -156,7 +174,7 @@ type Server struct {
 
 	// metering optionally wraps the handler chain with ACET token metering.
 	// When non-nil, OpenAI-compatible API requests (/v1/chat/completions,
-	// /v1/completions, /v1/embeddings) are metered and billed. Set via
+	// /v1/completions, /v1/embeddings, /v1/rerank, /v1/audio/*) are metered and billed. Set via
 	// SetMetering before Start.
 	metering *MeteringMiddleware
 
@@ -177,6 +195,22 @@ type Server struct {
 	// chat_route.go (issue #581, node-side complement of aceteam #6236).
 	chatLister ChatModelLister
 
+	// embeddingLister, when non-nil, enables model-routed /v1/embeddings and
+	// /v1/rerank on the node's TEI servers (and /v1/models when chat routing is
+	// off). Set via SetEmbeddingRouter; see embedding_route.go.
+	embeddingLister EmbeddingModelLister
+
+	// embeddingFallback is the static TEI address /v1/embeddings falls back to
+	// when embeddingLister finds no embedder. Set via SetEmbeddingFallback.
+	embeddingFallback string
+
+	// transcriber and synthesizer, when either is non-nil, enable the OpenAI
+	// audio routes (/v1/audio/transcriptions, /v1/audio/speech) backed by the
+	// node's whisper and kokoro sidecars. Set via SetAudioRouter; see
+	// audio_route.go.
+	transcriber Transcriber
+	synthesizer SpeechSynthesizer
+
 	// started is set once Start has registered the proxy handlers for the routes
 	// present at that moment. It gates WireModuleRoute: a route added AFTER Start
 	// must have its proxy handler registered live (Start's registration loop has
@@ -494,6 +528,12 @@ func (s *Server) Start(ctx context.Context) error {
 	if s.chatLister != nil {
 		s.registerChatRoutes()
 	}
+	if s.embeddingLister != nil {
+		s.registerEmbeddingRoutes()
+	}
+	if s.transcriber != nil || s.synthesizer != nil {
+		s.registerAudioRoutes()
+	}
 	// Any route added after this point (WireModuleRoute) must register its own
 	// proxy handler live, since this loop has already run.
 	s.started = true
@@ -607,6 +647,9 @@ func (s *Server) registerProxy(prefix string, upstream *Upstream) {
 					req.URL.Path = "/"
 				}
 			}
+			if rw := upstream.rewriter(); rw != nil {
+				rw(req)
+			}
 		},
 		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
 			log.Printf("[Gateway] proxy error for %s -> %s: %v", r.URL.Path, upstream.addr(), err)
@@ -767,7 +810,7 @@ func (s *Server) BuildHandler() http.Handler {
 	if s.metering != nil {
 		handler = s.metering.WrapHandler(handler)
 	}
-	return s.loggingMiddleware(s.permissionMiddleware(handler))
+	return s.loggingMiddleware(s.tracingMiddleware(s.permissionMiddleware(handler)))
 }
 
 // loggingMiddleware logs all requests.
@@ -7,6 +7,7 @@ import (
 	"io"
 	"log"
 	"net/http"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -71,6 +72,13 @@ func (m *MeteringMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// Extract consumer key from Authorization header
 	consumerKey := extractConsumerKey(r)
 
+	// Audio requests are multipart uploads or raw-audio responses with no usage
+	// object; the audio handlers report usage in headers instead.
+	if isAudioPath(r.URL.Path) {
+		m.handleAudioResponse(w, r, start, consumerKey)
+		return
+	}
+
 	// Detect if client requested streaming
 	isStream := false
 	if r.Body != nil {
@@ -140,6 +148,22 @@ func (m *MeteringMiddleware) handleStreamingResponse(w http.ResponseWriter, r *h
 	m.recordUsage(usage, r.URL.Path, consumerKey, latency)
 }
 
+// handleAudioResponse meters an audio request from the usage headers its
+// handler set. The response (audio bytes or an upload's transcript) is passed
+// through without buffering.
+func (m *MeteringMiddleware) handleAudioResponse(w http.ResponseWriter, r *http.Request, start time.Time, consumerKey string) {
+	m.next.ServeHTTP(w, r)
+
+	latency := time.Since(start).Seconds() * 1000
+
+	usage, ok := usageFromHeaders(w.Header())
+	if !ok {
+		return
+	}
+
+	m.recordUsage(usage, r.URL.Path, consumerKey, latency)
+}
+
 func (m *MeteringMiddleware) recordUsage(usage openAIUsage, path, consumerKey string, latencyMs float64) {
 	cost := CalculateACETCost(m.tier, usage.PromptTokens, usage.CompletionTokens)
 
@@ -232,14 +256,48 @@ func extractUsageFromSSELine(line []byte) (openAIUsage, bool) {
 	return usage, true
 }
 
+// Usage headers carry token-equivalent usage for responses that have no OpenAI
+// usage object (audio). They are set only on success and are visible to the
+// client as well.
+const (
+	usageModelHeader            = "X-Citadel-Usage-Model"
+	usagePromptTokensHeader     = "X-Citadel-Usage-Prompt-Tokens"
+	usageCompletionTokensHeader = "X-Citadel-Usage-Completion-Tokens"
+)
+
+// setUsageHeaders records usage on a response's headers for the middleware.
+func setUsageHeaders(h http.Header, model string, promptTokens, completionTokens int) {
+	h.Set(usageModelHeader, model)
+	h.Set(usagePromptTokensHeader, strconv.Itoa(promptTokens))
+	h.Set(usageCompletionTokensHeader, strconv.Itoa(completionTokens))
+}
+
+// usageFromHeaders reads usage set by setUsageHeaders. ok is false when there
+// is none to bill.
+func usageFromHeaders(h http.Header) (usage openAIUsage, ok bool) {
+	usage.PromptTokens, _ = strconv.Atoi(h.Get(usagePromptTokensHeader))
+	usage.CompletionTokens, _ = strconv.Atoi(h.Get(usageCompletionTokensHeader))
+	if usage.PromptTokens <= 0 && usage.CompletionTokens <= 0 {
+		return openAIUsage{}, false
+	}
+	usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+	usage.Model = h.Get(usageModelHeader)
+	return usage, true
+}
+
 // isMeteredPath returns true for OpenAI-compatible API paths.
 // Note: r.URL.Path never includes query strings, so exact match suffices.
 func isMeteredPath(path string) bool {
 	switch path {
-	case "/v1/chat/completions", "/v1/completions", "/v1/embeddings":
+	case "/v1/chat/completions", "/v1/completions", "/v1/embeddings", "/v1/rerank":
 		return true
 	}
-	return false
+	return isAudioPath(path)
+}
+
+// isAudioPath returns true for the OpenAI audio endpoints (audio_route.go).
+func isAudioPath(path string) bool {
+	return path == "/v1/audio/transcriptions" || path == "/v1/audio/speech"
 }
 
 func detectStream(body []byte) bool {
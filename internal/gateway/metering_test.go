@@ -19,6 +19,9 @@ func TestIsMeteredPath(t *testing.T) {
 		{"/v1/chat/completions", true},
 		{"/v1/completions", true},
 		{"/v1/embeddings", true},
+		{"/v1/rerank", true},
+		{"/v1/audio/transcriptions", true},
+		{"/v1/audio/speech", true},
 		{"/v1/models", false},
 		{"/health", false},
 		{"/", false},
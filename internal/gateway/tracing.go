@@ -0,0 +1,79 @@
+package gateway
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/aceteam-ai/citadel-cli/internal/tracing"
+)
+
+// tracingMiddleware opens a server span for each request, parented on the
+// caller's traceparent, and rewrites that header to the gateway's own span.
+// The reverse proxies forward inbound headers, so every upstream (chat,
+// embeddings, audio, static routes) continues the trace without per-route
+// code. A no-op while tracing is off.
+func (s *Server) tracingMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !tracing.Enabled() {
+			next.ServeHTTP(w, r)
+			return
+		}
+		ctx, span := tracing.Start(tracing.Extract(r.Context(), r.Header), "gateway "+r.Method,
+			tracing.WithKind(tracing.KindServer),
+			tracing.WithAttr("http.request.method", r.Method),
+			tracing.WithAttr("url.path", r.URL.Path))
+		defer span.End()
+		r = r.WithContext(ctx)
+		tracing.Inject(ctx, r.Header)
+
+		tw := &tracedResponseWriter{ResponseWriter: w}
+		next.ServeHTTP(tw, r)
+		if tw.status == 0 {
+			tw.status = http.StatusOK
+		}
+		span.SetAttr("http.response.status_code", tw.status)
+		if tw.status >= http.StatusInternalServerError {
+			span.RecordError(fmt.Errorf("HTTP %d", tw.status))
+		}
+	})
+}
+
+// tracedResponseWriter records the response status. It passes Flush and
+// Hijack through so streaming and WebSocket routes behave as unwrapped.
+type tracedResponseWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *tracedResponseWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *tracedResponseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *tracedResponseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *tracedResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("response writer does not support hijacking")
+	}
+	w.status = http.StatusSwitchingProtocols
+	return h.Hijack()
+}
+
+func (w *tracedResponseWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
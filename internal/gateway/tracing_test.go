@@ -0,0 +1,72 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aceteam-ai/citadel-cli/internal/tracing"
+)
+
+// proxyTraceparent sends one request with the given traceparent through a
+// gateway proxy and returns what the upstream received.
+func proxyTraceparent(t *testing.T, incoming string) string {
+	t.Helper()
+	var got string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get(tracing.TraceparentHeader)
+	}))
+	defer upstream.Close()
+
+	gw := NewServer(Config{Port: 0})
+	gw.registerProxy("/v1/embeddings", &Upstream{Address: strings.TrimPrefix(upstream.URL, "http://")})
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/embeddings", nil)
+	if incoming != "" {
+		req.Header.Set(tracing.TraceparentHeader, incoming)
+	}
+	w := httptest.NewRecorder()
+	gw.BuildHandler().ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d", w.Code)
+	}
+	return got
+}
+
+func TestTracingMiddlewareContinuesCallerTrace(t *testing.T) {
+	const incoming = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+	// Off: the caller's header passes through untouched.
+	if got := proxyTraceparent(t, incoming); got != incoming {
+		t.Errorf("tracing off: upstream saw %q, want the caller's header", got)
+	}
+
+	collector := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer collector.Close()
+	shutdown, err := tracing.Setup(tracing.Config{Endpoint: collector.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		shutdown(ctx)
+	}()
+
+	// On: the upstream's parent is the gateway's span, in the caller's trace.
+	got, ok := tracing.ParseTraceparent(proxyTraceparent(t, incoming))
+	if !ok {
+		t.Fatal("upstream saw no valid traceparent")
+	}
+	if got.TraceID.String() != "4bf92f3577b34da6a3ce929d0e0e4736" || got.SpanID.String() == "00f067aa0ba902b7" {
+		t.Errorf("upstream parent = %+v, want a gateway span in the caller's trace", got)
+	}
+
+	// On, no caller trace: the gateway starts one.
+	if _, ok := tracing.ParseTraceparent(proxyTraceparent(t, "")); !ok {
+		t.Error("gateway did not start a trace for an untraced request")
+	}
+}
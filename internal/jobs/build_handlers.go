@@ -20,14 +20,17 @@ const buildTimeout = 30 * time.Minute
 
 // runBuildCommand executes name with args in workspace (when non-empty) and
 // returns combined stdout/stderr plus the error. It is the single exec path for
-// all build handlers so platform gating and timeout behaviour stay consistent.
+// all build handlers so platform gating, timeout and cancellation behaviour
+// stay consistent: a cancelled job SIGTERMs the toolchain so gradle and
+// xcodebuild can stop their daemons and simulators before being killed.
 //
 // It is a variable (not a plain function) so tests can stub the exec layer and
 // exercise the handlers' result/error shaping without a real toolchain.
-var runBuildCommand = func(workspace, name string, args []string) ([]byte, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), buildTimeout)
+var runBuildCommand = func(ctx context.Context, workspace, name string, args []string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, buildTimeout)
 	defer cancel()
 	cmd := exec.CommandContext(ctx, name, args...)
+	stopGracefully(cmd)
 	if workspace != "" {
 		cmd.Dir = workspace
 	}
@@ -425,7 +428,7 @@ func (h *IOSBuildHandler) Execute(ctx JobContext, job *nexus.Job) ([]byte, error
 	cmdStr := "xcodebuild " + strings.Join(args, " ")
 	ctx.Log("info", "     - [Job %s] IOS_BUILD: %s", job.ID, cmdStr)
 
-	out, runErr := runBuildCommand(h.WorkspaceDir, "xcodebuild", args)
+	out, runErr := runBuildCommand(ctx.Context(), h.WorkspaceDir, "xcodebuild", args)
 	if runErr != nil {
 		return out, fmt.Errorf("xcodebuild failed: %w", runErr)
 	}
@@ -449,7 +452,7 @@ func (h *IOSBuildHandler) executeArchiveExport(ctx JobContext, job *nexus.Job) (
 
 	archiveCmd := "xcodebuild " + strings.Join(archiveArgs, " ")
 	ctx.Log("info", "     - [Job %s] IOS_BUILD archive: %s", job.ID, archiveCmd)
-	archiveOut, runErr := runBuildCommand(h.WorkspaceDir, "xcodebuild", archiveArgs)
+	archiveOut, runErr := runBuildCommand(ctx.Context(), h.WorkspaceDir, "xcodebuild", archiveArgs)
 	if runErr != nil {
 		return archiveOut, fmt.Errorf("xcodebuild archive failed: %w", runErr)
 	}
@@ -470,7 +473,7 @@ func (h *IOSBuildHandler) executeArchiveExport(ctx JobContext, job *nexus.Job) (
 	}
 	exportCmd := "xcodebuild " + strings.Join(exportArgs, " ")
 	ctx.Log("info", "     - [Job %s] IOS_BUILD export: %s", job.ID, exportCmd)
-	exportOut, runErr := runBuildCommand(h.WorkspaceDir, "xcodebuild", exportArgs)
+	exportOut, runErr := runBuildCommand(ctx.Context(), h.WorkspaceDir, "xcodebuild", exportArgs)
 	if runErr != nil {
 		return exportOut, fmt.Errorf("xcodebuild -exportArchive failed: %w", runErr)
 	}
@@ -549,7 +552,7 @@ func (h *AndroidBuildHandler) Execute(ctx JobContext, job *nexus.Job) ([]byte, e
 	cmdStr := "./gradlew " + strings.Join(args, " ")
 	ctx.Log("info", "     - [Job %s] ANDROID_BUILD: %s", job.ID, cmdStr)
 
-	out, runErr := runBuildCommand(h.WorkspaceDir, "./gradlew", args)
+	out, runErr := runBuildCommand(ctx.Context(), h.WorkspaceDir, "./gradlew", args)
 	if runErr != nil {
 		return out, fmt.Errorf("gradlew failed: %w", runErr)
 	}
@@ -638,7 +641,7 @@ func (h *GomobileBuildHandler) Execute(ctx JobContext, job *nexus.Job) ([]byte,
 	cmdStr := "gomobile " + strings.Join(args, " ")
 	ctx.Log("info", "     - [Job %s] GOMOBILE_BUILD: %s", job.ID, cmdStr)
 
-	out, runErr := runBuildCommand(h.WorkspaceDir, "gomobile", args)
+	out, runErr := runBuildCommand(ctx.Context(), h.WorkspaceDir, "gomobile", args)
 	if runErr != nil {
 		return out, fmt.Errorf("gomobile bind failed: %w", runErr)
 	}
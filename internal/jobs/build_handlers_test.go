@@ -1,6 +1,7 @@
 package jobs
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"reflect"
@@ -28,7 +29,7 @@ func stubBuildCommand(t *testing.T, out []byte, err error) *struct {
 		args      []string
 	}{}
 	orig := runBuildCommand
-	runBuildCommand = func(workspace, name string, args []string) ([]byte, error) {
+	runBuildCommand = func(_ context.Context, workspace, name string, args []string) ([]byte, error) {
 		rec.called = true
 		rec.workspace = workspace
 		rec.name = name
@@ -461,7 +462,7 @@ func TestIOSBuildHandler_ArchiveExportFlow(t *testing.T) {
 	// before the export phase.
 	var calls [][]string
 	orig := runBuildCommand
-	runBuildCommand = func(workspace, name string, args []string) ([]byte, error) {
+	runBuildCommand = func(_ context.Context, workspace, name string, args []string) ([]byte, error) {
 		calls = append(calls, args)
 		return []byte("** OK **"), nil
 	}
@@ -523,7 +524,7 @@ func TestIOSBuildHandler_ArchiveFailureStops(t *testing.T) {
 	}
 	var calls int
 	orig := runBuildCommand
-	runBuildCommand = func(workspace, name string, args []string) ([]byte, error) {
+	runBuildCommand = func(_ context.Context, workspace, name string, args []string) ([]byte, error) {
 		calls++
 		return []byte("** ARCHIVE FAILED **"), errors.New("exit status 65")
 	}
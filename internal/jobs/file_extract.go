@@ -0,0 +1,831 @@
+// internal/jobs/file_extract.go
+package jobs
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os/exec"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+
+	"github.com/aceteam-ai/citadel-cli/services"
+)
+
+// maxIndexDocumentBytes caps a rich document (PDF, Office, HTML, image) that
+// FILE_INDEX will extract. It is far above maxIndexFileBytes because a document's
+// size is mostly layout, fonts and images; the text it yields is still bounded
+// by maxChunksPerFile.
+const maxIndexDocumentBytes = 64 << 20 // 64 MiB
+
+// maxOCRPagesPerFile bounds how many pages of one document are sent to the OCR
+// service. Each page is a vision-model call, so an unbounded scan would tie the
+// node's GPU up for one file.
+const maxOCRPagesPerFile = 50
+
+// documentExtractorVersion is recorded with every extracted document. Bump it
+// whenever an extractor changes the text it yields, so FILE_INDEX extracts
+// documents indexed by an older release again even though their content did
+// not change.
+const documentExtractorVersion = 1
+
+// ocrRenderDPI is the resolution scanned PDF pages are rendered at before OCR.
+// 150 DPI keeps body text legible to the model at a fraction of 300 DPI's size.
+const ocrRenderDPI = 150
+
+// DocSection is one span of extracted document text plus where it sits in the
+// source, so chunks cut from it can cite a page and heading.
+type DocSection struct {
+	// Text is the extracted plain text. Paragraphs are blank-line separated so
+	// chunkText can split on them.
+	Text string
+	// Page is the 1-based page (PDF page, PPTX slide, DOCX explicit page
+	// break). Zero when the format has no pages.
+	Page int
+	// Section is the nearest heading, slide title or sheet name. Empty when
+	// the format has no structure.
+	Section string
+}
+
+// DocumentExtractor turns one file's bytes into indexable text sections.
+// Returning an error skips the file for this run without failing the job, so
+// one damaged document cannot stop a directory from indexing.
+type DocumentExtractor interface {
+	Extract(ctx context.Context, filePath string, content []byte) ([]DocSection, error)
+}
+
+// DocumentExtractorFunc adapts a plain function to DocumentExtractor.
+type DocumentExtractorFunc func(ctx context.Context, filePath string, content []byte) ([]DocSection, error)
+
+// Extract calls f.
+func (f DocumentExtractorFunc) Extract(ctx context.Context, filePath string, content []byte) ([]DocSection, error) {
+	return f(ctx, filePath, content)
+}
+
+// DefaultDocumentExtractors returns the built-in extractors keyed by lowercase
+// file extension. Files with no entry are indexed as plain text, as before.
+// Images are only indexable through OCR, so they are registered only when ocr
+// is set; scanned PDF pages are likewise OCRed only then.
+func DefaultDocumentExtractors(ocr bool) map[string]DocumentExtractor {
+	pdf := &pdfExtractor{ocr: ocr}
+	m := map[string]DocumentExtractor{
+		".pdf":   pdf,
+		".docx":  DocumentExtractorFunc(extractDOCX),
+		".xlsx":  DocumentExtractorFunc(extractXLSX),
+		".pptx":  DocumentExtractorFunc(extractPPTX),
+		".html":  DocumentExtractorFunc(extractHTML),
+		".htm":   DocumentExtractorFunc(extractHTML),
+		".xhtml": DocumentExtractorFunc(extractHTML),
+	}
+	if ocr {
+		for ext, mime := range ocrImageTypes {
+			m[ext] = imageOCRExtractor(mime)
+		}
+	}
+	return m
+}
+
+// ocrImageTypes maps the image extensions the OCR service accepts to the MIME
+// type of the data URL they are sent as.
+var ocrImageTypes = map[string]string{
+	".png":  "image/png",
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".webp": "image/webp",
+	".tif":  "image/tiff",
+	".tiff": "image/tiff",
+}
+
+// imageOCRExtractor indexes a scanned image as a single page of OCR text.
+func imageOCRExtractor(mime string) DocumentExtractor {
+	return DocumentExtractorFunc(func(ctx context.Context, _ string, content []byte) ([]DocSection, error) {
+		text, err := ocrImage(ctx, content, mime)
+		if err != nil {
+			return nil, err
+		}
+		return []DocSection{{Text: text, Page: 1}}, nil
+	})
+}
+
+// --- PDF (poppler) ---
+
+// The PDF edges are package vars so tests can stand in for poppler, which the
+// test environment does not have; production never reassigns them. They are
+// the same poppler tools DOCUMENT_RASTERIZE drives, over stdin/stdout, so the
+// document never touches the disk.
+var (
+	pdfToText     = pdfToTextWithPoppler
+	renderPDFPage = renderPDFPageWithPoppler
+)
+
+// errPDFToolsMissing reports that poppler is not installed, naming the package
+// so the skip log tells the operator what to install.
+var errPDFToolsMissing = errors.New("pdftotext not found in PATH (install poppler-utils, e.g. `apt-get install -y poppler-utils`)")
+
+// pdfExtractor extracts one section per PDF page. Pages with no text layer are
+// treated as scans and, when ocr is set, rendered and sent to the OCR service.
+type pdfExtractor struct {
+	ocr bool
+}
+
+func (e *pdfExtractor) Extract(ctx context.Context, _ string, content []byte) ([]DocSection, error) {
+	text, err := pdfToText(ctx, content)
+	if err != nil {
+		return nil, err
+	}
+	// pdftotext ends every page with a form feed, so the final split element
+	// is the empty remainder after the last page.
+	pages := strings.Split(text, "\f")
+	if len(pages) > 1 && strings.TrimSpace(pages[len(pages)-1]) == "" {
+		pages = pages[:len(pages)-1]
+	}
+	var out []DocSection
+	ocrPages := 0
+	for i, page := range pages {
+		pageNo := i + 1
+		if strings.TrimSpace(page) == "" {
+			if !e.ocr || ocrPages >= maxOCRPagesPerFile {
+				continue
+			}
+			ocrPages++
+			img, err := renderPDFPage(ctx, content, pageNo)
+			if err != nil {
+				return nil, fmt.Errorf("render page %d for OCR: %w", pageNo, err)
+			}
+			if page, err = ocrImage(ctx, img, "image/png"); err != nil {
+				return nil, fmt.Errorf("OCR page %d: %w", pageNo, err)
+			}
+		}
+		out = append(out, DocSection{Text: pdfParagraphs(page), Page: pageNo})
+	}
+	return out, nil
+}
+
+// pdfParagraphs normalizes pdftotext's line-per-visual-line output: lines are
+// kept, but runs of blank lines collapse to a single paragraph break.
+func pdfParagraphs(page string) string {
+	return strings.Join(splitParagraphs(page), "\n\n")
+}
+
+func pdfToTextWithPoppler(ctx context.Context, pdf []byte) (string, error) {
+	bin, err := exec.LookPath("pdftotext")
+	if err != nil {
+		return "", errPDFToolsMissing
+	}
+	out, err := runPoppler(ctx, bin, pdf, "-enc", "UTF-8", "-", "-")
+	return string(out), err
+}
+
+func renderPDFPageWithPoppler(ctx context.Context, pdf []byte, page int) ([]byte, error) {
+	bin, err := exec.LookPath("pdftoppm")
+	if err != nil {
+		return nil, errors.New("pdftoppm not found in PATH (install poppler-utils)")
+	}
+	return runPoppler(ctx, bin, pdf,
+		"-png", "-r", strconv.Itoa(ocrRenderDPI),
+		"-f", strconv.Itoa(page), "-l", strconv.Itoa(page),
+		"-singlefile", "-")
+}
+
+// runPoppler pipes pdf through a poppler tool and returns its stdout, keeping
+// only the first line of stderr on failure (a damaged file produces a cascade).
+func runPoppler(ctx context.Context, bin string, pdf []byte, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, bin, args...)
+	cmd.Stdin = bytes.NewReader(pdf)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		detail, _, _ := strings.Cut(strings.TrimSpace(stderr.String()), "\n")
+		if detail == "" {
+			detail = err.Error()
+		}
+		return nil, fmt.Errorf("%s: %s", path.Base(bin), detail)
+	}
+	return stdout.Bytes(), nil
+}
+
+// --- OCR (unlimited-ocr sidecar) ---
+
+// ocrHostPort is the citadel-owned host port of the unlimited-ocr service. A
+// package var only so tests can point it at an httptest listener.
+var ocrHostPort = services.UnlimitedOCRHostPort
+
+// ocrModel is the served model id pinned by services/compose/unlimited-ocr.yml.
+const ocrModel = "baidu/Unlimited-OCR"
+
+// ocrPrompt asks for reading-order text without markup so the result embeds
+// like any other document text.
+const ocrPrompt = "Transcribe all text on this page in reading order as plain text. Separate paragraphs with blank lines."
+
+// ocrClient bounds one page's OCR call; a page is a single vision-model
+// generation, which is slow on a cold engine but never minutes.
+var ocrClient = &http.Client{Timeout: 3 * time.Minute}
+
+// ocrImage sends one page image to the OCR service's OpenAI-compatible chat
+// endpoint and returns the recognized text.
+func ocrImage(ctx context.Context, img []byte, mime string) (string, error) {
+	body, err := json.Marshal(map[string]any{
+		"model":       ocrModel,
+		"temperature": 0,
+		"max_tokens":  4096,
+		"messages": []map[string]any{{
+			"role": "user",
+			"content": []map[string]any{
+				{"type": "image_url", "image_url": map[string]string{
+					"url": "data:" + mime + ";base64," + base64.StdEncoding.EncodeToString(img),
+				}},
+				{"type": "text", "text": ocrPrompt},
+			},
+		}},
+	})
+	if err != nil {
+		return "", err
+	}
+	url := fmt.Sprintf("http://localhost:%d/v1/chat/completions", ocrHostPort)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := ocrClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("OCR service unreachable at %s (start it with 'citadel service start unlimited-ocr'): %w", url, err)
+	}
+	defer resp.Body.Close()
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("OCR service returned %s: %s", resp.Status, truncateLine(string(raw), 200))
+	}
+	var out struct {
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+	}
+	if err := json.Unmarshal(raw, &out); err != nil {
+		return "", fmt.Errorf("decode OCR response: %w", err)
+	}
+	if len(out.Choices) == 0 {
+		return "", errors.New("OCR service returned no choices")
+	}
+	return out.Choices[0].Message.Content, nil
+}
+
+// --- Office Open XML (DOCX / XLSX / PPTX) ---
+
+// maxOfficePartBytes caps one decompressed XML part, so a zip bomb cannot
+// exhaust memory.
+const maxOfficePartBytes = 64 << 20
+
+// sectionBuilder accumulates paragraphs into DocSections, starting a new
+// section whenever the page or heading changes.
+type sectionBuilder struct {
+	out     []DocSection
+	cur     strings.Builder
+	page    int
+	section string
+}
+
+// paragraph appends one paragraph of text to the current section.
+func (b *sectionBuilder) paragraph(text string) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return
+	}
+	if b.cur.Len() > 0 {
+		b.cur.WriteString("\n\n")
+	}
+	b.cur.WriteString(text)
+}
+
+// start closes the current section and opens one at page/section.
+func (b *sectionBuilder) start(page int, section string) {
+	if page == b.page && section == b.section {
+		return
+	}
+	b.flush()
+	b.page, b.section = page, section
+}
+
+func (b *sectionBuilder) flush() {
+	if b.cur.Len() > 0 {
+		b.out = append(b.out, DocSection{Text: b.cur.String(), Page: b.page, Section: b.section})
+		b.cur.Reset()
+	}
+}
+
+func (b *sectionBuilder) sections() []DocSection {
+	b.flush()
+	return b.out
+}
+
+// openZipPart returns a decoder over one member of an OOXML package.
+func openZipPart(zr *zip.Reader, name string) (*xml.Decoder, io.Closer, error) {
+	f, err := zr.Open(name)
+	if err != nil {
+		return nil, nil, fmt.Errorf("missing %s: %w", name, err)
+	}
+	return xml.NewDecoder(io.LimitReader(f, maxOfficePartBytes)), f, nil
+}
+
+// attr returns the value of the attribute with the given local name.
+func attr(se xml.StartElement, local string) string {
+	for _, a := range se.Attr {
+		if a.Name.Local == local {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// isHeadingStyle reports whether a Word paragraph style id is a heading. The
+// built-in ids are locale-independent ("Heading1", "Title").
+func isHeadingStyle(style string) bool {
+	s := strings.ToLower(style)
+	return strings.HasPrefix(s, "heading") || s == "title"
+}
+
+// extractDOCX reads word/document.xml. Headings start new sections and
+// explicit page breaks advance the page; Word does not store rendered page
+// numbers, so pages count from 1 at the top and only move on hard breaks.
+func extractDOCX(_ context.Context, _ string, content []byte) ([]DocSection, error) {
+	zr, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return nil, fmt.Errorf("not a DOCX package: %w", err)
+	}
+	dec, closer, err := openZipPart(zr, "word/document.xml")
+	if err != nil {
+		return nil, err
+	}
+	defer closer.Close()
+
+	b := &sectionBuilder{page: 1}
+	var (
+		para     strings.Builder
+		depth    int // nested paragraphs (text boxes) fold into the outer one
+		inText   bool
+		heading  bool
+		inTabs   bool // w:tabs holds tab-stop definitions, not tab characters
+		fallback int  // mc:Fallback repeats its mc:Choice sibling's content
+	)
+	endParagraph := func() {
+		text := para.String()
+		para.Reset()
+		if heading && strings.TrimSpace(text) != "" {
+			b.start(b.page, strings.TrimSpace(text))
+		}
+		b.paragraph(text)
+		heading = false
+	}
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parse word/document.xml: %w", err)
+		}
+		if se, ok := tok.(xml.StartElement); ok && se.Name.Local == "Fallback" {
+			fallback++
+		}
+		if ee, ok := tok.(xml.EndElement); ok && ee.Name.Local == "Fallback" {
+			fallback--
+		}
+		if fallback > 0 {
+			continue
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "p":
+				depth++
+			case "tabs":
+				inTabs = true
+			case "pStyle":
+				if depth == 1 && isHeadingStyle(attr(t, "val")) {
+					heading = true
+				}
+			case "t":
+				inText = true
+			case "tab":
+				if !inTabs {
+					para.WriteByte('\t')
+				}
+			case "br", "cr":
+				if attr(t, "type") == "page" {
+					endParagraph()
+					b.start(b.page+1, b.section)
+				} else {
+					para.WriteByte('\n')
+				}
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "tabs":
+				inTabs = false
+			case "p":
+				depth--
+				if depth == 0 {
+					endParagraph()
+				}
+			}
+		case xml.CharData:
+			if inText {
+				para.Write(t)
+			}
+		}
+	}
+	return b.sections(), nil
+}
+
+var slidePartRe = regexp.MustCompile(`^ppt/slides/slide(\d+)\.xml$`)
+
+// extractPPTX reads each slide in order as one page, titled by the slide's
+// title placeholder when it has one.
+func extractPPTX(_ context.Context, _ string, content []byte) ([]DocSection, error) {
+	zr, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return nil, fmt.Errorf("not a PPTX package: %w", err)
+	}
+	type slide struct {
+		n    int
+		name string
+	}
+	var slides []slide
+	for _, f := range zr.File {
+		if m := slidePartRe.FindStringSubmatch(f.Name); m != nil {
+			n, _ := strconv.Atoi(m[1])
+			slides = append(slides, slide{n: n, name: f.Name})
+		}
+	}
+	sort.Slice(slides, func(i, j int) bool { return slides[i].n < slides[j].n })
+
+	var out []DocSection
+	for _, s := range slides {
+		title, body, err := readSlide(zr, s.name)
+		if err != nil {
+			return nil, err
+		}
+		if strings.TrimSpace(body) != "" {
+			out = append(out, DocSection{Text: body, Page: s.n, Section: title})
+		}
+	}
+	return out, nil
+}
+
+// readSlide returns a slide's title text and its full text, one paragraph per
+// DrawingML paragraph.
+func readSlide(zr *zip.Reader, name string) (title, body string, err error) {
+	dec, closer, err := openZipPart(zr, name)
+	if err != nil {
+		return "", "", err
+	}
+	defer closer.Close()
+
+	var (
+		b       sectionBuilder
+		para    strings.Builder
+		titles  []string
+		inText  bool
+		isTitle bool // inside a shape whose placeholder is the slide title
+	)
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", "", fmt.Errorf("parse %s: %w", name, err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "sp":
+				isTitle = false
+			case "ph":
+				if ph := attr(t, "type"); ph == "title" || ph == "ctrTitle" {
+					isTitle = true
+				}
+			case "t":
+				inText = true
+			case "br":
+				para.WriteByte('\n')
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				text := strings.TrimSpace(para.String())
+				para.Reset()
+				if isTitle && text != "" {
+					titles = append(titles, text)
+				}
+				b.paragraph(text)
+			}
+		case xml.CharData:
+			if inText {
+				para.Write(t)
+			}
+		}
+	}
+	var text string
+	if secs := b.sections(); len(secs) > 0 {
+		text = secs[0].Text
+	}
+	return strings.Join(titles, " "), text, nil
+}
+
+// extractXLSX reads each worksheet as one section named after its sheet, one
+// tab-separated row per paragraph.
+func extractXLSX(_ context.Context, _ string, content []byte) ([]DocSection, error) {
+	zr, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return nil, fmt.Errorf("not an XLSX package: %w", err)
+	}
+	shared, err := readSharedStrings(zr)
+	if err != nil {
+		return nil, err
+	}
+	sheets, err := readWorkbookSheets(zr)
+	if err != nil {
+		return nil, err
+	}
+	var out []DocSection
+	for _, sh := range sheets {
+		text, err := readWorksheet(zr, sh.part, shared)
+		if err != nil {
+			return nil, err
+		}
+		if strings.TrimSpace(text) != "" {
+			out = append(out, DocSection{Text: text, Section: sh.name})
+		}
+	}
+	return out, nil
+}
+
+// readSharedStrings loads xl/sharedStrings.xml, which a workbook may omit when
+// it holds no text cells. Phonetic runs (rPh) are annotations, not content.
+func readSharedStrings(zr *zip.Reader) ([]string, error) {
+	dec, closer, err := openZipPart(zr, "xl/sharedStrings.xml")
+	if err != nil {
+		return nil, nil
+	}
+	defer closer.Close()
+
+	var (
+		out    []string
+		cur    strings.Builder
+		inText bool
+		inRPh  bool
+	)
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return out, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parse xl/sharedStrings.xml: %w", err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "si":
+				cur.Reset()
+			case "rPh":
+				inRPh = true
+			case "t":
+				inText = !inRPh
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "si":
+				out = append(out, cur.String())
+			case "rPh":
+				inRPh = false
+			case "t":
+				inText = false
+			}
+		case xml.CharData:
+			if inText {
+				cur.Write(t)
+			}
+		}
+	}
+}
+
+type workbookSheet struct {
+	name string
+	part string
+}
+
+// readWorkbookSheets lists the worksheets in tab order with their zip part
+// names, resolving each sheet's relationship id through the workbook rels.
+func readWorkbookSheets(zr *zip.Reader) ([]workbookSheet, error) {
+	targets := map[string]string{}
+	if dec, closer, err := openZipPart(zr, "xl/_rels/workbook.xml.rels"); err == nil {
+		for {
+			tok, err := dec.Token()
+			if err != nil {
+				break
+			}
+			if se, ok := tok.(xml.StartElement); ok && se.Name.Local == "Relationship" {
+				targets[attr(se, "Id")] = attr(se, "Target")
+			}
+		}
+		closer.Close()
+	}
+
+	dec, closer, err := openZipPart(zr, "xl/workbook.xml")
+	if err != nil {
+		return nil, err
+	}
+	defer closer.Close()
+	var out []workbookSheet
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parse xl/workbook.xml: %w", err)
+		}
+		se, ok := tok.(xml.StartElement)
+		if !ok || se.Name.Local != "sheet" {
+			continue
+		}
+		target := targets[attr(se, "id")]
+		if target == "" {
+			continue
+		}
+		if strings.HasPrefix(target, "/") {
+			target = strings.TrimPrefix(target, "/")
+		} else {
+			target = path.Join("xl", target)
+		}
+		out = append(out, workbookSheet{name: attr(se, "name"), part: target})
+	}
+	return out, nil
+}
+
+// readWorksheet renders a worksheet's cells as rows of tab-separated values.
+func readWorksheet(zr *zip.Reader, part string, shared []string) (string, error) {
+	dec, closer, err := openZipPart(zr, part)
+	if err != nil {
+		return "", err
+	}
+	defer closer.Close()
+
+	var (
+		b        sectionBuilder
+		row      []string
+		cell     strings.Builder
+		cellType string
+		inValue  bool
+	)
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("parse %s: %w", part, err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "row":
+				row = row[:0]
+			case "c":
+				cell.Reset()
+				cellType = attr(t, "t")
+			case "v", "t":
+				inValue = true
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "v", "t":
+				inValue = false
+			case "c":
+				v := cell.String()
+				if cellType == "s" {
+					if i, err := strconv.Atoi(v); err == nil && i >= 0 && i < len(shared) {
+						v = shared[i]
+					}
+				}
+				if v = strings.TrimSpace(v); v != "" {
+					row = append(row, v)
+				}
+			case "row":
+				b.paragraph(strings.Join(row, "\t"))
+			}
+		case xml.CharData:
+			if inValue {
+				cell.Write(t)
+			}
+		}
+	}
+	var text string
+	if secs := b.sections(); len(secs) > 0 {
+		text = secs[0].Text
+	}
+	return text, nil
+}
+
+// --- HTML ---
+
+// htmlBlockTags end the current paragraph when they open or close.
+var htmlBlockTags = map[string]bool{
+	"p": true, "div": true, "br": true, "li": true, "tr": true, "td": true, "th": true,
+	"section": true, "article": true, "blockquote": true, "pre": true, "hr": true,
+	"ul": true, "ol": true, "table": true, "dt": true, "dd": true, "figcaption": true,
+}
+
+// htmlSkipTags hold no readable body text.
+var htmlSkipTags = map[string]bool{
+	"script": true, "style": true, "noscript": true, "template": true, "svg": true, "head": true,
+}
+
+// extractHTML converts HTML to paragraphs of visible text, starting a section
+// at each h1-h3 heading. Text in script, style and head never reaches the index.
+func extractHTML(_ context.Context, _ string, content []byte) ([]DocSection, error) {
+	z := html.NewTokenizer(bytes.NewReader(content))
+	var (
+		b    sectionBuilder
+		para strings.Builder
+		skip int
+	)
+	endParagraph := func() {
+		text := strings.Join(strings.Fields(para.String()), " ")
+		para.Reset()
+		b.paragraph(text)
+	}
+	for {
+		switch tt := z.Next(); tt {
+		case html.ErrorToken:
+			if z.Err() == io.EOF {
+				endParagraph()
+				return b.sections(), nil
+			}
+			return nil, fmt.Errorf("parse HTML: %w", z.Err())
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, _ := z.TagName()
+			tag := string(name)
+			if htmlSkipTags[tag] {
+				if tt == html.StartTagToken {
+					skip++
+				}
+				continue
+			}
+			if htmlBlockTags[tag] || htmlHeadingLevel(tag) > 0 {
+				endParagraph()
+			}
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			tag := string(name)
+			switch {
+			case htmlSkipTags[tag]:
+				if skip > 0 {
+					skip--
+				}
+			case htmlHeadingLevel(tag) > 0 && htmlHeadingLevel(tag) <= 3:
+				if title := strings.Join(strings.Fields(para.String()), " "); title != "" {
+					b.start(0, title)
+				}
+				endParagraph()
+			case htmlBlockTags[tag] || htmlHeadingLevel(tag) > 0:
+				endParagraph()
+			}
+		case html.TextToken:
+			if skip == 0 {
+				para.Write(z.Text())
+				para.WriteByte(' ')
+			}
+		}
+	}
+}
+
+// htmlHeadingLevel returns 1-6 for h1-h6 and 0 for any other tag.
+func htmlHeadingLevel(tag string) int {
+	if len(tag) == 2 && tag[0] == 'h' && tag[1] >= '1' && tag[1] <= '6' {
+		return int(tag[1] - '0')
+	}
+	return 0
+}
@@ -0,0 +1,320 @@
+package jobs
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/aceteam-ai/citadel-cli/internal/nexus"
+	"github.com/aceteam-ai/citadel-cli/internal/nodeindex"
+)
+
+// buildZip assembles an in-memory OOXML package from part name to content.
+func buildZip(t *testing.T, parts map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, body := range parts {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(body))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+const wNS = `xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"`
+
+func TestExtractDOCXHeadingsAndPageBreaks(t *testing.T) {
+	doc := buildZip(t, map[string]string{"word/document.xml": `<w:document ` + wNS + `><w:body>
+<w:p><w:pPr><w:pStyle w:val="Heading1"/><w:tabs><w:tab w:val="left" w:pos="720"/></w:tabs></w:pPr><w:r><w:t>Overview</w:t></w:r></w:p>
+<w:p><w:r><w:t>Refunds are</w:t></w:r><w:r><w:t xml:space="preserve"> issued in 30 days.</w:t></w:r></w:p>
+<w:p><w:r><w:br w:type="page"/></w:r></w:p>
+<w:p><w:pPr><w:pStyle w:val="Heading2"/></w:pPr><w:r><w:t>Exceptions</w:t></w:r></w:p>
+<w:p><w:r><w:t>Sale</w:t><w:tab/><w:t>items are final.</w:t></w:r></w:p>
+</w:body></w:document>`})
+
+	got, err := extractDOCX(context.Background(), "policy.docx", doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []DocSection{
+		{Text: "Overview\n\nRefunds are issued in 30 days.", Page: 1, Section: "Overview"},
+		{Text: "Exceptions\n\nSale\titems are final.", Page: 2, Section: "Exceptions"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("sections = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("section %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestExtractPPTXSlidesInOrderWithTitles(t *testing.T) {
+	slide := func(title, body string) string {
+		return `<p:sld xmlns:p="p" xmlns:a="a"><p:cSld><p:spTree>` +
+			`<p:sp><p:nvSpPr><p:nvPr><p:ph type="title"/></p:nvPr></p:nvSpPr><p:txBody><a:p><a:r><a:t>` + title + `</a:t></a:r></a:p></p:txBody></p:sp>` +
+			`<p:sp><p:txBody><a:p><a:r><a:t>` + body + `</a:t></a:r></a:p></p:txBody></p:sp>` +
+			`</p:spTree></p:cSld></p:sld>`
+	}
+	deck := buildZip(t, map[string]string{
+		"ppt/slides/slide10.xml":           slide("Roadmap", "Ship Q4"),
+		"ppt/slides/slide2.xml":            slide("Agenda", "Intro"),
+		"ppt/slides/_rels/slide2.xml.rels": `<Relationships/>`,
+	})
+	got, err := extractPPTX(context.Background(), "deck.pptx", deck)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 ||
+		got[0] != (DocSection{Text: "Agenda\n\nIntro", Page: 2, Section: "Agenda"}) ||
+		got[1] != (DocSection{Text: "Roadmap\n\nShip Q4", Page: 10, Section: "Roadmap"}) {
+		t.Fatalf("sections = %+v", got)
+	}
+}
+
+func TestExtractXLSXSheetsAndSharedStrings(t *testing.T) {
+	book := buildZip(t, map[string]string{
+		"xl/workbook.xml": `<workbook xmlns:r="rel"><sheets>` +
+			`<sheet name="Prices" sheetId="1" r:id="rId1"/><sheet name="Empty" sheetId="2" r:id="rId2"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships>` +
+			`<Relationship Id="rId1" Target="worksheets/sheet1.xml"/><Relationship Id="rId2" Target="/xl/worksheets/sheet2.xml"/></Relationships>`,
+		"xl/sharedStrings.xml": `<sst><si><t>Widget</t></si><si><r><t>Gad</t></r><r><t>get</t></r><rPh><t>ガジェット</t></rPh></si></sst>`,
+		"xl/worksheets/sheet1.xml": `<worksheet><sheetData>` +
+			`<row><c t="s"><v>0</v></c><c><v>9.5</v></c></row>` +
+			`<row><c t="s"><v>1</v></c><c t="inlineStr"><is><t>n/a</t></is></c></row>` +
+			`</sheetData></worksheet>`,
+		"xl/worksheets/sheet2.xml": `<worksheet><sheetData/></worksheet>`,
+	})
+	got, err := extractXLSX(context.Background(), "prices.xlsx", book)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0] != (DocSection{Text: "Widget\t9.5\n\nGadget\tn/a", Section: "Prices"}) {
+		t.Fatalf("sections = %+v", got)
+	}
+}
+
+func TestExtractHTMLSectionsSkipScripts(t *testing.T) {
+	page := `<html><head><title>T</title><style>p{color:red}</style></head><body>
+<p>Intro &amp; welcome.</p><script>var x = "secret";</script>
+<h2>Install <em>steps</em></h2><ul><li>Download</li><li>Run</li></ul>
+<svg/><p>Done.</p></body></html>`
+	got, err := extractHTML(context.Background(), "doc.html", []byte(page))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 ||
+		got[0] != (DocSection{Text: "Intro & welcome."}) ||
+		got[1] != (DocSection{Text: "Install steps\n\nDownload\n\nRun\n\nDone.", Section: "Install steps"}) {
+		t.Fatalf("sections = %+v", got)
+	}
+}
+
+// fakeOCR points the OCR client at a stub unlimited-ocr server that answers
+// every page with reply and counts the requests.
+func fakeOCR(t *testing.T, reply string) *int {
+	t.Helper()
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		var req struct {
+			Model    string `json:"model"`
+			Messages []struct {
+				Content []struct {
+					Type     string            `json:"type"`
+					ImageURL map[string]string `json:"image_url"`
+				} `json:"content"`
+			} `json:"messages"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		if r.URL.Path != "/v1/chat/completions" || req.Model != ocrModel ||
+			!strings.HasPrefix(req.Messages[0].Content[0].ImageURL["url"], "data:image/") {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"choices": []map[string]any{{"message": map[string]string{"content": reply}}},
+		})
+	}))
+	t.Cleanup(srv.Close)
+	_, portStr, _ := net.SplitHostPort(strings.TrimPrefix(srv.URL, "http://"))
+	port, _ := strconv.Atoi(portStr)
+	original := ocrHostPort
+	ocrHostPort = port
+	t.Cleanup(func() { ocrHostPort = original })
+	return &calls
+}
+
+// stubPoppler replaces the poppler edges with canned output.
+func stubPoppler(t *testing.T, text string, textErr error) {
+	t.Helper()
+	origText, origRender := pdfToText, renderPDFPage
+	pdfToText = func(context.Context, []byte) (string, error) { return text, textErr }
+	renderPDFPage = func(_ context.Context, _ []byte, page int) ([]byte, error) {
+		return []byte("PNG page " + strconv.Itoa(page)), nil
+	}
+	t.Cleanup(func() { pdfToText, renderPDFPage = origText, origRender })
+}
+
+func TestPDFExtractorPagesAndOCRFallback(t *testing.T) {
+	// Page 2 has no text layer (a scan).
+	stubPoppler(t, "First page.\n\n\n\nStill first.\f\f  Third page.\n\f", nil)
+	calls := fakeOCR(t, "Scanned words.")
+
+	got, err := (&pdfExtractor{}).Extract(context.Background(), "a.pdf", []byte("%PDF"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0] != (DocSection{Text: "First page.\n\nStill first.", Page: 1}) || got[1].Page != 3 {
+		t.Fatalf("without OCR: sections = %+v, want pages 1 and 3", got)
+	}
+	if *calls != 0 {
+		t.Fatalf("OCR called %d times with OCR off", *calls)
+	}
+
+	got, err = (&pdfExtractor{ocr: true}).Extract(context.Background(), "a.pdf", []byte("%PDF"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 || got[1] != (DocSection{Text: "Scanned words.", Page: 2}) || *calls != 1 {
+		t.Fatalf("with OCR: sections = %+v after %d OCR calls, want the scan read as page 2", got, *calls)
+	}
+}
+
+// TestFileIndexRecordsPageAndSection indexes a PDF, an HTML page, a plain text
+// file and an image, and checks the stored chunks carry their citations.
+func TestFileIndexRecordsPageAndSection(t *testing.T) {
+	tei := fakeTEI(t)
+	t.Setenv("CITADEL_TEI_URL", tei.URL)
+	stubPoppler(t, "Intro.\fThe cat sleeps.\f", nil)
+	fakeOCR(t, "A kitten photo caption.")
+
+	ws := t.TempDir()
+	os.WriteFile(filepath.Join(ws, "report.pdf"), []byte("%PDF-1.7\x00binary"), 0o644)
+	os.WriteFile(filepath.Join(ws, "guide.html"), []byte("<h1>Databases</h1><p>SQL query tips.</p>"), 0o644)
+	os.WriteFile(filepath.Join(ws, "notes.md"), []byte("plain notes"), 0o644)
+	os.WriteFile(filepath.Join(ws, "scan.png"), []byte("\x89PNG\x00"), 0o644)
+
+	dbPath := filepath.Join(t.TempDir(), "index.db")
+	idx := NewFileIndexHandler(ws, dbPath)
+	run := func(payload map[string]string) map[string]float64 {
+		t.Helper()
+		payload["path"] = ws
+		out, err := idx.Execute(JobContext{}, &nexus.Job{ID: "j", Type: "FILE_INDEX", Payload: payload})
+		if err != nil {
+			t.Fatalf("FILE_INDEX: %v", err)
+		}
+		var res map[string]any
+		json.Unmarshal(out, &res)
+		counts := map[string]float64{}
+		for k, v := range res {
+			if f, ok := v.(float64); ok {
+				counts[k] = f
+			}
+		}
+		return counts
+	}
+
+	if res := run(map[string]string{}); res["files_indexed"] != 3 || res["files_skipped"] != 1 {
+		t.Fatalf("without OCR: %v, want pdf/html/md indexed and the image skipped", res)
+	}
+	// Turning OCR on changes how every document is extracted, so the pdf and
+	// html are extracted again along with the image; plain text is not.
+	if res := run(map[string]string{"ocr": "true"}); res["files_indexed"] != 3 || res["files_skipped"] != 1 {
+		t.Fatalf("with OCR: %v, want the image indexed and the documents re-extracted", res)
+	}
+	if res := run(map[string]string{"ocr": "true"}); res["files_indexed"] != 0 {
+		t.Fatalf("with OCR again: %v, want nothing re-indexed", res)
+	}
+
+	store, err := nodeindex.Open(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	hits, err := store.Search([]float32{1, 0, 0}, 10) // the fake TEI's "cat" direction
+	if err != nil {
+		t.Fatal(err)
+	}
+	cites := map[string]nodeindex.SearchHit{}
+	for _, h := range hits {
+		cites[filepath.Base(h.Path)+"|"+h.Text] = h
+	}
+	if h := cites["report.pdf|The cat sleeps."]; h.Page != 2 {
+		t.Errorf("pdf hit = %+v, want page 2", h)
+	}
+	if h := cites["guide.html|Databases\n\nSQL query tips."]; h.Section != "Databases" {
+		t.Errorf("html hit = %+v, want section Databases", h)
+	}
+	if h := cites["scan.png|A kitten photo caption."]; h.Page != 1 {
+		t.Errorf("image hit = %+v, want page 1", h)
+	}
+}
+
+// TestFileIndexKeepsEntryWhenExtractionFails checks a document that fails to
+// extract is skipped without failing the job or pruning its prior entry.
+func TestFileIndexKeepsEntryWhenExtractionFails(t *testing.T) {
+	tei := fakeTEI(t)
+	t.Setenv("CITADEL_TEI_URL", tei.URL)
+	ws := t.TempDir()
+	doc := filepath.Join(ws, "a.pdf")
+	os.WriteFile(doc, []byte("%PDF v1"), 0o644)
+	dbPath := filepath.Join(t.TempDir(), "index.db")
+	idx := NewFileIndexHandler(ws, dbPath)
+	job := &nexus.Job{ID: "j", Type: "FILE_INDEX", Payload: map[string]string{"path": ws}}
+
+	stubPoppler(t, "text", nil)
+	if _, err := idx.Execute(JobContext{}, job); err != nil {
+		t.Fatal(err)
+	}
+
+	os.WriteFile(doc, []byte("%PDF v2"), 0o644)
+	stubPoppler(t, "", errPDFToolsMissing)
+	var logged []string
+	out, err := idx.Execute(JobContext{LogFn: func(_, msg string) { logged = append(logged, msg) }}, job)
+	if err != nil {
+		t.Fatalf("extraction failure failed the job: %v", err)
+	}
+	var res map[string]any
+	json.Unmarshal(out, &res)
+	if res["files_removed"] != 0.0 || res["files_skipped"] != 1.0 {
+		t.Errorf("result = %s, want the pdf skipped and not pruned", out)
+	}
+	if !strings.Contains(strings.Join(logged, "\n"), "poppler-utils") {
+		t.Errorf("log %q does not name the missing package", logged)
+	}
+
+	store, _ := nodeindex.Open(dbPath)
+	defer store.Close()
+	if _, _, indexed, _ := store.FileHash(doc); !indexed {
+		t.Error("prior index entry was dropped")
+	}
+}
+
+func TestDefaultDocumentExtractorsRegistersImagesOnlyWithOCR(t *testing.T) {
+	if _, ok := DefaultDocumentExtractors(false)[".png"]; ok {
+		t.Error(".png registered without OCR")
+	}
+	if _, ok := DefaultDocumentExtractors(true)[".png"]; !ok {
+		t.Error(".png not registered with OCR")
+	}
+	if _, err := extractDOCX(context.Background(), "x.docx", []byte("not a zip")); err == nil {
+		t.Error("extractDOCX accepted a non-zip document")
+	}
+}
@@ -35,11 +35,18 @@ const chunkTargetBytes = 1000
 const maxChunksPerFile = 200
 
 // FileIndexHandler handles FILE_INDEX jobs. It walks a workspace path, computes
-// each text file's content hash, and (re)embeds only files whose content changed
+// each file's content hash, and (re)embeds only files whose content changed
 // since the last index, upserting their chunk vectors into the node-local index.
+// A document is also re-extracted when the way it would be extracted changed:
+// a newer extractor version, or the job's ocr setting flipped.
 // Files previously indexed under the same root that no longer exist on disk are
 // pruned.
 //
+// Text is read through a DocumentExtractor chosen by file extension (PDF,
+// Office, HTML, and OCR'd images; see file_extract.go); anything without one is
+// indexed as plain text. Each chunk records the page and section it came from so
+// search hits can cite them.
+//
 // It is the node tier of aceteam#6087's two-tier index. Path validation and
 // binary/noise-dir skipping mirror FileSearchHandler so the two stay consistent.
 type FileIndexHandler struct {
@@ -50,6 +57,9 @@ type FileIndexHandler struct {
 	DBPath string
 	// AllowOutsideWorkspace mirrors the read-handler relaxation flag.
 	AllowOutsideWorkspace bool
+	// Extractors overrides the extractor registry, keyed by lowercase file
+	// extension. Nil uses DefaultDocumentExtractors for the job's ocr setting.
+	Extractors map[string]DocumentExtractor
 }
 
 // NewFileIndexHandler creates a FileIndexHandler rooted at workspace.
@@ -66,6 +76,8 @@ func NewFileIndexHandler(workspace, dbPath string) *FileIndexHandler {
 //   - model: TEI embedding model. Optional; defaults to CITADEL_EMBEDDING_MODEL
 //     or defaultEmbeddingModel.
 //   - file_pattern: optional glob to restrict indexed filenames (e.g. "*.md").
+//   - ocr: "true" sends images and scanned (textless) PDF pages to the local
+//     unlimited-ocr service. Default off: OCR is a GPU model call per page.
 //   - prune: "true" (default) removes index entries for files that no longer
 //     exist under the indexed root; "false" leaves them. Note: prune compares
 //     against the files this run actually visited, so combining prune with a
@@ -86,6 +98,11 @@ func (h *FileIndexHandler) Execute(ctx JobContext, job *nexus.Job) ([]byte, erro
 	}
 	filePattern := job.Payload["file_pattern"]
 	prune := job.Payload["prune"] != "false"
+	ocr := job.Payload["ocr"] == "true"
+	extractors := h.Extractors
+	if extractors == nil {
+		extractors = DefaultDocumentExtractors(ocr)
+	}
 
 	validated, err := ValidateReadPath(h.WorkspaceDir, path, h.AllowOutsideWorkspace)
 	if err != nil {
@@ -98,7 +115,7 @@ func (h *FileIndexHandler) Execute(ctx JobContext, job *nexus.Job) ([]byte, erro
 	}
 	defer store.Close()
 
-	ctx.Log("info", "     - [Job %s] FILE_INDEX %s model=%q pattern=%q", job.ID, validated, model, filePattern)
+	ctx.Log("info", "     - [Job %s] FILE_INDEX %s model=%q pattern=%q ocr=%t", job.ID, validated, model, filePattern, ocr)
 
 	// Enumerate candidate files first so pruning can compare against on-disk state.
 	seen := make(map[string]struct{})
@@ -126,7 +143,12 @@ func (h *FileIndexHandler) Execute(ctx JobContext, job *nexus.Job) ([]byte, erro
 		if err != nil {
 			return nil
 		}
-		if info.Size() > maxIndexFileBytes {
+		extractor, rich := extractors[strings.ToLower(filepath.Ext(p))]
+		limit := int64(maxIndexFileBytes)
+		if rich {
+			limit = maxIndexDocumentBytes
+		}
+		if info.Size() > limit {
 			skipped++
 			return nil
 		}
@@ -134,40 +156,59 @@ func (h *FileIndexHandler) Execute(ctx JobContext, job *nexus.Job) ([]byte, erro
 		if err != nil {
 			return nil
 		}
-		if len(content) == 0 || isBinaryContent(content) {
+		if len(content) == 0 || (!rich && isBinaryContent(content)) {
 			skipped++
 			return nil
 		}
 
 		seen[p] = struct{}{}
 		hash := hashContent(content)
+		extraction := ""
+		if rich {
+			extraction = documentExtraction(ocr)
+		}
 
-		prev, wasIndexed, herr := store.FileHash(p)
+		prev, prevExtraction, wasIndexed, herr := store.FileHash(p)
 		if herr != nil {
 			return fmt.Errorf("read prior hash for %s: %w", p, herr)
 		}
-		if wasIndexed && prev == hash {
+		if wasIndexed && prev == hash && prevExtraction == extraction {
 			skipped++
 			return nil // unchanged
 		}
 
-		chunks := chunkText(string(content))
+		sections := []DocSection{{Text: string(content)}}
+		if rich {
+			// A document that cannot be extracted this run (damaged file, poppler
+			// or the OCR service missing) keeps whatever the index already holds
+			// for it: it stays in seen, so prune leaves it alone.
+			if sections, err = extractor.Extract(ctx.Context(), p, content); err != nil {
+				ctx.Log("warn", "     - [Job %s] FILE_INDEX skipped %s: %v", job.ID, p, err)
+				skipped++
+				return nil
+			}
+		}
+		chunks := chunkSections(sections)
 		if len(chunks) == 0 {
 			skipped++
 			return nil
 		}
-		vecs, err := embedTexts(model, chunks)
+		texts := make([]string, len(chunks))
+		for i, c := range chunks {
+			texts[i] = c.Text
+		}
+		vecs, err := embedTexts(model, texts)
 		if err != nil {
 			return fmt.Errorf("embed %s: %w", p, err)
 		}
 		idxChunks := make([]nodeindex.Chunk, len(chunks))
-		for i := range chunks {
-			idxChunks[i] = nodeindex.Chunk{Index: i, Text: chunks[i], Embedding: vecs[i]}
+		for i, c := range chunks {
+			idxChunks[i] = nodeindex.Chunk{Index: i, Text: c.Text, Embedding: vecs[i], Page: c.Page, Section: c.Section}
 		}
 		if len(vecs) > 0 {
 			dim = len(vecs[0])
 		}
-		if err := store.UpsertFile(p, hash, info.ModTime().Unix(), info.Size(), model, dim, idxChunks); err != nil {
+		if err := store.UpsertFile(p, hash, extraction, info.ModTime().Unix(), info.Size(), model, dim, idxChunks); err != nil {
 			return fmt.Errorf("upsert %s: %w", p, err)
 		}
 		indexed++
@@ -213,6 +254,12 @@ func (h *FileIndexHandler) Execute(ctx JobContext, job *nexus.Job) ([]byte, erro
 	return json.Marshal(out)
 }
 
+// documentExtraction identifies how a document is extracted, recorded beside
+// its content hash.
+func documentExtraction(ocr bool) string {
+	return fmt.Sprintf("v%d ocr=%t", documentExtractorVersion, ocr)
+}
+
 // hashContent returns the full lowercase hex SHA-256 of content. The two-tier
 // index dedups across the central and node tiers by this full hash (aceteam#6087
 // standardizes on full sha256, replacing memory's 16-hex truncation).
@@ -269,6 +316,22 @@ func chunkText(text string) []string {
 	return chunks
 }
 
+// chunkSections chunks each extracted section on its own so a chunk never
+// straddles a page or heading, carrying the section's page and heading onto
+// every chunk cut from it. The per-file cap applies across all sections.
+func chunkSections(sections []DocSection) []DocSection {
+	var out []DocSection
+	for _, sec := range sections {
+		for _, c := range chunkText(sec.Text) {
+			if len(out) >= maxChunksPerFile {
+				return out
+			}
+			out = append(out, DocSection{Text: c, Page: sec.Page, Section: sec.Section})
+		}
+	}
+	return out
+}
+
 // splitParagraphs splits on blank lines, dropping empty paragraphs.
 func splitParagraphs(text string) []string {
 	raw := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n\n")
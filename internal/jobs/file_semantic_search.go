@@ -2,9 +2,16 @@
 package jobs
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
+	"net/http"
 	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/aceteam-ai/citadel-cli/internal/nexus"
 	"github.com/aceteam-ai/citadel-cli/internal/nodeindex"
@@ -18,6 +25,20 @@ const defaultSearchTopK = 10
 // search response stays small.
 const maxSearchSnippetBytes = 500
 
+// Search modes. vector is the default so a backend merging node hits with
+// central pgvector hits keeps receiving cosine scores; hybrid and lexical are
+// opt-in and return fused / BM25-derived scores instead.
+const (
+	searchModeVector  = "vector"
+	searchModeHybrid  = "hybrid"
+	searchModeLexical = "lexical"
+)
+
+// rerankCandidates is how many first-stage hits are sent to the cross-encoder
+// when reranking. The reranker scores every (query, chunk) pair, so this bounds
+// its cost independently of top_k.
+const rerankCandidates = 50
+
 // FileSemanticSearchHandler handles FILE_SEMANTIC_SEARCH jobs: it embeds the
 // query with the node's TEI service, runs a brute-force cosine KNN over the
 // node-local index, and returns the top chunk hits. This is the node half of
@@ -45,6 +66,15 @@ func NewFileSemanticSearchHandler(workspace, dbPath string) *FileSemanticSearchH
 //   - model: TEI embedding model. Optional; must match the model the index was
 //     built with for scores to be meaningful. Defaults to CITADEL_EMBEDDING_MODEL
 //     or defaultEmbeddingModel.
+//   - mode: "vector" (default, cosine KNN), "hybrid" (vector + FTS5 BM25 fused
+//     by reciprocal rank), or "lexical" (BM25 only; needs no TEI).
+//   - rerank: "true" reorders the first-stage hits with the cross-encoder behind
+//     TEI's /rerank (CITADEL_RERANK_URL, default the TEI URL). If the reranker is
+//     unavailable the first-stage order is kept and "reranked" reports false.
+//   - path_prefix: only files at or under this path.
+//   - extensions: comma-separated extensions to keep (e.g. "pdf,md").
+//   - modified_after / modified_before: mtime bounds, as RFC 3339, YYYY-MM-DD,
+//     or Unix seconds.
 func (h *FileSemanticSearchHandler) Execute(ctx JobContext, job *nexus.Job) ([]byte, error) {
 	query, ok := job.Payload["query"]
 	if !ok || query == "" {
@@ -58,6 +88,19 @@ func (h *FileSemanticSearchHandler) Execute(ctx JobContext, job *nexus.Job) ([]b
 			model = env
 		}
 	}
+	mode := job.Payload["mode"]
+	switch mode {
+	case "":
+		mode = searchModeVector
+	case searchModeVector, searchModeHybrid, searchModeLexical:
+	default:
+		return nil, fmt.Errorf("unknown search mode %q (want vector, hybrid, or lexical)", mode)
+	}
+	rerank := job.Payload["rerank"] == "true"
+	filter, err := parseSearchFilter(job.Payload)
+	if err != nil {
+		return nil, err
+	}
 
 	store, err := nodeindex.Open(resolveIndexDBPath(h.DBPath, h.WorkspaceDir))
 	if err != nil {
@@ -65,32 +108,162 @@ func (h *FileSemanticSearchHandler) Execute(ctx JobContext, job *nexus.Job) ([]b
 	}
 	defer store.Close()
 
-	ctx.Log("info", "     - [Job %s] FILE_SEMANTIC_SEARCH query=%q top_k=%d model=%q", job.ID, truncateLine(query, 80), topK, model)
+	ctx.Log("info", "     - [Job %s] FILE_SEMANTIC_SEARCH query=%q top_k=%d model=%q mode=%s rerank=%t",
+		job.ID, truncateLine(query, 80), topK, model, mode, rerank)
 
-	vecs, err := embedTexts(model, []string{query})
-	if err != nil {
-		return nil, fmt.Errorf("embed query: %w", err)
+	// Reranking reorders a wider first stage, then trims to top_k.
+	fetchK := topK
+	if rerank && fetchK < rerankCandidates {
+		fetchK = rerankCandidates
 	}
-	if len(vecs) == 0 || len(vecs[0]) == 0 {
-		return nil, fmt.Errorf("embedding service returned an empty query vector")
+
+	var vector []float32
+	if mode != searchModeLexical {
+		vecs, err := embedTexts(model, []string{query})
+		if err != nil {
+			return nil, fmt.Errorf("embed query: %w", err)
+		}
+		if len(vecs) == 0 || len(vecs[0]) == 0 {
+			return nil, fmt.Errorf("embedding service returned an empty query vector")
+		}
+		vector = vecs[0]
 	}
 
-	hits, err := store.Search(vecs[0], topK)
+	var hits []nodeindex.SearchHit
+	switch mode {
+	case searchModeHybrid:
+		hits, err = store.HybridSearch(nodeindex.HybridQuery{Vector: vector, Text: query, TopK: fetchK, Filter: filter})
+	case searchModeLexical:
+		hits, err = store.LexicalSearch(query, fetchK, filter)
+	default:
+		hits, err = store.SearchFiltered(vector, fetchK, filter)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("index search: %w", err)
 	}
+
+	reranked := false
+	if rerank && len(hits) > 0 {
+		if err := rerankHits(query, hits); err != nil {
+			ctx.Log("warn", "     - [Job %s] rerank unavailable, keeping %s order: %v", job.ID, mode, err)
+		} else {
+			reranked = true
+		}
+	}
+	if len(hits) > topK {
+		hits = hits[:topK]
+	}
 	for i := range hits {
 		hits[i].Text = truncateLine(hits[i].Text, maxSearchSnippetBytes)
 	}
 
 	out := map[string]any{
-		"hits":  hits,
-		"count": len(hits),
-		"model": model,
+		"hits":     hits,
+		"count":    len(hits),
+		"model":    model,
+		"mode":     mode,
+		"reranked": reranked,
 	}
 	return json.Marshal(out)
 }
 
+// parseSearchFilter reads the metadata filter fields of a FILE_SEMANTIC_SEARCH
+// payload.
+func parseSearchFilter(payload map[string]string) (nodeindex.SearchFilter, error) {
+	f := nodeindex.SearchFilter{PathPrefix: payload["path_prefix"]}
+	for _, ext := range strings.Split(payload["extensions"], ",") {
+		if ext = strings.TrimSpace(ext); ext != "" {
+			f.Extensions = append(f.Extensions, ext)
+		}
+	}
+	var err error
+	if f.ModifiedAfter, err = parseSearchTime(payload["modified_after"]); err != nil {
+		return f, fmt.Errorf("invalid 'modified_after': %w", err)
+	}
+	if f.ModifiedBefore, err = parseSearchTime(payload["modified_before"]); err != nil {
+		return f, fmt.Errorf("invalid 'modified_before': %w", err)
+	}
+	return f, nil
+}
+
+// parseSearchTime accepts RFC 3339, a bare YYYY-MM-DD date (UTC midnight), or
+// Unix seconds, returning Unix seconds. Empty yields 0 (unbounded).
+func parseSearchTime(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return n, nil
+	}
+	for _, layout := range []string{time.RFC3339, time.DateOnly} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.Unix(), nil
+		}
+	}
+	return 0, fmt.Errorf("%q is not RFC 3339, YYYY-MM-DD, or Unix seconds", s)
+}
+
+// rerankBaseURL returns the TEI instance serving the cross-encoder. A reranker
+// is a different model from the embedder, so nodes that run both usually serve
+// it from a second TEI container; CITADEL_RERANK_URL points there.
+func rerankBaseURL() string {
+	if v := os.Getenv("CITADEL_RERANK_URL"); v != "" {
+		return v
+	}
+	return teiBaseURL()
+}
+
+// rerankClient bounds one rerank call; scoring 50 pairs is sub-second on a
+// warm reranker, so a long stall means it is not serving.
+var rerankClient = &http.Client{Timeout: 30 * time.Second}
+
+// rerankHits scores every hit against query with TEI's /rerank and reorders
+// hits in place, best first, replacing Score with the reranker's relevance.
+func rerankHits(query string, hits []nodeindex.SearchHit) error {
+	texts := make([]string, len(hits))
+	for i, h := range hits {
+		texts[i] = h.Text
+	}
+	body, err := json.Marshal(map[string]any{"query": query, "texts": texts, "truncate": true})
+	if err != nil {
+		return err
+	}
+	resp, err := rerankClient.Post(rerankBaseURL()+"/rerank", "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("reranker unreachable: %w", err)
+	}
+	defer resp.Body.Close()
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("reranker returned %s: %s", resp.Status, truncateLine(string(raw), 200))
+	}
+	var ranked []struct {
+		Index int     `json:"index"`
+		Score float64 `json:"score"`
+	}
+	if err := json.Unmarshal(raw, &ranked); err != nil {
+		return fmt.Errorf("decode rerank response: %w", err)
+	}
+	if len(ranked) != len(hits) {
+		return fmt.Errorf("reranker scored %d of %d hits", len(ranked), len(hits))
+	}
+	reordered := make([]nodeindex.SearchHit, 0, len(hits))
+	seen := make(map[int]bool, len(hits))
+	for _, r := range ranked {
+		if r.Index < 0 || r.Index >= len(hits) || seen[r.Index] {
+			return fmt.Errorf("reranker returned an invalid or repeated index %d", r.Index)
+		}
+		seen[r.Index] = true
+		h := hits[r.Index]
+		h.Score = r.Score
+		reordered = append(reordered, h)
+	}
+	sort.SliceStable(reordered, func(i, j int) bool { return reordered[i].Score > reordered[j].Score })
+	copy(hits, reordered)
+	return nil
+}
+
 // embedTexts embeds inputs via the node's TEI service, waiting for readiness,
 // and returns the vectors as float32 (the index's storage type). It reuses the
 // same TEI call path as the EmbeddingHandler.
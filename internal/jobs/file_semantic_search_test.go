@@ -0,0 +1,171 @@
+package jobs
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aceteam-ai/citadel-cli/internal/nexus"
+)
+
+type searchOutput struct {
+	Hits []struct {
+		Path  string  `json:"path"`
+		Score float64 `json:"score"`
+	} `json:"hits"`
+	Mode     string `json:"mode"`
+	Reranked bool   `json:"reranked"`
+}
+
+// indexSearchFixture indexes a small workspace with the fake TEI and returns a
+// search runner over it.
+func indexSearchFixture(t *testing.T) (string, func(payload map[string]string) (searchOutput, error)) {
+	t.Helper()
+	tei := fakeTEI(t)
+	t.Setenv("CITADEL_TEI_URL", tei.URL)
+	t.Setenv("CITADEL_RERANK_URL", "")
+
+	ws := t.TempDir()
+	files := map[string]string{
+		"cats.md":         "The cat sat on the mat. A kitten is a small feline.",
+		"db.md":           "A database stores rows. SQL is a query language.",
+		"logs/errors.log": "upstream SQL failed with ERR_CONN_RESET after 3 retries",
+		"notes.md":        "Notes from Tuesday.",
+	}
+	for name, body := range files {
+		p := filepath.Join(ws, name)
+		os.MkdirAll(filepath.Dir(p), 0o755)
+		os.WriteFile(p, []byte(body), 0o644)
+	}
+	old := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	os.Chtimes(filepath.Join(ws, "db.md"), old, old)
+
+	dbPath := filepath.Join(t.TempDir(), "index.db")
+	if _, err := NewFileIndexHandler(ws, dbPath).Execute(JobContext{}, &nexus.Job{ID: "i", Payload: map[string]string{"path": ws}}); err != nil {
+		t.Fatal(err)
+	}
+	search := NewFileSemanticSearchHandler(ws, dbPath)
+	return ws, func(payload map[string]string) (searchOutput, error) {
+		var res searchOutput
+		out, err := search.Execute(JobContext{LogFn: func(string, string) {}}, &nexus.Job{ID: "s", Payload: payload})
+		if err == nil {
+			err = json.Unmarshal(out, &res)
+		}
+		return res, err
+	}
+}
+
+func TestFileSemanticSearchHybridFindsIdentifier(t *testing.T) {
+	_, search := indexSearchFixture(t)
+
+	res, err := search(map[string]string{"query": "ERR_CONN_RESET", "top_k": "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The fake embedder maps the identifier and notes.md to the same vector,
+	// so pure vector search ranks the wrong file first.
+	if res.Mode != "vector" || len(res.Hits) != 1 || !strings.HasSuffix(res.Hits[0].Path, "notes.md") {
+		t.Fatalf("vector default = %+v, want notes.md (the embedder's nearest)", res)
+	}
+	for _, mode := range []string{"hybrid", "lexical"} {
+		res, err = search(map[string]string{"query": "ERR_CONN_RESET", "top_k": "1", "mode": mode})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(res.Hits) != 1 || !strings.HasSuffix(res.Hits[0].Path, "errors.log") {
+			t.Errorf("%s hits = %+v, want errors.log", mode, res.Hits)
+		}
+	}
+	if _, err := search(map[string]string{"query": "x", "mode": "fuzzy"}); err == nil {
+		t.Error("unknown mode accepted")
+	}
+}
+
+func TestFileSemanticSearchFilters(t *testing.T) {
+	ws, search := indexSearchFixture(t)
+	tests := []struct {
+		payload map[string]string
+		want    string
+	}{
+		{map[string]string{"path_prefix": filepath.Join(ws, "logs")}, "errors.log"},
+		{map[string]string{"extensions": "log, .LOG"}, "errors.log"},
+		{map[string]string{"modified_before": "2021-01-01"}, "db.md"},
+	}
+	for _, tt := range tests {
+		tt.payload["query"] = "cat database"
+		tt.payload["mode"] = "hybrid"
+		res, err := search(tt.payload)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(res.Hits) != 1 || filepath.Base(res.Hits[0].Path) != tt.want {
+			t.Errorf("filter %v: hits = %+v, want only %s", tt.payload, res.Hits, tt.want)
+		}
+	}
+	if _, err := search(map[string]string{"query": "x", "modified_after": "last week"}); err == nil {
+		t.Error("unparseable modified_after accepted")
+	}
+}
+
+func TestFileSemanticSearchRerank(t *testing.T) {
+	_, search := indexSearchFixture(t)
+	var got struct {
+		Query string   `json:"query"`
+		Texts []string `json:"texts"`
+	}
+	reranker := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/rerank" {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		// Put the log line first regardless of first-stage order.
+		var out []map[string]any
+		for i, text := range got.Texts {
+			score := 0.1
+			if strings.Contains(text, "retries") {
+				score = 0.9
+			}
+			out = append(out, map[string]any{"index": i, "score": score})
+		}
+		json.NewEncoder(w).Encode(out)
+	}))
+	defer reranker.Close()
+	t.Setenv("CITADEL_RERANK_URL", reranker.URL)
+
+	res, err := search(map[string]string{"query": "why did it fail", "top_k": "2", "rerank": "true"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.Reranked || len(res.Hits) != 2 || !strings.HasSuffix(res.Hits[0].Path, "errors.log") || res.Hits[0].Score != 0.9 {
+		t.Fatalf("reranked = %+v, want errors.log first with the reranker's score", res)
+	}
+	if got.Query != "why did it fail" || len(got.Texts) != 4 {
+		t.Errorf("reranker saw query %q and %d texts, want every candidate beyond top_k", got.Query, len(got.Texts))
+	}
+
+	// An unavailable reranker degrades to first-stage order, not an error.
+	t.Setenv("CITADEL_RERANK_URL", "http://127.0.0.1:1")
+	res, err = search(map[string]string{"query": "cat", "rerank": "true"})
+	if err != nil || res.Reranked || len(res.Hits) == 0 {
+		t.Fatalf("unavailable reranker: %+v, %v; want first-stage hits with reranked=false", res, err)
+	}
+}
+
+func TestParseSearchTime(t *testing.T) {
+	for in, want := range map[string]int64{
+		"":                     0,
+		"1700000000":           1700000000,
+		"2024-03-01":           1709251200,
+		"2024-03-01T00:00:00Z": 1709251200,
+	} {
+		if got, err := parseSearchTime(in); err != nil || got != want {
+			t.Errorf("parseSearchTime(%q) = %d, %v; want %d", in, got, err, want)
+		}
+	}
+}
@@ -4,6 +4,9 @@ package jobs
 import (
 	"context"
 	"fmt"
+	"os/exec"
+	"syscall"
+	"time"
 
 	"github.com/aceteam-ai/citadel-cli/internal/nexus"
 )
@@ -45,3 +48,25 @@ func (c *JobContext) Log(level, format string, args ...any) {
 type JobHandler interface {
 	Execute(ctx JobContext, job *nexus.Job) (output []byte, err error)
 }
+
+// ChildStopGrace is how long a cancelled job's child process gets to exit
+// after SIGTERM before it is killed. The worker runner waits somewhat longer
+// than this for a cancelled handler to return, so a handler that stops its
+// child this way finishes inside the runner's grace period.
+const ChildStopGrace = 10 * time.Second
+
+// stopGracefully makes an exec.CommandContext command ask its process to stop
+// (SIGTERM) when the job context is done, instead of killing it outright, so
+// a build tool or `docker run` can tear down what it started. The process is
+// killed if it is still running ChildStopGrace later; the same delay bounds
+// the wait on stdio pipes inherited by a backgrounded grandchild.
+func stopGracefully(cmd *exec.Cmd) {
+	cmd.Cancel = func() error {
+		if err := cmd.Process.Signal(syscall.SIGTERM); err != nil {
+			// No SIGTERM on this platform (Windows) or the process is gone.
+			return cmd.Process.Kill()
+		}
+		return nil
+	}
+	cmd.WaitDelay = ChildStopGrace
+}
@@ -82,6 +82,55 @@ type LLMInferencePayload struct {
 
 	// Stop sequences to end generation
 	Stop []string `json:"stop,omitempty"`
+
+	// Tools are the OpenAI-shaped function tools the model may call, and
+	// ToolChoice ("auto", "none", "required" or a named function) steers it.
+	// Both are forwarded verbatim to OpenAI-compatible engines. Chat only.
+	Tools      json.RawMessage `json:"tools,omitempty"`
+	ToolChoice json.RawMessage `json:"tool_choice,omitempty"`
+
+	// ResponseFormat constrains the output to JSON, optionally to a schema.
+	ResponseFormat *ResponseFormat `json:"response_format,omitempty"`
+
+	// Logprobs asks for per-token log probabilities, with TopLogprobs
+	// alternatives per token.
+	Logprobs    bool `json:"logprobs,omitempty"`
+	TopLogprobs int  `json:"top_logprobs,omitempty"`
+}
+
+// Response format types (OpenAI response_format.type).
+const (
+	ResponseFormatText       = "text"
+	ResponseFormatJSONObject = "json_object"
+	ResponseFormatJSONSchema = "json_schema"
+)
+
+// ResponseFormat is the OpenAI response_format: "text", "json_object" (any
+// JSON object) or "json_schema" (JSON matching JSONSchema.Schema).
+type ResponseFormat struct {
+	Type       string            `json:"type"`
+	JSONSchema *JSONSchemaFormat `json:"json_schema,omitempty"`
+}
+
+// JSONSchemaFormat is the json_schema member of a response_format.
+type JSONSchemaFormat struct {
+	Name   string          `json:"name,omitempty"`
+	Schema json.RawMessage `json:"schema"`
+	Strict bool            `json:"strict,omitempty"`
+}
+
+// WantsJSON reports whether the format constrains the output to JSON.
+func (f *ResponseFormat) WantsJSON() bool {
+	return f != nil && (f.Type == ResponseFormatJSONObject || f.Type == ResponseFormatJSONSchema)
+}
+
+// Schema returns the JSON schema the output must match, or nil when there is
+// none (no format, text, or json_object).
+func (f *ResponseFormat) Schema() json.RawMessage {
+	if f == nil || f.Type != ResponseFormatJSONSchema || f.JSONSchema == nil {
+		return nil
+	}
+	return f.JSONSchema.Schema
 }
 
 // ChatMessage represents a message in chat-style APIs.
@@ -94,8 +143,15 @@ type LLMInferencePayload struct {
 // shape verbatim to an OpenAI-compatible engine without lossy re-encoding; use
 // Text() when a plain-text view is required.
 type ChatMessage struct {
-	Role    string          `json:"role"` // "system", "user", "assistant"
+	Role    string          `json:"role"` // "system", "user", "assistant", "tool"
 	Content json.RawMessage `json:"content,omitempty"`
+
+	// ToolCalls are the calls an earlier assistant turn made (OpenAI shape,
+	// forwarded verbatim); ToolCallID ties a "tool" turn's result to one of
+	// them, and Name is the function that produced it.
+	ToolCalls  json.RawMessage `json:"tool_calls,omitempty"`
+	ToolCallID string          `json:"tool_call_id,omitempty"`
+	Name       string          `json:"name,omitempty"`
 }
 
 // Text returns the message content as plain text. Content may be a JSON string
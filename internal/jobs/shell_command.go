@@ -8,7 +8,6 @@ import (
 	"os/exec"
 	"path/filepath"
 	"strings"
-	"time"
 
 	"github.com/aceteam-ai/citadel-cli/internal/nexus"
 )
@@ -325,11 +324,12 @@ func (h *ShellCommandHandler) Execute(ctx JobContext, job *nexus.Job) ([]byte, e
 
 	// Bind the command to the job context so a per-job deadline / cancellation
 	// terminates the child process instead of leaking it (aceteam#6000).
-	// CommandContext sends Kill when ctx is done; WaitDelay bounds how long
-	// CombinedOutput waits on inherited stdio pipes afterwards, so a backgrounded
-	// grandchild holding the pipe can't keep the handler blocked indefinitely.
+	// stopGracefully sends SIGTERM when ctx is done and kills after a grace
+	// period; the same WaitDelay bounds how long CombinedOutput waits on
+	// inherited stdio pipes afterwards, so a backgrounded grandchild holding the
+	// pipe can't keep the handler blocked indefinitely.
 	cmd := exec.CommandContext(ctx.Context(), "/bin/sh", "-c", cmdString)
-	cmd.WaitDelay = 10 * time.Second
+	stopGracefully(cmd)
 	cmd.Env = scrubEnv(os.Environ(), parseJobEnv(job))
 	if h.WorkspaceDir != "" {
 		cmd.Dir = h.WorkspaceDir
@@ -197,6 +197,19 @@ func (h *SynthesizeSpeechHandler) Execute(ctx JobContext, job *nexus.Job) ([]byt
 	return json.Marshal(result)
 }
 
+// WaitReady blocks until the kokoro sidecar has loaded its model, failing fast
+// if nothing is listening. The gateway's /v1/audio/speech route waits here
+// before proxying to BaseURL.
+func (h *SynthesizeSpeechHandler) WaitReady() error {
+	return h.waitForReady()
+}
+
+// BaseURL returns the kokoro sidecar base URL. The sidecar serves OpenAI's
+// /v1/audio/speech itself, so the gateway proxies to it directly.
+func (h *SynthesizeSpeechHandler) BaseURL() string {
+	return h.serviceURL()
+}
+
 // synthesizeReceiptFromHeaders extracts the per-item metering receipt the kokoro
 // service returns in its X-TTS-* response headers. Parsing is best-effort: a
 // missing or malformed header yields the field's zero value rather than failing
@@ -199,26 +199,13 @@ func (h *TranscribeAudioHandler) Execute(ctx JobContext, job *nexus.Job) ([]byte
 		return nil, fmt.Errorf("path validation failed: %w", err)
 	}
 
-	// The whisper sidecar mounts the workspace at /workspace, so send the path
-	// RELATIVE to the workspace root. The service joins it under its own mount;
-	// it never sees (or can escape to) the host filesystem.
-	//
-	// ValidatePath resolves relative inputs against the SYMLINK-RESOLVED
-	// workspace root, so compute the relative path against the resolved root
-	// too. Using the raw WorkspaceDir here would emit spurious "../" prefixes
-	// when the workspace contains a symlinked component (e.g. a symlinked
-	// tmpdir, or /var -> /private/var on macOS), which the sidecar would reject.
-	resolvedWorkspace, err := filepath.EvalSymlinks(h.WorkspaceDir)
+	rel, err := h.workspaceRelative(validated)
 	if err != nil {
-		return nil, fmt.Errorf("cannot resolve workspace %q: %w", h.WorkspaceDir, err)
-	}
-	rel, err := filepath.Rel(resolvedWorkspace, validated)
-	if err != nil {
-		return nil, fmt.Errorf("cannot compute workspace-relative path: %w", err)
+		return nil, err
 	}
 
 	ctx.Log("info", "     - [Job %s] Waiting for transcription service to become ready...", job.ID)
-	if err := h.waitForReady(); err != nil {
+	if err := h.waitForReady(ctx.Context()); err != nil {
 		return nil, err
 	}
 	ctx.Log("info", "     - [Job %s] TRANSCRIBE_AUDIO %s", job.ID, rel)
@@ -233,6 +220,35 @@ func (h *TranscribeAudioHandler) Execute(ctx JobContext, job *nexus.Job) ([]byte
 		requestPayload["diarize"] = true
 	}
 
+	return h.post(ctx.Context(), validated, requestPayload)
+}
+
+// workspaceRelative returns validated relative to the workspace root. The
+// whisper sidecar mounts the workspace at /workspace, so requests carry the
+// path RELATIVE to the workspace root; the service joins it under its own
+// mount and never sees (or can escape to) the host filesystem.
+//
+// ValidatePath resolves relative inputs against the SYMLINK-RESOLVED workspace
+// root, so compute the relative path against the resolved root too. Using the
+// raw WorkspaceDir here would emit spurious "../" prefixes when the workspace
+// contains a symlinked component (e.g. a symlinked tmpdir, or /var ->
+// /private/var on macOS), which the sidecar would reject.
+func (h *TranscribeAudioHandler) workspaceRelative(validated string) (string, error) {
+	resolvedWorkspace, err := filepath.EvalSymlinks(h.WorkspaceDir)
+	if err != nil {
+		return "", fmt.Errorf("cannot resolve workspace %q: %w", h.WorkspaceDir, err)
+	}
+	rel, err := filepath.Rel(resolvedWorkspace, validated)
+	if err != nil {
+		return "", fmt.Errorf("cannot compute workspace-relative path: %w", err)
+	}
+	return rel, nil
+}
+
+// post sends a transcribe request for the audio at validated and returns the
+// sidecar's JSON result. Cancelling ctx (a cancelled job) abandons the request,
+// which closes the connection the sidecar is transcribing for.
+func (h *TranscribeAudioHandler) post(ctx context.Context, validated string, requestPayload map[string]any) ([]byte, error) {
 	reqBody, err := json.Marshal(requestPayload)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
@@ -241,9 +257,9 @@ func (h *TranscribeAudioHandler) Execute(ctx JobContext, job *nexus.Job) ([]byte
 	// Size the whole-request budget from the audio's byte length so a long
 	// meeting's full-file transcription is not cut off mid-flight. The context
 	// governs the entire request including the body read below, so cancel only
-	// after Execute is done with the response.
+	// once the response has been read.
 	reqTimeout := h.requestTimeout(validated)
-	reqCtx, cancel := context.WithTimeout(context.Background(), reqTimeout)
+	reqCtx, cancel := context.WithTimeout(ctx, reqTimeout)
 	defer cancel()
 
 	req, err := http.NewRequestWithContext(reqCtx, http.MethodPost, h.serviceURL()+"/transcribe", bytes.NewBuffer(reqBody))
@@ -266,13 +282,79 @@ func (h *TranscribeAudioHandler) Execute(ctx JobContext, job *nexus.Job) ([]byte
 	return bodyBytes, nil
 }
 
-func (h *TranscribeAudioHandler) waitForReady() error {
+// gatewayUploadDir is the workspace subdirectory gateway uploads are staged in
+// while the sidecar transcribes them.
+const gatewayUploadDir = ".gateway-audio"
+
+// WaitReady blocks until the whisper sidecar reports healthy or ctx ends,
+// failing fast if nothing is listening. Callers of TranscribeUpload wait here
+// first so an absent sidecar is reported before the upload is staged.
+func (h *TranscribeAudioHandler) WaitReady(ctx context.Context) error {
+	return h.waitForReady(ctx)
+}
+
+// TranscribeUpload transcribes audio that did not come from the workspace (an
+// OpenAI /v1/audio/transcriptions upload on the gateway). The sidecar only
+// reads the workspace mount, so the audio is staged under gatewayUploadDir for
+// the duration of the request and removed afterwards. filename only supplies
+// the extension, which the decoder uses as a format hint. The result is the
+// sidecar's JSON, as for Execute. The request to the sidecar is abandoned when
+// ctx ends, so a client that hangs up does not keep whisper busy.
+func (h *TranscribeAudioHandler) TranscribeUpload(ctx context.Context, audio io.Reader, filename, language string) ([]byte, error) {
+	dir := filepath.Join(h.WorkspaceDir, gatewayUploadDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create upload directory: %w", err)
+	}
+	f, err := os.CreateTemp(dir, "upload-*"+uploadExt(filename))
+	if err != nil {
+		return nil, fmt.Errorf("failed to stage upload: %w", err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := io.Copy(f, audio); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to stage upload: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return nil, fmt.Errorf("failed to stage upload: %w", err)
+	}
+
+	validated, err := ValidatePath(h.WorkspaceDir, f.Name())
+	if err != nil {
+		return nil, fmt.Errorf("path validation failed: %w", err)
+	}
+	rel, err := h.workspaceRelative(validated)
+	if err != nil {
+		return nil, err
+	}
+	requestPayload := map[string]any{"audio_path": rel}
+	if language != "" {
+		requestPayload["language"] = language
+	}
+	return h.post(ctx, validated, requestPayload)
+}
+
+// uploadExt returns filename's extension if it is a short alphanumeric one,
+// else "" (a client-supplied name never reaches the filesystem otherwise).
+func uploadExt(filename string) string {
+	ext := strings.ToLower(filepath.Ext(filename))
+	if len(ext) < 2 || len(ext) > 8 {
+		return ""
+	}
+	for _, r := range ext[1:] {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') {
+			return ""
+		}
+	}
+	return ext
+}
+
+func (h *TranscribeAudioHandler) waitForReady(ctx context.Context) error {
 	healthURL := h.serviceURL() + "/health"
 	pollInterval := 1 * time.Second
 	startTime := time.Now()
 
 	for {
-		resp, err := h.healthCheck(healthURL)
+		resp, err := h.healthCheck(ctx, healthURL)
 		if err == nil {
 			ready := resp.StatusCode == http.StatusOK
 			resp.Body.Close()
@@ -291,7 +373,11 @@ func (h *TranscribeAudioHandler) waitForReady() error {
 		if time.Since(startTime) >= transcribeReadyTimeout {
 			break
 		}
-		time.Sleep(pollInterval)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(pollInterval):
+		}
 	}
 	return fmt.Errorf("transcription service did not become ready within %v", transcribeReadyTimeout)
 }
@@ -300,8 +386,8 @@ func (h *TranscribeAudioHandler) waitForReady() error {
 // so one poll cannot hang now that the shared client carries no fixed timeout.
 // A dead port still returns connection-refused immediately (before the
 // deadline), preserving the fast-fail path in waitForReady.
-func (h *TranscribeAudioHandler) healthCheck(healthURL string) (*http.Response, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), transcribeHealthTimeout)
+func (h *TranscribeAudioHandler) healthCheck(ctx context.Context, healthURL string) (*http.Response, error) {
+	ctx, cancel := context.WithTimeout(ctx, transcribeHealthTimeout)
 	defer cancel()
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, healthURL, nil)
 	if err != nil {
@@ -1,6 +1,7 @@
 package jobs
 
 import (
+	"context"
 	"encoding/json"
 	"io"
 	"net"
@@ -8,6 +9,7 @@ import (
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -266,7 +268,7 @@ func TestTranscribeAudio_WaitForReady_UnreachableFailsFast(t *testing.T) {
 	h.ServiceURL = "http://" + addr
 
 	start := time.Now()
-	err = h.waitForReady()
+	err = h.waitForReady(context.Background())
 	elapsed := time.Since(start)
 
 	if err == nil {
@@ -317,7 +319,7 @@ func TestTranscribeAudio_WaitForReady_PatientWhileLoading(t *testing.T) {
 	}()
 
 	start := time.Now()
-	if err := h.waitForReady(); err != nil {
+	if err := h.waitForReady(context.Background()); err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 	elapsed := time.Since(start)
@@ -389,3 +391,80 @@ func TestTranscribeAudio_SymlinkedWorkspace(t *testing.T) {
 		t.Errorf("forwarded audio_path = %q, want %q (no leading ../)", gotPath, audioRel)
 	}
 }
+
+// TestTranscribeAudio_TranscribeUpload verifies a gateway upload is staged in
+// the workspace, sent to the sidecar by relative path, and removed afterwards.
+func TestTranscribeAudio_TranscribeUpload(t *testing.T) {
+	dir := t.TempDir()
+	var gotPath, gotLang string
+	var staged []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		gotPath, _ = req["audio_path"].(string)
+		gotLang, _ = req["language"].(string)
+		staged, _ = os.ReadFile(filepath.Join(dir, gotPath))
+		_, _ = w.Write([]byte(`{"text":"hi","segments":[]}`))
+	}))
+	defer srv.Close()
+
+	h := NewTranscribeAudioHandler(dir)
+	h.ServiceURL = srv.URL
+	out, err := h.TranscribeUpload(context.Background(), strings.NewReader("fakeaudio"), "../../clip.MP3", "de")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(out) != `{"text":"hi","segments":[]}` {
+		t.Errorf("result = %s, want the sidecar JSON verbatim", out)
+	}
+	if filepath.Dir(gotPath) != gatewayUploadDir || filepath.Ext(gotPath) != ".mp3" {
+		t.Errorf("audio_path = %q, want %s/upload-*.mp3", gotPath, gatewayUploadDir)
+	}
+	if string(staged) != "fakeaudio" {
+		t.Errorf("staged file = %q, want the uploaded bytes", staged)
+	}
+	if gotLang != "de" {
+		t.Errorf("language = %q, want de", gotLang)
+	}
+	if entries, _ := os.ReadDir(filepath.Join(dir, gatewayUploadDir)); len(entries) != 0 {
+		t.Errorf("staged upload not removed: %v", entries)
+	}
+}
+
+// TestTranscribeAudio_TranscribeUploadStopsWithCaller verifies the sidecar
+// request is abandoned when the gateway client goes away.
+func TestTranscribeAudio_TranscribeUploadStopsWithCaller(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		<-r.Context().Done() // a whisper busy with a long file
+	}))
+	defer srv.Close()
+
+	h := NewTranscribeAudioHandler(t.TempDir())
+	h.ServiceURL = srv.URL
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		_, err := h.TranscribeUpload(ctx, strings.NewReader("fakeaudio"), "clip.wav", "")
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("TranscribeUpload succeeded after the caller went away")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("TranscribeUpload kept waiting on the sidecar after the caller went away")
+	}
+}
+
+func TestUploadExt(t *testing.T) {
+	for in, want := range map[string]string{
+		"a.wav": ".wav", "x.M4A": ".m4a", "noext": "", "evil.w/v": "", "a.b c": "", "a.toolongext": "",
+	} {
+		if got := uploadExt(in); got != want {
+			t.Errorf("uploadExt(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -219,7 +219,7 @@ func (a *accelerator) search(db *sql.DB, query []float32, qNorm float64, topK in
 		if !ok {
 			continue // row vanished between search and fetch; skip
 		}
-		hits = append(hits, SearchHit{Path: m.path, ChunkIndex: m.chunkIndex, Text: m.text, Score: sh.score})
+		hits = append(hits, SearchHit{Path: m.path, ChunkIndex: m.chunkIndex, Text: m.text, Score: sh.score, Page: m.page, Section: m.section})
 	}
 	sort.SliceStable(hits, func(i, j int) bool { return hits[i].Score > hits[j].Score })
 	if len(hits) > topK {
@@ -232,9 +232,11 @@ type chunkMeta struct {
 	path       string
 	chunkIndex int
 	text       string
+	page       int
+	section    string
 }
 
-// fetchChunkMeta loads (path, chunk_index, text) for the given chunk ids in one
+// fetchChunkMeta loads (path, chunk_index, text, page, section) for the given chunk ids in one
 // query, returning a map keyed by id.
 func fetchChunkMeta(db *sql.DB, ids []int64) (map[int64]chunkMeta, error) {
 	if len(ids) == 0 {
@@ -246,7 +248,7 @@ func fetchChunkMeta(db *sql.DB, ids []int64) (map[int64]chunkMeta, error) {
 		placeholders[i] = "?"
 		args[i] = id
 	}
-	q := "SELECT id, path, chunk_index, text FROM chunks WHERE id IN (" + strings.Join(placeholders, ",") + ")"
+	q := "SELECT id, path, chunk_index, text, page, section FROM chunks WHERE id IN (" + strings.Join(placeholders, ",") + ")"
 	rows, err := db.Query(q, args...)
 	if err != nil {
 		return nil, fmt.Errorf("fetch chunk metadata: %w", err)
@@ -258,7 +260,7 @@ func fetchChunkMeta(db *sql.DB, ids []int64) (map[int64]chunkMeta, error) {
 			id int64
 			m  chunkMeta
 		)
-		if err := rows.Scan(&id, &m.path, &m.chunkIndex, &m.text); err != nil {
+		if err := rows.Scan(&id, &m.path, &m.chunkIndex, &m.text, &m.page, &m.section); err != nil {
 			return nil, fmt.Errorf("scan chunk metadata: %w", err)
 		}
 		out[id] = m
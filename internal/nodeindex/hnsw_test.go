@@ -80,7 +80,7 @@ func TestHNSWParityWithBruteForce(t *testing.T) {
 		}
 		vecs[i] = v
 		path := fmt.Sprintf("/root/doc-%02d.md", i)
-		if err := s.UpsertFile(path, fmt.Sprintf("h%d", i), 1, 1, "m", dim, []Chunk{
+		if err := s.UpsertFile(path, fmt.Sprintf("h%d", i), "", 1, 1, "m", dim, []Chunk{
 			{Index: 0, Text: fmt.Sprintf("chunk %d", i), Embedding: vecs[i]},
 		}); err != nil {
 			t.Fatalf("UpsertFile %d: %v", i, err)
@@ -104,7 +104,7 @@ func TestHNSWParityWithBruteForce(t *testing.T) {
 		if !served {
 			t.Fatalf("query %d: accelerator did not serve a non-empty index", q)
 		}
-		bruteHits, err := s.searchBrute(query, qNorm, topK)
+		bruteHits, err := s.searchBrute(query, qNorm, topK, SearchFilter{})
 		if err != nil {
 			t.Fatalf("brute search: %v", err)
 		}
@@ -112,7 +112,7 @@ func TestHNSWParityWithBruteForce(t *testing.T) {
 		// accelerator result must fall inside it (precision), and scores must match
 		// exactly. Recall (finding ALL of the true top-k) is best-effort for an
 		// approximate index and deliberately NOT asserted per-query.
-		refNear, err := s.searchBrute(query, qNorm, 3*topK)
+		refNear, err := s.searchBrute(query, qNorm, 3*topK, SearchFilter{})
 		if err != nil {
 			t.Fatalf("brute ref search: %v", err)
 		}
@@ -163,7 +163,7 @@ func TestHNSWTopMatchesBrute(t *testing.T) {
 	vecs := make([][]float32, n)
 	for i := 0; i < n; i++ {
 		vecs[i] = randVec(r, dim)
-		_ = s.UpsertFile(fmt.Sprintf("/root/f%02d.md", i), fmt.Sprintf("h%d", i), 1, 1, "m", dim,
+		_ = s.UpsertFile(fmt.Sprintf("/root/f%02d.md", i), fmt.Sprintf("h%d", i), "", 1, 1, "m", dim,
 			[]Chunk{{Index: 0, Text: "t", Embedding: vecs[i]}})
 	}
 	// Query is a near-copy of doc 7, so doc 7 is unambiguously the nearest.
@@ -174,7 +174,7 @@ func TestHNSWTopMatchesBrute(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Search: %v", err)
 	}
-	want, err := s.searchBrute(query, norm(query), 5)
+	want, err := s.searchBrute(query, norm(query), 5, SearchFilter{})
 	if err != nil {
 		t.Fatalf("searchBrute: %v", err)
 	}
@@ -192,7 +192,7 @@ func TestHNSWTopMatchesBrute(t *testing.T) {
 func TestHNSWFingerprintRebuild(t *testing.T) {
 	s := openTemp(t)
 	seedAccel(s, 2)
-	_ = s.UpsertFile("/root/a.md", "h1", 1, 1, "m", 3, []Chunk{{Index: 0, Text: "a", Embedding: []float32{1, 0, 0}}})
+	_ = s.UpsertFile("/root/a.md", "h1", "", 1, 1, "m", 3, []Chunk{{Index: 0, Text: "a", Embedding: []float32{1, 0, 0}}})
 
 	// Prime the accelerator (builds the graph with just a.md).
 	if _, err := s.Search([]float32{1, 0, 0}, 5); err != nil {
@@ -200,7 +200,7 @@ func TestHNSWFingerprintRebuild(t *testing.T) {
 	}
 
 	// Insert a second file; the fingerprint (count, max_id) changes.
-	_ = s.UpsertFile("/root/b.md", "h2", 1, 1, "m", 3, []Chunk{{Index: 0, Text: "b", Embedding: []float32{0, 1, 0}}})
+	_ = s.UpsertFile("/root/b.md", "h2", "", 1, 1, "m", 3, []Chunk{{Index: 0, Text: "b", Embedding: []float32{0, 1, 0}}})
 
 	hits, err := s.Search([]float32{0, 1, 0}, 5)
 	if err != nil {
@@ -226,9 +226,9 @@ func TestHNSWMixedDimensionGraceful(t *testing.T) {
 	s := openTemp(t)
 	seedAccel(s, 4)
 	// First-seen dimension is 3.
-	_ = s.UpsertFile("/root/three.md", "h", 1, 1, "m", 3, []Chunk{{Index: 0, Text: "three", Embedding: []float32{1, 0, 0}}})
+	_ = s.UpsertFile("/root/three.md", "h", "", 1, 1, "m", 3, []Chunk{{Index: 0, Text: "three", Embedding: []float32{1, 0, 0}}})
 	// A 5-dim chunk (e.g. after a model change) must not crash the build.
-	_ = s.UpsertFile("/root/five.md", "h", 1, 1, "m", 5, []Chunk{{Index: 0, Text: "five", Embedding: []float32{1, 0, 0, 0, 0}}})
+	_ = s.UpsertFile("/root/five.md", "h", "", 1, 1, "m", 5, []Chunk{{Index: 0, Text: "five", Embedding: []float32{1, 0, 0, 0, 0}}})
 
 	query := []float32{1, 0, 0}
 	hits, err := s.Search(query, 5)
@@ -248,7 +248,7 @@ func TestAccelDisabledFallsBackToBrute(t *testing.T) {
 	if s.accel != nil {
 		t.Fatal("expected accelerator to be nil when CITADEL_INDEX_HNSW=0")
 	}
-	_ = s.UpsertFile("/root/a.md", "h", 1, 1, "m", 3, []Chunk{{Index: 0, Text: "a", Embedding: []float32{1, 0, 0}}})
+	_ = s.UpsertFile("/root/a.md", "h", "", 1, 1, "m", 3, []Chunk{{Index: 0, Text: "a", Embedding: []float32{1, 0, 0}}})
 	hits, err := s.Search([]float32{1, 0, 0}, 5)
 	if err != nil {
 		t.Fatalf("Search: %v", err)
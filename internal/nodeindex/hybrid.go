@@ -0,0 +1,254 @@
+package nodeindex
+
+import (
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ftsSchema is the lexical side of the index: an FTS5 table over chunks.text,
+// kept in sync by triggers so every writer (UpsertFile, DeleteFile, and any
+// future one) maintains it without knowing it exists. It is an external-content
+// table, so the chunk text is stored once, in chunks.
+//
+// The default unicode61 tokenizer splits identifiers like ERR_CONN_RESET into
+// their parts; ftsMatchQuery quotes each query term as a phrase, so the parts
+// must still appear adjacent and in order to match.
+const ftsSchema = `
+CREATE VIRTUAL TABLE IF NOT EXISTS chunks_fts USING fts5(
+    text, content='chunks', content_rowid='id', tokenize='unicode61 remove_diacritics 2'
+);
+CREATE TRIGGER IF NOT EXISTS chunks_fts_ai AFTER INSERT ON chunks BEGIN
+    INSERT INTO chunks_fts(rowid, text) VALUES (new.id, new.text);
+END;
+CREATE TRIGGER IF NOT EXISTS chunks_fts_ad AFTER DELETE ON chunks BEGIN
+    INSERT INTO chunks_fts(chunks_fts, rowid, text) VALUES ('delete', old.id, old.text);
+END;
+CREATE TRIGGER IF NOT EXISTS chunks_fts_au AFTER UPDATE ON chunks BEGIN
+    INSERT INTO chunks_fts(chunks_fts, rowid, text) VALUES ('delete', old.id, old.text);
+    INSERT INTO chunks_fts(rowid, text) VALUES (new.id, new.text);
+END;
+`
+
+// rrfK is the reciprocal-rank-fusion damping constant. 60 is the value from the
+// original RRF paper and what most hybrid engines ship; it keeps one list's top
+// hit from drowning out a chunk both lists rank well.
+const rrfK = 60
+
+// hybridPoolMin is the least number of candidates each leg contributes to
+// fusion, so a small top_k still fuses over enough of both rankings.
+const hybridPoolMin = 50
+
+// ensureFTS creates the FTS table and triggers, and backfills it once when an
+// index built before lexical search is opened for the first time.
+func ensureFTS(db *sql.DB) error {
+	var existing int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE name = 'chunks_fts'`).Scan(&existing); err != nil {
+		return fmt.Errorf("inspect fts table: %w", err)
+	}
+	if _, err := db.Exec(ftsSchema); err != nil {
+		return fmt.Errorf("create fts table: %w", err)
+	}
+	if existing == 0 {
+		if _, err := db.Exec(`INSERT INTO chunks_fts(chunks_fts) VALUES ('rebuild')`); err != nil {
+			return fmt.Errorf("backfill fts table: %w", err)
+		}
+	}
+	return nil
+}
+
+// SearchFilter narrows a search by file metadata. The zero value matches every
+// chunk.
+type SearchFilter struct {
+	// PathPrefix keeps files at or under this path (a directory or a file).
+	PathPrefix string
+	// Extensions keeps files with one of these extensions, compared
+	// case-insensitively. A leading dot is optional.
+	Extensions []string
+	// ModifiedAfter and ModifiedBefore bound the file's mtime in Unix seconds,
+	// inclusive. Zero leaves that side unbounded.
+	ModifiedAfter  int64
+	ModifiedBefore int64
+}
+
+// IsZero reports whether f filters nothing.
+func (f SearchFilter) IsZero() bool {
+	return f.PathPrefix == "" && len(f.Extensions) == 0 && f.ModifiedAfter == 0 && f.ModifiedBefore == 0
+}
+
+// sql renders f as a WHERE fragment over chunks c joined to indexed_files f.
+// It returns "" when f is zero.
+func (f SearchFilter) sql() (string, []any) {
+	var (
+		conds []string
+		args  []any
+	)
+	if f.PathPrefix != "" {
+		prefix := filepath.Clean(f.PathPrefix)
+		dir := strings.TrimSuffix(prefix, string(filepath.Separator)) + string(filepath.Separator)
+		// A range rather than substr: substr counts characters, not bytes, and
+		// under BINARY collation every path starting with dir sorts between dir
+		// and dir followed by the highest code point.
+		conds = append(conds, `(c.path = ? OR (c.path >= ? AND c.path < ?))`)
+		args = append(args, prefix, dir, dir+"\U0010FFFF")
+	}
+	if len(f.Extensions) > 0 {
+		var ors []string
+		for _, ext := range f.Extensions {
+			ext = strings.ToLower(strings.TrimSpace(ext))
+			if ext == "" {
+				continue
+			}
+			if !strings.HasPrefix(ext, ".") {
+				ext = "." + ext
+			}
+			ors = append(ors, `substr(lower(c.path), -?) = ?`)
+			args = append(args, utf8.RuneCountInString(ext), ext)
+		}
+		if len(ors) > 0 {
+			conds = append(conds, "("+strings.Join(ors, " OR ")+")")
+		}
+	}
+	if f.ModifiedAfter != 0 {
+		conds = append(conds, `f.mtime >= ?`)
+		args = append(args, f.ModifiedAfter)
+	}
+	if f.ModifiedBefore != 0 {
+		conds = append(conds, `f.mtime <= ?`)
+		args = append(args, f.ModifiedBefore)
+	}
+	return strings.Join(conds, " AND "), args
+}
+
+// ftsMatchQuery turns free text into an FTS5 MATCH expression: each
+// whitespace-separated term becomes a quoted phrase and the phrases are OR-ed,
+// so FTS5 operators in user input are inert and BM25 ranks chunks matching
+// more terms higher. Terms with no letters or digits are dropped. It returns ""
+// when nothing searchable remains.
+func ftsMatchQuery(text string) string {
+	var terms []string
+	for _, field := range strings.Fields(text) {
+		if strings.IndexFunc(field, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }) < 0 {
+			continue
+		}
+		terms = append(terms, `"`+strings.ReplaceAll(field, `"`, `""`)+`"`)
+	}
+	return strings.Join(terms, " OR ")
+}
+
+// LexicalSearch returns the topK chunks matching text under f, best BM25 match
+// first. Score is the negated BM25 rank, so higher is better like every other
+// score this package returns; it is only comparable within one result set.
+func (s *Store) LexicalSearch(text string, topK int, f SearchFilter) ([]SearchHit, error) {
+	if topK <= 0 {
+		topK = 10
+	}
+	match := ftsMatchQuery(text)
+	if match == "" {
+		return nil, nil
+	}
+	q := `SELECT c.path, c.chunk_index, c.text, c.page, c.section, bm25(chunks_fts)
+		FROM chunks_fts JOIN chunks c ON c.id = chunks_fts.rowid`
+	args := []any{match}
+	where, fargs := f.sql()
+	if where != "" {
+		q += ` JOIN indexed_files f ON f.path = c.path`
+	}
+	q += ` WHERE chunks_fts MATCH ?`
+	if where != "" {
+		q += ` AND ` + where
+		args = append(args, fargs...)
+	}
+	q += ` ORDER BY bm25(chunks_fts) LIMIT ?`
+	args = append(args, topK)
+
+	rows, err := s.db.Query(q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("lexical search: %w", err)
+	}
+	defer rows.Close()
+	var hits []SearchHit
+	for rows.Next() {
+		var (
+			h    SearchHit
+			rank float64
+		)
+		if err := rows.Scan(&h.Path, &h.ChunkIndex, &h.Text, &h.Page, &h.Section, &rank); err != nil {
+			return nil, fmt.Errorf("scan lexical hit: %w", err)
+		}
+		h.Score = -rank
+		hits = append(hits, h)
+	}
+	return hits, rows.Err()
+}
+
+// HybridQuery is one hybrid search: the query's embedding for the vector leg
+// and its text for the lexical leg.
+type HybridQuery struct {
+	Vector []float32
+	Text   string
+	TopK   int
+	Filter SearchFilter
+}
+
+// HybridSearch runs the vector and lexical searches and merges them with
+// reciprocal rank fusion: each chunk scores the sum of 1/(rrfK + rank) over the
+// lists it appears in. Exact identifiers the embedding model blurs still surface
+// through the lexical leg, and paraphrases with no shared words through the
+// vector leg. Score on the returned hits is the fused score.
+func (s *Store) HybridSearch(q HybridQuery) ([]SearchHit, error) {
+	topK := q.TopK
+	if topK <= 0 {
+		topK = 10
+	}
+	pool := topK * 4
+	if pool < hybridPoolMin {
+		pool = hybridPoolMin
+	}
+	vector, err := s.SearchFiltered(q.Vector, pool, q.Filter)
+	if err != nil {
+		return nil, err
+	}
+	lexical, err := s.LexicalSearch(q.Text, pool, q.Filter)
+	if err != nil {
+		return nil, err
+	}
+	return fuseRRF(topK, vector, lexical), nil
+}
+
+// fuseRRF merges ranked lists by reciprocal rank fusion, keyed by chunk.
+func fuseRRF(topK int, lists ...[]SearchHit) []SearchHit {
+	type key struct {
+		path  string
+		index int
+	}
+	fused := make(map[key]*SearchHit)
+	var order []key
+	for _, list := range lists {
+		for rank, h := range list {
+			k := key{h.Path, h.ChunkIndex}
+			cur, ok := fused[k]
+			if !ok {
+				hit := h
+				hit.Score = 0
+				cur = &hit
+				fused[k] = cur
+				order = append(order, k)
+			}
+			cur.Score += 1 / float64(rrfK+rank+1)
+		}
+	}
+	out := make([]SearchHit, 0, len(order))
+	for _, k := range order {
+		out = append(out, *fused[k])
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Score > out[j].Score })
+	if len(out) > topK {
+		out = out[:topK]
+	}
+	return out
+}
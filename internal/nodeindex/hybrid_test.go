@@ -0,0 +1,134 @@
+package nodeindex
+
+import (
+	"math"
+	"testing"
+)
+
+// seedHybrid indexes three files whose vectors and text disagree on purpose:
+// only errors.log mentions the identifier, but its vector points away from the
+// query.
+func seedHybrid(t *testing.T) *Store {
+	t.Helper()
+	s := openTemp(t)
+	files := []struct {
+		path  string
+		mtime int64
+		text  string
+		vec   []float32
+	}{
+		{"/ws/docs/net.md", 100, "Connections reset when the peer goes away.", []float32{1, 0}},
+		{"/ws/docs/errors.log", 200, "fatal: ERR_CONN_RESET from upstream", []float32{0, 1}},
+		{"/ws/other/net.md", 300, "Networking overview and retries.", []float32{0.9, 0.1}},
+	}
+	for _, f := range files {
+		if err := s.UpsertFile(f.path, "h", "", f.mtime, 1, "m", 2, []Chunk{{Index: 0, Text: f.text, Embedding: f.vec}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return s
+}
+
+func TestHybridSearchSurfacesExactIdentifier(t *testing.T) {
+	s := seedHybrid(t)
+	query := HybridQuery{Vector: []float32{1, 0}, Text: "ERR_CONN_RESET", TopK: 2}
+
+	vec, _ := s.Search(query.Vector, 2)
+	for _, h := range vec {
+		if h.Path == "/ws/docs/errors.log" {
+			t.Fatalf("vector search alone already finds the identifier; test is not exercising fusion")
+		}
+	}
+	hits, err := s.HybridSearch(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hits) != 2 || hits[0].Path != "/ws/docs/errors.log" {
+		t.Fatalf("hybrid hits = %+v, want errors.log first (ranked by both legs)", hits)
+	}
+}
+
+func TestLexicalSearchQuotesOperators(t *testing.T) {
+	s := seedHybrid(t)
+	for _, q := range []string{`"unbalanced`, `NEAR(`, `-- * ^`, `retries OR`} {
+		if _, err := s.LexicalSearch(q, 5, SearchFilter{}); err != nil {
+			t.Errorf("LexicalSearch(%q) = %v, want user text treated literally", q, err)
+		}
+	}
+	hits, _ := s.LexicalSearch("networking", 5, SearchFilter{})
+	if len(hits) != 1 || hits[0].Path != "/ws/other/net.md" || hits[0].Score <= 0 {
+		t.Errorf("hits = %+v, want other/net.md with a positive score", hits)
+	}
+}
+
+func TestSearchFilters(t *testing.T) {
+	s := seedHybrid(t)
+	// A non-ASCII path: its byte and character lengths differ.
+	if err := s.UpsertFile("/ws/José/notes.café", "h", "", 400, 1, "m", 2, []Chunk{{Index: 0, Text: "Connections from José", Embedding: []float32{1, 0}}}); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		filter SearchFilter
+		want   []string
+	}{
+		{"prefix dir", SearchFilter{PathPrefix: "/ws/docs/"}, []string{"/ws/docs/net.md", "/ws/docs/errors.log"}},
+		{"prefix is not a string prefix", SearchFilter{PathPrefix: "/ws/doc"}, nil},
+		{"prefix file", SearchFilter{PathPrefix: "/ws/other/net.md"}, []string{"/ws/other/net.md"}},
+		{"extension", SearchFilter{Extensions: []string{"LOG"}}, []string{"/ws/docs/errors.log"}},
+		{"non-ASCII prefix", SearchFilter{PathPrefix: "/ws/José"}, []string{"/ws/José/notes.café"}},
+		{"non-ASCII extension", SearchFilter{Extensions: []string{"café"}}, []string{"/ws/José/notes.café"}},
+		{"mtime range", SearchFilter{ModifiedAfter: 150, ModifiedBefore: 300}, []string{"/ws/docs/errors.log", "/ws/other/net.md"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vec, err := s.SearchFiltered([]float32{1, 1}, 10, tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := map[string]bool{}
+			for _, h := range vec {
+				got[h.Path] = true
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("vector hits = %v, want %v", got, tt.want)
+			}
+			for _, p := range tt.want {
+				if !got[p] {
+					t.Errorf("vector hits missing %s", p)
+				}
+			}
+			lex, err := s.LexicalSearch("ERR_CONN_RESET Connections Networking", 10, tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(lex) != len(tt.want) {
+				t.Errorf("lexical hits = %+v, want %v", lex, tt.want)
+			}
+		})
+	}
+}
+
+func TestLexicalIndexFollowsUpsertAndDelete(t *testing.T) {
+	s := seedHybrid(t)
+	_ = s.UpsertFile("/ws/docs/errors.log", "h2", "", 1, 1, "m", 2, []Chunk{{Index: 0, Text: "all clear", Embedding: []float32{0, 1}}})
+	if hits, _ := s.LexicalSearch("ERR_CONN_RESET", 5, SearchFilter{}); len(hits) != 0 {
+		t.Errorf("stale text still matches after re-upsert: %+v", hits)
+	}
+	_ = s.DeleteFile("/ws/other/net.md")
+	if hits, _ := s.LexicalSearch("networking", 5, SearchFilter{}); len(hits) != 0 {
+		t.Errorf("deleted file still matches: %+v", hits)
+	}
+}
+
+func TestFuseRRF(t *testing.T) {
+	a := []SearchHit{{Path: "x"}, {Path: "y"}}
+	b := []SearchHit{{Path: "y"}, {Path: "z"}}
+	got := fuseRRF(10, a, b)
+	if len(got) != 3 || got[0].Path != "y" {
+		t.Fatalf("fused = %+v, want y (in both lists) first", got)
+	}
+	if want := 1.0/62 + 1.0/61; math.Abs(got[0].Score-want) > 1e-12 {
+		t.Errorf("y score = %v, want %v", got[0].Score, want)
+	}
+}
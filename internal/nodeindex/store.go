@@ -16,6 +16,9 @@
 // durable source of truth. Search prefers the accelerator and falls back to the
 // brute-force cosine scan when it is empty, disabled, or dimension-mismatched
 // (aceteam#6087; citadel-cli#617-619).
+//
+// Beside the vectors, an FTS5 table over the chunk text (hybrid.go) serves
+// exact-term BM25 search, and HybridSearch fuses the two rankings.
 package nodeindex
 
 import (
@@ -36,6 +39,7 @@ const schema = `
 CREATE TABLE IF NOT EXISTS indexed_files (
     path          TEXT PRIMARY KEY,
     content_hash  TEXT NOT NULL,
+    extraction    TEXT NOT NULL DEFAULT '',
     mtime         INTEGER NOT NULL DEFAULT 0,
     size          INTEGER NOT NULL DEFAULT 0,
     model         TEXT NOT NULL DEFAULT '',
@@ -48,7 +52,9 @@ CREATE TABLE IF NOT EXISTS chunks (
     path         TEXT NOT NULL,
     chunk_index  INTEGER NOT NULL,
     text         TEXT NOT NULL,
-    embedding    BLOB NOT NULL
+    embedding    BLOB NOT NULL,
+    page         INTEGER NOT NULL DEFAULT 0,
+    section      TEXT NOT NULL DEFAULT ''
 );
 CREATE INDEX IF NOT EXISTS idx_chunks_path ON chunks(path);
 `
@@ -65,20 +71,41 @@ type Store struct {
 	accel *accelerator
 }
 
-// Chunk is one embedded unit of a file: a slice of text and its vector.
+// chunkCitationColumns are the chunks columns added after the original schema
+// shipped. CREATE TABLE IF NOT EXISTS leaves an existing table alone, so Open
+// adds any that are missing to an index built by an older release.
+var chunkCitationColumns = []struct{ name, ddl string }{
+	{"page", "ALTER TABLE chunks ADD COLUMN page INTEGER NOT NULL DEFAULT 0"},
+	{"section", "ALTER TABLE chunks ADD COLUMN section TEXT NOT NULL DEFAULT ''"},
+}
+
+// indexedFilesColumns are the indexed_files columns added after the original
+// schema shipped, added by Open the same way.
+var indexedFilesColumns = []struct{ name, ddl string }{
+	{"extraction", "ALTER TABLE indexed_files ADD COLUMN extraction TEXT NOT NULL DEFAULT ''"},
+}
+
+// Chunk is one embedded unit of a file: a slice of text and its vector. Page
+// and Section locate the text in its source document for citations; they are
+// zero for formats without pages or headings.
 type Chunk struct {
 	Index     int
 	Text      string
 	Embedding []float32
+	Page      int
+	Section   string
 }
 
 // SearchHit is one KNN result: the source file, which chunk, the chunk text,
-// and the cosine similarity score in [-1, 1].
+// and the cosine similarity score in [-1, 1]. Page (1-based) and Section cite
+// where in the document the chunk came from, when the extractor knew.
 type SearchHit struct {
 	Path       string  `json:"path"`
 	ChunkIndex int     `json:"chunk_index"`
 	Text       string  `json:"text"`
 	Score      float64 `json:"score"`
+	Page       int     `json:"page,omitempty"`
+	Section    string  `json:"section,omitempty"`
 }
 
 // Open opens (or creates) the node-local index database at dbPath and runs
@@ -106,6 +133,18 @@ func Open(dbPath string) (*Store, error) {
 		db.Close()
 		return nil, fmt.Errorf("run migrations: %w", err)
 	}
+	if err := addMissingColumns(db, "chunks", chunkCitationColumns); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("run migrations: %w", err)
+	}
+	if err := addMissingColumns(db, "indexed_files", indexedFilesColumns); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("run migrations: %w", err)
+	}
+	if err := ensureFTS(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("run migrations: %w", err)
+	}
 	s := &Store{db: db, dbPath: dbPath}
 	if !accelDisabled() {
 		s.accel = getAccelerator(dbPath)
@@ -113,22 +152,60 @@ func Open(dbPath string) (*Store, error) {
 	return s, nil
 }
 
+// addMissingColumns brings a table built by an older release up to the
+// current schema. Existing chunks rows get page 0 and no section, which is
+// what a plain-text re-index would record anyway; existing indexed_files rows
+// get no extraction, so documents are extracted again on the next run.
+func addMissingColumns(db *sql.DB, table string, cols []struct{ name, ddl string }) error {
+	rows, err := db.Query(`PRAGMA table_info(` + table + `)`)
+	if err != nil {
+		return fmt.Errorf("inspect %s table: %w", table, err)
+	}
+	have := make(map[string]bool)
+	for rows.Next() {
+		var (
+			cid, notNull, pk int
+			name, typ        string
+			dflt             sql.NullString
+		)
+		if err := rows.Scan(&cid, &name, &typ, &notNull, &dflt, &pk); err != nil {
+			rows.Close()
+			return fmt.Errorf("inspect %s table: %w", table, err)
+		}
+		have[name] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("inspect %s table: %w", table, err)
+	}
+	for _, col := range cols {
+		if have[col.name] {
+			continue
+		}
+		if _, err := db.Exec(col.ddl); err != nil {
+			return fmt.Errorf("add %s.%s: %w", table, col.name, err)
+		}
+	}
+	return nil
+}
+
 // Close releases the underlying database handle.
 func (s *Store) Close() error {
 	return s.db.Close()
 }
 
-// FileHash returns the content hash recorded for path and whether the path is
-// currently indexed. It lets FILE_INDEX skip files whose content is unchanged.
-func (s *Store) FileHash(path string) (hash string, indexed bool, err error) {
-	row := s.db.QueryRow(`SELECT content_hash FROM indexed_files WHERE path = ?`, path)
-	switch err := row.Scan(&hash); err {
+// FileHash returns the content hash and extraction recorded for path and
+// whether the path is currently indexed. It lets FILE_INDEX skip files whose
+// content, and the way it was extracted, are unchanged.
+func (s *Store) FileHash(path string) (hash, extraction string, indexed bool, err error) {
+	row := s.db.QueryRow(`SELECT content_hash, extraction FROM indexed_files WHERE path = ?`, path)
+	switch err := row.Scan(&hash, &extraction); err {
 	case nil:
-		return hash, true, nil
+		return hash, extraction, true, nil
 	case sql.ErrNoRows:
-		return "", false, nil
+		return "", "", false, nil
 	default:
-		return "", false, fmt.Errorf("query file hash: %w", err)
+		return "", "", false, fmt.Errorf("query file hash: %w", err)
 	}
 }
 
@@ -154,7 +231,10 @@ func (s *Store) IndexedPaths() (map[string]struct{}, error) {
 // UpsertFile replaces the indexed representation of a single file: it deletes any
 // prior chunks for path and inserts the file row plus its chunks in one
 // transaction, so a re-index never leaves duplicate or orphaned chunks.
-func (s *Store) UpsertFile(path, contentHash string, mtime, size int64, model string, dim int, chunks []Chunk) error {
+// extraction identifies how the text was extracted ("" for plain text); it is
+// kept beside contentHash, which stays the bare content hash the tiers dedup
+// by.
+func (s *Store) UpsertFile(path, contentHash, extraction string, mtime, size int64, model string, dim int, chunks []Chunk) error {
 	tx, err := s.db.Begin()
 	if err != nil {
 		return fmt.Errorf("begin tx: %w", err)
@@ -165,27 +245,28 @@ func (s *Store) UpsertFile(path, contentHash string, mtime, size int64, model st
 		return fmt.Errorf("delete old chunks: %w", err)
 	}
 	if _, err := tx.Exec(`
-		INSERT INTO indexed_files (path, content_hash, mtime, size, model, dim, chunk_count, indexed_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		INSERT INTO indexed_files (path, content_hash, extraction, mtime, size, model, dim, chunk_count, indexed_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
 		ON CONFLICT(path) DO UPDATE SET
 			content_hash=excluded.content_hash,
+			extraction=excluded.extraction,
 			mtime=excluded.mtime,
 			size=excluded.size,
 			model=excluded.model,
 			dim=excluded.dim,
 			chunk_count=excluded.chunk_count,
 			indexed_at=excluded.indexed_at`,
-		path, contentHash, mtime, size, model, dim, len(chunks),
+		path, contentHash, extraction, mtime, size, model, dim, len(chunks),
 		time.Now().UTC().Format(time.RFC3339)); err != nil {
 		return fmt.Errorf("upsert file row: %w", err)
 	}
-	stmt, err := tx.Prepare(`INSERT INTO chunks (path, chunk_index, text, embedding) VALUES (?, ?, ?, ?)`)
+	stmt, err := tx.Prepare(`INSERT INTO chunks (path, chunk_index, text, embedding, page, section) VALUES (?, ?, ?, ?, ?, ?)`)
 	if err != nil {
 		return fmt.Errorf("prepare chunk insert: %w", err)
 	}
 	defer stmt.Close()
 	for _, c := range chunks {
-		if _, err := stmt.Exec(path, c.Index, c.Text, encodeVector(c.Embedding)); err != nil {
+		if _, err := stmt.Exec(path, c.Index, c.Text, encodeVector(c.Embedding), c.Page, c.Section); err != nil {
 			return fmt.Errorf("insert chunk %d: %w", c.Index, err)
 		}
 	}
@@ -235,18 +316,41 @@ func (s *Store) Search(query []float32, topK int) ([]SearchHit, error) {
 			return hits, nil
 		}
 	}
-	return s.searchBrute(query, qNorm, topK)
+	return s.searchBrute(query, qNorm, topK, SearchFilter{})
+}
+
+// SearchFiltered is Search restricted to chunks matching f. The HNSW graph
+// cannot filter during descent, so a non-empty filter always takes the
+// brute-force scan over the matching rows, which keeps results exact rather
+// than over-fetching and hoping enough survive the filter.
+func (s *Store) SearchFiltered(query []float32, topK int, f SearchFilter) ([]SearchHit, error) {
+	if f.IsZero() {
+		return s.Search(query, topK)
+	}
+	if topK <= 0 {
+		topK = 10
+	}
+	qNorm := norm(query)
+	if qNorm == 0 {
+		return nil, nil
+	}
+	return s.searchBrute(query, qNorm, topK, f)
 }
 
-// searchBrute is the brute-force cosine KNN over every stored chunk. It is the
-// fallback path (empty/unbuilt accelerator, disabled accelerator, or a
-// dimension-mismatched query) and the parity reference the accelerator is tested
-// against.
-func (s *Store) searchBrute(query []float32, qNorm float64, topK int) ([]SearchHit, error) {
+// searchBrute is the brute-force cosine KNN over every stored chunk matching f.
+// It is the fallback path (empty/unbuilt accelerator, disabled accelerator, or a
+// dimension-mismatched query), the filtered path, and the parity reference the
+// accelerator is tested against.
+func (s *Store) searchBrute(query []float32, qNorm float64, topK int, f SearchFilter) ([]SearchHit, error) {
 	if topK <= 0 {
 		topK = 10
 	}
-	rows, err := s.db.Query(`SELECT path, chunk_index, text, embedding FROM chunks`)
+	q := `SELECT c.path, c.chunk_index, c.text, c.embedding, c.page, c.section FROM chunks c`
+	where, args := f.sql()
+	if where != "" {
+		q += ` JOIN indexed_files f ON f.path = c.path WHERE ` + where
+	}
+	rows, err := s.db.Query(q, args...)
 	if err != nil {
 		return nil, fmt.Errorf("scan chunks: %w", err)
 	}
@@ -255,12 +359,14 @@ func (s *Store) searchBrute(query []float32, qNorm float64, topK int) ([]SearchH
 	var hits []SearchHit
 	for rows.Next() {
 		var (
-			path string
-			idx  int
-			text string
-			blob []byte
+			path    string
+			idx     int
+			text    string
+			blob    []byte
+			page    int
+			section string
 		)
-		if err := rows.Scan(&path, &idx, &text, &blob); err != nil {
+		if err := rows.Scan(&path, &idx, &text, &blob, &page, &section); err != nil {
 			return nil, fmt.Errorf("scan chunk row: %w", err)
 		}
 		vec := decodeVector(blob)
@@ -268,7 +374,7 @@ func (s *Store) searchBrute(query []float32, qNorm float64, topK int) ([]SearchH
 		if math.IsNaN(score) {
 			continue
 		}
-		hits = append(hits, SearchHit{Path: path, ChunkIndex: idx, Text: text, Score: score})
+		hits = append(hits, SearchHit{Path: path, ChunkIndex: idx, Text: text, Score: score, Page: page, Section: section})
 	}
 	if err := rows.Err(); err != nil {
 		return nil, fmt.Errorf("iterate chunks: %w", err)
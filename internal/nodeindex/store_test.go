@@ -1,6 +1,7 @@
 package nodeindex
 
 import (
+	"database/sql"
 	"math"
 	"path/filepath"
 	"strings"
@@ -42,13 +43,13 @@ func TestUpsertAndSearchRoundTrip(t *testing.T) {
 	s := openTemp(t)
 
 	// Two orthogonal-ish vectors on distinct files.
-	err := s.UpsertFile("/ws/a.md", "hashA", 1, 10, "m", 3, []Chunk{
+	err := s.UpsertFile("/ws/a.md", "hashA", "", 1, 10, "m", 3, []Chunk{
 		{Index: 0, Text: "alpha doc", Embedding: []float32{1, 0, 0}},
 	})
 	if err != nil {
 		t.Fatalf("UpsertFile a: %v", err)
 	}
-	err = s.UpsertFile("/ws/b.md", "hashB", 2, 10, "m", 3, []Chunk{
+	err = s.UpsertFile("/ws/b.md", "hashB", "", 2, 10, "m", 3, []Chunk{
 		{Index: 0, Text: "beta doc", Embedding: []float32{0, 1, 0}},
 	})
 	if err != nil {
@@ -84,12 +85,12 @@ func TestUpsertAndSearchRoundTrip(t *testing.T) {
 
 func TestReUpsertReplacesChunks(t *testing.T) {
 	s := openTemp(t)
-	_ = s.UpsertFile("/ws/a.md", "h1", 1, 10, "m", 2, []Chunk{
+	_ = s.UpsertFile("/ws/a.md", "h1", "", 1, 10, "m", 2, []Chunk{
 		{Index: 0, Text: "one", Embedding: []float32{1, 0}},
 		{Index: 1, Text: "two", Embedding: []float32{0, 1}},
 	})
 	// Re-index the same path with fewer chunks; must not leave the stale one.
-	_ = s.UpsertFile("/ws/a.md", "h2", 2, 10, "m", 2, []Chunk{
+	_ = s.UpsertFile("/ws/a.md", "h2", "", 2, 10, "m", 2, []Chunk{
 		{Index: 0, Text: "only", Embedding: []float32{1, 1}},
 	})
 	files, chunks, err := s.Stats()
@@ -99,7 +100,7 @@ func TestReUpsertReplacesChunks(t *testing.T) {
 	if files != 1 || chunks != 1 {
 		t.Fatalf("re-upsert should leave 1 file / 1 chunk, got %d/%d", files, chunks)
 	}
-	hash, indexed, err := s.FileHash("/ws/a.md")
+	hash, _, indexed, err := s.FileHash("/ws/a.md")
 	if err != nil || !indexed {
 		t.Fatalf("FileHash: hash=%q indexed=%v err=%v", hash, indexed, err)
 	}
@@ -110,8 +111,8 @@ func TestReUpsertReplacesChunks(t *testing.T) {
 
 func TestDeleteFileAndIndexedPaths(t *testing.T) {
 	s := openTemp(t)
-	_ = s.UpsertFile("/ws/a.md", "h", 1, 1, "m", 1, []Chunk{{Index: 0, Text: "x", Embedding: []float32{1}}})
-	_ = s.UpsertFile("/ws/b.md", "h", 1, 1, "m", 1, []Chunk{{Index: 0, Text: "y", Embedding: []float32{1}}})
+	_ = s.UpsertFile("/ws/a.md", "h", "", 1, 1, "m", 1, []Chunk{{Index: 0, Text: "x", Embedding: []float32{1}}})
+	_ = s.UpsertFile("/ws/b.md", "h", "", 1, 1, "m", 1, []Chunk{{Index: 0, Text: "y", Embedding: []float32{1}}})
 
 	paths, err := s.IndexedPaths()
 	if err != nil {
@@ -143,7 +144,7 @@ func TestSearchEmptyIndexAndZeroQuery(t *testing.T) {
 		t.Fatalf("empty index should yield no hits, got %d", len(hits))
 	}
 
-	_ = s.UpsertFile("/ws/a.md", "h", 1, 1, "m", 3, []Chunk{{Index: 0, Text: "x", Embedding: []float32{1, 0, 0}}})
+	_ = s.UpsertFile("/ws/a.md", "h", "", 1, 1, "m", 3, []Chunk{{Index: 0, Text: "x", Embedding: []float32{1, 0, 0}}})
 	// Zero-magnitude query vector: no meaningful direction, expect no hits.
 	hits, err = s.Search([]float32{0, 0, 0}, 5)
 	if err != nil {
@@ -158,7 +159,7 @@ func TestSearchMismatchedDimSkipped(t *testing.T) {
 	s := openTemp(t)
 	// Index a 3-dim vector, then query with a 2-dim vector: dimensions differ,
 	// the hit must be skipped (NaN score), not crash or produce a bogus score.
-	_ = s.UpsertFile("/ws/a.md", "h", 1, 1, "m", 3, []Chunk{{Index: 0, Text: "x", Embedding: []float32{1, 0, 0}}})
+	_ = s.UpsertFile("/ws/a.md", "h", "", 1, 1, "m", 3, []Chunk{{Index: 0, Text: "x", Embedding: []float32{1, 0, 0}}})
 	hits, err := s.Search([]float32{1, 0}, 5)
 	if err != nil {
 		t.Fatalf("Search mismatched dim: %v", err)
@@ -172,7 +173,7 @@ func TestSearchTopKCap(t *testing.T) {
 	s := openTemp(t)
 	for i := 0; i < 5; i++ {
 		p := "/ws/f" + string(rune('a'+i)) + ".md"
-		_ = s.UpsertFile(p, "h", 1, 1, "m", 2, []Chunk{{Index: 0, Text: "t", Embedding: []float32{float32(i + 1), 1}}})
+		_ = s.UpsertFile(p, "h", "", 1, 1, "m", 2, []Chunk{{Index: 0, Text: "t", Embedding: []float32{float32(i + 1), 1}}})
 	}
 	hits, err := s.Search([]float32{1, 1}, 3)
 	if err != nil {
@@ -192,7 +193,7 @@ func TestSearchTopKCap(t *testing.T) {
 func TestLongTextChunkStored(t *testing.T) {
 	s := openTemp(t)
 	long := strings.Repeat("word ", 5000) // ~25 KB single chunk text
-	if err := s.UpsertFile("/ws/big.md", "h", 1, int64(len(long)), "m", 2, []Chunk{
+	if err := s.UpsertFile("/ws/big.md", "h", "", 1, int64(len(long)), "m", 2, []Chunk{
 		{Index: 0, Text: long, Embedding: []float32{1, 0}},
 	}); err != nil {
 		t.Fatalf("UpsertFile long: %v", err)
@@ -205,3 +206,66 @@ func TestLongTextChunkStored(t *testing.T) {
 		t.Fatalf("long text not round-tripped intact")
 	}
 }
+
+func TestSearchReturnsChunkCitations(t *testing.T) {
+	for _, accel := range []string{"off", ""} {
+		t.Run("hnsw="+accel, func(t *testing.T) {
+			t.Setenv("CITADEL_INDEX_HNSW", accel)
+			s := openTemp(t)
+			_ = s.UpsertFile("/ws/report.pdf", "h", "", 1, 1, "m", 2, []Chunk{
+				{Index: 0, Text: "intro", Embedding: []float32{0, 1}, Page: 1, Section: "Overview"},
+				{Index: 1, Text: "results", Embedding: []float32{1, 0}, Page: 7, Section: "Results"},
+			})
+			hits, err := s.Search([]float32{1, 0}, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(hits) != 1 || hits[0].Page != 7 || hits[0].Section != "Results" {
+				t.Fatalf("hits = %+v, want the page-7 Results chunk", hits)
+			}
+		})
+	}
+}
+
+// TestOpenMigratesPreCitationIndex opens an index.db written before chunks had
+// page/section columns or a lexical index and checks it still searches and
+// accepts new chunks.
+func TestOpenMigratesPreCitationIndex(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "index.db")
+	db, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`
+CREATE TABLE chunks (
+    id           INTEGER PRIMARY KEY AUTOINCREMENT,
+    path         TEXT NOT NULL,
+    chunk_index  INTEGER NOT NULL,
+    text         TEXT NOT NULL,
+    embedding    BLOB NOT NULL
+);
+INSERT INTO chunks (path, chunk_index, text, embedding) VALUES ('/ws/old.md', 0, 'old', ?);`,
+		encodeVector([]float32{1, 0})); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	s, err := Open(dbPath)
+	if err != nil {
+		t.Fatalf("Open on a pre-citation index: %v", err)
+	}
+	defer s.Close()
+	if err := s.UpsertFile("/ws/new.pdf", "h", "", 1, 1, "m", 2, []Chunk{
+		{Index: 0, Text: "new", Embedding: []float32{0, 1}, Page: 2},
+	}); err != nil {
+		t.Fatalf("UpsertFile after migration: %v", err)
+	}
+	hits, err := s.Search([]float32{1, 1}, 10)
+	if err != nil || len(hits) != 2 {
+		t.Fatalf("Search = %+v, %v; want both chunks", hits, err)
+	}
+	// The lexical index is backfilled from the chunks that predate it.
+	if lex, err := s.LexicalSearch("old", 10, SearchFilter{}); err != nil || len(lex) != 1 {
+		t.Fatalf("LexicalSearch = %+v, %v; want the pre-existing chunk", lex, err)
+	}
+}
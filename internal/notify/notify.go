@@ -22,7 +22,9 @@
 //	  "title": "<string, required>",
 //	  "body":  "<string, required>",
 //	  "target": "chat" | "nodes" | "terminal" | "settings",   // optional, default "chat"
-//	  "conversation_id": "<uuid>"                              // optional deep-link target
+//	  "conversation_id": "<uuid>",                             // optional deep-link target
+//	  "url": "<https url>",                                    // optional tap-through link
+//	  "image_url": "<https url>"                               // optional attachment image
 //	}
 //
 //	-> 2xx { "success": true }   (notification accepted / queued for delivery)
@@ -81,6 +83,12 @@ type Notification struct {
 	// ConversationID deep-links the notification to a specific chat/conversation.
 	// Optional.
 	ConversationID string `json:"conversation_id,omitempty"`
+	// URL is opened when the notification is tapped (e.g. an NVR clip).
+	// Optional.
+	URL string `json:"url,omitempty"`
+	// ImageURL is fetched by the notification service extension and shown as
+	// the push's attachment (e.g. an NVR event snapshot). Optional.
+	ImageURL string `json:"image_url,omitempty"`
 }
 
 // Result is what the caller learns about a send. Because actual APNs delivery
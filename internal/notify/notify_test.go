@@ -111,6 +111,42 @@ func TestSend_OmitsOptionalFields(t *testing.T) {
 	if _, ok := sent["conversation_id"]; ok {
 		t.Errorf("conversation_id should be omitted when empty")
 	}
+	for _, k := range []string{"url", "image_url"} {
+		if _, ok := sent[k]; ok {
+			t.Errorf("%s should be omitted when empty", k)
+		}
+	}
+}
+
+// TestSend_MediaLinks checks the NVR fields (clip link + snapshot attachment)
+// reach the backend under their wire names.
+func TestSend_MediaLinks(t *testing.T) {
+	var gotBody []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotBody, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	c := NewClient(Config{BaseURL: srv.URL, Token: "t"})
+	if _, err := c.Send(context.Background(), Notification{
+		Title:    "Person on front-door",
+		Body:     "Detected at 23:10:00 (85%)",
+		URL:      "https://node/expose/frigate/api/events/e1/clip.mp4",
+		ImageURL: "https://node/expose/frigate/api/events/e1/snapshot.jpg",
+	}); err != nil {
+		t.Fatalf("Send error: %v", err)
+	}
+	var sent map[string]any
+	if err := json.Unmarshal(gotBody, &sent); err != nil {
+		t.Fatalf("body not JSON: %v", err)
+	}
+	if sent["url"] != "https://node/expose/frigate/api/events/e1/clip.mp4" {
+		t.Errorf("url = %v", sent["url"])
+	}
+	if sent["image_url"] != "https://node/expose/frigate/api/events/e1/snapshot.jpg" {
+		t.Errorf("image_url = %v", sent["image_url"])
+	}
 }
 
 // TestSend_BackendErrorIsNotAccepted ensures a non-2xx is surfaced as an error
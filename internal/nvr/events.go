@@ -0,0 +1,142 @@
+package nvr
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// Frigate publishes one message per tracked-object lifecycle change on
+// <topic_prefix>/events: "new" when an object is first detected, "update" as
+// its best snapshot/score/zones improve, and "end" when it leaves the frame.
+// Each message carries the full object state before and after the change.
+const (
+	EventNew    = "new"
+	EventUpdate = "update"
+	EventEnd    = "end"
+)
+
+// EventsTopic is the topic Frigate publishes event lifecycle messages on.
+func EventsTopic(prefix string) string {
+	if prefix == "" {
+		prefix = DefaultMQTTTopicPrefix
+	}
+	return prefix + "/events"
+}
+
+// Event is the subset of a Frigate tracked object citadel acts on.
+type Event struct {
+	ID       string
+	Camera   string
+	Label    string
+	SubLabel string
+	// Score is the object's best detection score so far (0..1).
+	Score float64
+	Zones []string
+	Start time.Time
+	// End is zero while the event is in progress.
+	End           time.Time
+	HasClip       bool
+	HasSnapshot   bool
+	FalsePositive bool
+	// Type is the lifecycle change that produced this state (EventNew, ...).
+	Type string
+}
+
+// frigateEventMessage mirrors Frigate's <prefix>/events payload.
+type frigateEventMessage struct {
+	Type  string             `json:"type"`
+	After *frigateEventState `json:"after"`
+}
+
+type frigateEventState struct {
+	ID       string  `json:"id"`
+	Camera   string  `json:"camera"`
+	Label    string  `json:"label"`
+	SubLabel any     `json:"sub_label"`
+	TopScore float64 `json:"top_score"`
+	Score    float64 `json:"score"`
+	// Frigate reports times as fractional unix seconds; end_time is null
+	// until the event ends.
+	StartTime     float64  `json:"start_time"`
+	EndTime       *float64 `json:"end_time"`
+	HasClip       bool     `json:"has_clip"`
+	HasSnapshot   bool     `json:"has_snapshot"`
+	FalsePositive bool     `json:"false_positive"`
+	CurrentZones  []string `json:"current_zones"`
+	EnteredZones  []string `json:"entered_zones"`
+}
+
+// ParseEventMessage decodes one <prefix>/events payload into the object's
+// post-change state.
+func ParseEventMessage(payload []byte) (Event, error) {
+	var msg frigateEventMessage
+	if err := json.Unmarshal(payload, &msg); err != nil {
+		return Event{}, fmt.Errorf("nvr: parse event: %w", err)
+	}
+	a := msg.After
+	if a == nil || a.ID == "" || a.Camera == "" {
+		return Event{}, fmt.Errorf("nvr: parse event: missing after.id or after.camera")
+	}
+	ev := Event{
+		ID:            a.ID,
+		Camera:        a.Camera,
+		Label:         a.Label,
+		SubLabel:      subLabelString(a.SubLabel),
+		Score:         math.Max(a.TopScore, a.Score),
+		Zones:         a.EnteredZones,
+		Start:         unixFloat(a.StartTime),
+		HasClip:       a.HasClip,
+		HasSnapshot:   a.HasSnapshot,
+		FalsePositive: a.FalsePositive,
+		Type:          msg.Type,
+	}
+	if len(ev.Zones) == 0 {
+		ev.Zones = a.CurrentZones
+	}
+	if a.EndTime != nil {
+		ev.End = unixFloat(*a.EndTime)
+	}
+	return ev, nil
+}
+
+// subLabelString normalizes sub_label, which Frigate has sent both as a bare
+// string and as a [name, score] pair.
+func subLabelString(v any) string {
+	switch s := v.(type) {
+	case string:
+		return s
+	case []any:
+		if len(s) > 0 {
+			if name, ok := s[0].(string); ok {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
+func unixFloat(f float64) time.Time {
+	if f <= 0 {
+		return time.Time{}
+	}
+	sec, frac := math.Modf(f)
+	return time.Unix(int64(sec), int64(frac*1e9))
+}
+
+// SnapshotPath and ClipPath are Frigate API paths for an event's best
+// snapshot and recorded clip, relative to the Frigate base URL.
+func SnapshotPath(eventID string) string { return "/api/events/" + eventID + "/snapshot.jpg" }
+func ClipPath(eventID string) string     { return "/api/events/" + eventID + "/clip.mp4" }
+
+// EventLinks builds absolute snapshot and clip URLs for an event under base
+// (e.g. the gateway exposure of the Frigate UI). Both are empty when base is.
+func EventLinks(base, eventID string) (snapshot, clip string) {
+	base = strings.TrimRight(base, "/")
+	if base == "" {
+		return "", ""
+	}
+	return base + SnapshotPath(eventID), base + ClipPath(eventID)
+}
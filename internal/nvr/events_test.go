@@ -0,0 +1,87 @@
+package nvr
+
+import (
+	"testing"
+	"time"
+)
+
+const frigateNewEvent = `{
+  "type": "new",
+  "before": {"id": "1718000000.123-abc", "camera": "front-door"},
+  "after": {
+    "id": "1718000000.123-abc",
+    "camera": "front-door",
+    "label": "person",
+    "sub_label": ["alice", 0.91],
+    "top_score": 0.84,
+    "score": 0.79,
+    "start_time": 1718000000.5,
+    "end_time": null,
+    "has_clip": false,
+    "has_snapshot": true,
+    "false_positive": false,
+    "current_zones": ["porch"],
+    "entered_zones": ["driveway", "porch"]
+  }
+}`
+
+func TestParseEventMessage(t *testing.T) {
+	ev, err := ParseEventMessage([]byte(frigateNewEvent))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ev.ID != "1718000000.123-abc" || ev.Camera != "front-door" || ev.Label != "person" {
+		t.Errorf("identity = %+v", ev)
+	}
+	if ev.Type != EventNew {
+		t.Errorf("type = %q, want new", ev.Type)
+	}
+	if ev.SubLabel != "alice" {
+		t.Errorf("sub_label pair = %q, want alice", ev.SubLabel)
+	}
+	if ev.Score != 0.84 {
+		t.Errorf("score = %v, want top_score 0.84", ev.Score)
+	}
+	if want := time.Unix(1718000000, 500_000_000); !ev.Start.Equal(want) {
+		t.Errorf("start = %v, want %v", ev.Start, want)
+	}
+	if !ev.End.IsZero() {
+		t.Errorf("null end_time should leave End zero, got %v", ev.End)
+	}
+	if len(ev.Zones) != 2 || ev.Zones[0] != "driveway" {
+		t.Errorf("zones = %v, want entered zones", ev.Zones)
+	}
+	if !ev.HasSnapshot || ev.HasClip {
+		t.Errorf("has_snapshot/has_clip = %v/%v", ev.HasSnapshot, ev.HasClip)
+	}
+}
+
+func TestParseEventMessageRejectsIncomplete(t *testing.T) {
+	for _, payload := range []string{`not json`, `{"type":"new"}`, `{"type":"new","after":{"id":"x"}}`} {
+		if _, err := ParseEventMessage([]byte(payload)); err == nil {
+			t.Errorf("ParseEventMessage(%s) = nil error", payload)
+		}
+	}
+}
+
+func TestEventLinks(t *testing.T) {
+	snap, clip := EventLinks("https://100.64.0.5:8443/expose/frigate/", "e1")
+	if snap != "https://100.64.0.5:8443/expose/frigate/api/events/e1/snapshot.jpg" {
+		t.Errorf("snapshot = %q", snap)
+	}
+	if clip != "https://100.64.0.5:8443/expose/frigate/api/events/e1/clip.mp4" {
+		t.Errorf("clip = %q", clip)
+	}
+	if s, c := EventLinks("", "e1"); s != "" || c != "" {
+		t.Errorf("empty base should yield no links, got %q %q", s, c)
+	}
+}
+
+func TestEventsTopic(t *testing.T) {
+	if got := EventsTopic(""); got != "frigate/events" {
+		t.Errorf("default = %q", got)
+	}
+	if got := EventsTopic("cams"); got != "cams/events" {
+		t.Errorf("custom = %q", got)
+	}
+}
@@ -0,0 +1,237 @@
+package nvr
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// localIndexRel is the event index database. Like Frigate's own DB it must
+// stay on local disk (SQLite over NFS corrupts), so it never follows the media
+// storage target.
+const localIndexRel = ".citadel-cli/nvr/events.db"
+
+// IndexPath returns the absolute path of the event index database.
+func IndexPath() (string, error) { return homeJoin(localIndexRel) }
+
+const indexSchema = `
+CREATE TABLE IF NOT EXISTS nvr_events (
+    id             TEXT PRIMARY KEY,
+    camera         TEXT NOT NULL,
+    label          TEXT NOT NULL DEFAULT '',
+    sub_label      TEXT NOT NULL DEFAULT '',
+    score          REAL NOT NULL DEFAULT 0,
+    zones          TEXT NOT NULL DEFAULT '',
+    start_time     TEXT NOT NULL,
+    end_time       TEXT NOT NULL DEFAULT '',
+    has_clip       INTEGER NOT NULL DEFAULT 0,
+    has_snapshot   INTEGER NOT NULL DEFAULT 0,
+    false_positive INTEGER NOT NULL DEFAULT 0,
+    notified       TEXT NOT NULL DEFAULT '',
+    updated_at     TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_nvr_events_start ON nvr_events(start_time);
+CREATE INDEX IF NOT EXISTS idx_nvr_events_camera ON nvr_events(camera, start_time);
+`
+
+// IndexedEvent is an Event as recorded in the index.
+type IndexedEvent struct {
+	Event
+	// Notified lists the rules that pushed a notification for this event.
+	Notified []string
+	Updated  time.Time
+}
+
+// EventQuery filters Index.Query. Zero fields do not filter.
+type EventQuery struct {
+	Camera string
+	Label  string
+	Since  time.Time
+	Until  time.Time
+	// NotifiedOnly restricts results to events that produced a push.
+	NotifiedOnly bool
+	// Limit caps the result count (default 50).
+	Limit int
+}
+
+// Index is the SQLite-backed Frigate event index.
+type Index struct {
+	db *sql.DB
+}
+
+// OpenIndex opens (or creates) the event index at path.
+func OpenIndex(path string) (*Index, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("nvr: create index dir: %w", err)
+	}
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("nvr: open event index: %w", err)
+	}
+	// WAL so `citadel nvr events` can read while the watcher writes.
+	if _, err := db.Exec("PRAGMA journal_mode=WAL"); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("nvr: enable WAL: %w", err)
+	}
+	if _, err := db.Exec(indexSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("nvr: migrate event index: %w", err)
+	}
+	return &Index{db: db}, nil
+}
+
+// Close closes the database.
+func (x *Index) Close() error { return x.db.Close() }
+
+// Upsert records the latest state of an event. Frigate sends several messages
+// per event; each overwrites the previous state, keeping the notified set.
+func (x *Index) Upsert(ev Event, now time.Time) error {
+	_, err := x.db.Exec(`
+		INSERT INTO nvr_events (
+			id, camera, label, sub_label, score, zones,
+			start_time, end_time, has_clip, has_snapshot, false_positive, updated_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET
+			camera = excluded.camera,
+			label = excluded.label,
+			sub_label = excluded.sub_label,
+			score = excluded.score,
+			zones = excluded.zones,
+			start_time = excluded.start_time,
+			end_time = excluded.end_time,
+			has_clip = excluded.has_clip,
+			has_snapshot = excluded.has_snapshot,
+			false_positive = excluded.false_positive,
+			updated_at = excluded.updated_at`,
+		ev.ID, ev.Camera, ev.Label, ev.SubLabel, ev.Score, strings.Join(ev.Zones, ","),
+		formatIndexTime(ev.Start), formatIndexTime(ev.End),
+		ev.HasClip, ev.HasSnapshot, ev.FalsePositive, formatIndexTime(now),
+	)
+	if err != nil {
+		return fmt.Errorf("nvr: index event %s: %w", ev.ID, err)
+	}
+	return nil
+}
+
+// likeEscaper escapes the LIKE wildcards (and the escape character itself)
+// so a value matches literally under ESCAPE '\'.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// MarkNotified records that rule pushed a notification for event id.
+func (x *Index) MarkNotified(id, rule string) error {
+	_, err := x.db.Exec(`
+		UPDATE nvr_events
+		SET notified = CASE WHEN notified = '' THEN ? ELSE notified || ',' || ? END
+		WHERE id = ? AND (',' || notified || ',') NOT LIKE ('%,' || ? || ',%') ESCAPE '\'`,
+		rule, rule, id, likeEscaper.Replace(rule))
+	if err != nil {
+		return fmt.Errorf("nvr: mark event %s notified: %w", id, err)
+	}
+	return nil
+}
+
+// Query returns matching events, newest first.
+func (x *Index) Query(q EventQuery) ([]IndexedEvent, error) {
+	var where []string
+	var args []any
+	if q.Camera != "" {
+		where = append(where, "camera = ?")
+		args = append(args, q.Camera)
+	}
+	if q.Label != "" {
+		where = append(where, "label = ?")
+		args = append(args, q.Label)
+	}
+	if !q.Since.IsZero() {
+		where = append(where, "start_time >= ?")
+		args = append(args, formatIndexTime(q.Since))
+	}
+	if !q.Until.IsZero() {
+		where = append(where, "start_time < ?")
+		args = append(args, formatIndexTime(q.Until))
+	}
+	if q.NotifiedOnly {
+		where = append(where, "notified != ''")
+	}
+	limit := q.Limit
+	if limit <= 0 {
+		limit = 50
+	}
+	stmt := `
+		SELECT id, camera, label, sub_label, score, zones,
+		       start_time, end_time, has_clip, has_snapshot, false_positive,
+		       notified, updated_at
+		FROM nvr_events`
+	if len(where) > 0 {
+		stmt += " WHERE " + strings.Join(where, " AND ")
+	}
+	stmt += " ORDER BY start_time DESC LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := x.db.Query(stmt, args...)
+	if err != nil {
+		return nil, fmt.Errorf("nvr: query events: %w", err)
+	}
+	defer rows.Close()
+
+	var out []IndexedEvent
+	for rows.Next() {
+		var e IndexedEvent
+		var zones, start, end, notified, updated string
+		if err := rows.Scan(&e.ID, &e.Camera, &e.Label, &e.SubLabel, &e.Score, &zones,
+			&start, &end, &e.HasClip, &e.HasSnapshot, &e.FalsePositive,
+			&notified, &updated); err != nil {
+			return nil, fmt.Errorf("nvr: scan event: %w", err)
+		}
+		e.Zones = splitList(zones)
+		e.Notified = splitList(notified)
+		e.Start = parseIndexTime(start)
+		e.End = parseIndexTime(end)
+		e.Updated = parseIndexTime(updated)
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
+
+// PruneBefore deletes events that started before cutoff and returns how many
+// were removed.
+func (x *Index) PruneBefore(cutoff time.Time) (int64, error) {
+	res, err := x.db.Exec(`DELETE FROM nvr_events WHERE start_time < ?`, formatIndexTime(cutoff))
+	if err != nil {
+		return 0, fmt.Errorf("nvr: prune events: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+// Times are stored as fixed-width UTC RFC 3339 so string order is time order.
+const indexTimeLayout = "2006-01-02T15:04:05.000Z"
+
+func formatIndexTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(indexTimeLayout)
+}
+
+func parseIndexTime(s string) time.Time {
+	if s == "" {
+		return time.Time{}
+	}
+	t, err := time.Parse(indexTimeLayout, s)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+func splitList(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, ",")
+}
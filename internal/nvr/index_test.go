@@ -0,0 +1,140 @@
+package nvr
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func openTestIndex(t *testing.T) *Index {
+	t.Helper()
+	idx, err := OpenIndex(filepath.Join(t.TempDir(), "nvr", "events.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { idx.Close() })
+	return idx
+}
+
+func TestIndexUpsertKeepsLatestState(t *testing.T) {
+	idx := openTestIndex(t)
+	start := time.Unix(1_718_000_000, 0)
+	ev := Event{ID: "e1", Camera: "front-door", Label: "person", Score: 0.6, Start: start, Type: EventNew}
+	if err := idx.Upsert(ev, start); err != nil {
+		t.Fatal(err)
+	}
+	if err := idx.MarkNotified("e1", "night-person"); err != nil {
+		t.Fatal(err)
+	}
+	ev.Score, ev.End, ev.HasClip, ev.Zones = 0.9, start.Add(20*time.Second), true, []string{"porch"}
+	if err := idx.Upsert(ev, start.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	// Marking twice must not duplicate the rule.
+	if err := idx.MarkNotified("e1", "night-person"); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := idx.Query(EventQuery{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("events = %d, want 1", len(got))
+	}
+	e := got[0]
+	if e.Score != 0.9 || !e.HasClip || !e.End.Equal(ev.End) || len(e.Zones) != 1 {
+		t.Errorf("state not updated: %+v", e)
+	}
+	if len(e.Notified) != 1 || e.Notified[0] != "night-person" {
+		t.Errorf("notified = %v, want [night-person]", e.Notified)
+	}
+	if !e.Start.Equal(start) {
+		t.Errorf("start = %v, want %v", e.Start, start)
+	}
+}
+
+func TestIndexMarkNotifiedMatchesRuleLiterally(t *testing.T) {
+	idx := openTestIndex(t)
+	start := time.Unix(1_718_000_000, 0)
+	if err := idx.Upsert(Event{ID: "e1", Camera: "front-door", Start: start}, start); err != nil {
+		t.Fatal(err)
+	}
+	// Each rule's name is a LIKE pattern matching the one before it, so an
+	// unescaped comparison would take every later rule as already recorded.
+	rules := []string{"night_person", "night%", `a\b`, `a\%`}
+	for _, rule := range rules {
+		if err := idx.MarkNotified("e1", rule); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, err := idx.Query(EventQuery{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || strings.Join(got[0].Notified, "|") != strings.Join(rules, "|") {
+		t.Fatalf("notified = %q, want %q", got[0].Notified, rules)
+	}
+}
+
+func TestIndexQueryFilters(t *testing.T) {
+	idx := openTestIndex(t)
+	base := time.Unix(1_718_000_000, 0)
+	for i, ev := range []Event{
+		{ID: "a", Camera: "front-door", Label: "person"},
+		{ID: "b", Camera: "front-door", Label: "car"},
+		{ID: "c", Camera: "garage", Label: "person"},
+		{ID: "d", Camera: "front-door", Label: "person"},
+	} {
+		ev.Start = base.Add(time.Duration(i) * time.Hour)
+		if err := idx.Upsert(ev, ev.Start); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := idx.MarkNotified("a", "r"); err != nil {
+		t.Fatal(err)
+	}
+
+	ids := func(q EventQuery) []string {
+		t.Helper()
+		evs, err := idx.Query(q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out []string
+		for _, e := range evs {
+			out = append(out, e.ID)
+		}
+		return out
+	}
+	check := func(name string, got []string, want ...string) {
+		t.Helper()
+		if len(got) != len(want) {
+			t.Errorf("%s = %v, want %v", name, got, want)
+			return
+		}
+		for i := range got {
+			if got[i] != want[i] {
+				t.Errorf("%s = %v, want %v", name, got, want)
+				return
+			}
+		}
+	}
+
+	check("all newest first", ids(EventQuery{}), "d", "c", "b", "a")
+	check("camera+label", ids(EventQuery{Camera: "front-door", Label: "person"}), "d", "a")
+	check("since", ids(EventQuery{Since: base.Add(2 * time.Hour)}), "d", "c")
+	check("until", ids(EventQuery{Until: base.Add(time.Hour)}), "a")
+	check("notified", ids(EventQuery{NotifiedOnly: true}), "a")
+	check("limit", ids(EventQuery{Limit: 1}), "d")
+
+	n, err := idx.PruneBefore(base.Add(2 * time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("pruned %d, want 2", n)
+	}
+	check("after prune", ids(EventQuery{}), "d", "c")
+}
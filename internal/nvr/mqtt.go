@@ -0,0 +1,310 @@
+package nvr
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// A deliberately minimal MQTT 3.1.1 subscriber: CONNECT with credentials,
+// SUBSCRIBE at QoS 0, receive PUBLISH, keep alive with PINGREQ. That is all
+// the event watcher needs from the module's node-local broker — Frigate
+// publishes at QoS 0, citadel never publishes, and the broker sits on the
+// module's compose network, so TLS, QoS 1/2 and session state are out of
+// scope. It avoids pulling an MQTT library into the node binary for one
+// read-only subscription.
+
+// MQTT control packet types (MQTT 3.1.1 §2.2.1).
+const (
+	mqttConnect   = 1
+	mqttConnack   = 2
+	mqttPublish   = 3
+	mqttPuback    = 4
+	mqttSubscribe = 8
+	mqttSuback    = 9
+	mqttPingreq   = 12
+	mqttPingresp  = 13
+)
+
+// mqttMaxPacket bounds a single inbound packet. Frigate event payloads are a
+// few KB; anything far larger is a broken or hostile broker.
+const mqttMaxPacket = 1 << 20
+
+// MQTTMessage is one PUBLISH received on a subscription.
+type MQTTMessage struct {
+	Topic   string
+	Payload []byte
+}
+
+// MQTTOptions configures Subscribe.
+type MQTTOptions struct {
+	// Addr is the broker's host:port.
+	Addr string
+	// ClientID identifies this connection to the broker. Required: with a
+	// clean session the broker would accept an empty one, but a stable id
+	// makes the subscriber recognizable in the broker log.
+	ClientID string
+	// User and Password authenticate to the broker (the module's mosquitto
+	// rejects anonymous clients).
+	User     string
+	Password string
+	// KeepAlive is the ping interval negotiated with the broker (default 60s).
+	KeepAlive time.Duration
+	// DialTimeout bounds the TCP connect and CONNACK wait (default 10s).
+	DialTimeout time.Duration
+	// OnConnect, when set, is called once the broker has accepted the
+	// connection and the subscription has been sent.
+	OnConnect func()
+}
+
+// Subscribe connects to the broker, subscribes to topic (wildcards allowed)
+// and calls handle for every message until ctx is cancelled or the connection
+// fails. It always returns a non-nil error; ctx.Err() after a cancellation.
+// handle runs on the read loop, so a slow handler delays keepalive replies —
+// keep it short or hand off.
+func Subscribe(ctx context.Context, opts MQTTOptions, topic string, handle func(MQTTMessage)) error {
+	if opts.KeepAlive <= 0 {
+		opts.KeepAlive = 60 * time.Second
+	}
+	if opts.DialTimeout <= 0 {
+		opts.DialTimeout = 10 * time.Second
+	}
+	d := net.Dialer{Timeout: opts.DialTimeout}
+	conn, err := d.DialContext(ctx, "tcp", opts.Addr)
+	if err != nil {
+		return fmt.Errorf("nvr: mqtt connect %s: %w", opts.Addr, err)
+	}
+	defer conn.Close()
+
+	// Unblock the read loop on cancellation.
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	c := &mqttConn{conn: conn, r: bufio.NewReader(conn)}
+	conn.SetDeadline(time.Now().Add(opts.DialTimeout))
+	if err := c.write(mqttConnect<<4, connectBody(opts)); err != nil {
+		return c.wrap(ctx, "send CONNECT", err)
+	}
+	typ, body, err := c.read()
+	if err != nil {
+		return c.wrap(ctx, "read CONNACK", err)
+	}
+	if typ != mqttConnack || len(body) != 2 {
+		return fmt.Errorf("nvr: mqtt: expected CONNACK, got packet type %d", typ)
+	}
+	if code := body[1]; code != 0 {
+		return fmt.Errorf("nvr: mqtt: broker refused connection: %s", connackReason(code))
+	}
+
+	const subID = 1
+	sub := binary.BigEndian.AppendUint16(nil, subID)
+	sub = appendMQTTString(sub, topic)
+	sub = append(sub, 0) // requested QoS 0
+	if err := c.write(mqttSubscribe<<4|0x02, sub); err != nil {
+		return c.wrap(ctx, "send SUBSCRIBE", err)
+	}
+	conn.SetDeadline(time.Time{})
+	if opts.OnConnect != nil {
+		opts.OnConnect()
+	}
+
+	pingDone := make(chan struct{})
+	defer close(pingDone)
+	go func() {
+		t := time.NewTicker(opts.KeepAlive / 2)
+		defer t.Stop()
+		for {
+			select {
+			case <-pingDone:
+				return
+			case <-t.C:
+				if c.write(mqttPingreq<<4, nil) != nil {
+					conn.Close()
+					return
+				}
+			}
+		}
+	}()
+
+	for {
+		// The broker answers every PINGREQ, so silence past 1.5x the keepalive
+		// means the connection is dead even if TCP has not noticed.
+		conn.SetReadDeadline(time.Now().Add(opts.KeepAlive * 3 / 2))
+		typ, body, err := c.readWithFlags()
+		if err != nil {
+			return c.wrap(ctx, "read", err)
+		}
+		switch typ >> 4 {
+		case mqttPublish:
+			msg, id, err := parsePublish(typ&0x0f, body)
+			if err != nil {
+				return err
+			}
+			// The broker downgrades to our granted QoS 0, but acknowledge a
+			// QoS 1 delivery anyway rather than have it redelivered forever.
+			if id != 0 {
+				if err := c.write(mqttPuback<<4, binary.BigEndian.AppendUint16(nil, id)); err != nil {
+					return c.wrap(ctx, "send PUBACK", err)
+				}
+			}
+			handle(msg)
+		case mqttSuback:
+			if len(body) == 3 && body[2] == 0x80 {
+				return fmt.Errorf("nvr: mqtt: broker rejected subscription to %q", topic)
+			}
+		case mqttPingresp:
+		default:
+			// Nothing else is expected on a QoS 0 subscriber; ignore it.
+		}
+	}
+}
+
+// mqttConn serializes writes (the read loop and the pinger both send).
+type mqttConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+	mu   sync.Mutex
+}
+
+func (c *mqttConn) write(header byte, body []byte) error {
+	pkt := append([]byte{header}, appendRemainingLength(nil, len(body))...)
+	pkt = append(pkt, body...)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, err := c.conn.Write(pkt)
+	return err
+}
+
+// read returns the packet type (upper nibble only) and body.
+func (c *mqttConn) read() (byte, []byte, error) {
+	typ, body, err := c.readWithFlags()
+	return typ >> 4, body, err
+}
+
+// readWithFlags returns the full fixed-header byte and the body.
+func (c *mqttConn) readWithFlags() (byte, []byte, error) {
+	header, err := c.r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	n, err := readRemainingLength(c.r)
+	if err != nil {
+		return 0, nil, err
+	}
+	if n > mqttMaxPacket {
+		return 0, nil, fmt.Errorf("packet of %d bytes exceeds %d byte limit", n, mqttMaxPacket)
+	}
+	body := make([]byte, n)
+	if _, err := io.ReadFull(c.r, body); err != nil {
+		return 0, nil, err
+	}
+	return header, body, nil
+}
+
+func (c *mqttConn) wrap(ctx context.Context, op string, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return fmt.Errorf("nvr: mqtt %s: %w", op, err)
+}
+
+func connectBody(opts MQTTOptions) []byte {
+	b := appendMQTTString(nil, "MQTT")
+	b = append(b, 4)    // protocol level 3.1.1
+	flags := byte(0x02) // clean session
+	if opts.User != "" {
+		flags |= 0x80
+		if opts.Password != "" {
+			flags |= 0x40
+		}
+	}
+	b = append(b, flags)
+	b = binary.BigEndian.AppendUint16(b, uint16(opts.KeepAlive/time.Second))
+	b = appendMQTTString(b, opts.ClientID)
+	if opts.User != "" {
+		b = appendMQTTString(b, opts.User)
+		if opts.Password != "" {
+			b = appendMQTTString(b, opts.Password)
+		}
+	}
+	return b
+}
+
+func parsePublish(flags byte, body []byte) (MQTTMessage, uint16, error) {
+	if len(body) < 2 {
+		return MQTTMessage{}, 0, errors.New("nvr: mqtt: short PUBLISH")
+	}
+	tl := int(binary.BigEndian.Uint16(body))
+	if len(body) < 2+tl {
+		return MQTTMessage{}, 0, errors.New("nvr: mqtt: truncated PUBLISH topic")
+	}
+	msg := MQTTMessage{Topic: string(body[2 : 2+tl])}
+	rest := body[2+tl:]
+	var id uint16
+	if qos := (flags >> 1) & 0x03; qos > 0 {
+		if len(rest) < 2 {
+			return MQTTMessage{}, 0, errors.New("nvr: mqtt: PUBLISH missing packet id")
+		}
+		id = binary.BigEndian.Uint16(rest)
+		rest = rest[2:]
+	}
+	msg.Payload = rest
+	return msg, id, nil
+}
+
+func appendMQTTString(b []byte, s string) []byte {
+	b = binary.BigEndian.AppendUint16(b, uint16(len(s)))
+	return append(b, s...)
+}
+
+func appendRemainingLength(b []byte, n int) []byte {
+	for {
+		digit := byte(n % 128)
+		n /= 128
+		if n > 0 {
+			digit |= 0x80
+		}
+		b = append(b, digit)
+		if n == 0 {
+			return b
+		}
+	}
+}
+
+func readRemainingLength(r io.ByteReader) (int, error) {
+	n, mult := 0, 1
+	for i := 0; i < 4; i++ {
+		digit, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		n += int(digit&0x7f) * mult
+		if digit&0x80 == 0 {
+			return n, nil
+		}
+		mult *= 128
+	}
+	return 0, errors.New("malformed remaining length")
+}
+
+func connackReason(code byte) string {
+	switch code {
+	case 1:
+		return "unacceptable protocol version"
+	case 2:
+		return "client identifier rejected"
+	case 3:
+		return "server unavailable"
+	case 4:
+		return "bad user name or password"
+	case 5:
+		return "not authorized"
+	}
+	return fmt.Sprintf("return code %d", code)
+}
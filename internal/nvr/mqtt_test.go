@@ -0,0 +1,122 @@
+package nvr
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeBroker accepts one connection, checks CONNECT/SUBSCRIBE, then publishes
+// the given messages.
+func fakeBroker(t *testing.T, connackCode byte, publish []MQTTMessage) (addr string, gotConnect chan []byte) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	gotConnect = make(chan []byte, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		c := &mqttConn{conn: conn, r: bufio.NewReader(conn)}
+		typ, body, err := c.read()
+		if err != nil || typ != mqttConnect {
+			return
+		}
+		gotConnect <- body
+		c.write(mqttConnack<<4, []byte{0, connackCode})
+		if connackCode != 0 {
+			return
+		}
+		typ, body, err = c.read()
+		if err != nil || typ != mqttSubscribe {
+			return
+		}
+		c.write(mqttSuback<<4, append(body[:2:2], 0))
+		for _, m := range publish {
+			c.write(mqttPublish<<4, append(appendMQTTString(nil, m.Topic), m.Payload...))
+		}
+		// Hold the connection until the client goes away.
+		c.read()
+	}()
+	return ln.Addr().String(), gotConnect
+}
+
+func TestSubscribeReceivesPublishes(t *testing.T) {
+	addr, gotConnect := fakeBroker(t, 0, []MQTTMessage{
+		{Topic: "frigate/events", Payload: []byte(`{"a":1}`)},
+		{Topic: "frigate/events", Payload: []byte(`{"a":2}`)},
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var got []string
+	connected := false
+	err := Subscribe(ctx, MQTTOptions{
+		Addr: addr, ClientID: "test", User: "frigate", Password: "secret",
+		OnConnect: func() { connected = true },
+	}, "frigate/events", func(m MQTTMessage) {
+		got = append(got, string(m.Payload))
+		if len(got) == 2 {
+			cancel()
+		}
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Subscribe = %v, want context.Canceled", err)
+	}
+	if !connected {
+		t.Error("OnConnect not called")
+	}
+	if len(got) != 2 || got[0] != `{"a":1}` || got[1] != `{"a":2}` {
+		t.Errorf("payloads = %v", got)
+	}
+
+	body := <-gotConnect
+	if flags := body[7]; flags != 0xc2 {
+		t.Errorf("connect flags = %#x, want user+password+clean session", flags)
+	}
+	if ka := binary.BigEndian.Uint16(body[8:]); ka != 60 {
+		t.Errorf("keepalive = %d, want 60", ka)
+	}
+}
+
+func TestSubscribeRefused(t *testing.T) {
+	addr, _ := fakeBroker(t, 4, nil)
+	err := Subscribe(context.Background(), MQTTOptions{Addr: addr, ClientID: "test"}, "frigate/events", func(MQTTMessage) {})
+	if err == nil || !strings.Contains(err.Error(), "bad user name or password") {
+		t.Fatalf("Subscribe = %v, want credential refusal", err)
+	}
+}
+
+func TestParsePublishQoS1(t *testing.T) {
+	body := appendMQTTString(nil, "t")
+	body = binary.BigEndian.AppendUint16(body, 7)
+	body = append(body, "hi"...)
+	msg, id, err := parsePublish(0x02, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Topic != "t" || string(msg.Payload) != "hi" || id != 7 {
+		t.Errorf("got %+v id %d", msg, id)
+	}
+}
+
+func TestRemainingLengthRoundTrip(t *testing.T) {
+	for _, n := range []int{0, 127, 128, 16383, 16384, 2097151, 2097152} {
+		b := appendRemainingLength(nil, n)
+		got, err := readRemainingLength(bytes.NewReader(b))
+		if err != nil || got != n {
+			t.Errorf("round trip %d = %d, %v", n, got, err)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package nvr
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// localRulesRel is the node-local notification rules file (next to the
+// Frigate config dir, never inside it: Frigate owns /config).
+const localRulesRel = ".citadel-cli/nvr/rules.yaml"
+
+// DefaultExposureName is the gateway exposure the Frigate UI is conventionally
+// published under (`citadel service expose frigate --port 8212`). Notification
+// links point through it.
+const DefaultExposureName = "frigate"
+
+// defaultRuleCooldown suppresses repeat pushes for the same rule and camera;
+// a person pacing past a doorway is several Frigate events in a minute.
+const defaultRuleCooldown = 5 * time.Minute
+
+// RulesPath returns the absolute path of the notification rules file.
+func RulesPath() (string, error) { return homeJoin(localRulesRel) }
+
+// RuleSet is the parsed rules file.
+type RuleSet struct {
+	// Exposure names the gateway exposure of the Frigate UI that snapshot and
+	// clip links go through. Defaults to DefaultExposureName.
+	Exposure string `yaml:"exposure"`
+	Rules    []Rule `yaml:"rules"`
+}
+
+// Rule decides which Frigate events become a push. Empty Cameras, Labels and
+// Zones match anything; all non-empty constraints must hold.
+type Rule struct {
+	Name    string   `yaml:"name"`
+	Cameras []string `yaml:"cameras"`
+	Labels  []string `yaml:"labels"`
+	// Zones matches when the object entered any of these Frigate zones.
+	Zones []string `yaml:"zones"`
+	// MinScore is the minimum best detection score (0..1).
+	MinScore float64 `yaml:"min_score"`
+	// From and To bound a daily window in the node's local time, as "HH:MM".
+	// A window whose To is earlier than its From wraps midnight
+	// ("22:00"-"06:00" is overnight). Both empty means all day.
+	From string `yaml:"from"`
+	To   string `yaml:"to"`
+	// Cooldown is the minimum gap between pushes for this rule on one camera
+	// (default 5m; "0s" disables).
+	Cooldown *Duration `yaml:"cooldown"`
+	// Title overrides the push title (default "<Label> on <camera>").
+	Title string `yaml:"title"`
+
+	from, to int // minutes since midnight; -1 = unbounded
+}
+
+// Duration is a time.Duration that unmarshals from "5m"-style YAML strings.
+type Duration struct{ time.Duration }
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (d *Duration) UnmarshalYAML(n *yaml.Node) error {
+	v, err := time.ParseDuration(n.Value)
+	if err != nil {
+		return fmt.Errorf("invalid duration %q: %w", n.Value, err)
+	}
+	d.Duration = v
+	return nil
+}
+
+// LoadRules reads the rules file at path. A missing file yields an empty set
+// (the watcher still indexes events, it just never pushes).
+func LoadRules(path string) (*RuleSet, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &RuleSet{Exposure: DefaultExposureName}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("nvr: read rules: %w", err)
+	}
+	return ParseRules(data)
+}
+
+// ParseRules parses and validates a rules document.
+func ParseRules(data []byte) (*RuleSet, error) {
+	var rs RuleSet
+	if err := yaml.Unmarshal(data, &rs); err != nil {
+		return nil, fmt.Errorf("nvr: parse rules: %w", err)
+	}
+	if rs.Exposure == "" {
+		rs.Exposure = DefaultExposureName
+	}
+	seen := make(map[string]bool)
+	for i := range rs.Rules {
+		r := &rs.Rules[i]
+		if r.Name == "" {
+			r.Name = fmt.Sprintf("rule-%d", i+1)
+		}
+		if seen[r.Name] {
+			return nil, fmt.Errorf("nvr: rules: duplicate rule name %q", r.Name)
+		}
+		seen[r.Name] = true
+		if r.MinScore < 0 || r.MinScore > 1 {
+			return nil, fmt.Errorf("nvr: rule %q: min_score must be between 0 and 1", r.Name)
+		}
+		if (r.From == "") != (r.To == "") {
+			return nil, fmt.Errorf("nvr: rule %q: from and to must be set together", r.Name)
+		}
+		r.from, r.to = -1, -1
+		if r.From != "" {
+			var err error
+			if r.from, err = parseClock(r.From); err != nil {
+				return nil, fmt.Errorf("nvr: rule %q: from: %w", r.Name, err)
+			}
+			if r.to, err = parseClock(r.To); err != nil {
+				return nil, fmt.Errorf("nvr: rule %q: to: %w", r.Name, err)
+			}
+		}
+	}
+	return &rs, nil
+}
+
+// parseClock parses "HH:MM" into minutes since midnight.
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q (want HH:MM)", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// CooldownOrDefault returns the rule's effective cooldown.
+func (r *Rule) CooldownOrDefault() time.Duration {
+	if r.Cooldown == nil {
+		return defaultRuleCooldown
+	}
+	return r.Cooldown.Duration
+}
+
+// Matches reports whether ev satisfies every constraint of the rule, with the
+// time window evaluated at at (in at's location). False positives never match.
+func (r *Rule) Matches(ev Event, at time.Time) bool {
+	if ev.FalsePositive {
+		return false
+	}
+	if len(r.Cameras) > 0 && !containsFold(r.Cameras, ev.Camera) {
+		return false
+	}
+	if len(r.Labels) > 0 && !containsFold(r.Labels, ev.Label) {
+		return false
+	}
+	if len(r.Zones) > 0 && !anyFold(r.Zones, ev.Zones) {
+		return false
+	}
+	if ev.Score < r.MinScore {
+		return false
+	}
+	return r.inWindow(at)
+}
+
+func (r *Rule) inWindow(at time.Time) bool {
+	if r.from < 0 || r.from == r.to {
+		return true
+	}
+	m := at.Hour()*60 + at.Minute()
+	if r.from < r.to {
+		return m >= r.from && m < r.to
+	}
+	// Wraps midnight.
+	return m >= r.from || m < r.to
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func anyFold(want, have []string) bool {
+	for _, h := range have {
+		if containsFold(want, h) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,129 @@
+package nvr
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const testRules = `
+rules:
+  - name: night-person
+    cameras: [front-door]
+    labels: [person]
+    min_score: 0.7
+    from: "22:00"
+    to: "06:00"
+  - name: driveway-car
+    labels: [car]
+    zones: [driveway]
+    cooldown: 0s
+`
+
+func at(hour, min int) time.Time { return time.Date(2026, 1, 2, hour, min, 0, 0, time.UTC) }
+
+func TestParseRules(t *testing.T) {
+	rs, err := ParseRules([]byte(testRules))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rs.Exposure != DefaultExposureName {
+		t.Errorf("exposure = %q, want default", rs.Exposure)
+	}
+	if len(rs.Rules) != 2 {
+		t.Fatalf("rules = %d", len(rs.Rules))
+	}
+	if got := rs.Rules[0].CooldownOrDefault(); got != defaultRuleCooldown {
+		t.Errorf("unset cooldown = %v, want default", got)
+	}
+	if got := rs.Rules[1].CooldownOrDefault(); got != 0 {
+		t.Errorf("explicit 0s cooldown = %v", got)
+	}
+}
+
+func TestRuleMatchesOvernightWindow(t *testing.T) {
+	rs, err := ParseRules([]byte(testRules))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &rs.Rules[0]
+	ev := Event{Camera: "Front-Door", Label: "person", Score: 0.8}
+	for _, tc := range []struct {
+		at   time.Time
+		want bool
+	}{
+		{at(23, 30), true},
+		{at(2, 0), true},
+		{at(5, 59), true},
+		{at(6, 0), false},
+		{at(12, 0), false},
+		{at(22, 0), true},
+	} {
+		if got := r.Matches(ev, tc.at); got != tc.want {
+			t.Errorf("Matches at %s = %v, want %v", tc.at.Format("15:04"), got, tc.want)
+		}
+	}
+
+	if r.Matches(Event{Camera: "garage", Label: "person", Score: 0.9}, at(23, 0)) {
+		t.Error("other camera matched")
+	}
+	if r.Matches(Event{Camera: "front-door", Label: "person", Score: 0.5}, at(23, 0)) {
+		t.Error("low score matched")
+	}
+	if r.Matches(Event{Camera: "front-door", Label: "person", Score: 0.9, FalsePositive: true}, at(23, 0)) {
+		t.Error("false positive matched")
+	}
+}
+
+func TestRuleMatchesZones(t *testing.T) {
+	rs, err := ParseRules([]byte(testRules))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &rs.Rules[1]
+	if !r.Matches(Event{Camera: "any", Label: "car", Zones: []string{"street", "driveway"}}, at(12, 0)) {
+		t.Error("car entering driveway should match")
+	}
+	if r.Matches(Event{Camera: "any", Label: "car", Zones: []string{"street"}}, at(12, 0)) {
+		t.Error("car outside the zone matched")
+	}
+}
+
+func TestParseRulesRejectsInvalid(t *testing.T) {
+	for name, doc := range map[string]string{
+		"half window":  "rules:\n  - name: a\n    from: \"22:00\"\n",
+		"bad clock":    "rules:\n  - name: a\n    from: \"25:00\"\n    to: \"06:00\"\n",
+		"bad score":    "rules:\n  - name: a\n    min_score: 70\n",
+		"duplicate":    "rules:\n  - name: a\n  - name: a\n",
+		"bad cooldown": "rules:\n  - name: a\n    cooldown: soon\n",
+	} {
+		if _, err := ParseRules([]byte(doc)); err == nil {
+			t.Errorf("%s: ParseRules succeeded", name)
+		}
+	}
+}
+
+func TestLoadRulesMissingFile(t *testing.T) {
+	rs, err := LoadRules(filepath.Join(t.TempDir(), "rules.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rs.Rules) != 0 || rs.Exposure != DefaultExposureName {
+		t.Errorf("missing file = %+v, want empty default set", rs)
+	}
+}
+
+func TestLoadRulesFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	if err := os.WriteFile(path, []byte("exposure: cams\n"+testRules), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	rs, err := LoadRules(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rs.Exposure != "cams" || len(rs.Rules) != 2 {
+		t.Errorf("loaded %+v", rs)
+	}
+}
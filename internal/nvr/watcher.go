@@ -0,0 +1,291 @@
+package nvr
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/aceteam-ai/citadel-cli/internal/notify"
+)
+
+// Event watcher: the consuming half of the module's MQTT egress (#637). It
+// subscribes to Frigate's events topic on the node-local broker, records every
+// event in the index, and turns events that match a notification rule into an
+// org push with the event snapshot attached and a tap-through link to the
+// clip. Links go through the gateway exposure of the Frigate UI, so they only
+// open for callers the exposure admits; without an exposure the push is sent
+// without links rather than pointing at an unreachable host port.
+
+// Notifier delivers a push. *notify.Client satisfies it.
+type Notifier interface {
+	Send(ctx context.Context, n notify.Notification) (*notify.Result, error)
+}
+
+// DefaultEventRetention bounds how long the index keeps events. Frigate's own
+// retention (NVR_RETENTION_DAYS, 12 by default) decides how long the clips
+// behind them exist; the index outliving the clips is harmless.
+const DefaultEventRetention = 30 * 24 * time.Hour
+
+// WatcherConfig configures a Watcher.
+type WatcherConfig struct {
+	// BrokerAddr resolves the broker's host:port. It is called before every
+	// connection attempt because the broker's compose-network address changes
+	// when its container is recreated.
+	BrokerAddr func(ctx context.Context) (string, error)
+	// User and Password authenticate to the broker.
+	User     string
+	Password string
+	// TopicPrefix is Frigate's MQTT topic prefix (default "frigate").
+	TopicPrefix string
+	// ClientID identifies the subscriber to the broker (default "citadel-nvr").
+	ClientID string
+
+	Index *Index
+	// RulesPath is re-read whenever it changes, so rule edits apply without
+	// restarting the worker. Empty disables notifications.
+	RulesPath string
+	// LinkBase returns the base URL of the Frigate exposure for the named
+	// exposure, or "" when none is reachable. Called per push.
+	LinkBase func(exposure string) string
+	// Notifier sends pushes; nil disables notifications (index only).
+	Notifier Notifier
+
+	// Retention is how long indexed events are kept (default
+	// DefaultEventRetention).
+	Retention time.Duration
+	// Location is the zone rule windows are evaluated in (default time.Local).
+	Location *time.Location
+
+	Logf func(format string, args ...any)
+	// Now is the clock (tests override it).
+	Now func() time.Time
+}
+
+// Watcher consumes Frigate events. Create with NewWatcher, then Run.
+type Watcher struct {
+	cfg WatcherConfig
+
+	mu        sync.Mutex
+	rules     *RuleSet
+	rulesMod  time.Time
+	lastPush  map[string]time.Time // rule + "\x00" + camera
+	pushedFor map[string]time.Time // event id + "\x00" + rule
+	sends     sync.WaitGroup
+}
+
+// NewWatcher validates cfg and returns a Watcher.
+func NewWatcher(cfg WatcherConfig) (*Watcher, error) {
+	if cfg.BrokerAddr == nil {
+		return nil, fmt.Errorf("nvr: watcher needs a broker address resolver")
+	}
+	if cfg.Index == nil {
+		return nil, fmt.Errorf("nvr: watcher needs an event index")
+	}
+	if cfg.ClientID == "" {
+		cfg.ClientID = "citadel-nvr"
+	}
+	if cfg.Retention <= 0 {
+		cfg.Retention = DefaultEventRetention
+	}
+	if cfg.Location == nil {
+		cfg.Location = time.Local
+	}
+	if cfg.Logf == nil {
+		cfg.Logf = func(string, ...any) {}
+	}
+	if cfg.Now == nil {
+		cfg.Now = time.Now
+	}
+	return &Watcher{
+		cfg:       cfg,
+		lastPush:  make(map[string]time.Time),
+		pushedFor: make(map[string]time.Time),
+	}, nil
+}
+
+// Run subscribes and processes events until ctx is cancelled, reconnecting
+// with backoff when the broker is unreachable (the module may not be running
+// yet, or is being restarted by a reconcile).
+func (w *Watcher) Run(ctx context.Context) {
+	defer w.sends.Wait()
+	topic := EventsTopic(w.cfg.TopicPrefix)
+	backoff := 5 * time.Second
+	const maxBackoff = 2 * time.Minute
+	var lastErr string
+	w.prune()
+	lastPrune := w.cfg.Now()
+	for ctx.Err() == nil {
+		addr, err := w.cfg.BrokerAddr(ctx)
+		if err == nil {
+			err = Subscribe(ctx, MQTTOptions{
+				Addr:     addr,
+				ClientID: w.cfg.ClientID,
+				User:     w.cfg.User,
+				Password: w.cfg.Password,
+				OnConnect: func() {
+					backoff = 5 * time.Second
+					lastErr = ""
+					w.cfg.Logf("nvr: watching %s on %s", topic, addr)
+				},
+			}, topic, func(m MQTTMessage) {
+				w.HandleMessage(ctx, m.Payload)
+				if now := w.cfg.Now(); now.Sub(lastPrune) >= 24*time.Hour {
+					w.prune()
+					lastPrune = now
+				}
+			})
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		// Log each distinct failure once: a module that is not installed
+		// would otherwise log every backoff interval forever.
+		if msg := err.Error(); msg != lastErr {
+			w.cfg.Logf("nvr: event watcher: %v (retrying)", err)
+			lastErr = msg
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxBackoff)
+	}
+}
+
+func (w *Watcher) prune() {
+	n, err := w.cfg.Index.PruneBefore(w.cfg.Now().Add(-w.cfg.Retention))
+	if err != nil {
+		w.cfg.Logf("nvr: %v", err)
+	} else if n > 0 {
+		w.cfg.Logf("nvr: pruned %d indexed events older than %s", n, w.cfg.Retention)
+	}
+}
+
+// HandleMessage indexes one events-topic payload and starts a push for every
+// rule it newly matches. Pushes are sent asynchronously so a slow backend
+// never stalls the MQTT read loop.
+func (w *Watcher) HandleMessage(ctx context.Context, payload []byte) {
+	ev, err := ParseEventMessage(payload)
+	if err != nil {
+		w.cfg.Logf("%v", err)
+		return
+	}
+	now := w.cfg.Now()
+	if err := w.cfg.Index.Upsert(ev, now); err != nil {
+		w.cfg.Logf("%v", err)
+	}
+	if w.cfg.Notifier == nil {
+		return
+	}
+	rules := w.loadRules()
+	if rules == nil {
+		return
+	}
+	// Windows are judged by when the object appeared, not when the message
+	// arrived: an update minutes later is still the same 05:58 sighting.
+	at := ev.Start
+	if at.IsZero() {
+		at = now
+	}
+	at = at.In(w.cfg.Location)
+	for i := range rules.Rules {
+		r := &rules.Rules[i]
+		if !r.Matches(ev, at) || !w.claim(ev, r, now) {
+			continue
+		}
+		n := w.notification(ev, r, rules.Exposure, at)
+		w.sends.Add(1)
+		go func(rule string) {
+			defer w.sends.Done()
+			sendCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 20*time.Second)
+			defer cancel()
+			if _, err := w.cfg.Notifier.Send(sendCtx, n); err != nil {
+				w.cfg.Logf("nvr: push for event %s (rule %s): %v", ev.ID, rule, err)
+				return
+			}
+			if err := w.cfg.Index.MarkNotified(ev.ID, rule); err != nil {
+				w.cfg.Logf("%v", err)
+			}
+		}(r.Name)
+	}
+}
+
+// claim reports whether a push for (ev, r) should be sent now and records it:
+// each rule pushes at most once per event, and at most once per cooldown per
+// camera.
+func (w *Watcher) claim(ev Event, r *Rule, now time.Time) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	eventKey := ev.ID + "\x00" + r.Name
+	if _, done := w.pushedFor[eventKey]; done {
+		return false
+	}
+	camKey := r.Name + "\x00" + ev.Camera
+	if last, ok := w.lastPush[camKey]; ok && now.Sub(last) < r.CooldownOrDefault() {
+		return false
+	}
+	w.pushedFor[eventKey] = now
+	w.lastPush[camKey] = now
+	// Frigate events rarely outlive an hour; forget older claims so the map
+	// stays bounded on a busy camera.
+	for k, t := range w.pushedFor {
+		if now.Sub(t) > time.Hour {
+			delete(w.pushedFor, k)
+		}
+	}
+	return true
+}
+
+// loadRules returns the current rule set, re-reading the file when its mtime
+// changes. A broken edit keeps the previous rules in force.
+func (w *Watcher) loadRules() *RuleSet {
+	if w.cfg.RulesPath == "" {
+		return nil
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var mod time.Time
+	if fi, err := os.Stat(w.cfg.RulesPath); err == nil {
+		mod = fi.ModTime()
+	}
+	if w.rules != nil && mod.Equal(w.rulesMod) {
+		return w.rules
+	}
+	rs, err := LoadRules(w.cfg.RulesPath)
+	if err != nil {
+		w.cfg.Logf("%v (keeping previous rules)", err)
+		return w.rules
+	}
+	w.rules, w.rulesMod = rs, mod
+	return rs
+}
+
+func (w *Watcher) notification(ev Event, r *Rule, exposure string, at time.Time) notify.Notification {
+	title := r.Title
+	if title == "" {
+		title = fmt.Sprintf("%s on %s", titleCase(ev.Label), ev.Camera)
+	}
+	body := fmt.Sprintf("Detected at %s (%.0f%%)", at.Format("15:04:05"), ev.Score*100)
+	if len(ev.Zones) > 0 {
+		body += " in " + strings.Join(ev.Zones, ", ")
+	}
+	n := notify.Notification{Title: title, Body: body, Target: notify.TargetNodes}
+	if w.cfg.LinkBase != nil {
+		n.ImageURL, n.URL = EventLinks(w.cfg.LinkBase(exposure), ev.ID)
+	}
+	return n
+}
+
+func titleCase(s string) string {
+	if s == "" {
+		return "Object"
+	}
+	r := []rune(s)
+	r[0] = unicode.ToUpper(r[0])
+	return string(r)
+}
@@ -0,0 +1,170 @@
+package nvr
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aceteam-ai/citadel-cli/internal/notify"
+)
+
+type fakeNotifier struct {
+	mu   sync.Mutex
+	sent []notify.Notification
+}
+
+func (f *fakeNotifier) Send(_ context.Context, n notify.Notification) (*notify.Result, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sent = append(f.sent, n)
+	return &notify.Result{Accepted: true, StatusCode: 200}, nil
+}
+
+func (f *fakeNotifier) count() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.sent)
+}
+
+// eventPayload builds a Frigate events message for a person on front-door
+// that started at 23:10 UTC.
+func eventPayload(id, typ string, score float64) []byte {
+	start := time.Date(2026, 1, 2, 23, 10, 0, 0, time.UTC).Unix()
+	return []byte(fmt.Sprintf(`{"type":%q,"after":{"id":%q,"camera":"front-door","label":"person",`+
+		`"top_score":%v,"start_time":%d,"has_snapshot":true,"entered_zones":["porch"]}}`, typ, id, score, start))
+}
+
+func newTestWatcher(t *testing.T, rules string) (*Watcher, *fakeNotifier, *Index, *time.Time) {
+	t.Helper()
+	dir := t.TempDir()
+	rulesPath := filepath.Join(dir, "rules.yaml")
+	if err := os.WriteFile(rulesPath, []byte(rules), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	idx := openTestIndex(t)
+	n := &fakeNotifier{}
+	now := time.Date(2026, 1, 2, 23, 10, 5, 0, time.UTC)
+	w, err := NewWatcher(WatcherConfig{
+		BrokerAddr: func(context.Context) (string, error) { return "", fmt.Errorf("unused") },
+		Index:      idx,
+		RulesPath:  rulesPath,
+		LinkBase:   func(exposure string) string { return "https://100.64.0.5:8443/expose/" + exposure },
+		Notifier:   n,
+		Location:   time.UTC,
+		Now:        func() time.Time { return now },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return w, n, idx, &now
+}
+
+const nightRule = `
+rules:
+  - name: night-person
+    cameras: [front-door]
+    labels: [person]
+    min_score: 0.7
+    from: "22:00"
+    to: "06:00"
+`
+
+func TestWatcherPushesOncePerEvent(t *testing.T) {
+	w, n, idx, _ := newTestWatcher(t, nightRule)
+	ctx := context.Background()
+
+	// Below min_score on "new": indexed, no push.
+	w.HandleMessage(ctx, eventPayload("e1", EventNew, 0.6))
+	// The score crosses the threshold on an update: push.
+	w.HandleMessage(ctx, eventPayload("e1", EventUpdate, 0.85))
+	// Later updates of the same event: no repeat.
+	w.HandleMessage(ctx, eventPayload("e1", EventEnd, 0.9))
+	w.sends.Wait()
+
+	if n.count() != 1 {
+		t.Fatalf("pushes = %d, want 1", n.count())
+	}
+	p := n.sent[0]
+	if p.Title != "Person on front-door" {
+		t.Errorf("title = %q", p.Title)
+	}
+	if p.Body != "Detected at 23:10:00 (85%) in porch" {
+		t.Errorf("body = %q", p.Body)
+	}
+	if p.ImageURL != "https://100.64.0.5:8443/expose/frigate/api/events/e1/snapshot.jpg" {
+		t.Errorf("image url = %q", p.ImageURL)
+	}
+	if p.URL != "https://100.64.0.5:8443/expose/frigate/api/events/e1/clip.mp4" {
+		t.Errorf("url = %q", p.URL)
+	}
+
+	evs, err := idx.Query(EventQuery{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(evs) != 1 || evs[0].Score != 0.9 || len(evs[0].Notified) != 1 {
+		t.Errorf("index = %+v, want one event at 0.9 marked notified", evs)
+	}
+}
+
+func TestWatcherCooldownPerCamera(t *testing.T) {
+	w, n, _, now := newTestWatcher(t, nightRule)
+	ctx := context.Background()
+
+	w.HandleMessage(ctx, eventPayload("e1", EventNew, 0.9))
+	*now = now.Add(time.Minute)
+	w.HandleMessage(ctx, eventPayload("e2", EventNew, 0.9))
+	w.sends.Wait()
+	if n.count() != 1 {
+		t.Fatalf("pushes inside cooldown = %d, want 1", n.count())
+	}
+
+	*now = now.Add(defaultRuleCooldown)
+	w.HandleMessage(ctx, eventPayload("e3", EventNew, 0.9))
+	w.sends.Wait()
+	if n.count() != 2 {
+		t.Errorf("pushes after cooldown = %d, want 2", n.count())
+	}
+}
+
+func TestWatcherOutsideWindowIndexesOnly(t *testing.T) {
+	w, n, idx, _ := newTestWatcher(t, `
+rules:
+  - name: day-person
+    labels: [person]
+    from: "08:00"
+    to: "18:00"
+`)
+	w.HandleMessage(context.Background(), eventPayload("e1", EventNew, 0.9))
+	w.sends.Wait()
+	if n.count() != 0 {
+		t.Errorf("pushes = %d, want 0 outside the window", n.count())
+	}
+	if evs, _ := idx.Query(EventQuery{}); len(evs) != 1 {
+		t.Errorf("indexed %d events, want 1", len(evs))
+	}
+}
+
+func TestWatcherReloadsRules(t *testing.T) {
+	w, n, _, _ := newTestWatcher(t, "rules: []\n")
+	ctx := context.Background()
+	w.HandleMessage(ctx, eventPayload("e1", EventNew, 0.9))
+
+	if err := os.WriteFile(w.cfg.RulesPath, []byte(nightRule), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	// Make the edit visible even on filesystems with coarse mtimes.
+	future := time.Now().Add(time.Hour)
+	if err := os.Chtimes(w.cfg.RulesPath, future, future); err != nil {
+		t.Fatal(err)
+	}
+	w.HandleMessage(ctx, eventPayload("e2", EventNew, 0.9))
+	w.sends.Wait()
+	if n.count() != 1 {
+		t.Errorf("pushes = %d, want 1 after the rule was added", n.count())
+	}
+}
@@ -2,7 +2,9 @@
 package platform
 
 import (
+	"context"
 	"fmt"
+	"os"
 	"os/exec"
 	"runtime"
 	"strings"
@@ -11,26 +13,32 @@ import (
 // CopyToClipboard copies the given text to the system clipboard.
 // Returns nil on success, or an error if clipboard access failed.
 func CopyToClipboard(text string) error {
+	return CopyToClipboardContext(context.Background(), text)
+}
+
+// CopyToClipboardContext is CopyToClipboard with the clipboard utility bound
+// to ctx, so a tool stuck on an unresponsive display is killed.
+func CopyToClipboardContext(ctx context.Context, text string) error {
 	var cmd *exec.Cmd
 
 	switch runtime.GOOS {
 	case "darwin":
-		cmd = exec.Command("pbcopy")
+		cmd = exec.CommandContext(ctx, "pbcopy")
 	case "linux":
 		// Try different clipboard utilities in order of preference
 		if isCommandAvailable("xclip") {
-			cmd = exec.Command("xclip", "-selection", "clipboard")
+			cmd = exec.CommandContext(ctx, "xclip", "-selection", "clipboard")
 		} else if isCommandAvailable("xsel") {
-			cmd = exec.Command("xsel", "--clipboard", "--input")
+			cmd = exec.CommandContext(ctx, "xsel", "--clipboard", "--input")
 		} else if isCommandAvailable("wl-copy") {
 			// Wayland clipboard
-			cmd = exec.Command("wl-copy")
+			cmd = exec.CommandContext(ctx, "wl-copy")
 		} else {
 			return fmt.Errorf("no clipboard utility found (install xclip, xsel, or wl-copy)")
 		}
 	case "windows":
 		// Use PowerShell's Set-Clipboard cmdlet
-		cmd = exec.Command("powershell", "-command", "Set-Clipboard", "-Value", text)
+		cmd = exec.CommandContext(ctx, "powershell", "-command", "Set-Clipboard", "-Value", text)
 	default:
 		return fmt.Errorf("clipboard not supported on %s", runtime.GOOS)
 	}
@@ -39,6 +47,7 @@ func CopyToClipboard(text string) error {
 	if runtime.GOOS != "windows" {
 		cmd.Stdin = strings.NewReader(text)
 	}
+	cmd.Env = clipboardEnv()
 
 	if err := cmd.Run(); err != nil {
 		return fmt.Errorf("failed to copy to clipboard: %w", err)
@@ -46,3 +55,56 @@ func CopyToClipboard(text string) error {
 
 	return nil
 }
+
+// ReadClipboard returns the current text contents of the system clipboard.
+func ReadClipboard() (string, error) {
+	return ReadClipboardContext(context.Background())
+}
+
+// ReadClipboardContext is ReadClipboard with the clipboard utility bound to
+// ctx, so a tool stuck on an unresponsive display is killed.
+func ReadClipboardContext(ctx context.Context) (string, error) {
+	var cmd *exec.Cmd
+
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.CommandContext(ctx, "pbpaste")
+	case "linux":
+		if isCommandAvailable("xclip") {
+			cmd = exec.CommandContext(ctx, "xclip", "-selection", "clipboard", "-o")
+		} else if isCommandAvailable("xsel") {
+			cmd = exec.CommandContext(ctx, "xsel", "--clipboard", "--output")
+		} else if isCommandAvailable("wl-paste") {
+			cmd = exec.CommandContext(ctx, "wl-paste", "--no-newline")
+		} else {
+			return "", fmt.Errorf("no clipboard utility found (install xclip, xsel, or wl-paste)")
+		}
+	case "windows":
+		cmd = exec.CommandContext(ctx, "powershell", "-command", "Get-Clipboard", "-Raw")
+	default:
+		return "", fmt.Errorf("clipboard not supported on %s", runtime.GOOS)
+	}
+	cmd.Env = clipboardEnv()
+
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to read clipboard: %w", err)
+	}
+	return string(out), nil
+}
+
+// clipboardEnv returns the environment for clipboard utilities. On Linux a
+// process started by a service manager has no DISPLAY, so the active
+// session's display and cookie are resolved the same way screen capture does.
+// Elsewhere it returns nil, which makes exec inherit the current environment.
+func clipboardEnv() []string {
+	if runtime.GOOS != "linux" || os.Getenv("DISPLAY") != "" || os.Getenv("WAYLAND_DISPLAY") != "" {
+		return nil
+	}
+	display, xauthority := ResolveX11Env()
+	env := append(os.Environ(), "DISPLAY="+display)
+	if xauthority != "" {
+		env = append(env, "XAUTHORITY="+xauthority)
+	}
+	return env
+}
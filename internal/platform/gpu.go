@@ -1,6 +1,7 @@
 package platform
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
@@ -18,6 +19,8 @@ type GPUInfo struct {
 	Temperature string
 	Utilization string
 	Driver      string
+	// Vendor is GPUVendorNVIDIA or GPUVendorAMD when known, "" otherwise.
+	Vendor string
 }
 
 // GPUDetector interface defines operations for GPU detection
@@ -124,7 +127,8 @@ func GetGPUDetector() (GPUDetector, error) {
 	}
 }
 
-// LinuxGPUDetector implements GPUDetector for Linux systems (NVIDIA)
+// LinuxGPUDetector implements GPUDetector for Linux systems: NVIDIA through
+// nvidia-smi, falling back to AMD through rocm-smi.
 type LinuxGPUDetector struct{}
 
 func (l *LinuxGPUDetector) HasGPU() bool {
@@ -136,13 +140,23 @@ func (l *LinuxGPUDetector) HasGPU() bool {
 
 	// Check using nvidia-smi
 	cmd = exec.Command("nvidia-smi")
-	return cmd.Run() == nil
+	if cmd.Run() == nil {
+		return true
+	}
+
+	// AMD: rocm-smi must list a GPU. lspci alone is not enough here, since it
+	// also matches the integrated Radeon graphics of a Ryzen APU.
+	_, err := QueryROCmGPUs(context.Background())
+	return err == nil
 }
 
 func (l *LinuxGPUDetector) GetGPUCount() int {
 	cmd := exec.Command("nvidia-smi", "--query-gpu=name", "--format=csv,noheader")
 	output, err := cmd.Output()
 	if err != nil {
+		if gpus, rerr := QueryROCmGPUs(context.Background()); rerr == nil {
+			return len(gpus)
+		}
 		return 0
 	}
 
@@ -159,6 +173,9 @@ func (l *LinuxGPUDetector) GetGPUInfo() ([]GPUInfo, error) {
 
 	output, err := cmd.Output()
 	if err != nil {
+		if gpus, rerr := QueryROCmGPUs(context.Background()); rerr == nil {
+			return gpuInfoFromROCm(gpus), nil
+		}
 		return nil, fmt.Errorf("failed to query NVIDIA GPUs: %w", err)
 	}
 
@@ -178,6 +195,7 @@ func (l *LinuxGPUDetector) GetGPUInfo() ([]GPUInfo, error) {
 			Temperature: strings.TrimSpace(parts[3]) + "°C",
 			Utilization: strings.TrimSpace(parts[4]) + "%",
 			Driver:      strings.TrimSpace(parts[5]),
+			Vendor:      GPUVendorNVIDIA,
 		}
 		gpus = append(gpus, gpu)
 	}
@@ -390,6 +408,7 @@ func (w *WindowsGPUDetector) GetGPUInfo() ([]GPUInfo, error) {
 			Temperature: strings.TrimSpace(parts[3]) + "°C",
 			Utilization: strings.TrimSpace(parts[4]) + "%",
 			Driver:      strings.TrimSpace(parts[5]),
+			Vendor:      GPUVendorNVIDIA,
 		}
 		gpus = append(gpus, gpu)
 	}
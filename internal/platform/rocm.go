@@ -0,0 +1,238 @@
+package platform
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AMD GPUs are read through rocm-smi, the ROCm counterpart of nvidia-smi. Every
+// GPU consumer (status, pulse stats, resmon, footprints, capability tags) falls
+// back to it when nvidia-smi is absent, so an MI210 or Radeon node reports the
+// same shapes an NVIDIA node does. rocm-smi's JSON keys changed casing and
+// wording across ROCm releases ("Card series" vs "Card Series", "Average" vs
+// "Current Socket" package power), so the parser matches keys loosely.
+
+// GPU vendors reported by DetectGPUVendor.
+const (
+	GPUVendorNVIDIA = "nvidia"
+	GPUVendorAMD    = "amd"
+)
+
+// rocmSMITimeout bounds one rocm-smi call, like the nvidia-smi callers do.
+const rocmSMITimeout = 5 * time.Second
+
+// rocmSMIArgs selects the fields ParseROCmSMI reads.
+var rocmSMIArgs = []string{
+	"--showproductname", "--showmeminfo", "vram", "--showuse",
+	"--showtemp", "--showpower", "--showdriverversion", "--json",
+}
+
+// ROCmGPU is one AMD GPU as reported by rocm-smi. Numeric fields rocm-smi did
+// not report are -1 (VRAM fields 0), so callers can omit them rather than
+// ship a fake zero.
+type ROCmGPU struct {
+	Index int
+	// Name is the marketing name ("AMD Instinct MI210"), falling back to the
+	// card model when the series is not reported.
+	Name        string
+	GFXVersion  string
+	VRAMTotalMB int
+	VRAMUsedMB  int
+	UtilPercent float64
+	TempC       float64
+	PowerW      float64
+	Driver      string
+}
+
+// QueryROCmGPUs runs rocm-smi and parses its GPUs. It returns an error when
+// rocm-smi is missing or fails, and when it lists no GPU (an APU's integrated
+// graphics, or no amdgpu driver).
+func QueryROCmGPUs(ctx context.Context) ([]ROCmGPU, error) {
+	path, err := exec.LookPath("rocm-smi")
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, rocmSMITimeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, path, rocmSMIArgs...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("rocm-smi: %w", err)
+	}
+	gpus, err := ParseROCmSMI(out)
+	if err != nil {
+		return nil, err
+	}
+	if len(gpus) == 0 {
+		return nil, fmt.Errorf("rocm-smi reported no GPUs")
+	}
+	return gpus, nil
+}
+
+// QueryROCmPIDs runs `rocm-smi --showpids --json` and returns the VRAM each
+// GPU process holds, in bytes.
+func QueryROCmPIDs(ctx context.Context) (map[int]uint64, error) {
+	path, err := exec.LookPath("rocm-smi")
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, rocmSMITimeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, path, "--showpids", "--json").Output()
+	if err != nil {
+		return nil, fmt.Errorf("rocm-smi: %w", err)
+	}
+	return ParseROCmPIDs(out)
+}
+
+// ParseROCmSMI parses `rocm-smi --json` output: one "cardN" object per GPU
+// plus a "system" object carrying the driver version. GPUs are returned in
+// card order.
+func ParseROCmSMI(out []byte) ([]ROCmGPU, error) {
+	var doc map[string]map[string]string
+	if err := json.Unmarshal(out, &doc); err != nil {
+		return nil, fmt.Errorf("parse rocm-smi output: %w", err)
+	}
+	driver := rocmField(doc["system"], "driver version")
+
+	var gpus []ROCmGPU
+	for key, fields := range doc {
+		idx, ok := strings.CutPrefix(strings.ToLower(key), "card")
+		if !ok {
+			continue
+		}
+		index, err := strconv.Atoi(idx)
+		if err != nil {
+			continue
+		}
+		gpu := ROCmGPU{
+			Index:       index,
+			Name:        rocmField(fields, "card series"),
+			GFXVersion:  rocmField(fields, "gfx version"),
+			UtilPercent: rocmFloat(fields, "gpu use (%)"),
+			TempC:       rocmFloat(fields, "temperature (sensor edge) (c)"),
+			PowerW:      -1,
+			Driver:      driver,
+		}
+		if gpu.Name == "" {
+			gpu.Name = rocmField(fields, "card model")
+		}
+		if gpu.TempC < 0 {
+			gpu.TempC = rocmFloat(fields, "temperature (sensor junction) (c)")
+		}
+		if total := rocmFloat(fields, "vram total memory (b)"); total > 0 {
+			gpu.VRAMTotalMB = int(total / (1 << 20))
+		}
+		if used := rocmFloat(fields, "vram total used memory (b)"); used > 0 {
+			gpu.VRAMUsedMB = int(used / (1 << 20))
+		}
+		for k, v := range fields {
+			if strings.Contains(strings.ToLower(k), "graphics package power (w)") {
+				if w, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+					gpu.PowerW = w
+				}
+			}
+		}
+		gpus = append(gpus, gpu)
+	}
+	sort.Slice(gpus, func(i, j int) bool { return gpus[i].Index < gpus[j].Index })
+	return gpus, nil
+}
+
+// ParseROCmPIDs parses `rocm-smi --showpids --json`, whose "system" object
+// maps "PID<n>" to "<name>, <gpu count>, <vram bytes>, <sdma bytes>, <cu
+// occupancy>". Rows that do not parse are skipped.
+func ParseROCmPIDs(out []byte) (map[int]uint64, error) {
+	var doc map[string]map[string]string
+	if err := json.Unmarshal(out, &doc); err != nil {
+		return nil, fmt.Errorf("parse rocm-smi pids: %w", err)
+	}
+	res := map[int]uint64{}
+	for key, value := range doc["system"] {
+		pidStr, ok := strings.CutPrefix(key, "PID")
+		if !ok {
+			continue
+		}
+		pid, err := strconv.Atoi(pidStr)
+		if err != nil {
+			continue
+		}
+		parts := strings.Split(value, ",")
+		if len(parts) < 3 {
+			continue
+		}
+		vram, err := strconv.ParseUint(strings.TrimSpace(parts[2]), 10, 64)
+		if err != nil {
+			continue
+		}
+		res[pid] += vram
+	}
+	return res, nil
+}
+
+// DetectGPUVendor reports which GPU stack the node runs: GPUVendorNVIDIA when
+// nvidia-smi answers, GPUVendorAMD when rocm-smi lists a GPU, "" otherwise.
+func DetectGPUVendor() string {
+	if path, err := exec.LookPath("nvidia-smi"); err == nil {
+		if exec.Command(path, "-L").Run() == nil {
+			return GPUVendorNVIDIA
+		}
+	}
+	if _, err := QueryROCmGPUs(context.Background()); err == nil {
+		return GPUVendorAMD
+	}
+	return ""
+}
+
+// rocmField looks a key up case-insensitively, treating rocm-smi's "N/A" as
+// absent.
+func rocmField(fields map[string]string, key string) string {
+	for k, v := range fields {
+		if strings.EqualFold(k, key) {
+			v = strings.TrimSpace(v)
+			if strings.EqualFold(v, "N/A") {
+				return ""
+			}
+			return v
+		}
+	}
+	return ""
+}
+
+// rocmFloat is rocmField parsed as a number, or -1 when absent.
+func rocmFloat(fields map[string]string, key string) float64 {
+	v, err := strconv.ParseFloat(rocmField(fields, key), 64)
+	if err != nil {
+		return -1
+	}
+	return v
+}
+
+// gpuInfoFromROCm converts rocm-smi GPUs to the detector's GPUInfo shape.
+func gpuInfoFromROCm(gpus []ROCmGPU) []GPUInfo {
+	infos := make([]GPUInfo, 0, len(gpus))
+	for _, g := range gpus {
+		info := GPUInfo{
+			Name:   g.Name,
+			Driver: g.Driver,
+			Vendor: GPUVendorAMD,
+		}
+		if g.VRAMTotalMB > 0 {
+			info.Memory = fmt.Sprintf("%d MB", g.VRAMTotalMB)
+			info.MemoryUsed = fmt.Sprintf("%d MB", g.VRAMUsedMB)
+		}
+		if g.TempC >= 0 {
+			info.Temperature = fmt.Sprintf("%.0f°C", g.TempC)
+		}
+		if g.UtilPercent >= 0 {
+			info.Utilization = fmt.Sprintf("%.0f%%", g.UtilPercent)
+		}
+		infos = append(infos, info)
+	}
+	return infos
+}
@@ -0,0 +1,81 @@
+package platform
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func readFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestParseROCmSMIInstinct(t *testing.T) {
+	gpus, err := ParseROCmSMI(readFixture(t, "rocm-smi-mi210.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(gpus) != 2 {
+		t.Fatalf("got %d GPUs, want 2", len(gpus))
+	}
+	g := gpus[0]
+	if g.Index != 0 || g.Name != "AMD Instinct MI210" || g.GFXVersion != "gfx90a" || g.Driver != "6.7.0" {
+		t.Errorf("gpu0 identity = %+v", g)
+	}
+	if g.VRAMTotalMB != 65520 || g.VRAMUsedMB != 32768 {
+		t.Errorf("gpu0 VRAM = %d/%d MB, want 32768/65520", g.VRAMUsedMB, g.VRAMTotalMB)
+	}
+	if g.UtilPercent != 63 || g.TempC != 41 || g.PowerW != 97 {
+		t.Errorf("gpu0 util/temp/power = %v/%v/%v", g.UtilPercent, g.TempC, g.PowerW)
+	}
+	// N/A edge temperature falls back to the junction sensor; N/A power is unknown.
+	if g1 := gpus[1]; g1.Index != 1 || g1.TempC != 38 || g1.PowerW != -1 {
+		t.Errorf("gpu1 = %+v", g1)
+	}
+}
+
+func TestParseROCmSMIOlderKeyCasing(t *testing.T) {
+	gpus, err := ParseROCmSMI(readFixture(t, "rocm-smi-radeon.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(gpus) != 1 || gpus[0].Name != "Radeon RX 7900 XTX" || gpus[0].PowerW != 31 || gpus[0].VRAMTotalMB != 24560 {
+		t.Errorf("gpus = %+v", gpus)
+	}
+
+	info := gpuInfoFromROCm(gpus)
+	if info[0].Memory != "24560 MB" || info[0].Utilization != "2%" || info[0].Temperature != "48°C" || info[0].Vendor != GPUVendorAMD {
+		t.Errorf("GPUInfo = %+v", info[0])
+	}
+}
+
+func TestParseROCmSMIRejectsGarbage(t *testing.T) {
+	if _, err := ParseROCmSMI([]byte("WARNING: AMD GPU device(s) is/are in a low-power state")); err == nil {
+		t.Error("expected an error for non-JSON output")
+	}
+	gpus, err := ParseROCmSMI([]byte(`{"system": {"Driver version": "6.2.4"}}`))
+	if err != nil || len(gpus) != 0 {
+		t.Errorf("no cards = %+v, %v", gpus, err)
+	}
+}
+
+func TestParseROCmPIDs(t *testing.T) {
+	pids, err := ParseROCmPIDs(readFixture(t, "rocm-smi-pids.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[int]uint64{31207: 34359738368, 4112: 8589934592}
+	if len(pids) != len(want) {
+		t.Fatalf("pids = %v, want %v", pids, want)
+	}
+	for pid, vram := range want {
+		if pids[pid] != vram {
+			t.Errorf("pid %d vram = %d, want %d", pid, pids[pid], vram)
+		}
+	}
+}
@@ -0,0 +1,351 @@
+package provision
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"time"
+)
+
+var (
+	// ErrQuotaExceeded means the spec would take its org past its quota.
+	ErrQuotaExceeded = errors.New("quota exceeded")
+	// ErrInsufficientCapacity means the spec does not fit the node's free
+	// capacity right now. With Policy.Queue set such specs are queued
+	// instead.
+	ErrInsufficientCapacity = errors.New("insufficient capacity")
+	// ErrExceedsNodeCapacity means the spec is larger than the node could
+	// ever give it, so it is rejected rather than queued.
+	ErrExceedsNodeCapacity = errors.New("exceeds node capacity")
+)
+
+// AdmissionConfig enables admission control on a Manager.
+type AdmissionConfig struct {
+	// Policy holds the quotas, headroom and queueing switch.
+	Policy Policy
+	// Probe measures the node. Nil uses CollectCapacity.
+	Probe CapacityProbe
+	// GPUs pins GPU requests to devices. Nil passes GPU requests through
+	// to the backend unpinned and unaccounted.
+	GPUs GPUAllocator
+}
+
+// EnableAdmission turns on quota and capacity checks for Create. Without it
+// the manager starts every valid spec, as before. GPU devices pinned by
+// existing resources are re-acquired from cfg.GPUs, so a restarted daemon
+// does not hand them to GPU jobs.
+func (m *Manager) EnableAdmission(cfg AdmissionConfig) {
+	if cfg.Probe == nil {
+		cfg.Probe = CollectCapacity
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.admission = &cfg
+	if cfg.GPUs == nil {
+		return
+	}
+	for _, r := range m.store.List() {
+		if holdsCapacity(r.Status) {
+			for _, d := range r.GPUDevices {
+				if cfg.GPUs.AcquireSpecific(d) {
+					m.held[d] = true
+				}
+			}
+		}
+	}
+}
+
+// Capacity measures the node with the admission probe (CollectCapacity when
+// admission control is off).
+func (m *Manager) Capacity(ctx context.Context) (NodeCapacity, error) {
+	m.mu.Lock()
+	adm := m.admission
+	m.mu.Unlock()
+
+	probe := CapacityProbe(CollectCapacity)
+	if adm != nil {
+		probe = adm.Probe
+	}
+	c, err := probe(ctx)
+	if err != nil {
+		return NodeCapacity{}, err
+	}
+	if adm != nil && adm.GPUs != nil {
+		c.GPUs = adm.GPUs.Total()
+	}
+	return c, nil
+}
+
+// Reservations returns the node's provisioning ledger.
+func (m *Manager) Reservations() ReservationSummary {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	sum := ReservationSummary{Reservations: []Reservation{}}
+	for _, r := range m.store.List() {
+		if r.Status != StatusQueued && !holdsCapacity(r.Status) {
+			continue
+		}
+		u := reservedBy(r)
+		sum.Reservations = append(sum.Reservations, Reservation{
+			ResourceID: r.ID,
+			Name:       r.Spec.Name,
+			OrgID:      r.Spec.OrgID,
+			Type:       r.Spec.Type,
+			Status:     r.Status,
+			CPUs:       u.CPUs,
+			MemoryMB:   u.MemoryMB,
+			GPUDevices: r.GPUDevices,
+		})
+		if r.Status == StatusQueued {
+			sum.Queued++
+		} else if onNode(r.Spec.Type) {
+			sum.Reserved.CPUs += u.CPUs
+			sum.Reserved.MemoryMB += u.MemoryMB
+			sum.Reserved.GPUs += u.GPUs
+		}
+	}
+	sort.Slice(sum.Reservations, func(i, j int) bool {
+		return sum.Reservations[i].Name < sum.Reservations[j].Name
+	})
+	return sum
+}
+
+// AdmitQueued starts every queued resource that now fits, oldest first. It
+// returns how many were started. The manager calls it itself whenever a
+// resource is destroyed, or found destroyed by Status; the daemon also calls
+// it on startup and periodically, since capacity frees up when GPU jobs
+// finish too.
+func (m *Manager) AdmitQueued(ctx context.Context) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.syncLocked()
+	return m.admitQueuedLocked(ctx)
+}
+
+func (m *Manager) admitQueuedLocked(ctx context.Context) int {
+	if m.admission == nil {
+		return 0
+	}
+	var queued []*Resource
+	for _, r := range m.store.List() {
+		if r.Status == StatusQueued {
+			queued = append(queued, r)
+		}
+	}
+	if len(queued) == 0 {
+		return 0
+	}
+	sort.Slice(queued, func(i, j int) bool { return queued[i].CreatedAt.Before(queued[j].CreatedAt) })
+
+	var (
+		capacity *NodeCapacity
+		started  int
+	)
+	for _, r := range queued {
+		backend, ok := m.backends[r.Spec.Type]
+		if !ok {
+			continue
+		}
+		if capacity == nil && onNode(r.Spec.Type) {
+			c, err := m.admission.Probe(ctx)
+			if err != nil {
+				log.Printf("[provision] queue: measuring node capacity: %v", err)
+				return started
+			}
+			capacity = &c
+		}
+		if err := m.admitLocked(r, capacity); err != nil {
+			if errors.Is(err, ErrInsufficientCapacity) {
+				continue
+			}
+			// The policy changed under the queued spec; it can never start.
+			r.Status = StatusError
+			r.Error = err.Error()
+			r.UpdatedAt = time.Now().UTC()
+			_ = m.store.Put(r)
+			continue
+		}
+		log.Printf("[provision] queue: starting %s (%s)", r.Spec.Name, r.ID)
+		if err := m.startLocked(ctx, backend, r); err != nil {
+			log.Printf("[provision] queue: %s (%s): %v", r.Spec.Name, r.ID, err)
+			continue
+		}
+		started++
+	}
+	return started
+}
+
+// applyDefaults fills in the policy's default limits on specs that set none.
+func (a *AdmissionConfig) applyDefaults(spec *ResourceSpec) {
+	if !onNode(spec.Type) {
+		return
+	}
+	if spec.CPUs == "" && a.Policy.DefaultCPUs > 0 {
+		spec.CPUs = strconv.FormatFloat(a.Policy.DefaultCPUs, 'f', -1, 64)
+	}
+	if spec.MemoryMB == 0 && a.Policy.DefaultMemoryMB > 0 {
+		spec.MemoryMB = a.Policy.DefaultMemoryMB
+	}
+}
+
+// admitLocked checks r against its org's quota and, for resources on this
+// node, against capacity, which must then be non-nil. On success r's GPU
+// request is pinned to devices recorded in r.GPUDevices.
+func (m *Manager) admitLocked(r *Resource, capacity *NodeCapacity) error {
+	a := m.admission
+	spec := &r.Spec
+
+	cpus, err := specCPUs(spec)
+	if err != nil {
+		return err
+	}
+	totalGPUs := 0
+	if a.GPUs != nil {
+		totalGPUs = a.GPUs.Total()
+	}
+	gpus, err := parseGPURequest(spec.GPUs, totalGPUs)
+	if err != nil {
+		return err
+	}
+
+	// Quota: everything the org holds or has queued, other than r itself.
+	if q, ok := a.Policy.quotaFor(spec.OrgID); ok {
+		var used Usage
+		count := 0
+		for _, o := range m.store.List() {
+			if o.ID == r.ID || o.Spec.OrgID != spec.OrgID || (o.Status != StatusQueued && !holdsCapacity(o.Status)) {
+				continue
+			}
+			u := reservedBy(o)
+			used.CPUs += u.CPUs
+			used.MemoryMB += u.MemoryMB
+			used.GPUs += u.GPUs
+			count++
+		}
+		org := spec.OrgID
+		if org == "" {
+			org = "(none)"
+		}
+		switch {
+		case q.Resources > 0 && count+1 > q.Resources:
+			return fmt.Errorf("%w: org %s already has %d of %d resources", ErrQuotaExceeded, org, count, q.Resources)
+		case q.CPUs > 0 && used.CPUs+cpus > q.CPUs:
+			return fmt.Errorf("%w: org %s would use %g of %g CPUs", ErrQuotaExceeded, org, used.CPUs+cpus, q.CPUs)
+		case q.MemoryMB > 0 && used.MemoryMB+spec.MemoryMB > q.MemoryMB:
+			return fmt.Errorf("%w: org %s would use %d of %d MB memory", ErrQuotaExceeded, org, used.MemoryMB+spec.MemoryMB, q.MemoryMB)
+		case q.GPUs > 0 && used.GPUs+gpus.count > q.GPUs:
+			return fmt.Errorf("%w: org %s would use %d of %d GPUs", ErrQuotaExceeded, org, used.GPUs+gpus.count, q.GPUs)
+		}
+	}
+
+	if !onNode(spec.Type) {
+		return nil
+	}
+
+	// Capacity: the node less headroom, engine memory and active reservations.
+	allocCPUs := capacity.CPUs - a.Policy.ReserveCPUs
+	allocMem := capacity.MemoryMB - a.Policy.ReserveMemoryMB
+	if cpus > allocCPUs {
+		return fmt.Errorf("%w: %g CPUs requested, node has %g to allocate", ErrExceedsNodeCapacity, cpus, allocCPUs)
+	}
+	if spec.MemoryMB > allocMem {
+		return fmt.Errorf("%w: %d MB requested, node has %d MB to allocate", ErrExceedsNodeCapacity, spec.MemoryMB, allocMem)
+	}
+	if a.GPUs != nil && gpus.count > totalGPUs {
+		return fmt.Errorf("%w: %d GPUs requested, node has %d", ErrExceedsNodeCapacity, gpus.count, totalGPUs)
+	}
+
+	var reserved Usage
+	pinned := make(map[int]bool)
+	for _, o := range m.store.List() {
+		if o.ID == r.ID || !onNode(o.Spec.Type) || !holdsCapacity(o.Status) {
+			continue
+		}
+		u := reservedBy(o)
+		reserved.CPUs += u.CPUs
+		reserved.MemoryMB += u.MemoryMB
+		for _, d := range o.GPUDevices {
+			pinned[d] = true
+		}
+	}
+	if free := allocCPUs - reserved.CPUs; cpus > free {
+		return fmt.Errorf("%w: %g CPUs requested, %g free", ErrInsufficientCapacity, cpus, free)
+	}
+	if free := allocMem - capacity.EngineMemoryMB - reserved.MemoryMB; spec.MemoryMB > free {
+		return fmt.Errorf("%w: %d MB requested, %d MB free", ErrInsufficientCapacity, spec.MemoryMB, free)
+	}
+
+	if gpus.count > 0 && a.GPUs != nil {
+		devices, err := pinGPUs(a.GPUs, gpus, pinned)
+		if err != nil {
+			return err
+		}
+		r.GPUDevices = devices
+		m.holdGPUsLocked(devices)
+	}
+	return nil
+}
+
+// pinGPUs acquires the requested devices, or the first free ones when only a
+// count was asked for. Devices pinned by other resources or held by GPU jobs
+// are skipped.
+func pinGPUs(alloc GPUAllocator, req gpuRequest, pinned map[int]bool) ([]int, error) {
+	candidates := req.devices
+	if candidates == nil {
+		for i := 0; i < alloc.Total(); i++ {
+			candidates = append(candidates, i)
+		}
+	}
+	var got []int
+	for _, d := range candidates {
+		if len(got) == req.count {
+			break
+		}
+		if d >= alloc.Total() {
+			releaseGPUs(alloc, got)
+			return nil, fmt.Errorf("%w: GPU %d does not exist", ErrExceedsNodeCapacity, d)
+		}
+		if pinned[d] || !alloc.AcquireSpecific(d) {
+			if req.devices != nil {
+				releaseGPUs(alloc, got)
+				return nil, fmt.Errorf("%w: GPU %d is in use", ErrInsufficientCapacity, d)
+			}
+			continue
+		}
+		got = append(got, d)
+	}
+	if len(got) < req.count {
+		releaseGPUs(alloc, got)
+		return nil, fmt.Errorf("%w: %d GPUs requested, %d free", ErrInsufficientCapacity, req.count, len(got))
+	}
+	return got, nil
+}
+
+func releaseGPUs(alloc GPUAllocator, devices []int) {
+	if alloc == nil {
+		return
+	}
+	for _, d := range devices {
+		alloc.Release(d)
+	}
+}
+
+// reservedBy is the capacity r holds. An unpinned GPU request counts its
+// requested number of devices ("all" counts none: there is no allocator to
+// say how many that is).
+func reservedBy(r *Resource) Usage {
+	cpus, _ := specCPUs(&r.Spec)
+	u := Usage{CPUs: cpus, MemoryMB: r.Spec.MemoryMB, GPUs: len(r.GPUDevices)}
+	if u.GPUs == 0 {
+		if req, err := parseGPURequest(r.Spec.GPUs, 0); err == nil {
+			u.GPUs = req.count
+		}
+	}
+	return u
+}
@@ -0,0 +1,456 @@
+package provision
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeGPUs is a GPUAllocator over a fixed number of devices.
+type fakeGPUs struct {
+	mu   sync.Mutex
+	busy []bool
+}
+
+func newFakeGPUs(n int) *fakeGPUs { return &fakeGPUs{busy: make([]bool, n)} }
+
+func (g *fakeGPUs) AcquireSpecific(i int) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if i < 0 || i >= len(g.busy) || g.busy[i] {
+		return false
+	}
+	g.busy[i] = true
+	return true
+}
+
+func (g *fakeGPUs) Release(i int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if i >= 0 && i < len(g.busy) {
+		g.busy[i] = false
+	}
+}
+
+func (g *fakeGPUs) Total() int { return len(g.busy) }
+
+func (g *fakeGPUs) held() []int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	var out []int
+	for i, b := range g.busy {
+		if b {
+			out = append(out, i)
+		}
+	}
+	return out
+}
+
+// fixedCapacity is a probe for an 8-CPU, 16 GB node whose engines hold 4 GB.
+func fixedCapacity(context.Context) (NodeCapacity, error) {
+	return NodeCapacity{CPUs: 8, MemoryMB: 16384, EngineMemoryMB: 4096}, nil
+}
+
+func newAdmissionManager(t *testing.T, policy Policy, gpus GPUAllocator) (*Manager, *mockBackend) {
+	t.Helper()
+	backend := newMockBackend()
+	mgr := newTestManager(t, backend)
+	mgr.EnableAdmission(AdmissionConfig{Policy: policy, Probe: fixedCapacity, GPUs: gpus})
+	return mgr, backend
+}
+
+func dockerSpec(name, cpus string, memMB int) *ResourceSpec {
+	return &ResourceSpec{Name: name, Type: ResourceTypeDocker, Image: "nginx", CPUs: cpus, MemoryMB: memMB}
+}
+
+func TestAdmissionRejectsOverCapacity(t *testing.T) {
+	mgr, _ := newAdmissionManager(t, Policy{ReserveMemoryMB: 2048}, nil)
+	ctx := context.Background()
+
+	// 16 GB - 2 GB headroom - 4 GB engines = 10 GB free.
+	if _, err := mgr.Create(ctx, dockerSpec("a", "4", 8192)); err != nil {
+		t.Fatalf("first create: %v", err)
+	}
+	_, err := mgr.Create(ctx, dockerSpec("b", "2", 4096))
+	if !errors.Is(err, ErrInsufficientCapacity) {
+		t.Fatalf("err = %v, want ErrInsufficientCapacity", err)
+	}
+	_, err = mgr.Create(ctx, dockerSpec("c", "16", 0))
+	if !errors.Is(err, ErrExceedsNodeCapacity) {
+		t.Fatalf("err = %v, want ErrExceedsNodeCapacity", err)
+	}
+	if got := len(mgr.List()); got != 1 {
+		t.Errorf("rejected specs were persisted: %d resources", got)
+	}
+}
+
+func TestAdmissionQueuesAndStartsOnDestroy(t *testing.T) {
+	mgr, backend := newAdmissionManager(t, Policy{Queue: true}, nil)
+	ctx := context.Background()
+
+	first, err := mgr.Create(ctx, dockerSpec("a", "6", 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := mgr.Create(ctx, dockerSpec("b", "4", 0))
+	if err != nil {
+		t.Fatalf("create b: %v", err)
+	}
+	if !second.Queued || second.Resource.Status != StatusQueued || backend.createCalls != 1 {
+		t.Fatalf("b = %+v (queued=%v), %d backend creates; want queued without a backend call",
+			second.Resource, second.Queued, backend.createCalls)
+	}
+	if _, err := mgr.Create(ctx, dockerSpec("huge", "64", 0)); !errors.Is(err, ErrExceedsNodeCapacity) {
+		t.Fatalf("err = %v, want a spec that can never fit rejected, not queued", err)
+	}
+	if sum := mgr.Reservations(); sum.Queued != 1 || sum.Reserved.CPUs != 6 {
+		t.Errorf("summary = %+v, want 1 queued, 6 CPUs reserved", sum)
+	}
+
+	if err := mgr.Destroy(ctx, first.Resource.ID); err != nil {
+		t.Fatal(err)
+	}
+	if r := mgr.Get(second.Resource.ID); r.Status != StatusRunning || r.Error != "" {
+		t.Errorf("b after destroy = %+v, want running", r)
+	}
+}
+
+func TestStatusFindingDestroyedFreesCapacity(t *testing.T) {
+	gpus := newFakeGPUs(1)
+	mgr, backend := newAdmissionManager(t, Policy{Queue: true}, gpus)
+	ctx := context.Background()
+
+	spec := func(name string) *ResourceSpec {
+		s := dockerSpec(name, "", 0)
+		s.GPUs = "1"
+		return s
+	}
+	first, err := mgr.Create(ctx, spec("a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := mgr.Create(ctx, spec("b"))
+	if err != nil || !second.Queued {
+		t.Fatalf("b = %+v, %v; want queued behind a's GPU", second, err)
+	}
+
+	// a's container disappears outside the manager. Reconciling only records
+	// it; the queue is left for the daemon.
+	backend.mu.Lock()
+	delete(backend.containers, first.Resource.ID)
+	backend.mu.Unlock()
+	mgr.ReconcileAll(ctx)
+	if r := mgr.Get(second.Resource.ID); r.Status != StatusQueued {
+		t.Fatalf("b after ReconcileAll = %s, want still queued", r.Status)
+	}
+	if len(gpus.held()) != 0 {
+		t.Fatalf("held = %v, want a's device released", gpus.held())
+	}
+
+	// Status takes the same path as Destroy: b is admitted onto the device.
+	mgr.store.Put(&Resource{ID: first.Resource.ID, Spec: first.Resource.Spec, Status: StatusRunning, GPUDevices: []int{0}})
+	gpus.AcquireSpecific(0)
+	if r, err := mgr.Status(ctx, first.Resource.ID); err != nil || r.Status != StatusDestroyed {
+		t.Fatalf("status = %+v, %v; want destroyed", r, err)
+	}
+	if r := mgr.Get(second.Resource.ID); r.Status != StatusRunning || fmt.Sprint(r.GPUDevices) != "[0]" {
+		t.Errorf("b after Status = %s on %v, want running on [0]", r.Status, r.GPUDevices)
+	}
+}
+
+func TestAdmissionOrgQuotas(t *testing.T) {
+	policy := Policy{Quotas: map[string]Quota{
+		"acme":          {CPUs: 3, Resources: 5},
+		DefaultQuotaKey: {Resources: 1},
+	}}
+	mgr, _ := newAdmissionManager(t, policy, nil)
+	ctx := context.Background()
+
+	spec := func(name, org, cpus string) *ResourceSpec {
+		s := dockerSpec(name, cpus, 0)
+		s.OrgID = org
+		return s
+	}
+	if _, err := mgr.Create(ctx, spec("a1", "acme", "2")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mgr.Create(ctx, spec("a2", "acme", "2")); !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("err = %v, want acme's CPU quota exceeded", err)
+	}
+	if _, err := mgr.Create(ctx, spec("a3", "acme", "1")); err != nil {
+		t.Fatalf("a3 fits acme's quota: %v", err)
+	}
+
+	// Other orgs fall under the default quota, each on its own.
+	if _, err := mgr.Create(ctx, spec("b1", "beta", "4")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mgr.Create(ctx, spec("b2", "beta", "")); !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("err = %v, want beta's resource quota exceeded", err)
+	}
+	if _, err := mgr.Create(ctx, spec("c1", "gamma", "")); err != nil {
+		t.Fatalf("gamma has its own default quota: %v", err)
+	}
+}
+
+func TestAdmissionQuotaIsNotQueued(t *testing.T) {
+	policy := Policy{Queue: true, Quotas: map[string]Quota{DefaultQuotaKey: {Resources: 1}}}
+	mgr, _ := newAdmissionManager(t, policy, nil)
+	ctx := context.Background()
+
+	mgr.Create(ctx, dockerSpec("a", "", 0))
+	if _, err := mgr.Create(ctx, dockerSpec("b", "", 0)); !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("err = %v, want quota rejection even with queueing on", err)
+	}
+}
+
+func TestAdmissionAppliesDefaultLimits(t *testing.T) {
+	mgr, _ := newAdmissionManager(t, Policy{DefaultCPUs: 0.5, DefaultMemoryMB: 1024}, nil)
+
+	result, err := mgr.Create(context.Background(), dockerSpec("a", "", 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if spec := result.Resource.Spec; spec.CPUs != "0.5" || spec.MemoryMB != 1024 {
+		t.Errorf("spec = %+v, want default limits filled in", spec)
+	}
+	if sum := mgr.Reservations(); sum.Reserved.CPUs != 0.5 || sum.Reserved.MemoryMB != 1024 {
+		t.Errorf("reserved = %+v", sum.Reserved)
+	}
+}
+
+func TestAdmissionPinsGPUs(t *testing.T) {
+	gpus := newFakeGPUs(3)
+	gpus.AcquireSpecific(0) // a GPU job holds device 0
+	mgr, _ := newAdmissionManager(t, Policy{}, gpus)
+	ctx := context.Background()
+
+	gpuSpec := func(name, req string) *ResourceSpec {
+		s := dockerSpec(name, "", 0)
+		s.GPUs = req
+		return s
+	}
+	a, err := mgr.Create(ctx, gpuSpec("a", "1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(a.Resource.GPUDevices) != "[1]" {
+		t.Errorf("a pinned %v, want [1] (device 0 is busy)", a.Resource.GPUDevices)
+	}
+	if _, err := mgr.Create(ctx, gpuSpec("b", "device=1")); !errors.Is(err, ErrInsufficientCapacity) {
+		t.Errorf("err = %v, want device 1 already pinned", err)
+	}
+	if _, err := mgr.Create(ctx, gpuSpec("c", "2")); !errors.Is(err, ErrInsufficientCapacity) {
+		t.Errorf("err = %v, want only one free device", err)
+	}
+	if _, err := mgr.Create(ctx, gpuSpec("d", "4")); !errors.Is(err, ErrExceedsNodeCapacity) {
+		t.Errorf("err = %v, want more GPUs than the node has", err)
+	}
+	if fmt.Sprint(gpus.held()) != "[0 1]" {
+		t.Errorf("held = %v, want failed requests to release what they took", gpus.held())
+	}
+
+	if err := mgr.Destroy(ctx, a.Resource.ID); err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(gpus.held()) != "[0]" {
+		t.Errorf("held after destroy = %v, want [0]", gpus.held())
+	}
+}
+
+func TestEnableAdmissionReacquiresPinnedGPUs(t *testing.T) {
+	backend := newMockBackend()
+	mgr := newTestManager(t, backend)
+	mgr.store.Put(&Resource{ID: "r1", Spec: *dockerSpec("a", "", 0), Status: StatusRunning, GPUDevices: []int{1}})
+	mgr.store.Put(&Resource{ID: "r2", Spec: *dockerSpec("b", "", 0), Status: StatusDestroyed, GPUDevices: []int{0}})
+
+	gpus := newFakeGPUs(2)
+	mgr.EnableAdmission(AdmissionConfig{Probe: fixedCapacity, GPUs: gpus})
+	if fmt.Sprint(gpus.held()) != "[1]" {
+		t.Errorf("held = %v, want the running resource's device", gpus.held())
+	}
+}
+
+func TestAdmissionSeesGPUsPinnedByAnotherProcess(t *testing.T) {
+	dir := t.TempDir()
+	open := func(gpus GPUAllocator) *Manager {
+		store, err := NewStore(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		mgr := NewManager(store, map[ResourceType]Backend{ResourceTypeDocker: newMockBackend()})
+		mgr.EnableAdmission(AdmissionConfig{Probe: fixedCapacity, GPUs: gpus})
+		return mgr
+	}
+	ctx := context.Background()
+	gpuSpec := func(name string) *ResourceSpec {
+		s := dockerSpec(name, "", 0)
+		s.GPUs = "1"
+		return s
+	}
+
+	daemonGPUs := newFakeGPUs(2)
+	daemon := open(daemonGPUs)
+	cli := open(newFakeGPUs(2)) // the CLI's own tracker, blind to the daemon's
+
+	fromCLI, err := cli.Create(ctx, gpuSpec("from-cli"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromDaemon, err := daemon.Create(ctx, gpuSpec("from-daemon"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(fromCLI.Resource.GPUDevices) != "[0]" || fmt.Sprint(fromDaemon.Resource.GPUDevices) != "[1]" {
+		t.Errorf("pinned %v and %v, want the daemon to skip the CLI's device", fromCLI.Resource.GPUDevices, fromDaemon.Resource.GPUDevices)
+	}
+	if fmt.Sprint(daemonGPUs.held()) != "[0 1]" {
+		t.Errorf("daemon held = %v, want the CLI's pin too", daemonGPUs.held())
+	}
+
+	// The CLI destroys its resource; the daemon gives the device back to jobs.
+	if err := cli.Destroy(ctx, fromCLI.Resource.ID); err != nil {
+		t.Fatal(err)
+	}
+	daemon.AdmitQueued(ctx)
+	if fmt.Sprint(daemonGPUs.held()) != "[1]" {
+		t.Errorf("daemon held after the CLI destroy = %v, want [1]", daemonGPUs.held())
+	}
+}
+
+func TestFailedDestroyKeepsGPUsUntilTheContainerIsGone(t *testing.T) {
+	gpus := newFakeGPUs(1)
+	mgr, backend := newAdmissionManager(t, Policy{}, gpus)
+	ctx := context.Background()
+	spec := dockerSpec("a", "", 0)
+	spec.GPUs = "1"
+	res, err := mgr.Create(ctx, spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := res.Resource.ID
+
+	backend.destroyErr = errors.New("daemon gone")
+	if err := mgr.Destroy(ctx, id); err == nil {
+		t.Fatal("destroy succeeded, want the backend error")
+	}
+	if r := mgr.Get(id); r.Status != StatusRunning || len(r.GPUDevices) != 1 || r.Error == "" {
+		t.Errorf("resource = %s on %v (%q), want still running on its device with the error", r.Status, r.GPUDevices, r.Error)
+	}
+	if len(gpus.held()) != 1 {
+		t.Fatalf("held = %v, want the device kept while the container may exist", gpus.held())
+	}
+	spec2 := dockerSpec("b", "", 0)
+	spec2.GPUs = "1"
+	if _, err := mgr.Create(ctx, spec2); err == nil {
+		t.Error("a second resource was pinned to the device of an undestroyed one")
+	}
+
+	// The container went away on its own; reconciling frees the device.
+	backend.mu.Lock()
+	delete(backend.containers, id)
+	backend.mu.Unlock()
+	if _, err := mgr.Status(ctx, id); err != nil {
+		t.Fatal(err)
+	}
+	if len(gpus.held()) != 0 {
+		t.Errorf("held = %v, want the device released once the container is gone", gpus.held())
+	}
+}
+
+func TestDockerCreatePassesPinnedDevices(t *testing.T) {
+	var args []string
+	docker := &DockerBackend{DockerBin: "docker", Run: func(_ context.Context, _ string, a ...string) (string, error) {
+		if a[0] == "run" {
+			args = a
+			return "0123456789abcdef\n", nil
+		}
+		return "", nil
+	}}
+	store, err := NewStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	mgr := NewManager(store, map[ResourceType]Backend{ResourceTypeDocker: docker})
+	mgr.EnableAdmission(AdmissionConfig{Probe: fixedCapacity, GPUs: newFakeGPUs(4)})
+
+	spec := dockerSpec("gpu", "", 0)
+	spec.GPUs = "device=1,3"
+	result, err := mgr.Create(context.Background(), spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(strings.Join(args, " "), `--gpus "device=1,3"`) {
+		t.Errorf("docker run args = %v, want the pinned device list", args)
+	}
+	if result.Resource.Spec.GPUs != "device=1,3" || fmt.Sprint(result.Resource.GPUDevices) != "[1 3]" {
+		t.Errorf("resource = %+v", result.Resource)
+	}
+}
+
+func TestHTTPCreateAdmissionStatusCodes(t *testing.T) {
+	policy := Policy{Queue: true, Quotas: map[string]Quota{"capped": {Resources: 1}}}
+	mgr, _ := newAdmissionManager(t, policy, nil)
+	handler := NewHandler(mgr)
+	mux := http.NewServeMux()
+	handler.RegisterRoutes(mux, noAuthMiddleware)
+
+	create := func(spec ResourceSpec) int {
+		body, _ := json.Marshal(spec)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/provision/create", bytes.NewReader(body)))
+		return w.Code
+	}
+	for _, tc := range []struct {
+		spec ResourceSpec
+		want int
+	}{
+		{ResourceSpec{Name: "a", Type: ResourceTypeDocker, Image: "nginx", OrgID: "capped", CPUs: "6"}, http.StatusCreated},
+		{ResourceSpec{Name: "b", Type: ResourceTypeDocker, Image: "nginx", OrgID: "capped"}, http.StatusForbidden},
+		{ResourceSpec{Name: "c", Type: ResourceTypeDocker, Image: "nginx", CPUs: "4"}, http.StatusAccepted},
+		{ResourceSpec{Name: "d", Type: ResourceTypeDocker, Image: "nginx", CPUs: "100"}, http.StatusConflict},
+	} {
+		if got := create(tc.spec); got != tc.want {
+			t.Errorf("create %s: status %d, want %d", tc.spec.Name, got, tc.want)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/provision/list", nil))
+	var resp struct {
+		Reservations ReservationSummary `json:"reservations"`
+	}
+	json.NewDecoder(w.Body).Decode(&resp)
+	if resp.Reservations.Queued != 1 || resp.Reservations.Reserved.CPUs != 6 || len(resp.Reservations.Reservations) != 2 {
+		t.Errorf("list reservations = %+v", resp.Reservations)
+	}
+}
+
+func TestParseGPURequest(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		want string
+	}{
+		{"", "{0 []}"},
+		{"2", "{2 []}"},
+		{"all", "{3 [0 1 2]}"},
+		{"device=2, 0", "{2 [2 0]}"},
+	} {
+		got, err := parseGPURequest(tc.in, 3)
+		if err != nil || fmt.Sprint(got) != tc.want {
+			t.Errorf("parseGPURequest(%q) = %v, %v; want %s", tc.in, got, err, tc.want)
+		}
+	}
+	for _, bad := range []string{"many", "-1", "device=x"} {
+		if _, err := parseGPURequest(bad, 3); err == nil {
+			t.Errorf("parseGPURequest(%q) succeeded", bad)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package provision
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/aceteam-ai/citadel-cli/internal/resmon"
+	"github.com/shirou/gopsutil/v3/cpu"
+	"github.com/shirou/gopsutil/v3/mem"
+)
+
+// NodeCapacity is the node's capacity model: what the host has, and how much
+// memory the inference engines already hold.
+type NodeCapacity struct {
+	// CPUs is the host's logical CPU count.
+	CPUs float64 `json:"cpus"`
+	// MemoryMB is the host's total memory.
+	MemoryMB int `json:"memory_mb"`
+	// GPUs is the number of GPU devices, from the GPU allocator.
+	GPUs int `json:"gpus"`
+	// EngineMemoryMB is the resident memory of every GPU process resmon sees
+	// (vLLM, Ollama, stray containers). It is not available to provisioning.
+	// A GPU-pinned provisioned container shows up here as well as in its own
+	// reservation, which errs on the side of admitting less.
+	EngineMemoryMB int `json:"engine_memory_mb"`
+}
+
+// CapacityProbe measures the node. CollectCapacity is the live one.
+type CapacityProbe func(ctx context.Context) (NodeCapacity, error)
+
+// CollectCapacity measures the host's CPUs and memory and takes a resmon
+// snapshot for the memory held by GPU processes. GPUs is left zero; the
+// manager fills it from its GPU allocator.
+func CollectCapacity(ctx context.Context) (NodeCapacity, error) {
+	cpus, err := cpu.CountsWithContext(ctx, true)
+	if err != nil {
+		return NodeCapacity{}, fmt.Errorf("counting CPUs: %w", err)
+	}
+	vm, err := mem.VirtualMemoryWithContext(ctx)
+	if err != nil {
+		return NodeCapacity{}, fmt.Errorf("reading memory: %w", err)
+	}
+	var rss uint64
+	for _, c := range resmon.Collect(ctx).Consumers {
+		rss += c.RSSBytes
+	}
+	return NodeCapacity{
+		CPUs:           float64(cpus),
+		MemoryMB:       int(vm.Total >> 20),
+		EngineMemoryMB: int(rss >> 20),
+	}, nil
+}
+
+// GPUAllocator hands out GPU devices by index. *worker.GPUTracker satisfies
+// it; sharing the runner's tracker keeps provisioned containers and GPU jobs
+// off each other's devices.
+type GPUAllocator interface {
+	AcquireSpecific(index int) bool
+	Release(index int)
+	Total() int
+}
+
+// Usage is an amount of node capacity.
+type Usage struct {
+	CPUs     float64 `json:"cpus"`
+	MemoryMB int     `json:"memory_mb"`
+	GPUs     int     `json:"gpus"`
+}
+
+// Reservation is the capacity one resource holds (or, while queued, waits
+// for).
+type Reservation struct {
+	ResourceID string         `json:"resource_id"`
+	Name       string         `json:"name"`
+	OrgID      string         `json:"org_id,omitempty"`
+	Type       ResourceType   `json:"type"`
+	Status     ResourceStatus `json:"status"`
+	CPUs       float64        `json:"cpus"`
+	MemoryMB   int            `json:"memory_mb"`
+	GPUDevices []int          `json:"gpu_devices,omitempty"`
+}
+
+// ReservationSummary is the node's provisioning ledger.
+type ReservationSummary struct {
+	// Reserved totals the active docker and lxc reservations, which are what
+	// is taken from this node. VMs run on the hypervisor and count only
+	// toward quotas.
+	Reserved Usage `json:"reserved"`
+	// Queued is the number of specs waiting for capacity.
+	Queued int `json:"queued"`
+	// Reservations lists every queued or active resource.
+	Reservations []Reservation `json:"reservations"`
+}
+
+// gpuRequest is a parsed ResourceSpec.GPUs.
+type gpuRequest struct {
+	count   int
+	devices []int // explicit devices; nil when only a count was asked for
+}
+
+// parseGPURequest parses "all", a count, or "device=0,2". total is the
+// node's device count, which "all" expands to.
+func parseGPURequest(s string, total int) (gpuRequest, error) {
+	switch {
+	case s == "":
+		return gpuRequest{}, nil
+	case s == "all":
+		devices := make([]int, total)
+		for i := range devices {
+			devices[i] = i
+		}
+		return gpuRequest{count: total, devices: devices}, nil
+	case strings.HasPrefix(s, "device="):
+		var devices []int
+		for _, f := range strings.Split(strings.TrimPrefix(s, "device="), ",") {
+			d, err := strconv.Atoi(strings.TrimSpace(f))
+			if err != nil || d < 0 {
+				return gpuRequest{}, fmt.Errorf("invalid gpu device %q", f)
+			}
+			devices = append(devices, d)
+		}
+		return gpuRequest{count: len(devices), devices: devices}, nil
+	default:
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			return gpuRequest{}, fmt.Errorf("invalid gpus %q: want all, a count, or device=<i>[,<i>...]", s)
+		}
+		return gpuRequest{count: n}, nil
+	}
+}
+
+// deviceArg renders pinned devices as a ResourceSpec.GPUs value.
+func deviceArg(devices []int) string {
+	parts := make([]string, len(devices))
+	for i, d := range devices {
+		parts[i] = strconv.Itoa(d)
+	}
+	return "device=" + strings.Join(parts, ",")
+}
+
+// specCPUs parses ResourceSpec.CPUs; empty is zero.
+func specCPUs(spec *ResourceSpec) (float64, error) {
+	if spec.CPUs == "" {
+		return 0, nil
+	}
+	cpus, err := strconv.ParseFloat(spec.CPUs, 64)
+	if err != nil || cpus < 0 {
+		return 0, fmt.Errorf("invalid cpus %q", spec.CPUs)
+	}
+	return cpus, nil
+}
+
+// onNode reports whether a resource type consumes this node's capacity.
+func onNode(t ResourceType) bool {
+	return t == ResourceTypeDocker || t == ResourceTypeLXC
+}
+
+// holdsCapacity reports whether a resource in this state holds its
+// reservation. Stopped resources keep theirs: they can be restarted in place.
+func holdsCapacity(s ResourceStatus) bool {
+	return s == StatusCreating || s == StatusRunning || s == StatusStopped
+}
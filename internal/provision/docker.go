@@ -14,6 +14,9 @@ import (
 type DockerBackend struct {
 	// DockerBin is the path to the docker binary. Defaults to "docker".
 	DockerBin string
+
+	// Run executes docker commands. Defaults to running them on the host.
+	Run CommandRunner
 }
 
 // NewDockerBackend creates a DockerBackend. It verifies that the docker
@@ -70,7 +73,13 @@ func (b *DockerBackend) Create(ctx context.Context, id string, spec *ResourceSpe
 	}
 
 	if spec.GPUs != "" {
-		args = append(args, "--gpus", spec.GPUs)
+		gpus := spec.GPUs
+		// Docker parses --gpus as CSV, so a device list must be quoted to
+		// keep its commas.
+		if strings.HasPrefix(gpus, "device=") {
+			gpus = `"` + gpus + `"`
+		}
+		args = append(args, "--gpus", gpus)
 	}
 
 	if len(spec.Command) > 0 {
@@ -147,10 +156,24 @@ func containerName(id, specName string) string {
 }
 
 func (b *DockerBackend) run(ctx context.Context, args ...string) (string, error) {
+	run := b.Run
+	if run == nil {
+		run = runCommand
+	}
+	return run(ctx, b.DockerBin, args...)
+}
+
+// CommandRunner runs a host command and returns its combined stdout and
+// stderr. Backends that shell out take one so tests can substitute a fake.
+type CommandRunner func(ctx context.Context, name string, args ...string) (string, error)
+
+// runCommand is the default CommandRunner. Each command gets a five-minute
+// ceiling so a wedged CLI cannot hold the manager lock forever.
+func runCommand(ctx context.Context, name string, args ...string) (string, error) {
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
 	defer cancel()
 
-	cmd := exec.CommandContext(ctx, b.DockerBin, args...)
+	cmd := exec.CommandContext(ctx, name, args...)
 	var buf bytes.Buffer
 	cmd.Stdout = &buf
 	cmd.Stderr = &buf
@@ -2,6 +2,7 @@ package provision
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -47,11 +48,22 @@ func (h *Handler) handleCreate(w http.ResponseWriter, r *http.Request) {
 
 	result, err := h.manager.Create(r.Context(), &spec)
 	if err != nil {
-		writeError(w, http.StatusBadRequest, err.Error())
+		switch {
+		case errors.Is(err, ErrQuotaExceeded):
+			writeError(w, http.StatusForbidden, err.Error())
+		case errors.Is(err, ErrInsufficientCapacity), errors.Is(err, ErrExceedsNodeCapacity):
+			writeError(w, http.StatusConflict, err.Error())
+		default:
+			writeError(w, http.StatusBadRequest, err.Error())
+		}
 		return
 	}
 
-	writeJSON(w, http.StatusCreated, result)
+	code := http.StatusCreated
+	if result.Queued {
+		code = http.StatusAccepted
+	}
+	writeJSON(w, code, result)
 }
 
 func (h *Handler) handleList(w http.ResponseWriter, r *http.Request) {
@@ -66,8 +78,9 @@ func (h *Handler) handleList(w http.ResponseWriter, r *http.Request) {
 	}
 
 	writeJSON(w, http.StatusOK, map[string]any{
-		"resources": resources,
-		"count":     len(resources),
+		"resources":    resources,
+		"count":        len(resources),
+		"reservations": h.manager.Reservations(),
 	})
 }
 
@@ -0,0 +1,254 @@
+package provision
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// defaultLXCPath is where LXC keeps container configs and root filesystems
+// when lxc-config cannot tell us otherwise.
+const defaultLXCPath = "/var/lib/lxc"
+
+// lxcPeriodUS is the cgroup v2 CPU bandwidth period used to express
+// fractional CPU limits as lxc.cgroup2.cpu.max.
+const lxcPeriodUS = 100000
+
+// LXCBackend implements resource lifecycle operations for system containers
+// using the classic LXC tools (lxc-create, lxc-start, lxc-info, ...). Like
+// DockerBackend it shells out, so it works in CGO_ENABLED=0 builds.
+//
+// The spec's Image is a distribution for the "download" template in the form
+// "dist:release" or "dist:release:arch", e.g. "ubuntu:24.04". Resource limits,
+// environment, bind mounts and the init command are appended to the
+// container's config file so they survive restarts.
+type LXCBackend struct {
+	// LXCPath is the LXC storage directory (lxc.lxcpath). Defaults to
+	// /var/lib/lxc.
+	LXCPath string
+
+	// Run executes the lxc-* commands. Defaults to running them on the host.
+	Run CommandRunner
+}
+
+// NewLXCBackend creates an LXCBackend. It verifies that the LXC tools are
+// available on PATH and asks lxc-config for the storage directory.
+func NewLXCBackend() (*LXCBackend, error) {
+	if _, err := exec.LookPath("lxc-create"); err != nil {
+		return nil, fmt.Errorf("lxc-create not found on PATH: %w", err)
+	}
+	b := &LXCBackend{LXCPath: defaultLXCPath, Run: runCommand}
+	if out, err := b.Run(context.Background(), "lxc-config", "lxc.lxcpath"); err == nil {
+		if p := strings.TrimSpace(out); p != "" {
+			b.LXCPath = p
+		}
+	}
+	return b, nil
+}
+
+// Create downloads the distribution image into a new container, writes the
+// spec into its config and starts it. It returns the container name.
+func (b *LXCBackend) Create(ctx context.Context, id string, spec *ResourceSpec) (containerID string, err error) {
+	dist, release, arch, err := parseLXCImage(spec.Image)
+	if err != nil {
+		return "", err
+	}
+	name := containerName(id, spec.Name)
+
+	out, err := b.run(ctx, "lxc-create", "-n", name, "-t", "download", "--",
+		"--dist", dist, "--release", release, "--arch", arch)
+	if err != nil {
+		return "", fmt.Errorf("lxc-create failed: %s: %w", strings.TrimSpace(out), err)
+	}
+
+	if err := b.appendConfig(id, name, spec); err != nil {
+		_, _ = b.run(ctx, "lxc-destroy", "-n", name)
+		return "", err
+	}
+
+	if out, err := b.run(ctx, "lxc-start", "-n", name, "-d"); err != nil {
+		_, _ = b.run(ctx, "lxc-destroy", "-n", name)
+		return "", fmt.Errorf("lxc-start failed: %s: %w", strings.TrimSpace(out), err)
+	}
+	return name, nil
+}
+
+// Destroy stops and removes a container. A container that no longer exists
+// is already destroyed.
+func (b *LXCBackend) Destroy(ctx context.Context, id string, spec *ResourceSpec) error {
+	name := containerName(id, spec.Name)
+	_, _ = b.run(ctx, "lxc-stop", "-n", name, "-k")
+	out, err := b.run(ctx, "lxc-destroy", "-n", name)
+	if err != nil {
+		if lxcNotFound(out, err) {
+			return nil
+		}
+		return fmt.Errorf("lxc-destroy failed: %s: %w", strings.TrimSpace(out), err)
+	}
+	return nil
+}
+
+// Inspect returns the running status of a container.
+func (b *LXCBackend) Inspect(ctx context.Context, id string, spec *ResourceSpec) (ResourceStatus, error) {
+	name := containerName(id, spec.Name)
+	out, err := b.run(ctx, "lxc-info", "-n", name, "-s", "-H")
+	if err != nil {
+		if lxcNotFound(out, err) {
+			return StatusDestroyed, nil
+		}
+		return StatusError, fmt.Errorf("lxc-info failed: %s: %w", strings.TrimSpace(out), err)
+	}
+
+	// -H prints the bare state; older releases still prefix "State:".
+	fields := strings.Fields(out)
+	if len(fields) == 0 {
+		return StatusError, fmt.Errorf("lxc-info returned no state")
+	}
+	switch state := fields[len(fields)-1]; state {
+	case "RUNNING":
+		return StatusRunning, nil
+	case "STOPPED", "FROZEN", "ABORTING":
+		return StatusStopped, nil
+	case "STARTING", "STOPPING", "FREEZING", "THAWED":
+		return StatusCreating, nil
+	default:
+		return StatusError, fmt.Errorf("unknown lxc state: %s", state)
+	}
+}
+
+// Logs returns the tail of the container's console log.
+func (b *LXCBackend) Logs(ctx context.Context, id string, spec *ResourceSpec, tail int) (string, error) {
+	if tail <= 0 {
+		tail = 100
+	}
+	data, err := os.ReadFile(b.consoleLog(containerName(id, spec.Name)))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", fmt.Errorf("reading console log: %w", err)
+	}
+	lines := strings.SplitAfter(string(data), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) > tail {
+		lines = lines[len(lines)-tail:]
+	}
+	return strings.Join(lines, ""), nil
+}
+
+// appendConfig adds the spec's settings to the container config that
+// lxc-create wrote.
+func (b *LXCBackend) appendConfig(id, name string, spec *ResourceSpec) error {
+	lines := []string{
+		"",
+		"# citadel resource " + id,
+		"lxc.start.auto = 1",
+		"lxc.console.logfile = " + b.consoleLog(name),
+	}
+
+	keys := make([]string, 0, len(spec.Env))
+	for k := range spec.Env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		lines = append(lines, "lxc.environment = "+k+"="+spec.Env[k])
+	}
+
+	for _, v := range spec.Volumes {
+		opts := "bind,create=dir"
+		if v.ReadOnly {
+			opts += ",ro"
+		}
+		lines = append(lines, fmt.Sprintf("lxc.mount.entry = %s %s none %s 0 0",
+			fstabEscape(v.HostPath), fstabEscape(strings.TrimPrefix(v.ContainerPath, "/")), opts))
+	}
+
+	if spec.CPUs != "" {
+		cpus, err := strconv.ParseFloat(spec.CPUs, 64)
+		if err != nil || cpus <= 0 {
+			return fmt.Errorf("invalid cpus %q", spec.CPUs)
+		}
+		lines = append(lines, fmt.Sprintf("lxc.cgroup2.cpu.max = %d %d", int(cpus*lxcPeriodUS), lxcPeriodUS))
+	}
+
+	if spec.MemoryMB > 0 {
+		lines = append(lines, fmt.Sprintf("lxc.cgroup2.memory.max = %dM", spec.MemoryMB))
+	}
+
+	if len(spec.Command) > 0 {
+		args := make([]string, len(spec.Command))
+		for i, a := range spec.Command {
+			args[i] = shellQuote(a)
+		}
+		lines = append(lines, "lxc.init.cmd = "+strings.Join(args, " "))
+	}
+
+	f, err := os.OpenFile(filepath.Join(b.LXCPath, name, "config"), os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		return fmt.Errorf("opening lxc config: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(strings.Join(lines, "\n") + "\n"); err != nil {
+		return fmt.Errorf("writing lxc config: %w", err)
+	}
+	return nil
+}
+
+func (b *LXCBackend) consoleLog(name string) string {
+	return filepath.Join(b.LXCPath, name, "console.log")
+}
+
+func (b *LXCBackend) run(ctx context.Context, name string, args ...string) (string, error) {
+	run := b.Run
+	if run == nil {
+		run = runCommand
+	}
+	return run(ctx, name, args...)
+}
+
+// parseLXCImage splits "dist:release[:arch]". The architecture defaults to
+// the host's.
+func parseLXCImage(image string) (dist, release, arch string, err error) {
+	parts := strings.Split(image, ":")
+	if len(parts) < 2 || len(parts) > 3 || parts[0] == "" || parts[1] == "" {
+		return "", "", "", fmt.Errorf("invalid lxc image %q: expected dist:release[:arch]", image)
+	}
+	arch = runtime.GOARCH
+	if len(parts) == 3 && parts[2] != "" {
+		arch = parts[2]
+	}
+	return parts[0], parts[1], arch, nil
+}
+
+// lxcNotFound reports whether an lxc-* failure means the container is not
+// defined.
+func lxcNotFound(out string, err error) bool {
+	msg := strings.ToLower(out + " " + err.Error())
+	return strings.Contains(msg, "doesn't exist") ||
+		strings.Contains(msg, "does not exist") ||
+		strings.Contains(msg, "not defined")
+}
+
+// fstabEscape escapes whitespace the way lxc.mount.entry (fstab syntax)
+// expects.
+func fstabEscape(s string) string {
+	return strings.NewReplacer(" ", `\040`, "\t", `\011`).Replace(s)
+}
+
+// shellQuote single-quotes an lxc.init.cmd argument when it contains
+// characters LXC would otherwise split on.
+func shellQuote(s string) string {
+	if s != "" && !strings.ContainsAny(s, " \t'\"\\") {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,242 @@
+package provision
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeLXC stands in for the lxc-* tools: lxc-create writes a config file
+// under the LXC path and the other commands act on an in-memory state table.
+type fakeLXC struct {
+	mu      sync.Mutex
+	lxcPath string
+	states  map[string]string
+	calls   []string
+	failOn  string
+}
+
+func newFakeLXC(t *testing.T) *fakeLXC {
+	return &fakeLXC{lxcPath: t.TempDir(), states: make(map[string]string)}
+}
+
+func (f *fakeLXC) run(_ context.Context, name string, args ...string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, name+" "+strings.Join(args, " "))
+	if name == f.failOn {
+		return name + ": boom", fmt.Errorf("exit status 1")
+	}
+	ct := args[1] // every call is "-n <name> ..."
+	if _, ok := f.states[ct]; !ok && name != "lxc-create" {
+		return ct + " doesn't exist", fmt.Errorf("exit status 1")
+	}
+	switch name {
+	case "lxc-create":
+		dir := filepath.Join(f.lxcPath, ct)
+		os.MkdirAll(dir, 0o755)
+		os.WriteFile(filepath.Join(dir, "config"), []byte("lxc.include = /usr/share/lxc/config/common.conf\n"), 0o644)
+		f.states[ct] = "STOPPED"
+	case "lxc-start":
+		f.states[ct] = "RUNNING"
+	case "lxc-stop":
+		f.states[ct] = "STOPPED"
+	case "lxc-destroy":
+		delete(f.states, ct)
+		os.RemoveAll(filepath.Join(f.lxcPath, ct))
+	case "lxc-info":
+		return f.states[ct] + "\n", nil
+	}
+	return "", nil
+}
+
+func (f *fakeLXC) backend() *LXCBackend {
+	return &LXCBackend{LXCPath: f.lxcPath, Run: f.run}
+}
+
+func newLXCTestManager(t *testing.T, f *fakeLXC) *Manager {
+	t.Helper()
+	store, err := NewStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewManager(store, map[ResourceType]Backend{ResourceTypeLXC: f.backend()})
+}
+
+func TestLXCCreateWritesConfigAndStarts(t *testing.T) {
+	f := newFakeLXC(t)
+	mgr := newLXCTestManager(t, f)
+
+	result, err := mgr.Create(context.Background(), &ResourceSpec{
+		Name: "box", Type: ResourceTypeLXC, Image: "ubuntu:24.04:arm64",
+		Env:      map[string]string{"B": "2", "A": "1"},
+		Volumes:  []VolumeMount{{HostPath: "/tmp/data dir", ContainerPath: "/data", ReadOnly: true}},
+		CPUs:     "1.5",
+		MemoryMB: 512,
+		Command:  []string{"/bin/sh", "-c", "echo hi"},
+	})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if result.Resource.Status != StatusRunning || result.Resource.ContainerID != "citadel-box" {
+		t.Errorf("resource = %+v, want running citadel-box", result.Resource)
+	}
+	if got := f.calls[0]; got != "lxc-create -n citadel-box -t download -- --dist ubuntu --release 24.04 --arch arm64" {
+		t.Errorf("create call = %q", got)
+	}
+
+	data, err := os.ReadFile(filepath.Join(f.lxcPath, "citadel-box", "config"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := string(data)
+	for _, want := range []string{
+		"lxc.include = /usr/share/lxc/config/common.conf\n",
+		"# citadel resource " + result.Resource.ID,
+		"lxc.start.auto = 1",
+		"lxc.console.logfile = " + filepath.Join(f.lxcPath, "citadel-box", "console.log"),
+		"lxc.environment = A=1\nlxc.environment = B=2",
+		`lxc.mount.entry = /tmp/data\040dir data none bind,create=dir,ro 0 0`,
+		"lxc.cgroup2.cpu.max = 150000 100000",
+		"lxc.cgroup2.memory.max = 512M",
+		`lxc.init.cmd = /bin/sh -c 'echo hi'`,
+	} {
+		if !strings.Contains(config, want) {
+			t.Errorf("config missing %q:\n%s", want, config)
+		}
+	}
+}
+
+func TestLXCCreateRejectsConfigInjection(t *testing.T) {
+	inject := "\nlxc.mount.entry = / host none bind 0 0"
+	for name, spec := range map[string]ResourceSpec{
+		"env value":      {Env: map[string]string{"A": "1" + inject}},
+		"env key":        {Env: map[string]string{"A" + inject: "1"}},
+		"host path":      {Volumes: []VolumeMount{{HostPath: "/tmp/data" + inject, ContainerPath: "/data"}}},
+		"container path": {Volumes: []VolumeMount{{HostPath: "/tmp/data", ContainerPath: "/data" + inject}}},
+		"command":        {Command: []string{"/bin/sh", "-c", "echo hi\rlxc.apparmor.profile = unconfined"}},
+		"nul":            {Command: []string{"/bin/sh\x00"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			f := newFakeLXC(t)
+			mgr := newLXCTestManager(t, f)
+			spec.Name, spec.Type, spec.Image = "box", ResourceTypeLXC, "ubuntu:24.04"
+			_, err := mgr.Create(context.Background(), &spec)
+			if err == nil || !strings.Contains(err.Error(), "control character") {
+				t.Fatalf("Create = %v, want a control character error", err)
+			}
+			if len(f.calls) != 0 {
+				t.Errorf("a rejected spec reached lxc: %v", f.calls)
+			}
+		})
+	}
+}
+
+func TestLXCCreateStartFailureRemovesContainer(t *testing.T) {
+	f := newFakeLXC(t)
+	f.failOn = "lxc-start"
+	mgr := newLXCTestManager(t, f)
+
+	if _, err := mgr.Create(context.Background(), &ResourceSpec{Name: "box", Type: ResourceTypeLXC, Image: "debian:12"}); err == nil {
+		t.Fatal("expected error")
+	}
+	if _, ok := f.states["citadel-box"]; ok {
+		t.Error("container left behind after failed start")
+	}
+	if r := mgr.List()[0]; r.Status != StatusError {
+		t.Errorf("status = %s, want error", r.Status)
+	}
+}
+
+func TestLXCInspectAndReconcile(t *testing.T) {
+	f := newFakeLXC(t)
+	mgr := newLXCTestManager(t, f)
+	ctx := context.Background()
+
+	result, err := mgr.Create(ctx, &ResourceSpec{Name: "box", Type: ResourceTypeLXC, Image: "debian:12"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := result.Resource.ID
+
+	f.states["citadel-box"] = "STOPPED"
+	mgr.ReconcileAll(ctx)
+	if r := mgr.Get(id); r.Status != StatusStopped {
+		t.Fatalf("after stop: status = %s, want stopped", r.Status)
+	}
+
+	delete(f.states, "citadel-box")
+	mgr.ReconcileAll(ctx)
+	if r := mgr.Get(id); r.Status != StatusDestroyed {
+		t.Fatalf("after removal: status = %s, want destroyed", r.Status)
+	}
+}
+
+func TestLXCReconcileRecoversInterruptedCreate(t *testing.T) {
+	f := newFakeLXC(t)
+	mgr := newLXCTestManager(t, f)
+	ctx := context.Background()
+
+	// The daemon died after lxc-create but before the resource was saved as
+	// running: the record says creating and the container exists, stopped.
+	r := &Resource{ID: "0123456789ab", Spec: ResourceSpec{Name: "box", Type: ResourceTypeLXC, Image: "debian:12"}, Status: StatusCreating}
+	if err := mgr.store.Put(r); err != nil {
+		t.Fatal(err)
+	}
+	f.run(ctx, "lxc-create", "-n", "citadel-box")
+
+	mgr.ReconcileAll(ctx)
+	if got := mgr.Get(r.ID); got.Status != StatusStopped {
+		t.Fatalf("status = %s, want stopped", got.Status)
+	}
+	if err := mgr.Destroy(ctx, r.ID); err != nil {
+		t.Fatalf("Destroy: %v", err)
+	}
+	if _, ok := f.states["citadel-box"]; ok {
+		t.Error("orphaned container not destroyed")
+	}
+}
+
+func TestLXCDestroyMissingContainer(t *testing.T) {
+	f := newFakeLXC(t)
+	b := f.backend()
+	if err := b.Destroy(context.Background(), "id", &ResourceSpec{Name: "gone"}); err != nil {
+		t.Fatalf("Destroy of missing container: %v", err)
+	}
+}
+
+func TestLXCLogsTailsConsoleLog(t *testing.T) {
+	f := newFakeLXC(t)
+	b := f.backend()
+	spec := &ResourceSpec{Name: "box"}
+	ctx := context.Background()
+
+	if out, err := b.Logs(ctx, "id", spec, 10); err != nil || out != "" {
+		t.Fatalf("Logs before boot = %q, %v; want empty", out, err)
+	}
+
+	dir := filepath.Join(f.lxcPath, "citadel-box")
+	os.MkdirAll(dir, 0o755)
+	os.WriteFile(filepath.Join(dir, "console.log"), []byte("one\ntwo\nthree\n"), 0o644)
+	out, err := b.Logs(ctx, "id", spec, 2)
+	if err != nil || out != "two\nthree\n" {
+		t.Errorf("Logs = %q, %v; want last two lines", out, err)
+	}
+}
+
+func TestParseLXCImage(t *testing.T) {
+	if _, _, _, err := parseLXCImage("nginx:latest:amd64:x"); err == nil {
+		t.Error("expected error for too many parts")
+	}
+	if _, _, _, err := parseLXCImage("ubuntu"); err == nil {
+		t.Error("expected error without a release")
+	}
+	dist, release, arch, err := parseLXCImage("alpine:3.20:riscv64")
+	if err != nil || dist != "alpine" || release != "3.20" || arch != "riscv64" {
+		t.Errorf("parseLXCImage = %s %s %s %v", dist, release, arch, err)
+	}
+}
@@ -2,6 +2,7 @@ package provision
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"sync"
@@ -24,6 +25,14 @@ type Manager struct {
 	mu       sync.Mutex
 	store    *Store
 	backends map[ResourceType]Backend
+
+	// admission, when set by EnableAdmission, gates Create on quotas and
+	// node capacity.
+	admission *AdmissionConfig
+	// held is the GPU devices this manager acquired for resources. Syncing
+	// with the store only ever releases these, never a device a GPU job
+	// holds.
+	held map[int]bool
 }
 
 // NewManager creates a Manager with the given store and backends.
@@ -31,6 +40,7 @@ func NewManager(store *Store, backends map[ResourceType]Backend) *Manager {
 	return &Manager{
 		store:    store,
 		backends: backends,
+		held:     make(map[int]bool),
 	}
 }
 
@@ -38,6 +48,11 @@ func NewManager(store *Store, backends map[ResourceType]Backend) *Manager {
 // the same name already exists and is not destroyed, the existing resource
 // is returned (idempotency). The resource is persisted before and after
 // the backend call so crash recovery can detect orphans.
+//
+// With admission control enabled the spec must also fit its org's quota and
+// the node's free capacity. A spec that does not fit is rejected with
+// ErrQuotaExceeded, ErrInsufficientCapacity or ErrExceedsNodeCapacity, or,
+// when the policy queues, persisted as queued and returned with Queued set.
 func (m *Manager) Create(ctx context.Context, spec *ResourceSpec) (*CreateResult, error) {
 	if err := ValidateSpec(spec); err != nil {
 		return nil, fmt.Errorf("invalid spec: %w", err)
@@ -45,6 +60,7 @@ func (m *Manager) Create(ctx context.Context, spec *ResourceSpec) (*CreateResult
 
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	m.syncLocked()
 
 	if existing := m.store.FindByName(spec.Name); existing != nil {
 		if existing.Status != StatusDestroyed {
@@ -67,17 +83,62 @@ func (m *Manager) Create(ctx context.Context, spec *ResourceSpec) (*CreateResult
 		UpdatedAt: now,
 	}
 
+	if m.admission != nil {
+		m.admission.applyDefaults(&r.Spec)
+		var capacity *NodeCapacity
+		if onNode(r.Spec.Type) {
+			c, err := m.admission.Probe(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("measuring node capacity: %w", err)
+			}
+			capacity = &c
+		}
+		if err := m.admitLocked(r, capacity); err != nil {
+			if !m.admission.Policy.Queue || !errors.Is(err, ErrInsufficientCapacity) {
+				return nil, err
+			}
+			r.Status = StatusQueued
+			r.Error = err.Error()
+			if err := m.store.Put(r); err != nil {
+				return nil, fmt.Errorf("persisting resource: %w", err)
+			}
+			return &CreateResult{Resource: r, Queued: true}, nil
+		}
+	}
+
+	if err := m.startLocked(ctx, backend, r); err != nil {
+		return nil, err
+	}
+
+	return &CreateResult{Resource: r}, nil
+}
+
+// startLocked persists r as creating, runs the backend create and records
+// the outcome. Pinned GPUs are handed to the backend as a device list and
+// released again if the create fails.
+func (m *Manager) startLocked(ctx context.Context, backend Backend, r *Resource) error {
+	spec := r.Spec
+	if len(r.GPUDevices) > 0 {
+		spec.GPUs = deviceArg(r.GPUDevices)
+	}
+
+	r.Status = StatusCreating
+	r.Error = ""
+	r.UpdatedAt = time.Now().UTC()
 	if err := m.store.Put(r); err != nil {
-		return nil, fmt.Errorf("persisting resource: %w", err)
+		m.releaseGPUsLocked(r.GPUDevices)
+		return fmt.Errorf("persisting resource: %w", err)
 	}
 
-	containerID, err := backend.Create(ctx, r.ID, spec)
+	containerID, err := backend.Create(ctx, r.ID, &spec)
 	if err != nil {
+		m.releaseGPUsLocked(r.GPUDevices)
+		r.GPUDevices = nil
 		r.Status = StatusError
 		r.Error = err.Error()
 		r.UpdatedAt = time.Now().UTC()
 		_ = m.store.Put(r)
-		return nil, fmt.Errorf("creating resource: %w", err)
+		return fmt.Errorf("creating resource: %w", err)
 	}
 
 	r.ContainerID = containerID
@@ -85,16 +146,74 @@ func (m *Manager) Create(ctx context.Context, spec *ResourceSpec) (*CreateResult
 	r.UpdatedAt = time.Now().UTC()
 
 	if err := m.store.Put(r); err != nil {
-		return nil, fmt.Errorf("persisting resource after create: %w", err)
+		return fmt.Errorf("persisting resource after create: %w", err)
 	}
+	return nil
+}
 
-	return &CreateResult{Resource: r}, nil
+// gpus returns the admission GPU allocator, or nil.
+func (m *Manager) gpus() GPUAllocator {
+	if m.admission == nil {
+		return nil
+	}
+	return m.admission.GPUs
+}
+
+// syncLocked reloads the store, since the CLI and the daemon share it, and
+// brings the GPU allocator in line with it: devices pinned by a resource
+// another process created are acquired, and devices of a resource another
+// process destroyed are released. Without it, each process would pin GPUs
+// blind to the other's resources. Caller holds m.mu.
+func (m *Manager) syncLocked() {
+	if err := m.store.Reload(); err != nil {
+		log.Printf("[provision] reloading resource store: %v", err)
+	}
+	alloc := m.gpus()
+	if alloc == nil {
+		return
+	}
+	pinned := make(map[int]bool)
+	for _, r := range m.store.List() {
+		if holdsCapacity(r.Status) {
+			for _, d := range r.GPUDevices {
+				pinned[d] = true
+			}
+		}
+	}
+	for d := range m.held {
+		if !pinned[d] {
+			alloc.Release(d)
+			delete(m.held, d)
+		}
+	}
+	for d := range pinned {
+		if !m.held[d] && alloc.AcquireSpecific(d) {
+			m.held[d] = true
+		}
+	}
+}
+
+// holdGPUsLocked records devices just pinned to a resource. Caller holds m.mu.
+func (m *Manager) holdGPUsLocked(devices []int) {
+	for _, d := range devices {
+		m.held[d] = true
+	}
+}
+
+// releaseGPUsLocked gives back devices pinned to a resource. Caller holds
+// m.mu.
+func (m *Manager) releaseGPUsLocked(devices []int) {
+	releaseGPUs(m.gpus(), devices)
+	for _, d := range devices {
+		delete(m.held, d)
+	}
 }
 
 // Destroy stops and removes a resource.
 func (m *Manager) Destroy(ctx context.Context, id string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	m.syncLocked()
 
 	r := m.store.Get(id)
 	if r == nil {
@@ -105,23 +224,34 @@ func (m *Manager) Destroy(ctx context.Context, id string) error {
 		return nil
 	}
 
-	backend, ok := m.backends[r.Spec.Type]
-	if !ok {
-		return fmt.Errorf("no backend for resource type %q", r.Spec.Type)
-	}
+	// A queued resource was never started; there is nothing to tear down.
+	if r.Status != StatusQueued {
+		backend, ok := m.backends[r.Spec.Type]
+		if !ok {
+			return fmt.Errorf("no backend for resource type %q", r.Spec.Type)
+		}
 
-	if err := backend.Destroy(ctx, r.ID, &r.Spec); err != nil {
-		r.Status = StatusError
-		r.Error = err.Error()
-		r.UpdatedAt = time.Now().UTC()
-		_ = m.store.Put(r)
-		return fmt.Errorf("destroying resource: %w", err)
+		if err := backend.Destroy(ctx, r.ID, &r.Spec); err != nil {
+			// A failed teardown usually leaves the container behind, still
+			// holding its GPUs, so the resource keeps its status, pins and
+			// capacity. They are freed by a later Destroy that succeeds or
+			// by Status once Inspect finds the container gone.
+			r.Error = "destroy failed: " + err.Error()
+			r.UpdatedAt = time.Now().UTC()
+			_ = m.store.Put(r)
+			return fmt.Errorf("destroying resource: %w", err)
+		}
 	}
 
+	m.releaseGPUsLocked(r.GPUDevices)
 	r.Status = StatusDestroyed
 	r.UpdatedAt = time.Now().UTC()
 
-	return m.store.Put(r)
+	if err := m.store.Put(r); err != nil {
+		return err
+	}
+	m.admitQueuedLocked(ctx)
+	return nil
 }
 
 // Status returns the current status of a resource after reconciling with
@@ -135,7 +265,7 @@ func (m *Manager) Status(ctx context.Context, id string) (*Resource, error) {
 		return nil, fmt.Errorf("resource %q not found", id)
 	}
 
-	if r.Status == StatusDestroyed {
+	if r.Status == StatusDestroyed || r.Status == StatusQueued {
 		return r, nil
 	}
 
@@ -149,15 +279,27 @@ func (m *Manager) Status(ctx context.Context, id string) (*Resource, error) {
 		return r, fmt.Errorf("inspecting resource: %w", err)
 	}
 
-	if actualStatus != r.Status {
-		r.Status = actualStatus
-		r.UpdatedAt = time.Now().UTC()
-		_ = m.store.Put(r)
+	if actualStatus != r.Status && m.setStatusLocked(r, actualStatus) {
+		m.admitQueuedLocked(ctx)
 	}
 
 	return r, nil
 }
 
+// setStatusLocked records a status observed on the backend. A resource found
+// destroyed gives back its GPU pins as Destroy does, and true is returned so
+// the caller admits queued resources into the freed capacity.
+func (m *Manager) setStatusLocked(r *Resource, status ResourceStatus) (freed bool) {
+	if status == StatusDestroyed {
+		m.releaseGPUsLocked(r.GPUDevices)
+		freed = true
+	}
+	r.Status = status
+	r.UpdatedAt = time.Now().UTC()
+	_ = m.store.Put(r)
+	return freed
+}
+
 // List returns all resources.
 func (m *Manager) List() []*Resource {
 	return m.store.List()
@@ -174,6 +316,9 @@ func (m *Manager) Logs(ctx context.Context, id string, tail int) (string, error)
 	if r == nil {
 		return "", fmt.Errorf("resource %q not found", id)
 	}
+	if r.Status == StatusQueued {
+		return "", fmt.Errorf("resource %q is queued and has not started", id)
+	}
 
 	backend, ok := m.backends[r.Spec.Type]
 	if !ok {
@@ -184,13 +329,15 @@ func (m *Manager) Logs(ctx context.Context, id string, tail int) (string, error)
 }
 
 // ReconcileAll reconciles the status of all non-destroyed resources with
-// their backends. This is used for crash recovery on startup.
+// their backends. This is used for crash recovery on startup. It does not
+// start queued resources; the daemon calls AdmitQueued for that, so a
+// short-lived CLI process reconciling its view never launches anything.
 func (m *Manager) ReconcileAll(ctx context.Context) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
 	for _, r := range m.store.List() {
-		if r.Status == StatusDestroyed {
+		if r.Status == StatusDestroyed || r.Status == StatusQueued {
 			continue
 		}
 
@@ -207,9 +354,7 @@ func (m *Manager) ReconcileAll(ctx context.Context) {
 
 		if actualStatus != r.Status {
 			log.Printf("[provision] reconcile %s (%s): %s -> %s", r.Spec.Name, r.ID, r.Status, actualStatus)
-			r.Status = actualStatus
-			r.UpdatedAt = time.Now().UTC()
-			_ = m.store.Put(r)
+			m.setStatusLocked(r, actualStatus)
 		}
 	}
 }
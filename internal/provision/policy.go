@@ -0,0 +1,79 @@
+package provision
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const policyFileName = "provision-policy.json"
+
+// DefaultQuotaKey is the Policy.Quotas entry that applies to orgs without an
+// entry of their own, including specs that name no org.
+const DefaultQuotaKey = "*"
+
+// Policy configures admission control for provisioned resources. It lives in
+// provision-policy.json in the Citadel config directory; a missing file means
+// the zero Policy, which admits anything that fits the node.
+type Policy struct {
+	// ReserveCPUs and ReserveMemoryMB are held back from provisioning for the
+	// host itself, on top of what the inference engines already use.
+	ReserveCPUs     float64 `json:"reserve_cpus,omitempty"`
+	ReserveMemoryMB int     `json:"reserve_memory_mb,omitempty"`
+
+	// DefaultCPUs and DefaultMemoryMB are written into docker and lxc specs
+	// that set no limit, so every resource is both bounded and accounted.
+	// Zero leaves such specs unlimited and unaccounted on that axis.
+	DefaultCPUs     float64 `json:"default_cpus,omitempty"`
+	DefaultMemoryMB int     `json:"default_memory_mb,omitempty"`
+
+	// Queue parks specs that do not fit the node's free capacity instead of
+	// rejecting them. Queued resources start, oldest first, as capacity frees
+	// up. Quota violations are always rejected.
+	Queue bool `json:"queue,omitempty"`
+
+	// Quotas caps each org's total reservations, keyed by org ID. The "*"
+	// entry applies to orgs with no entry of their own.
+	Quotas map[string]Quota `json:"quotas,omitempty"`
+}
+
+// Quota caps one org's reservations. Zero fields are unlimited.
+type Quota struct {
+	CPUs      float64 `json:"cpus,omitempty"`
+	MemoryMB  int     `json:"memory_mb,omitempty"`
+	GPUs      int     `json:"gpus,omitempty"`
+	Resources int     `json:"resources,omitempty"`
+}
+
+// quotaFor returns the quota that applies to orgID.
+func (p Policy) quotaFor(orgID string) (Quota, bool) {
+	if q, ok := p.Quotas[orgID]; ok && orgID != "" {
+		return q, true
+	}
+	q, ok := p.Quotas[DefaultQuotaKey]
+	return q, ok
+}
+
+// PolicyPath returns the path of the provisioning policy file for the given
+// config directory.
+func PolicyPath(configDir string) string {
+	return filepath.Join(configDir, policyFileName)
+}
+
+// LoadPolicy reads the provisioning policy from the given config directory.
+// A missing file yields the zero Policy.
+func LoadPolicy(configDir string) (Policy, error) {
+	data, err := os.ReadFile(PolicyPath(configDir))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return Policy{}, nil
+		}
+		return Policy{}, fmt.Errorf("reading provision policy: %w", err)
+	}
+	var p Policy
+	if err := json.Unmarshal(data, &p); err != nil {
+		return Policy{}, fmt.Errorf("parsing provision policy: %w", err)
+	}
+	return p, nil
+}
@@ -180,9 +180,30 @@ func TestValidateSpec(t *testing.T) {
 			wantErr: "invalid name",
 		},
 		{
-			name:    "unsupported lxc",
-			spec:    ResourceSpec{Name: "test", Type: ResourceTypeLXC, Image: "ubuntu"},
-			wantErr: "not yet supported",
+			name: "lxc ports",
+			spec: ResourceSpec{
+				Name: "test", Type: ResourceTypeLXC, Image: "ubuntu:24.04",
+				Ports: []PortMapping{{HostPort: 8080, ContainerPort: 80}},
+			},
+			wantErr: "ports not supported for lxc",
+		},
+		{
+			name:    "lxc missing image",
+			spec:    ResourceSpec{Name: "test", Type: ResourceTypeLXC},
+			wantErr: "image is required",
+		},
+		{
+			name:    "vm command",
+			spec:    ResourceSpec{Name: "test", Type: ResourceTypeVM, Command: []string{"sh"}, GPUs: "all"},
+			wantErr: "command, gpus not supported for vm",
+		},
+		{
+			name: "valid lxc",
+			spec: ResourceSpec{Name: "box", Type: ResourceTypeLXC, Image: "ubuntu:24.04", MemoryMB: 512},
+		},
+		{
+			name: "valid vm without image",
+			spec: ResourceSpec{Name: "vm-1", Type: ResourceTypeVM, CPUs: "2", MemoryMB: 4096},
 		},
 		{
 			name:    "unknown type",
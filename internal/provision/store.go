@@ -83,6 +83,22 @@ func (s *Store) Delete(id string) error {
 	return s.save()
 }
 
+// Reload replaces the in-memory state with what is on disk, picking up
+// resources another process (the CLI, or the daemon) wrote since this store
+// loaded. On error the current state is kept.
+func (s *Store) Reload() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	prev := s.resources
+	s.resources = make(map[string]*Resource)
+	if err := s.load(); err != nil {
+		s.resources = prev
+		return err
+	}
+	return nil
+}
+
 // load reads the store file from disk into memory.
 func (s *Store) load() error {
 	data, err := os.ReadFile(s.path)
@@ -121,5 +137,11 @@ func (s *Store) save() error {
 		return fmt.Errorf("marshalling resource store: %w", err)
 	}
 
-	return os.WriteFile(s.path, data, 0600)
+	// Write then rename, so a process reloading the store never reads a
+	// half-written file.
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
 }
@@ -19,6 +19,7 @@ const (
 type ResourceStatus string
 
 const (
+	StatusQueued    ResourceStatus = "queued"
 	StatusCreating  ResourceStatus = "creating"
 	StatusRunning   ResourceStatus = "running"
 	StatusStopped   ResourceStatus = "stopped"
@@ -56,11 +57,16 @@ type ResourceSpec struct {
 	// Name is a human-readable identifier (must be unique per node).
 	Name string `json:"name"`
 
-	// Type selects the backend: "docker" (MVP), "lxc", or "vm" (future).
+	// Type selects the backend: "docker", "lxc", or "vm".
 	Type ResourceType `json:"type"`
 
-	// Image is the container image reference (e.g., "nginx:latest").
-	// Required for docker and lxc types.
+	// OrgID is the org the resource is provisioned for. Quotas are enforced
+	// per org; an empty OrgID falls under the policy's default quota.
+	OrgID string `json:"org_id,omitempty"`
+
+	// Image is the container image reference (e.g., "nginx:latest") for
+	// docker, the distribution (e.g., "ubuntu:24.04") for lxc, and the
+	// template VMID or name for vm. Required for docker and lxc types.
 	Image string `json:"image,omitempty"`
 
 	// Env is a map of environment variables injected into the resource.
@@ -82,7 +88,9 @@ type ResourceSpec struct {
 	MemoryMB int `json:"memory_mb,omitempty"`
 
 	// GPUs requests GPU access. "all" passes all GPUs; a number like "1"
-	// requests that many.
+	// requests that many; "device=0,2" asks for those devices. With admission
+	// control enabled the request is pinned to specific devices, recorded in
+	// Resource.GPUDevices.
 	GPUs string `json:"gpus,omitempty"`
 }
 
@@ -100,7 +108,12 @@ type Resource struct {
 	// ContainerID is the backend-specific identifier (e.g., Docker container ID).
 	ContainerID string `json:"container_id,omitempty"`
 
-	// Error records the last error message, if Status == "error".
+	// GPUDevices are the GPU indices pinned to the resource by admission
+	// control. They stay reserved until the resource is destroyed.
+	GPUDevices []int `json:"gpu_devices,omitempty"`
+
+	// Error records the last error message, if Status == "error", or why a
+	// queued resource is still waiting.
 	Error string `json:"error,omitempty"`
 
 	// CreatedAt is when the resource was first created.
@@ -116,4 +129,7 @@ type CreateResult struct {
 	// Reused is true when an existing resource with the same name was returned
 	// instead of creating a new one (idempotency).
 	Reused bool `json:"reused,omitempty"`
+	// Queued is true when the spec did not fit the node's free capacity and
+	// was parked; it starts once capacity frees up.
+	Queued bool `json:"queued,omitempty"`
 }
@@ -5,6 +5,7 @@ import (
 	"path/filepath"
 	"regexp"
 	"strings"
+	"unicode"
 )
 
 // validNameRe restricts resource names to safe characters.
@@ -41,16 +42,22 @@ func ValidateSpec(spec *ResourceSpec) error {
 	}
 
 	switch spec.Type {
-	case ResourceTypeDocker:
-		// OK
-	case ResourceTypeLXC, ResourceTypeVM:
-		return fmt.Errorf("resource type %q is not yet supported", spec.Type)
+	case ResourceTypeDocker, ResourceTypeLXC:
+		if spec.Image == "" {
+			return fmt.Errorf("image is required for %s resources", spec.Type)
+		}
+	case ResourceTypeVM:
+		// Image optionally names the template; the backend has a default.
 	default:
 		return fmt.Errorf("unknown resource type %q", spec.Type)
 	}
 
-	if spec.Image == "" {
-		return fmt.Errorf("image is required for %s resources", spec.Type)
+	if err := validateTypeFields(spec); err != nil {
+		return err
+	}
+
+	if err := validateNoControlChars(spec); err != nil {
+		return err
 	}
 
 	for _, v := range spec.Volumes {
@@ -81,6 +88,78 @@ func ValidateSpec(spec *ResourceSpec) error {
 	return nil
 }
 
+// validateTypeFields rejects spec fields the resource type's backend cannot
+// honour, rather than silently dropping them. LXC containers get their own
+// address on the bridge, so there is nothing to publish; a VM is a clone of a
+// template and only takes its size from the spec.
+func validateTypeFields(spec *ResourceSpec) error {
+	var unsupported []string
+	switch spec.Type {
+	case ResourceTypeLXC:
+		if len(spec.Ports) > 0 {
+			unsupported = append(unsupported, "ports")
+		}
+		if spec.GPUs != "" {
+			unsupported = append(unsupported, "gpus")
+		}
+	case ResourceTypeVM:
+		if len(spec.Env) > 0 {
+			unsupported = append(unsupported, "env")
+		}
+		if len(spec.Ports) > 0 {
+			unsupported = append(unsupported, "ports")
+		}
+		if len(spec.Volumes) > 0 {
+			unsupported = append(unsupported, "volumes")
+		}
+		if len(spec.Command) > 0 {
+			unsupported = append(unsupported, "command")
+		}
+		if spec.GPUs != "" {
+			unsupported = append(unsupported, "gpus")
+		}
+	}
+	if len(unsupported) > 0 {
+		return fmt.Errorf("%s not supported for %s resources", strings.Join(unsupported, ", "), spec.Type)
+	}
+	return nil
+}
+
+// validateNoControlChars rejects control characters in every field a backend
+// writes into a line-oriented config file. The LXC backend appends env, mounts
+// and the init command to the container config one setting per line, so a
+// newline in any of them would add arbitrary lxc.* settings of the caller's
+// choosing (a bind mount of host /, an unconfined AppArmor profile, ...).
+func validateNoControlChars(spec *ResourceSpec) error {
+	check := func(field, value string) error {
+		if i := strings.IndexFunc(value, unicode.IsControl); i >= 0 {
+			return fmt.Errorf("%s contains control character %q", field, value[i])
+		}
+		return nil
+	}
+	fields := [][2]string{
+		{"image", spec.Image},
+		{"org_id", spec.OrgID},
+		{"cpus", spec.CPUs},
+		{"gpus", spec.GPUs},
+	}
+	for k, v := range spec.Env {
+		fields = append(fields, [2]string{"env key", k}, [2]string{"env " + k, v})
+	}
+	for _, v := range spec.Volumes {
+		fields = append(fields, [2]string{"volume host_path", v.HostPath}, [2]string{"volume container_path", v.ContainerPath})
+	}
+	for _, a := range spec.Command {
+		fields = append(fields, [2]string{"command", a})
+	}
+	for _, f := range fields {
+		if err := check(f[0], f[1]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func validateVolumePath(hostPath string) error {
 	if hostPath == "" {
 		return fmt.Errorf("host_path is required")
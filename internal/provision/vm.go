@@ -0,0 +1,298 @@
+package provision
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aceteam-ai/citadel-cli/internal/proxmox"
+)
+
+// vmTaskTimeout bounds each clone or delete task on the hypervisor.
+const vmTaskTimeout = 5 * time.Minute
+
+// vmLogTasks is how many hypervisor tasks Logs reports when no tail is given.
+const vmLogTasks = 50
+
+// Hypervisor is the slice of the Proxmox VE API the VM backend drives.
+// *proxmox.Client satisfies it.
+type Hypervisor interface {
+	NextID(ctx context.Context) (int, error)
+	ListVMs(ctx context.Context, node string) ([]proxmox.Guest, error)
+	CloneVM(ctx context.Context, node string, srcVMID, newVMID int, opts proxmox.CloneOptions) (string, error)
+	ConfigureVM(ctx context.Context, node string, vmid int, params map[string]string) error
+	StartGuest(ctx context.Context, node, guestType string, vmid int) error
+	StopGuest(ctx context.Context, node, guestType string, vmid int) error
+	DeleteVM(ctx context.Context, node string, vmid int, purge bool) (string, error)
+	WaitForTask(ctx context.Context, node, upid string, timeout time.Duration) error
+	ListGuestTasks(ctx context.Context, node string, vmid, limit int) ([]proxmox.Task, error)
+	GetGuestConfig(ctx context.Context, node, guestType string, vmid int) (json.RawMessage, error)
+}
+
+// VMConfig configures a VMBackend.
+type VMConfig struct {
+	// Node is the PVE node VMs are created on. Required.
+	Node string
+
+	// TemplateVMID is the template cloned when a spec names no image.
+	TemplateVMID int
+
+	// Storage is the target storage for full clones (optional; PVE default).
+	Storage string
+}
+
+// VMBackend implements resource lifecycle operations for virtual machines on
+// a Proxmox VE hypervisor. A VM is a full clone of a template: the spec's
+// Image names the template by VMID or name, falling back to
+// VMConfig.TemplateVMID. CPUs and MemoryMB size the clone.
+//
+// VMs are found again by name ("citadel-<spec name>"), not by the VMID
+// recorded at creation, so a VM cloned just before a crash is still
+// reconciled and destroyed. A VM of that name counts as the resource's only
+// when it carries the citadel tag or the "citadel resource <id>" description
+// (set on the clone itself, before anything can be interrupted): a name alone
+// could be an operator's VM, and Destroy purges its disks.
+type VMBackend struct {
+	client Hypervisor
+	cfg    VMConfig
+}
+
+// NewVMBackend creates a VMBackend that drives the given hypervisor.
+func NewVMBackend(client Hypervisor, cfg VMConfig) (*VMBackend, error) {
+	if client == nil {
+		return nil, fmt.Errorf("hypervisor client is required")
+	}
+	if cfg.Node == "" {
+		return nil, fmt.Errorf("hypervisor node is required")
+	}
+	return &VMBackend{client: client, cfg: cfg}, nil
+}
+
+// Create clones the template, sizes the clone and boots it. It returns the
+// new VMID.
+func (b *VMBackend) Create(ctx context.Context, id string, spec *ResourceSpec) (containerID string, err error) {
+	name := vmName(id, spec.Name)
+	guests, err := b.client.ListVMs(ctx, b.cfg.Node)
+	if err != nil {
+		return "", fmt.Errorf("listing VMs: %w", err)
+	}
+	if g := findVM(guests, name); g != nil {
+		return "", fmt.Errorf("VM %q already exists (vmid %d)", name, g.VMID)
+	}
+	template, err := b.template(guests, spec.Image)
+	if err != nil {
+		return "", err
+	}
+
+	vmid, err := b.client.NextID(ctx)
+	if err != nil {
+		return "", fmt.Errorf("allocating VMID: %w", err)
+	}
+	upid, err := b.client.CloneVM(ctx, b.cfg.Node, template, vmid, proxmox.CloneOptions{
+		Name:        name,
+		Full:        true,
+		Storage:     b.cfg.Storage,
+		Description: vmDescription(id),
+	})
+	if err != nil {
+		return "", fmt.Errorf("cloning template %d: %w", template, err)
+	}
+	if err := b.client.WaitForTask(ctx, b.cfg.Node, upid, vmTaskTimeout); err != nil {
+		b.cleanup(ctx, vmid)
+		return "", fmt.Errorf("clone task: %w", err)
+	}
+
+	params := map[string]string{
+		"tags":        vmTag,
+		"description": vmDescription(id),
+	}
+	if spec.CPUs != "" {
+		cpus, err := strconv.ParseFloat(spec.CPUs, 64)
+		if err != nil || cpus <= 0 {
+			b.cleanup(ctx, vmid)
+			return "", fmt.Errorf("invalid cpus %q", spec.CPUs)
+		}
+		params["cores"] = strconv.Itoa(int(math.Ceil(cpus)))
+	}
+	if spec.MemoryMB > 0 {
+		params["memory"] = strconv.Itoa(spec.MemoryMB)
+	}
+	if err := b.client.ConfigureVM(ctx, b.cfg.Node, vmid, params); err != nil {
+		b.cleanup(ctx, vmid)
+		return "", fmt.Errorf("configuring VM %d: %w", vmid, err)
+	}
+
+	if err := b.client.StartGuest(ctx, b.cfg.Node, "qemu", vmid); err != nil {
+		b.cleanup(ctx, vmid)
+		return "", fmt.Errorf("starting VM %d: %w", vmid, err)
+	}
+	return strconv.Itoa(vmid), nil
+}
+
+// Destroy stops and deletes the VM, purging its disks. A VM that no longer
+// exists is already destroyed.
+func (b *VMBackend) Destroy(ctx context.Context, id string, spec *ResourceSpec) error {
+	g, err := b.lookup(ctx, id, spec)
+	if err != nil {
+		return err
+	}
+	if g == nil {
+		return nil
+	}
+	_ = b.client.StopGuest(ctx, b.cfg.Node, "qemu", g.VMID)
+	upid, err := b.client.DeleteVM(ctx, b.cfg.Node, g.VMID, true)
+	if err != nil {
+		return fmt.Errorf("deleting VM %d: %w", g.VMID, err)
+	}
+	if err := b.client.WaitForTask(ctx, b.cfg.Node, upid, vmTaskTimeout); err != nil {
+		return fmt.Errorf("delete task: %w", err)
+	}
+	return nil
+}
+
+// Inspect returns the running status of the VM.
+func (b *VMBackend) Inspect(ctx context.Context, id string, spec *ResourceSpec) (ResourceStatus, error) {
+	g, err := b.lookup(ctx, id, spec)
+	if err != nil {
+		return StatusError, err
+	}
+	if g == nil {
+		return StatusDestroyed, nil
+	}
+	// PVE locks a guest while a clone or config change is in flight.
+	if g.Lock == "clone" || g.Lock == "create" {
+		return StatusCreating, nil
+	}
+	switch g.Status {
+	case "running":
+		return StatusRunning, nil
+	case "stopped", "paused":
+		return StatusStopped, nil
+	default:
+		return StatusError, fmt.Errorf("unknown VM state: %s", g.Status)
+	}
+}
+
+// Logs returns the VM's recent hypervisor task history, oldest first. Guest
+// console output is not reachable through the PVE API.
+func (b *VMBackend) Logs(ctx context.Context, id string, spec *ResourceSpec, tail int) (string, error) {
+	g, err := b.lookup(ctx, id, spec)
+	if err != nil {
+		return "", err
+	}
+	if g == nil {
+		return "", fmt.Errorf("VM %q not found", vmName(id, spec.Name))
+	}
+	if tail <= 0 {
+		tail = vmLogTasks
+	}
+	tasks, err := b.client.ListGuestTasks(ctx, b.cfg.Node, g.VMID, tail)
+	if err != nil {
+		return "", fmt.Errorf("listing tasks for VM %d: %w", g.VMID, err)
+	}
+	var sb strings.Builder
+	for i := len(tasks) - 1; i >= 0; i-- {
+		t := tasks[i]
+		status := t.Status
+		if status == "" {
+			status = "running"
+		}
+		fmt.Fprintf(&sb, "%s %s %s %s\n",
+			time.Unix(t.StartTime, 0).UTC().Format(time.RFC3339), t.Type, t.User, status)
+	}
+	return sb.String(), nil
+}
+
+// lookup finds the resource's VM by name. It returns nil when there is none,
+// or when the VM of that name is not marked as citadel's.
+func (b *VMBackend) lookup(ctx context.Context, id string, spec *ResourceSpec) (*proxmox.Guest, error) {
+	guests, err := b.client.ListVMs(ctx, b.cfg.Node)
+	if err != nil {
+		return nil, fmt.Errorf("listing VMs: %w", err)
+	}
+	g := findVM(guests, vmName(id, spec.Name))
+	if g == nil || hasTag(g.Tags, vmTag) {
+		return g, nil
+	}
+	raw, err := b.client.GetGuestConfig(ctx, b.cfg.Node, "qemu", g.VMID)
+	if err != nil {
+		return nil, fmt.Errorf("reading VM %d config: %w", g.VMID, err)
+	}
+	var cfg struct {
+		Description string `json:"description"`
+	}
+	if err := json.Unmarshal(raw, &cfg); err != nil {
+		return nil, fmt.Errorf("parsing VM %d config: %w", g.VMID, err)
+	}
+	if !strings.Contains(cfg.Description, vmDescription(id)) {
+		return nil, nil
+	}
+	return g, nil
+}
+
+// template resolves the image to a template VMID. Only a guest marked as a
+// template is a clone source: a VMID naming an ordinary guest would otherwise
+// full-clone someone else's running VM, disks and all.
+func (b *VMBackend) template(guests []proxmox.Guest, image string) (int, error) {
+	if image == "" {
+		if b.cfg.TemplateVMID <= 0 {
+			return 0, fmt.Errorf("no image given and no default VM template configured")
+		}
+		return b.cfg.TemplateVMID, nil
+	}
+	vmid, err := strconv.Atoi(image)
+	for _, g := range guests {
+		if g.Template != 1 {
+			continue
+		}
+		if (err == nil && g.VMID == vmid) || (err != nil && g.Name == image) {
+			return g.VMID, nil
+		}
+	}
+	return 0, fmt.Errorf("VM template %q not found on node %s", image, b.cfg.Node)
+}
+
+// cleanup deletes a half-created VM, best effort.
+func (b *VMBackend) cleanup(ctx context.Context, vmid int) {
+	_ = b.client.StopGuest(ctx, b.cfg.Node, "qemu", vmid)
+	if upid, err := b.client.DeleteVM(ctx, b.cfg.Node, vmid, true); err == nil {
+		_ = b.client.WaitForTask(ctx, b.cfg.Node, upid, vmTaskTimeout)
+	}
+}
+
+// vmTag is the PVE tag Create puts on every VM it makes.
+const vmTag = "citadel"
+
+// vmDescription is the description that marks a VM as resource id's.
+func vmDescription(id string) string {
+	return "citadel resource " + id
+}
+
+// hasTag reports whether a PVE tag list (";"-separated, though "," and
+// spaces are accepted too) contains tag.
+func hasTag(tags, tag string) bool {
+	for _, t := range strings.FieldsFunc(tags, func(r rune) bool { return r == ';' || r == ',' || r == ' ' }) {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// vmName is containerName made DNS-safe, as PVE requires of VM names.
+func vmName(id, specName string) string {
+	return strings.ReplaceAll(containerName(id, specName), "_", "-")
+}
+
+func findVM(guests []proxmox.Guest, name string) *proxmox.Guest {
+	for i := range guests {
+		if guests[i].Template == 0 && guests[i].Name == name {
+			return &guests[i]
+		}
+	}
+	return nil
+}
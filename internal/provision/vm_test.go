@@ -0,0 +1,296 @@
+package provision
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aceteam-ai/citadel-cli/internal/proxmox"
+)
+
+// fakePVE is a minimal Proxmox VE API for one node ("pve1"): it tracks VMs,
+// completes every task immediately and records config writes.
+type fakePVE struct {
+	mu      sync.Mutex
+	vms     map[int]*proxmox.Guest
+	nextID  int
+	config  map[string]string
+	descs   map[int]string // per-VM description
+	failOn  string
+	deleted []int
+}
+
+func newFakePVE(t *testing.T) (*fakePVE, *proxmox.Client) {
+	t.Helper()
+	f := &fakePVE{
+		vms:    map[int]*proxmox.Guest{9000: {VMID: 9000, Name: "ubuntu-cloud", Status: "stopped", Template: 1}},
+		nextID: 200,
+		descs:  map[int]string{},
+	}
+	srv := httptest.NewServer(http.HandlerFunc(f.serve))
+	t.Cleanup(srv.Close)
+	return f, proxmox.NewClient(proxmox.ClientConfig{
+		BaseURL:     srv.URL,
+		TokenID:     "test@pve!citadel",
+		TokenSecret: "secret",
+	})
+}
+
+func (f *fakePVE) serve(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	r.ParseForm()
+
+	path := strings.TrimPrefix(r.URL.Path, "/api2/json")
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	reply := func(v any) { json.NewEncoder(w).Encode(map[string]any{"data": v}) }
+	if f.failOn != "" && strings.HasSuffix(path, f.failOn) {
+		http.Error(w, `{"errors":"boom"}`, http.StatusInternalServerError)
+		return
+	}
+
+	switch {
+	case path == "/cluster/nextid":
+		reply(strconv.Itoa(f.nextID))
+		f.nextID++
+	case path == "/nodes/pve1/qemu" && r.Method == http.MethodGet:
+		var out []proxmox.Guest
+		for _, g := range f.vms {
+			out = append(out, *g)
+		}
+		reply(out)
+	case path == "/nodes/pve1/tasks" && r.Method == http.MethodGet:
+		reply([]proxmox.Task{
+			{Type: "qmstart", User: "citadel@pve", Status: "OK", StartTime: 1700000100},
+			{Type: "qmclone", User: "citadel@pve", Status: "OK", StartTime: 1700000000},
+		})
+	case len(parts) == 5 && parts[2] == "tasks" && parts[4] == "status":
+		reply(map[string]string{"status": "stopped", "exitstatus": "OK"})
+	case len(parts) >= 4 && parts[0] == "nodes" && parts[2] == "qemu":
+		vmid, _ := strconv.Atoi(parts[3])
+		action := strings.Join(parts[4:], "/")
+		switch {
+		case action == "clone":
+			newID, _ := strconv.Atoi(r.PostForm.Get("newid"))
+			f.vms[newID] = &proxmox.Guest{VMID: newID, Name: r.PostForm.Get("name"), Status: "stopped"}
+			f.descs[newID] = r.PostForm.Get("description")
+			reply("UPID:pve1:clone")
+		case action == "config" && r.Method == http.MethodGet:
+			reply(map[string]string{"description": f.descs[vmid]})
+		case action == "config":
+			f.config = map[string]string{}
+			for k := range r.PostForm {
+				f.config[k] = r.PostForm.Get(k)
+			}
+			if g := f.vms[vmid]; g != nil && r.PostForm.Has("tags") {
+				g.Tags = r.PostForm.Get("tags")
+			}
+			if r.PostForm.Has("description") {
+				f.descs[vmid] = r.PostForm.Get("description")
+			}
+			reply(nil)
+		case action == "status/start":
+			f.vms[vmid].Status = "running"
+			reply(nil)
+		case action == "status/stop":
+			if g := f.vms[vmid]; g != nil {
+				g.Status = "stopped"
+			}
+			reply(nil)
+		case action == "" && r.Method == http.MethodDelete:
+			delete(f.vms, vmid)
+			f.deleted = append(f.deleted, vmid)
+			reply("UPID:pve1:destroy")
+		default:
+			http.NotFound(w, r)
+		}
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func newVMTestManager(t *testing.T, client Hypervisor) *Manager {
+	t.Helper()
+	store, err := NewStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	vm, err := NewVMBackend(client, VMConfig{Node: "pve1", TemplateVMID: 9000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewManager(store, map[ResourceType]Backend{ResourceTypeVM: vm})
+}
+
+func TestVMCreateClonesSizesAndStarts(t *testing.T) {
+	f, client := newFakePVE(t)
+	mgr := newVMTestManager(t, client)
+
+	result, err := mgr.Create(context.Background(), &ResourceSpec{
+		Name: "gpu_box", Type: ResourceTypeVM, Image: "ubuntu-cloud", CPUs: "1.5", MemoryMB: 4096,
+	})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if result.Resource.ContainerID != "200" || result.Resource.Status != StatusRunning {
+		t.Errorf("resource = %+v, want running vmid 200", result.Resource)
+	}
+	g := f.vms[200]
+	if g == nil || g.Name != "citadel-gpu-box" || g.Status != "running" {
+		t.Fatalf("cloned VM = %+v, want running citadel-gpu-box", g)
+	}
+	if f.config["cores"] != "2" || f.config["memory"] != "4096" || f.config["tags"] != "citadel" {
+		t.Errorf("config = %v, want 2 cores, 4096 MB, citadel tag", f.config)
+	}
+	if f.descs[200] != vmDescription(result.Resource.ID) {
+		t.Errorf("description = %q, want the resource marker", f.descs[200])
+	}
+}
+
+func TestVMCreateFailureDeletesClone(t *testing.T) {
+	f, client := newFakePVE(t)
+	f.failOn = "/status/start"
+	mgr := newVMTestManager(t, client)
+
+	if _, err := mgr.Create(context.Background(), &ResourceSpec{Name: "vm1", Type: ResourceTypeVM}); err == nil {
+		t.Fatal("expected error")
+	}
+	if len(f.deleted) != 1 || f.deleted[0] != 200 {
+		t.Errorf("deleted = %v, want the half-created VM 200", f.deleted)
+	}
+}
+
+func TestVMCreateUnknownTemplate(t *testing.T) {
+	_, client := newFakePVE(t)
+	mgr := newVMTestManager(t, client)
+
+	_, err := mgr.Create(context.Background(), &ResourceSpec{Name: "vm1", Type: ResourceTypeVM, Image: "no-such-template"})
+	if err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Fatalf("err = %v, want template not found", err)
+	}
+}
+
+func TestVMCreateRefusesToCloneANonTemplate(t *testing.T) {
+	f, client := newFakePVE(t)
+	f.vms[150] = &proxmox.Guest{VMID: 150, Name: "tenant-db", Status: "running"}
+	mgr := newVMTestManager(t, client)
+
+	_, err := mgr.Create(context.Background(), &ResourceSpec{Name: "vm1", Type: ResourceTypeVM, Image: "150"})
+	if err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Fatalf("err = %v, want template not found", err)
+	}
+	if len(f.vms) != 2 {
+		t.Errorf("vms = %v, want no clone", f.vms)
+	}
+
+	if _, err := mgr.Create(context.Background(), &ResourceSpec{Name: "vm2", Type: ResourceTypeVM, Image: "9000"}); err != nil {
+		t.Fatalf("Create from template VMID: %v", err)
+	}
+}
+
+func TestVMReconcileAndDestroy(t *testing.T) {
+	f, client := newFakePVE(t)
+	mgr := newVMTestManager(t, client)
+	ctx := context.Background()
+
+	result, err := mgr.Create(ctx, &ResourceSpec{Name: "vm1", Type: ResourceTypeVM})
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := result.Resource.ID
+
+	f.vms[200].Status = "stopped"
+	mgr.ReconcileAll(ctx)
+	if r := mgr.Get(id); r.Status != StatusStopped {
+		t.Fatalf("status = %s, want stopped", r.Status)
+	}
+
+	if err := mgr.Destroy(ctx, id); err != nil {
+		t.Fatalf("Destroy: %v", err)
+	}
+	if _, ok := f.vms[200]; ok {
+		t.Error("VM still present after destroy")
+	}
+	// Destroying again, or a VM removed out of band, is a no-op.
+	vm := mgr.backends[ResourceTypeVM]
+	if err := vm.Destroy(ctx, id, &result.Resource.Spec); err != nil {
+		t.Errorf("second Destroy: %v", err)
+	}
+}
+
+func TestVMReconcileFindsCloneFromInterruptedCreate(t *testing.T) {
+	f, client := newFakePVE(t)
+	mgr := newVMTestManager(t, client)
+	ctx := context.Background()
+
+	// The daemon died after the clone finished but before the VMID was
+	// recorded or the VM tagged; the VM is found by name and recognised by
+	// the description the clone carried.
+	r := &Resource{ID: "0123456789ab", Spec: ResourceSpec{Name: "vm1", Type: ResourceTypeVM}, Status: StatusCreating}
+	if err := mgr.store.Put(r); err != nil {
+		t.Fatal(err)
+	}
+	f.vms[300] = &proxmox.Guest{VMID: 300, Name: "citadel-vm1", Status: "stopped"}
+	f.descs[300] = vmDescription(r.ID)
+
+	mgr.ReconcileAll(ctx)
+	if got := mgr.Get(r.ID); got.Status != StatusStopped {
+		t.Fatalf("status = %s, want stopped", got.Status)
+	}
+	if err := mgr.Destroy(ctx, r.ID); err != nil {
+		t.Fatal(err)
+	}
+	if len(f.deleted) != 1 || f.deleted[0] != 300 {
+		t.Errorf("deleted = %v, want 300", f.deleted)
+	}
+}
+
+func TestVMLeavesUnmarkedVMOfTheSameNameAlone(t *testing.T) {
+	f, client := newFakePVE(t)
+	mgr := newVMTestManager(t, client)
+	ctx := context.Background()
+
+	r := &Resource{ID: "0123456789ab", Spec: ResourceSpec{Name: "vm1", Type: ResourceTypeVM}, Status: StatusRunning}
+	if err := mgr.store.Put(r); err != nil {
+		t.Fatal(err)
+	}
+	// An operator's VM that happens to share the name, or another resource's.
+	f.vms[300] = &proxmox.Guest{VMID: 300, Name: "citadel-vm1", Status: "running"}
+	f.descs[300] = "citadel resource ffffffffffff"
+
+	if err := mgr.Destroy(ctx, r.ID); err != nil {
+		t.Fatal(err)
+	}
+	if len(f.deleted) != 0 {
+		t.Errorf("deleted = %v, want the unmarked VM kept", f.deleted)
+	}
+
+	f.vms[300].Tags = "prod;citadel"
+	if g, err := mgr.backends[ResourceTypeVM].(*VMBackend).lookup(ctx, r.ID, &r.Spec); err != nil || g == nil {
+		t.Errorf("lookup = %v, %v; want the citadel-tagged VM", g, err)
+	}
+}
+
+func TestVMLogsListsTasksOldestFirst(t *testing.T) {
+	_, client := newFakePVE(t)
+	mgr := newVMTestManager(t, client)
+	ctx := context.Background()
+
+	result, err := mgr.Create(ctx, &ResourceSpec{Name: "vm1", Type: ResourceTypeVM})
+	if err != nil {
+		t.Fatal(err)
+	}
+	logs, err := mgr.Logs(ctx, result.Resource.ID, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "2023-11-14T22:13:20Z qmclone citadel@pve OK\n2023-11-14T22:15:00Z qmstart citadel@pve OK\n"
+	if logs != want {
+		t.Errorf("logs = %q, want %q", logs, want)
+	}
+}
@@ -9,8 +9,10 @@ import (
 	"context"
 	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"net/url"
 	"strings"
@@ -154,6 +156,43 @@ func (c *Client) post(ctx context.Context, path string, form url.Values) (json.R
 	return c.doRequest(ctx, http.MethodPost, path, body)
 }
 
+// APIError is a non-2xx response from the PVE API. It keeps the status so a
+// caller can tell a refused request (4xx) from a server-side failure.
+type APIError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("API error (status %d): %s", e.StatusCode, e.Body)
+}
+
+// TaskError is a PVE task that stopped with an exit status other than "OK".
+type TaskError struct {
+	UPID       string
+	ExitStatus string
+}
+
+func (e *TaskError) Error() string {
+	return fmt.Sprintf("task %s failed: %s", e.UPID, e.ExitStatus)
+}
+
+// IsTransient reports whether err may succeed on retry. It is opt-in: only a
+// network failure talking to PVE or PVE answering with a 5xx counts. Anything
+// else, including PVE refusing the request (a 4xx), a task that ran and failed
+// and our own validation of the request, is permanent.
+func IsTransient(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode >= 500
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
 func (c *Client) doRequest(ctx context.Context, method, path string, body io.Reader) (json.RawMessage, error) {
 	fullURL := c.baseURL + "/api2/json" + path
 
@@ -188,7 +227,7 @@ func (c *Client) doRequest(ctx context.Context, method, path string, body io.Rea
 	}
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(respBody))
+		return nil, &APIError{StatusCode: resp.StatusCode, Body: string(respBody)}
 	}
 
 	var envelope apiResponse
@@ -385,6 +424,7 @@ type ClusterResource struct {
 	Status  string  `json:"status"`
 	Name    string  `json:"name"`
 	VMID    int     `json:"vmid,omitempty"`
+	Pool    string  `json:"pool,omitempty"`
 	CPU     float64 `json:"cpu"`
 	MaxCPU  int     `json:"maxcpu"`
 	Mem     int64   `json:"mem"`
@@ -3,8 +3,12 @@ package proxmox
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
 	"path/filepath"
 	"testing"
@@ -261,6 +265,27 @@ func TestAPIError(t *testing.T) {
 	}
 }
 
+func TestIsTransient(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"server error", &APIError{StatusCode: 500}, true},
+		{"bad gateway", fmt.Errorf("wrapped: %w", &APIError{StatusCode: 502}), true},
+		{"refused", &APIError{StatusCode: 400}, false},
+		{"task failed", &TaskError{UPID: "UPID:x", ExitStatus: "boom"}, false},
+		{"network", fmt.Errorf("request failed: %w", &url.Error{Op: "Get", URL: "https://pve", Err: &net.OpError{Op: "dial", Err: errors.New("connection refused")}}), true},
+		{"validation", errors.New(`invalid snapshot name "aceteam-x"`), false},
+		{"canceled", fmt.Errorf("request failed: %w", &url.Error{Op: "Get", URL: "https://pve", Err: context.Canceled}), false},
+		{"nil", nil, false},
+	} {
+		if got := IsTransient(tc.err); got != tc.want {
+			t.Errorf("%s: IsTransient = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
 func TestPing(t *testing.T) {
 	_, client := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
 		if r.URL.Path != "/api2/json/version" {
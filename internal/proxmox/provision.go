@@ -65,6 +65,11 @@ type CloneOptions struct {
 	Storage string
 	// Pool assigns the new VM to a resource pool at creation (optional).
 	Pool string
+	// Snapname clones the VM as it was at this snapshot instead of its
+	// current state (optional; requires Full).
+	Snapname string
+	// Description is the new VM's description (optional).
+	Description string
 }
 
 // CloneVM clones srcVMID on the given node into newVMID. Returns the UPID of
@@ -83,6 +88,12 @@ func (c *Client) CloneVM(ctx context.Context, node string, srcVMID, newVMID int,
 	if opts.Pool != "" {
 		form.Set("pool", opts.Pool)
 	}
+	if opts.Snapname != "" {
+		form.Set("snapname", opts.Snapname)
+	}
+	if opts.Description != "" {
+		form.Set("description", opts.Description)
+	}
 	data, err := c.post(ctx, fmt.Sprintf("/nodes/%s/qemu/%d/clone", node, srcVMID), form)
 	if err != nil {
 		return "", err
@@ -155,7 +166,7 @@ func (c *Client) WaitForTask(ctx context.Context, node, upid string, timeout tim
 		}
 		if ts.Status == "stopped" {
 			if ts.ExitStatus != "OK" {
-				return fmt.Errorf("task %s failed: %s", upid, ts.ExitStatus)
+				return &TaskError{UPID: upid, ExitStatus: ts.ExitStatus}
 			}
 			return nil
 		}
@@ -173,6 +184,30 @@ func (c *Client) WaitForTask(ctx context.Context, node, upid string, timeout tim
 // taskPollInterval is how often WaitForTask polls. Overridable in tests.
 var taskPollInterval = 2 * time.Second
 
+// Task is one entry of a node's task history.
+type Task struct {
+	UPID      string `json:"upid"`
+	Type      string `json:"type"` // e.g. "qmclone", "qmstart", "qmstop"
+	User      string `json:"user"`
+	Status    string `json:"status,omitempty"` // "OK" or the error; empty while running
+	StartTime int64  `json:"starttime"`
+	EndTime   int64  `json:"endtime,omitempty"`
+}
+
+// ListGuestTasks returns up to limit of the most recent tasks run against a
+// guest, newest first.
+func (c *Client) ListGuestTasks(ctx context.Context, node string, vmid, limit int) ([]Task, error) {
+	data, err := c.get(ctx, fmt.Sprintf("/nodes/%s/tasks?vmid=%d&limit=%d&source=all", node, vmid, limit))
+	if err != nil {
+		return nil, err
+	}
+	var tasks []Task
+	if err := json.Unmarshal(data, &tasks); err != nil {
+		return nil, fmt.Errorf("parsing tasks: %w", err)
+	}
+	return tasks, nil
+}
+
 // Pool represents a PVE resource pool.
 type Pool struct {
 	PoolID  string `json:"poolid"`
@@ -156,6 +156,25 @@ func TestWaitForTaskFailure(t *testing.T) {
 	}
 }
 
+func TestListGuestTasks(t *testing.T) {
+	_, client := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api2/json/nodes/pve1/tasks" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		if q := r.URL.Query(); q.Get("vmid") != "105" || q.Get("limit") != "20" {
+			t.Errorf("unexpected query: %s", r.URL.RawQuery)
+		}
+		w.Write([]byte(`{"data":[{"upid":"UPID:b","type":"qmstart","user":"root@pam","status":"OK","starttime":1700000100,"endtime":1700000102},{"upid":"UPID:a","type":"qmclone","user":"root@pam","starttime":1700000000}]}`))
+	})
+	tasks, err := client.ListGuestTasks(context.Background(), "pve1", 105, 20)
+	if err != nil {
+		t.Fatalf("ListGuestTasks: %v", err)
+	}
+	if len(tasks) != 2 || tasks[0].Type != "qmstart" || tasks[0].Status != "OK" || tasks[1].Status != "" {
+		t.Errorf("unexpected tasks: %+v", tasks)
+	}
+}
+
 func TestEnsurePool(t *testing.T) {
 	created := false
 	_, client := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
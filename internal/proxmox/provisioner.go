@@ -4,7 +4,8 @@
 // calls: clone a cloud-init template, size the VM from a small instance-type
 // table, inject an org-scoped mesh authkey via a cloud-init user-data snippet,
 // group the customer org's instances into a PVE resource pool (used for the
-// per-org instance cap), and drive start/stop/destroy/status.
+// per-org instance cap and snapshot retention), and drive start/stop/destroy/
+// status plus snapshot/rollback/backup.
 //
 // Mesh delivery: the platform mints a headscale authkey for the CUSTOMER org
 // (org_<id>), the provision payload carries it here, and the rendered
@@ -55,6 +56,18 @@ type ProvisioningConfig struct {
 	// MaxInstancesPerOrg caps instances per customer org, enforced via the
 	// org's PVE resource pool (default 3; 0 uses the default, -1 disables).
 	MaxInstancesPerOrg int `json:"max_instances_per_org,omitempty"`
+	// SnapshotRetention is how many auto-named snapshots an instance keeps;
+	// taking one more prunes the oldest (default 5; 0 uses the default, -1
+	// disables). Snapshots taken with an explicit name are never pruned.
+	SnapshotRetention int `json:"snapshot_retention,omitempty"`
+	// PoolSnapshotRetention overrides SnapshotRetention for instances in the
+	// given PVE pools, keyed by pool id (e.g. "aceteam-org-<id>").
+	PoolSnapshotRetention map[string]int `json:"pool_snapshot_retention,omitempty"`
+	// BackupStorage is the vzdump target storage (default "local").
+	BackupStorage string `json:"backup_storage,omitempty"`
+	// BackupMode is the vzdump mode: "snapshot", "suspend" or "stop"
+	// (default "snapshot").
+	BackupMode string `json:"backup_mode,omitempty"`
 }
 
 const (
@@ -63,7 +76,14 @@ const (
 	defaultSnippetsStorage    = "local"
 	defaultLoginServer        = "https://nexus.aceteam.ai"
 	defaultMaxInstancesPerOrg = 3
+	defaultSnapshotRetention  = 5
+	defaultBackupStorage      = "local"
+	defaultBackupMode         = "snapshot"
 	provisionTaskTimeout      = 5 * time.Minute
+	// snapshotTaskTimeout allows for saving a large VM's RAM with vmstate.
+	snapshotTaskTimeout = 15 * time.Minute
+	// backupTaskTimeout bounds a vzdump run; archive size dominates.
+	backupTaskTimeout = 6 * time.Hour
 )
 
 // InstanceType describes one row of the instance sizing table.
@@ -201,6 +221,15 @@ func NewProvisioner(client *Client, cfg ProvisioningConfig, snippets SnippetStor
 	if cfg.MaxInstancesPerOrg == 0 {
 		cfg.MaxInstancesPerOrg = defaultMaxInstancesPerOrg
 	}
+	if cfg.SnapshotRetention == 0 {
+		cfg.SnapshotRetention = defaultSnapshotRetention
+	}
+	if cfg.BackupStorage == "" {
+		cfg.BackupStorage = defaultBackupStorage
+	}
+	if cfg.BackupMode == "" {
+		cfg.BackupMode = defaultBackupMode
+	}
 	if snippets == nil {
 		dir := cfg.SnippetsDir
 		if dir == "" {
@@ -428,6 +457,330 @@ func (p *Provisioner) Status(ctx context.Context, ref InstanceRef) (*InstanceSta
 	}, nil
 }
 
+// autoSnapshotPrefix starts the names Snapshot generates. Retention only
+// prunes snapshots with it, never ones an operator named, so Snapshot refuses
+// an explicit name that uses it.
+const autoSnapshotPrefix = "aceteam-"
+
+// SnapshotRequest is the input for snapshotting an instance.
+type SnapshotRequest struct {
+	// Name is the snapshot name (optional; defaults to "aceteam-<UTC time>").
+	// The "aceteam-" prefix is reserved for those default names.
+	Name string
+	// Description is stored with the snapshot (optional).
+	Description string
+	// IncludeRAM saves the running VM's memory so a rollback resumes it
+	// instead of cold-booting.
+	IncludeRAM bool
+	// RequestID identifies the request across redeliveries (the job ID). It is
+	// recorded in the snapshot's description so that a redelivered request can
+	// recognise the snapshot its earlier attempt took.
+	RequestID string
+}
+
+// SnapshotResult reports a snapshot taken by Snapshot.
+type SnapshotResult struct {
+	Name   string   `json:"name"`
+	Pool   string   `json:"pool,omitempty"`
+	Pruned []string `json:"pruned,omitempty"`
+}
+
+// BackupResult reports a finished vzdump backup.
+type BackupResult struct {
+	Storage string `json:"storage"`
+	Mode    string `json:"mode"`
+	UPID    string `json:"upid"`
+}
+
+// TemplateResult reports a snapshot promoted into a new template.
+type TemplateResult struct {
+	VMID       int    `json:"vmid"`
+	Name       string `json:"name"`
+	SourceVMID int    `json:"source_vmid"`
+	Snapshot   string `json:"snapshot"`
+}
+
+// snapshotNow names default snapshots. Overridable in tests.
+var snapshotNow = time.Now
+
+// Snapshot snapshots an instance VM, then prunes its oldest auto-named
+// snapshots down to the retention configured for the VM's pool. A failed prune
+// is logged, not returned: the snapshot itself succeeded.
+//
+// A named snapshot is taken at most once. When the create fails and a snapshot
+// of that name already exists, it is the result only if its description
+// carries req.RequestID (a redelivered request whose earlier attempt got
+// through); otherwise Snapshot fails with "already exists".
+func (p *Provisioner) Snapshot(ctx context.Context, ref InstanceRef, req SnapshotRequest) (*SnapshotResult, error) {
+	name := req.Name
+	switch {
+	case name == "":
+		name = autoSnapshotPrefix + snapshotNow().UTC().Format("20060102-150405")
+	case strings.HasPrefix(name, autoSnapshotPrefix):
+		// Retention would count it as auto-created and eventually prune it.
+		return nil, fmt.Errorf("invalid snapshot name %q: the %q prefix is reserved for automatic snapshots", name, autoSnapshotPrefix)
+	}
+	if err := ValidateSnapshotName(name); err != nil {
+		return nil, err
+	}
+	node := p.node(ref)
+	description := req.Description
+	if req.RequestID != "" {
+		description = strings.TrimSpace(description + "\n" + snapshotRequestMarker(req.RequestID))
+	}
+	upid, err := p.client.CreateSnapshot(ctx, node, ref.VMID, name, description, req.IncludeRAM)
+	if err == nil {
+		if err = p.client.WaitForTask(ctx, node, upid, snapshotTaskTimeout); err != nil {
+			err = fmt.Errorf("snapshot task: %w", err)
+		}
+	} else {
+		err = fmt.Errorf("snapshotting VM %d: %w", ref.VMID, err)
+	}
+	if err != nil {
+		existing, found := p.findSnapshot(ctx, node, ref.VMID, name)
+		if req.Name == "" || !found {
+			return nil, err
+		}
+		// A same-named snapshot is ours only when it carries this request's
+		// marker: a redelivery whose earlier attempt got as far as taking it.
+		// Anything else is someone else's snapshot and must not be reported as
+		// this one.
+		if req.RequestID == "" || !strings.Contains(existing.Description, snapshotRequestMarker(req.RequestID)) {
+			return nil, fmt.Errorf("snapshot %q already exists on VM %d", name, ref.VMID)
+		}
+		p.log("INSTANCE_SNAPSHOT: vmid %d: snapshot %s was taken by an earlier attempt of request %s; keeping it",
+			ref.VMID, name, req.RequestID)
+	}
+
+	res := &SnapshotResult{Name: name}
+	pool, err := p.poolOf(ctx, ref.VMID)
+	if err != nil {
+		p.log("INSTANCE_SNAPSHOT: vmid %d: not pruning, pool lookup failed: %v", ref.VMID, err)
+		return res, nil
+	}
+	res.Pool = pool
+	res.Pruned, err = p.pruneSnapshots(ctx, node, ref.VMID, p.snapshotRetention(pool))
+	if err != nil {
+		p.log("INSTANCE_SNAPSHOT: vmid %d: pruning old snapshots: %v", ref.VMID, err)
+	}
+	return res, nil
+}
+
+// snapshotRetention returns how many snapshots an instance in pool keeps;
+// a negative value disables pruning.
+func (p *Provisioner) snapshotRetention(pool string) int {
+	if n, ok := p.cfg.PoolSnapshotRetention[pool]; ok && n != 0 {
+		return n
+	}
+	return p.cfg.SnapshotRetention
+}
+
+// poolOf returns the resource pool a VM belongs to ("" when it has none).
+func (p *Provisioner) poolOf(ctx context.Context, vmid int) (string, error) {
+	resources, err := p.client.ListClusterResources(ctx)
+	if err != nil {
+		return "", err
+	}
+	for _, r := range resources {
+		if r.Type == "qemu" && r.VMID == vmid {
+			return r.Pool, nil
+		}
+	}
+	return "", fmt.Errorf("VM %d not found in cluster resources", vmid)
+}
+
+// findSnapshot returns the snapshot of a VM called name, if it has one. A
+// failed lookup reports none.
+func (p *Provisioner) findSnapshot(ctx context.Context, node string, vmid int, name string) (Snapshot, bool) {
+	snaps, err := p.client.ListSnapshots(ctx, node, vmid)
+	if err != nil {
+		return Snapshot{}, false
+	}
+	for _, s := range snaps {
+		if s.Name == name {
+			return s, true
+		}
+	}
+	return Snapshot{}, false
+}
+
+// snapshotRequestMarker is the line Snapshot adds to a snapshot's description
+// to record which request took it.
+func snapshotRequestMarker(requestID string) string {
+	return "citadel-request: " + requestID
+}
+
+// pruneSnapshots deletes the oldest auto-named snapshots of a VM until at most
+// keep of them remain, and returns the names it deleted. Snapshots an operator
+// named are neither counted nor deleted.
+func (p *Provisioner) pruneSnapshots(ctx context.Context, node string, vmid, keep int) ([]string, error) {
+	if keep < 0 {
+		return nil, nil
+	}
+	all, err := p.client.ListSnapshots(ctx, node, vmid)
+	if err != nil {
+		return nil, fmt.Errorf("listing snapshots: %w", err)
+	}
+	var snaps []Snapshot
+	for _, s := range all {
+		if strings.HasPrefix(s.Name, autoSnapshotPrefix) {
+			snaps = append(snaps, s)
+		}
+	}
+	var pruned []string
+	for i := 0; i < len(snaps)-keep; i++ {
+		name := snaps[i].Name
+		upid, err := p.client.DeleteSnapshot(ctx, node, vmid, name)
+		if err != nil {
+			return pruned, fmt.Errorf("deleting snapshot %s: %w", name, err)
+		}
+		if err := p.client.WaitForTask(ctx, node, upid, snapshotTaskTimeout); err != nil {
+			return pruned, fmt.Errorf("deleting snapshot %s: %w", name, err)
+		}
+		pruned = append(pruned, name)
+	}
+	return pruned, nil
+}
+
+// Snapshots lists an instance VM's snapshots, oldest first.
+func (p *Provisioner) Snapshots(ctx context.Context, ref InstanceRef) ([]Snapshot, error) {
+	return p.client.ListSnapshots(ctx, p.node(ref), ref.VMID)
+}
+
+// DeleteSnapshot removes one snapshot of an instance VM.
+func (p *Provisioner) DeleteSnapshot(ctx context.Context, ref InstanceRef, name string) error {
+	if err := ValidateSnapshotName(name); err != nil {
+		return err
+	}
+	node := p.node(ref)
+	upid, err := p.client.DeleteSnapshot(ctx, node, ref.VMID, name)
+	if err != nil {
+		return fmt.Errorf("deleting snapshot %s of VM %d: %w", name, ref.VMID, err)
+	}
+	return p.client.WaitForTask(ctx, node, upid, snapshotTaskTimeout)
+}
+
+// Rollback reverts an instance VM to a snapshot. A snapshot taken without RAM
+// rolls back to a stopped VM, so an instance that was running is started
+// again: a rollback should not double as an outage.
+func (p *Provisioner) Rollback(ctx context.Context, ref InstanceRef, name string) error {
+	if err := ValidateSnapshotName(name); err != nil {
+		return err
+	}
+	node := p.node(ref)
+	before, err := p.client.GetGuestStatus(ctx, node, "qemu", ref.VMID)
+	if err != nil {
+		return fmt.Errorf("reading VM %d status: %w", ref.VMID, err)
+	}
+	upid, err := p.client.RollbackSnapshot(ctx, node, ref.VMID, name)
+	if err != nil {
+		return fmt.Errorf("rolling back VM %d to %s: %w", ref.VMID, name, err)
+	}
+	if err := p.client.WaitForTask(ctx, node, upid, snapshotTaskTimeout); err != nil {
+		return fmt.Errorf("rollback task: %w", err)
+	}
+	if before.Status != "running" {
+		return nil
+	}
+	after, err := p.client.GetGuestStatus(ctx, node, "qemu", ref.VMID)
+	if err != nil {
+		return fmt.Errorf("reading VM %d status after rollback: %w", ref.VMID, err)
+	}
+	if after.Status != "running" {
+		if err := p.client.StartGuest(ctx, node, "qemu", ref.VMID); err != nil {
+			return fmt.Errorf("restarting VM %d after rollback: %w", ref.VMID, err)
+		}
+	}
+	return nil
+}
+
+// Backup writes a vzdump archive of an instance VM. Empty Storage and Mode
+// fall back to the configured backup storage and mode.
+func (p *Provisioner) Backup(ctx context.Context, ref InstanceRef, opts BackupOptions) (*BackupResult, error) {
+	if opts.Storage == "" {
+		opts.Storage = p.cfg.BackupStorage
+	}
+	if opts.Mode == "" {
+		opts.Mode = p.cfg.BackupMode
+	}
+	switch opts.Mode {
+	case "snapshot", "suspend", "stop":
+	default:
+		return nil, fmt.Errorf("invalid backup mode %q (want snapshot|suspend|stop)", opts.Mode)
+	}
+	node := p.node(ref)
+	upid, err := p.client.BackupVM(ctx, node, ref.VMID, opts)
+	if err != nil {
+		return nil, fmt.Errorf("backing up VM %d: %w", ref.VMID, err)
+	}
+	if err := p.client.WaitForTask(ctx, node, upid, backupTaskTimeout); err != nil {
+		return nil, fmt.Errorf("backup task: %w", err)
+	}
+	return &BackupResult{Storage: opts.Storage, Mode: opts.Mode, UPID: upid}, nil
+}
+
+// PromoteSnapshot full-clones an instance VM as it was at a snapshot into a
+// new VMID and converts the clone into a template that Provision can clone
+// from (point provisioning.template_vmid at it). The instance's cicustom
+// snippet, tags and description are stripped from the clone: the snippet
+// carries that instance's mesh authkey.
+//
+// The template inherits the snapshot's disk as-is. Promote a snapshot taken
+// after the guest was generalized (cloud-init clean, tailscale logout);
+// otherwise every clone boots with the source instance's machine and mesh
+// identity.
+func (p *Provisioner) PromoteSnapshot(ctx context.Context, ref InstanceRef, snapshot, name string) (*TemplateResult, error) {
+	if err := ValidateSnapshotName(snapshot); err != nil {
+		return nil, err
+	}
+	if name == "" {
+		name = fmt.Sprintf("template-%d-%s", ref.VMID, snapshot)
+	}
+	name = sanitizeName(name)
+	node := p.node(ref)
+
+	vmid, err := p.client.NextID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("allocating vmid: %w", err)
+	}
+	p.log("promoting snapshot %s of vmid %d -> template %d (%s)", snapshot, ref.VMID, vmid, name)
+	upid, err := p.client.CloneVM(ctx, node, ref.VMID, vmid, CloneOptions{
+		Name:     name,
+		Full:     true,
+		Storage:  p.cfg.Storage,
+		Snapname: snapshot,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cloning snapshot: %w", err)
+	}
+	if err := p.client.WaitForTask(ctx, node, upid, provisionTaskTimeout); err != nil {
+		return nil, fmt.Errorf("clone task: %w", err)
+	}
+
+	fail := func(err error) (*TemplateResult, error) {
+		if upid, derr := p.client.DeleteVM(ctx, node, vmid, true); derr == nil {
+			_ = p.client.WaitForTask(ctx, node, upid, provisionTaskTimeout)
+		}
+		return nil, err
+	}
+	if err := p.client.ConfigureVM(ctx, node, vmid, map[string]string{
+		"delete":      "cicustom,tags",
+		"description": fmt.Sprintf("AceTeam instance template from VM %d snapshot %s", ref.VMID, snapshot),
+	}); err != nil {
+		return fail(fmt.Errorf("clearing instance config: %w", err))
+	}
+	upid, err = p.client.ConvertToTemplate(ctx, node, vmid)
+	if err != nil {
+		return fail(fmt.Errorf("converting to template: %w", err))
+	}
+	if upid != "" {
+		if err := p.client.WaitForTask(ctx, node, upid, provisionTaskTimeout); err != nil {
+			return fail(fmt.Errorf("template task: %w", err))
+		}
+	}
+	return &TemplateResult{VMID: vmid, Name: name, SourceVMID: ref.VMID, Snapshot: snapshot}, nil
+}
+
 func (p *Provisioner) node(ref InstanceRef) string {
 	if ref.PVENode != "" {
 		return ref.PVENode
@@ -41,6 +41,17 @@ type pveMock struct {
 	deleted    bool
 	failClone  bool
 	failResize bool
+
+	// Snapshot/backup state.
+	vmPool       string
+	vmStopped    bool
+	snapshots    []Snapshot
+	snapForm     url.Values
+	cloneForm    url.Values
+	deletedSnaps []string
+	rolledBack   string
+	backupForm   url.Values
+	templated    bool
 }
 
 func (m *pveMock) handler(w http.ResponseWriter, r *http.Request) {
@@ -61,7 +72,47 @@ func (m *pveMock) handler(w http.ResponseWriter, r *http.Request) {
 		fmt.Fprintf(w, `{"data":{"members":%s}}`, marshalOrDie(m.t, m.pools[id]))
 	case r.Method == http.MethodGet && path == "/cluster/nextid":
 		w.Write([]byte(`{"data":"105"}`))
+	case r.Method == http.MethodGet && path == "/cluster/resources":
+		fmt.Fprintf(w, `{"data":[{"type":"qemu","vmid":105,"node":"pve1","pool":%q}]}`, m.vmPool)
+	case r.Method == http.MethodGet && strings.HasSuffix(path, "/snapshot"):
+		snaps := append(append([]Snapshot{}, m.snapshots...), Snapshot{Name: "current"})
+		fmt.Fprintf(w, `{"data":%s}`, marshalOrDie(m.t, snaps))
+	case r.Method == http.MethodPost && strings.HasSuffix(path, "/snapshot"):
+		m.snapForm = r.PostForm
+		for _, sn := range m.snapshots {
+			if sn.Name == r.PostForm.Get("snapname") {
+				http.Error(w, fmt.Sprintf(`{"data":null,"message":"snapshot name '%s' already used"}`, sn.Name), 500)
+				return
+			}
+		}
+		m.snapshots = append(m.snapshots, Snapshot{
+			Name:        r.PostForm.Get("snapname"),
+			Description: r.PostForm.Get("description"),
+			SnapTime:    int64(1000 + len(m.snapshots)),
+		})
+		w.Write([]byte(`{"data":"UPID:pve1:0003:qmsnapshot:"}`))
+	case r.Method == http.MethodDelete && strings.Contains(path, "/snapshot/"):
+		name := path[strings.LastIndex(path, "/")+1:]
+		m.deletedSnaps = append(m.deletedSnaps, name)
+		for i, sn := range m.snapshots {
+			if sn.Name == name {
+				m.snapshots = append(m.snapshots[:i], m.snapshots[i+1:]...)
+				break
+			}
+		}
+		w.Write([]byte(`{"data":"UPID:pve1:0004:qmdelsnapshot:"}`))
+	case r.Method == http.MethodPost && strings.HasSuffix(path, "/rollback"):
+		m.rolledBack = strings.TrimSuffix(path[strings.Index(path, "/snapshot/")+len("/snapshot/"):], "/rollback")
+		m.vmStopped = true
+		w.Write([]byte(`{"data":"UPID:pve1:0005:qmrollback:"}`))
+	case r.Method == http.MethodPost && path == "/nodes/pve1/vzdump":
+		m.backupForm = r.PostForm
+		w.Write([]byte(`{"data":"UPID:pve1:0006:vzdump:"}`))
+	case r.Method == http.MethodPost && strings.HasSuffix(path, "/template"):
+		m.templated = true
+		w.Write([]byte(`{"data":null}`))
 	case r.Method == http.MethodPost && strings.HasSuffix(path, "/clone"):
+		m.cloneForm = r.PostForm
 		if m.failClone {
 			http.Error(w, `{"errors":{"newid":"already exists"}}`, 400)
 			return
@@ -86,6 +137,7 @@ func (m *pveMock) handler(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte(`{"data":null}`))
 	case r.Method == http.MethodPost && strings.HasSuffix(path, "/status/start"):
 		m.started = true
+		m.vmStopped = false
 		w.Write([]byte(`{"data":null}`))
 	case r.Method == http.MethodPost && strings.HasSuffix(path, "/status/stop"):
 		m.stopped = true
@@ -94,6 +146,10 @@ func (m *pveMock) handler(w http.ResponseWriter, r *http.Request) {
 		m.deleted = true
 		w.Write([]byte(`{"data":"UPID:pve1:0002:qmdestroy:"}`))
 	case r.Method == http.MethodGet && strings.HasSuffix(path, "/status/current"):
+		if m.vmStopped {
+			w.Write([]byte(`{"data":{"status":"stopped","vmid":105}}`))
+			return
+		}
 		w.Write([]byte(`{"data":{"status":"running","vmid":105,"uptime":42,"cpus":2,"maxmem":4294967296}}`))
 	default:
 		m.t.Errorf("unexpected request: %s %s", r.Method, path)
@@ -294,6 +350,165 @@ func TestStatus(t *testing.T) {
 	}
 }
 
+func TestSnapshotPrunesToPoolRetention(t *testing.T) {
+	mock := &pveMock{t: t, pools: map[string][]PoolMember{}, vmPool: "aceteam-org-abc"}
+	for i, name := range []string{"aceteam-s1", "pre-upgrade", "aceteam-s2", "aceteam-s3"} {
+		mock.snapshots = append(mock.snapshots, Snapshot{Name: name, SnapTime: int64(i)})
+	}
+	p, _ := newTestProvisioner(t, mock, ProvisioningConfig{
+		SnapshotRetention:     10,
+		PoolSnapshotRetention: map[string]int{"aceteam-org-abc": 2},
+	})
+	orig := snapshotNow
+	snapshotNow = func() time.Time { return time.Date(2026, 3, 4, 5, 6, 7, 0, time.UTC) }
+	defer func() { snapshotNow = orig }()
+
+	res, err := p.Snapshot(context.Background(), InstanceRef{VMID: 105}, SnapshotRequest{IncludeRAM: true})
+	if err != nil {
+		t.Fatalf("Snapshot: %v", err)
+	}
+	if res.Name != "aceteam-20260304-050607" {
+		t.Errorf("default name = %q", res.Name)
+	}
+	if mock.snapForm.Get("vmstate") != "1" {
+		t.Errorf("vmstate not requested: %v", mock.snapForm)
+	}
+	if res.Pool != "aceteam-org-abc" {
+		t.Errorf("pool = %q", res.Pool)
+	}
+	// 4 auto-named snapshots, pool keeps 2: the two oldest go. The one an
+	// operator named is not counted and survives.
+	if strings.Join(res.Pruned, ",") != "aceteam-s1,aceteam-s2" || strings.Join(mock.deletedSnaps, ",") != "aceteam-s1,aceteam-s2" {
+		t.Errorf("pruned = %v (deleted %v), want [aceteam-s1 aceteam-s2]", res.Pruned, mock.deletedSnaps)
+	}
+}
+
+func TestSnapshotRedeliveryOfNamedSnapshotKeepsIt(t *testing.T) {
+	mock := &pveMock{t: t, pools: map[string][]PoolMember{}}
+	p, _ := newTestProvisioner(t, mock, ProvisioningConfig{})
+	req := SnapshotRequest{Name: "nightly", Description: "before upgrade", RequestID: "job-1"}
+	if _, err := p.Snapshot(context.Background(), InstanceRef{VMID: 105}, req); err != nil {
+		t.Fatalf("first Snapshot: %v", err)
+	}
+	res, err := p.Snapshot(context.Background(), InstanceRef{VMID: 105}, req)
+	if err != nil {
+		t.Fatalf("redelivered Snapshot: %v", err)
+	}
+	if res.Name != "nightly" || len(mock.snapshots) != 1 {
+		t.Errorf("res = %+v, snapshots = %v; want the existing snapshot kept", res, mock.snapshots)
+	}
+	if !strings.HasPrefix(mock.snapshots[0].Description, "before upgrade\n") {
+		t.Errorf("description = %q, want the caller's description first", mock.snapshots[0].Description)
+	}
+}
+
+func TestSnapshotRefusesSomeoneElsesSnapshot(t *testing.T) {
+	for _, existing := range []Snapshot{
+		{Name: "nightly"},
+		{Name: "nightly", Description: snapshotRequestMarker("job-other")},
+	} {
+		mock := &pveMock{t: t, pools: map[string][]PoolMember{}}
+		mock.snapshots = []Snapshot{existing}
+		p, _ := newTestProvisioner(t, mock, ProvisioningConfig{})
+		_, err := p.Snapshot(context.Background(), InstanceRef{VMID: 105}, SnapshotRequest{Name: "nightly", RequestID: "job-1"})
+		if err == nil || !strings.Contains(err.Error(), "already exists") {
+			t.Errorf("existing %+v: err = %v, want already exists", existing, err)
+		}
+		if IsTransient(err) {
+			t.Errorf("existing %+v: already exists must not be retried", existing)
+		}
+	}
+}
+
+func TestSnapshotRetentionDisabled(t *testing.T) {
+	mock := &pveMock{t: t, pools: map[string][]PoolMember{}}
+	mock.snapshots = []Snapshot{{Name: "s1"}, {Name: "s2"}}
+	p, _ := newTestProvisioner(t, mock, ProvisioningConfig{SnapshotRetention: -1})
+	res, err := p.Snapshot(context.Background(), InstanceRef{VMID: 105}, SnapshotRequest{Name: "keep-all"})
+	if err != nil {
+		t.Fatalf("Snapshot: %v", err)
+	}
+	if len(res.Pruned) != 0 || len(mock.deletedSnaps) != 0 {
+		t.Errorf("nothing should be pruned: %v", mock.deletedSnaps)
+	}
+}
+
+func TestSnapshotRejectsInvalidName(t *testing.T) {
+	mock := &pveMock{t: t, pools: map[string][]PoolMember{}}
+	p, _ := newTestProvisioner(t, mock, ProvisioningConfig{})
+	for _, name := range []string{"1abc", "has space", "current", strings.Repeat("a", 41), "aceteam-mine"} {
+		if _, err := p.Snapshot(context.Background(), InstanceRef{VMID: 105}, SnapshotRequest{Name: name}); err == nil {
+			t.Errorf("name %q: expected an error", name)
+		}
+	}
+}
+
+func TestRollbackRestartsRunningInstance(t *testing.T) {
+	mock := &pveMock{t: t, pools: map[string][]PoolMember{}}
+	p, _ := newTestProvisioner(t, mock, ProvisioningConfig{})
+	if err := p.Rollback(context.Background(), InstanceRef{VMID: 105}, "before-upgrade"); err != nil {
+		t.Fatalf("Rollback: %v", err)
+	}
+	if mock.rolledBack != "before-upgrade" {
+		t.Errorf("rolled back to %q", mock.rolledBack)
+	}
+	if !mock.started {
+		t.Error("a running instance should be started again after rollback")
+	}
+}
+
+func TestRollbackLeavesStoppedInstanceStopped(t *testing.T) {
+	mock := &pveMock{t: t, pools: map[string][]PoolMember{}, vmStopped: true}
+	p, _ := newTestProvisioner(t, mock, ProvisioningConfig{})
+	if err := p.Rollback(context.Background(), InstanceRef{VMID: 105}, "before-upgrade"); err != nil {
+		t.Fatalf("Rollback: %v", err)
+	}
+	if mock.started {
+		t.Error("a stopped instance must stay stopped")
+	}
+}
+
+func TestBackupUsesConfiguredDefaults(t *testing.T) {
+	mock := &pveMock{t: t, pools: map[string][]PoolMember{}}
+	p, _ := newTestProvisioner(t, mock, ProvisioningConfig{BackupStorage: "pbs"})
+	res, err := p.Backup(context.Background(), InstanceRef{VMID: 105}, BackupOptions{})
+	if err != nil {
+		t.Fatalf("Backup: %v", err)
+	}
+	if res.Storage != "pbs" || res.Mode != "snapshot" {
+		t.Errorf("unexpected result: %+v", res)
+	}
+	for k, want := range map[string]string{"vmid": "105", "storage": "pbs", "mode": "snapshot", "compress": "zstd"} {
+		if got := mock.backupForm.Get(k); got != want {
+			t.Errorf("form[%s] = %q, want %q", k, got, want)
+		}
+	}
+	if _, err := p.Backup(context.Background(), InstanceRef{VMID: 105}, BackupOptions{Mode: "hot"}); err == nil {
+		t.Error("expected an error for an invalid mode")
+	}
+}
+
+func TestPromoteSnapshotToTemplate(t *testing.T) {
+	mock := &pveMock{t: t, pools: map[string][]PoolMember{}}
+	p, _ := newTestProvisioner(t, mock, ProvisioningConfig{})
+	res, err := p.PromoteSnapshot(context.Background(), InstanceRef{VMID: 104}, "golden", "")
+	if err != nil {
+		t.Fatalf("PromoteSnapshot: %v", err)
+	}
+	if res.VMID != 105 || res.SourceVMID != 104 || res.Name != "template-104-golden" {
+		t.Errorf("unexpected result: %+v", res)
+	}
+	if mock.cloneForm.Get("snapname") != "golden" || mock.cloneForm.Get("full") != "1" {
+		t.Errorf("clone not taken from the snapshot: %v", mock.cloneForm)
+	}
+	if mock.configForm.Get("delete") != "cicustom,tags" {
+		t.Errorf("instance cloud-init not stripped: %v", mock.configForm)
+	}
+	if !mock.templated {
+		t.Error("clone was not converted to a template")
+	}
+}
+
 func TestNewProvisionerValidation(t *testing.T) {
 	client := NewClient(ClientConfig{BaseURL: "https://example:8006"})
 	cases := []struct {
@@ -0,0 +1,159 @@
+// internal/proxmox/snapshot.go
+//
+// Snapshot, backup and template Proxmox VE API methods for instance VMs.
+// Snapshots live on the VM's own storage (ZFS, LVM-thin, qcow2, Ceph) and are
+// cheap to take and roll back; backups are vzdump archives written to a
+// backup-capable storage and survive the VM being destroyed. A snapshot can
+// also be cloned out and converted into a new template.
+//
+// Snapshot create/rollback/delete and vzdump run as PVE tasks: the methods
+// return the UPID and callers wait with WaitForTask.
+package proxmox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+// currentSnapshot is the pseudo-entry PVE appends to every snapshot list to
+// mark the VM's live state ("You are here!"). It is not a real snapshot.
+const currentSnapshot = "current"
+
+// Snapshot describes one VM snapshot.
+type Snapshot struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Parent      string `json:"parent,omitempty"`
+	SnapTime    int64  `json:"snaptime,omitempty"`
+	// VMState is 1 when the snapshot includes the VM's RAM.
+	VMState int `json:"vmstate,omitempty"`
+}
+
+// snapshotNamePattern is PVE's configid format with its 40-character limit.
+var snapshotNamePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_-]{1,39}$`)
+
+// ValidateSnapshotName reports whether PVE will accept name as a snapshot name.
+func ValidateSnapshotName(name string) error {
+	if name == currentSnapshot || !snapshotNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid snapshot name %q: must start with a letter, use only letters, digits, '-' and '_', and be 2-40 characters", name)
+	}
+	return nil
+}
+
+// ListSnapshots returns the snapshots of a QEMU VM, oldest first.
+func (c *Client) ListSnapshots(ctx context.Context, node string, vmid int) ([]Snapshot, error) {
+	data, err := c.get(ctx, fmt.Sprintf("/nodes/%s/qemu/%d/snapshot", node, vmid))
+	if err != nil {
+		return nil, err
+	}
+	var all []Snapshot
+	if err := json.Unmarshal(data, &all); err != nil {
+		return nil, fmt.Errorf("parsing snapshots: %w", err)
+	}
+	snaps := all[:0]
+	for _, s := range all {
+		if s.Name != currentSnapshot {
+			snaps = append(snaps, s)
+		}
+	}
+	sort.SliceStable(snaps, func(i, j int) bool { return snaps[i].SnapTime < snaps[j].SnapTime })
+	return snaps, nil
+}
+
+// CreateSnapshot snapshots a QEMU VM. With vmstate the running VM's RAM is
+// saved too, so a rollback resumes it exactly. Returns the UPID of the
+// snapshot task.
+func (c *Client) CreateSnapshot(ctx context.Context, node string, vmid int, name, description string, vmstate bool) (string, error) {
+	form := url.Values{"snapname": {name}}
+	if description != "" {
+		form.Set("description", description)
+	}
+	if vmstate {
+		form.Set("vmstate", "1")
+	}
+	data, err := c.post(ctx, fmt.Sprintf("/nodes/%s/qemu/%d/snapshot", node, vmid), form)
+	if err != nil {
+		return "", err
+	}
+	return parseUPID(data)
+}
+
+// RollbackSnapshot reverts a QEMU VM to a snapshot. Returns the UPID of the
+// rollback task.
+func (c *Client) RollbackSnapshot(ctx context.Context, node string, vmid int, name string) (string, error) {
+	path := fmt.Sprintf("/nodes/%s/qemu/%d/snapshot/%s/rollback", node, vmid, url.PathEscape(name))
+	data, err := c.post(ctx, path, nil)
+	if err != nil {
+		return "", err
+	}
+	return parseUPID(data)
+}
+
+// DeleteSnapshot removes a snapshot, merging its data into its child. Returns
+// the UPID of the delete task.
+func (c *Client) DeleteSnapshot(ctx context.Context, node string, vmid int, name string) (string, error) {
+	path := fmt.Sprintf("/nodes/%s/qemu/%d/snapshot/%s", node, vmid, url.PathEscape(name))
+	data, err := c.delete(ctx, path)
+	if err != nil {
+		return "", err
+	}
+	return parseUPID(data)
+}
+
+// BackupOptions holds the vzdump parameters for BackupVM.
+type BackupOptions struct {
+	// Storage is the target storage; it must allow the "backup" content type.
+	Storage string
+	// Mode is "snapshot" (live, default), "suspend" or "stop".
+	Mode string
+	// Compress is the archive compression: "zstd" (default), "lzo", "gzip" or "0".
+	Compress string
+	// Notes is attached to the backup and shown in the PVE UI (optional).
+	Notes string
+}
+
+// BackupVM starts a vzdump backup of one guest on the given node. Returns the
+// UPID of the vzdump task.
+func (c *Client) BackupVM(ctx context.Context, node string, vmid int, opts BackupOptions) (string, error) {
+	form := url.Values{"vmid": {strconv.Itoa(vmid)}}
+	if opts.Storage != "" {
+		form.Set("storage", opts.Storage)
+	}
+	mode := opts.Mode
+	if mode == "" {
+		mode = "snapshot"
+	}
+	form.Set("mode", mode)
+	compress := opts.Compress
+	if compress == "" {
+		compress = "zstd"
+	}
+	form.Set("compress", compress)
+	if opts.Notes != "" {
+		form.Set("notes-template", opts.Notes)
+	}
+	data, err := c.post(ctx, fmt.Sprintf("/nodes/%s/vzdump", node), form)
+	if err != nil {
+		return "", err
+	}
+	return parseUPID(data)
+}
+
+// ConvertToTemplate turns a stopped QEMU VM into a template. Recent PVE
+// versions run the conversion as a task and return its UPID; older ones
+// convert synchronously and return an empty upid.
+func (c *Client) ConvertToTemplate(ctx context.Context, node string, vmid int) (string, error) {
+	data, err := c.post(ctx, fmt.Sprintf("/nodes/%s/qemu/%d/template", node, vmid), nil)
+	if err != nil {
+		return "", err
+	}
+	if len(data) == 0 || string(data) == "null" {
+		return "", nil
+	}
+	return parseUPID(data)
+}
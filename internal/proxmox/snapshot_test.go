@@ -0,0 +1,71 @@
+package proxmox
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+func TestListSnapshotsSkipsCurrentAndSorts(t *testing.T) {
+	_, client := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api2/json/nodes/pve1/qemu/105/snapshot" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		w.Write([]byte(`{"data":[
+			{"name":"newer","snaptime":200,"parent":"older"},
+			{"name":"current","parent":"newer","description":"You are here!"},
+			{"name":"older","snaptime":100,"vmstate":1}
+		]}`))
+	})
+	snaps, err := client.ListSnapshots(context.Background(), "pve1", 105)
+	if err != nil {
+		t.Fatalf("ListSnapshots: %v", err)
+	}
+	if len(snaps) != 2 || snaps[0].Name != "older" || snaps[1].Name != "newer" {
+		t.Fatalf("unexpected snapshots: %+v", snaps)
+	}
+	if snaps[0].VMState != 1 {
+		t.Errorf("vmstate not decoded: %+v", snaps[0])
+	}
+}
+
+func TestRollbackAndDeleteSnapshotPaths(t *testing.T) {
+	var got []string
+	_, client := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.Method+" "+r.URL.Path)
+		w.Write([]byte(`{"data":"UPID:pve1:0001:snap:"}`))
+	})
+	ctx := context.Background()
+	if _, err := client.RollbackSnapshot(ctx, "pve1", 105, "pre-upgrade"); err != nil {
+		t.Fatalf("RollbackSnapshot: %v", err)
+	}
+	if _, err := client.DeleteSnapshot(ctx, "pve1", 105, "pre-upgrade"); err != nil {
+		t.Fatalf("DeleteSnapshot: %v", err)
+	}
+	want := []string{
+		"POST /api2/json/nodes/pve1/qemu/105/snapshot/pre-upgrade/rollback",
+		"DELETE /api2/json/nodes/pve1/qemu/105/snapshot/pre-upgrade",
+	}
+	if len(got) != 2 || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("requests = %v, want %v", got, want)
+	}
+}
+
+func TestConvertToTemplateSyncAndTask(t *testing.T) {
+	resp := `{"data":null}`
+	_, client := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api2/json/nodes/pve1/qemu/105/template" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		w.Write([]byte(resp))
+	})
+	upid, err := client.ConvertToTemplate(context.Background(), "pve1", 105)
+	if err != nil || upid != "" {
+		t.Fatalf("sync conversion: upid=%q err=%v", upid, err)
+	}
+	resp = `{"data":"UPID:pve1:0001:qmtemplate:"}`
+	upid, err = client.ConvertToTemplate(context.Background(), "pve1", 105)
+	if err != nil || upid == "" {
+		t.Fatalf("task conversion: upid=%q err=%v", upid, err)
+	}
+}
@@ -35,8 +35,8 @@ type StatsBlock struct {
 	V int `json:"v"`
 	// TS is the Unix timestamp (seconds) of the collection.
 	TS int64 `json:"ts"`
-	// GPUs holds per-GPU utilization, sourced from nvidia-smi. Omitted when
-	// no NVIDIA GPU / driver is present.
+	// GPUs holds per-GPU utilization, sourced from nvidia-smi (rocm-smi on AMD
+	// nodes). Omitted when no GPU / driver is present.
 	GPUs []GPUStat `json:"gpus,omitempty"`
 	// Inference holds per-engine internals scraped from local Prometheus
 	// /metrics endpoints. Omitted when no inference engine is reachable.
@@ -100,7 +100,7 @@ type CollectorConfig struct {
 	// Targets are the engine metrics endpoints to scrape (default
 	// DefaultEngineTargets()).
 	Targets []EngineTarget
-	// GPUFn overrides GPU collection (tests). Default: nvidia-smi.
+	// GPUFn overrides GPU collection (tests). Default: nvidia-smi, else rocm-smi.
 	GPUFn func(context.Context) []GPUStat
 }
 
@@ -7,6 +7,8 @@ import (
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/aceteam-ai/citadel-cli/internal/platform"
 )
 
 // nvidiaSMITimeout bounds the nvidia-smi subprocess so a hung driver can never
@@ -18,13 +20,18 @@ const nvidiaSMITimeout = 3 * time.Second
 // depends on this order.
 const nvidiaSMIQuery = "index,utilization.gpu,memory.used,memory.total,temperature.gpu,power.draw"
 
-// collectGPUStats queries nvidia-smi for per-GPU utilization. It returns nil
-// (omit the gpus array) when the binary is absent, times out, or errors —
-// a CPU-only node is the normal case, not a failure.
+// collectGPUStats queries nvidia-smi for per-GPU utilization, falling back to
+// rocm-smi on AMD nodes. It returns nil (omit the gpus array) when neither
+// binary is present, times out, or errors — a CPU-only node is the normal
+// case, not a failure.
 func collectGPUStats(ctx context.Context) []GPUStat {
 	path, err := exec.LookPath("nvidia-smi")
 	if err != nil {
-		return nil
+		gpus, err := platform.QueryROCmGPUs(ctx)
+		if err != nil {
+			return nil
+		}
+		return gpuStatsFromROCm(gpus)
 	}
 
 	ctx, cancel := context.WithTimeout(ctx, nvidiaSMITimeout)
@@ -80,6 +87,30 @@ func parseNvidiaSMICSV(out string) []GPUStat {
 	return gpus
 }
 
+// gpuStatsFromROCm converts rocm-smi GPUs to GPUStat, omitting the fields
+// rocm-smi did not report.
+func gpuStatsFromROCm(gpus []platform.ROCmGPU) []GPUStat {
+	stats := make([]GPUStat, 0, len(gpus))
+	for _, g := range gpus {
+		stat := GPUStat{Index: g.Index}
+		if g.UtilPercent >= 0 {
+			stat.UtilPct = float64Ptr(g.UtilPercent)
+		}
+		if g.VRAMTotalMB > 0 {
+			stat.MemUsedMB = intPtr(g.VRAMUsedMB)
+			stat.MemTotalMB = intPtr(g.VRAMTotalMB)
+		}
+		if g.TempC >= 0 {
+			stat.TempC = intPtr(int(math.Round(g.TempC)))
+		}
+		if g.PowerW >= 0 {
+			stat.PowerW = float64Ptr(round1(g.PowerW))
+		}
+		stats = append(stats, stat)
+	}
+	return stats
+}
+
 // smiFloat parses one nvidia-smi CSV field, reporting ok=false for the
 // sentinel "not available" markers so callers omit the field.
 func smiFloat(field string) (float64, bool) {
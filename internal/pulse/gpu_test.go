@@ -2,7 +2,10 @@ package pulse
 
 import (
 	"encoding/json"
+	"os"
 	"testing"
+
+	"github.com/aceteam-ai/citadel-cli/internal/platform"
 )
 
 func TestParseNvidiaSMICSV(t *testing.T) {
@@ -62,3 +65,26 @@ func TestParseNvidiaSMICSV(t *testing.T) {
 		}
 	})
 }
+
+func TestGPUStatsFromROCm(t *testing.T) {
+	data, err := os.ReadFile("../platform/testdata/rocm-smi-mi210.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := platform.ParseROCmSMI(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gpus := gpuStatsFromROCm(parsed)
+	if len(gpus) != 2 {
+		t.Fatalf("expected 2 gpus, got %d", len(gpus))
+	}
+	b, _ := json.Marshal(gpus[0])
+	if want := `{"i":0,"util_pct":63,"mem_used_mb":32768,"mem_total_mb":65520,"temp_c":41,"power_w":97}`; string(b) != want {
+		t.Errorf("gpu 0: got %s, want %s", b, want)
+	}
+	// rocm-smi's N/A power is omitted, like nvidia-smi's [N/A].
+	if gpus[1].PowerW != nil {
+		t.Errorf("gpu 1 power = %v, want omitted", *gpus[1].PowerW)
+	}
+}
@@ -48,9 +48,11 @@ func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, res)
 }
 
+// queryRequest is the /rag/query body: the query plus the optional search
+// options (top_k, mode, rerank, filters) inlined at the top level.
 type queryRequest struct {
 	Query string `json:"query"`
-	TopK  int    `json:"top_k"`
+	QueryOptions
 }
 
 func (s *Server) handleQuery(w http.ResponseWriter, r *http.Request) {
@@ -67,7 +69,7 @@ func (s *Server) handleQuery(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "query is required"})
 		return
 	}
-	res, err := s.svc.Query(r.Context(), req.Query, req.TopK)
+	res, err := s.svc.QueryWithOptions(r.Context(), req.Query, req.QueryOptions)
 	if err != nil {
 		writeJSON(w, http.StatusBadGateway, map[string]string{"error": err.Error()})
 		return
@@ -19,6 +19,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/aceteam-ai/citadel-cli/internal/jobs"
 	"github.com/aceteam-ai/citadel-cli/internal/nexus"
@@ -53,6 +54,9 @@ type Service struct {
 	// (cmd/work.go constructs the Service with New(), not NewWithRoots()).
 	roots     []string
 	rootsMode bool
+	// ocr sends images and scanned PDF pages through the local OCR service when
+	// indexing. Off by default: each page is a GPU model call.
+	ocr bool
 }
 
 // New constructs a Service with the mesh-safe default (index paths confined to
@@ -94,6 +98,13 @@ func NewWithRoots(roots []string, workspaceForDB, modelOverride string) *Service
 	}
 }
 
+// EnableOCR makes Index OCR images and textless PDF pages through the node's
+// unlimited-ocr service. Returns s for chaining at construction.
+func (s *Service) EnableOCR() *Service {
+	s.ocr = true
+	return s
+}
+
 // Roots returns the authorized roots this Service enforces (nil when not in
 // roots mode).
 func (s *Service) Roots() []string { return s.roots }
@@ -165,6 +176,9 @@ func (s *Service) Index(ctx context.Context, path, filePattern string) (IndexRes
 	if filePattern != "" {
 		payload["file_pattern"] = filePattern
 	}
+	if s.ocr {
+		payload["ocr"] = "true"
+	}
 	out, err := h.Execute(jobCtx(ctx), &nexus.Job{ID: "rag-index", Type: "FILE_INDEX", Payload: payload})
 	if err != nil {
 		return IndexResult{}, err
@@ -176,31 +190,91 @@ func (s *Service) Index(ctx context.Context, path, filePattern string) (IndexRes
 	return res, nil
 }
 
-// Hit is one semantic-search result with its local provenance.
+// Hit is one semantic-search result with its local provenance. Page and
+// Section locate the chunk in its source document when the extractor knew them
+// (PDF pages, slides, headings, sheet names); both are zero for plain text.
 type Hit struct {
 	Path       string  `json:"path"`
 	ChunkIndex int     `json:"chunk_index"`
 	Text       string  `json:"text"`
 	Score      float64 `json:"score"`
+	Page       int     `json:"page,omitempty"`
+	Section    string  `json:"section,omitempty"`
+}
+
+// Citation renders where a hit came from for human output, e.g.
+// "report.pdf p.12 · Results" or "notes.md#3" when no page is known.
+func (h Hit) Citation() string {
+	loc := fmt.Sprintf("%s#%d", filepath.Base(h.Path), h.ChunkIndex)
+	if h.Page > 0 {
+		loc = fmt.Sprintf("%s p.%d", filepath.Base(h.Path), h.Page)
+	}
+	if h.Section != "" {
+		loc += " · " + h.Section
+	}
+	return loc
 }
 
-// QueryResult is the outcome of a Query call.
+// QueryResult is the outcome of a Query call. Mode is the retrieval mode the
+// handler ran and Reranked whether the cross-encoder reordered the hits; Score
+// on each hit is cosine similarity in vector mode, the fused rank score in
+// hybrid mode, and the reranker's relevance when Reranked.
 type QueryResult struct {
 	Hits       []Hit  `json:"hits"`
 	Count      int    `json:"count"`
 	Model      string `json:"model"`
+	Mode       string `json:"mode"`
+	Reranked   bool   `json:"reranked"`
 	Provenance string `json:"provenance"`
 }
 
+// QueryOptions tunes a search beyond the query text. The zero value is a plain
+// top-10 vector search, matching Query.
+type QueryOptions struct {
+	// TopK is the number of hits; <= 0 uses the handler default.
+	TopK int `json:"top_k,omitempty"`
+	// Mode is "vector", "hybrid" or "lexical"; empty means vector.
+	Mode string `json:"mode,omitempty"`
+	// Rerank reorders hits with the TEI cross-encoder when it is available.
+	Rerank bool `json:"rerank,omitempty"`
+	// PathPrefix, Extensions, ModifiedAfter and ModifiedBefore filter by file
+	// metadata. Times are RFC 3339, YYYY-MM-DD or Unix seconds.
+	PathPrefix     string   `json:"path_prefix,omitempty"`
+	Extensions     []string `json:"extensions,omitempty"`
+	ModifiedAfter  string   `json:"modified_after,omitempty"`
+	ModifiedBefore string   `json:"modified_before,omitempty"`
+}
+
 // Query embeds the query with the local TEI service and returns the top-k
 // cosine-nearest chunks from the node-local index. topK <= 0 uses the handler
 // default.
 func (s *Service) Query(ctx context.Context, query string, topK int) (QueryResult, error) {
+	return s.QueryWithOptions(ctx, query, QueryOptions{TopK: topK})
+}
+
+// QueryWithOptions is Query with a retrieval mode, optional reranking and
+// metadata filters (see FileSemanticSearchHandler for their semantics).
+func (s *Service) QueryWithOptions(ctx context.Context, query string, opts QueryOptions) (QueryResult, error) {
 	if query == "" {
 		return QueryResult{}, fmt.Errorf("query is required")
 	}
+	topK := opts.TopK
 	h := jobs.NewFileSemanticSearchHandler(s.workspaceDir, s.dbPath)
 	payload := map[string]string{"query": query, "model": s.model}
+	for k, v := range map[string]string{
+		"mode":            opts.Mode,
+		"path_prefix":     opts.PathPrefix,
+		"extensions":      strings.Join(opts.Extensions, ","),
+		"modified_after":  opts.ModifiedAfter,
+		"modified_before": opts.ModifiedBefore,
+	} {
+		if v != "" {
+			payload[k] = v
+		}
+	}
+	if opts.Rerank {
+		payload["rerank"] = "true"
+	}
 	// In roots mode we filter returned hits to authorized roots, so over-fetch to
 	// compensate for hits dropped by the filter (result count may still be < topK
 	// when many hits fall outside the roots).
@@ -215,11 +289,14 @@ func (s *Service) Query(ctx context.Context, query string, topK int) (QueryResul
 	if err != nil {
 		return QueryResult{}, err
 	}
-	// The handler emits {hits, count, model}; decode and attach provenance.
+	// The handler emits {hits, count, model, mode, reranked}; decode and attach
+	// provenance.
 	var raw struct {
-		Hits  []Hit  `json:"hits"`
-		Count int    `json:"count"`
-		Model string `json:"model"`
+		Hits     []Hit  `json:"hits"`
+		Count    int    `json:"count"`
+		Model    string `json:"model"`
+		Mode     string `json:"mode"`
+		Reranked bool   `json:"reranked"`
 	}
 	if err := json.Unmarshal(out, &raw); err != nil {
 		return QueryResult{}, fmt.Errorf("decode query result: %w", err)
@@ -232,6 +309,8 @@ func (s *Service) Query(ctx context.Context, query string, topK int) (QueryResul
 		Hits:       hits,
 		Count:      len(hits),
 		Model:      raw.Model,
+		Mode:       raw.Mode,
+		Reranked:   raw.Reranked,
 		Provenance: s.Provenance(),
 	}, nil
 }
@@ -205,6 +205,25 @@ func TestServiceWorkspaceBoundary(t *testing.T) {
 	}
 }
 
+func TestServiceQueryCitesSection(t *testing.T) {
+	svc, ws := newTestService(t)
+	writeFile(t, ws, "pets.html", "<h2>Kittens</h2><p>A kitten is a small cat.</p>")
+
+	if _, err := svc.Index(context.Background(), ws, ""); err != nil {
+		t.Fatalf("Index: %v", err)
+	}
+	res, err := svc.Query(context.Background(), "kitten", 1)
+	if err != nil || len(res.Hits) != 1 {
+		t.Fatalf("Query = %+v, %v", res, err)
+	}
+	if got := res.Hits[0].Citation(); got != "pets.html#0 · Kittens" {
+		t.Errorf("Citation() = %q, want the heading cited", got)
+	}
+	if got := (Hit{Path: "/d/report.pdf", ChunkIndex: 4, Page: 12}).Citation(); got != "report.pdf p.12" {
+		t.Errorf("Citation() = %q, want the page cited", got)
+	}
+}
+
 func writeFile(t *testing.T, dir, name, content string) {
 	t.Helper()
 	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
@@ -337,6 +337,35 @@ func (c *Client) parseMessage(msg redis.XMessage) (*Job, error) {
 	return job, nil
 }
 
+// PeekBacklog returns up to count messages on queue that the consumer group
+// has not delivered to any consumer yet, oldest first. Nothing is claimed: the
+// messages stay where they are for XREADGROUP. Messages that fail to parse are
+// skipped; the consuming read reports them.
+func (c *Client) PeekBacklog(ctx context.Context, queue string, count int64) ([]*Job, error) {
+	groups, err := c.client.XInfoGroups(ctx, queue).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect %s: %w", queue, err)
+	}
+	start := "-"
+	for _, g := range groups {
+		if g.Name == c.consumerGroup {
+			start = "(" + g.LastDeliveredID
+			break
+		}
+	}
+	msgs, err := c.client.XRangeN(ctx, queue, start, "+", count).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backlog of %s: %w", queue, err)
+	}
+	jobs := make([]*Job, 0, len(msgs))
+	for _, msg := range msgs {
+		if job, err := c.parseMessage(msg); err == nil {
+			jobs = append(jobs, job)
+		}
+	}
+	return jobs, nil
+}
+
 // AckJob acknowledges a successfully processed message.
 func (c *Client) AckJob(ctx context.Context, messageID string) error {
 	return c.client.XAck(ctx, c.queueName, c.consumerGroup, messageID).Err()
@@ -450,10 +479,22 @@ func (c *Client) PublishStart(ctx context.Context, jobID, rayID, message string)
 
 // PublishChunk publishes a "chunk" event for streaming responses.
 func (c *Client) PublishChunk(ctx context.Context, jobID, rayID, content string, index int) error {
-	return c.PublishStreamEvent(ctx, jobID, rayID, "chunk", map[string]interface{}{
+	return c.PublishChunkData(ctx, jobID, rayID, content, index, nil)
+}
+
+// PublishChunkData publishes a "chunk" event carrying structured fields (e.g.
+// tool-call deltas, logprobs) alongside the content.
+func (c *Client) PublishChunkData(ctx context.Context, jobID, rayID, content string, index int, data map[string]interface{}) error {
+	event := map[string]interface{}{
 		"content": content,
 		"index":   index,
-	})
+	}
+	for k, v := range data {
+		if k != "content" && k != "index" {
+			event[k] = v
+		}
+	}
+	return c.PublishStreamEvent(ctx, jobID, rayID, "chunk", event)
 }
 
 // PublishEnd publishes an "end" event when job completes.
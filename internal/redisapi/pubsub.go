@@ -89,10 +89,22 @@ func (c *Client) PublishStart(ctx context.Context, jobID, rayID, message string)
 
 // PublishChunk publishes a "chunk" event for streaming responses.
 func (c *Client) PublishChunk(ctx context.Context, jobID, rayID, content string, index int) error {
-	return c.PublishStreamEvent(ctx, jobID, rayID, "chunk", map[string]any{
+	return c.PublishChunkData(ctx, jobID, rayID, content, index, nil)
+}
+
+// PublishChunkData publishes a "chunk" event carrying structured fields (e.g.
+// tool-call deltas, logprobs) alongside the content.
+func (c *Client) PublishChunkData(ctx context.Context, jobID, rayID, content string, index int, data map[string]any) error {
+	event := map[string]any{
 		"content": content,
 		"index":   index,
-	})
+	}
+	for k, v := range data {
+		if k != "content" && k != "index" {
+			event[k] = v
+		}
+	}
+	return c.PublishStreamEvent(ctx, jobID, rayID, "chunk", event)
 }
 
 // PublishEnd publishes an "end" event when job completes.
@@ -7,6 +7,8 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+
+	"github.com/aceteam-ai/citadel-cli/internal/platform"
 )
 
 // parseComputeApps parses nvidia-smi `--query-compute-apps=pid,used_memory`
@@ -69,6 +71,20 @@ func parseGPUTotals(out string) (used, total uint64, util float64) {
 	return used, total, util
 }
 
+// rocmTotals is parseGPUTotals for rocm-smi GPUs: VRAM summed across devices
+// in bytes, and the max utilization (-1 when no device reports one).
+func rocmTotals(gpus []platform.ROCmGPU) (used, total uint64, util float64) {
+	util = -1
+	for _, g := range gpus {
+		used += uint64(g.VRAMUsedMB) << 20
+		total += uint64(g.VRAMTotalMB) << 20
+		if g.UtilPercent > util {
+			util = g.UtilPercent
+		}
+	}
+	return used, total, util
+}
+
 // parsePsOutput parses tab-separated `<engine> ps --format {{.ID}}\t{{.Names}}`
 // output into a container-id → name map. Both the full (untruncated) id and a
 // 12-char short id are indexed, because /proc/<pid>/cgroup may reference either
@@ -26,6 +26,7 @@ import (
 	"time"
 
 	"github.com/aceteam-ai/citadel-cli/internal/catalog"
+	"github.com/aceteam-ai/citadel-cli/internal/platform"
 )
 
 // collectTimeout bounds the nvidia-smi / container-runtime execs so a hung
@@ -191,16 +192,16 @@ func collectWith(ctx context.Context, p probe, tracker *idleTracker, managedName
 	return snap
 }
 
-// realProbe is the production probe backed by nvidia-smi, the selected
-// container runtime, and /proc.
+// realProbe is the production probe backed by nvidia-smi (rocm-smi on AMD
+// nodes), the selected container runtime, and /proc.
 type realProbe struct{}
 
 // GPU runs one compute-apps query (pid → vram) and one gpu memory+util query,
-// returning per-pid VRAM plus whole-node totals. hasGPU is false when nvidia-smi
-// is absent or the compute-apps query fails.
+// returning per-pid VRAM plus whole-node totals. hasGPU is false when neither
+// nvidia-smi nor rocm-smi is present, or the per-process query fails.
 func (realProbe) GPU(ctx context.Context) (pidVRAM map[int]uint64, used, total uint64, util float64, hasGPU bool) {
 	if _, err := exec.LookPath("nvidia-smi"); err != nil {
-		return nil, 0, 0, -1, false
+		return rocmGPU(ctx)
 	}
 	appsOut, err := exec.CommandContext(ctx, "nvidia-smi",
 		"--query-compute-apps=pid,used_memory", "--format=csv,noheader,nounits").Output()
@@ -219,6 +220,21 @@ func (realProbe) GPU(ctx context.Context) (pidVRAM map[int]uint64, used, total u
 	return pidVRAM, used, total, util, true
 }
 
+// rocmGPU is the AMD form of realProbe.GPU: the same two reads, through
+// rocm-smi's --showpids and device queries.
+func rocmGPU(ctx context.Context) (pidVRAM map[int]uint64, used, total uint64, util float64, hasGPU bool) {
+	pidVRAM, err := platform.QueryROCmPIDs(ctx)
+	if err != nil {
+		return nil, 0, 0, -1, false
+	}
+	gpus, err := platform.QueryROCmGPUs(ctx)
+	if err != nil {
+		return pidVRAM, 0, 0, -1, true
+	}
+	used, total, util = rocmTotals(gpus)
+	return pidVRAM, used, total, util, true
+}
+
 // Containers runs one `<engine> ps --no-trunc` and returns container-id → name.
 // Empty map on any error (no runtime / daemon down) — callers then classify
 // every containerized pid as host, which is a safe degradation.
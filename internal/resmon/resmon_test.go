@@ -3,8 +3,11 @@ package resmon
 import (
 	"context"
 	"encoding/json"
+	"os"
 	"testing"
 	"time"
+
+	"github.com/aceteam-ai/citadel-cli/internal/platform"
 )
 
 // gib is a non-constant GiB multiplier so fractional byte literals compile as
@@ -61,6 +64,27 @@ func TestParseGPUTotals_Empty(t *testing.T) {
 	}
 }
 
+func TestROCmTotals(t *testing.T) {
+	data, err := os.ReadFile("../platform/testdata/rocm-smi-mi210.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gpus, err := platform.ParseROCmSMI(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	used, total, util := rocmTotals(gpus)
+	if want := uint64(32768+10) * (1 << 20); used != want {
+		t.Errorf("used = %d, want %d", used, want)
+	}
+	if want := uint64(65520+65520) * (1 << 20); total != want {
+		t.Errorf("total = %d, want %d", total, want)
+	}
+	if util != 63 {
+		t.Errorf("util = %v, want 63 (max across devices)", util)
+	}
+}
+
 func TestParsePsOutput(t *testing.T) {
 	out := "abc123def4560000000000000000000000000000000000000000000000000000\ttei-gte\n" +
 		"fff000\tcileadel\n"
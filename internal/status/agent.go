@@ -5,6 +5,8 @@ import (
 	"io"
 	"net/http"
 	"strconv"
+
+	"github.com/aceteam-ai/citadel-cli/internal/events"
 )
 
 // Agent-facing introspection & control endpoints (issue #236).
@@ -64,6 +66,11 @@ type AgentProviders struct {
 	// Unexpose revokes an exposure by name, the inverse of Expose. Same
 	// in-process reasoning: only this process holds the live gateway.
 	Unexpose func(name string) (any, error)
+
+	// Events subscribes to the node-local event bus for the events matching
+	// patterns (all when empty), backing the /agent/events SSE stream. The
+	// returned function unsubscribes.
+	Events func(patterns []string) (<-chan events.Event, func())
 }
 
 // ExposeSpec is the /agent/expose request body. It mirrors the EXPOSE_SET job
@@ -85,6 +92,9 @@ type ExposeSpec struct {
 	// Epoch, when >0, is bound into a `link` token so all outstanding tokens can
 	// be revoked by bumping it.
 	Epoch int `json:"epoch"`
+	// StorageKey is the issued storage access key (id) an object-storage
+	// exposure re-signs requests with; empty for ordinary services.
+	StorageKey string `json:"storage_key,omitempty"`
 }
 
 // LogQuery describes a log tail/grep request.
@@ -134,6 +144,7 @@ func (s *Server) registerAgentRoutes(mux *http.ServeMux) {
 	})))
 
 	mux.HandleFunc("/agent/logs", s.requireVPNOrAuth(s.handleAgentLogs))
+	mux.HandleFunc("/agent/events", s.requireVPNOrAuth(s.handleAgentEvents))
 
 	// Control endpoints (POST, may be nil -> 503).
 	mux.HandleFunc("/agent/set-log-level", s.requireVPNOrAuth(s.handleSetLogLevel))
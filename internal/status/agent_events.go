@@ -0,0 +1,81 @@
+package status
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// sseKeepAlive is how often an idle /agent/events stream sends a comment, so
+// proxies and clients do not time the connection out between events.
+const sseKeepAlive = 15 * time.Second
+
+// handleAgentEvents serves GET /agent/events?types=job.*,service.state_changed
+// as a Server-Sent Events stream of the node's event bus. Each event is sent
+// with its type as the SSE event name, its ID as the SSE id and the JSON event
+// as data. A client that falls behind misses events rather than stalling the
+// bus; it is expected to reconnect and re-read state.
+func (s *Server) handleAgentEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.agent == nil || s.agent.Events == nil {
+		writeAgentError(w, errUnavailable)
+		return
+	}
+	var patterns []string
+	for _, p := range strings.Split(r.URL.Query().Get("types"), ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			patterns = append(patterns, p)
+		}
+	}
+
+	// The server's WriteTimeout would cut the stream after 30s; lift it for
+	// this response only.
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		writeAgentError(w, err)
+		return
+	}
+
+	ch, unsubscribe := s.agent.Events(patterns)
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, ": subscribed\n\n")
+	rc.Flush()
+
+	keepAlive := time.NewTicker(sseKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		case ev, ok := <-ch:
+			if !ok {
+				return
+			}
+			data, err := json.Marshal(ev)
+			if err != nil {
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", ev.ID, ev.Type, data); err != nil {
+				return
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package status
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aceteam-ai/citadel-cli/internal/events"
+)
+
+func TestAgentEventsStreamsMatchingEvents(t *testing.T) {
+	bus := events.NewBus()
+	subscribed := make(chan []string, 1)
+	_, mux := newAgentMux(&AgentProviders{
+		Events: func(patterns []string) (<-chan events.Event, func()) {
+			ch, cancel := bus.Subscribe(patterns...)
+			subscribed <- patterns
+			return ch, cancel
+		},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r := vpnReq(http.MethodGet, "/agent/events?types=job.*,+swap.finished").WithContext(ctx)
+	w := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		mux.ServeHTTP(w, r)
+		close(done)
+	}()
+
+	select {
+	case patterns := <-subscribed:
+		if len(patterns) != 2 || patterns[0] != "job.*" || patterns[1] != "swap.finished" {
+			t.Errorf("patterns = %q", patterns)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("handler never subscribed")
+	}
+	bus.Publish(events.TypeSwapStarted, nil)
+	bus.Publish(events.TypeJobFailed, map[string]any{"job_id": "j1"})
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	<-done
+
+	if ct := w.Header().Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	body := w.Body.String()
+	if !strings.Contains(body, "event: job.failed\n") || !strings.Contains(body, `"job_id":"j1"`) {
+		t.Errorf("stream missing the job event:\n%s", body)
+	}
+	if strings.Contains(body, "swap.started") {
+		t.Errorf("stream carried an unsubscribed event:\n%s", body)
+	}
+}
+
+func TestAgentEventsUnavailable(t *testing.T) {
+	_, mux := newAgentMux(&AgentProviders{})
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, vpnReq(http.MethodGet, "/agent/events"))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 for unwired provider, got %d", w.Code)
+	}
+}
@@ -34,6 +34,8 @@ type Collector struct {
 	netIdleTracker *IdleTracker           // network-activity idle for non-vLLM services (citadel #433)
 	pinnedServices map[string]bool        // node pinned_services allowlist -> ServiceInfo.Pinned (citadel #577)
 	modelHotswap   bool                   // advertise installed-vs-resident models (citadel #632)
+	supervision    func() map[string]SupervisionState
+	failedUpdate   func() *FailedUpdate
 }
 
 // ServiceConfig holds the configuration for a service from the manifest.
@@ -64,6 +66,14 @@ type CollectorConfig struct {
 	// default; wired from CITADEL_MODEL_HOTSWAP. Requires ConfigDir to enumerate
 	// installed engines. When false the heartbeat output is unchanged.
 	ModelHotswap bool
+	// Supervision, when set, returns the service supervisor's per-service
+	// state (restart count, last failure, crash loop) keyed by service name,
+	// attached to each reported service. Optional: nil when `citadel work`
+	// runs without the supervisor.
+	Supervision func() map[string]SupervisionState
+	// FailedUpdate, when set, returns the last self-update rolled back after
+	// failing verification, reported as NodeStatus.FailedUpdate. Optional.
+	FailedUpdate func() *FailedUpdate
 }
 
 // NewCollector creates a new status collector.
@@ -81,6 +91,8 @@ func NewCollector(cfg CollectorConfig) *Collector {
 		netIdleTracker: NewIdleTracker(IdleThresholdSeconds()),
 		pinnedServices: toStringSet(cfg.PinnedServices),
 		modelHotswap:   cfg.ModelHotswap,
+		supervision:    cfg.Supervision,
+		failedUpdate:   cfg.FailedUpdate,
 	}
 }
 
@@ -182,6 +194,20 @@ func (c *Collector) Collect() (*NodeStatus, error) {
 		}
 	}
 
+	// Attach the supervisor's restart counts and crash loops, and report a
+	// crash-looping service that is no longer running. Such a service joins
+	// `reported` so hotswap does not advertise it again as a swap-in candidate.
+	if c.supervision != nil {
+		attachSupervision(status, c.supervision())
+		for _, svc := range status.Services {
+			reported[svc.Name] = struct{}{}
+		}
+	}
+
+	if c.failedUpdate != nil {
+		status.FailedUpdate = c.failedUpdate()
+	}
+
 	// Collect installed app status
 	status.Apps = c.collectAppStatus()
 
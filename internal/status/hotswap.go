@@ -33,6 +33,23 @@ func ModelHotswapEnabled() bool {
 	}
 }
 
+// ModelPrewarmEnabled reports whether speculative model prewarming (loading the
+// engine the demand predictor expects next into free VRAM) is active. Default
+// OFF: it spends GPU memory and load time on a guess, so a node opts in with a
+// truthy CITADEL_MODEL_PREWARM (1/true/yes/on). It rides on hotswap, so it is
+// also off whenever ModelHotswapEnabled is.
+func ModelPrewarmEnabled() bool {
+	if !ModelHotswapEnabled() {
+		return false
+	}
+	switch strings.ToLower(strings.TrimSpace(os.Getenv("CITADEL_MODEL_PREWARM"))) {
+	case "1", "true", "yes", "on":
+		return true
+	default:
+		return false
+	}
+}
+
 // engineModelEnvVars maps a managed serving engine to the <name>.env variable(s)
 // (in preference order) that select its served model. Mirrors the compose files'
 // ${VAR:-default} interpolation so a stopped engine advertises the same model id
@@ -57,6 +57,10 @@ type Server struct {
 	// install additional routes (e.g., provisioning API) during Start.
 	routeRegistrars []RouteRegistrar
 
+	// reservations, when set via SetReservationsProvider, reports the
+	// provisioning ledger alongside the /resources snapshot.
+	reservations func() any
+
 	// extraRoutes is called during Start() to register additional HTTP routes.
 	extraRoutes func(mux *http.ServeMux)
 	// extraListeners are additional net.Listeners the server will also serve on
@@ -224,6 +228,14 @@ func (s *Server) AddRouteRegistrar(reg RouteRegistrar) {
 	s.routeRegistrars = append(s.routeRegistrars, reg)
 }
 
+// SetReservationsProvider makes /resources include the capacity reserved by
+// provisioned resources under a "reservations" key. It takes a func rather
+// than the provision manager to keep this package free of that import.
+// Must be called before Start.
+func (s *Server) SetReservationsProvider(fn func() any) {
+	s.reservations = fn
+}
+
 // buildMux constructs the HTTP route multiplexer for the server, registering
 // all enabled endpoints based on the server's configuration. It reads config
 // fields but mutates no shared Server state, so it can be invoked synchronously
@@ -491,7 +503,9 @@ func (s *Server) handleServices(w http.ResponseWriter, r *http.Request) {
 // reclaimable flag for heavy-idle unmanaged consumers (issue #427). This is
 // what lets the fabric answer "is this node's GPU actually free?" without SSH,
 // including the leftover test/dev services (#421's tei-gte leftover) that
-// citadel doesn't own and previously couldn't see.
+// citadel doesn't own and previously couldn't see. When provisioning is
+// enabled, the capacity held by provisioned resources rides along under
+// "reservations" so placement sees both sides of the node.
 // GET /resources
 func (s *Server) handleResources(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
@@ -501,10 +515,16 @@ func (s *Server) handleResources(w http.ResponseWriter, r *http.Request) {
 
 	ctx, cancel := context.WithTimeout(r.Context(), 6*time.Second)
 	defer cancel()
-	snapshot := resmon.Collect(ctx)
+	resp := struct {
+		resmon.Snapshot
+		Reservations any `json:"reservations,omitempty"`
+	}{Snapshot: resmon.Collect(ctx)}
+	if s.reservations != nil {
+		resp.Reservations = s.reservations()
+	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(snapshot)
+	json.NewEncoder(w).Encode(resp)
 }
 
 // handlePing returns a lightweight pong response for health checks.
@@ -582,3 +582,27 @@ func TestServerWorkerEndpointRejectsNonGET(t *testing.T) {
 		t.Errorf("StatusCode = %v, want %v", w.Code, http.StatusMethodNotAllowed)
 	}
 }
+
+func TestServerResourcesEndpointIncludesReservations(t *testing.T) {
+	server := NewServer(ServerConfig{}, NewCollector(CollectorConfig{}))
+	server.SetReservationsProvider(func() any {
+		return map[string]int{"queued": 2}
+	})
+
+	w := httptest.NewRecorder()
+	server.handleResources(w, httptest.NewRequest(http.MethodGet, "/resources", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("StatusCode = %v, want %v", w.Code, http.StatusOK)
+	}
+	var body struct {
+		Reservations struct {
+			Queued int `json:"queued"`
+		} `json:"reservations"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if body.Reservations.Queued != 2 {
+		t.Errorf("reservations = %+v, want the provider's ledger", body.Reservations)
+	}
+}
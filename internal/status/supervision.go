@@ -0,0 +1,54 @@
+package status
+
+import (
+	"sort"
+	"time"
+)
+
+// SupervisionState is the service supervisor's view of one service: how often
+// `citadel work` restarted it and why it last failed. It is embedded in
+// ServiceInfo (like IdleState) so its fields sit at the top level of the JSON,
+// and it is omitted entirely for a service that never failed.
+type SupervisionState struct {
+	RestartCount  int        `json:"restart_count,omitempty"`
+	LastFailure   string     `json:"last_failure,omitempty"`
+	LastFailureAt *time.Time `json:"last_failure_at,omitempty"`
+	// CrashLooping means the supervisor gave up restarting the service after
+	// it kept failing; it needs an operator.
+	CrashLooping bool `json:"crash_looping,omitempty"`
+}
+
+// attachSupervision copies supervision state onto the reported services and
+// marks a crash-looping one unhealthy. A crash-looping service usually has no
+// running container, so the collector did not report it at all; it is
+// appended as an errored service so the platform sees why it is gone instead
+// of the service silently vanishing from the heartbeat.
+func attachSupervision(status *NodeStatus, states map[string]SupervisionState) {
+	reported := make(map[string]bool, len(status.Services))
+	for i := range status.Services {
+		reported[status.Services[i].Name] = true
+		if st, ok := states[status.Services[i].Name]; ok {
+			status.Services[i].SupervisionState = &st
+			if st.CrashLooping {
+				status.Services[i].Health = HealthStatusUnhealthy
+			}
+		}
+	}
+	var down []string
+	for name, st := range states {
+		if st.CrashLooping && !reported[name] {
+			down = append(down, name)
+		}
+	}
+	sort.Strings(down)
+	for _, name := range down {
+		st := states[name]
+		status.Services = append(status.Services, ServiceInfo{
+			Name:             name,
+			Type:             ServiceTypeOther,
+			Status:           ServiceStatusError,
+			Health:           HealthStatusUnhealthy,
+			SupervisionState: &st,
+		})
+	}
+}
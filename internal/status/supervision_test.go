@@ -0,0 +1,48 @@
+package status
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAttachSupervision(t *testing.T) {
+	failedAt := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	st := &NodeStatus{Services: []ServiceInfo{
+		{Name: "vllm", Status: ServiceStatusRunning, Health: HealthStatusOK},
+		{Name: "kokoro", Status: ServiceStatusRunning, Health: HealthStatusOK},
+	}}
+	attachSupervision(st, map[string]SupervisionState{
+		"vllm":   {RestartCount: 2, LastFailure: "container exited with code 137", LastFailureAt: &failedAt},
+		"comfy":  {RestartCount: 5, LastFailure: "container exited with code 1", CrashLooping: true},
+		"stable": {RestartCount: 1},
+	})
+
+	if len(st.Services) != 3 {
+		t.Fatalf("services = %+v, want the crash-looping service appended", st.Services)
+	}
+	if st.Services[0].SupervisionState == nil || st.Services[0].RestartCount != 2 {
+		t.Errorf("vllm supervision = %+v", st.Services[0].SupervisionState)
+	}
+	if st.Services[1].SupervisionState != nil {
+		t.Errorf("kokoro never failed but got %+v", st.Services[1].SupervisionState)
+	}
+	comfy := st.Services[2]
+	if comfy.Name != "comfy" || comfy.Status != ServiceStatusError || comfy.Health != HealthStatusUnhealthy || !comfy.CrashLooping {
+		t.Errorf("comfy = %+v", comfy)
+	}
+
+	data, err := json.Marshal(st.Services[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`"restart_count":2`, `"last_failure":"container exited with code 137"`, `"last_failure_at":"2026-01-01T12:00:00Z"`} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("JSON %s missing %s", data, want)
+		}
+	}
+	if data, _ := json.Marshal(st.Services[1]); strings.Contains(string(data), "restart_count") {
+		t.Errorf("unsupervised service JSON carries supervision fields: %s", data)
+	}
+}
@@ -39,6 +39,17 @@ type NodeStatus struct {
 	// jobs (issue #548). Additive and back-compatible: omitted on nodes that run
 	// no worker loop (pure status/desktop nodes) and on legacy builds.
 	Worker *WorkerLiveness `json:"worker,omitempty"`
+	// FailedUpdate is the last self-update this node rolled back after it
+	// failed post-restart verification, so the platform can tell why the node
+	// is still on its old version. Omitted when no update has failed.
+	FailedUpdate *FailedUpdate `json:"failed_update,omitempty"`
+}
+
+// FailedUpdate is the heartbeat-facing record of a rolled-back self-update.
+type FailedUpdate struct {
+	Version string     `json:"version"`
+	Reason  string     `json:"reason"`
+	At      *time.Time `json:"at,omitempty"`
 }
 
 // WorkerLiveness is the heartbeat-facing view of the job consume loop. It is the
@@ -226,6 +237,12 @@ type ServiceInfo struct {
 	// to the top level of the JSON object. See IdleState.
 	*IdleState
 
+	// Supervision is the service supervisor's restart count, last failure and
+	// crash-loop flag (restart_count/last_failure/last_failure_at/
+	// crash_looping at the top level). Omitted for a service that never
+	// failed, or when `citadel work` runs without the supervisor.
+	*SupervisionState
+
 	// Footprint is the live resource footprint (CPU/RAM/VRAM/GPU) of the
 	// service's container, populated for running managed services (citadel
 	// #421). Omitted when stats could not be read. Rides the heartbeat so the
@@ -0,0 +1,183 @@
+package storage
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Exposing the gateway through the node's HTTPS gateway. The exposure serves
+// it under /expose/<name>/, so a client's SigV4 signature, which covers the
+// host and path it sent, can never verify at VersityGW. Instead the exposure
+// authenticates callers itself (org membership or a link token) and the
+// rewrite below re-signs each request with one issued key. That key, not the
+// caller's, is what the gateway authorizes, so its bucket scope and read-only
+// mode bound everything the exposure can reach. Clients still need an S3
+// signature to be well-formed, so they use any placeholder credential with a
+// path-style endpoint of https://<node>/expose/<name>.
+
+// presignParams are the query parameters of a presigned URL. The exposure
+// re-signs with headers, so a client's presigned query auth is discarded.
+var presignParams = []string{
+	"X-Amz-Algorithm", "X-Amz-Credential", "X-Amz-Date", "X-Amz-Expires",
+	"X-Amz-Signature", "X-Amz-SignedHeaders", "X-Amz-Security-Token",
+}
+
+// ExposeRewrite returns the gateway.ExposePolicy.Rewrite hook that re-signs
+// proxied requests as key. The request it sees already targets the gateway's
+// loopback port with the exposure prefix stripped.
+func ExposeRewrite(key AccessKey) func(*http.Request) {
+	return func(req *http.Request) {
+		rewriteForKey(req, key, time.Now())
+	}
+}
+
+func rewriteForKey(req *http.Request, key AccessKey, now time.Time) {
+	chunked := isAWSChunked(req)
+	// The caller's credentials are meaningless upstream: strip them, the
+	// exposure's own token, and any signing state the client computed.
+	req.Header.Del("Authorization")
+	req.Header.Del("Cookie")
+	req.Header.Del("X-Citadel-Access")
+	req.Header.Del("X-Amz-Date")
+	req.Header.Del("X-Amz-Content-Sha256")
+	req.Header.Del("X-Amz-Security-Token")
+	if q := req.URL.Query(); len(q) > 0 {
+		q.Del("access_token")
+		for _, p := range presignParams {
+			q.Del(p)
+		}
+		req.URL.RawQuery = encodeQuery(q)
+	}
+	if chunked {
+		decodeAWSChunked(req)
+	}
+	signV4(req, key.AccessKey, key.SecretKey, unsignedPayload, now)
+}
+
+// encodeQuery is url.Values.Encode but keeps valueless keys bare ("?policy",
+// not "?policy="), which some S3 servers route on.
+func encodeQuery(q map[string][]string) string {
+	var parts []string
+	for _, k := range sortedKeys(q) {
+		for _, v := range q[k] {
+			if v == "" {
+				parts = append(parts, s3Escape(k, true))
+				continue
+			}
+			parts = append(parts, s3Escape(k, true)+"="+s3Escape(v, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// isAWSChunked reports whether the body uses SigV4 streaming framing
+// (aws-chunked), which SDKs use for uploads by default. Its per-chunk
+// signatures chain from the client's seed signature and cannot be re-signed.
+func isAWSChunked(req *http.Request) bool {
+	if strings.HasPrefix(req.Header.Get("X-Amz-Content-Sha256"), "STREAMING-") {
+		return true
+	}
+	for _, enc := range strings.Split(req.Header.Get("Content-Encoding"), ",") {
+		if strings.TrimSpace(enc) == "aws-chunked" {
+			return true
+		}
+	}
+	return req.Header.Get("X-Amz-Decoded-Content-Length") != ""
+}
+
+// decodeAWSChunked replaces an aws-chunked body with the plain payload it
+// frames, so the request can be forwarded as an ordinary UNSIGNED-PAYLOAD
+// upload. Chunk signatures and trailers (checksums) are dropped.
+func decodeAWSChunked(req *http.Request) {
+	var encs []string
+	for _, enc := range strings.Split(req.Header.Get("Content-Encoding"), ",") {
+		if enc = strings.TrimSpace(enc); enc != "" && enc != "aws-chunked" {
+			encs = append(encs, enc)
+		}
+	}
+	if len(encs) > 0 {
+		req.Header.Set("Content-Encoding", strings.Join(encs, ","))
+	} else {
+		req.Header.Del("Content-Encoding")
+	}
+	// The transport sends ContentLength; unknown (-1) goes out chunked.
+	req.ContentLength = -1
+	if n, err := strconv.ParseInt(req.Header.Get("X-Amz-Decoded-Content-Length"), 10, 64); err == nil {
+		req.ContentLength = n
+	}
+	req.Header.Del("X-Amz-Decoded-Content-Length")
+	req.Header.Del("X-Amz-Trailer")
+	if req.Body != nil && req.Body != http.NoBody {
+		req.Body = &awsChunkedReader{r: bufio.NewReader(req.Body), closer: req.Body}
+	}
+}
+
+// awsChunkedReader yields the payload of an aws-chunked body:
+//
+//	<hex-size>[;chunk-signature=<sig>]\r\n<data>\r\n ... 0[;...]\r\n[trailers]\r\n
+type awsChunkedReader struct {
+	r      *bufio.Reader
+	closer io.Closer
+	left   int64 // bytes remaining in the current chunk
+	done   bool
+}
+
+func (c *awsChunkedReader) Read(p []byte) (int, error) {
+	for c.left == 0 {
+		if c.done {
+			return 0, io.EOF
+		}
+		if err := c.nextChunk(); err != nil {
+			return 0, err
+		}
+	}
+	if int64(len(p)) > c.left {
+		p = p[:c.left]
+	}
+	n, err := c.r.Read(p)
+	c.left -= int64(n)
+	if c.left == 0 && err == nil {
+		err = c.expectCRLF()
+	}
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return n, err
+}
+
+// nextChunk reads a chunk header; a zero-size chunk ends the payload.
+func (c *awsChunkedReader) nextChunk() error {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return fmt.Errorf("aws-chunked: reading chunk header: %w", io.ErrUnexpectedEOF)
+	}
+	size := strings.TrimSpace(line)
+	if i := strings.IndexByte(size, ';'); i >= 0 {
+		size = size[:i]
+	}
+	n, err := strconv.ParseInt(size, 16, 64)
+	if err != nil || n < 0 {
+		return fmt.Errorf("aws-chunked: bad chunk size %q", size)
+	}
+	if n == 0 {
+		c.done = true
+		return nil
+	}
+	c.left = n
+	return nil
+}
+
+func (c *awsChunkedReader) expectCRLF() error {
+	var crlf [2]byte
+	if _, err := io.ReadFull(c.r, crlf[:]); err != nil || crlf != [2]byte{'\r', '\n'} {
+		return fmt.Errorf("aws-chunked: missing chunk terminator")
+	}
+	return nil
+}
+
+func (c *awsChunkedReader) Close() error { return c.closer.Close() }
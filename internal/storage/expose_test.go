@@ -0,0 +1,72 @@
+package storage
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRewriteForKey_ReplacesClientAuth(t *testing.T) {
+	key := AccessKey{AccessKey: "ISSUED", SecretKey: "secret"}
+	req, _ := http.NewRequest(http.MethodGet,
+		"http://127.0.0.1:7070/bucket/obj?access_token=tok&X-Amz-Signature=abc&X-Amz-Credential=c&versionId=7", nil)
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential=placeholder/...")
+	req.Header.Set("X-Citadel-Access", "tok")
+	req.Header.Set("Cookie", "citadel_expose_storage=tok")
+	req.Header.Set("X-Amz-Security-Token", "sts")
+
+	rewriteForKey(req, key, time.Unix(1700000000, 0))
+
+	if got := req.URL.RawQuery; got != "versionId=7" {
+		t.Fatalf("query = %q, want only versionId", got)
+	}
+	for _, h := range []string{"X-Citadel-Access", "Cookie", "X-Amz-Security-Token"} {
+		if req.Header.Get(h) != "" {
+			t.Fatalf("%s must not reach the gateway", h)
+		}
+	}
+	if auth := req.Header.Get("Authorization"); !strings.Contains(auth, "Credential=ISSUED/") {
+		t.Fatalf("not re-signed as the issued key: %s", auth)
+	}
+	if req.Header.Get("X-Amz-Content-Sha256") != unsignedPayload {
+		t.Fatalf("payload must be unsigned so uploads stream")
+	}
+}
+
+func TestRewriteForKey_DecodesAWSChunked(t *testing.T) {
+	body := "5;chunk-signature=aaaa\r\nhello\r\n" +
+		"6;chunk-signature=bbbb\r\n world\r\n" +
+		"0;chunk-signature=cccc\r\nx-amz-checksum-crc32:AAAAAA==\r\n\r\n"
+	req, _ := http.NewRequest(http.MethodPut, "http://127.0.0.1:7070/bucket/obj", strings.NewReader(body))
+	req.Header.Set("X-Amz-Content-Sha256", "STREAMING-AWS4-HMAC-SHA256-PAYLOAD-TRAILER")
+	req.Header.Set("Content-Encoding", "aws-chunked")
+	req.Header.Set("X-Amz-Decoded-Content-Length", "11")
+	req.Header.Set("X-Amz-Trailer", "x-amz-checksum-crc32")
+
+	rewriteForKey(req, AccessKey{AccessKey: "K", SecretKey: "S"}, time.Now())
+
+	got, err := io.ReadAll(req.Body)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if string(got) != "hello world" {
+		t.Fatalf("decoded body = %q", got)
+	}
+	if req.ContentLength != 11 {
+		t.Fatalf("ContentLength = %d, want 11", req.ContentLength)
+	}
+	if req.Header.Get("Content-Encoding") != "" || req.Header.Get("X-Amz-Trailer") != "" {
+		t.Fatalf("chunked framing headers must be dropped: %v", req.Header)
+	}
+}
+
+func TestAWSChunkedReader_Truncated(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodPut, "http://h/b/o", strings.NewReader("5;chunk-signature=a\r\nhel"))
+	req.Header.Set("Content-Encoding", "aws-chunked")
+	decodeAWSChunked(req)
+	if _, err := io.ReadAll(req.Body); err == nil {
+		t.Fatal("truncated chunk must be an error, not a short object")
+	}
+}
@@ -50,8 +50,13 @@ func HostPort() int { return services.StorageHostPort }
 // 0.0.0.0, so it is not exposed on the LAN or publicly. It is also not bound to
 // the node's mesh IP: that address is a userspace-tsnet virtual IP with no
 // kernel interface, so `docker -p <mesh-ip>:...` cannot bind it. Mesh
-// reachability for S3 needs a TCP-preserving relay (a path-rewriting gateway
-// route would break SigV4, which signs host+path); that is deferred past M1.
+// reachability for S3 goes through a gateway exposure instead, which re-signs
+// each proxied request with an issued key (see ExposeRewrite): a plain
+// path-rewriting route would break the client's SigV4, which signs host+path.
+//
+// The IAM cache is disabled so issued and revoked keys (users.json, rewritten
+// by CreateKey/RevokeKey) take effect on the next request rather than after
+// the cache expires.
 //
 // Credentials are passed as environment (ROOT_ACCESS_KEY/ROOT_SECRET_KEY), not
 // on the command line, so the secret never appears in the container's argv.
@@ -75,6 +80,7 @@ func dockerRunArgs(creds Credentials, hostPort int, dataDir, iamDir string) []st
 		// Global flags precede the posix subcommand; posix takes the backing dir.
 		"--port", fmt.Sprintf(":%d", containerPort),
 		"--iam-dir", containerIAMDir,
+		"--iam-cache-disable",
 		"posix", containerDataDir,
 	}
 	return args
@@ -90,6 +96,10 @@ type Status struct {
 	BucketCount int    `json:"bucket_count"`
 	BytesUsed   int64  `json:"bytes_used"`
 	AccessKey   string `json:"access_key"`
+	// KeyCount is the number of issued (non-root) access keys.
+	KeyCount int `json:"key_count"`
+	// Buckets is per-bucket usage and quota, by name.
+	Buckets []BucketStatus `json:"buckets,omitempty"`
 }
 
 // Start launches the gateway container. It is idempotent: if the container is
@@ -141,6 +151,12 @@ func Start() error {
 		return fmt.Errorf("failed to create iam dir %s: %w", iamDir, err)
 	}
 
+	// Issued keys survive in state.json; make sure the IAM store matches it
+	// before the gateway loads it.
+	if err := writeIAM(iamDir, state.Keys); err != nil {
+		return err
+	}
+
 	// A stale stopped container with the same name blocks `docker run --name`;
 	// remove it first (best-effort). The backing dirs on disk are untouched, so a
 	// relaunch reattaches the same durable state and credentials.
@@ -181,6 +197,7 @@ func GetStatus() (*Status, error) {
 		MeshIP:    meshIP(),
 		Port:      port,
 		AccessKey: state.Credentials.AccessKey,
+		KeyCount:  len(state.Keys),
 	}
 
 	dataDir, derr := DataDir()
@@ -189,6 +206,9 @@ func GetStatus() (*Status, error) {
 			st.BucketCount = count
 			st.BytesUsed = bytes
 		}
+		if buckets, berr := bucketStatuses(dataDir, state.Quotas); berr == nil {
+			st.Buckets = buckets
+		}
 	}
 
 	if running {
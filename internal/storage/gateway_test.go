@@ -58,6 +58,12 @@ func TestDockerRunArgs(t *testing.T) {
 	if imgIdx < 0 || posixIdx < 0 || imgIdx > posixIdx {
 		t.Fatalf("image must precede the posix subcommand: %s", joined)
 	}
+
+	// Issued keys must apply without waiting out the IAM cache, and the flag
+	// is global, so it precedes the subcommand.
+	if i := indexOf(args, "--iam-cache-disable"); i < imgIdx || i > posixIdx {
+		t.Fatalf("expected --iam-cache-disable between image and posix: %s", joined)
+	}
 }
 
 // TestStorageHostPort_Allocation asserts the fixed host port is a well-behaved
@@ -0,0 +1,225 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"time"
+)
+
+// Issued access keys. The root credential stays the operator's; consumers get
+// their own keys, each scoped to a set of buckets and optionally read-only, so
+// a leaked key exposes only what it was issued for and can be revoked alone.
+//
+// Enforcement is split across VersityGW's two mechanisms:
+//
+//   - Authentication: every key is an account in the gateway's internal IAM
+//     store (users.json in the IAM dir), with the unprivileged "user" role.
+//     Revoking removes the account, so the key stops authenticating at all.
+//   - Authorization: a "user" account reaches a bucket only through that
+//     bucket's policy. ApplyPolicies writes one policy per bucket granting each
+//     key in scope read or read-write actions (see policy.go).
+//
+// The key list in state.json is the source of truth; users.json and the
+// bucket policies are derived from it and rewritten wholesale on every change.
+
+// AccessKey is one issued S3 credential.
+type AccessKey struct {
+	AccessKey string `json:"access_key"`
+	SecretKey string `json:"secret_key"`
+	// Name is an operator label ("backups", "frigate-export").
+	Name string `json:"name,omitempty"`
+	// Buckets scopes the key. Empty means every bucket on the gateway,
+	// including ones created later.
+	Buckets []string `json:"buckets,omitempty"`
+	// ReadOnly limits the key to listing and reading objects.
+	ReadOnly bool      `json:"read_only,omitempty"`
+	Created  time.Time `json:"created"`
+}
+
+// AllowsBucket reports whether the key's scope covers bucket.
+func (k AccessKey) AllowsBucket(bucket string) bool {
+	if len(k.Buckets) == 0 {
+		return true
+	}
+	for _, b := range k.Buckets {
+		if b == bucket {
+			return true
+		}
+	}
+	return false
+}
+
+// bucketNamePattern is the S3 bucket naming rule (lowercase, digits, dots and
+// hyphens, 3-63 characters, alphanumeric at both ends).
+var bucketNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9.-]{1,61}[a-z0-9]$`)
+
+// ValidateBucketName reports whether name is a valid S3 bucket name.
+func ValidateBucketName(name string) error {
+	if !bucketNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid bucket name %q: use 3-63 lowercase letters, digits, '.' and '-'", name)
+	}
+	return nil
+}
+
+// KeyRequest describes a key to issue.
+type KeyRequest struct {
+	Name     string
+	Buckets  []string
+	ReadOnly bool
+}
+
+// CreateKey issues a new access key, registers it with the gateway's IAM store
+// and re-applies bucket policies. The returned key carries the secret; it is
+// also kept in state.json (0600) because the exposure proxy signs with it.
+func CreateKey(req KeyRequest) (*AccessKey, error) {
+	for _, b := range req.Buckets {
+		if err := ValidateBucketName(b); err != nil {
+			return nil, err
+		}
+	}
+	state, err := LoadOrCreateState()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load storage state: %w", err)
+	}
+	creds, err := generateCredentials()
+	if err != nil {
+		return nil, err
+	}
+	buckets := append([]string(nil), req.Buckets...)
+	sort.Strings(buckets)
+	key := AccessKey{
+		AccessKey: creds.AccessKey,
+		SecretKey: creds.SecretKey,
+		Name:      req.Name,
+		Buckets:   buckets,
+		ReadOnly:  req.ReadOnly,
+		Created:   time.Now().UTC(),
+	}
+	state.Keys = append(state.Keys, key)
+	if err := state.Save(); err != nil {
+		return nil, err
+	}
+	if err := syncIAM(state); err != nil {
+		return nil, err
+	}
+	if err := applyPoliciesIfRunning(state); err != nil {
+		return &key, fmt.Errorf("key created, but bucket policies were not updated: %w", err)
+	}
+	return &key, nil
+}
+
+// RevokeKey deletes an issued key by access key id. The IAM entry goes first,
+// so the key stops authenticating even if the policy update fails.
+func RevokeKey(accessKey string) error {
+	state, err := loadState()
+	if err != nil {
+		return err
+	}
+	kept := state.Keys[:0]
+	found := false
+	for _, k := range state.Keys {
+		if k.AccessKey == accessKey {
+			found = true
+			continue
+		}
+		kept = append(kept, k)
+	}
+	if !found {
+		return fmt.Errorf("no access key %q", accessKey)
+	}
+	state.Keys = kept
+	if err := state.Save(); err != nil {
+		return err
+	}
+	if err := syncIAM(state); err != nil {
+		return err
+	}
+	if err := applyPoliciesIfRunning(state); err != nil {
+		return fmt.Errorf("key revoked, but bucket policies still name it: %w", err)
+	}
+	return nil
+}
+
+// ListKeys returns the issued keys, oldest first.
+func ListKeys() ([]AccessKey, error) {
+	state, err := loadState()
+	if err != nil {
+		return nil, err
+	}
+	return state.Keys, nil
+}
+
+// LookupKey returns the issued key with the given access key id.
+func LookupKey(accessKey string) (*AccessKey, error) {
+	keys, err := ListKeys()
+	if err != nil {
+		return nil, err
+	}
+	for i := range keys {
+		if keys[i].AccessKey == accessKey {
+			return &keys[i], nil
+		}
+	}
+	return nil, fmt.Errorf("no access key %q (see `citadel storage keys list`)", accessKey)
+}
+
+// iamFile is VersityGW's internal IAM store inside --iam-dir.
+const iamFile = "users.json"
+
+// iamAccount mirrors VersityGW's internal IAM account record.
+type iamAccount struct {
+	Access    string `json:"access"`
+	Secret    string `json:"secret"`
+	Role      string `json:"role"`
+	UserID    int    `json:"userID"`
+	GroupID   int    `json:"groupID"`
+	ProjectID int    `json:"projectID"`
+}
+
+type iamConfig struct {
+	AccessAccounts map[string]iamAccount `json:"accessAccounts"`
+}
+
+// iamUsersJSON renders the IAM store for the issued keys. The root account is
+// not listed: VersityGW takes it from ROOT_ACCESS_KEY/ROOT_SECRET_KEY.
+func iamUsersJSON(keys []AccessKey) ([]byte, error) {
+	cfg := iamConfig{AccessAccounts: make(map[string]iamAccount, len(keys))}
+	for _, k := range keys {
+		cfg.AccessAccounts[k.AccessKey] = iamAccount{Access: k.AccessKey, Secret: k.SecretKey, Role: "user"}
+	}
+	return json.MarshalIndent(cfg, "", "  ")
+}
+
+// syncIAM rewrites users.json from state. The gateway runs with its IAM cache
+// disabled (see dockerRunArgs), so the change applies to the next request.
+func syncIAM(state *State) error {
+	dir, err := IAMDir()
+	if err != nil {
+		return err
+	}
+	return writeIAM(dir, state.Keys)
+}
+
+func writeIAM(dir string, keys []AccessKey) error {
+	data, err := iamUsersJSON(keys)
+	if err != nil {
+		return fmt.Errorf("failed to render IAM store: %w", err)
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("failed to create iam dir %s: %w", dir, err)
+	}
+	p := filepath.Join(dir, iamFile)
+	tmp := p + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write IAM store: %w", err)
+	}
+	if err := os.Rename(tmp, p); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to persist IAM store: %w", err)
+	}
+	return nil
+}
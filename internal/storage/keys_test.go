@@ -0,0 +1,76 @@
+package storage
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAccessKey_AllowsBucket(t *testing.T) {
+	all := AccessKey{}
+	scoped := AccessKey{Buckets: []string{"media", "backups"}}
+	if !all.AllowsBucket("anything") {
+		t.Fatal("unscoped key must reach every bucket")
+	}
+	if !scoped.AllowsBucket("backups") || scoped.AllowsBucket("other") {
+		t.Fatal("scoped key must reach exactly its buckets")
+	}
+}
+
+func TestValidateBucketName(t *testing.T) {
+	for _, ok := range []string{"media", "frigate-clips", "a.b.c", "abc"} {
+		if err := ValidateBucketName(ok); err != nil {
+			t.Errorf("%q rejected: %v", ok, err)
+		}
+	}
+	for _, bad := range []string{"", "ab", "Media", "-media", "media-", "a/b", "../etc"} {
+		if ValidateBucketName(bad) == nil {
+			t.Errorf("%q accepted", bad)
+		}
+	}
+}
+
+// TestWriteIAM asserts users.json carries every issued key as an unprivileged
+// account and never the root credential, and is written owner-only.
+func TestWriteIAM(t *testing.T) {
+	dir := t.TempDir()
+	keys := []AccessKey{
+		{AccessKey: "K1", SecretKey: "S1"},
+		{AccessKey: "K2", SecretKey: "S2", ReadOnly: true},
+	}
+	if err := writeIAM(dir, keys); err != nil {
+		t.Fatalf("writeIAM: %v", err)
+	}
+	path := filepath.Join(dir, iamFile)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cfg iamConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		t.Fatalf("users.json: %v", err)
+	}
+	if len(cfg.AccessAccounts) != 2 {
+		t.Fatalf("accounts = %+v", cfg.AccessAccounts)
+	}
+	for access, acct := range cfg.AccessAccounts {
+		if acct.Access != access || acct.Role != "user" {
+			t.Fatalf("account %s = %+v, want role user", access, acct)
+		}
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0600 {
+		t.Fatalf("users.json mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	// Revoking everything leaves an empty store, not a stale one.
+	if err := writeIAM(dir, nil); err != nil {
+		t.Fatal(err)
+	}
+	data, _ = os.ReadFile(path)
+	cfg = iamConfig{}
+	_ = json.Unmarshal(data, &cfg)
+	if len(cfg.AccessAccounts) != 0 {
+		t.Fatalf("revoked keys still present: %s", data)
+	}
+}
@@ -0,0 +1,263 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Bucket policies are derived, never hand-edited: for every bucket on disk the
+// policy is a pure function of the issued keys and the bucket's quota state.
+// Only Allow statements are emitted; a key with no statement on a bucket has
+// no access to it. That keeps the documents within what VersityGW evaluates
+// and makes "over quota" a downgrade rather than a deny: a read-write key on a
+// full bucket keeps read and delete (so it can free space) but loses writes.
+
+const policyVersion = "2012-10-17"
+
+// readActions are granted to read-only keys and to every key in scope.
+var readActions = []string{
+	"s3:GetBucketLocation",
+	"s3:ListBucket",
+	"s3:ListBucketMultipartUploads",
+	"s3:GetObject",
+	"s3:GetObjectTagging",
+}
+
+// writeActions are granted to read-write keys on a bucket under its quota, on
+// top of readActions and reclaimActions. The list is explicit rather than
+// "s3:*" so no issued key can touch bucket configuration: a wildcard would
+// include s3:PutBucketPolicy and let a tenant lift its own quota downgrade.
+var writeActions = []string{
+	"s3:PutObject",
+	"s3:PutObjectTagging",
+	"s3:DeleteObjectTagging",
+	"s3:ListMultipartUploadParts",
+}
+
+// reclaimActions are what a read-write key keeps on an over-quota bucket on
+// top of readActions: enough to get back under the quota.
+var reclaimActions = []string{
+	"s3:DeleteObject",
+	"s3:AbortMultipartUpload",
+}
+
+type policyDocument struct {
+	Version   string            `json:"Version"`
+	Statement []policyStatement `json:"Statement"`
+}
+
+type policyStatement struct {
+	Sid       string              `json:"Sid"`
+	Effect    string              `json:"Effect"`
+	Principal map[string][]string `json:"Principal"`
+	Action    []string            `json:"Action"`
+	Resource  []string            `json:"Resource"`
+}
+
+// bucketPolicy renders the policy for bucket, or nil when no issued key
+// reaches it (the policy is then deleted).
+func bucketPolicy(bucket string, keys []AccessKey, overQuota bool) []byte {
+	var ro, rw []string
+	for _, k := range keys {
+		if !k.AllowsBucket(bucket) {
+			continue
+		}
+		if k.ReadOnly {
+			ro = append(ro, k.AccessKey)
+		} else {
+			rw = append(rw, k.AccessKey)
+		}
+	}
+	if len(ro) == 0 && len(rw) == 0 {
+		return nil
+	}
+	resources := []string{"arn:aws:s3:::" + bucket, "arn:aws:s3:::" + bucket + "/*"}
+	doc := policyDocument{Version: policyVersion}
+	if len(ro) > 0 {
+		sort.Strings(ro)
+		doc.Statement = append(doc.Statement, policyStatement{
+			Sid: "CitadelReadOnly", Effect: "Allow",
+			Principal: map[string][]string{"AWS": ro},
+			Action:    readActions, Resource: resources,
+		})
+	}
+	if len(rw) > 0 {
+		sort.Strings(rw)
+		st := policyStatement{
+			Sid: "CitadelReadWrite", Effect: "Allow",
+			Principal: map[string][]string{"AWS": rw},
+			Action:    append(append(append([]string(nil), readActions...), reclaimActions...), writeActions...),
+			Resource:  resources,
+		}
+		if overQuota {
+			st.Sid = "CitadelOverQuota"
+			st.Action = append(append([]string(nil), readActions...), reclaimActions...)
+		}
+		doc.Statement = append(doc.Statement, st)
+	}
+	data, _ := json.Marshal(doc)
+	return data
+}
+
+// adminClient makes root-signed S3 calls to the local gateway.
+type adminClient struct {
+	endpoint string
+	creds    Credentials
+	http     *http.Client
+	now      func() time.Time
+}
+
+func newAdminClient(creds Credentials) *adminClient {
+	return &adminClient{
+		endpoint: fmt.Sprintf("http://127.0.0.1:%d", HostPort()),
+		creds:    creds,
+		http:     &http.Client{Timeout: 15 * time.Second},
+		now:      time.Now,
+	}
+}
+
+// bucketPolicy returns bucket's current policy, or nil when it has none.
+func (c *adminClient) bucketPolicy(ctx context.Context, bucket string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.endpoint+"/"+bucket+"?policy", nil)
+	if err != nil {
+		return nil, err
+	}
+	signV4(req, c.creds.AccessKey, c.creds.SecretKey, hexSHA256(nil), c.now())
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("bucket %s policy: %w", bucket, err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode == http.StatusNotFound {
+		// NoSuchBucketPolicy: no policy is set.
+		return nil, nil
+	}
+	if resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("bucket %s policy: HTTP %d: %s", bucket, resp.StatusCode, strings.TrimSpace(string(body[:min(len(body), 2048)])))
+	}
+	return body, nil
+}
+
+// samePolicy reports whether two policy documents are equal once decoded, so
+// whitespace or key order the gateway introduces does not force a rewrite.
+func samePolicy(a, b []byte) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	var da, db any
+	if json.Unmarshal(a, &da) != nil || json.Unmarshal(b, &db) != nil {
+		return false
+	}
+	return reflect.DeepEqual(da, db)
+}
+
+// setBucketPolicy puts doc as bucket's policy, or deletes the policy when doc
+// is nil.
+func (c *adminClient) setBucketPolicy(ctx context.Context, bucket string, doc []byte) error {
+	method := http.MethodPut
+	if doc == nil {
+		method = http.MethodDelete
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.endpoint+"/"+bucket+"?policy", bytes.NewReader(doc))
+	if err != nil {
+		return err
+	}
+	if doc != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	signV4(req, c.creds.AccessKey, c.creds.SecretKey, hexSHA256(doc), c.now())
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("bucket %s policy: %w", bucket, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 2048))
+		return fmt.Errorf("bucket %s policy: HTTP %d: %s", bucket, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// policyApplier writes bucket policies, skipping buckets whose policy on the
+// gateway already matches. The comparison is against the gateway, not a
+// memo of past writes, so a policy changed behind citadel's back (by the root
+// credential, or by anything else that got s3:PutBucketPolicy) is put back on
+// the next pass.
+type policyApplier struct {
+	client *adminClient
+}
+
+func newPolicyApplier(creds Credentials) *policyApplier {
+	return &policyApplier{client: newAdminClient(creds)}
+}
+
+// apply reconciles every bucket's policy with state and returns the per-bucket
+// status it computed. Per-bucket failures are joined; the rest still apply.
+func (a *policyApplier) apply(ctx context.Context, state *State) ([]BucketStatus, error) {
+	dataDir, err := DataDir()
+	if err != nil {
+		return nil, err
+	}
+	buckets, err := bucketStatuses(dataDir, state.Quotas)
+	if err != nil {
+		return nil, err
+	}
+	var errs []string
+	for _, b := range buckets {
+		// A quota may name a bucket before it is created; there is nothing to
+		// put a policy on until it exists.
+		if _, err := os.Stat(filepath.Join(dataDir, b.Name)); err != nil {
+			continue
+		}
+		doc := bucketPolicy(b.Name, state.Keys, b.OverQuota)
+		current, err := a.client.bucketPolicy(ctx, b.Name)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		if samePolicy(current, doc) {
+			continue
+		}
+		if err := a.client.setBucketPolicy(ctx, b.Name, doc); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return buckets, fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return buckets, nil
+}
+
+// ApplyPolicies rewrites every bucket's policy from the current keys and
+// quotas. Requires the gateway to be running.
+func ApplyPolicies(ctx context.Context) error {
+	state, err := loadState()
+	if err != nil {
+		return err
+	}
+	_, err = newPolicyApplier(state.Credentials).apply(ctx, state)
+	return err
+}
+
+// applyPoliciesIfRunning applies policies when the gateway is up. A stopped
+// gateway is not an error: the quota enforcer applies everything once it runs.
+func applyPoliciesIfRunning(state *State) error {
+	if !containerRunning() {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	_, err := newPolicyApplier(state.Credentials).apply(ctx, state)
+	return err
+}
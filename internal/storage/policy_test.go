@@ -0,0 +1,147 @@
+package storage
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func decodePolicy(t *testing.T, doc []byte) policyDocument {
+	t.Helper()
+	var p policyDocument
+	if err := json.Unmarshal(doc, &p); err != nil {
+		t.Fatalf("policy is not JSON: %v\n%s", err, doc)
+	}
+	return p
+}
+
+func TestBucketPolicy_ScopesKeys(t *testing.T) {
+	keys := []AccessKey{
+		{AccessKey: "ALL"},
+		{AccessKey: "RO", Buckets: []string{"media"}, ReadOnly: true},
+		{AccessKey: "OTHER", Buckets: []string{"backups"}},
+	}
+
+	p := decodePolicy(t, bucketPolicy("media", keys, false))
+	if len(p.Statement) != 2 {
+		t.Fatalf("want read-only and read-write statements, got %+v", p.Statement)
+	}
+	ro, rw := p.Statement[0], p.Statement[1]
+	if !reflect.DeepEqual(ro.Principal["AWS"], []string{"RO"}) || !reflect.DeepEqual(ro.Action, readActions) {
+		t.Fatalf("read-only statement = %+v", ro)
+	}
+	if !reflect.DeepEqual(rw.Principal["AWS"], []string{"ALL"}) || !contains(rw.Action, "s3:PutObject") {
+		t.Fatalf("read-write statement = %+v", rw)
+	}
+	for _, a := range rw.Action {
+		if a == "s3:*" || strings.HasPrefix(a, "s3:PutBucket") || strings.HasPrefix(a, "s3:DeleteBucket") {
+			t.Fatalf("read-write key must not manage the bucket itself, got %s", a)
+		}
+	}
+	want := []string{"arn:aws:s3:::media", "arn:aws:s3:::media/*"}
+	if !reflect.DeepEqual(rw.Resource, want) {
+		t.Fatalf("resources = %v, want %v", rw.Resource, want)
+	}
+
+	if doc := bucketPolicy("archive", []AccessKey{{AccessKey: "OTHER", Buckets: []string{"backups"}}}, false); doc != nil {
+		t.Fatalf("no key in scope must delete the policy, got %s", doc)
+	}
+}
+
+func TestBucketPolicy_OverQuotaDropsWrites(t *testing.T) {
+	keys := []AccessKey{{AccessKey: "RW"}, {AccessKey: "RO", ReadOnly: true}}
+	p := decodePolicy(t, bucketPolicy("media", keys, true))
+	for _, st := range p.Statement {
+		if st.Effect != "Allow" {
+			t.Fatalf("only Allow statements are emitted: %+v", st)
+		}
+		for _, a := range st.Action {
+			if a == "s3:*" || a == "s3:PutObject" {
+				t.Fatalf("over-quota bucket still grants %s to %v", a, st.Principal)
+			}
+		}
+	}
+	rw := p.Statement[1]
+	if rw.Sid != "CitadelOverQuota" || !contains(rw.Action, "s3:DeleteObject") {
+		t.Fatalf("read-write key must keep delete to reclaim space: %+v", rw)
+	}
+}
+
+func TestPolicyApplier_RestoresTamperedPolicy(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	dataDir, err := DataDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, b := range []string{"media", "backups"} {
+		if err := os.MkdirAll(filepath.Join(dataDir, b), 0700); err != nil {
+			t.Fatal(err)
+		}
+	}
+	state := &State{
+		Keys:   []AccessKey{{AccessKey: "RW"}},
+		Quotas: map[string]int64{"future": 1 << 20},
+	}
+	backups := bucketPolicy("backups", state.Keys, false)
+
+	var mu sync.Mutex
+	stored := map[string][]byte{
+		"media":   []byte(`{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Principal":{"AWS":["RW"]},"Action":["s3:*"],"Resource":["arn:aws:s3:::media/*"]}]}`),
+		"backups": backups,
+	}
+	var puts []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		bucket := strings.TrimPrefix(r.URL.Path, "/")
+		switch r.Method {
+		case http.MethodGet:
+			doc, ok := stored[bucket]
+			if !ok {
+				http.Error(w, "NoSuchBucket", http.StatusNotFound)
+				return
+			}
+			w.Write(doc)
+		case http.MethodPut:
+			body, _ := io.ReadAll(r.Body)
+			stored[bucket] = body
+			puts = append(puts, bucket)
+		}
+	}))
+	defer srv.Close()
+
+	a := &policyApplier{client: &adminClient{endpoint: srv.URL, http: srv.Client(), now: time.Now}}
+	if _, err := a.apply(t.Context(), state); err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	if !reflect.DeepEqual(puts, []string{"media"}) {
+		t.Fatalf("PUTs = %v; want only the tampered bucket (backups matches, future does not exist)", puts)
+	}
+	if !samePolicy(stored["media"], bucketPolicy("media", state.Keys, false)) {
+		t.Fatalf("media policy not restored: %s", stored["media"])
+	}
+
+	// A second pass finds nothing to do.
+	puts = nil
+	if _, err := a.apply(t.Context(), state); err != nil || len(puts) != 0 {
+		t.Fatalf("second apply = %v, PUTs %v; want no writes", err, puts)
+	}
+}
+
+func contains(list []string, v string) bool {
+	for _, s := range list {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,226 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Per-bucket size quotas. VersityGW has no quota support of its own, so the
+// enforcer measures each bucket's posix directory on an interval and, once a
+// bucket is at or over its limit, downgrades the bucket policy so issued keys
+// can no longer write to it (they keep read and delete). Enforcement is
+// therefore eventual: a bucket can overshoot by what is uploaded within one
+// interval. The root credential is not subject to bucket policies and
+// bypasses quotas; it is the operator's, not a consumer's.
+
+// DefaultQuotaInterval is how often the enforcer re-measures buckets.
+const DefaultQuotaInterval = time.Minute
+
+// BucketStatus is one bucket's usage and quota as reported by GetStatus.
+type BucketStatus struct {
+	Name      string `json:"name"`
+	BytesUsed int64  `json:"bytes_used"`
+	// QuotaBytes is the configured limit; 0 means unlimited.
+	QuotaBytes int64 `json:"quota_bytes,omitempty"`
+	OverQuota  bool  `json:"over_quota,omitempty"`
+}
+
+// SetQuota limits bucket to limit bytes and re-applies policies when the
+// gateway is running. The bucket need not exist yet; its policy is applied
+// once it does.
+func SetQuota(bucket string, limit int64) error {
+	if err := ValidateBucketName(bucket); err != nil {
+		return err
+	}
+	if limit <= 0 {
+		return fmt.Errorf("quota must be positive (use `quota clear` to remove it)")
+	}
+	state, err := LoadOrCreateState()
+	if err != nil {
+		return fmt.Errorf("failed to load storage state: %w", err)
+	}
+	if state.Quotas == nil {
+		state.Quotas = make(map[string]int64)
+	}
+	state.Quotas[bucket] = limit
+	if err := state.Save(); err != nil {
+		return err
+	}
+	return applyPoliciesIfRunning(state)
+}
+
+// ClearQuota removes bucket's quota.
+func ClearQuota(bucket string) error {
+	state, err := loadState()
+	if err != nil {
+		return err
+	}
+	if _, ok := state.Quotas[bucket]; !ok {
+		return fmt.Errorf("bucket %q has no quota", bucket)
+	}
+	delete(state.Quotas, bucket)
+	if err := state.Save(); err != nil {
+		return err
+	}
+	return applyPoliciesIfRunning(state)
+}
+
+// bucketStatuses measures every bucket under dataDir, plus any bucket that
+// has a quota but does not exist yet, sorted by name.
+func bucketStatuses(dataDir string, quotas map[string]int64) ([]BucketStatus, error) {
+	entries, err := os.ReadDir(dataDir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	var out []BucketStatus
+	for _, e := range entries {
+		if !e.IsDir() || strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		b := BucketStatus{Name: e.Name(), BytesUsed: dirSize(filepath.Join(dataDir, e.Name()))}
+		b.QuotaBytes = quotas[b.Name]
+		b.OverQuota = b.QuotaBytes > 0 && b.BytesUsed >= b.QuotaBytes
+		out = append(out, b)
+		seen[b.Name] = true
+	}
+	for name, limit := range quotas {
+		if !seen[name] {
+			out = append(out, BucketStatus{Name: name, QuotaBytes: limit})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out, nil
+}
+
+// dirSize sums regular-file sizes under dir, best-effort. In-progress
+// multipart parts live inside the bucket dir and count: they occupy the disk
+// the quota protects.
+func dirSize(dir string) int64 {
+	var total int64
+	_ = filepath.WalkDir(dir, func(_ string, d fs.DirEntry, walkErr error) error {
+		if walkErr != nil || d.IsDir() {
+			return nil
+		}
+		if info, err := d.Info(); err == nil {
+			total += info.Size()
+		}
+		return nil
+	})
+	return total
+}
+
+// RunQuotaEnforcer keeps bucket policies in step with keys and quotas until
+// ctx is cancelled. It also covers buckets created after a key was issued: a
+// new bucket gets its policy on the next tick. Ticks while the gateway is
+// stopped are skipped. logf reports failures and quota transitions.
+func RunQuotaEnforcer(ctx context.Context, interval time.Duration, logf func(format string, args ...any)) {
+	if interval <= 0 {
+		interval = DefaultQuotaInterval
+	}
+	var applier *policyApplier
+	over := make(map[string]bool)
+	var lastErr string
+	tick := func() {
+		state, err := loadState()
+		if err != nil {
+			logf("storage quota: %v", err)
+			return
+		}
+		// Nothing issued and nothing applied: no policies to keep, and no
+		// reason to probe docker on nodes that do not use storage.
+		if len(state.Keys) == 0 && len(state.Quotas) == 0 && applier == nil {
+			return
+		}
+		if !containerRunning() {
+			// Start over once the gateway is back.
+			applier = nil
+			return
+		}
+		if applier == nil {
+			applier = newPolicyApplier(state.Credentials)
+		}
+		ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+		defer cancel()
+		buckets, err := applier.apply(ctx, state)
+		// Log a persistent failure once, not every interval.
+		msg := ""
+		if err != nil {
+			msg = err.Error()
+			if msg != lastErr {
+				logf("storage quota: %v", err)
+			}
+		}
+		lastErr = msg
+		for _, b := range buckets {
+			if b.OverQuota != over[b.Name] {
+				if b.OverQuota {
+					logf("storage quota: bucket %s is at %s of its %s quota; writes by issued keys are blocked",
+						b.Name, FormatSize(b.BytesUsed), FormatSize(b.QuotaBytes))
+				} else {
+					logf("storage quota: bucket %s is back under its quota; writes re-enabled", b.Name)
+				}
+				over[b.Name] = b.OverQuota
+			}
+		}
+	}
+
+	tick()
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			tick()
+		}
+	}
+}
+
+// ParseSize parses a byte size such as "500M", "10GiB" or "1073741824".
+// Suffixes are binary (K = 1024), with or without a trailing "iB"/"B".
+func ParseSize(s string) (int64, error) {
+	raw := strings.TrimSpace(s)
+	t := strings.ToUpper(raw)
+	t = strings.TrimSuffix(t, "B")
+	t = strings.TrimSuffix(t, "I")
+	mult := int64(1)
+	if n := len(t); n > 0 {
+		if i := strings.IndexByte("KMGTP", t[n-1]); i >= 0 {
+			mult = int64(1) << (10 * (i + 1))
+			t = t[:n-1]
+		}
+	}
+	v, err := strconv.ParseFloat(strings.TrimSpace(t), 64)
+	if err != nil || v < 0 || math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0, fmt.Errorf("invalid size %q (e.g. 500M, 10G)", raw)
+	}
+	// float64(math.MaxInt64) rounds up to 2^63, so >= catches every overflow.
+	if n := v * float64(mult); n >= float64(math.MaxInt64) {
+		return 0, fmt.Errorf("size %q is too large", raw)
+	}
+	return int64(v * float64(mult)), nil
+}
+
+// FormatSize renders a byte count compactly (e.g. "1.5 GiB").
+func FormatSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for x := n / unit; x >= unit; x /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,75 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestBucketStatuses(t *testing.T) {
+	dir := t.TempDir()
+	write := func(rel string, n int) {
+		p := filepath.Join(dir, rel)
+		if err := os.MkdirAll(filepath.Dir(p), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, make([]byte, n), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("media/a.mp4", 600)
+	write("media/nested/b.mp4", 500)
+	write("backups/db.tar", 100)
+	write(".sgwtmp/upload", 9999) // gateway scratch, not a bucket
+
+	got, err := bucketStatuses(dir, map[string]int64{"media": 1000, "backups": 1000, "future": 50})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []BucketStatus{
+		{Name: "backups", BytesUsed: 100, QuotaBytes: 1000},
+		{Name: "future", QuotaBytes: 50},
+		{Name: "media", BytesUsed: 1100, QuotaBytes: 1000, OverQuota: true},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("bucketStatuses =\n %+v\nwant\n %+v", got, want)
+	}
+
+	if got, err := bucketStatuses(filepath.Join(dir, "missing"), nil); err != nil || len(got) != 0 {
+		t.Fatalf("missing data dir = %v, %v; want empty", got, err)
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	cases := map[string]int64{
+		"1024":   1024,
+		"500M":   500 << 20,
+		"10GiB":  10 << 30,
+		"10gb":   10 << 30,
+		"1.5K":   1536,
+		" 2T ":   2 << 40,
+		"100B":   100,
+		"512KiB": 512 << 10,
+	}
+	for in, want := range cases {
+		got, err := ParseSize(in)
+		if err != nil || got != want {
+			t.Errorf("ParseSize(%q) = %d, %v; want %d", in, got, err, want)
+		}
+	}
+	for _, bad := range []string{"", "G", "ten", "-5M", "NaN", "Inf", "+InfG", "9000000P", "1e300"} {
+		if _, err := ParseSize(bad); err == nil {
+			t.Errorf("ParseSize(%q) accepted", bad)
+		}
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	cases := map[int64]string{0: "0 B", 1023: "1023 B", 1536: "1.5 KiB", 10 << 30: "10.0 GiB"}
+	for in, want := range cases {
+		if got := FormatSize(in); got != want {
+			t.Errorf("FormatSize(%d) = %q, want %q", in, got, want)
+		}
+	}
+}
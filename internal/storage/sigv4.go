@@ -0,0 +1,158 @@
+package storage
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Minimal AWS Signature Version 4 for the two places citadel talks S3 to its
+// own gateway: the bucket-policy admin calls (signed as root) and the gateway
+// exposure, which re-signs proxied requests with an issued key. Only header
+// signing is implemented; presigned query auth is not needed for either.
+// Signing aws-chunked streaming bodies is not implemented either: the exposure
+// decodes them to a plain body first and signs it as UNSIGNED-PAYLOAD
+// (decodeAWSChunked in expose.go).
+
+// signingRegion is VersityGW's default --region. The gateway checks the
+// credential scope's region, so it must match the launch flags.
+const signingRegion = "us-east-1"
+
+// unsignedPayload marks the request body as not covered by the signature. It
+// lets the exposure proxy stream uploads without buffering them to hash.
+const unsignedPayload = "UNSIGNED-PAYLOAD"
+
+const sigV4Algorithm = "AWS4-HMAC-SHA256"
+
+// signV4 signs req in place for the S3 service. payloadHash is the hex SHA-256
+// of the body, or unsignedPayload. req.URL.Host must already hold the target
+// host: it is copied to req.Host so the Host header sent is the one signed.
+// Every x-amz-* header on the request is signed along with host, as S3
+// requires; other headers are left unsigned.
+func signV4(req *http.Request, accessKey, secretKey, payloadHash string, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	day := now.Format("20060102")
+
+	req.Host = req.URL.Host
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	canonicalURI := s3EscapePath(req.URL.Path)
+	// Send exactly the path that was signed.
+	req.URL.RawPath = canonicalURI
+
+	names := []string{"host"}
+	values := map[string]string{"host": req.Host}
+	for k, v := range req.Header {
+		lk := strings.ToLower(k)
+		if !strings.HasPrefix(lk, "x-amz-") {
+			continue
+		}
+		trimmed := make([]string, len(v))
+		for i := range v {
+			trimmed[i] = strings.Join(strings.Fields(v[i]), " ")
+		}
+		names = append(names, lk)
+		values[lk] = strings.Join(trimmed, ",")
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, n := range names {
+		canonicalHeaders.WriteString(n + ":" + values[n] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		canonicalURI,
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := day + "/" + signingRegion + "/s3/aws4_request"
+	stringToSign := strings.Join([]string{
+		sigV4Algorithm,
+		amzDate,
+		scope,
+		hexSHA256([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+secretKey), day)
+	key = hmacSHA256(key, signingRegion)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		sigV4Algorithm, accessKey, scope, signedHeaders, signature))
+}
+
+// canonicalQuery renders the query string in SigV4 canonical form: keys and
+// values URI-encoded, sorted by key then value, valueless keys as "k=".
+func canonicalQuery(q map[string][]string) string {
+	var parts []string
+	for _, k := range sortedKeys(q) {
+		vals := append([]string(nil), q[k]...)
+		sort.Strings(vals)
+		for _, v := range vals {
+			parts = append(parts, s3Escape(k, true)+"="+s3Escape(v, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+func sortedKeys(q map[string][]string) []string {
+	keys := make([]string, 0, len(q))
+	for k := range q {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// s3EscapePath URI-encodes an object path, keeping the '/' separators. S3
+// encodes the path once (no double encoding, unlike other AWS services).
+func s3EscapePath(p string) string {
+	if p == "" {
+		return "/"
+	}
+	return s3Escape(p, false)
+}
+
+// s3Escape percent-encodes every byte outside the RFC 3986 unreserved set;
+// '/' is kept unless encodeSlash.
+func s3Escape(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func hexSHA256(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -41,11 +41,16 @@ type Credentials struct {
 	SecretKey string `json:"secret_key"`
 }
 
-// State is the persisted storage-service state. It currently holds only the
-// create-once credentials; the port is a fixed registry slot and the backing
-// dirs are derived from the base dir, so neither is persisted here.
+// State is the persisted storage-service state: the create-once root
+// credentials, the issued access keys and the per-bucket quotas. The port is a
+// fixed registry slot and the backing dirs are derived from the base dir, so
+// neither is persisted here.
 type State struct {
 	Credentials Credentials `json:"credentials"`
+	// Keys are the issued, bucket-scoped access keys (see keys.go).
+	Keys []AccessKey `json:"keys,omitempty"`
+	// Quotas maps bucket name to its size limit in bytes (see quota.go).
+	Quotas map[string]int64 `json:"quotas,omitempty"`
 
 	mu   sync.Mutex
 	path string
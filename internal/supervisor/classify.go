@@ -0,0 +1,95 @@
+package supervisor
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/aceteam-ai/citadel-cli/internal/compose"
+)
+
+// exitCodeRe extracts the exit code from a `docker compose ps` Status such as
+// "Exited (1) 2 minutes ago".
+var exitCodeRe = regexp.MustCompile(`(?i)exited \((-?\d+)\)`)
+
+// ClassifyContainer judges a service from its `docker compose ps -a` record.
+// Pure -- table-tested. It is deliberately conservative, because a wrong
+// "unhealthy" restarts a service the operator meant to leave alone:
+//
+//   - no container -> unknown (never started, or removed by `compose down`).
+//   - restarting, or exited with a non-zero code -> unhealthy (it crashed).
+//   - exited 0, or 128+n for a stop signal (SIGINT, SIGKILL, SIGTERM: 130, 137,
+//     143) -> unknown. `docker stop` and `compose stop` end a container with
+//     SIGTERM then SIGKILL, and ps does not say whether anyone asked, so these
+//     read as an intentional stop. The exception is a SIGKILL from the kernel's
+//     OOM killer (137 with OOMKilled set), which is a crash. Other signal
+//     deaths (SIGSEGV's 139) are crashes too.
+//   - running with a failing compose healthcheck ("(unhealthy)") -> unhealthy.
+//   - running otherwise, including "(health: starting)" -> healthy.
+func ClassifyContainer(c *compose.PSContainer) Check {
+	if c == nil {
+		return Check{Health: HealthUnknown}
+	}
+	state := strings.ToLower(strings.TrimSpace(c.State))
+	status := strings.ToLower(c.Status)
+
+	switch {
+	case state == "restarting" || strings.HasPrefix(status, "restarting"):
+		return Check{Health: HealthUnhealthy, Reason: "container is restart-looping: " + c.Status}
+	case state == "exited" || state == "dead" || strings.HasPrefix(status, "exited"):
+		m := exitCodeRe.FindStringSubmatch(c.Status)
+		if m == nil {
+			if state == "dead" {
+				return Check{Health: HealthUnhealthy, Reason: "container is dead"}
+			}
+			return Check{Health: HealthUnknown}
+		}
+		code, _ := strconv.Atoi(m[1])
+		if code == 128+9 && c.OOMKilled {
+			return Check{Health: HealthUnhealthy, Reason: "container was killed for running out of memory (exit code 137)"}
+		}
+		if code == 0 || stopSignalExit(code) {
+			return Check{Health: HealthUnknown}
+		}
+		return Check{Health: HealthUnhealthy, Reason: fmt.Sprintf("container exited with code %d", code)}
+	case c.Running():
+		if strings.Contains(status, "(unhealthy)") {
+			return Check{Health: HealthUnhealthy, Reason: "container healthcheck failing"}
+		}
+		return Check{Health: HealthHealthy}
+	default:
+		return Check{Health: HealthUnknown}
+	}
+}
+
+// stopSignalExit reports whether code is a shell-style 128+n exit for a signal
+// used to stop a container: SIGINT (2), SIGKILL (9) or SIGTERM (15).
+func stopSignalExit(code int) bool {
+	switch code - 128 {
+	case 2, 9, 15:
+		return true
+	}
+	return false
+}
+
+// ClassifyService judges a service from all of its declared containers, so a
+// crashed sidecar (a bridge's database) is not hidden by a healthy main
+// container. Any unhealthy container makes the service unhealthy; otherwise
+// it is healthy if any container is, and unknown when none is running.
+func ClassifyService(containers []compose.PSContainer) Check {
+	result := Check{Health: HealthUnknown}
+	for i := range containers {
+		check := ClassifyContainer(&containers[i])
+		switch check.Health {
+		case HealthUnhealthy:
+			if len(containers) > 1 {
+				check.Reason = containers[i].Service + ": " + check.Reason
+			}
+			return check
+		case HealthHealthy:
+			result = check
+		}
+	}
+	return result
+}
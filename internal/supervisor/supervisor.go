@@ -0,0 +1,378 @@
+// Package supervisor keeps a node's managed services healthy: `citadel work`
+// runs it in the background to probe every service and module on an interval,
+// restart the ones that stay unhealthy with exponential backoff, and give up on
+// (mark crash-looping) a service that keeps failing after restarts, so a broken
+// image is reported instead of being restarted forever.
+//
+// The package knows nothing about compose or the manifest: the caller supplies
+// the targets, each with its own probe and restart, so the decision logic is
+// unit-testable without Docker.
+package supervisor
+
+import (
+	"context"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Defaults for Config fields left zero.
+const (
+	DefaultInterval         = 30 * time.Second
+	DefaultFailureThreshold = 3
+	DefaultBackoffBase      = 10 * time.Second
+	DefaultBackoffMax       = 10 * time.Minute
+	DefaultMaxRestarts      = 5
+	DefaultStablePeriod     = 5 * time.Minute
+)
+
+// IntervalEnvVar overrides the probe interval in seconds. A value <= 0
+// disables supervision entirely.
+const IntervalEnvVar = "CITADEL_SUPERVISE_INTERVAL"
+
+// IntervalFromEnv resolves the probe interval, honoring IntervalEnvVar. An
+// unset or invalid value falls back to DefaultInterval; a value <= 0 disables
+// the supervisor (enabled=false).
+func IntervalFromEnv() (interval time.Duration, enabled bool) {
+	raw := strings.TrimSpace(os.Getenv(IntervalEnvVar))
+	if raw == "" {
+		return DefaultInterval, true
+	}
+	secs, err := strconv.Atoi(raw)
+	if err != nil {
+		return DefaultInterval, true
+	}
+	if secs <= 0 {
+		return 0, false
+	}
+	return time.Duration(secs) * time.Second, true
+}
+
+// Health is the outcome of probing one target.
+type Health int
+
+const (
+	// HealthUnknown means the probe could not tell: the service is not
+	// running by intent (no container, a clean exit) or has nothing to probe.
+	// The supervisor never restarts on an unknown result.
+	HealthUnknown Health = iota
+	// HealthHealthy means the service is up and answering.
+	HealthHealthy
+	// HealthUnhealthy means the service is definitely broken: its container
+	// crashed or is restarting, its compose healthcheck fails, or its health
+	// endpoint answers with a server error.
+	HealthUnhealthy
+)
+
+func (h Health) String() string {
+	switch h {
+	case HealthHealthy:
+		return "healthy"
+	case HealthUnhealthy:
+		return "unhealthy"
+	default:
+		return "unknown"
+	}
+}
+
+// Service states reported to Config.OnStateChange.
+const (
+	StateHealthy      = "healthy"
+	StateUnhealthy    = "unhealthy"
+	StateRestarting   = "restarting"
+	StateCrashLooping = "crash_looping"
+)
+
+// Check is the result of one probe. Reason explains an unhealthy result and
+// is surfaced as the service's last failure.
+type Check struct {
+	Health Health
+	Reason string
+}
+
+// Target is one supervised service.
+type Target struct {
+	// Name is the service name, the key records are reported under.
+	Name string
+	// FailureThreshold overrides Config.FailureThreshold for this target (the
+	// retries of its declared health check). Zero uses the config default.
+	FailureThreshold int
+	// Probe checks the service once. Required.
+	Probe func(ctx context.Context) Check
+	// Restart restarts the service. Required.
+	Restart func(ctx context.Context) error
+}
+
+// Record is the supervision state reported for one target.
+type Record struct {
+	Name string
+	// RestartCount is how many times the supervisor has restarted the service
+	// since `citadel work` started.
+	RestartCount int
+	// LastFailure is the reason of the most recent unhealthy probe or failed
+	// restart, and LastFailureAt when it happened. Empty until a failure.
+	LastFailure   string
+	LastFailureAt time.Time
+	// CrashLooping is set once the service stayed unhealthy after
+	// Config.MaxRestarts consecutive restarts; the supervisor no longer
+	// restarts it until it recovers on its own or is restarted by hand.
+	CrashLooping bool
+}
+
+// Config configures a Supervisor.
+type Config struct {
+	// Targets lists the services to supervise. It is called on every pass, so
+	// a service stopped or uninstalled by the operator drops out (and its
+	// record with it) without restarting the supervisor. Required.
+	Targets func() []Target
+	// Interval is the time between probe passes.
+	Interval time.Duration
+	// FailureThreshold is how many consecutive unhealthy probes trigger a
+	// restart, so a single slow answer does not bounce a service.
+	FailureThreshold int
+	// BackoffBase is the wait after the first restart before the next one is
+	// allowed; it doubles with every consecutive restart up to BackoffMax.
+	BackoffBase time.Duration
+	BackoffMax  time.Duration
+	// MaxRestarts is how many consecutive restarts are attempted before the
+	// service is marked crash-looping.
+	MaxRestarts int
+	// StablePeriod is how long a service must stay healthy after a restart
+	// before its consecutive restart count (and backoff) resets.
+	StablePeriod time.Duration
+	// OnGiveUp is called once when a service is marked crash-looping.
+	// Optional.
+	OnGiveUp func(rec Record)
+	// OnStateChange is called when a service moves between the State*
+	// values, with the reason of the probe or restart behind it. A service
+	// first seen healthy reports nothing. Optional.
+	OnStateChange func(rec Record, state string)
+	// Logf is an optional log sink (e.g. cmd.Log). May be nil.
+	Logf func(format string, args ...any)
+}
+
+// Supervisor probes and restarts targets. Create it with New and start it
+// with Run; Records is safe to call concurrently (the heartbeat reads it).
+type Supervisor struct {
+	cfg Config
+	now func() time.Time
+
+	mu     sync.Mutex
+	states map[string]*targetState
+}
+
+// targetState is the bookkeeping behind a Record.
+type targetState struct {
+	rec Record
+	// failures counts consecutive unhealthy probes since the last restart.
+	failures int
+	// streak counts consecutive restarts not followed by a stable period.
+	streak int
+	// nextRestart is the earliest time the backoff allows another restart.
+	nextRestart time.Time
+	// healthySince is when the current healthy run began (zero when not
+	// healthy).
+	healthySince time.Time
+	// state is the last State* value reported (empty before the first).
+	state string
+}
+
+// New builds a Supervisor, filling zero Config fields with the defaults.
+func New(cfg Config) *Supervisor {
+	if cfg.Interval <= 0 {
+		cfg.Interval = DefaultInterval
+	}
+	if cfg.FailureThreshold <= 0 {
+		cfg.FailureThreshold = DefaultFailureThreshold
+	}
+	if cfg.BackoffBase <= 0 {
+		cfg.BackoffBase = DefaultBackoffBase
+	}
+	if cfg.BackoffMax <= 0 {
+		cfg.BackoffMax = DefaultBackoffMax
+	}
+	if cfg.MaxRestarts <= 0 {
+		cfg.MaxRestarts = DefaultMaxRestarts
+	}
+	if cfg.StablePeriod <= 0 {
+		cfg.StablePeriod = DefaultStablePeriod
+	}
+	return &Supervisor{cfg: cfg, now: time.Now, states: make(map[string]*targetState)}
+}
+
+// Interval returns the effective probe interval.
+func (s *Supervisor) Interval() time.Duration {
+	return s.cfg.Interval
+}
+
+// Run probes all targets every Interval until ctx is cancelled. The first
+// pass waits one interval, so services `citadel work` is still bringing up
+// are not judged while they start.
+func (s *Supervisor) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.checkAll(ctx)
+		}
+	}
+}
+
+// Records returns the supervision state of every current target, sorted by
+// name.
+func (s *Supervisor) Records() []Record {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]Record, 0, len(s.states))
+	for _, st := range s.states {
+		out = append(out, st.rec)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// checkAll runs one probe pass over the current targets. Probes and restarts
+// run sequentially: a pass restarting several services at once would only
+// contend for the same disk and GPU.
+func (s *Supervisor) checkAll(ctx context.Context) {
+	targets := s.cfg.Targets()
+
+	s.mu.Lock()
+	live := make(map[string]bool, len(targets))
+	for _, t := range targets {
+		live[t.Name] = true
+	}
+	for name := range s.states {
+		if !live[name] {
+			delete(s.states, name)
+		}
+	}
+	s.mu.Unlock()
+
+	for _, t := range targets {
+		if ctx.Err() != nil {
+			return
+		}
+		s.check(ctx, t)
+	}
+}
+
+// check probes one target and restarts it when the failure threshold and
+// backoff allow.
+func (s *Supervisor) check(ctx context.Context, t Target) {
+	result := t.Probe(ctx)
+
+	s.mu.Lock()
+	st := s.states[t.Name]
+	if st == nil {
+		st = &targetState{rec: Record{Name: t.Name}}
+		s.states[t.Name] = st
+	}
+	now := s.now()
+
+	switch result.Health {
+	case HealthHealthy:
+		st.failures = 0
+		if st.healthySince.IsZero() {
+			st.healthySince = now
+		}
+		if (st.streak > 0 || st.rec.CrashLooping) && now.Sub(st.healthySince) >= s.cfg.StablePeriod {
+			st.streak = 0
+			st.nextRestart = time.Time{}
+			st.rec.CrashLooping = false
+		}
+		notify := s.setState(st, StateHealthy)
+		s.mu.Unlock()
+		notify()
+		return
+	case HealthUnknown:
+		st.failures = 0
+		st.healthySince = time.Time{}
+		s.mu.Unlock()
+		return
+	}
+
+	st.healthySince = time.Time{}
+	st.failures++
+	st.rec.LastFailure = result.Reason
+	st.rec.LastFailureAt = now
+	threshold := s.cfg.FailureThreshold
+	if t.FailureThreshold > 0 {
+		threshold = t.FailureThreshold
+	}
+	if st.rec.CrashLooping || st.failures < threshold || now.Before(st.nextRestart) {
+		notify := func() {}
+		if !st.rec.CrashLooping && st.state != StateRestarting {
+			notify = s.setState(st, StateUnhealthy)
+		}
+		s.mu.Unlock()
+		notify()
+		return
+	}
+	if st.streak >= s.cfg.MaxRestarts {
+		st.rec.CrashLooping = true
+		rec := st.rec
+		notify := s.setState(st, StateCrashLooping)
+		s.mu.Unlock()
+		s.logf("supervisor: %s is crash-looping after %d restarts (last failure: %s); giving up", t.Name, s.cfg.MaxRestarts, rec.LastFailure)
+		if s.cfg.OnGiveUp != nil {
+			s.cfg.OnGiveUp(rec)
+		}
+		notify()
+		return
+	}
+	st.streak++
+	st.failures = 0
+	st.rec.RestartCount++
+	st.nextRestart = now.Add(s.backoff(st.streak))
+	attempt := st.streak
+	notify := s.setState(st, StateRestarting)
+	s.mu.Unlock()
+	notify()
+
+	s.logf("supervisor: restarting %s (attempt %d/%d): %s", t.Name, attempt, s.cfg.MaxRestarts, result.Reason)
+	if err := t.Restart(ctx); err != nil {
+		s.logf("supervisor: restart %s failed: %v", t.Name, err)
+		s.mu.Lock()
+		st.rec.LastFailure = "restart failed: " + err.Error()
+		st.rec.LastFailureAt = s.now()
+		s.mu.Unlock()
+	}
+}
+
+// setState records st's new state and returns the OnStateChange call to make
+// once s.mu is released (a no-op when nothing changed). Caller holds s.mu.
+func (s *Supervisor) setState(st *targetState, state string) func() {
+	prev := st.state
+	st.state = state
+	if prev == state || (prev == "" && state == StateHealthy) || s.cfg.OnStateChange == nil {
+		return func() {}
+	}
+	rec := st.rec
+	return func() { s.cfg.OnStateChange(rec, state) }
+}
+
+// backoff is the wait after the n-th consecutive restart: BackoffBase doubled
+// n-1 times, capped at BackoffMax.
+func (s *Supervisor) backoff(n int) time.Duration {
+	d := s.cfg.BackoffBase
+	for i := 1; i < n; i++ {
+		d *= 2
+		if d >= s.cfg.BackoffMax {
+			return s.cfg.BackoffMax
+		}
+	}
+	return min(d, s.cfg.BackoffMax)
+}
+
+func (s *Supervisor) logf(format string, args ...any) {
+	if s.cfg.Logf != nil {
+		s.cfg.Logf(format, args...)
+	}
+}
@@ -0,0 +1,231 @@
+package supervisor
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aceteam-ai/citadel-cli/internal/compose"
+)
+
+// fakeTarget is a supervised service whose health the test scripts.
+type fakeTarget struct {
+	health     Health
+	restarts   int
+	restartErr error
+}
+
+func (f *fakeTarget) target(name string) Target {
+	return Target{
+		Name:    name,
+		Probe:   func(context.Context) Check { return Check{Health: f.health, Reason: "HTTP 500"} },
+		Restart: func(context.Context) error { f.restarts++; return f.restartErr },
+	}
+}
+
+// newTestSupervisor returns a supervisor over one target and a clock the test
+// advances by hand.
+func newTestSupervisor(cfg Config, targets ...Target) (*Supervisor, *time.Time) {
+	cfg.Targets = func() []Target { return targets }
+	s := New(cfg)
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+	return s, &now
+}
+
+func TestSupervisorRestartsAfterThresholdWithBackoff(t *testing.T) {
+	svc := &fakeTarget{health: HealthUnhealthy}
+	s, now := newTestSupervisor(Config{FailureThreshold: 2, BackoffBase: time.Minute}, svc.target("vllm"))
+	ctx := context.Background()
+
+	s.checkAll(ctx)
+	if svc.restarts != 0 {
+		t.Fatal("restarted below the failure threshold")
+	}
+	s.checkAll(ctx)
+	if svc.restarts != 1 {
+		t.Fatalf("restarts = %d, want 1 at the threshold", svc.restarts)
+	}
+
+	// Still failing, but inside the 1m backoff.
+	*now = now.Add(30 * time.Second)
+	s.checkAll(ctx)
+	s.checkAll(ctx)
+	if svc.restarts != 1 {
+		t.Fatalf("restarts = %d, want 1 inside the backoff", svc.restarts)
+	}
+
+	// The second backoff doubles to 2m.
+	*now = now.Add(31 * time.Second)
+	s.checkAll(ctx)
+	if svc.restarts != 2 {
+		t.Fatalf("restarts = %d, want 2 after the backoff", svc.restarts)
+	}
+	*now = now.Add(90 * time.Second)
+	s.checkAll(ctx)
+	s.checkAll(ctx)
+	if svc.restarts != 2 {
+		t.Fatalf("restarts = %d, want the second backoff to be 2m", svc.restarts)
+	}
+
+	recs := s.Records()
+	if len(recs) != 1 || recs[0].RestartCount != 2 || recs[0].LastFailure != "HTTP 500" || recs[0].CrashLooping {
+		t.Errorf("records = %+v", recs)
+	}
+}
+
+func TestSupervisorGivesUpOnCrashLoop(t *testing.T) {
+	svc := &fakeTarget{health: HealthUnhealthy}
+	var gaveUp []Record
+	s, now := newTestSupervisor(Config{
+		FailureThreshold: 1,
+		BackoffBase:      time.Second,
+		MaxRestarts:      2,
+		StablePeriod:     time.Minute,
+		OnGiveUp:         func(r Record) { gaveUp = append(gaveUp, r) },
+	}, svc.target("kokoro"))
+	ctx := context.Background()
+
+	for range 6 {
+		s.checkAll(ctx)
+		*now = now.Add(10 * time.Second)
+	}
+	if svc.restarts != 2 {
+		t.Errorf("restarts = %d, want MaxRestarts", svc.restarts)
+	}
+	if len(gaveUp) != 1 || gaveUp[0].Name != "kokoro" || !gaveUp[0].CrashLooping {
+		t.Fatalf("OnGiveUp calls = %+v, want exactly one for kokoro", gaveUp)
+	}
+
+	// Recovering (e.g. a manual fix) clears the crash loop only once stable.
+	svc.health = HealthHealthy
+	s.checkAll(ctx)
+	if !s.Records()[0].CrashLooping {
+		t.Error("crash loop cleared before the stable period")
+	}
+	*now = now.Add(time.Minute)
+	s.checkAll(ctx)
+	if rec := s.Records()[0]; rec.CrashLooping || rec.RestartCount != 2 {
+		t.Errorf("after a stable period record = %+v, want not crash-looping with the count kept", rec)
+	}
+}
+
+func TestSupervisorReportsStateChanges(t *testing.T) {
+	svc := &fakeTarget{health: HealthHealthy}
+	var states []string
+	s, now := newTestSupervisor(Config{
+		FailureThreshold: 2,
+		BackoffBase:      time.Second,
+		MaxRestarts:      1,
+		OnStateChange:    func(_ Record, state string) { states = append(states, state) },
+	}, svc.target("whisper"))
+	ctx := context.Background()
+
+	steps := []Health{HealthHealthy, HealthUnhealthy, HealthUnhealthy, HealthUnhealthy, HealthHealthy, HealthUnhealthy, HealthUnhealthy, HealthUnhealthy}
+	for _, h := range steps {
+		svc.health = h
+		s.checkAll(ctx)
+		*now = now.Add(10 * time.Second)
+	}
+	// First seen healthy: silent. Then unhealthy, restarted once, still failing
+	// after the restart (no second "unhealthy"), recovered, failing again, and
+	// crash-looping since MaxRestarts is spent.
+	want := []string{StateUnhealthy, StateRestarting, StateHealthy, StateUnhealthy, StateCrashLooping}
+	if len(states) != len(want) {
+		t.Fatalf("states = %v, want %v", states, want)
+	}
+	for i := range want {
+		if states[i] != want[i] {
+			t.Fatalf("states = %v, want %v", states, want)
+		}
+	}
+}
+
+func TestSupervisorLeavesUnknownAndDroppedTargetsAlone(t *testing.T) {
+	svc := &fakeTarget{health: HealthUnknown}
+	targets := []Target{svc.target("ollama")}
+	s := New(Config{FailureThreshold: 1, Targets: func() []Target { return targets }})
+	ctx := context.Background()
+
+	s.checkAll(ctx)
+	s.checkAll(ctx)
+	if svc.restarts != 0 {
+		t.Errorf("restarted a service with unknown health")
+	}
+
+	svc.health = HealthUnhealthy
+	svc.restartErr = errors.New("compose up failed")
+	s.checkAll(ctx)
+	if rec := s.Records()[0]; rec.LastFailure != "restart failed: compose up failed" {
+		t.Errorf("last failure = %q, want the restart error", rec.LastFailure)
+	}
+
+	// The operator stopped it: it leaves the target list and its record goes.
+	targets = nil
+	s.checkAll(ctx)
+	if recs := s.Records(); len(recs) != 0 {
+		t.Errorf("records = %+v, want none for a dropped target", recs)
+	}
+}
+
+func TestClassifyContainer(t *testing.T) {
+	tests := []struct {
+		name string
+		c    *compose.PSContainer
+		want Health
+	}{
+		{"no container", nil, HealthUnknown},
+		{"running", &compose.PSContainer{State: "running", Status: "Up 5 minutes"}, HealthHealthy},
+		{"healthy", &compose.PSContainer{State: "running", Status: "Up 5 minutes (healthy)"}, HealthHealthy},
+		{"starting", &compose.PSContainer{State: "running", Status: "Up 5 seconds (health: starting)"}, HealthHealthy},
+		{"unhealthy", &compose.PSContainer{State: "running", Status: "Up 5 minutes (unhealthy)"}, HealthUnhealthy},
+		{"restarting", &compose.PSContainer{State: "restarting", Status: "Restarting (1) 3 seconds ago"}, HealthUnhealthy},
+		{"crashed", &compose.PSContainer{State: "exited", Status: "Exited (1) 2 minutes ago"}, HealthUnhealthy},
+		{"segfault", &compose.PSContainer{State: "exited", Status: "Exited (139) 2 minutes ago"}, HealthUnhealthy},
+		{"stopped (SIGTERM)", &compose.PSContainer{State: "exited", Status: "Exited (143) 2 minutes ago"}, HealthUnknown},
+		{"stopped (SIGKILL)", &compose.PSContainer{State: "exited", Status: "Exited (137) 2 minutes ago"}, HealthUnknown},
+		{"OOM-killed", &compose.PSContainer{State: "exited", Status: "Exited (137) 2 minutes ago", OOMKilled: true}, HealthUnhealthy},
+		{"clean exit", &compose.PSContainer{State: "exited", Status: "Exited (0) 2 minutes ago"}, HealthUnknown},
+		{"created", &compose.PSContainer{State: "created", Status: "Created"}, HealthUnknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ClassifyContainer(tt.c)
+			if got.Health != tt.want {
+				t.Errorf("ClassifyContainer = %v, want %v", got.Health, tt.want)
+			}
+			if got.Health == HealthUnhealthy && got.Reason == "" {
+				t.Error("unhealthy result has no reason")
+			}
+		})
+	}
+}
+
+func TestIntervalFromEnv(t *testing.T) {
+	t.Setenv(IntervalEnvVar, "")
+	if d, ok := IntervalFromEnv(); !ok || d != DefaultInterval {
+		t.Errorf("unset = %v, %v", d, ok)
+	}
+	t.Setenv(IntervalEnvVar, "15")
+	if d, ok := IntervalFromEnv(); !ok || d != 15*time.Second {
+		t.Errorf("15 = %v, %v", d, ok)
+	}
+	t.Setenv(IntervalEnvVar, "0")
+	if _, ok := IntervalFromEnv(); ok {
+		t.Error("0 should disable supervision")
+	}
+}
+
+func TestClassifyServiceReportsCrashedSidecar(t *testing.T) {
+	got := ClassifyService([]compose.PSContainer{
+		{Service: "bridge", State: "running", Status: "Up 1 hour"},
+		{Service: "db", State: "exited", Status: "Exited (1) 5 minutes ago"},
+	})
+	if got.Health != HealthUnhealthy || got.Reason != "db: container exited with code 1" {
+		t.Errorf("ClassifyService = %+v, want the crashed db reported", got)
+	}
+	if got := ClassifyService(nil); got.Health != HealthUnknown {
+		t.Errorf("no containers = %v, want unknown", got.Health)
+	}
+}
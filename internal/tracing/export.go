@@ -0,0 +1,281 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// exportQueueSize bounds spans waiting for export. A full queue drops new
+	// spans rather than blocking the job path on a slow collector.
+	exportQueueSize = 2048
+
+	// exportBatchSize is the most spans sent in one request.
+	exportBatchSize = 512
+
+	// exportInterval is how often a partial batch is flushed.
+	exportInterval = 5 * time.Second
+
+	// exportTimeout bounds one export request.
+	exportTimeout = 10 * time.Second
+)
+
+// exporter batches ended spans and POSTs them as OTLP/HTTP JSON.
+type exporter struct {
+	url      string
+	headers  map[string]string
+	resource []otlpKeyValue
+	client   *http.Client
+	logf     func(format string, args ...any)
+
+	queue    chan spanRecord
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+
+	dropped atomic.Int64
+	failing bool // only touched by run
+}
+
+func newExporter(cfg Config) *exporter {
+	resource := []otlpKeyValue{kv("service.name", cfg.ServiceName)}
+	if cfg.ServiceVersion != "" {
+		resource = append(resource, kv("service.version", cfg.ServiceVersion))
+	}
+	if cfg.NodeName != "" {
+		resource = append(resource, kv("host.name", cfg.NodeName))
+	}
+	logf := cfg.Logf
+	if logf == nil {
+		logf = func(string, ...any) {}
+	}
+	return &exporter{
+		url:      cfg.Endpoint,
+		headers:  cfg.Headers,
+		resource: resource,
+		client:   &http.Client{Timeout: exportTimeout},
+		logf:     logf,
+		queue:    make(chan spanRecord, exportQueueSize),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+func (e *exporter) enqueue(rec spanRecord) {
+	select {
+	case e.queue <- rec:
+	default:
+		e.dropped.Add(1)
+	}
+}
+
+// run batches queued spans until shutdown, then flushes what is left.
+func (e *exporter) run() {
+	defer close(e.done)
+	ticker := time.NewTicker(exportInterval)
+	defer ticker.Stop()
+
+	var batch []spanRecord
+	for {
+		select {
+		case rec := <-e.queue:
+			batch = append(batch, rec)
+			if len(batch) >= exportBatchSize {
+				e.send(batch)
+				batch = nil
+			}
+		case <-ticker.C:
+			e.send(batch)
+			batch = nil
+		case <-e.stop:
+			for {
+				select {
+				case rec := <-e.queue:
+					batch = append(batch, rec)
+				default:
+					for len(batch) > 0 {
+						n := min(len(batch), exportBatchSize)
+						e.send(batch[:n])
+						batch = batch[n:]
+					}
+					return
+				}
+			}
+		}
+	}
+}
+
+// shutdown flushes queued spans, waiting at most until ctx is done.
+func (e *exporter) shutdown(ctx context.Context) error {
+	e.stopOnce.Do(func() { close(e.stop) })
+	select {
+	case <-e.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// send exports one batch. Failures are logged once per failing streak so an
+// unreachable collector does not flood the log.
+func (e *exporter) send(batch []spanRecord) {
+	if len(batch) == 0 {
+		return
+	}
+	err := e.post(batch)
+	if dropped := e.dropped.Swap(0); dropped > 0 {
+		e.logf("tracing: dropped %d spans (export queue full)", dropped)
+	}
+	switch {
+	case err != nil && !e.failing:
+		e.failing = true
+		e.logf("tracing: span export to %s failing: %v", e.url, err)
+	case err == nil && e.failing:
+		e.failing = false
+		e.logf("tracing: span export to %s recovered", e.url)
+	}
+}
+
+func (e *exporter) post(batch []spanRecord) error {
+	body, err := json.Marshal(e.request(batch))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range e.headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("collector returned HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// OTLP/HTTP JSON wire types (opentelemetry-proto, JSON mapping). Trace and
+// span IDs are hex strings and 64-bit integers are decimal strings.
+
+type otlpExportRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	ParentSpanID      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              int            `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Status            *otlpStatus    `json:"status,omitempty"`
+}
+
+// otlpStatusError is STATUS_CODE_ERROR.
+const otlpStatusError = 2
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+type otlpAnyValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+}
+
+func (e *exporter) request(batch []spanRecord) otlpExportRequest {
+	spans := make([]otlpSpan, 0, len(batch))
+	for _, rec := range batch {
+		s := otlpSpan{
+			TraceID:           rec.sc.TraceID.String(),
+			SpanID:            rec.sc.SpanID.String(),
+			Name:              rec.name,
+			Kind:              int(rec.kind),
+			StartTimeUnixNano: strconv.FormatInt(rec.start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(rec.end.UnixNano(), 10),
+		}
+		if rec.parent.IsValid() {
+			s.ParentSpanID = rec.parent.String()
+		}
+		for _, a := range rec.attrs {
+			s.Attributes = append(s.Attributes, kv(a.key, a.value))
+		}
+		if rec.errMsg != "" {
+			s.Status = &otlpStatus{Code: otlpStatusError, Message: rec.errMsg}
+		}
+		spans = append(spans, s)
+	}
+	return otlpExportRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource:   otlpResource{Attributes: e.resource},
+		ScopeSpans: []otlpScopeSpans{{Scope: otlpScope{Name: "github.com/aceteam-ai/citadel-cli"}, Spans: spans}},
+	}}}
+}
+
+// kv encodes one attribute. Types without an OTLP scalar are stringified.
+func kv(key string, value any) otlpKeyValue {
+	var v otlpAnyValue
+	switch x := value.(type) {
+	case string:
+		v.StringValue = &x
+	case bool:
+		v.BoolValue = &x
+	case int:
+		s := strconv.Itoa(x)
+		v.IntValue = &s
+	case int64:
+		s := strconv.FormatInt(x, 10)
+		v.IntValue = &s
+	case float64:
+		v.DoubleValue = &x
+	case time.Duration:
+		s := strconv.FormatInt(x.Milliseconds(), 10)
+		v.IntValue = &s
+	default:
+		s := fmt.Sprint(x)
+		v.StringValue = &s
+	}
+	return otlpKeyValue{Key: key, Value: v}
+}
@@ -0,0 +1,104 @@
+package tracing
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+// TraceparentHeader is the W3C Trace Context header.
+const TraceparentHeader = "traceparent"
+
+// Traceparent formats sc as a W3C traceparent value (always sampled), or ""
+// when sc has no span to parent on.
+func (sc SpanContext) Traceparent() string {
+	if !sc.TraceID.IsValid() || !sc.SpanID.IsValid() {
+		return ""
+	}
+	return "00-" + sc.TraceID.String() + "-" + sc.SpanID.String() + "-01"
+}
+
+// ParseTraceparent parses a W3C traceparent value. Unknown future versions are
+// accepted as long as the version-00 fields parse, per the spec.
+func ParseTraceparent(v string) (SpanContext, bool) {
+	parts := strings.Split(strings.TrimSpace(v), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || (parts[0] == "00" && len(parts) != 4) {
+		return SpanContext{}, false
+	}
+	var sc SpanContext
+	if !decodeHex(sc.TraceID[:], parts[1]) || !decodeHex(sc.SpanID[:], parts[2]) || len(parts[3]) != 2 {
+		return SpanContext{}, false
+	}
+	if !sc.TraceID.IsValid() || !sc.SpanID.IsValid() {
+		return SpanContext{}, false
+	}
+	return sc, true
+}
+
+// decodeHex fills dst from exactly len(dst)*2 lowercase hex digits.
+func decodeHex(dst []byte, s string) bool {
+	if len(s) != len(dst)*2 || strings.ToLower(s) != s {
+		return false
+	}
+	_, err := hex.Decode(dst, []byte(s))
+	return err == nil
+}
+
+// TraceIDFromRayID maps a JQS-Core RayID onto a trace ID, deterministically so
+// the platform can find a job's node spans from its RayID alone:
+//   - a W3C traceparent contributes its trace ID;
+//   - 32 hex digits (a bare trace ID, or a UUID once its dashes are dropped)
+//     are used as-is;
+//   - anything else is hashed: the first 16 bytes of its SHA-256.
+func TraceIDFromRayID(rayID string) TraceID {
+	var id TraceID
+	if rayID == "" {
+		return id
+	}
+	if sc, ok := ParseTraceparent(rayID); ok {
+		return sc.TraceID
+	}
+	if hexID := strings.ToLower(strings.ReplaceAll(rayID, "-", "")); decodeHex(id[:], hexID) && id.IsValid() {
+		return id
+	}
+	sum := sha256.Sum256([]byte(rayID))
+	copy(id[:], sum[:len(id)])
+	return id
+}
+
+// ContextWithRayID parents the next span on the job's RayID: on the remote
+// span itself when the RayID is a traceparent, otherwise as a root span of
+// the RayID's trace. An empty RayID leaves ctx unchanged.
+func ContextWithRayID(ctx context.Context, rayID string) context.Context {
+	if sc, ok := ParseTraceparent(rayID); ok {
+		return ContextWithSpanContext(ctx, sc)
+	}
+	if id := TraceIDFromRayID(rayID); id.IsValid() {
+		return ContextWithSpanContext(ctx, SpanContext{TraceID: id})
+	}
+	return ctx
+}
+
+// Inject sets the traceparent header for the span in ctx. It writes nothing
+// while tracing is off or ctx carries no span.
+func Inject(ctx context.Context, h http.Header) {
+	if !Enabled() {
+		return
+	}
+	if sc, ok := SpanContextFromContext(ctx); ok {
+		if tp := sc.Traceparent(); tp != "" {
+			h.Set(TraceparentHeader, tp)
+		}
+	}
+}
+
+// Extract returns ctx parented on the caller's span from an incoming
+// traceparent header, or ctx unchanged when there is none or it is malformed.
+func Extract(ctx context.Context, h http.Header) context.Context {
+	if sc, ok := ParseTraceparent(h.Get(TraceparentHeader)); ok {
+		return ContextWithSpanContext(ctx, sc)
+	}
+	return ctx
+}
@@ -0,0 +1,195 @@
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// TraceID is a W3C trace ID.
+type TraceID [16]byte
+
+// IsValid reports whether t is non-zero.
+func (t TraceID) IsValid() bool { return t != TraceID{} }
+
+func (t TraceID) String() string { return hex.EncodeToString(t[:]) }
+
+// SpanID is a W3C span (parent) ID.
+type SpanID [8]byte
+
+// IsValid reports whether s is non-zero.
+func (s SpanID) IsValid() bool { return s != SpanID{} }
+
+func (s SpanID) String() string { return hex.EncodeToString(s[:]) }
+
+// SpanContext identifies a span for parenting and propagation. A context
+// carrying a TraceID with no SpanID (a RayID that is not itself a
+// traceparent) makes the next span a root of that trace.
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+}
+
+// SpanKind is the OTLP span kind.
+type SpanKind int
+
+const (
+	KindInternal SpanKind = 1
+	KindServer   SpanKind = 2
+	KindClient   SpanKind = 3
+	KindProducer SpanKind = 4
+	KindConsumer SpanKind = 5
+)
+
+type spanContextKey struct{}
+
+// ContextWithSpanContext returns ctx with sc as the parent of the next span.
+func ContextWithSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, spanContextKey{}, sc)
+}
+
+// SpanContextFromContext returns the span context ctx carries, if any.
+func SpanContextFromContext(ctx context.Context) (SpanContext, bool) {
+	sc, ok := ctx.Value(spanContextKey{}).(SpanContext)
+	return sc, ok && sc.TraceID.IsValid()
+}
+
+// Span is one timed operation. A nil *Span (tracing off) is valid and every
+// method on it is a no-op.
+type Span struct {
+	exp    *exporter
+	sc     SpanContext
+	parent SpanID
+	name   string
+	kind   SpanKind
+	start  time.Time
+
+	mu     sync.Mutex
+	attrs  []attr
+	errMsg string
+	ended  bool
+}
+
+type attr struct {
+	key   string
+	value any
+}
+
+// StartOption customizes Start.
+type StartOption func(*Span)
+
+// WithKind sets the span kind (default KindInternal).
+func WithKind(k SpanKind) StartOption { return func(s *Span) { s.kind = k } }
+
+// WithStartTime backdates the span, for work timed before its trace was known
+// (the fetch that delivered a job and its RayID).
+func WithStartTime(t time.Time) StartOption { return func(s *Span) { s.start = t } }
+
+// WithAttr sets an attribute at start.
+func WithAttr(key string, value any) StartOption {
+	return func(s *Span) { s.attrs = append(s.attrs, attr{key, value}) }
+}
+
+// Start begins a span named name as a child of the span context in ctx (a
+// new trace when there is none) and returns a context carrying it. With
+// tracing off it returns ctx unchanged and a nil span.
+func Start(ctx context.Context, name string, opts ...StartOption) (context.Context, *Span) {
+	exp := current.Load()
+	if exp == nil {
+		return ctx, nil
+	}
+	s := &Span{exp: exp, name: name, kind: KindInternal, start: time.Now()}
+	if parent, ok := SpanContextFromContext(ctx); ok {
+		s.sc.TraceID = parent.TraceID
+		s.parent = parent.SpanID
+	} else {
+		rand.Read(s.sc.TraceID[:])
+	}
+	rand.Read(s.sc.SpanID[:])
+	for _, opt := range opts {
+		opt(s)
+	}
+	return ContextWithSpanContext(ctx, s.sc), s
+}
+
+// SpanContext returns the span's identity (zero for a nil span).
+func (s *Span) SpanContext() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.sc
+}
+
+// SetAttr sets an attribute, replacing an earlier value for key. Changes
+// after End are ignored.
+func (s *Span) SetAttr(key string, value any) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ended {
+		return
+	}
+	for i := range s.attrs {
+		if s.attrs[i].key == key {
+			s.attrs[i].value = value
+			return
+		}
+	}
+	s.attrs = append(s.attrs, attr{key, value})
+}
+
+// RecordError marks the span failed with err's message. A nil err is ignored.
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.ended {
+		s.errMsg = err.Error()
+	}
+}
+
+// End finishes the span now and queues it for export.
+func (s *Span) End() { s.EndAt(time.Now()) }
+
+// EndAt finishes the span at t. Only the first End counts.
+func (s *Span) EndAt(t time.Time) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	rec := spanRecord{
+		sc:     s.sc,
+		parent: s.parent,
+		name:   s.name,
+		kind:   s.kind,
+		start:  s.start,
+		end:    t,
+		attrs:  s.attrs,
+		errMsg: s.errMsg,
+	}
+	s.mu.Unlock()
+	s.exp.enqueue(rec)
+}
+
+// spanRecord is an ended span's immutable snapshot, handed to the exporter.
+type spanRecord struct {
+	sc     SpanContext
+	parent SpanID
+	name   string
+	kind   SpanKind
+	start  time.Time
+	end    time.Time
+	attrs  []attr
+	errMsg string
+}
@@ -0,0 +1,150 @@
+// Package tracing records OpenTelemetry spans for the work a node does on a
+// job and exports them to an OTLP/HTTP collector.
+//
+// A job's spans are keyed on its JQS-Core RayID (see TraceIDFromRayID), so the
+// node's fetch, claim, swap, readiness, execute and publish spans join the
+// platform's trace for the request that produced the job, and the W3C
+// traceparent header carries that trace on into the gateway and the engine
+// HTTP calls. That is what answers "was this slow request queued, swapping,
+// warming or generating?".
+//
+// Tracing is OFF unless an endpoint is configured. While it is off Start
+// returns a nil *Span, whose methods are all no-ops, and Inject writes
+// nothing, so call sites never branch on it.
+//
+// The package speaks the OTLP wire format (JSON encoding) directly instead of
+// linking the OpenTelemetry SDK: the node needs a few spans per job and
+// traceparent propagation, not the SDK's metric/log pipelines or its gRPC
+// dependency tree.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"sync/atomic"
+)
+
+// DefaultServiceName is the service.name resource attribute when none is set.
+const DefaultServiceName = "citadel"
+
+// Config configures span export.
+type Config struct {
+	// Endpoint is the OTLP/HTTP traces URL. A bare collector address
+	// ("http://collector:4318") gets the standard /v1/traces path appended.
+	// Empty disables tracing.
+	Endpoint string
+
+	// Headers are sent with every export request (e.g. a collector API key).
+	Headers map[string]string
+
+	// ServiceName and ServiceVersion become the service.name and
+	// service.version resource attributes.
+	ServiceName    string
+	ServiceVersion string
+
+	// NodeName becomes the host.name resource attribute.
+	NodeName string
+
+	// Logf reports export failures. Nil discards them.
+	Logf func(format string, args ...any)
+}
+
+// current is the active exporter; nil means tracing is off.
+var current atomic.Pointer[exporter]
+
+// Setup starts exporting spans per cfg and returns the function that flushes
+// and stops the exporter. With no endpoint configured it leaves tracing off
+// and returns a no-op shutdown.
+func Setup(cfg Config) (shutdown func(context.Context) error, err error) {
+	if cfg.Endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+	endpoint, err := TracesURL(cfg.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Endpoint = endpoint
+	if cfg.ServiceName == "" {
+		cfg.ServiceName = DefaultServiceName
+	}
+	exp := newExporter(cfg)
+	if prev := current.Swap(exp); prev != nil {
+		prev.shutdown(context.Background())
+	}
+	go exp.run()
+	return func(ctx context.Context) error {
+		current.CompareAndSwap(exp, nil)
+		return exp.shutdown(ctx)
+	}, nil
+}
+
+// Enabled reports whether spans are being recorded.
+func Enabled() bool {
+	return current.Load() != nil
+}
+
+// TracesURL normalizes an OTLP/HTTP endpoint: a URL with no path gets
+// /v1/traces, one with a path is used as given.
+func TracesURL(endpoint string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(endpoint))
+	if err != nil {
+		return "", fmt.Errorf("invalid OTLP endpoint %q: %w", endpoint, err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", fmt.Errorf("invalid OTLP endpoint %q: want an http(s)://host[:port] URL", endpoint)
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = "/v1/traces"
+	}
+	return u.String(), nil
+}
+
+// ConfigFromEnv reads the standard OpenTelemetry exporter variables:
+// OTEL_EXPORTER_OTLP_TRACES_ENDPOINT (used as-is) or OTEL_EXPORTER_OTLP_ENDPOINT
+// (the collector base; /v1/traces is appended), their _HEADERS counterparts as
+// comma-separated key=value pairs, and OTEL_SERVICE_NAME. OTEL_SDK_DISABLED=true
+// or OTEL_TRACES_EXPORTER=none leaves the endpoint empty.
+func ConfigFromEnv() Config {
+	cfg := Config{ServiceName: os.Getenv("OTEL_SERVICE_NAME")}
+	if strings.EqualFold(os.Getenv("OTEL_SDK_DISABLED"), "true") ||
+		strings.EqualFold(os.Getenv("OTEL_TRACES_EXPORTER"), "none") {
+		return cfg
+	}
+	if ep := strings.TrimSpace(os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT")); ep != "" {
+		cfg.Endpoint = ep
+	} else if base := strings.TrimSpace(os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")); base != "" {
+		cfg.Endpoint = strings.TrimRight(base, "/") + "/v1/traces"
+	}
+	cfg.Headers = parseHeaders(os.Getenv("OTEL_EXPORTER_OTLP_HEADERS"))
+	for k, v := range parseHeaders(os.Getenv("OTEL_EXPORTER_OTLP_TRACES_HEADERS")) {
+		if cfg.Headers == nil {
+			cfg.Headers = map[string]string{}
+		}
+		cfg.Headers[k] = v
+	}
+	return cfg
+}
+
+// parseHeaders parses the OTEL_EXPORTER_OTLP_HEADERS format: comma-separated
+// key=value pairs with URL-encoded values.
+func parseHeaders(raw string) map[string]string {
+	var headers map[string]string
+	for _, pair := range strings.Split(raw, ",") {
+		k, v, ok := strings.Cut(pair, "=")
+		k = strings.TrimSpace(k)
+		if !ok || k == "" {
+			continue
+		}
+		if dec, err := url.QueryUnescape(strings.TrimSpace(v)); err == nil {
+			v = dec
+		}
+		if headers == nil {
+			headers = map[string]string{}
+		}
+		headers[k] = strings.TrimSpace(v)
+	}
+	return headers
+}
@@ -0,0 +1,249 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// collector is a fake OTLP/HTTP endpoint that keeps every span it receives.
+type collector struct {
+	mu      sync.Mutex
+	spans   []otlpSpan
+	service string
+	header  string
+}
+
+func (c *collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req otlpExportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.header = r.Header.Get("X-Api-Key")
+	for _, rs := range req.ResourceSpans {
+		for _, a := range rs.Resource.Attributes {
+			if a.Key == "service.name" && a.Value.StringValue != nil {
+				c.service = *a.Value.StringValue
+			}
+		}
+		for _, ss := range rs.ScopeSpans {
+			c.spans = append(c.spans, ss.Spans...)
+		}
+	}
+}
+
+func (c *collector) byName(name string) (otlpSpan, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, s := range c.spans {
+		if s.Name == name {
+			return s, true
+		}
+	}
+	return otlpSpan{}, false
+}
+
+// startCollector enables tracing against a fake collector for one test.
+func startCollector(t *testing.T) (*collector, func()) {
+	t.Helper()
+	c := &collector{}
+	srv := httptest.NewServer(c)
+	t.Cleanup(srv.Close)
+	shutdown, err := Setup(Config{Endpoint: srv.URL, Headers: map[string]string{"X-Api-Key": "k"}})
+	if err != nil {
+		t.Fatalf("Setup: %v", err)
+	}
+	flush := func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdown(ctx); err != nil {
+			t.Fatalf("shutdown: %v", err)
+		}
+	}
+	t.Cleanup(func() { current.Store(nil) })
+	return c, flush
+}
+
+func TestDisabledIsNoop(t *testing.T) {
+	if Enabled() {
+		t.Fatal("tracing is on with no endpoint configured")
+	}
+	shutdown, err := Setup(Config{})
+	if err != nil || Enabled() {
+		t.Fatalf("Setup with no endpoint: err=%v enabled=%v", err, Enabled())
+	}
+	defer shutdown(context.Background())
+
+	ctx, span := Start(ContextWithRayID(context.Background(), "ray-abc"), "job")
+	if span != nil {
+		t.Fatal("Start returned a span with tracing off")
+	}
+	span.SetAttr("k", "v")
+	span.RecordError(errors.New("boom"))
+	span.End()
+
+	h := http.Header{}
+	Inject(ctx, h)
+	if h.Get(TraceparentHeader) != "" {
+		t.Errorf("Inject wrote %q with tracing off", h.Get(TraceparentHeader))
+	}
+}
+
+func TestExportParentsSpansOnRayID(t *testing.T) {
+	c, flush := startCollector(t)
+
+	ctx := ContextWithRayID(context.Background(), "ray-abc")
+	ctx, root := Start(ctx, "job LLM_INFERENCE", WithKind(KindConsumer), WithAttr("citadel.job.id", "job-1"))
+	_, child := Start(ctx, "engine.swap")
+	child.SetAttr("citadel.swap.ready", false)
+	child.SetAttr("citadel.swap.ready", true)
+	child.RecordError(errors.New("swap failed"))
+	child.End()
+	root.End()
+	flush()
+
+	want := TraceIDFromRayID("ray-abc").String()
+	r, ok := c.byName("job LLM_INFERENCE")
+	if !ok {
+		t.Fatal("root span not exported")
+	}
+	s, ok := c.byName("engine.swap")
+	if !ok {
+		t.Fatal("child span not exported")
+	}
+	if r.TraceID != want || s.TraceID != want {
+		t.Errorf("trace IDs = %s, %s; want both %s", r.TraceID, s.TraceID, want)
+	}
+	if r.ParentSpanID != "" || s.ParentSpanID != r.SpanID {
+		t.Errorf("root parent %q, child parent %q; want root to be the child's parent", r.ParentSpanID, s.ParentSpanID)
+	}
+	if r.Kind != int(KindConsumer) || len(r.Attributes) != 1 || *r.Attributes[0].Value.StringValue != "job-1" {
+		t.Errorf("root = %+v", r)
+	}
+	if len(s.Attributes) != 1 || s.Attributes[0].Value.BoolValue == nil || !*s.Attributes[0].Value.BoolValue {
+		t.Errorf("child attributes = %+v, want the replaced value only", s.Attributes)
+	}
+	if s.Status == nil || s.Status.Code != otlpStatusError || s.Status.Message != "swap failed" {
+		t.Errorf("child status = %+v", s.Status)
+	}
+	if c.service != DefaultServiceName || c.header != "k" {
+		t.Errorf("service %q, header %q", c.service, c.header)
+	}
+}
+
+func TestRayIDTraceparentParentsOnRemoteSpan(t *testing.T) {
+	c, flush := startCollector(t)
+
+	const tp = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	_, span := Start(ContextWithRayID(context.Background(), tp), "job")
+	span.End()
+	flush()
+
+	s, _ := c.byName("job")
+	if s.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" || s.ParentSpanID != "00f067aa0ba902b7" {
+		t.Errorf("span = %+v, want it parented on the traceparent RayID", s)
+	}
+}
+
+func TestInjectExtractRoundTrip(t *testing.T) {
+	_, flush := startCollector(t)
+	defer flush()
+
+	ctx, span := Start(context.Background(), "gateway")
+	h := http.Header{}
+	Inject(ctx, h)
+	got, ok := SpanContextFromContext(Extract(context.Background(), h))
+	if !ok || got != span.SpanContext() {
+		t.Errorf("extracted %+v (ok=%v), want %+v", got, ok, span.SpanContext())
+	}
+}
+
+func TestParseTraceparent(t *testing.T) {
+	tests := []struct {
+		in string
+		ok bool
+	}{
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", true},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", true},
+		{"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", true},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", false},
+		{"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", false},
+		{"00-00000000000000000000000000000000-00f067aa0ba902b7-01", false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", false},
+		{"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01", false},
+		{"00-4bf92f35-00f067aa0ba902b7-01", false},
+		{"ray-abc", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if _, ok := ParseTraceparent(tt.in); ok != tt.ok {
+			t.Errorf("ParseTraceparent(%q) ok = %v, want %v", tt.in, ok, tt.ok)
+		}
+	}
+}
+
+func TestTraceIDFromRayID(t *testing.T) {
+	tests := []struct {
+		rayID string
+		want  string
+	}{
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", "4bf92f3577b34da6a3ce929d0e0e4736"},
+		{"4bf92f3577b34da6a3ce929d0e0e4736", "4bf92f3577b34da6a3ce929d0e0e4736"},
+		{"3F2504E0-4F89-41D3-9A0C-0305E82C3301", "3f2504e04f8941d39a0c0305e82c3301"},
+		// sha256("ray-abc")[:16]
+		{"ray-abc", "d1424eedea6c058b05717ee1d3607abb"},
+		{"", "00000000000000000000000000000000"},
+	}
+	for _, tt := range tests {
+		if got := TraceIDFromRayID(tt.rayID).String(); got != tt.want {
+			t.Errorf("TraceIDFromRayID(%q) = %s, want %s", tt.rayID, got, tt.want)
+		}
+	}
+}
+
+func TestTracesURL(t *testing.T) {
+	tests := []struct {
+		in, want string
+		wantErr  bool
+	}{
+		{"http://collector:4318", "http://collector:4318/v1/traces", false},
+		{"https://otel.example.com/", "https://otel.example.com/v1/traces", false},
+		{"http://collector:4318/custom/traces", "http://collector:4318/custom/traces", false},
+		{"collector:4318", "", true},
+		{"grpc://collector:4317", "", true},
+	}
+	for _, tt := range tests {
+		got, err := TracesURL(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("TracesURL(%q) = %q, %v; want %q (err=%v)", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "http://collector:4318/")
+	t.Setenv("OTEL_EXPORTER_OTLP_HEADERS", "x-api-key=abc%3D,bad")
+	t.Setenv("OTEL_SERVICE_NAME", "citadel-gpu-1")
+	cfg := ConfigFromEnv()
+	if cfg.Endpoint != "http://collector:4318/v1/traces" || cfg.Headers["x-api-key"] != "abc=" || len(cfg.Headers) != 1 || cfg.ServiceName != "citadel-gpu-1" {
+		t.Errorf("cfg = %+v", cfg)
+	}
+
+	t.Setenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "http://other:4318/traces")
+	if cfg := ConfigFromEnv(); cfg.Endpoint != "http://other:4318/traces" {
+		t.Errorf("traces endpoint = %q, want it to win over the base", cfg.Endpoint)
+	}
+
+	t.Setenv("OTEL_SDK_DISABLED", "true")
+	if cfg := ConfigFromEnv(); cfg.Endpoint != "" {
+		t.Errorf("endpoint = %q with OTEL_SDK_DISABLED", cfg.Endpoint)
+	}
+}
@@ -606,6 +606,9 @@ type ControlCenter struct {
 	// Module install (install a service module from any standardized repo)
 	moduleInstallConfig ModuleInstallCallbacks
 
+	// Jobs (in-flight/recent jobs of the in-process worker)
+	jobsConfig JobsCallbacks
+
 	// Mouse control. mouseEnabled is the resolved initial state (persisted config
 	// overridden by the --no-mouse flag); it is applied to the app in Run() and
 	// can be flipped live from the Settings pane. Keyboard navigation is fully
@@ -693,6 +696,7 @@ type Config struct {
 	Settings      SettingsCallbacks      // Settings page hooks (telemetry load/save)
 	WhatsApp      WhatsAppCallbacks      // WhatsApp bridge page hooks (deploy/stop/status/QR)
 	ModuleInstall ModuleInstallCallbacks // Install-module page hooks (resolve source + install)
+	Jobs          JobsCallbacks          // Jobs page hooks (in-flight/recent jobs, cancel, re-run)
 
 	// MouseEnabled is the resolved initial mouse state (persisted preference with
 	// the --no-mouse flag applied). When true, the control center opts into
@@ -758,6 +762,7 @@ func New(cfg Config) *ControlCenter {
 		settingsConfig:      cfg.Settings,
 		whatsappConfig:      cfg.WhatsApp,
 		moduleInstallConfig: cfg.ModuleInstall,
+		jobsConfig:          cfg.Jobs,
 		mouseEnabled:        cfg.MouseEnabled,
 		fullscreenEnabled:   cfg.FullscreenEnabled,
 	}
@@ -872,7 +877,13 @@ func (cc *ControlCenter) Run() error {
 	// Alt+4: Gateway page (hidden until gateway ledger appears on disk)
 	gatewayBaseDir := filepath.Join(os.Getenv("HOME"), ".citadel-cli")
 	cc.pmgr.Register(NewGatewayPage(gatewayBaseDir), false)
-	cc.pmgr.Register(NewPlaceholderPage("jobs", "Jobs"), false)
+	// Jobs page: live view of the in-process worker's jobs, falling back to
+	// the usage history when the worker runs elsewhere (`citadel work`).
+	cc.pmgr.Register(NewJobsPage(JobsPageConfig{
+		Callbacks:  cc.jobsConfig,
+		ActivityFn: cc.AddActivity,
+		ConfirmFn:  cc.showConfirm,
+	}), true)
 	cc.pmgr.Register(NewPlaceholderPage("network", "Network"), false)
 
 	// Proxmox page: gated on real detection (saved config or a detected local
@@ -0,0 +1,496 @@
+package controlcenter
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// JobEntry is one row of the Jobs page: a job in flight on this node's worker
+// or a recently finished one. Payload and Result arrive already redacted.
+type JobEntry struct {
+	JobRecord
+	Source  string // job source ("redis", "api", "local" for a re-run)
+	Queue   string // stream the job was read from, if known
+	Chunks  int64  // stream chunks published so far
+	Payload map[string]any
+	Result  map[string]any
+}
+
+// running reports whether the job is still in flight.
+func (e JobEntry) running() bool { return e.Status == "running" }
+
+// elapsed is how long the job has run, or ran.
+func (e JobEntry) elapsed(now time.Time) time.Duration {
+	if e.StartedAt.IsZero() {
+		return e.Duration
+	}
+	if e.running() || e.CompletedAt.IsZero() {
+		return now.Sub(e.StartedAt)
+	}
+	return e.CompletedAt.Sub(e.StartedAt)
+}
+
+// JobsCallbacks wires the Jobs page to the worker. All are optional: with no
+// worker running in this process InFlight returns nothing, Recent falls back
+// to the usage history, and the actions report that there is no worker.
+type JobsCallbacks struct {
+	// InFlight returns the jobs currently executing, oldest first.
+	InFlight func() []JobEntry
+	// Recent returns finished jobs, newest first.
+	Recent func() []JobEntry
+	// Cancel stops an in-flight job; it is closed out as cancelled.
+	Cancel func(id string) error
+	// Rerun queues a failed job to run again locally and returns the new ID.
+	Rerun func(id string) (string, error)
+}
+
+// JobsPage shows what the worker is doing: jobs in flight with their source
+// queue, elapsed time and streamed chunk count, the recent history with
+// status and errors, and a detail pane with the selected job's payload and
+// result. Numbered actions cancel an in-flight job or re-run a failed one.
+type JobsPage struct {
+	app *tview.Application
+	cb  JobsCallbacks
+
+	// confirmFn shows a modal yes/no dialog for cancel; wired from the
+	// ControlCenter. When nil the action runs without a prompt.
+	confirmFn  func(prompt, confirmLabel string, onConfirm func())
+	activityFn func(level, msg string)
+
+	// UI
+	root       *tview.Flex
+	statusView *tview.TextView
+	jobTable   *tview.Table
+	detailView *tview.TextView
+	helpBar    *tview.TextView
+
+	// Data: rows in table order (in flight, then recent)
+	mu       sync.Mutex
+	inFlight []JobEntry
+	recent   []JobEntry
+	rows     []JobEntry
+	detailID string // job shown in the detail pane
+	active   bool
+	stopCh   chan struct{}
+}
+
+// JobsPageConfig holds configuration for creating a JobsPage.
+type JobsPageConfig struct {
+	Callbacks  JobsCallbacks
+	ActivityFn func(level, msg string)
+	ConfirmFn  func(prompt, confirmLabel string, onConfirm func())
+}
+
+// NewJobsPage creates a Jobs page wired to the given callbacks.
+func NewJobsPage(cfg JobsPageConfig) *JobsPage {
+	activityFn := cfg.ActivityFn
+	if activityFn == nil {
+		activityFn = func(string, string) {}
+	}
+	return &JobsPage{
+		cb:         cfg.Callbacks,
+		confirmFn:  cfg.ConfirmFn,
+		activityFn: activityFn,
+	}
+}
+
+func (p *JobsPage) Name() string  { return "jobs" }
+func (p *JobsPage) Title() string { return "Jobs" }
+
+func (p *JobsPage) Build(app *tview.Application) tview.Primitive {
+	p.app = app
+
+	p.statusView = tview.NewTextView().
+		SetDynamicColors(true).
+		SetTextAlign(tview.AlignLeft)
+
+	p.jobTable = tview.NewTable().
+		SetFixed(1, 0).
+		SetSelectable(true, false).
+		SetSelectedStyle(tcell.StyleDefault.
+			Foreground(tcell.ColorBlack).
+			Background(tcell.ColorWhite))
+	p.jobTable.SetBorder(true).
+		SetTitle(" Jobs ").
+		SetTitleAlign(tview.AlignLeft)
+
+	p.detailView = tview.NewTextView().
+		SetDynamicColors(true).
+		SetScrollable(true).
+		SetWrap(true)
+	p.detailView.SetBorder(true).
+		SetTitle(" Details ").
+		SetTitleAlign(tview.AlignLeft)
+
+	p.helpBar = tview.NewTextView().
+		SetDynamicColors(true).
+		SetTextAlign(tview.AlignLeft)
+	p.helpBar.SetText(" [yellow]Enter[-]=details  [yellow]1[-]=cancel  [yellow]2[-]=re-run failed  [yellow]3[-]=refresh")
+
+	contentFlex := tview.NewFlex().SetDirection(tview.FlexColumn).
+		AddItem(p.jobTable, 0, 3, true).
+		AddItem(p.detailView, 0, 2, false)
+
+	p.root = tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(p.statusView, 1, 0, false).
+		AddItem(contentFlex, 0, 1, true).
+		AddItem(p.helpBar, 1, 0, false)
+
+	p.updateStatus()
+	p.updateJobTable()
+
+	return p.root
+}
+
+func (p *JobsPage) OnActivate() {
+	p.mu.Lock()
+	p.active = true
+	p.stopCh = make(chan struct{})
+	p.mu.Unlock()
+
+	go p.pollLoop()
+}
+
+func (p *JobsPage) OnDeactivate() {
+	p.mu.Lock()
+	p.active = false
+	if p.stopCh != nil {
+		close(p.stopCh)
+		p.stopCh = nil
+	}
+	p.mu.Unlock()
+}
+
+// HandleInput implements Page. Numbered actions act on the selected row:
+// 1=cancel (in flight), 2=re-run (failed), 3=refresh. Callbacks and refreshes
+// run off the event-loop goroutine, which must never wait on QueueUpdateDraw.
+func (p *JobsPage) HandleInput(event *tcell.EventKey) *tcell.EventKey {
+	if event.Key() == tcell.KeyEnter {
+		p.showSelectedDetail()
+		return nil
+	}
+	if event.Key() != tcell.KeyRune {
+		return event
+	}
+	switch event.Rune() {
+	case '1':
+		p.cancelSelected()
+		return nil
+	case '2':
+		p.rerunSelected()
+		return nil
+	case '3':
+		go p.refreshData()
+		return nil
+	}
+	return event
+}
+
+// pollLoop refreshes every second while the page is active, so elapsed times
+// and chunk counts tick along with the job.
+func (p *JobsPage) pollLoop() {
+	p.refreshData()
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		p.mu.Lock()
+		stopCh := p.stopCh
+		p.mu.Unlock()
+		if stopCh == nil {
+			return
+		}
+
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+			p.refreshData()
+		}
+	}
+}
+
+func (p *JobsPage) refreshData() {
+	var inFlight, recent []JobEntry
+	if p.cb.InFlight != nil {
+		inFlight = p.cb.InFlight()
+	}
+	if p.cb.Recent != nil {
+		recent = p.cb.Recent()
+	}
+
+	p.mu.Lock()
+	p.inFlight = inFlight
+	p.recent = recent
+	p.mu.Unlock()
+
+	if p.app == nil {
+		return
+	}
+	p.app.QueueUpdateDraw(func() {
+		p.updateStatus()
+		p.updateJobTable()
+		p.updateDetail()
+	})
+}
+
+func (p *JobsPage) updateStatus() {
+	p.mu.Lock()
+	running, recent := len(p.inFlight), len(p.recent)
+	failed := 0
+	for _, j := range p.recent {
+		if j.Status == "failed" {
+			failed++
+		}
+	}
+	p.mu.Unlock()
+
+	status := " [green::b]Jobs[-:-:-]  "
+	if running > 0 {
+		status += fmt.Sprintf("[cyan]%s %d running[-]", Glyph(MarkerActive), running)
+	} else {
+		status += fmt.Sprintf("[gray]%s idle[-]", Glyph(MarkerInactive))
+	}
+	status += fmt.Sprintf("  |  %d recent", recent)
+	if failed > 0 {
+		status += fmt.Sprintf(" ([red]%d failed[-])", failed)
+	}
+	p.statusView.SetText(status)
+}
+
+func (p *JobsPage) updateJobTable() {
+	// Keep the selection on the same job as rows shift.
+	selectedID := ""
+	if j, ok := p.selectedJob(); ok {
+		selectedID = j.ID
+	}
+
+	p.mu.Lock()
+	rows := make([]JobEntry, 0, len(p.inFlight)+len(p.recent))
+	rows = append(rows, p.inFlight...)
+	rows = append(rows, p.recent...)
+	p.rows = rows
+	p.mu.Unlock()
+
+	p.jobTable.Clear()
+
+	headers := []string{"Status", "Type", "Job", "Queue", "Elapsed", "Chunks", "Error"}
+	expansions := []int{0, 0, 1, 0, 0, 0, 2}
+	for i, h := range headers {
+		p.jobTable.SetCell(0, i, tview.NewTableCell(h).
+			SetTextColor(tcell.ColorYellow).
+			SetSelectable(false).
+			SetExpansion(expansions[i]))
+	}
+
+	if len(rows) == 0 {
+		p.jobTable.SetCell(1, 0,
+			tview.NewTableCell("  No jobs yet. Jobs this node's worker runs appear here.").
+				SetTextColor(tcell.ColorGray).
+				SetSelectable(false).
+				SetExpansion(1))
+		return
+	}
+
+	now := time.Now()
+	selectRow := 1
+	for i, j := range rows {
+		row := i + 1
+		if j.ID == selectedID {
+			selectRow = row
+		}
+		queue := j.Queue
+		if queue == "" {
+			queue = j.Source
+		}
+		chunks := "-"
+		if j.Chunks > 0 {
+			chunks = fmt.Sprintf("%d", j.Chunks)
+		}
+		p.jobTable.SetCell(row, 0, tview.NewTableCell(" "+jobStatusLabel(j.Status)))
+		p.jobTable.SetCell(row, 1, tview.NewTableCell(j.Type).SetTextColor(tcell.ColorAqua))
+		p.jobTable.SetCell(row, 2, tview.NewTableCell(j.ID).SetTextColor(tcell.ColorWhite).SetExpansion(1))
+		p.jobTable.SetCell(row, 3, tview.NewTableCell(queue).SetTextColor(tcell.ColorGray))
+		p.jobTable.SetCell(row, 4, tview.NewTableCell(formatDurationCompact(j.elapsed(now))).SetTextColor(tcell.ColorWhite))
+		p.jobTable.SetCell(row, 5, tview.NewTableCell(chunks).SetTextColor(tcell.ColorWhite))
+		p.jobTable.SetCell(row, 6, tview.NewTableCell(firstLine(j.Error)).SetTextColor(tcell.ColorRed).SetExpansion(2))
+	}
+	p.jobTable.Select(selectRow, 0)
+}
+
+// jobStatusLabel colors a job status for the table.
+func jobStatusLabel(status string) string {
+	switch status {
+	case "running":
+		return "[cyan]" + Glyph(MarkerActive) + " running[-]"
+	case "success":
+		return "[green]" + Glyph(MarkerOK) + " success[-]"
+	case "failed":
+		return "[red]" + Glyph(MarkerError) + " failed[-]"
+	case "cancelled":
+		return "[yellow]" + Glyph(MarkerInactive) + " cancelled[-]"
+	default:
+		return "[gray]" + status + "[-]"
+	}
+}
+
+// firstLine returns s up to its first newline.
+func firstLine(s string) string {
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
+
+// selectedJob returns the job on the selected row.
+func (p *JobsPage) selectedJob() (JobEntry, bool) {
+	row, _ := p.jobTable.GetSelection()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if row < 1 || row > len(p.rows) {
+		return JobEntry{}, false
+	}
+	return p.rows[row-1], true
+}
+
+// showSelectedDetail pins the selected job in the detail pane.
+func (p *JobsPage) showSelectedDetail() {
+	j, ok := p.selectedJob()
+	if !ok {
+		return
+	}
+	p.mu.Lock()
+	p.detailID = j.ID
+	p.mu.Unlock()
+	p.updateDetail()
+}
+
+// updateDetail re-renders the pinned job, which may have moved from in flight
+// to the history since it was selected.
+func (p *JobsPage) updateDetail() {
+	p.mu.Lock()
+	id := p.detailID
+	var entry *JobEntry
+	for i := range p.rows {
+		if p.rows[i].ID == id {
+			entry = &p.rows[i]
+			break
+		}
+	}
+	p.mu.Unlock()
+
+	switch {
+	case id == "":
+		p.detailView.SetText("[gray]Select a job and press Enter for its payload and result.[-]")
+	case entry == nil:
+		p.detailView.SetText(fmt.Sprintf("[gray]Job %s is no longer in the recent history.[-]", tview.Escape(id)))
+	default:
+		p.detailView.SetText(formatJobDetail(*entry, time.Now()))
+		p.detailView.ScrollToBeginning()
+	}
+}
+
+// formatJobDetail renders a job's fields, payload and result for the detail
+// pane.
+func formatJobDetail(j JobEntry, now time.Time) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "[yellow::b]%s[-:-:-]  %s\n\n", tview.Escape(j.Type), jobStatusLabel(j.Status))
+	field := func(label, value string) {
+		if value != "" {
+			fmt.Fprintf(&sb, "[white::b]%-8s[-:-:-] %s\n", label+":", tview.Escape(value))
+		}
+	}
+	field("ID", j.ID)
+	field("Source", j.Source)
+	field("Queue", j.Queue)
+	if !j.StartedAt.IsZero() {
+		field("Started", j.StartedAt.Local().Format("2006-01-02 15:04:05"))
+	}
+	field("Elapsed", formatDurationCompact(j.elapsed(now)))
+	if j.Chunks > 0 {
+		field("Chunks", fmt.Sprintf("%d", j.Chunks))
+	}
+	if j.Error != "" {
+		fmt.Fprintf(&sb, "\n[red::b]Error[-:-:-]\n[red]%s[-]\n", tview.Escape(j.Error))
+	}
+	fmt.Fprintf(&sb, "\n[yellow::b]Payload[-:-:-] [gray](secrets redacted)[-]\n%s\n", formatJobJSON(j.Payload))
+	if j.Result != nil {
+		fmt.Fprintf(&sb, "\n[yellow::b]Result[-:-:-]\n%s\n", formatJobJSON(j.Result))
+	}
+	return sb.String()
+}
+
+// formatJobJSON pretty-prints a payload or result for the detail pane.
+func formatJobJSON(m map[string]any) string {
+	if m == nil {
+		return "[gray](not recorded)[-]"
+	}
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Sprintf("[red]%v[-]", err)
+	}
+	return tview.Escape(string(b))
+}
+
+// cancelSelected asks to cancel the selected in-flight job.
+func (p *JobsPage) cancelSelected() {
+	j, ok := p.selectedJob()
+	if !ok {
+		return
+	}
+	if !j.running() {
+		p.activityFn("warning", fmt.Sprintf("Job %s is not running", j.ID))
+		return
+	}
+	if p.cb.Cancel == nil {
+		p.activityFn("warning", "No worker is running in this process; cancel jobs from the node running them")
+		return
+	}
+	cancel := func() {
+		go func() {
+			if err := p.cb.Cancel(j.ID); err != nil {
+				p.activityFn("error", fmt.Sprintf("Cancel %s: %v", j.ID, err))
+				return
+			}
+			p.activityFn("info", fmt.Sprintf("Cancelling %s job %s...", j.Type, j.ID))
+			p.refreshData()
+		}()
+	}
+	if p.confirmFn == nil {
+		cancel()
+		return
+	}
+	p.confirmFn(fmt.Sprintf("Cancel %s job %s?\nIts handler gets a grace period to clean up.", j.Type, j.ID), "Cancel job", cancel)
+}
+
+// rerunSelected queues the selected failed job to run again on this node.
+func (p *JobsPage) rerunSelected() {
+	j, ok := p.selectedJob()
+	if !ok {
+		return
+	}
+	if j.Status != "failed" {
+		p.activityFn("warning", fmt.Sprintf("Only failed jobs can be re-run (%s is %s)", j.ID, j.Status))
+		return
+	}
+	if p.cb.Rerun == nil {
+		p.activityFn("warning", "No worker is running in this process; start it from the Dashboard to re-run jobs")
+		return
+	}
+	go func() {
+		newID, err := p.cb.Rerun(j.ID)
+		if err != nil {
+			p.activityFn("error", fmt.Sprintf("Re-run %s: %v", j.ID, err))
+			return
+		}
+		p.activityFn("info", fmt.Sprintf("Re-running %s job %s locally as %s", j.Type, j.ID, newID))
+		p.refreshData()
+	}()
+}
@@ -0,0 +1,132 @@
+package controlcenter
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// newTestJobsPage builds a Jobs page showing one running and one failed job,
+// with the running job selected. The app is dropped after Build: nothing
+// drains QueueUpdateDraw in a test, so refreshes only update page state.
+func newTestJobsPage(cb JobsCallbacks, confirmFn func(string, string, func())) (*JobsPage, *[]string) {
+	var activity []string
+	p := NewJobsPage(JobsPageConfig{
+		Callbacks:  cb,
+		ConfirmFn:  confirmFn,
+		ActivityFn: func(level, msg string) { activity = append(activity, level+": "+msg) },
+	})
+	p.Build(tview.NewApplication())
+	p.app = nil
+	p.inFlight = []JobEntry{{JobRecord: JobRecord{ID: "job-run", Type: "LLM_INFERENCE", Status: "running", StartedAt: time.Now()}}}
+	p.recent = []JobEntry{{JobRecord: JobRecord{ID: "job-bad", Type: "LLM_INFERENCE", Status: "failed", Error: "engine down"}}}
+	p.updateJobTable()
+	return p, &activity
+}
+
+func pressJobsKey(p *JobsPage, r rune) {
+	p.HandleInput(tcell.NewEventKey(tcell.KeyRune, r, tcell.ModNone))
+}
+
+func TestJobsPageCancelConfirmsThenCancels(t *testing.T) {
+	cancelled := make(chan string, 1)
+	var prompt string
+	var onConfirm func()
+	p, _ := newTestJobsPage(JobsCallbacks{
+		Cancel: func(id string) error { cancelled <- id; return nil },
+	}, func(msg, _ string, confirm func()) { prompt, onConfirm = msg, confirm })
+
+	pressJobsKey(p, '1')
+	if onConfirm == nil || !strings.Contains(prompt, "job-run") {
+		t.Fatalf("cancel did not ask for confirmation (prompt %q)", prompt)
+	}
+	select {
+	case <-cancelled:
+		t.Fatal("cancelled before confirmation")
+	default:
+	}
+
+	onConfirm()
+	select {
+	case id := <-cancelled:
+		if id != "job-run" {
+			t.Errorf("cancelled %q, want job-run", id)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Cancel was not called after confirmation")
+	}
+}
+
+func TestJobsPageActionsCheckJobStatus(t *testing.T) {
+	reran := make(chan string, 1)
+	p, activity := newTestJobsPage(JobsCallbacks{
+		Cancel: func(string) error { t.Error("cancelled a finished job"); return nil },
+		Rerun:  func(id string) (string, error) { reran <- id; return id + "-rerun-1", nil },
+	}, nil)
+
+	// The running job cannot be re-run.
+	pressJobsKey(p, '2')
+	if len(*activity) != 1 || !strings.Contains((*activity)[0], "Only failed jobs") {
+		t.Errorf("activity = %v, want a re-run warning", *activity)
+	}
+
+	// The failed job cannot be cancelled, but can be re-run.
+	p.jobTable.Select(2, 0)
+	pressJobsKey(p, '1')
+	pressJobsKey(p, '2')
+	select {
+	case id := <-reran:
+		if id != "job-bad" {
+			t.Errorf("re-ran %q, want job-bad", id)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Rerun was not called for the failed job")
+	}
+}
+
+func TestJobsPageWithoutWorkerExplains(t *testing.T) {
+	p, activity := newTestJobsPage(JobsCallbacks{}, nil)
+	pressJobsKey(p, '1')
+	p.jobTable.Select(2, 0)
+	pressJobsKey(p, '2')
+	if len(*activity) != 2 || !strings.Contains((*activity)[0], "No worker") || !strings.Contains((*activity)[1], "No worker") {
+		t.Errorf("activity = %v, want both actions to explain there is no worker", *activity)
+	}
+}
+
+func TestJobsPageKeepsSelectionAcrossRefresh(t *testing.T) {
+	p, _ := newTestJobsPage(JobsCallbacks{}, nil)
+	p.jobTable.Select(2, 0)
+
+	// The running job finishes: job-bad moves down a row.
+	p.inFlight = nil
+	p.recent = append([]JobEntry{{JobRecord: JobRecord{ID: "job-run", Status: "success"}}}, p.recent...)
+	p.updateJobTable()
+
+	if j, ok := p.selectedJob(); !ok || j.ID != "job-bad" {
+		t.Errorf("selected %+v, want job-bad to stay selected", j)
+	}
+}
+
+func TestFormatJobDetail(t *testing.T) {
+	j := JobEntry{
+		JobRecord: JobRecord{ID: "job-1", Type: "SHELL_COMMAND", Status: "failed", Error: "exit [1]"},
+		Source:    "redis",
+		Queue:     "jobs:v1:node",
+		Payload:   map[string]any{"command": "ls", "api_key": "[redacted]"},
+		Result:    map[string]any{"exit_code": float64(1)},
+	}
+	got := formatJobDetail(j, time.Now())
+	for _, want := range []string{"job-1", "jobs:v1:node", `"command": "ls"`, `"api_key": "[redacted[]"`, `"exit_code": 1`, "exit [1[]"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("detail missing %q:\n%s", want, got)
+		}
+	}
+
+	if got := formatJobDetail(JobEntry{JobRecord: JobRecord{ID: "job-2", Status: "running"}}, time.Now()); !strings.Contains(got, "(not recorded)") || strings.Contains(got, "Result") {
+		t.Errorf("detail without payload/result:\n%s", got)
+	}
+}
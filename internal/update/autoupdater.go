@@ -2,25 +2,38 @@
 // Opt-in periodic self-update for the long-lived Citadel agent.
 //
 // The AutoUpdater runs as a background goroutine launched by `citadel work`
-// when enabled. On each tick it checks GitHub Releases for a newer version,
-// and if one is found it downloads + checksum-verifies the binary, waits for
-// an idle moment (no in-flight jobs), atomically swaps the running binary, and
-// restarts the process so the new node-side capabilities take effect.
+// when enabled. On each tick it checks the release source (GitHub Releases or
+// a mirror, see source.go) for a newer version, and if one is found it
+// downloads + checksum-verifies the binary, waits for an idle moment (no
+// in-flight jobs), atomically swaps the running binary, and restarts the
+// process so the new node-side capabilities take effect.
 //
 // Design notes:
-//   - Reuses the existing Client (CheckForUpdate / DownloadAndVerify) and
+//   - Reuses the existing Client (CheckForUpdates / DownloadAndVerify) and
 //     ApplyUpdate / Rollback machinery so the release-asset naming and checksum
 //     contract is preserved.
 //   - This is a separate opt-in from the notify-only State.AutoUpdate gate used
 //     by root.go: auto-INSTALL must be explicitly enabled and defaults off.
 //   - Fail-safe: any error is reported via the logger and never panics or kills
 //     the agent. In-flight jobs are always drained before the swap.
+//   - Health-gated: every install leaves a pending-verification marker, so a
+//     release that restarts but never becomes healthy is rolled back by the
+//     new process (verify.go), and a version that failed is skipped here.
+//   - Signed: DownloadAndVerify also checks the release's detached signature
+//     (signature.go). A refusal is logged once and that release is not
+//     retried until a newer one is published.
+//   - Staged: a node in a rollout ring (rollout.go) holds a release back until
+//     it has soaked for the ring's delay and the node's slot in the spread.
 package update
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"os"
 	"time"
+
+	"github.com/aceteam-ai/citadel-cli/internal/events"
 )
 
 const (
@@ -37,9 +50,9 @@ const (
 // ReleaseChecker abstracts the "is there a newer release?" lookup so the loop
 // can be tested without hitting the network. *Client satisfies it.
 type ReleaseChecker interface {
-	// CheckForUpdate returns a non-nil Release if a strictly newer version is
-	// available, or (nil, nil) if already up to date.
-	CheckForUpdate() (*Release, error)
+	// CheckForUpdates returns the releases strictly newer than the running
+	// version, newest first, or none if already up to date.
+	CheckForUpdates() ([]*Release, error)
 	// DownloadAndVerify downloads the release asset for the current platform
 	// to destPath and verifies its checksum.
 	DownloadAndVerify(release *Release, destPath string) error
@@ -50,6 +63,10 @@ type AutoUpdaterConfig struct {
 	// Checker performs release lookups and downloads. Required.
 	Checker ReleaseChecker
 
+	// CurrentVersion is the running version, recorded as the rollback target
+	// of the pending-verification marker.
+	CurrentVersion string
+
 	// Interval between checks. Values below MinAutoUpdateInterval are clamped
 	// up to the floor. Zero uses DefaultAutoUpdateInterval.
 	Interval time.Duration
@@ -92,6 +109,31 @@ type AutoUpdaterConfig struct {
 	// Defaults to GetPendingBinaryPath().
 	PendingPath string
 
+	// SkipVersion reports whether a release must not be installed because it
+	// already failed verification on this node. Defaults to the failed-update
+	// history in State.
+	SkipVersion func(version string) bool
+
+	// MarkPending writes the pending-verification marker after a successful
+	// swap. Defaults to MarkPendingVerification with DefaultVerifyWindow.
+	MarkPending func(fromVersion, toVersion string) error
+
+	// Ring returns this node's rollout ring, consulted every tick so a ring
+	// change reaches a running agent. Defaults to ResolveRing.
+	Ring func() Ring
+
+	// NodeID seeds the node's position within its ring. Defaults to the
+	// hostname.
+	NodeID string
+
+	// Now is the clock for the rollout gate. Defaults to time.Now.
+	Now func() time.Time
+
+	// SignaturePolicy returns the release signature policy, consulted when a
+	// refused release comes up again so a relaxed policy retries it. Defaults
+	// to ResolveSignaturePolicy.
+	SignaturePolicy func() SignaturePolicy
+
 	// Log reports progress and errors. Required for visibility; if nil a no-op
 	// logger is used.
 	Log func(format string, args ...any)
@@ -100,6 +142,13 @@ type AutoUpdaterConfig struct {
 // AutoUpdater periodically checks for and applies updates.
 type AutoUpdater struct {
 	cfg AutoUpdaterConfig
+	// refused maps each release refused for its signature to the policy it
+	// was refused under, so it is not downloaded again every tick. A policy
+	// change (e.g. require to warn) retries it.
+	refused map[string]SignaturePolicy
+	// deferred is the last release held back by the rollout ring, so the
+	// wait is logged once rather than every tick.
+	deferred string
 }
 
 // NewAutoUpdater constructs an AutoUpdater, applying defaults and clamping the
@@ -126,6 +175,26 @@ func NewAutoUpdater(cfg AutoUpdaterConfig) *AutoUpdater {
 	if cfg.PendingPath == "" {
 		cfg.PendingPath = GetPendingBinaryPath()
 	}
+	if cfg.SkipVersion == nil {
+		cfg.SkipVersion = HasFailedVerification
+	}
+	if cfg.MarkPending == nil {
+		cfg.MarkPending = func(from, to string) error {
+			return MarkPendingVerification(from, to, DefaultVerifyWindow)
+		}
+	}
+	if cfg.Ring == nil {
+		cfg.Ring = ResolveRing
+	}
+	if cfg.NodeID == "" {
+		cfg.NodeID, _ = os.Hostname()
+	}
+	if cfg.Now == nil {
+		cfg.Now = time.Now
+	}
+	if cfg.SignaturePolicy == nil {
+		cfg.SignaturePolicy = ResolveSignaturePolicy
+	}
 	if cfg.Log == nil {
 		cfg.Log = func(string, ...any) {}
 	}
@@ -173,21 +242,35 @@ func (a *AutoUpdater) Run(ctx context.Context) {
 // replaced and not return). On any error it logs and returns false so the next
 // tick retries.
 func (a *AutoUpdater) runOnce(ctx context.Context) (restarted bool) {
-	release, err := a.cfg.Checker.CheckForUpdate()
+	releases, err := a.cfg.Checker.CheckForUpdates()
 	if err != nil {
 		a.cfg.Log("auto-update: check failed: %v", err)
 		return false
 	}
-	if release == nil {
+	if len(releases) == 0 {
 		a.cfg.Log("auto-update: up to date")
 		return false
 	}
 
+	policy := a.cfg.SignaturePolicy()
+	release := a.selectRelease(releases, policy)
+	if release == nil {
+		return false
+	}
+
 	a.cfg.Log("auto-update: new version available: %s, downloading...", release.TagName)
 
 	// Download + verify while jobs may still be running — this is the slow part
 	// and does not require an idle node.
 	if err := a.cfg.Checker.DownloadAndVerify(release, a.cfg.PendingPath); err != nil {
+		if errors.Is(err, ErrSignatureRefused) {
+			if a.refused == nil {
+				a.refused = make(map[string]SignaturePolicy)
+			}
+			a.refused[release.TagName] = policy
+			a.cfg.Log("auto-update: REFUSING %s: %v; staying on the current version", release.TagName, err)
+			return false
+		}
 		a.cfg.Log("auto-update: download/verify failed: %v", err)
 		return false
 	}
@@ -214,13 +297,27 @@ func (a *AutoUpdater) runOnce(ctx context.Context) (restarted bool) {
 	a.cfg.Log("auto-update: applied %s, restarting...", release.TagName)
 
 	// Record the update in persistent state for the new process / status cmd.
+	from := a.cfg.CurrentVersion
 	if state, serr := LoadState(); serr == nil {
-		RecordUpdate(state, state.CurrentVersion, release.TagName)
+		if from == "" {
+			from = state.CurrentVersion
+		}
+		RecordUpdate(state, from, release.TagName)
 		state.AvailableUpdate = ""
 		UpdateLastCheck(state)
 		_ = SaveState(state)
 	}
 
+	// The new process must prove itself healthy or roll itself back. Without
+	// the marker a bad release would only be caught by an operator.
+	if err := a.cfg.MarkPending(from, release.TagName); err != nil {
+		a.cfg.Log("auto-update: could not arm post-update verification: %v", err)
+	}
+
+	// Published before the restart: a webhook delivery for it is persisted to
+	// the outbox and sent by the new process.
+	events.Publish(events.TypeUpdateApplied, map[string]any{"from": from, "to": release.TagName})
+
 	if err := a.cfg.Restart(); err != nil {
 		// If restart fails the new binary is already in place; the supervisor
 		// (systemd Restart=always / Windows SCM) will pick it up on the next
@@ -231,23 +328,67 @@ func (a *AutoUpdater) runOnce(ctx context.Context) (restarted bool) {
 	return true
 }
 
+// selectRelease returns the newest of releases (newest first) this node may
+// install now, or nil. Releases that failed verification here or were refused
+// under the current signature policy are passed over, and so is one still
+// soaking for the node's rollout ring: when releases ship faster than the
+// ring's delay the newest never soaks long enough, so an older one whose
+// window has opened is installed instead.
+func (a *AutoUpdater) selectRelease(releases []*Release, policy SignaturePolicy) *Release {
+	ring := a.cfg.Ring()
+	now := a.cfg.Now()
+	var waiting *Release
+	var waitingAt time.Time
+	for _, release := range releases {
+		if a.cfg.SkipVersion(release.TagName) {
+			a.cfg.Log("auto-update: skipping %s: it failed verification on this node and was rolled back", release.TagName)
+			continue
+		}
+		if p, ok := a.refused[release.TagName]; ok && p == policy {
+			continue
+		}
+		if ring == RingNone {
+			return release
+		}
+		at := EligibleAt(ring, a.cfg.NodeID, release.TagName, releaseAnchor(release, now))
+		if !now.Before(at) {
+			return release
+		}
+		if waiting == nil {
+			waiting, waitingAt = release, at
+		}
+	}
+	if waiting != nil && a.deferred != waiting.TagName {
+		a.deferred = waiting.TagName
+		a.cfg.Log("auto-update: %s is available; %s ring installs it after %s",
+			waiting.TagName, ring, waitingAt.Local().Format(time.RFC3339))
+	}
+	return nil
+}
+
 // waitForIdle blocks until ActiveJobs reports 0, ctx is cancelled, or the idle
 // timeout elapses.
 func (a *AutoUpdater) waitForIdle(ctx context.Context) error {
-	if a.cfg.ActiveJobs == nil {
+	return waitForIdle(ctx, a.cfg.ActiveJobs, a.cfg.IdlePollInterval, a.cfg.IdleTimeout)
+}
+
+// waitForIdle blocks until activeJobs reports 0, ctx is cancelled, or timeout
+// elapses. A nil activeJobs is always idle.
+func waitForIdle(ctx context.Context, activeJobs func() int, poll, timeout time.Duration) error {
+	if activeJobs == nil {
 		return nil
 	}
-	deadline := time.Now().Add(a.cfg.IdleTimeout)
-	ticker := time.NewTicker(a.cfg.IdlePollInterval)
+	deadline := time.Now().Add(timeout)
+	ticker := time.NewTicker(poll)
 	defer ticker.Stop()
 
 	for {
-		if a.cfg.ActiveJobs() == 0 {
+		if activeJobs() == 0 {
 			return nil
 		}
 		if time.Now().After(deadline) {
 			return fmt.Errorf("timed out after %s waiting for %d in-flight job(s) to finish",
-				a.cfg.IdleTimeout, a.cfg.ActiveJobs())
+				timeout, activeJobs())
 		}
 		select {
 		case <-ctx.Done():
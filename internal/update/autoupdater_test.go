@@ -4,6 +4,7 @@ package update
 import (
 	"context"
 	"errors"
+	"fmt"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -11,24 +12,34 @@ import (
 )
 
 // fakeChecker is an in-memory ReleaseChecker for tests. No network.
+// It offers releases (newest first) or, when that is empty, release alone.
 type fakeChecker struct {
 	release      *Release
+	releases     []*Release
 	checkErr     error
 	downloadErr  error
 	checkCalls   int32
 	downloadHits int32
+	downloaded   []string
 }
 
-func (f *fakeChecker) CheckForUpdate() (*Release, error) {
+func (f *fakeChecker) CheckForUpdates() ([]*Release, error) {
 	atomic.AddInt32(&f.checkCalls, 1)
 	if f.checkErr != nil {
 		return nil, f.checkErr
 	}
-	return f.release, nil
+	if len(f.releases) > 0 {
+		return f.releases, nil
+	}
+	if f.release == nil {
+		return nil, nil
+	}
+	return []*Release{f.release}, nil
 }
 
 func (f *fakeChecker) DownloadAndVerify(release *Release, destPath string) error {
 	atomic.AddInt32(&f.downloadHits, 1)
+	f.downloaded = append(f.downloaded, release.TagName)
 	return f.downloadErr
 }
 
@@ -129,17 +140,19 @@ func TestRunOnce_DownloadError_NoApply(t *testing.T) {
 }
 
 func TestRunOnce_HappyPath_DrainsAppliesRestarts(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
 	var order []string
 	var mu sync.Mutex
 	add := func(s string) { mu.Lock(); order = append(order, s); mu.Unlock() }
 
 	drained := false
 	u := NewAutoUpdater(AutoUpdaterConfig{
-		Checker:    &fakeChecker{release: &Release{TagName: "v9.9.9"}},
-		ActiveJobs: func() int { return 0 }, // idle immediately
-		Drain:      func() { drained = true; add("drain") },
-		Apply:      func(string) error { add("apply"); return nil },
-		Restart:    func() error { add("restart"); return nil },
+		Checker:        &fakeChecker{release: &Release{TagName: "v9.9.9"}},
+		CurrentVersion: "v1.0.0",
+		ActiveJobs:     func() int { return 0 }, // idle immediately
+		Drain:          func() { drained = true; add("drain") },
+		Apply:          func(string) error { add("apply"); return nil },
+		Restart:        func() error { add("restart"); return nil },
 	})
 
 	if !u.runOnce(context.Background()) {
@@ -148,6 +161,11 @@ func TestRunOnce_HappyPath_DrainsAppliesRestarts(t *testing.T) {
 	if !drained {
 		t.Error("expected drain to be called")
 	}
+	// The restart must find a verification marker for the new version.
+	p, err := LoadPendingVerification()
+	if err != nil || p == nil || p.ToVersion != "v9.9.9" || p.FromVersion != "v1.0.0" {
+		t.Fatalf("pending verification = %+v, %v; want v1.0.0 -> v9.9.9", p, err)
+	}
 	want := []string{"drain", "apply", "restart"}
 	if len(order) != len(want) {
 		t.Fatalf("call order = %v, want %v", order, want)
@@ -159,7 +177,130 @@ func TestRunOnce_HappyPath_DrainsAppliesRestarts(t *testing.T) {
 	}
 }
 
+func TestRunOnce_SkipsFailedVersion(t *testing.T) {
+	checker := &fakeChecker{release: &Release{TagName: "v9.9.9"}}
+	u := NewAutoUpdater(AutoUpdaterConfig{
+		Checker:     checker,
+		SkipVersion: func(v string) bool { return v == "v9.9.9" },
+		Apply:       func(string) error { t.Fatal("a failed version must not be applied"); return nil },
+	})
+	if u.runOnce(context.Background()) {
+		t.Fatal("skipped version must not restart")
+	}
+	if atomic.LoadInt32(&checker.downloadHits) != 0 {
+		t.Fatal("skipped version must not be downloaded")
+	}
+}
+
+func TestRunOnce_SignatureRefused_NotRetried(t *testing.T) {
+	checker := &fakeChecker{
+		release:     &Release{TagName: "v9.9.9"},
+		downloadErr: fmt.Errorf("%w: bad signature", ErrSignatureRefused),
+	}
+	policy := SignatureRequire
+	u := NewAutoUpdater(AutoUpdaterConfig{
+		Checker:         checker,
+		SkipVersion:     func(string) bool { return false },
+		Apply:           func(string) error { t.Fatal("a refused release must not be applied"); return nil },
+		SignaturePolicy: func() SignaturePolicy { return policy },
+	})
+	for i := 0; i < 2; i++ {
+		if u.runOnce(context.Background()) {
+			t.Fatal("refused release must not restart")
+		}
+	}
+	if n := atomic.LoadInt32(&checker.downloadHits); n != 1 {
+		t.Fatalf("download attempts = %d, want 1 (refusal remembered)", n)
+	}
+
+	// Relaxing the policy retries the same release.
+	policy = SignatureWarn
+	u.runOnce(context.Background())
+	if n := atomic.LoadInt32(&checker.downloadHits); n != 2 {
+		t.Fatalf("download attempts = %d, want 2 (retried after a policy change)", n)
+	}
+
+	checker.release = &Release{TagName: "v9.9.10"}
+	checker.downloadErr = errors.New("network down")
+	u.runOnce(context.Background())
+	if n := atomic.LoadInt32(&checker.downloadHits); n != 3 {
+		t.Fatalf("download attempts = %d, want 3 (newer release tried)", n)
+	}
+}
+
+func TestRunOnce_RingDefersUntilEligible(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	published := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	checker := &fakeChecker{release: &Release{TagName: "v9.9.9", PublishedAt: published}}
+	now := published.Add(time.Hour)
+	u := NewAutoUpdater(AutoUpdaterConfig{
+		Checker:     checker,
+		SkipVersion: func(string) bool { return false },
+		Ring:        func() Ring { return RingEarly },
+		NodeID:      "node-1",
+		Now:         func() time.Time { return now },
+		Apply:       func(string) error { return nil },
+		Restart:     func() error { return nil },
+	})
+	if u.runOnce(context.Background()) {
+		t.Fatal("early ring installed an hour after publication")
+	}
+	if atomic.LoadInt32(&checker.downloadHits) != 0 {
+		t.Fatal("a deferred release must not be downloaded")
+	}
+
+	now = EligibleAt(RingEarly, "node-1", "v9.9.9", published)
+	if !u.runOnce(context.Background()) {
+		t.Fatal("release not installed once the node's slot arrived")
+	}
+}
+
+func TestRunOnce_RingInstallsNewestEligibleRelease(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	// Two releases 12h apart: when the older one's early-ring window has
+	// opened the newer one is still soaking, and it always will be if
+	// releases keep shipping at that pace.
+	older := &Release{TagName: "v9.9.8", PublishedAt: time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)}
+	newer := &Release{TagName: "v9.9.9", PublishedAt: older.PublishedAt.Add(12 * time.Hour)}
+	now := EligibleAt(RingEarly, "node-1", older.TagName, older.PublishedAt)
+	if !now.Before(EligibleAt(RingEarly, "node-1", newer.TagName, newer.PublishedAt)) {
+		t.Fatal("test setup: the newer release must still be soaking")
+	}
+	checker := &fakeChecker{releases: []*Release{newer, older}}
+	var marked string
+	u := NewAutoUpdater(AutoUpdaterConfig{
+		Checker:     checker,
+		SkipVersion: func(string) bool { return false },
+		MarkPending: func(_, to string) error { marked = to; return nil },
+		Ring:        func() Ring { return RingEarly },
+		NodeID:      "node-1",
+		Now:         func() time.Time { return now },
+		Apply:       func(string) error { return nil },
+		Restart:     func() error { return nil },
+	})
+	if !u.runOnce(context.Background()) {
+		t.Fatal("the eligible older release was not installed")
+	}
+	if len(checker.downloaded) != 1 || checker.downloaded[0] != older.TagName {
+		t.Fatalf("downloaded %v, want only %s", checker.downloaded, older.TagName)
+	}
+	if marked != older.TagName {
+		t.Fatalf("marked %q pending, want %s", marked, older.TagName)
+	}
+
+	// Before the older release's window nothing is installed.
+	checker.downloaded = nil
+	now = older.PublishedAt.Add(time.Hour)
+	if u.runOnce(context.Background()) {
+		t.Fatal("installed a release before any ring window opened")
+	}
+	if len(checker.downloaded) != 0 {
+		t.Fatalf("downloaded %v while every release was soaking", checker.downloaded)
+	}
+}
+
 func TestRunOnce_DrainsBeforeApply_WaitsForIdle(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
 	// active starts at 1, drops to 0 after Drain is called: verifies the
 	// updater waits for in-flight work and only applies once idle.
 	var active int32 = 1
@@ -195,6 +336,7 @@ func TestRunOnce_DrainsBeforeApply_WaitsForIdle(t *testing.T) {
 }
 
 func TestRunOnce_IdleTimeout_DefersUpdate(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
 	applied := false
 	u := NewAutoUpdater(AutoUpdaterConfig{
 		Checker:          &fakeChecker{release: &Release{TagName: "v9.9.9"}},
@@ -214,6 +356,7 @@ func TestRunOnce_IdleTimeout_DefersUpdate(t *testing.T) {
 }
 
 func TestRunOnce_ApplyError_NoRestart(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
 	restarted := false
 	u := NewAutoUpdater(AutoUpdaterConfig{
 		Checker:    &fakeChecker{release: &Release{TagName: "v9.9.9"}},
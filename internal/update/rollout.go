@@ -0,0 +1,117 @@
+// internal/update/rollout.go
+// Staged rollout rings for automatic updates.
+//
+// Without rings every auto-updating node installs a release within one check
+// interval of its publication, so a bad release reaches the whole fleet at
+// once. A node assigned to a ring only installs a release once it has soaked
+// for the ring's delay, and within the ring nodes are spread over a window by
+// a deterministic hash of node ID and release tag: every node computes its
+// own slot without coordination, the same node always gets the same slot for
+// a release, and a different release reshuffles who goes first.
+//
+//	canary  installs immediately
+//	early   after 1 day, spread over the next day
+//	broad   after 3 days, spread over the next 2 days
+//
+// Combined with health-gated rollback (verify.go), a release that breaks
+// canaries is rolled back there and, if pulled or superseded, never reaches
+// the later rings. Rings gate only the AutoUpdater; `citadel update install`
+// and AGENT_UPDATE are explicit and install immediately.
+package update
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Ring is a rollout ring. The zero value is no ring: install immediately, as
+// before rings existed.
+type Ring string
+
+const (
+	RingNone   Ring = ""
+	RingCanary Ring = "canary"
+	RingEarly  Ring = "early"
+	RingBroad  Ring = "broad"
+)
+
+// RingSchedule is when a ring installs a release, relative to its publication.
+type RingSchedule struct {
+	// Delay is the soak before any node in the ring installs.
+	Delay time.Duration
+	// Spread is the window after Delay over which the ring's nodes install.
+	Spread time.Duration
+}
+
+// RingSchedules are the built-in ring schedules.
+var RingSchedules = map[Ring]RingSchedule{
+	RingNone:   {},
+	RingCanary: {},
+	RingEarly:  {Delay: 24 * time.Hour, Spread: 24 * time.Hour},
+	RingBroad:  {Delay: 72 * time.Hour, Spread: 48 * time.Hour},
+}
+
+// ParseRing validates a ring name. "" and "none" mean no ring.
+func ParseRing(s string) (Ring, error) {
+	r := Ring(strings.ToLower(strings.TrimSpace(s)))
+	if r == "none" {
+		return RingNone, nil
+	}
+	if _, ok := RingSchedules[r]; !ok {
+		return RingNone, fmt.Errorf("invalid rollout ring %q (want canary, early, broad or none)", s)
+	}
+	return r, nil
+}
+
+// ResolveRing returns this node's ring: CITADEL_UPDATE_RING > State.Ring.
+// An invalid value yields the most conservative ring rather than none.
+func ResolveRing() Ring {
+	raw := os.Getenv("CITADEL_UPDATE_RING")
+	if strings.TrimSpace(raw) == "" {
+		if state, err := LoadState(); err == nil {
+			raw = state.Ring
+		}
+	}
+	r, err := ParseRing(raw)
+	if err != nil {
+		return RingBroad
+	}
+	return r
+}
+
+// rolloutFraction maps (nodeID, tag) to a stable position in [0, 1).
+func rolloutFraction(nodeID, tag string) float64 {
+	sum := sha256.Sum256([]byte(nodeID + "\x00" + strings.TrimPrefix(tag, "v")))
+	return float64(binary.BigEndian.Uint64(sum[:8])>>11) / (1 << 53)
+}
+
+// EligibleAt returns when a node in ring may install a release published (or
+// first seen) at published.
+func EligibleAt(ring Ring, nodeID, tag string, published time.Time) time.Time {
+	s := RingSchedules[ring]
+	offset := s.Delay + time.Duration(rolloutFraction(nodeID, tag)*float64(s.Spread))
+	return published.Add(offset)
+}
+
+// releaseAnchor returns the time the rollout soak is measured from: the
+// release's publication time, or for sources that omit it, when this node
+// first saw the release (persisted so restarts do not reset the soak).
+func releaseAnchor(release *Release, now time.Time) time.Time {
+	if !release.PublishedAt.IsZero() {
+		return release.PublishedAt
+	}
+	state, err := LoadState()
+	if err != nil {
+		return now
+	}
+	if state.SeenRelease != release.TagName || state.SeenReleaseAt.IsZero() {
+		state.SeenRelease = release.TagName
+		state.SeenReleaseAt = now
+		_ = SaveState(state)
+	}
+	return state.SeenReleaseAt
+}
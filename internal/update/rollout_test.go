@@ -0,0 +1,93 @@
+// internal/update/rollout_test.go
+package update
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestParseRing(t *testing.T) {
+	for in, want := range map[string]Ring{"": RingNone, "none": RingNone, "Canary": RingCanary, " early ": RingEarly, "broad": RingBroad} {
+		if got, err := ParseRing(in); err != nil || got != want {
+			t.Errorf("ParseRing(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	if _, err := ParseRing("beta"); err == nil {
+		t.Error("unknown ring accepted")
+	}
+}
+
+func TestResolveRing(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("CITADEL_UPDATE_RING", "")
+	if got := ResolveRing(); got != RingNone {
+		t.Errorf("default = %q, want none", got)
+	}
+	state, _ := LoadState()
+	state.Ring = "early"
+	if err := SaveState(state); err != nil {
+		t.Fatal(err)
+	}
+	if got := ResolveRing(); got != RingEarly {
+		t.Errorf("from state = %q, want early", got)
+	}
+	t.Setenv("CITADEL_UPDATE_RING", "typo")
+	if got := ResolveRing(); got != RingBroad {
+		t.Errorf("invalid value = %q, want broad (most conservative)", got)
+	}
+}
+
+func TestEligibleAt(t *testing.T) {
+	published := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	if got := EligibleAt(RingCanary, "node-1", "v2.0.0", published); !got.Equal(published) {
+		t.Errorf("canary eligible at %v, want publication", got)
+	}
+
+	// Every node in a ring lands inside [delay, delay+spread), and the slot is
+	// stable for a node and release.
+	for _, ring := range []Ring{RingEarly, RingBroad} {
+		s := RingSchedules[ring]
+		var first, last time.Duration = s.Delay + s.Spread, 0
+		for i := 0; i < 200; i++ {
+			node := fmt.Sprintf("node-%d", i)
+			off := EligibleAt(ring, node, "v2.0.0", published).Sub(published)
+			if off < s.Delay || off >= s.Delay+s.Spread {
+				t.Fatalf("%s/%s: offset %v outside [%v, %v)", ring, node, off, s.Delay, s.Delay+s.Spread)
+			}
+			if again := EligibleAt(ring, node, "2.0.0", published).Sub(published); again != off {
+				t.Fatalf("%s/%s: slot not stable (%v vs %v)", ring, node, off, again)
+			}
+			first, last = min(first, off), max(last, off)
+		}
+		if last-first < s.Spread/2 {
+			t.Errorf("%s: 200 nodes spread over only %v of %v", ring, last-first, s.Spread)
+		}
+	}
+
+	// Rings are ordered: the last early node goes before the first broad one.
+	early := RingSchedules[RingEarly]
+	if early.Delay+early.Spread > RingSchedules[RingBroad].Delay {
+		t.Error("early ring overlaps broad ring")
+	}
+}
+
+func TestReleaseAnchorFallsBackToFirstSeen(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	published := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	if got := releaseAnchor(&Release{TagName: "v2.0.0", PublishedAt: published}, time.Now()); !got.Equal(published) {
+		t.Errorf("anchor = %v, want published_at", got)
+	}
+
+	seen := time.Date(2026, 10, 2, 0, 0, 0, 0, time.UTC)
+	rel := &Release{TagName: "v2.0.1"}
+	if got := releaseAnchor(rel, seen); !got.Equal(seen) {
+		t.Errorf("first sighting anchor = %v, want %v", got, seen)
+	}
+	if got := releaseAnchor(rel, seen.Add(time.Hour)); !got.Equal(seen) {
+		t.Errorf("later sighting anchor = %v, want the persisted first sighting %v", got, seen)
+	}
+	if got := releaseAnchor(&Release{TagName: "v2.0.2"}, seen.Add(2*time.Hour)); !got.Equal(seen.Add(2 * time.Hour)) {
+		t.Errorf("new release anchor = %v, want its own first sighting", got)
+	}
+}
@@ -0,0 +1,217 @@
+// internal/update/signature_test.go
+package update
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// testMinisignKey returns a fresh key pair and its minisign public key line.
+func testMinisignKey(t *testing.T) (ed25519.PrivateKey, []byte, string) {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyID := []byte{1, 2, 3, 4, 5, 6, 7, 8}
+	raw := append(append([]byte("Ed"), keyID...), pub...)
+	return priv, keyID, base64.StdEncoding.EncodeToString(raw)
+}
+
+// minisignSign produces a minisign signature file, as `minisign -S` would.
+func minisignSign(priv ed25519.PrivateKey, keyID []byte, data []byte, prehash bool) []byte {
+	alg, msg := "Ed", data
+	if prehash {
+		h := blake2b.Sum512(data)
+		alg, msg = "ED", h[:]
+	}
+	sig := ed25519.Sign(priv, msg)
+	trusted := "timestamp:1700000000\tfile:citadel.tar.gz"
+	global := ed25519.Sign(priv, append(append([]byte{}, sig...), trusted...))
+	blob := append(append([]byte(alg), keyID...), sig...)
+	return []byte("untrusted comment: signature from minisign secret key\n" +
+		base64.StdEncoding.EncodeToString(blob) + "\n" +
+		"trusted comment: " + trusted + "\n" +
+		base64.StdEncoding.EncodeToString(global) + "\n")
+}
+
+func TestVerifyMinisign(t *testing.T) {
+	priv, keyID, pub := testMinisignKey(t)
+	_, otherID, otherPub := testMinisignKey(t)
+	otherID[0] = 9
+	data := []byte("archive bytes")
+
+	for _, prehash := range []bool{false, true} {
+		sig := minisignSign(priv, keyID, data, prehash)
+		if err := verifyMinisign([]string{pub}, data, sig); err != nil {
+			t.Errorf("prehash=%v: valid signature rejected: %v", prehash, err)
+		}
+		if err := verifyMinisign([]string{pub}, []byte("tampered"), sig); err == nil {
+			t.Errorf("prehash=%v: tampered archive accepted", prehash)
+		}
+	}
+
+	sig := minisignSign(priv, keyID, data, true)
+	tampered := strings.Replace(string(sig), "timestamp:1700000000", "timestamp:1800000000", 1)
+	if err := verifyMinisign([]string{pub}, data, []byte(tampered)); err == nil {
+		t.Error("tampered trusted comment accepted")
+	}
+
+	raw, _ := base64.StdEncoding.DecodeString(otherPub)
+	copy(raw[2:10], otherID)
+	if err := verifyMinisign([]string{base64.StdEncoding.EncodeToString(raw)}, data, sig); err == nil ||
+		!strings.Contains(err.Error(), "unknown key") {
+		t.Errorf("signature by an untrusted key: err = %v, want unknown key", err)
+	}
+
+	if err := verifyMinisign([]string{pub}, data, []byte("garbage")); err == nil {
+		t.Error("malformed signature accepted")
+	}
+}
+
+func TestParseSignaturePolicy(t *testing.T) {
+	for in, want := range map[string]SignaturePolicy{
+		"": DefaultSignaturePolicy, "off": SignatureOff, " Warn ": SignatureWarn, "REQUIRE": SignatureRequire,
+	} {
+		if got, err := ParseSignaturePolicy(in); err != nil || got != want {
+			t.Errorf("ParseSignaturePolicy(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	if _, err := ParseSignaturePolicy("strict"); err == nil {
+		t.Error("invalid policy accepted")
+	}
+}
+
+func TestResolveSignaturePolicy(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("CITADEL_UPDATE_SIGNATURE", "")
+	if got := ResolveSignaturePolicy(); got != DefaultSignaturePolicy {
+		t.Errorf("default = %q, want %q", got, DefaultSignaturePolicy)
+	}
+
+	state, _ := LoadState()
+	state.SignaturePolicy = "require"
+	if err := SaveState(state); err != nil {
+		t.Fatal(err)
+	}
+	if got := ResolveSignaturePolicy(); got != SignatureRequire {
+		t.Errorf("from state = %q, want require", got)
+	}
+
+	t.Setenv("CITADEL_UPDATE_SIGNATURE", "off")
+	if got := ResolveSignaturePolicy(); got != SignatureOff {
+		t.Errorf("env override = %q, want off", got)
+	}
+
+	t.Setenv("CITADEL_UPDATE_SIGNATURE", "bogus")
+	if got := ResolveSignaturePolicy(); got != SignatureRequire {
+		t.Errorf("invalid value = %q, want require (fail closed)", got)
+	}
+}
+
+// signatureFixture serves a release's signature assets and writes its archive.
+func signatureFixture(t *testing.T, assets map[string][]byte) (*Client, *Release, string) {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, ok := assets[strings.TrimPrefix(r.URL.Path, "/")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(data)
+	}))
+	t.Cleanup(server.Close)
+
+	client := NewClient("v1.0.0")
+	release := &Release{TagName: "v2.0.0"}
+	archive := client.getBinaryArchiveName(release)
+	release.Assets = append(release.Assets, Asset{Name: archive, BrowserDownloadURL: server.URL + "/" + archive})
+	for name := range assets {
+		release.Assets = append(release.Assets, Asset{Name: name, BrowserDownloadURL: server.URL + "/" + name})
+	}
+	archivePath := filepath.Join(t.TempDir(), "pending.archive")
+	if err := os.WriteFile(archivePath, []byte("archive bytes"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return client, release, archivePath
+}
+
+func TestVerifyArchiveSignaturePolicies(t *testing.T) {
+	priv, keyID, pub := testMinisignKey(t)
+	old := ReleaseSigningKeys
+	ReleaseSigningKeys = pub
+	t.Cleanup(func() { ReleaseSigningKeys = old })
+	t.Setenv("CITADEL_UPDATE_PUBLIC_KEY", "")
+
+	archiveName := NewClient("").getBinaryArchiveName(&Release{TagName: "v2.0.0"})
+	good := minisignSign(priv, keyID, []byte("archive bytes"), true)
+	bad := minisignSign(priv, keyID, []byte("other bytes"), true)
+
+	tests := []struct {
+		name    string
+		policy  SignaturePolicy
+		minisig []byte
+		refused bool
+		warned  bool
+	}{
+		{"valid signature", SignatureRequire, good, false, false},
+		{"invalid signature under warn", SignatureWarn, bad, true, false},
+		{"unsigned under warn", SignatureWarn, nil, false, true},
+		{"unsigned under require", SignatureRequire, nil, true, false},
+		{"invalid signature under off", SignatureOff, bad, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assets := map[string][]byte{}
+			if tt.minisig != nil {
+				assets[archiveName+".minisig"] = tt.minisig
+			}
+			client, release, archivePath := signatureFixture(t, assets)
+			var warned bool
+			client.WithWarnf(func(string, ...any) { warned = true })
+
+			minisig, err := client.fetchAsset(release, archiveName+".minisig")
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = client.verifyArchiveSignature(archivePath, archiveName, minisig, tt.policy)
+			if refused := errors.Is(err, ErrSignatureRefused); refused != tt.refused {
+				t.Errorf("err = %v, refused = %v, want %v", err, refused, tt.refused)
+			}
+			if warned != tt.warned {
+				t.Errorf("warned = %v, want %v", warned, tt.warned)
+			}
+		})
+	}
+}
+
+func TestVerifyArchiveSignatureWithoutTrustedKey(t *testing.T) {
+	priv, keyID, _ := testMinisignKey(t)
+	old := ReleaseSigningKeys
+	ReleaseSigningKeys = ""
+	t.Cleanup(func() { ReleaseSigningKeys = old })
+	t.Setenv("CITADEL_UPDATE_PUBLIC_KEY", "")
+
+	archiveName := NewClient("").getBinaryArchiveName(&Release{TagName: "v2.0.0"})
+	client, release, archivePath := signatureFixture(t, map[string][]byte{
+		archiveName + ".minisig": minisignSign(priv, keyID, []byte("archive bytes"), true),
+	})
+	minisig, err := client.fetchAsset(release, archiveName+".minisig")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.verifyArchiveSignature(archivePath, archiveName, minisig, SignatureRequire); !errors.Is(err, ErrSignatureRefused) ||
+		!strings.Contains(err.Error(), "no release signing key") {
+		t.Errorf("err = %v, want refusal naming the missing key", err)
+	}
+}
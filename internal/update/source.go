@@ -0,0 +1,318 @@
+// internal/update/source.go
+// Configurable release source: GitHub Releases (the default), an HTTP mirror,
+// or a local directory.
+//
+// A mirror serves the same Release/Asset JSON the GitHub API returns:
+//
+//	<source>/latest.json            the newest release
+//	<source>/<tag>/release.json     a specific release (pinned installs)
+//	<source>/<tag>/<asset>          archives, checksums.txt, signatures
+//
+// Asset URLs in the JSON may be absolute, relative to the JSON document, or
+// empty (meaning <source>/<tag>/<name>), so a mirror directory can be copied
+// anywhere. `citadel update mirror` writes this layout from GitHub for
+// air-gapped sites. Mirrored archives still pass the checksum and signature
+// checks, so a mirror is not a trust root.
+package update
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// SourceGitHub explicitly selects GitHub Releases, overriding a configured
+// mirror.
+const SourceGitHub = "github"
+
+// NormalizeSource canonicalizes a release source: "" and "github" mean GitHub
+// (returned as ""), http(s) and file URLs are kept, and a local path becomes a
+// file:// URL.
+func NormalizeSource(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || strings.EqualFold(s, SourceGitHub) {
+		return "", nil
+	}
+	if u, err := url.Parse(s); err == nil && (u.Scheme == "http" || u.Scheme == "https" || u.Scheme == "file") {
+		if u.Host == "" && u.Scheme != "file" {
+			return "", fmt.Errorf("invalid release source %q: missing host", s)
+		}
+		return strings.TrimRight(s, "/"), nil
+	}
+	abs, err := filepath.Abs(s)
+	if err != nil {
+		return "", fmt.Errorf("invalid release source %q: %w", s, err)
+	}
+	p := filepath.ToSlash(abs)
+	if !strings.HasPrefix(p, "/") {
+		p = "/" + p // Windows drive path: file:///C:/...
+	}
+	return "file://" + strings.TrimRight(p, "/"), nil
+}
+
+// ResolveReleaseSource returns the configured source:
+// CITADEL_UPDATE_SOURCE > State.Source > GitHub (""). An invalid value is
+// reported rather than silently falling back to GitHub, which an air-gapped
+// node cannot reach anyway.
+func ResolveReleaseSource() (string, error) {
+	raw := os.Getenv("CITADEL_UPDATE_SOURCE")
+	if strings.TrimSpace(raw) == "" {
+		if state, err := LoadState(); err == nil {
+			raw = state.Source
+		}
+	}
+	return NormalizeSource(raw)
+}
+
+// source returns the client's release source ("" for GitHub).
+func (c *Client) source() (string, error) {
+	if c.Source != "" {
+		return NormalizeSource(c.Source)
+	}
+	return ResolveReleaseSource()
+}
+
+// fetchReleaseJSON decodes a Release document from a mirror and resolves its
+// asset URLs.
+func (c *Client) fetchReleaseJSON(docURL, tag string) (*Release, error) {
+	resp, err := c.httpClient.Get(docURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %w", docURL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		if tag != "" {
+			return nil, fmt.Errorf("release %s not found in mirror", tag)
+		}
+		return nil, fmt.Errorf("mirror has no %s", docURL)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("mirror returned status: %s", resp.Status)
+	}
+	var release Release
+	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
+		return nil, fmt.Errorf("invalid release JSON at %s: %w", docURL, err)
+	}
+	if release.TagName == "" {
+		return nil, fmt.Errorf("release JSON at %s has no tag_name", docURL)
+	}
+	src, _ := c.source()
+	resolveAssetURLs(&release, src, docURL)
+	return &release, nil
+}
+
+// resolveAssetURLs makes every asset URL absolute: empty URLs map to
+// <src>/<tag>/<name>, relative ones resolve against the JSON document.
+func resolveAssetURLs(release *Release, src, docURL string) {
+	base, _ := url.Parse(docURL)
+	for i := range release.Assets {
+		a := &release.Assets[i]
+		if a.BrowserDownloadURL == "" {
+			a.BrowserDownloadURL = src + "/" + url.PathEscape(release.TagName) + "/" + url.PathEscape(a.Name)
+			continue
+		}
+		if u, err := url.Parse(a.BrowserDownloadURL); err == nil && !u.IsAbs() && base != nil {
+			a.BrowserDownloadURL = base.ResolveReference(u).String()
+		}
+	}
+}
+
+// assetURL returns where to download the named asset of release: the URL the
+// release lists for it, else the source's conventional path.
+func (c *Client) assetURL(release *Release, name string) string {
+	for _, a := range release.Assets {
+		if a.Name == name && a.BrowserDownloadURL != "" {
+			return a.BrowserDownloadURL
+		}
+	}
+	if src, err := c.source(); err == nil && src != "" {
+		return src + "/" + url.PathEscape(release.TagName) + "/" + url.PathEscape(name)
+	}
+	return fmt.Sprintf("%s/%s/releases/download/%s/%s",
+		GitHubDownloadBase, GitHubRepo, release.TagName, name)
+}
+
+// localSourceDir returns the directory of a local (file://) release source, or
+// "" when the source is GitHub or an HTTP mirror.
+func (c *Client) localSourceDir() string {
+	src, err := c.source()
+	if err != nil || !strings.HasPrefix(src, "file://") {
+		return ""
+	}
+	u, err := url.Parse(src)
+	if err != nil {
+		return ""
+	}
+	return localPath(u.Path)
+}
+
+// localPath turns a file URL path into an OS path.
+func localPath(p string) string {
+	if runtime.GOOS == "windows" && len(p) > 2 && p[0] == '/' && p[2] == ':' {
+		p = p[1:]
+	}
+	return filepath.Clean(filepath.FromSlash(p))
+}
+
+// fileTransport serves file:// URLs so a local directory works as a source
+// through the same HTTP code path. A missing file is a 404.
+//
+// Asset URLs come from the release JSON, so a remote manifest could otherwise
+// name file:///etc/shadow and have it downloaded (and mirrored). root returns
+// the configured local source directory; a file URL is only served when the
+// source is local and the file lies under it.
+type fileTransport struct {
+	root func() string
+}
+
+func (t fileTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	root := ""
+	if t.root != nil {
+		root = t.root()
+	}
+	p := localPath(req.URL.Path)
+	if root == "" {
+		return nil, fmt.Errorf("refusing %s: file URLs are only read from a local release source", req.URL)
+	}
+	if rel, err := filepath.Rel(root, p); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return nil, fmt.Errorf("refusing %s: outside the release source %s", req.URL, root)
+	}
+	resp := &http.Response{
+		Proto: "HTTP/1.0", ProtoMajor: 1,
+		Header:  make(http.Header),
+		Request: req,
+	}
+	f, err := os.Open(p)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return nil, err
+		}
+		resp.StatusCode, resp.Status = http.StatusNotFound, "404 Not Found"
+		resp.Body = io.NopCloser(strings.NewReader(""))
+		return resp, nil
+	}
+	if info, err := f.Stat(); err == nil {
+		if info.IsDir() {
+			f.Close()
+			return nil, fmt.Errorf("%s is a directory", p)
+		}
+		resp.ContentLength = info.Size()
+	}
+	resp.StatusCode, resp.Status = http.StatusOK, "200 OK"
+	resp.Body = f
+	return resp, nil
+}
+
+// newHTTPClient returns the update client's HTTP client. It reads file:// URLs
+// under localRoot (see fileTransport) and follows redirects only to http(s),
+// never from https down to http: an HTTP mirror cannot bounce a download onto
+// a local file, but one that moved to https still works.
+func newHTTPClient(timeout time.Duration, localRoot func() string) *http.Client {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.RegisterProtocol("file", fileTransport{root: localRoot})
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: t,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 10 {
+				return fmt.Errorf("stopped after 10 redirects")
+			}
+			from, to := via[len(via)-1].URL.Scheme, req.URL.Scheme
+			if to != "http" && to != "https" {
+				return fmt.Errorf("refusing redirect from %s to %s: not an http(s) URL", from, to)
+			}
+			if from == "https" && to == "http" {
+				return fmt.Errorf("refusing redirect from https to http: downgrade")
+			}
+			return nil
+		},
+	}
+}
+
+// MirrorRelease copies every asset of release from the client's source into
+// dir/<tag>/ and writes dir/<tag>/release.json with relocatable (empty) asset
+// URLs. When latest is true it also writes dir/latest.json. Existing files of
+// the right size are kept, so an interrupted mirror resumes.
+func (c *Client) MirrorRelease(release *Release, dir string, latest bool, logf func(format string, args ...any)) error {
+	if logf == nil {
+		logf = func(string, ...any) {}
+	}
+	if release.TagName == "" || release.TagName != filepath.Base(release.TagName) {
+		return fmt.Errorf("refusing to mirror release with unsafe tag %q", release.TagName)
+	}
+	tagDir := filepath.Join(dir, release.TagName)
+	if err := os.MkdirAll(tagDir, 0755); err != nil {
+		return err
+	}
+	out := *release
+	out.Assets = nil
+	for _, a := range release.Assets {
+		if a.Name != filepath.Base(a.Name) || a.Name == "release.json" {
+			return fmt.Errorf("refusing to mirror asset with unsafe name %q", a.Name)
+		}
+		dst := filepath.Join(tagDir, a.Name)
+		if info, err := os.Stat(dst); err == nil && a.Size > 0 && info.Size() == a.Size {
+			logf("%s: already mirrored", a.Name)
+		} else {
+			logf("%s: downloading", a.Name)
+			if err := c.downloadTo(c.assetURL(release, a.Name), dst); err != nil {
+				return fmt.Errorf("mirror %s: %w", a.Name, err)
+			}
+		}
+		a.BrowserDownloadURL = ""
+		out.Assets = append(out.Assets, a)
+	}
+	data, err := json.MarshalIndent(&out, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(filepath.Join(tagDir, "release.json"), data); err != nil {
+		return err
+	}
+	if latest {
+		return writeFileAtomic(filepath.Join(dir, "latest.json"), data)
+	}
+	return nil
+}
+
+// downloadTo fetches url into dst via a temp file.
+func (c *Client) downloadTo(url, dst string) error {
+	resp, err := c.httpClient.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("download failed with status: %s", resp.Status)
+	}
+	tmp := dst + ".part"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, resp.Body); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, dst)
+}
+
+func writeFileAtomic(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
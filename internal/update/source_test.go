@@ -0,0 +1,200 @@
+// internal/update/source_test.go
+package update
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNormalizeSource(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		in, want string
+		wantErr  bool
+	}{
+		{"", "", false},
+		{"GitHub", "", false},
+		{"https://mirror.example/citadel/", "https://mirror.example/citadel", false},
+		{"file:///srv/releases", "file:///srv/releases", false},
+		{dir, "file://" + filepath.ToSlash(dir), false},
+		{"https:///nohost", "", true},
+	}
+	for _, tt := range tests {
+		got, err := NormalizeSource(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("NormalizeSource(%q) = %q, %v; want %q (err %v)", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+// writeMirror lays out a one-release mirror in dir.
+func writeMirror(t *testing.T, dir string, release Release, files map[string]string) {
+	t.Helper()
+	tagDir := filepath.Join(dir, release.TagName)
+	if err := os.MkdirAll(tagDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, body := range files {
+		if err := os.WriteFile(filepath.Join(tagDir, name), []byte(body), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	data, _ := json.Marshal(release)
+	for _, p := range []string{filepath.Join(dir, "latest.json"), filepath.Join(tagDir, "release.json")} {
+		if err := os.WriteFile(p, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestLocalDirectorySource(t *testing.T) {
+	dir := t.TempDir()
+	writeMirror(t, dir, Release{
+		TagName: "v2.0.0",
+		Assets:  []Asset{{Name: "checksums.txt"}, {Name: "notes.txt", BrowserDownloadURL: "v2.0.0/notes.txt"}},
+	}, map[string]string{"checksums.txt": "abc  citadel.tar.gz\n", "notes.txt": "hi"})
+
+	client := NewClient("v1.0.0").WithSource(dir)
+	release, err := client.CheckForUpdate()
+	if err != nil || release == nil || release.TagName != "v2.0.0" {
+		t.Fatalf("CheckForUpdate = %+v, %v; want v2.0.0", release, err)
+	}
+	for _, name := range []string{"checksums.txt", "notes.txt"} {
+		data, err := client.fetchAsset(release, name)
+		if err != nil || len(data) == 0 {
+			t.Errorf("fetchAsset(%s) = %q, %v", name, data, err)
+		}
+	}
+	if data, err := client.fetchAsset(release, "missing.minisig"); err != nil || data != nil {
+		t.Errorf("unlisted asset = %q, %v; want nil, nil", data, err)
+	}
+
+	if releases, err := client.CheckForUpdates(); err != nil || len(releases) != 1 || releases[0].TagName != "v2.0.0" {
+		t.Errorf("CheckForUpdates = %v, %v; want [v2.0.0]", releases, err)
+	}
+	if releases, err := NewClient("v2.0.0").WithSource(dir).CheckForUpdates(); err != nil || len(releases) != 0 {
+		t.Errorf("CheckForUpdates on the latest = %v, %v; want none", releases, err)
+	}
+
+	pinned, err := client.GetReleaseByTag("2.0.0")
+	if err != nil || pinned.TagName != "v2.0.0" {
+		t.Fatalf("GetReleaseByTag = %+v, %v", pinned, err)
+	}
+	if _, err := client.GetReleaseByTag("v9.0.0"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("missing tag: err = %v, want not found", err)
+	}
+}
+
+func TestHTTPMirrorSource(t *testing.T) {
+	dir := t.TempDir()
+	writeMirror(t, dir, Release{TagName: "v2.0.0", Assets: []Asset{{Name: "checksums.txt"}}},
+		map[string]string{"checksums.txt": "sums"})
+	server := httptest.NewServer(http.FileServer(http.Dir(dir)))
+	defer server.Close()
+
+	client := NewClient("v1.0.0").WithSource(server.URL + "/")
+	release, err := client.CheckForUpdate()
+	if err != nil || release == nil {
+		t.Fatalf("CheckForUpdate = %+v, %v", release, err)
+	}
+	if got, want := release.Assets[0].BrowserDownloadURL, server.URL+"/v2.0.0/checksums.txt"; got != want {
+		t.Errorf("asset URL = %q, want %q", got, want)
+	}
+	if got := client.getChecksumURL(release); !strings.HasPrefix(got, server.URL) {
+		t.Errorf("checksum URL %q does not use the mirror", got)
+	}
+}
+
+func TestMirrorReleaseRoundTrip(t *testing.T) {
+	upstream := t.TempDir()
+	writeMirror(t, upstream, Release{TagName: "v2.0.0", Assets: []Asset{{Name: "checksums.txt", Size: 4}}},
+		map[string]string{"checksums.txt": "sums"})
+	src := NewClient("v1.0.0").WithSource(upstream)
+	release, err := src.GetLatestRelease()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mirror := t.TempDir()
+	if err := src.MirrorRelease(release, mirror, true, nil); err != nil {
+		t.Fatalf("MirrorRelease: %v", err)
+	}
+	got, err := NewClient("v1.0.0").WithSource(mirror).GetLatestRelease()
+	if err != nil || got.TagName != "v2.0.0" {
+		t.Fatalf("mirrored latest = %+v, %v", got, err)
+	}
+	if !strings.HasPrefix(got.Assets[0].BrowserDownloadURL, "file://") {
+		t.Errorf("mirrored asset URL %q should resolve into the mirror", got.Assets[0].BrowserDownloadURL)
+	}
+
+	bad := *release
+	bad.TagName = "../escape"
+	if err := src.MirrorRelease(&bad, mirror, false, nil); err == nil {
+		t.Error("unsafe tag accepted")
+	}
+}
+
+func TestFileURLsOnlyFromALocalSource(t *testing.T) {
+	secret := filepath.Join(t.TempDir(), "secret")
+	if err := os.WriteFile(secret, []byte("key"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	secretURL, _ := NormalizeSource(secret)
+
+	// A remote manifest naming a local file.
+	dir := t.TempDir()
+	writeMirror(t, dir, Release{TagName: "v2.0.0", Assets: []Asset{{Name: "checksums.txt", BrowserDownloadURL: secretURL}}}, nil)
+	server := httptest.NewServer(http.FileServer(http.Dir(dir)))
+	defer server.Close()
+	remote := NewClient("v1.0.0").WithSource(server.URL)
+	release, err := remote.GetLatestRelease()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := remote.MirrorRelease(release, t.TempDir(), false, nil); err == nil || !strings.Contains(err.Error(), "local release source") {
+		t.Errorf("MirrorRelease = %v; an HTTP source's manifest must not read a local file", err)
+	}
+
+	// A local source may not reach outside its own directory either.
+	local := t.TempDir()
+	writeMirror(t, local, Release{TagName: "v2.0.0", Assets: []Asset{{Name: "checksums.txt", BrowserDownloadURL: secretURL}}}, nil)
+	client := NewClient("v1.0.0").WithSource(local)
+	release, err = client.GetLatestRelease()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.MirrorRelease(release, t.TempDir(), false, nil); err == nil || !strings.Contains(err.Error(), "outside the release source") {
+		t.Errorf("MirrorRelease = %v; a local source must not read outside its directory", err)
+	}
+}
+
+func TestRedirectMayNotLeaveHTTPS(t *testing.T) {
+	server := httptest.NewServer(http.RedirectHandler("file:///etc/passwd", http.StatusFound))
+	defer server.Close()
+	client := NewClient("v1.0.0").WithSource(server.URL)
+	if _, err := client.GetLatestRelease(); err == nil || !strings.Contains(err.Error(), "not an http(s) URL") {
+		t.Errorf("GetLatestRelease = %v, want a refused redirect", err)
+	}
+
+	check := newHTTPClient(time.Second, func() string { return "" }).CheckRedirect
+	for _, tt := range []struct {
+		from, to string
+		ok       bool
+	}{
+		{"http://mirror.example/latest.json", "https://mirror.example/latest.json", true},
+		{"https://mirror.example/latest.json", "https://cdn.example/latest.json", true},
+		{"https://mirror.example/latest.json", "http://mirror.example/latest.json", false},
+		{"http://mirror.example/latest.json", "file:///etc/passwd", false},
+	} {
+		via := []*http.Request{httptest.NewRequest(http.MethodGet, tt.from, nil)}
+		if err := check(httptest.NewRequest(http.MethodGet, tt.to, nil), via); (err == nil) != tt.ok {
+			t.Errorf("redirect %s -> %s: err = %v, want allowed=%v", tt.from, tt.to, err, tt.ok)
+		}
+	}
+}
@@ -19,6 +19,20 @@ type State struct {
 	LastUpdate      time.Time `json:"last_update,omitzero"`
 	AutoUpdate      bool      `json:"auto_update"`
 	Channel         string    `json:"channel"` // "stable" or "rc"
+	// FailedUpdates are versions rolled back after failing post-install
+	// verification (see verify.go); the auto-updater skips them.
+	FailedUpdates []FailedUpdate `json:"failed_updates,omitempty"`
+	// SignaturePolicy is "off", "warn" or "require" (signature.go); empty
+	// means the default.
+	SignaturePolicy string `json:"signature_policy,omitempty"`
+	// Source is the release source (source.go); empty means GitHub.
+	Source string `json:"source,omitempty"`
+	// Ring is this node's rollout ring (rollout.go); empty means none.
+	Ring string `json:"ring,omitempty"`
+	// SeenRelease/SeenReleaseAt record when the newest release was first
+	// seen, the soak anchor for sources that publish no published_at.
+	SeenRelease   string    `json:"seen_release,omitempty"`
+	SeenReleaseAt time.Time `json:"seen_release_at,omitzero"`
 }
 
 // DefaultCheckInterval is the minimum time between update checks
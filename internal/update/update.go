@@ -15,6 +15,7 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"strings"
 	"time"
 
@@ -40,6 +41,8 @@ type Release struct {
 	Prerelease bool    `json:"prerelease"`
 	Assets     []Asset `json:"assets"`
 	HTMLURL    string  `json:"html_url"`
+	// PublishedAt anchors the rollout-ring soak (rollout.go).
+	PublishedAt time.Time `json:"published_at,omitzero"`
 }
 
 // Asset represents a release asset
@@ -53,7 +56,18 @@ type Asset struct {
 type Client struct {
 	CurrentVersion string
 	Channel        string // "stable" or "rc"
-	httpClient     *http.Client
+	// Source is the release source (source.go): a mirror URL, a local
+	// directory, or SourceGitHub. Empty resolves the configured source on
+	// every request.
+	Source string
+	// SignaturePolicy governs unsigned/unverifiable releases (signature.go).
+	// Empty resolves it on every download, so a changed setting reaches a
+	// running agent.
+	SignaturePolicy SignaturePolicy
+	// Warnf reports a release installed without a verified signature under
+	// the "warn" policy. Defaults to stderr.
+	Warnf      func(format string, args ...any)
+	httpClient *http.Client
 }
 
 // NewClient creates a new update client with default timeout (30s)
@@ -63,13 +77,12 @@ func NewClient(currentVersion string) *Client {
 
 // NewClientWithTimeout creates a new update client with a custom timeout
 func NewClientWithTimeout(currentVersion string, timeout time.Duration) *Client {
-	return &Client{
+	c := &Client{
 		CurrentVersion: currentVersion,
 		Channel:        "stable",
-		httpClient: &http.Client{
-			Timeout: timeout,
-		},
 	}
+	c.httpClient = newHTTPClient(timeout, c.localSourceDir)
+	return c
 }
 
 // WithChannel sets the release channel
@@ -78,6 +91,24 @@ func (c *Client) WithChannel(channel string) *Client {
 	return c
 }
 
+// WithSource sets the release source
+func (c *Client) WithSource(source string) *Client {
+	c.Source = source
+	return c
+}
+
+// WithSignaturePolicy sets the release signature policy
+func (c *Client) WithSignaturePolicy(policy SignaturePolicy) *Client {
+	c.SignaturePolicy = policy
+	return c
+}
+
+// WithWarnf sets where signature warnings are reported
+func (c *Client) WithWarnf(warnf func(format string, args ...any)) *Client {
+	c.Warnf = warnf
+	return c
+}
+
 // CheckForUpdate checks if a new version is available
 // Returns nil if already on latest version
 func (c *Client) CheckForUpdate() (*Release, error) {
@@ -99,6 +130,39 @@ func (c *Client) CheckForUpdate() (*Release, error) {
 	return release, nil
 }
 
+// CheckForUpdates returns every published release newer than the current
+// version, newest first, or nil if already on the latest. The auto-updater
+// walks this list so a rollout ring still updates when releases ship faster
+// than the ring's soak. A mirror only publishes latest.json, so from a mirror
+// this is at most that one release.
+func (c *Client) CheckForUpdates() ([]*Release, error) {
+	releases, err := c.fetchReleases()
+	if err != nil {
+		return nil, err
+	}
+
+	var newer []*Release
+	for _, release := range releases {
+		hasUpdate, err := c.isNewerVersion(release.TagName)
+		if err != nil {
+			// One malformed tag in the listing must not hide the others.
+			continue
+		}
+		if hasUpdate {
+			newer = append(newer, release)
+		}
+	}
+	slices.SortStableFunc(newer, func(a, b *Release) int {
+		av, aerr := version.NewVersion(strings.TrimPrefix(a.TagName, "v"))
+		bv, berr := version.NewVersion(strings.TrimPrefix(b.TagName, "v"))
+		if aerr != nil || berr != nil {
+			return 0
+		}
+		return bv.Compare(av)
+	})
+	return newer, nil
+}
+
 // GetLatestRelease fetches the latest release info without version comparison
 func (c *Client) GetLatestRelease() (*Release, error) {
 	return c.fetchLatestRelease()
@@ -117,6 +181,14 @@ func (c *Client) GetReleaseByTag(tag string) (*Release, error) {
 		tag = "v" + tag
 	}
 
+	src, err := c.source()
+	if err != nil {
+		return nil, err
+	}
+	if src != "" {
+		return c.fetchReleaseJSON(src+"/"+tag+"/release.json", tag)
+	}
+
 	url := fmt.Sprintf("%s/repos/%s/releases/tags/%s", GitHubAPIBase, GitHubRepo, tag)
 
 	req, err := http.NewRequest("GET", url, nil)
@@ -248,7 +320,9 @@ func (c *Client) VerifyChecksum(filePath string, release *Release) error {
 	return nil
 }
 
-// DownloadAndVerify downloads and verifies the binary in one step
+// DownloadAndVerify downloads and verifies the binary in one step: checksum,
+// then detached signature per the client's SignaturePolicy. Signature
+// refusals wrap ErrSignatureRefused.
 func (c *Client) DownloadAndVerify(release *Release, destPath string) error {
 	downloadURL := c.getDownloadURL(release)
 
@@ -256,6 +330,21 @@ func (c *Client) DownloadAndVerify(release *Release, destPath string) error {
 		return fmt.Errorf("failed to create update directory: %w", err)
 	}
 
+	// The signature is fetched first so that an unsigned release under the
+	// "require" policy is refused without downloading the archive.
+	policy := c.signaturePolicy()
+	archive := c.getBinaryArchiveName(release)
+	var minisig []byte
+	if policy != SignatureOff {
+		var err error
+		if minisig, err = c.fetchAsset(release, archive+".minisig"); err != nil {
+			return err
+		}
+		if policy == SignatureRequire && minisig == nil {
+			return fmt.Errorf("%w: %s: release has no signature (signature policy is require)", ErrSignatureRefused, archive)
+		}
+	}
+
 	// Download archive
 	archivePath := destPath + ".archive"
 	resp, err := c.httpClient.Get(downloadURL)
@@ -285,6 +374,10 @@ func (c *Client) DownloadAndVerify(release *Release, destPath string) error {
 		os.Remove(archivePath)
 		return err
 	}
+	if err := c.verifyArchiveSignature(archivePath, archive, minisig, policy); err != nil {
+		os.Remove(archivePath)
+		return err
+	}
 
 	// Extract binary
 	if err := c.extractBinary(archivePath, destPath); err != nil {
@@ -307,6 +400,14 @@ func (c *Client) DownloadAndVerify(release *Release, destPath string) error {
 
 // fetchLatestRelease fetches the latest release from GitHub
 func (c *Client) fetchLatestRelease() (*Release, error) {
+	src, err := c.source()
+	if err != nil {
+		return nil, err
+	}
+	if src != "" {
+		return c.fetchReleaseJSON(src+"/latest.json", "")
+	}
+
 	url := fmt.Sprintf("%s/repos/%s/releases/latest", GitHubAPIBase, GitHubRepo)
 
 	req, err := http.NewRequest("GET", url, nil)
@@ -334,6 +435,58 @@ func (c *Client) fetchLatestRelease() (*Release, error) {
 	return &release, nil
 }
 
+// releaseListPageSize is how many recent releases CheckForUpdates inspects.
+// A ring's soak is at most a few days, so this covers far more releases than
+// could ever be waiting.
+const releaseListPageSize = 30
+
+// fetchReleases lists recent published releases. Drafts and prereleases are
+// left out, as GitHub's "latest" leaves them out.
+func (c *Client) fetchReleases() ([]*Release, error) {
+	src, err := c.source()
+	if err != nil {
+		return nil, err
+	}
+	if src != "" {
+		release, err := c.fetchReleaseJSON(src+"/latest.json", "")
+		if err != nil {
+			return nil, err
+		}
+		return []*Release{release}, nil
+	}
+
+	url := fmt.Sprintf("%s/repos/%s/releases?per_page=%d", GitHubAPIBase, GitHubRepo, releaseListPageSize)
+
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github.v3+json")
+	req.Header.Set("User-Agent", "citadel-cli")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GitHub API returned status: %s", resp.Status)
+	}
+
+	var all []*Release
+	if err := json.NewDecoder(resp.Body).Decode(&all); err != nil {
+		return nil, err
+	}
+	releases := all[:0]
+	for _, release := range all {
+		if release != nil && !release.Draft && !release.Prerelease {
+			releases = append(releases, release)
+		}
+	}
+	return releases, nil
+}
+
 // IsNewerVersion reports whether candidate is a strictly newer semver than
 // current. Both values may optionally carry a leading "v" prefix. The "dev"
 // and empty current versions are always considered outdated (returns true).
@@ -407,15 +560,12 @@ func ShouldAutoInstall(currentVersion string, noAutoUpdateFlag bool, noAutoUpdat
 
 // getDownloadURL constructs the download URL for the current platform
 func (c *Client) getDownloadURL(release *Release) string {
-	binaryName := c.getBinaryArchiveName(release)
-	return fmt.Sprintf("%s/%s/releases/download/%s/%s",
-		GitHubDownloadBase, GitHubRepo, release.TagName, binaryName)
+	return c.assetURL(release, c.getBinaryArchiveName(release))
 }
 
 // getChecksumURL returns the URL to the checksums.txt file
 func (c *Client) getChecksumURL(release *Release) string {
-	return fmt.Sprintf("%s/%s/releases/download/%s/checksums.txt",
-		GitHubDownloadBase, GitHubRepo, release.TagName)
+	return c.assetURL(release, "checksums.txt")
 }
 
 // getBinaryArchiveName returns the archive filename for the current platform
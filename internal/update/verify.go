@@ -0,0 +1,411 @@
+// internal/update/verify.go
+// Health-gated verification of a freshly-installed binary.
+//
+// ApplyUpdate only proves the new binary runs `citadel version`. A release can
+// pass that and still be unable to do its job on this node (mesh never comes
+// up, job source rejects it, status server fails to bind), leaving the node
+// dark until someone SSHes in. So every automatic install (AutoUpdater and
+// the AGENT_UPDATE handler) writes a "pending verification" marker next to
+// the update state. When the new version next starts as `citadel work`:
+//
+//  1. BeginVerification counts the boot. A marker that has already outlived
+//     its window, or a binary that keeps restarting before it passes (a crash
+//     loop under the service manager), is rolled back immediately.
+//  2. Otherwise RunVerifier polls the caller's health bar until every check
+//     passes (marker cleared: the update is verified) or the window elapses
+//     (roll back to the previous binary, record the version as failed, and
+//     re-exec onto the restored binary).
+//
+// Failed versions are recorded in State so the updater skips them on later
+// ticks instead of reinstalling the same broken release every hour.
+package update
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultVerifyWindow is how long a freshly-installed version has, from its
+	// first start, to reach the health bar.
+	DefaultVerifyWindow = 10 * time.Minute
+
+	// MaxVerifyBoots is how many times the new version may start without
+	// passing before it is treated as crash-looping and rolled back at startup.
+	MaxVerifyBoots = 3
+
+	// maxFailedUpdates bounds the failed-version history kept in State.
+	maxFailedUpdates = 10
+)
+
+// PendingVerification is the on-disk marker for an installed-but-unverified
+// version.
+type PendingVerification struct {
+	FromVersion string    `json:"from_version"`
+	ToVersion   string    `json:"to_version"`
+	AppliedAt   time.Time `json:"applied_at"`
+	// WindowSeconds is the verification window, measured from FirstBootAt so a
+	// binary installed without an immediate restart is not failed for the gap.
+	WindowSeconds int       `json:"window_seconds"`
+	FirstBootAt   time.Time `json:"first_boot_at,omitzero"`
+	Boots         int       `json:"boots"`
+}
+
+// Deadline is when the new version must have passed by. Zero before the first
+// boot.
+func (p *PendingVerification) Deadline() time.Time {
+	if p.FirstBootAt.IsZero() {
+		return time.Time{}
+	}
+	return p.FirstBootAt.Add(time.Duration(p.WindowSeconds) * time.Second)
+}
+
+// FailedUpdate records a version that was rolled back after failing
+// verification.
+type FailedUpdate struct {
+	Version string    `json:"version"`
+	Reason  string    `json:"reason"`
+	At      time.Time `json:"at"`
+}
+
+// GetVerifyFilePath returns the path to the pending-verification marker.
+func GetVerifyFilePath() string {
+	return filepath.Join(GetUpdateDir(), "verify.json")
+}
+
+// MarkPendingVerification records that toVersion was just installed over
+// fromVersion and must pass verification within window of its first start.
+func MarkPendingVerification(fromVersion, toVersion string, window time.Duration) error {
+	if window <= 0 {
+		window = DefaultVerifyWindow
+	}
+	return savePendingVerification(&PendingVerification{
+		FromVersion:   fromVersion,
+		ToVersion:     toVersion,
+		AppliedAt:     time.Now(),
+		WindowSeconds: int(window / time.Second),
+	})
+}
+
+// LoadPendingVerification returns the marker, or nil when no update is
+// awaiting verification.
+func LoadPendingVerification() (*PendingVerification, error) {
+	data, err := os.ReadFile(GetVerifyFilePath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var p PendingVerification
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("corrupt verification marker %s: %w", GetVerifyFilePath(), err)
+	}
+	return &p, nil
+}
+
+// ClearPendingVerification removes the marker. A missing marker is not an
+// error.
+func ClearPendingVerification() error {
+	if err := os.Remove(GetVerifyFilePath()); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func savePendingVerification(p *PendingVerification) error {
+	if err := EnsureUpdateDir(); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(GetVerifyFilePath(), data, 0644)
+}
+
+// VerifyAction is BeginVerification's decision.
+type VerifyAction int
+
+const (
+	// VerifyNone: nothing is pending for this version.
+	VerifyNone VerifyAction = iota
+	// VerifyWatch: run the health bar (RunVerifier) until it passes or the
+	// window closes.
+	VerifyWatch
+	// VerifyRollback: the version has already failed (window elapsed across
+	// restarts, or too many boots); roll back now.
+	VerifyRollback
+)
+
+// BeginVerification is called once when the agent starts. It counts the boot
+// against a marker for version and decides what to do. A marker for another
+// version is stale (e.g. the operator installed something else by hand) and
+// is cleared, except when this is still the old version, which happens when a
+// binary was installed without a restart; the marker then waits for the new
+// version's first start.
+func BeginVerification(version string, now time.Time) (*PendingVerification, VerifyAction, error) {
+	p, err := LoadPendingVerification()
+	if err != nil {
+		// An unreadable marker cannot gate anything; drop it rather than
+		// failing every start.
+		_ = ClearPendingVerification()
+		return nil, VerifyNone, err
+	}
+	if p == nil {
+		return nil, VerifyNone, nil
+	}
+	if !sameVersion(p.ToVersion, version) {
+		if !sameVersion(p.FromVersion, version) {
+			_ = ClearPendingVerification()
+		}
+		return nil, VerifyNone, nil
+	}
+
+	p.Boots++
+	if p.FirstBootAt.IsZero() {
+		p.FirstBootAt = now
+	}
+	if err := savePendingVerification(p); err != nil {
+		return p, VerifyWatch, fmt.Errorf("record verification boot: %w", err)
+	}
+	if p.Boots > MaxVerifyBoots || now.After(p.Deadline()) {
+		return p, VerifyRollback, nil
+	}
+	return p, VerifyWatch, nil
+}
+
+// HealthCheck is one item of the verification health bar. Check returns nil
+// once the item is healthy, or an error describing what is still missing.
+type HealthCheck struct {
+	Name  string
+	Check func() error
+}
+
+// VerifierConfig configures RunVerifier.
+type VerifierConfig struct {
+	// Pending is the marker returned by BeginVerification. Required.
+	Pending *PendingVerification
+
+	// Checks is the health bar; all must pass at the same time.
+	Checks []HealthCheck
+
+	// PollInterval is how often the checks are evaluated. Zero uses 5s.
+	PollInterval time.Duration
+
+	// Rollback restores the previous binary. Defaults to Rollback.
+	Rollback func() error
+
+	// Restart re-execs onto the restored binary. Defaults to RestartProcess.
+	Restart func() error
+
+	// ActiveJobs reports the number of in-flight jobs. A failed verification
+	// waits for it to reach 0 before restarting, so running jobs finish and
+	// are acked rather than dying with the process. If nil, the node is always
+	// considered idle.
+	ActiveJobs func() int
+
+	// Drain, if set, stops the runner fetching new jobs before the wait.
+	Drain func()
+
+	// IdlePollInterval is how often ActiveJobs is re-checked. Zero uses 2s.
+	IdlePollInterval time.Duration
+
+	// IdleTimeout bounds the wait for in-flight jobs. Unlike the updater, a
+	// failed verification restarts anyway when it elapses: the rollback is
+	// already on disk and the broken version must not keep serving. Zero uses
+	// 10m.
+	IdleTimeout time.Duration
+
+	// Log reports progress. If nil a no-op logger is used.
+	Log func(format string, args ...any)
+
+	// Now is the clock. Defaults to time.Now.
+	Now func() time.Time
+}
+
+func (c *VerifierConfig) withDefaults() {
+	if c.PollInterval <= 0 {
+		c.PollInterval = 5 * time.Second
+	}
+	if c.Rollback == nil {
+		c.Rollback = Rollback
+	}
+	if c.Restart == nil {
+		c.Restart = RestartProcess
+	}
+	if c.IdlePollInterval <= 0 {
+		c.IdlePollInterval = 2 * time.Second
+	}
+	if c.IdleTimeout <= 0 {
+		c.IdleTimeout = 10 * time.Minute
+	}
+	if c.Log == nil {
+		c.Log = func(string, ...any) {}
+	}
+	if c.Now == nil {
+		c.Now = time.Now
+	}
+}
+
+// RunVerifier evaluates the health bar until it passes or the marker's
+// deadline passes, whichever is first. It returns true when the update was
+// verified. On failure it rolls back, drains in-flight jobs (bounded by
+// IdleTimeout) and restarts; it only returns (false) if that restart fails or
+// ctx is cancelled first. A cancelled drain still leaves the restored binary
+// for the next start.
+func RunVerifier(ctx context.Context, cfg VerifierConfig) bool {
+	cfg.withDefaults()
+	p := cfg.Pending
+	cfg.Log("update verification: %s must pass the health bar by %s (boot %d/%d)",
+		p.ToVersion, p.Deadline().Local().Format(time.Kitchen), p.Boots, MaxVerifyBoots)
+
+	ticker := time.NewTicker(cfg.PollInterval)
+	defer ticker.Stop()
+	for {
+		failing := failingChecks(cfg.Checks)
+		if len(failing) == 0 {
+			if err := ClearPendingVerification(); err != nil {
+				cfg.Log("update verification: %s passed but the marker could not be cleared: %v", p.ToVersion, err)
+			} else {
+				cfg.Log("update verification: %s is healthy; update verified", p.ToVersion)
+			}
+			return true
+		}
+		if cfg.Now().After(p.Deadline()) {
+			reason := "health bar not reached within " +
+				(time.Duration(p.WindowSeconds) * time.Second).String() + ": " + strings.Join(failing, "; ")
+			RollBackFailedUpdate(p, reason, cfg.Rollback, cfg.Log)
+			if cfg.Drain != nil {
+				cfg.Drain()
+			}
+			if err := waitForIdle(ctx, cfg.ActiveJobs, cfg.IdlePollInterval, cfg.IdleTimeout); err != nil {
+				if ctx.Err() != nil {
+					return false
+				}
+				cfg.Log("update verification: %v; restarting onto %s anyway", err, p.FromVersion)
+			}
+			if err := cfg.Restart(); err != nil {
+				cfg.Log("update verification: restart onto %s failed: %v (it loads on the next restart)", p.FromVersion, err)
+			}
+			return false
+		}
+		select {
+		case <-ctx.Done():
+			return false
+		case <-ticker.C:
+		}
+	}
+}
+
+// failingChecks returns "name: reason" for every check that does not pass.
+func failingChecks(checks []HealthCheck) []string {
+	var out []string
+	for _, c := range checks {
+		if err := c.Check(); err != nil {
+			out = append(out, c.Name+": "+err.Error())
+		}
+	}
+	return out
+}
+
+// RollBackFailedUpdate restores the previous binary, records p.ToVersion as
+// failed so the updater skips it, and clears the marker. The marker is cleared
+// even if the restore fails: retrying on every start could not succeed either,
+// and the failed-version record still stops the updater from reinstalling it.
+func RollBackFailedUpdate(p *PendingVerification, reason string, rollback func() error, logf func(format string, args ...any)) {
+	if rollback == nil {
+		rollback = Rollback
+	}
+	if logf == nil {
+		logf = func(string, ...any) {}
+	}
+	logf("update verification: %s failed (%s); rolling back to %s", p.ToVersion, reason, p.FromVersion)
+	rbErr := rollback()
+	if rbErr != nil {
+		logf("update verification: rollback failed: %v", rbErr)
+		reason += " (rollback failed: " + rbErr.Error() + ")"
+	}
+	if state, err := LoadState(); err == nil {
+		RecordFailedUpdate(state, p.ToVersion, reason, time.Now())
+		if rbErr == nil {
+			state.CurrentVersion = p.FromVersion
+			state.PreviousVersion = p.ToVersion
+		}
+		if err := SaveState(state); err != nil {
+			logf("update verification: could not record failed version %s: %v", p.ToVersion, err)
+		}
+	}
+	if err := ClearPendingVerification(); err != nil {
+		logf("update verification: could not clear marker: %v", err)
+	}
+}
+
+// RecordFailedUpdate adds version to the failed-update history, replacing an
+// older entry for the same version and keeping the newest maxFailedUpdates.
+func RecordFailedUpdate(state *State, version, reason string, at time.Time) {
+	kept := state.FailedUpdates[:0]
+	for _, f := range state.FailedUpdates {
+		if !sameVersion(f.Version, version) {
+			kept = append(kept, f)
+		}
+	}
+	kept = append(kept, FailedUpdate{Version: version, Reason: reason, At: at})
+	if len(kept) > maxFailedUpdates {
+		kept = kept[len(kept)-maxFailedUpdates:]
+	}
+	state.FailedUpdates = kept
+}
+
+// IsFailedVersion reports whether version was rolled back after failing
+// verification.
+func IsFailedVersion(state *State, version string) bool {
+	for _, f := range state.FailedUpdates {
+		if sameVersion(f.Version, version) {
+			return true
+		}
+	}
+	return false
+}
+
+// ForgetFailedVersion removes version from the failed-update history, for an
+// operator who installs it explicitly.
+func ForgetFailedVersion(state *State, version string) {
+	kept := state.FailedUpdates[:0]
+	for _, f := range state.FailedUpdates {
+		if !sameVersion(f.Version, version) {
+			kept = append(kept, f)
+		}
+	}
+	state.FailedUpdates = kept
+}
+
+// LatestFailedUpdate returns the most recently rolled-back version, or nil when
+// none has failed.
+func LatestFailedUpdate(state *State) *FailedUpdate {
+	if len(state.FailedUpdates) == 0 {
+		return nil
+	}
+	latest := state.FailedUpdates[len(state.FailedUpdates)-1]
+	return &latest
+}
+
+// HasFailedVerification reports whether version was rolled back on this node
+// after failing verification. It is the default AutoUpdaterConfig.SkipVersion.
+func HasFailedVerification(version string) bool {
+	state, err := LoadState()
+	if err != nil {
+		return false
+	}
+	return IsFailedVersion(state, version)
+}
+
+// sameVersion compares tags ignoring the optional "v" prefix.
+func sameVersion(a, b string) bool {
+	return a != "" && strings.TrimPrefix(a, "v") == strings.TrimPrefix(b, "v")
+}
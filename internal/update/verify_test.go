@@ -0,0 +1,211 @@
+// internal/update/verify_test.go
+package update
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBeginVerification_CountsBootsAndExpires(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	if err := MarkPendingVerification("v1.0.0", "v1.1.0", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	// The old binary (installed without a restart) leaves the marker alone.
+	if _, act, _ := BeginVerification("v1.0.0", start); act != VerifyNone {
+		t.Fatalf("old version: action = %v, want none", act)
+	}
+	if p, _ := LoadPendingVerification(); p == nil {
+		t.Fatal("old version must not clear the marker")
+	}
+
+	p, act, err := BeginVerification("1.1.0", start) // "v" prefix is optional
+	if err != nil || act != VerifyWatch {
+		t.Fatalf("first boot: action = %v, err = %v; want watch", act, err)
+	}
+	if !p.Deadline().Equal(start.Add(time.Minute)) {
+		t.Fatalf("deadline = %v, want first boot + window", p.Deadline())
+	}
+
+	// A later boot past the deadline rolls back at once.
+	if _, act, _ := BeginVerification("v1.1.0", start.Add(2*time.Minute)); act != VerifyRollback {
+		t.Fatalf("boot after deadline: action = %v, want rollback", act)
+	}
+}
+
+func TestBeginVerification_CrashLoopRollsBack(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	if err := MarkPendingVerification("v1.0.0", "v1.1.0", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	for i := 1; i <= MaxVerifyBoots; i++ {
+		if _, act, _ := BeginVerification("v1.1.0", now); act != VerifyWatch {
+			t.Fatalf("boot %d: action = %v, want watch", i, act)
+		}
+	}
+	if _, act, _ := BeginVerification("v1.1.0", now); act != VerifyRollback {
+		t.Fatalf("boot %d: action = %v, want rollback", MaxVerifyBoots+1, act)
+	}
+}
+
+func TestBeginVerification_StaleMarkerCleared(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	if err := MarkPendingVerification("v1.0.0", "v1.1.0", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if _, act, _ := BeginVerification("v2.0.0", time.Now()); act != VerifyNone {
+		t.Fatalf("action = %v, want none", act)
+	}
+	if p, _ := LoadPendingVerification(); p != nil {
+		t.Fatalf("marker for an unrelated version must be cleared, got %+v", p)
+	}
+}
+
+func TestRunVerifier_PassClearsMarker(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	_ = MarkPendingVerification("v1.0.0", "v1.1.0", time.Hour)
+	p, _, _ := BeginVerification("v1.1.0", time.Now())
+
+	polls := 0
+	ok := RunVerifier(context.Background(), VerifierConfig{
+		Pending:      p,
+		PollInterval: time.Millisecond,
+		Checks: []HealthCheck{{Name: "poll", Check: func() error {
+			if polls++; polls < 3 {
+				return errors.New("no poll yet")
+			}
+			return nil
+		}}},
+		Rollback: func() error { t.Fatal("healthy update must not roll back"); return nil },
+	})
+	if !ok {
+		t.Fatal("verifier should report success")
+	}
+	if p, _ := LoadPendingVerification(); p != nil {
+		t.Fatal("marker must be cleared once verified")
+	}
+}
+
+func TestRunVerifier_DeadlineRollsBackAndRecordsFailure(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	_ = MarkPendingVerification("v1.0.0", "v1.1.0", time.Minute)
+	start := time.Now()
+	p, _, _ := BeginVerification("v1.1.0", start)
+
+	var rolledBack, restarted bool
+	ok := RunVerifier(context.Background(), VerifierConfig{
+		Pending:      p,
+		PollInterval: time.Millisecond,
+		Checks:       []HealthCheck{{Name: "network", Check: func() error { return errors.New("not connected") }}},
+		Rollback:     func() error { rolledBack = true; return nil },
+		Restart:      func() error { restarted = true; return nil },
+		Now:          func() time.Time { return start.Add(2 * time.Minute) },
+	})
+	if ok || !rolledBack || !restarted {
+		t.Fatalf("ok=%v rolledBack=%v restarted=%v; want rollback + restart", ok, rolledBack, restarted)
+	}
+	state, _ := LoadState()
+	if !IsFailedVersion(state, "v1.1.0") {
+		t.Fatalf("failed version not recorded: %+v", state.FailedUpdates)
+	}
+	if latest := LatestFailedUpdate(state); latest == nil || latest.Version != "v1.1.0" || !strings.Contains(latest.Reason, "network: not connected") {
+		t.Fatalf("latest failed update = %+v, want v1.1.0 naming the failing check", latest)
+	}
+	if state.CurrentVersion != "v1.0.0" {
+		t.Fatalf("current version = %q, want the restored v1.0.0", state.CurrentVersion)
+	}
+	if !HasFailedVerification("1.1.0") {
+		t.Fatal("updater must skip the failed version")
+	}
+	if p, _ := LoadPendingVerification(); p != nil {
+		t.Fatal("marker must be cleared after rollback")
+	}
+}
+
+func TestRunVerifier_DeadlineDrainsJobsBeforeRestart(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	_ = MarkPendingVerification("v1.0.0", "v1.1.0", time.Minute)
+	start := time.Now()
+	p, _, _ := BeginVerification("v1.1.0", start)
+
+	var mu sync.Mutex
+	active, drained, restarted := 1, false, false
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		mu.Lock()
+		active = 0
+		mu.Unlock()
+	}()
+	RunVerifier(context.Background(), VerifierConfig{
+		Pending:          p,
+		PollInterval:     time.Millisecond,
+		IdlePollInterval: time.Millisecond,
+		Checks:           []HealthCheck{{Name: "network", Check: func() error { return errors.New("not connected") }}},
+		Rollback:         func() error { return nil },
+		Drain:            func() { mu.Lock(); drained = true; mu.Unlock() },
+		ActiveJobs:       func() int { mu.Lock(); defer mu.Unlock(); return active },
+		Restart: func() error {
+			mu.Lock()
+			defer mu.Unlock()
+			if active != 0 {
+				t.Errorf("restarted with %d job(s) still in flight", active)
+			}
+			restarted = true
+			return nil
+		},
+		Now: func() time.Time { return start.Add(2 * time.Minute) },
+	})
+	if !drained || !restarted {
+		t.Fatalf("drained=%v restarted=%v; want drain then restart", drained, restarted)
+	}
+
+	// A job that never finishes delays the restart by the idle timeout only.
+	_ = MarkPendingVerification("v1.0.0", "v1.1.0", time.Minute)
+	p, _, _ = BeginVerification("v1.1.0", start)
+	restarted = false
+	RunVerifier(context.Background(), VerifierConfig{
+		Pending:          p,
+		PollInterval:     time.Millisecond,
+		IdlePollInterval: time.Millisecond,
+		IdleTimeout:      10 * time.Millisecond,
+		Checks:           []HealthCheck{{Name: "network", Check: func() error { return errors.New("not connected") }}},
+		Rollback:         func() error { return nil },
+		ActiveJobs:       func() int { return 1 },
+		Restart:          func() error { restarted = true; return nil },
+		Now:              func() time.Time { return start.Add(2 * time.Minute) },
+	})
+	if !restarted {
+		t.Fatal("a stuck job must not hold a failed version in place past the idle timeout")
+	}
+}
+
+func TestRecordFailedUpdate_DedupesAndBounds(t *testing.T) {
+	s := defaultState()
+	now := time.Now()
+	for i := 0; i < maxFailedUpdates+5; i++ {
+		RecordFailedUpdate(s, "v1.0."+string(rune('a'+i)), "x", now)
+	}
+	RecordFailedUpdate(s, "v2.0.0", "first", now)
+	RecordFailedUpdate(s, "v2.0.0", "second", now)
+	if len(s.FailedUpdates) != maxFailedUpdates {
+		t.Fatalf("history length = %d, want %d", len(s.FailedUpdates), maxFailedUpdates)
+	}
+	last := s.FailedUpdates[len(s.FailedUpdates)-1]
+	if last.Version != "v2.0.0" || last.Reason != "second" {
+		t.Fatalf("latest entry = %+v", last)
+	}
+	if latest := LatestFailedUpdate(s); latest == nil || *latest != last {
+		t.Fatalf("LatestFailedUpdate = %+v, want %+v", latest, last)
+	}
+	ForgetFailedVersion(s, "v2.0.0")
+	if IsFailedVersion(s, "v2.0.0") {
+		t.Fatal("forgotten version still failed")
+	}
+}
@@ -81,14 +81,17 @@ func (s *Store) Insert(r UsageRecord) error {
 	return nil
 }
 
-// QueryUnsynced returns up to limit records that have not been synced.
-func (s *Store) QueryUnsynced(limit int) ([]UsageRecord, error) {
-	rows, err := s.db.Query(`
-		SELECT id, job_id, job_type, backend, model, status,
+// recordColumns is the column list scanRecords expects.
+const recordColumns = `id, job_id, job_type, backend, model, status,
 		       started_at, completed_at, duration_ms,
 		       prompt_tokens, completion_tokens, total_tokens,
 		       request_bytes, response_bytes,
-		       error_message, node_id
+		       error_message, node_id`
+
+// QueryUnsynced returns up to limit records that have not been synced.
+func (s *Store) QueryUnsynced(limit int) ([]UsageRecord, error) {
+	rows, err := s.db.Query(`
+		SELECT `+recordColumns+`
 		FROM job_usage
 		WHERE synced = 0
 		ORDER BY id ASC
@@ -96,6 +99,25 @@ func (s *Store) QueryUnsynced(limit int) ([]UsageRecord, error) {
 	if err != nil {
 		return nil, fmt.Errorf("query unsynced: %w", err)
 	}
+	return scanRecords(rows)
+}
+
+// Recent returns up to limit records, newest first, synced or not. It backs
+// the control center's job history.
+func (s *Store) Recent(limit int) ([]UsageRecord, error) {
+	rows, err := s.db.Query(`
+		SELECT `+recordColumns+`
+		FROM job_usage
+		ORDER BY id DESC
+		LIMIT ?`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("query recent: %w", err)
+	}
+	return scanRecords(rows)
+}
+
+// scanRecords reads and closes rows selected with recordColumns.
+func scanRecords(rows *sql.Rows) ([]UsageRecord, error) {
 	defer rows.Close()
 
 	var records []UsageRecord
@@ -259,3 +259,43 @@ func TestInsertWithErrorMessage(t *testing.T) {
 		t.Errorf("ErrorMessage = %q, want %q", records[0].ErrorMessage, "out of memory")
 	}
 }
+
+func TestRecentNewestFirstIncludingSynced(t *testing.T) {
+	store, err := OpenStore(tempDBPath(t))
+	if err != nil {
+		t.Fatalf("OpenStore: %v", err)
+	}
+	defer store.Close()
+
+	now := time.Now().UTC()
+	for i := range 3 {
+		if err := store.Insert(UsageRecord{
+			JobID:       fmt.Sprintf("job-%d", i),
+			JobType:     "test",
+			Status:      "success",
+			StartedAt:   now,
+			CompletedAt: now,
+			NodeID:      "node1",
+		}); err != nil {
+			t.Fatalf("Insert: %v", err)
+		}
+	}
+	unsynced, err := store.QueryUnsynced(1)
+	if err != nil {
+		t.Fatalf("QueryUnsynced: %v", err)
+	}
+	if err := store.MarkSynced([]int64{unsynced[0].ID}); err != nil {
+		t.Fatalf("MarkSynced: %v", err)
+	}
+
+	records, err := store.Recent(10)
+	if err != nil {
+		t.Fatalf("Recent: %v", err)
+	}
+	if len(records) != 3 || records[0].JobID != "job-2" || records[2].JobID != "job-0" {
+		t.Errorf("Recent = %+v, want all three newest first", records)
+	}
+	if records, _ := store.Recent(1); len(records) != 1 || records[0].JobID != "job-2" {
+		t.Errorf("Recent(1) = %+v", records)
+	}
+}
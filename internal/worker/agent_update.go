@@ -44,6 +44,12 @@
 // (CITADEL_SERVICE=true); in a foreground/interactive run we report "updated,
 // restart required" and leave the operator's session alone.
 //
+// Health gate: like the AutoUpdater, the default RecordState also arms
+// post-install verification (internal/update/verify.go), so a release that
+// restarts but never becomes healthy rolls itself back. An untargeted
+// ("latest") update skips a version that already failed verification on this
+// node; an explicit target_version is the operator overriding that.
+//
 // Privilege gating: AGENT_UPDATE is only honored when the job arrives on the
 // per-node stream (jobs:v1:shell:org_<id>:node:<nodeid>), never the shared org
 // pool — updating a node is a privileged, node-targeted operation. Server-side
@@ -121,9 +127,15 @@ type AgentUpdateConfig struct {
 	Log func(format string, args ...any)
 
 	// RecordState persists the update to disk (previous/current version) so the
-	// new process and `citadel update status` reflect it. Defaults to writing
-	// update state; overridable/no-op in tests.
+	// new process and `citadel update status` reflect it, and arms post-install
+	// verification. Defaults to writing update state and the verification
+	// marker; overridable/no-op in tests.
 	RecordState func(oldVersion, newVersion string)
+
+	// SkipVersion reports whether a release failed verification on this node.
+	// Consulted for untargeted updates only. Defaults to
+	// update.HasFailedVerification.
+	SkipVersion func(version string) bool
 }
 
 // AgentUpdateHandler processes AGENT_UPDATE jobs.
@@ -192,11 +204,18 @@ func NewAgentUpdateHandler(cfg AgentUpdateConfig) *AgentUpdateHandler {
 				return
 			}
 			update.RecordUpdate(state, oldVersion, newVersion)
+			// An explicit install clears a previous failure; verification
+			// records it again if it fails again.
+			update.ForgetFailedVersion(state, newVersion)
 			state.AvailableUpdate = ""
 			update.UpdateLastCheck(state)
 			_ = update.SaveState(state)
+			_ = update.MarkPendingVerification(oldVersion, newVersion, update.DefaultVerifyWindow)
 		}
 	}
+	if cfg.SkipVersion == nil {
+		cfg.SkipVersion = update.HasFailedVerification
+	}
 	return &AgentUpdateHandler{cfg: cfg}
 }
 
@@ -236,6 +255,15 @@ func (h *AgentUpdateHandler) Execute(ctx context.Context, job *Job, stream Strea
 			"new_version": h.cfg.Version,
 		}), nil
 	}
+	if target == "" && h.cfg.SkipVersion(release.TagName) {
+		h.cfg.Log("AGENT_UPDATE: latest release %s failed verification on this node; not reinstalling", release.TagName)
+		return h.success(map[string]any{
+			"updated":     false,
+			"reason":      "failed-verification",
+			"old_version": h.cfg.Version,
+			"new_version": release.TagName,
+		}), nil
+	}
 
 	h.cfg.Log("AGENT_UPDATE: downloading %s", release.TagName)
 	if err := h.cfg.Download(release, h.cfg.PendingPath); err != nil {
@@ -31,6 +31,7 @@ func newTestHandler(t *testing.T, mutate func(*AgentUpdateConfig)) *AgentUpdateH
 		IsService:   func() bool { return true },
 		Restart:     func() error { return nil },
 		RecordState: func(string, string) {},
+		SkipVersion: func(string) bool { return false },
 		// Fast idle polling so the ordering test doesn't wait on the 500ms default.
 		IdlePollInterval: time.Millisecond,
 		IdleTimeout:      2 * time.Second,
@@ -82,6 +83,37 @@ func TestAgentUpdateAlreadyLatest(t *testing.T) {
 	}
 }
 
+// TestAgentUpdateSkipsFailedVersion: an untargeted update does not reinstall a
+// release that already failed verification here, but an explicit
+// target_version does.
+func TestAgentUpdateSkipsFailedVersion(t *testing.T) {
+	var applied int
+	h := newTestHandler(t, func(c *AgentUpdateConfig) {
+		c.SkipVersion = func(v string) bool { return v == "v2.47.0" }
+		c.GetRelease = func(string) (*update.Release, error) { return &update.Release{TagName: "v2.47.0"}, nil }
+		c.Apply = func(string) error { applied++; return nil }
+		c.IsService = func() bool { return false }
+	})
+
+	res, err := h.Execute(context.Background(), agentUpdateJob(perNodeQueue, nil), &NoOpStreamWriter{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.Status != JobStatusSuccess || res.Output["reason"] != "failed-verification" {
+		t.Fatalf("result = %v %v, want success/failed-verification", res.Status, res.Output)
+	}
+	if applied != 0 {
+		t.Fatal("Apply was called for a failed version")
+	}
+
+	if _, err := h.Execute(context.Background(), agentUpdateJob(perNodeQueue, map[string]any{"target_version": "v2.47.0"}), &NoOpStreamWriter{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if applied != 1 {
+		t.Errorf("explicit target_version: Apply calls = %d, want 1", applied)
+	}
+}
+
 // TestAgentUpdateTargetVersionPassthrough: an explicit target_version payload is
 // parsed and forwarded verbatim to GetRelease.
 func TestAgentUpdateTargetVersionPassthrough(t *testing.T) {
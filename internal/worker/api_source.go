@@ -451,6 +451,12 @@ func (s *APISource) convertJob(aj *redisapi.Job) *Job {
 // Ack acknowledges successful job completion.
 func (s *APISource) Ack(ctx context.Context, job *Job) error {
 	s.client.SetJobStatus(ctx, job.ID, "completed", nil)
+	return s.ReleaseMessage(ctx, job)
+}
+
+// ReleaseMessage removes the job's message from the consumer group's pending
+// list without touching the job's status. Satisfies messageReleaser.
+func (s *APISource) ReleaseMessage(ctx context.Context, job *Job) error {
 	queue := job.SourceQueue
 	if queue == "" {
 		if qs := s.snapshotQueues(); len(qs) > 0 {
@@ -562,3 +568,6 @@ func (s *APISource) IsJobCancelled(ctx context.Context, jobID string) bool {
 
 // Ensure APISource implements JobSource
 var _ JobSource = (*APISource)(nil)
+
+// Ensure APISource can take a deferred job off its queue.
+var _ messageReleaser = (*APISource)(nil)
@@ -0,0 +1,148 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/aceteam-ai/citadel-cli/internal/jobs"
+)
+
+// Mid-flight cancellation. JQS-Core (Section 5.6) has the producer set
+// job:cancelled:{jobId}; the runner used to read that flag only once, before
+// the handler started, so cancelling a 20-minute TRANSCRIBE_AUDIO, LLM or
+// ANDROID_BUILD job still ran it to the end. Now the flag is polled for as
+// long as the handler runs. When it appears the handler's context is
+// cancelled (with errJobCancelled as the cause), the handler gets a grace
+// period to stop its child processes and containers, and the job is closed
+// out with a "cancelled" event and usage record, exactly like a job cancelled
+// before it started.
+
+const (
+	// defaultCancelPollInterval is how often a running job's cancellation
+	// flag is read. Each poll is one Redis EXISTS (or one KV GET on API-path
+	// nodes), so a few seconds of latency is cheap.
+	defaultCancelPollInterval = 5 * time.Second
+
+	// defaultCancelGracePeriod is how long a cancelled handler has to return
+	// before the runner abandons it. It exceeds jobs.ChildStopGrace so a
+	// handler that SIGTERMs its child and waits out the kill still finishes
+	// inside it.
+	defaultCancelGracePeriod = jobs.ChildStopGrace + 20*time.Second
+)
+
+// errJobCancelled is the cause of a job context cancelled by the producer,
+// and the error a cancelled execution returns.
+var errJobCancelled = errors.New("job cancelled by producer")
+
+// handlerResult carries a handler's return values out of its goroutine.
+type handlerResult struct {
+	result *JobResult
+	err    error
+}
+
+// cancelPollInterval resolves RunnerConfig.CancelPollInterval; ok=false
+// means mid-flight watching is disabled.
+func (r *Runner) cancelPollInterval() (time.Duration, bool) {
+	switch d := r.config.CancelPollInterval; {
+	case d < 0:
+		return 0, false
+	case d == 0:
+		return defaultCancelPollInterval, true
+	default:
+		return d, true
+	}
+}
+
+// cancelGracePeriod resolves RunnerConfig.CancelGracePeriod.
+func (r *Runner) cancelGracePeriod() time.Duration {
+	if r.config.CancelGracePeriod > 0 {
+		return r.config.CancelGracePeriod
+	}
+	return defaultCancelGracePeriod
+}
+
+// watchCancellation returns a child of ctx that is cancelled with cause
+// errJobCancelled once the source reports job cancelled, or the job is
+// cancelled from the control center's Jobs page. stop ends the watch and must
+// be called when the handler is done.
+func (r *Runner) watchCancellation(ctx context.Context, job *Job, tracked *trackedJob) (jobCtx context.Context, stop func()) {
+	jobCtx, cancel := context.WithCancelCause(ctx)
+	r.state.setCancel(tracked, cancel)
+	interval, ok := r.cancelPollInterval()
+	if !ok {
+		return jobCtx, func() { cancel(nil) }
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-jobCtx.Done():
+				return
+			case <-ticker.C:
+				if r.sourceFor(job).IsJobCancelled(jobCtx, job.ID) {
+					r.log("info", "Job %s was cancelled while running; stopping its handler", job.ID)
+					cancel(errJobCancelled)
+					return
+				}
+			}
+		}
+	}()
+	return jobCtx, func() { cancel(nil) }
+}
+
+// jobCancelled reports whether ctx was cancelled by watchCancellation.
+func jobCancelled(ctx context.Context) bool {
+	return ctx.Err() != nil && errors.Is(context.Cause(ctx), errJobCancelled)
+}
+
+// executeCancellable runs a handler that has no deadline. It behaves like a
+// plain synchronous call, except that once the job is cancelled the handler
+// has the grace period to return before it is abandoned.
+func (r *Runner) executeCancellable(ctx context.Context, handler JobHandler, job *Job, stream StreamWriter) (*JobResult, error) {
+	// Buffered so an abandoned handler can still deliver its result and exit.
+	done := make(chan handlerResult, 1)
+	go func() {
+		result, err := handler.Execute(ctx, job, stream)
+		done <- handlerResult{result: result, err: err}
+	}()
+
+	select {
+	case hr := <-done:
+		return cancelledOutcome(ctx, hr)
+	case <-ctx.Done():
+		if !jobCancelled(ctx) {
+			// Worker shutdown: wait for the handler, as the synchronous call did.
+			hr := <-done
+			return hr.result, hr.err
+		}
+		return r.awaitCancelledHandler(job, done)
+	}
+}
+
+// awaitCancelledHandler gives a cancelled job's handler the grace period to
+// return. Either way the outcome is errJobCancelled; a returned result is kept
+// for its usage. An abandoned handler's GPU slot is released when processJob
+// returns, with the same tradeoff as a deadline abandon.
+func (r *Runner) awaitCancelledHandler(job *Job, done <-chan handlerResult) (*JobResult, error) {
+	grace := r.cancelGracePeriod()
+	select {
+	case hr := <-done:
+		return hr.result, errJobCancelled
+	case <-time.After(grace):
+		r.log("warning", "Job %s handler did not stop within %s of cancellation; abandoning it", job.ID, grace)
+		return nil, errJobCancelled
+	}
+}
+
+// cancelledOutcome maps what a handler returned under a possibly cancelled
+// context: a failure after the job was cancelled is the cancellation, while a
+// handler that finished successfully anyway keeps its result.
+func cancelledOutcome(ctx context.Context, hr handlerResult) (*JobResult, error) {
+	failed := hr.err != nil || (hr.result != nil && hr.result.Status != JobStatusSuccess)
+	if failed && jobCancelled(ctx) {
+		return hr.result, errJobCancelled
+	}
+	return hr.result, hr.err
+}
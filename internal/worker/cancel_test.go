@@ -0,0 +1,183 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aceteam-ai/citadel-cli/internal/usage"
+)
+
+// cancelOnStartHandler flags its job cancelled at the source as soon as it
+// starts, then waits for its context. cleanup is how long it takes to return
+// after the context is done; ignoreCtx makes it never return on its own.
+type cancelOnStartHandler struct {
+	jobType   string
+	source    *MockJobSource
+	cleanup   time.Duration
+	ignoreCtx bool
+	release   chan struct{}
+
+	mu    sync.Mutex
+	cause error
+}
+
+func (h *cancelOnStartHandler) CanHandle(jobType string) bool { return h.jobType == jobType }
+
+func (h *cancelOnStartHandler) Execute(ctx context.Context, job *Job, stream StreamWriter) (*JobResult, error) {
+	h.source.mu.Lock()
+	if h.source.cancelledJobs == nil {
+		h.source.cancelledJobs = map[string]bool{}
+	}
+	h.source.cancelledJobs[job.ID] = true
+	h.source.mu.Unlock()
+
+	if h.ignoreCtx {
+		<-h.release
+		return &JobResult{Status: JobStatusSuccess}, nil
+	}
+	<-ctx.Done()
+	h.mu.Lock()
+	h.cause = context.Cause(ctx)
+	h.mu.Unlock()
+	time.Sleep(h.cleanup)
+	return &JobResult{
+		Status: JobStatusFailure,
+		Output: map[string]any{"_usage_prompt_tokens": 40},
+	}, ctx.Err()
+}
+
+// runCancelTest runs jobs to completion under a fast cancel poll and returns
+// the usage records.
+func runCancelTest(t *testing.T, source *MockJobSource, handlers []JobHandler, factory *recordingFactory, grace time.Duration) []usage.UsageRecord {
+	t.Helper()
+	var (
+		mu      sync.Mutex
+		records []usage.UsageRecord
+	)
+	runner := NewRunner(source, handlers, RunnerConfig{
+		WorkerID:           "test",
+		CancelPollInterval: 10 * time.Millisecond,
+		CancelGracePeriod:  grace,
+		JobRecordFn: func(r usage.UsageRecord) {
+			mu.Lock()
+			defer mu.Unlock()
+			records = append(records, r)
+		},
+	})
+	runner.WithStreamWriterFactory(factory.factory)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		runner.Run(ctx)
+		close(done)
+	}()
+	deadline := time.After(time.Second)
+	for {
+		if len(source.AckedJobs())+len(source.NackedJobs())+len(source.FailedJobs()) == len(source.jobs) {
+			break
+		}
+		select {
+		case <-deadline:
+			t.Fatal("jobs were not all settled")
+		case <-time.After(5 * time.Millisecond):
+		}
+	}
+	cancel()
+	<-done
+
+	mu.Lock()
+	defer mu.Unlock()
+	return records
+}
+
+func TestRunnerCancelsRunningHandler(t *testing.T) {
+	jobs := []*Job{{ID: "job-1", Type: "LONG_JOB", Payload: map[string]any{}}}
+	source := NewMockJobSource("test", jobs)
+	h := &cancelOnStartHandler{jobType: "LONG_JOB", source: source, cleanup: 20 * time.Millisecond}
+	factory := newRecordingFactory()
+
+	records := runCancelTest(t, source, []JobHandler{h}, factory, time.Second)
+
+	if !errors.Is(h.cause, errJobCancelled) {
+		t.Errorf("handler context cause = %v, want errJobCancelled", h.cause)
+	}
+	w := factory.get("job-1")
+	if !w.cancelled || w.errored || w.ended {
+		t.Errorf("stream = %+v, want only a cancelled terminal event", w)
+	}
+	if a := source.AckedJobs(); len(a) != 1 || len(source.NackedJobs()) != 0 {
+		t.Errorf("acked %d, nacked %d; want the cancelled job acked", len(a), len(source.NackedJobs()))
+	}
+	if len(records) != 1 || records[0].Status != "cancelled" || records[0].PromptTokens != 40 {
+		t.Errorf("usage = %+v, want one cancelled record keeping the handler's usage", records)
+	}
+}
+
+func TestRunnerCancelAbandonsHandlerAfterGrace(t *testing.T) {
+	jobs := []*Job{
+		{ID: "job-1", Type: "STUCK_JOB", Payload: map[string]any{}},
+		{ID: "job-2", Type: "TEST_JOB", Payload: map[string]any{}},
+	}
+	source := NewMockJobSource("test", jobs)
+	stuck := &cancelOnStartHandler{jobType: "STUCK_JOB", source: source, ignoreCtx: true, release: make(chan struct{})}
+	defer close(stuck.release)
+	next := newSignalHandler("TEST_JOB")
+	factory := newRecordingFactory()
+
+	start := time.Now()
+	records := runCancelTest(t, source, []JobHandler{stuck, next}, factory, 50*time.Millisecond)
+
+	select {
+	case <-next.done:
+	default:
+		t.Fatal("the loop never reached job-2")
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("took %s; the stuck handler should be abandoned after its grace period", elapsed)
+	}
+	if !factory.get("job-1").cancelled {
+		t.Error("job-1 did not publish a cancelled event")
+	}
+	if len(records) != 2 || records[0].Status != "cancelled" {
+		t.Errorf("usage = %+v, want job-1 cancelled", records)
+	}
+}
+
+func TestRunnerCancelBeatsDeadline(t *testing.T) {
+	jobs := []*Job{{ID: "job-1", Type: "LONG_JOB", Payload: map[string]any{"timeout_ms": float64(60000)}}}
+	source := NewMockJobSource("test", jobs)
+	h := &cancelOnStartHandler{jobType: "LONG_JOB", source: source, cleanup: 20 * time.Millisecond}
+	factory := newRecordingFactory()
+
+	records := runCancelTest(t, source, []JobHandler{h}, factory, time.Second)
+
+	if w := factory.get("job-1"); !w.cancelled || w.errored {
+		t.Errorf("stream = %+v, want cancelled rather than a deadline error", w)
+	}
+	if len(source.FailedJobs()) != 0 || len(records) != 1 || records[0].PromptTokens != 40 {
+		t.Errorf("failed = %v, usage = %+v", source.FailedJobs(), records)
+	}
+}
+
+func TestRunnerCancelPollingDisabled(t *testing.T) {
+	jobs := []*Job{{ID: "job-1", Type: "LONG_JOB", Payload: map[string]any{"timeout_ms": float64(100)}}}
+	source := NewMockJobSource("test", jobs)
+	h := &cancelOnStartHandler{jobType: "LONG_JOB", source: source}
+	factory := newRecordingFactory()
+
+	runner := NewRunner(source, []JobHandler{h}, RunnerConfig{WorkerID: "test", CancelPollInterval: -1})
+	runner.WithStreamWriterFactory(factory.factory)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	runner.Run(ctx)
+
+	// Without the watcher only the deadline stops the handler.
+	if w := factory.get("job-1"); w.cancelled || !w.errored {
+		t.Errorf("stream = %+v, want the deadline error", w)
+	}
+}
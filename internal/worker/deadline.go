@@ -52,10 +52,11 @@ var longSessionJobTypes = map[string]struct{}{
 }
 
 // unboundedJobTypes get NO fallback deadline: their duration is dominated by
-// external factors (download size / build time / VM clone) with opaque progress,
-// so any blanket cap risks killing a legitimate job. They are still bounded when
-// the backend sends an explicit timeout_ms, and the self-heal monitor (#548) is
-// the backstop if one of these ever truly wedges.
+// external factors (download size / build time / VM clone / VM backup / batch
+// size) with opaque progress, so any blanket cap risks killing a legitimate
+// job. They are still bounded when the backend sends an explicit timeout_ms,
+// and the self-heal monitor (#548) is the backstop if one of these ever truly
+// wedges.
 var unboundedJobTypes = map[string]struct{}{
 	JobTypeDownloadModel:     {},
 	JobTypeOllamaPull:        {},
@@ -65,8 +66,10 @@ var unboundedJobTypes = map[string]struct{}{
 	JobTypeAndroidBuild:      {},
 	JobTypeGomobileBuild:     {},
 	JobTypeInstanceProvision: {},
+	JobTypeInstanceBackup:    {},
 	JobTypeAgentUpdate:       {},
 	JobTypeWhatsAppProvision: {},
+	JobTypeLLMBatch:          {},
 }
 
 // resolveJobTimeout returns the execution budget the runner should apply to a
@@ -210,10 +213,6 @@ func (r *Runner) executeWithDeadline(
 	execCtx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
-	type handlerResult struct {
-		result *JobResult
-		err    error
-	}
 	// Buffered (size 1) so a handler that ignores cancellation and finishes
 	// AFTER the deadline can still send its result and exit, rather than leaking
 	// blocked on the channel forever.
@@ -231,12 +230,16 @@ func (r *Runner) executeWithDeadline(
 		if hr.err != nil && errors.Is(execCtx.Err(), context.DeadlineExceeded) {
 			return nil, &deadlineExceededError{timeout: timeout}
 		}
-		return hr.result, hr.err
+		return cancelledOutcome(ctx, hr)
 	case <-execCtx.Done():
 		if errors.Is(execCtx.Err(), context.DeadlineExceeded) {
 			r.log("error", "Job %s abandoned: exceeded execution deadline of %s", job.ID, timeout)
 			return nil, &deadlineExceededError{timeout: timeout}
 		}
+		if jobCancelled(ctx) {
+			// Cancelled by the producer: let the handler clean up first.
+			return r.awaitCancelledHandler(job, done)
+		}
 		// Parent context cancelled (worker shutdown): surface the raw error so
 		// the loop unwinds without misreporting a deadline breach.
 		return nil, execCtx.Err()
@@ -0,0 +1,428 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aceteam-ai/citadel-cli/internal/config"
+	"github.com/aceteam-ai/citadel-cli/internal/usage"
+)
+
+// Energy-aware deferral. Much fabric work (batch embeddings, FILE_INDEX, model
+// pulls, builds) does not need to run the moment it arrives. With an energy
+// schedule loaded (RunnerConfig.EnergySchedule), a job whose payload sets
+// "deferrable": true that arrives in a peak hour is claimed and then held
+// until the next cheap window opens or its deferral deadline arrives,
+// whichever is first. The run loop keeps consuming meanwhile: a held job sits
+// in its own goroutine, not in a concurrency slot.
+//
+// A hold can last hours, far longer than the platform's pending-message
+// recovery leaves an unacked message alone, so a held job is taken off its
+// source: it is written to a local spool (RunnerConfig.DeferSpoolPath) with
+// the time it may run, and only then is its message removed from the queue
+// without settling the job's status. A worker that stops while holding
+// leaves the job in the spool, and the next run resumes the hold. Holding
+// needs both a spool and a source that can remove a message that way
+// (messageReleaser); without either, deferrable jobs run on arrival. The cost
+// is retries: a released job that fails is reported failed, not redelivered,
+// because its message is already gone.
+//
+// A held job counts toward ActiveJobs, so an auto-update waits for it; Drain
+// releases every hold at once so a drain is never stuck behind a cheap window.
+// A held job whose producer cancels it is settled as cancelled without
+// running. Live holds are capped (RunnerConfig.MaxDeferredJobs); a deferrable
+// job arriving over the cap runs at once, and the source is never gated on
+// holds.
+
+const (
+	// deferDeadlinePayloadKey bounds how long a deferrable job may be held, in
+	// milliseconds from when the worker receives it. Relative for the same
+	// clock-skew reason as timeout_ms.
+	deferDeadlinePayloadKey = "defer_deadline_ms"
+
+	// deferMaxEnvVar tunes the default hold bound for jobs without
+	// defer_deadline_ms. 0 disables holding altogether.
+	deferMaxEnvVar = "WORKER_DEFER_MAX_SECONDS"
+
+	// defaultDeferMaxSeconds is the hold bound for jobs without
+	// defer_deadline_ms: long enough to carry a daytime arrival into the
+	// night.
+	defaultDeferMaxSeconds = 24 * 3600
+
+	// deferPollInterval is how often a hold checks for a drain or a producer
+	// cancellation.
+	deferPollInterval = 30 * time.Second
+
+	// defaultMaxDeferredJobs caps live holds when RunnerConfig.MaxDeferredJobs
+	// is 0.
+	defaultMaxDeferredJobs = 16
+)
+
+// messageReleaser is a source that can remove a job's message from its queue
+// without settling the job's status, so the job can wait in the deferral
+// spool instead. RedisSource and APISource implement it.
+type messageReleaser interface {
+	ReleaseMessage(ctx context.Context, job *Job) error
+}
+
+// jobHold is the deferral state of one held job.
+type jobHold struct {
+	arrived  time.Time
+	arrival  config.EnergyWindow
+	until    time.Time
+	released time.Time
+}
+
+// JobWindow is a finished job and the energy windows it arrived and ran in,
+// passed to RunnerConfig.JobWindowFn.
+type JobWindow struct {
+	Record usage.UsageRecord
+	// Deferred reports whether the job was held; Held is for how long.
+	Deferred bool
+	Held     time.Duration
+	// Arrival is the window the job reached the worker in, Run the one it
+	// started in. They differ only for a deferred job.
+	Arrival config.EnergyWindow
+	Run     config.EnergyWindow
+}
+
+// jobDeferrable reports whether the producer marked the job deferrable.
+func jobDeferrable(job *Job) bool {
+	if job == nil || job.Payload == nil {
+		return false
+	}
+	switch v := job.Payload["deferrable"].(type) {
+	case bool:
+		return v
+	case string:
+		return strings.EqualFold(v, "true") || v == "1"
+	default:
+		return false
+	}
+}
+
+// deferDeadline returns how long a deferrable job may be held: the payload's
+// defer_deadline_ms when positive, else the WORKER_DEFER_MAX_SECONDS default.
+// ok=false means the job must not be held.
+func deferDeadline(job *Job) (time.Duration, bool) {
+	d, ok := envTimeoutSeconds(deferMaxEnvVar, defaultDeferMaxSeconds)
+	if raw, found := job.Payload[deferDeadlinePayloadKey]; found {
+		if ms, valid := coerceToInt64(raw); valid && ms > 0 {
+			d, ok = time.Duration(ms)*time.Millisecond, true
+		}
+	}
+	return d, ok
+}
+
+// holdDeferrable holds job for a cheap energy window when it is deferrable
+// and arrived in a peak hour, reporting whether it did. A held job is claimed
+// here, moved from its source to the spool, and handed back to the run loop
+// through r.released.
+func (r *Runner) holdDeferrable(ctx context.Context, job *Job) bool {
+	sched := r.config.EnergySchedule
+	if sched == nil || job.Source == LocalJobSource || r.isDraining() || !jobDeferrable(job) {
+		return false
+	}
+	releaser, ok := r.source.(messageReleaser)
+	if !ok || r.spool == nil {
+		return false
+	}
+	if limit := r.maxDeferredJobs(); limit < 0 || atomic.LoadInt64(&r.heldJobs) >= int64(limit) {
+		return false // over the cap: run it now rather than claim it and sit on it
+	}
+	// A job for another node is skipped by processJob; never hold it.
+	if target, ok := job.Payload["target_node"].(string); ok && target != "" && target != r.config.NodeID {
+		return false
+	}
+	maxHold, ok := deferDeadline(job)
+	if !ok {
+		return false
+	}
+
+	now := time.Now()
+	arrival := sched.WindowAt(now)
+	if arrival.Cheap {
+		return false
+	}
+	until := now.Add(maxHold)
+	if next, ok := sched.NextCheap(now); ok && next.Before(until) {
+		until = next
+	}
+
+	h := &jobHold{arrived: now, arrival: arrival, until: until}
+	// Spool first, then release: a crash between the two leaves the job both
+	// spooled and pending, which runs it twice, rather than in neither, which
+	// loses it.
+	if err := r.spool.put(spooledJob{Job: job, Arrived: now, Arrival: arrival, Until: until}); err != nil {
+		r.log("warning", "Running deferrable job %s now: cannot spool it: %v", job.ID, err)
+		return false
+	}
+	if err := releaser.ReleaseMessage(ctx, job); err != nil {
+		r.unspool(job)
+		r.log("warning", "Running deferrable job %s now: cannot take it off %s: %v", job.ID, r.source.Name(), err)
+		return false
+	}
+	r.holds.Store(job.ID, h)
+	atomic.AddInt64(&r.heldJobs, 1)
+
+	stream := r.newStreamWriter(job)
+	if err := stream.WriteClaimed(r.agentVersion); err != nil {
+		r.log("warning", "Failed to publish claimed event for job %s: %v", job.ID, err)
+	}
+	publishJobClaimed(job)
+	r.log("info", "Holding deferrable job %s (type: %s) in a peak energy window (%s %g): runs at %s",
+		job.ID, job.Type, sched.Metric, arrival.Value, until.Format(time.RFC3339))
+
+	r.holdWG.Add(1)
+	go r.awaitRelease(ctx, job, h, stream)
+	return true
+}
+
+// resumeSpooledHolds picks up the holds a previous run left in the spool.
+// Without a schedule any more, they are released at once.
+func (r *Runner) resumeSpooledHolds(ctx context.Context) {
+	if r.spool == nil {
+		return
+	}
+	entries, err := r.spool.load()
+	if err != nil {
+		r.log("warning", "Cannot read the deferral spool: %v", err)
+		return
+	}
+	for _, e := range entries {
+		if e.Job == nil || e.Job.ID == "" {
+			continue
+		}
+		h := &jobHold{arrived: e.Arrived, arrival: e.Arrival, until: e.Until}
+		if r.config.EnergySchedule == nil {
+			h.until = time.Now()
+		}
+		r.holds.Store(e.Job.ID, h)
+		atomic.AddInt64(&r.heldJobs, 1)
+		r.log("info", "Resuming hold of deferrable job %s (type: %s): runs at %s",
+			e.Job.ID, e.Job.Type, h.until.Format(time.RFC3339))
+		r.holdWG.Add(1)
+		go r.awaitRelease(ctx, e.Job, h, r.newStreamWriter(e.Job))
+	}
+}
+
+// awaitRelease waits out one hold, then queues the job for the run loop.
+func (r *Runner) awaitRelease(ctx context.Context, job *Job, h *jobHold, stream StreamWriter) {
+	defer r.holdWG.Done()
+	timer := time.NewTimer(time.Until(h.until))
+	defer timer.Stop()
+	poll := time.NewTicker(r.deferPollInterval())
+	defer poll.Stop()
+
+	for released := false; !released; {
+		select {
+		case <-ctx.Done():
+			r.dropHold(job)
+			r.log("info", "Worker stopping with deferrable job %s still held; it stays in the deferral spool", job.ID)
+			return
+		case <-timer.C:
+			released = true
+		case <-poll.C:
+			if r.isDraining() {
+				released = true
+				continue
+			}
+			if r.source.IsJobCancelled(ctx, job.ID) {
+				r.cancelHeld(ctx, job, h, stream)
+				return
+			}
+		}
+	}
+
+	h.released = time.Now()
+	r.log("info", "Releasing deferrable job %s after holding it %s", job.ID, h.released.Sub(h.arrived).Truncate(time.Second))
+	select {
+	case r.released <- job:
+	case <-ctx.Done():
+		r.dropHold(job)
+	}
+}
+
+// cancelHeld settles a held job its producer cancelled, without running it.
+func (r *Runner) cancelHeld(ctx context.Context, job *Job, h *jobHold, stream StreamWriter) {
+	r.log("info", "Job %s was cancelled while deferred", job.ID)
+	if err := stream.WriteCancelled("Job cancelled while deferred for a cheap energy window"); err != nil {
+		r.log("warning", "Failed to publish cancelled event for job %s: %v", job.ID, err)
+	}
+	r.recordJob(buildUsageRecord(job, "cancelled", h.arrived, time.Now(), nil, nil))
+	r.dropHold(job)
+	r.source.Ack(ctx, job)
+}
+
+// unspool removes a job from the deferral spool, logging a failure: a stale
+// entry would run the job again after a restart.
+func (r *Runner) unspool(job *Job) {
+	if r.spool == nil {
+		return
+	}
+	if err := r.spool.remove(job.ID); err != nil {
+		r.log("warning", "Cannot remove job %s from the deferral spool: %v", job.ID, err)
+	}
+}
+
+// dropHold forgets a hold that ends without the job running.
+func (r *Runner) dropHold(job *Job) {
+	r.holds.Delete(job.ID)
+	atomic.AddInt64(&r.heldJobs, -1)
+}
+
+// takeReleasedJob returns a job whose hold ended, if any, without blocking.
+func (r *Runner) takeReleasedJob() *Job {
+	select {
+	case job := <-r.released:
+		return job
+	default:
+		return nil
+	}
+}
+
+// heldJob returns the hold a job was released from, or nil if it was never
+// held. The hold stays recorded until the job's outcome is (recordJobWindow).
+func (r *Runner) heldJob(job *Job) *jobHold {
+	if v, ok := r.holds.Load(job.ID); ok {
+		return v.(*jobHold)
+	}
+	return nil
+}
+
+// recordJobWindow ends a released job's hold, taking it out of the spool, and
+// reports the finished job and its energy windows to JobWindowFn when a
+// schedule is loaded.
+func (r *Runner) recordJobWindow(record usage.UsageRecord) {
+	v, wasHeld := r.holds.LoadAndDelete(record.JobID)
+	if wasHeld {
+		r.unspool(&Job{ID: record.JobID})
+	}
+	sched := r.config.EnergySchedule
+	if sched == nil || r.config.JobWindowFn == nil {
+		return
+	}
+	jw := JobWindow{Record: record, Run: sched.WindowAt(record.StartedAt)}
+	jw.Arrival = jw.Run
+	if wasHeld {
+		h := v.(*jobHold)
+		jw.Deferred = true
+		jw.Arrival = h.arrival
+		if !h.released.IsZero() {
+			jw.Held = h.released.Sub(h.arrived)
+		}
+	}
+	r.config.JobWindowFn(jw)
+}
+
+func (r *Runner) deferPollInterval() time.Duration {
+	if r.config.DeferPollInterval > 0 {
+		return r.config.DeferPollInterval
+	}
+	return deferPollInterval
+}
+
+// maxDeferredJobs is the cap on live holds; negative disables holding.
+func (r *Runner) maxDeferredJobs() int {
+	if r.config.MaxDeferredJobs != 0 {
+		return r.config.MaxDeferredJobs
+	}
+	return defaultMaxDeferredJobs
+}
+
+// spooledJob is one held job as the deferral spool stores it.
+type spooledJob struct {
+	Job     *Job                `json:"job"`
+	Arrived time.Time           `json:"arrived"`
+	Arrival config.EnergyWindow `json:"arrival"`
+	Until   time.Time           `json:"until"`
+}
+
+// deferSpool is the file that keeps held jobs across restarts: a JSON array,
+// rewritten whole on every change. It stays small because live holds are
+// capped.
+type deferSpool struct {
+	path string
+	mu   sync.Mutex
+}
+
+// load returns the spooled jobs; a missing spool is empty.
+func (s *deferSpool) load() ([]spooledJob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.readLocked()
+}
+
+func (s *deferSpool) readLocked() ([]spooledJob, error) {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var entries []spooledJob
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// put adds or replaces the entry for e.Job.ID.
+func (s *deferSpool) put(e spooledJob) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entries, err := s.readLocked()
+	if err != nil {
+		return err
+	}
+	out := entries[:0]
+	for _, old := range entries {
+		if old.Job == nil || old.Job.ID != e.Job.ID {
+			out = append(out, old)
+		}
+	}
+	return s.writeLocked(append(out, e))
+}
+
+// remove drops the entry for id, if any.
+func (s *deferSpool) remove(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entries, err := s.readLocked()
+	if err != nil {
+		return err
+	}
+	out := entries[:0]
+	for _, e := range entries {
+		if e.Job != nil && e.Job.ID != id {
+			out = append(out, e)
+		}
+	}
+	if len(out) == len(entries) {
+		return nil
+	}
+	return s.writeLocked(out)
+}
+
+func (s *deferSpool) writeLocked(entries []spooledJob) error {
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
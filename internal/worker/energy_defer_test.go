@@ -0,0 +1,335 @@
+package worker
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aceteam-ai/citadel-cli/internal/config"
+)
+
+// loadTestSchedule writes a flat 24-hour schedule of value with the given
+// cheap threshold and loads it, so every hour is cheap or every hour is peak.
+func loadTestSchedule(t *testing.T, value, cheapAt string) *config.EnergySchedule {
+	t.Helper()
+	dir := t.TempDir()
+	hours := strings.TrimSuffix(strings.Repeat(value+", ", 24), ", ")
+	body := "metric: tariff\nunit: USD/kWh\ntimezone: UTC\ncheap_at: " + cheapAt + "\nhours: [" + hours + "]\n"
+	if err := os.WriteFile(filepath.Join(dir, "energy-schedule.yaml"), []byte(body), 0644); err != nil {
+		t.Fatal(err)
+	}
+	s, err := config.LoadEnergySchedule(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// deferTestRunner runs jobs under sched and collects their job windows.
+type deferTestRunner struct {
+	runner  *Runner
+	source  *MockJobSource
+	factory *recordingFactory
+	cancel  context.CancelFunc
+	done    chan struct{}
+
+	mu      sync.Mutex
+	windows []JobWindow
+}
+
+func startDeferTest(t *testing.T, sched *config.EnergySchedule, jobs []*Job, handlers ...JobHandler) *deferTestRunner {
+	t.Helper()
+	return startDeferTestCapped(t, sched, 0, jobs, handlers...)
+}
+
+// startDeferTestCapped is startDeferTest with RunnerConfig.MaxDeferredJobs.
+func startDeferTestCapped(t *testing.T, sched *config.EnergySchedule, maxHolds int, jobs []*Job, handlers ...JobHandler) *deferTestRunner {
+	t.Helper()
+	return startDeferTestSpooled(t, sched, maxHolds, filepath.Join(t.TempDir(), "deferred-jobs.json"), jobs, handlers...)
+}
+
+// startDeferTestSpooled is startDeferTestCapped with an explicit spool path,
+// so a test can restart a runner over the same spool.
+func startDeferTestSpooled(t *testing.T, sched *config.EnergySchedule, maxHolds int, spool string, jobs []*Job, handlers ...JobHandler) *deferTestRunner {
+	t.Helper()
+	d := &deferTestRunner{
+		source:  NewMockJobSource("test", jobs),
+		factory: newRecordingFactory(),
+		done:    make(chan struct{}),
+	}
+	d.runner = NewRunner(d.source, handlers, RunnerConfig{
+		WorkerID:          "test",
+		ActivityFn:        func(string, string) {},
+		EnergySchedule:    sched,
+		DeferPollInterval: 10 * time.Millisecond,
+		MaxDeferredJobs:   maxHolds,
+		DeferSpoolPath:    spool,
+		JobWindowFn: func(jw JobWindow) {
+			d.mu.Lock()
+			defer d.mu.Unlock()
+			d.windows = append(d.windows, jw)
+		},
+	})
+	d.runner.WithStreamWriterFactory(d.factory.factory)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	d.cancel = cancel
+	go func() {
+		d.runner.Run(ctx)
+		close(d.done)
+	}()
+	t.Cleanup(d.stop)
+	return d
+}
+
+func (d *deferTestRunner) stop() {
+	d.cancel()
+	<-d.done
+}
+
+// waitAcked waits until n jobs are acked.
+func (d *deferTestRunner) waitAcked(t *testing.T, n int) {
+	t.Helper()
+	deadline := time.After(3 * time.Second)
+	for len(d.source.AckedJobs()) < n {
+		select {
+		case <-deadline:
+			t.Fatalf("acked %d jobs, want %d", len(d.source.AckedJobs()), n)
+		case <-time.After(5 * time.Millisecond):
+		}
+	}
+}
+
+func (d *deferTestRunner) jobWindows() []JobWindow {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]JobWindow(nil), d.windows...)
+}
+
+func TestDeferrableJobHeldUntilDeadlineInPeakWindow(t *testing.T) {
+	// Every hour is peak, so the hold ends at the job's own deadline.
+	sched := loadTestSchedule(t, "0.30", "0.10")
+	jobs := []*Job{
+		{ID: "index-1", Type: "FILE_INDEX", Payload: map[string]any{"deferrable": true, "defer_deadline_ms": float64(150)}},
+		{ID: "shell-1", Type: "SHELL_COMMAND", Payload: map[string]any{}},
+	}
+	index := NewMockJobHandler("FILE_INDEX", false)
+	shell := NewMockJobHandler("SHELL_COMMAND", false)
+	d := startDeferTest(t, sched, jobs, index, shell)
+
+	// The non-deferrable job behind it is not stuck behind the hold.
+	deadline := time.After(time.Second)
+	for len(shell.ExecutedJobs()) == 0 {
+		select {
+		case <-deadline:
+			t.Fatal("a held job must not block the jobs behind it")
+		case <-time.After(5 * time.Millisecond):
+		}
+	}
+	if len(index.ExecutedJobs()) != 0 {
+		t.Fatal("the deferrable job ran in a peak window before its deadline")
+	}
+	if d.runner.ActiveJobs() != 1 {
+		t.Errorf("ActiveJobs = %d while holding, want 1", d.runner.ActiveJobs())
+	}
+
+	d.waitAcked(t, 2)
+	if len(index.ExecutedJobs()) != 1 {
+		t.Fatal("the deferrable job never ran after its deadline")
+	}
+	w := d.factory.get("index-1")
+	if w == nil || w.claimed || !w.ended {
+		t.Errorf("the released run must not claim again and must end, got %+v", w)
+	}
+
+	var held *JobWindow
+	for _, jw := range d.jobWindows() {
+		if jw.Record.JobID == "index-1" {
+			jw := jw
+			held = &jw
+		} else if jw.Deferred {
+			t.Errorf("job %s was not held but is reported deferred", jw.Record.JobID)
+		}
+	}
+	if held == nil || !held.Deferred || held.Held < 100*time.Millisecond || held.Arrival.Value != 0.30 || held.Run.Cheap {
+		t.Errorf("held job window = %+v, want deferred ~150ms in peak", held)
+	}
+}
+
+func TestDeferrableJobRunsImmediatelyInCheapWindow(t *testing.T) {
+	sched := loadTestSchedule(t, "0.08", "0.10")
+	jobs := []*Job{{ID: "index-1", Type: "FILE_INDEX", Payload: map[string]any{"deferrable": true}}}
+	index := NewMockJobHandler("FILE_INDEX", false)
+	d := startDeferTest(t, sched, jobs, index)
+
+	d.waitAcked(t, 1)
+	if w := d.factory.get("index-1"); !w.claimed {
+		t.Error("an unheld job claims as usual")
+	}
+	windows := d.jobWindows()
+	if len(windows) != 1 || windows[0].Deferred || !windows[0].Run.Cheap || windows[0].Run.Value != 0.08 {
+		t.Errorf("windows = %+v, want one undeferred cheap run", windows)
+	}
+}
+
+func TestHeldJobCancelledWithoutRunning(t *testing.T) {
+	sched := loadTestSchedule(t, "0.30", "0.10")
+	jobs := []*Job{{ID: "index-1", Type: "FILE_INDEX", Payload: map[string]any{"deferrable": "true"}}}
+	index := NewMockJobHandler("FILE_INDEX", false)
+	d := startDeferTest(t, sched, jobs, index)
+
+	deadline := time.After(time.Second)
+	for d.runner.ActiveJobs() == 0 {
+		select {
+		case <-deadline:
+			t.Fatal("job was never held")
+		case <-time.After(5 * time.Millisecond):
+		}
+	}
+	d.source.mu.Lock()
+	d.source.cancelledJobs = map[string]bool{"index-1": true}
+	d.source.mu.Unlock()
+
+	d.waitAcked(t, 1)
+	if len(index.ExecutedJobs()) != 0 {
+		t.Error("a job cancelled while held must not run")
+	}
+	if w := d.factory.get("index-1"); !w.claimed || !w.cancelled {
+		t.Errorf("held job stream = %+v, want claimed then cancelled", w)
+	}
+	if n := d.runner.ActiveJobs(); n != 0 {
+		t.Errorf("ActiveJobs = %d after the cancel, want 0", n)
+	}
+	windows := d.jobWindows()
+	if len(windows) != 1 || windows[0].Record.Status != "cancelled" || !windows[0].Deferred {
+		t.Errorf("windows = %+v, want one deferred cancelled job", windows)
+	}
+}
+
+func TestDrainReleasesHeldJobs(t *testing.T) {
+	sched := loadTestSchedule(t, "0.30", "0.10")
+	jobs := []*Job{{ID: "index-1", Type: "FILE_INDEX", Payload: map[string]any{"deferrable": true}}}
+	index := NewMockJobHandler("FILE_INDEX", false)
+	d := startDeferTest(t, sched, jobs, index)
+
+	deadline := time.After(time.Second)
+	for d.runner.ActiveJobs() == 0 {
+		select {
+		case <-deadline:
+			t.Fatal("job was never held")
+		case <-time.After(5 * time.Millisecond):
+		}
+	}
+	d.runner.Drain()
+	d.waitAcked(t, 1)
+	if len(index.ExecutedJobs()) != 1 {
+		t.Error("a drain must run held jobs rather than wait for a cheap window")
+	}
+	if n := d.runner.ActiveJobs(); n != 0 {
+		t.Errorf("ActiveJobs = %d after the drain, want 0", n)
+	}
+}
+
+func TestHoldCapRunsOverflowAndKeepsFetching(t *testing.T) {
+	sched := loadTestSchedule(t, "0.30", "0.10")
+	jobs := []*Job{
+		{ID: "index-1", Type: "FILE_INDEX", Payload: map[string]any{"deferrable": true}},
+		{ID: "index-2", Type: "FILE_INDEX", Payload: map[string]any{"deferrable": true}},
+		{ID: "shell-1", Type: "SHELL_COMMAND", Payload: map[string]any{}},
+	}
+	index := NewMockJobHandler("FILE_INDEX", false)
+	shell := NewMockJobHandler("SHELL_COMMAND", false)
+	d := startDeferTestCapped(t, sched, 1, jobs, index, shell)
+
+	// One hold fills the cap: the second deferrable job runs at once, and the
+	// job behind it is still fetched.
+	d.waitAcked(t, 2)
+	if got := index.ExecutedJobs(); len(got) != 1 || got[0].ID != "index-2" {
+		t.Errorf("ran %v, want only the over-cap index-2", got)
+	}
+	if len(shell.ExecutedJobs()) != 1 {
+		t.Error("the hold cap must not stop the runner fetching")
+	}
+	if n := d.runner.ActiveJobs(); n != 1 {
+		t.Errorf("ActiveJobs = %d, want the one hold", n)
+	}
+}
+
+func TestDeferDeadline(t *testing.T) {
+	t.Setenv(deferMaxEnvVar, "")
+	if d, ok := deferDeadline(&Job{Payload: map[string]any{"defer_deadline_ms": float64(60000)}}); !ok || d != time.Minute {
+		t.Errorf("payload deadline = %v, %v; want 1m", d, ok)
+	}
+	if d, ok := deferDeadline(&Job{Payload: map[string]any{}}); !ok || d != 24*time.Hour {
+		t.Errorf("default deadline = %v, %v; want 24h", d, ok)
+	}
+	// A held job is off its source, so a long deadline is honoured as given.
+	if d, _ := deferDeadline(&Job{Payload: map[string]any{"defer_deadline_ms": float64(12 * 3600 * 1000)}}); d != 12*time.Hour {
+		t.Errorf("payload deadline = %v, want 12h", d)
+	}
+	t.Setenv(deferMaxEnvVar, "43200")
+	if d, _ := deferDeadline(&Job{Payload: map[string]any{}}); d != 12*time.Hour {
+		t.Errorf("env deadline = %v, want 12h", d)
+	}
+	t.Setenv(deferMaxEnvVar, "0")
+	if _, ok := deferDeadline(&Job{Payload: map[string]any{}}); ok {
+		t.Error("WORKER_DEFER_MAX_SECONDS=0 must disable holding")
+	}
+}
+
+func TestHeldJobSpooledOffSourceAndResumedAfterRestart(t *testing.T) {
+	sched := loadTestSchedule(t, "0.30", "0.10")
+	spool := filepath.Join(t.TempDir(), "deferred-jobs.json")
+	jobs := []*Job{{ID: "index-1", Type: "FILE_INDEX", Payload: map[string]any{"deferrable": true, "defer_deadline_ms": float64(400)}}}
+	index := NewMockJobHandler("FILE_INDEX", false)
+	d := startDeferTestSpooled(t, sched, 0, spool, jobs, index)
+
+	deadline := time.After(time.Second)
+	for d.runner.ActiveJobs() == 0 {
+		select {
+		case <-deadline:
+			t.Fatal("job was never held")
+		case <-time.After(5 * time.Millisecond):
+		}
+	}
+	d.source.mu.Lock()
+	released := len(d.source.released)
+	d.source.mu.Unlock()
+	if released != 1 || len(d.source.AckedJobs()) != 0 {
+		t.Fatalf("released %d, acked %d; want the held job off its source but not settled", released, len(d.source.AckedJobs()))
+	}
+	d.stop() // the worker stops mid-hold
+
+	entries, err := (&deferSpool{path: spool}).load()
+	if err != nil || len(entries) != 1 || entries[0].Job.ID != "index-1" {
+		t.Fatalf("spool = %+v, %v; want the held job", entries, err)
+	}
+	if len(index.ExecutedJobs()) != 0 {
+		t.Fatal("the held job ran before its deadline")
+	}
+
+	// The next run resumes the hold from the spool and runs it at its time.
+	d = startDeferTestSpooled(t, sched, 0, spool, nil, index)
+	d.waitAcked(t, 1)
+	if got := index.ExecutedJobs(); len(got) != 1 || got[0].ID != "index-1" {
+		t.Fatalf("ran %v, want the resumed index-1", got)
+	}
+	if entries, _ := (&deferSpool{path: spool}).load(); len(entries) != 0 {
+		t.Errorf("spool = %+v after the job ran, want empty", entries)
+	}
+}
+
+func TestNoSpoolNoHold(t *testing.T) {
+	sched := loadTestSchedule(t, "0.30", "0.10")
+	jobs := []*Job{{ID: "index-1", Type: "FILE_INDEX", Payload: map[string]any{"deferrable": true}}}
+	index := NewMockJobHandler("FILE_INDEX", false)
+	d := startDeferTestSpooled(t, sched, 0, "", jobs, index)
+
+	d.waitAcked(t, 1)
+	if len(index.ExecutedJobs()) != 1 {
+		t.Error("without a spool a deferrable job must run on arrival")
+	}
+}
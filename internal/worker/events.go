@@ -0,0 +1,48 @@
+package worker
+
+import (
+	"github.com/aceteam-ai/citadel-cli/internal/events"
+	"github.com/aceteam-ai/citadel-cli/internal/usage"
+)
+
+// Job lifecycle events on the node-local bus (internal/events), for local
+// tooling and webhooks to react to a job without tailing the log. A job that
+// will be retried publishes nothing at that point: its next run does.
+
+// publishJobClaimed announces that this node took ownership of job.
+func publishJobClaimed(job *Job) {
+	events.Publish(events.TypeJobClaimed, map[string]any{
+		"job_id":   job.ID,
+		"job_type": job.Type,
+		"source":   job.Source,
+	})
+}
+
+// publishJobOutcome announces a job's final outcome: job.failed for a failure,
+// job.finished for a success or a cancellation (the status tells them apart).
+func publishJobOutcome(record usage.UsageRecord) {
+	typ := events.TypeJobFinished
+	switch record.Status {
+	case "failed":
+		typ = events.TypeJobFailed
+	case "success", "cancelled":
+	default:
+		return
+	}
+	data := map[string]any{
+		"job_id":      record.JobID,
+		"job_type":    record.JobType,
+		"status":      record.Status,
+		"duration_ms": record.DurationMs,
+	}
+	if record.Backend != "" {
+		data["backend"] = record.Backend
+	}
+	if record.Model != "" {
+		data["model"] = record.Model
+	}
+	if record.ErrorMessage != "" {
+		data["error"] = record.ErrorMessage
+	}
+	events.Publish(typ, data)
+}
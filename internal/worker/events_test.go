@@ -0,0 +1,41 @@
+package worker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aceteam-ai/citadel-cli/internal/events"
+)
+
+func TestRunnerPublishesJobEvents(t *testing.T) {
+	ch, unsubscribe := events.Default.Subscribe("job.*")
+	defer unsubscribe()
+
+	jobs := []*Job{
+		{ID: "events-ok", Type: "SHELL_COMMAND", Payload: map[string]any{}},
+		{ID: "events-bad", Type: "FILE_INDEX", Payload: map[string]any{}},
+	}
+	startDeferTest(t, nil, jobs, NewMockJobHandler("SHELL_COMMAND", false), NewMockJobHandler("FILE_INDEX", true))
+
+	var got []string
+	deadline := time.After(3 * time.Second)
+	for len(got) < 4 {
+		select {
+		case ev := <-ch:
+			if id, _ := ev.Data["job_id"].(string); id == "events-ok" || id == "events-bad" {
+				got = append(got, ev.Type+" "+id)
+			}
+		case <-deadline:
+			t.Fatalf("job events = %v, want claimed and outcome for both jobs", got)
+		}
+	}
+	want := []string{
+		events.TypeJobClaimed + " events-ok", events.TypeJobFinished + " events-ok",
+		events.TypeJobClaimed + " events-bad", events.TypeJobFailed + " events-bad",
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("job events = %v, want %v", got, want)
+		}
+	}
+}
@@ -48,6 +48,10 @@ type ExposeRequest struct {
 	// Epoch, when >0, is bound into a `link` token so the backend can revoke all
 	// outstanding tokens for this exposure by bumping it. Defaults to 1.
 	Epoch int `json:"epoch"`
+	// StorageKey, when set, marks this as an exposure of the node's object
+	// storage gateway: proxied requests are re-signed with this issued access
+	// key (by id), which bounds what callers can reach.
+	StorageKey string `json:"storage_key,omitempty"`
 }
 
 // ExposeResult is what the ops layer returns after programming the gateway.
@@ -32,6 +32,11 @@ type StreamWriter interface {
 	// WriteChunk sends an incremental output chunk (e.g., LLM token).
 	WriteChunk(content string, index int) error
 
+	// WriteChunkData sends an incremental chunk carrying structured fields
+	// beside the text, such as an LLM's tool-call deltas or token logprobs.
+	// data's keys sit next to content and index in the chunk event.
+	WriteChunkData(content string, index int, data map[string]any) error
+
 	// WriteEnd signals successful job completion with final result.
 	WriteEnd(result map[string]any) error
 
@@ -46,9 +51,12 @@ type StreamWriter interface {
 // Used when streaming is not supported or needed.
 type NoOpStreamWriter struct{}
 
-func (n *NoOpStreamWriter) WriteClaimed(agentVersion string) error       { return nil }
-func (n *NoOpStreamWriter) WriteStart(message string) error              { return nil }
-func (n *NoOpStreamWriter) WriteChunk(content string, index int) error   { return nil }
+func (n *NoOpStreamWriter) WriteClaimed(agentVersion string) error     { return nil }
+func (n *NoOpStreamWriter) WriteStart(message string) error            { return nil }
+func (n *NoOpStreamWriter) WriteChunk(content string, index int) error { return nil }
+func (n *NoOpStreamWriter) WriteChunkData(content string, index int, data map[string]any) error {
+	return nil
+}
 func (n *NoOpStreamWriter) WriteEnd(result map[string]any) error         { return nil }
 func (n *NoOpStreamWriter) WriteError(err error, recoverable bool) error { return nil }
 func (n *NoOpStreamWriter) WriteCancelled(reason string) error           { return nil }
@@ -0,0 +1,402 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/aceteam-ai/citadel-cli/internal/usage"
+)
+
+// Job inspection for the control center's Jobs page. WorkerState keeps every
+// in-flight job and a short history of finished ones, with the payload and
+// result, so the page can show what is running right now (type, queue,
+// elapsed, streamed chunks), drill into a job, cancel it, and re-run a failed
+// one on this node. The usage store has the long history but no payloads.
+
+const (
+	// recentJobsLimit bounds the in-memory finished-job history.
+	recentJobsLimit = 50
+
+	// localJobQueueSize bounds re-runs waiting for the run loop.
+	localJobQueueSize = 8
+
+	// LocalJobSource is Job.Source for a job re-run from the control center.
+	// It never came off a queue, so it is never acked, nacked or failed there
+	// and publishes no stream events.
+	LocalJobSource = "local"
+)
+
+// JobView is an in-flight or recently finished job as the Jobs page shows it.
+// Payload and Result are copies with secret-looking values redacted.
+type JobView struct {
+	ID          string
+	Type        string
+	Source      string
+	Queue       string
+	RayID       string
+	Status      string // "running" while in flight, else the usage status
+	Error       string
+	StartedAt   time.Time
+	CompletedAt time.Time // zero while running
+	Chunks      int64
+	Payload     map[string]any
+	Result      map[string]any
+}
+
+// trackedJob is WorkerState's record of one job.
+type trackedJob struct {
+	view    JobView
+	payload map[string]any // unredacted, for a re-run
+	chunks  atomic.Int64
+
+	// cancel stops the handler once execution has started; cancelRequested
+	// carries a cancel that arrived before then.
+	cancel          context.CancelCauseFunc
+	cancelRequested bool
+}
+
+func (t *trackedJob) snapshot() JobView {
+	v := t.view
+	v.Chunks = t.chunks.Load()
+	return v
+}
+
+// trackJobStart records a job the runner has claimed.
+func (s *WorkerState) trackJobStart(job *Job) *trackedJob {
+	if s == nil {
+		return nil
+	}
+	payload := make(map[string]any, len(job.Payload))
+	for k, v := range job.Payload {
+		payload[k] = v
+	}
+	t := &trackedJob{
+		view: JobView{
+			ID:        job.ID,
+			Type:      job.Type,
+			Source:    job.Source,
+			Queue:     job.SourceQueue,
+			RayID:     job.RayID,
+			Status:    "running",
+			StartedAt: time.Now(),
+			Payload:   RedactPayload(job.Payload),
+		},
+		payload: payload,
+	}
+	s.jobsMu.Lock()
+	defer s.jobsMu.Unlock()
+	if s.activeJobs == nil {
+		s.activeJobs = make(map[string]*trackedJob)
+	}
+	s.activeJobs[job.ID] = t
+	return t
+}
+
+// setCancel arms cancellation for a job whose handler is about to run. A
+// cancel requested during the claim fires immediately.
+func (s *WorkerState) setCancel(t *trackedJob, cancel context.CancelCauseFunc) {
+	if s == nil || t == nil {
+		return
+	}
+	s.jobsMu.Lock()
+	t.cancel = cancel
+	fire := t.cancelRequested
+	s.jobsMu.Unlock()
+	if fire {
+		cancel(errJobCancelled)
+	}
+}
+
+// noteOutcome copies a job's usage record (status, error, completion time)
+// onto its tracked entry.
+func (s *WorkerState) noteOutcome(record usage.UsageRecord) {
+	if s == nil {
+		return
+	}
+	s.jobsMu.Lock()
+	defer s.jobsMu.Unlock()
+	if t := s.activeJobs[record.JobID]; t != nil {
+		t.view.Status = record.Status
+		t.view.Error = record.ErrorMessage
+		t.view.CompletedAt = record.CompletedAt
+	}
+}
+
+// noteResult records a job's handler output.
+func (s *WorkerState) noteResult(t *trackedJob, result *JobResult) {
+	if s == nil || t == nil || result == nil {
+		return
+	}
+	s.jobsMu.Lock()
+	t.view.Result = RedactPayload(result.Output)
+	s.jobsMu.Unlock()
+}
+
+// trackJobEnd moves a job from in-flight to the recent history.
+func (s *WorkerState) trackJobEnd(t *trackedJob) {
+	if s == nil || t == nil {
+		return
+	}
+	s.jobsMu.Lock()
+	defer s.jobsMu.Unlock()
+	delete(s.activeJobs, t.view.ID)
+	t.cancel = nil
+	if t.view.Status == "running" {
+		t.view.Status = "unknown"
+	}
+	if t.view.CompletedAt.IsZero() {
+		t.view.CompletedAt = time.Now()
+	}
+	s.recentJobs = append(s.recentJobs, t)
+	if len(s.recentJobs) > recentJobsLimit {
+		s.recentJobs = s.recentJobs[len(s.recentJobs)-recentJobsLimit:]
+	}
+}
+
+// InFlightJobs returns the jobs currently being processed, oldest first.
+func (s *WorkerState) InFlightJobs() []JobView {
+	if s == nil {
+		return nil
+	}
+	s.jobsMu.Lock()
+	out := make([]JobView, 0, len(s.activeJobs))
+	for _, t := range s.activeJobs {
+		out = append(out, t.snapshot())
+	}
+	s.jobsMu.Unlock()
+	sort.Slice(out, func(i, j int) bool { return out[i].StartedAt.Before(out[j].StartedAt) })
+	return out
+}
+
+// RecentJobs returns finished jobs, newest first.
+func (s *WorkerState) RecentJobs() []JobView {
+	if s == nil {
+		return nil
+	}
+	s.jobsMu.Lock()
+	defer s.jobsMu.Unlock()
+	out := make([]JobView, 0, len(s.recentJobs))
+	for i := len(s.recentJobs) - 1; i >= 0; i-- {
+		out = append(out, s.recentJobs[i].snapshot())
+	}
+	return out
+}
+
+// CancelJob cancels an in-flight job as if its producer had: the handler's
+// context is cancelled, it gets the usual grace period, and the job is closed
+// out with a "cancelled" event and usage record (cancel.go).
+func (s *WorkerState) CancelJob(id string) error {
+	if s == nil {
+		return fmt.Errorf("worker is not running")
+	}
+	s.jobsMu.Lock()
+	t := s.activeJobs[id]
+	if t == nil {
+		s.jobsMu.Unlock()
+		return fmt.Errorf("job %s is not in flight", id)
+	}
+	cancel := t.cancel
+	t.cancelRequested = true
+	s.jobsMu.Unlock()
+	if cancel != nil {
+		cancel(errJobCancelled)
+	}
+	return nil
+}
+
+// failedJob returns a copy of a finished, failed job for a re-run.
+func (s *WorkerState) failedJob(id string) (*Job, error) {
+	if s == nil {
+		return nil, fmt.Errorf("worker is not running")
+	}
+	s.jobsMu.Lock()
+	defer s.jobsMu.Unlock()
+	for i := len(s.recentJobs) - 1; i >= 0; i-- {
+		t := s.recentJobs[i]
+		if t.view.ID != id {
+			continue
+		}
+		if t.view.Status != "failed" {
+			return nil, fmt.Errorf("job %s is %s; only failed jobs can be re-run", id, t.view.Status)
+		}
+		payload := make(map[string]any, len(t.payload))
+		for k, v := range t.payload {
+			payload[k] = v
+		}
+		return &Job{Type: t.view.Type, Payload: payload, RayID: t.view.RayID}, nil
+	}
+	return nil, fmt.Errorf("job %s is not in the recent history", id)
+}
+
+// RerunJob queues a failed job from the recent history to run again on this
+// node, under a new ID, and returns that ID. The re-run goes through the same
+// handler, deadline and cancellation path as a queued job; only the source
+// bookkeeping is skipped.
+func (r *Runner) RerunJob(id string) (string, error) {
+	job, err := r.state.failedJob(id)
+	if err != nil {
+		return "", err
+	}
+	if !r.CanHandle(job.Type) {
+		return "", fmt.Errorf("no handler for %s on this node", job.Type)
+	}
+	job.ID = fmt.Sprintf("%s-rerun-%d", id, time.Now().UnixMilli())
+	job.Source = LocalJobSource
+	job.Metadata.CreatedAt = time.Now()
+	select {
+	case r.localJobs <- job:
+		r.log("info", "Queued local re-run %s of failed job %s", job.ID, id)
+		return job.ID, nil
+	default:
+		return "", fmt.Errorf("too many re-runs queued; try again shortly")
+	}
+}
+
+// takeLocalJob returns a queued re-run, if any, without blocking.
+func (r *Runner) takeLocalJob() *Job {
+	select {
+	case job := <-r.localJobs:
+		return job
+	default:
+		return nil
+	}
+}
+
+// sourceFor returns the source that settles job: the runner's source, or a
+// no-op stand-in for a local re-run.
+func (r *Runner) sourceFor(job *Job) JobSource {
+	if job.Source == LocalJobSource {
+		return localSource{r.source}
+	}
+	return r.source
+}
+
+// localSource settles local re-runs. They were never read off a queue, so
+// there is nothing to ack, nack or fail, and no producer to cancel them.
+type localSource struct {
+	JobSource
+}
+
+func (localSource) Ack(context.Context, *Job) error                         { return nil }
+func (localSource) Nack(context.Context, *Job, error) error                 { return nil }
+func (localSource) Fail(context.Context, *Job, error, map[string]any) error { return nil }
+func (localSource) IsJobCancelled(context.Context, string) bool             { return false }
+
+// chunkCountingWriter counts a job's streamed chunks for the Jobs page.
+type chunkCountingWriter struct {
+	StreamWriter
+	t *trackedJob
+}
+
+// countChunks wraps stream when the job is tracked.
+func countChunks(t *trackedJob, stream StreamWriter) StreamWriter {
+	if t == nil {
+		return stream
+	}
+	return &chunkCountingWriter{StreamWriter: stream, t: t}
+}
+
+func (w *chunkCountingWriter) WriteChunk(content string, index int) error {
+	w.t.chunks.Add(1)
+	return w.StreamWriter.WriteChunk(content, index)
+}
+
+func (w *chunkCountingWriter) WriteChunkData(content string, index int, data map[string]any) error {
+	w.t.chunks.Add(1)
+	return w.StreamWriter.WriteChunkData(content, index, data)
+}
+
+// redactedValue replaces a secret in a redacted payload.
+const redactedValue = "[redacted]"
+
+// secretKeyMarkers are key substrings that mark a payload value as a secret.
+// They err on the side of hiding: "key" and "auth" also catch names like
+// SIGNING_KEY or X_AUTH, and a connection string carries its password.
+var secretKeyMarkers = []string{
+	"token", "secret", "password", "passwd", "passcode", "key", "auth",
+	"credential", "cookie", "bearer", "dsn", "database_url",
+}
+
+// isSecretKey reports whether a payload key names a secret.
+func isSecretKey(key string) bool {
+	k := strings.ToLower(strings.ReplaceAll(key, "-", "_"))
+	for _, m := range secretKeyMarkers {
+		if strings.Contains(k, m) {
+			return true
+		}
+	}
+	return false
+}
+
+// RedactPayload returns a deep copy of m with the values of secret-looking
+// keys (tokens, passwords, API keys, ...) replaced, at any depth. Token
+// COUNTS (prompt_tokens, max_tokens, ...) are numbers and are kept. A string
+// value holding a JSON object or array (SHELL_COMMAND accepts env that way)
+// is decoded, redacted and re-encoded.
+func RedactPayload(m map[string]any) map[string]any {
+	if m == nil {
+		return nil
+	}
+	out := make(map[string]any, len(m))
+	for k, v := range m {
+		if isSecretKey(k) {
+			if _, isNumber := v.(float64); isNumber {
+				out[k] = v
+				continue
+			}
+			if _, isInt := v.(int); isInt {
+				out[k] = v
+				continue
+			}
+			out[k] = redactedValue
+			continue
+		}
+		out[k] = redactValue(v)
+	}
+	return out
+}
+
+func redactValue(v any) any {
+	switch x := v.(type) {
+	case map[string]any:
+		return RedactPayload(x)
+	case []any:
+		out := make([]any, len(x))
+		for i, e := range x {
+			out[i] = redactValue(e)
+		}
+		return out
+	case string:
+		return redactJSONString(x)
+	default:
+		return v
+	}
+}
+
+// redactJSONString redacts s when it is a JSON-encoded object or array, and
+// returns it unchanged otherwise.
+func redactJSONString(s string) string {
+	trimmed := strings.TrimSpace(s)
+	if !strings.HasPrefix(trimmed, "{") && !strings.HasPrefix(trimmed, "[") {
+		return s
+	}
+	var decoded any
+	if err := json.Unmarshal([]byte(trimmed), &decoded); err != nil {
+		return s
+	}
+	redacted := redactValue(decoded)
+	if reflect.DeepEqual(redacted, decoded) {
+		return s
+	}
+	b, err := json.Marshal(redacted)
+	if err != nil {
+		return redactedValue
+	}
+	return string(b)
+}
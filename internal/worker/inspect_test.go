@@ -0,0 +1,196 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// flakyHandler streams a chunk, then fails its first run and succeeds after.
+// With block set it instead waits for its context.
+type flakyHandler struct {
+	runs    atomic.Int32
+	block   bool
+	started chan struct{}
+}
+
+func (h *flakyHandler) CanHandle(jobType string) bool { return jobType == "TEST_JOB" }
+
+func (h *flakyHandler) Execute(ctx context.Context, job *Job, stream StreamWriter) (*JobResult, error) {
+	stream.WriteChunk("a", 0)
+	if h.block {
+		close(h.started)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	if h.runs.Add(1) == 1 {
+		return nil, errors.New("engine unavailable")
+	}
+	return &JobResult{Status: JobStatusSuccess, Output: map[string]any{"text": "ok"}}, nil
+}
+
+// startInspectRunner runs source through a runner with job tracking on, until
+// the test ends.
+func startInspectRunner(t *testing.T, source JobSource, h JobHandler) (*Runner, *WorkerState) {
+	t.Helper()
+	state := NewWorkerState()
+	runner := NewRunner(source, []JobHandler{h}, RunnerConfig{
+		WorkerID:           "test",
+		State:              state,
+		CancelPollInterval: time.Hour,
+		ActivityFn:         func(string, string) {},
+	})
+	runner.WithStreamWriterFactory(newRecordingFactory().factory)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		runner.Run(ctx)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return runner, state
+}
+
+// waitForRecent polls until a finished job matches.
+func waitForRecent(t *testing.T, state *WorkerState, match func(JobView) bool) JobView {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		for _, v := range state.RecentJobs() {
+			if match(v) {
+				return v
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("no matching finished job in %+v", state.RecentJobs())
+	return JobView{}
+}
+
+func TestInspectorCancelsInFlightJob(t *testing.T) {
+	h := &flakyHandler{block: true, started: make(chan struct{})}
+	source := NewMockJobSource("test", []*Job{{ID: "job-1", Type: "TEST_JOB", Payload: map[string]any{}}})
+	_, state := startInspectRunner(t, source, h)
+
+	<-h.started
+	inFlight := state.InFlightJobs()
+	if len(inFlight) != 1 || inFlight[0].ID != "job-1" || inFlight[0].Status != "running" || inFlight[0].Chunks != 1 {
+		t.Fatalf("in flight = %+v, want job-1 running with one chunk", inFlight)
+	}
+	if err := state.CancelJob("job-1"); err != nil {
+		t.Fatal(err)
+	}
+
+	v := waitForRecent(t, state, func(v JobView) bool { return v.ID == "job-1" })
+	if v.Status != "cancelled" {
+		t.Errorf("status = %q, want cancelled", v.Status)
+	}
+	if len(state.InFlightJobs()) != 0 {
+		t.Error("job still in flight after cancel")
+	}
+	if err := state.CancelJob("job-1"); err == nil {
+		t.Error("cancelling a finished job succeeded")
+	}
+}
+
+func TestInspectorRerunsFailedJobLocally(t *testing.T) {
+	h := &flakyHandler{}
+	source := NewMockJobSource("test", []*Job{{
+		ID: "job-1", Type: "TEST_JOB", RayID: "ray-1",
+		Payload: map[string]any{"prompt": "hi", "api_key": "sk-secret"},
+	}})
+	runner, state := startInspectRunner(t, source, h)
+
+	failed := waitForRecent(t, state, func(v JobView) bool { return v.ID == "job-1" })
+	if failed.Status != "failed" || failed.Error != "engine unavailable" {
+		t.Fatalf("first run = %+v, want failed", failed)
+	}
+	if failed.Payload["api_key"] != redactedValue || failed.Payload["prompt"] != "hi" {
+		t.Errorf("payload = %v, want the key redacted", failed.Payload)
+	}
+
+	newID, err := runner.RerunJob("job-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(newID, "job-1-rerun-") {
+		t.Errorf("re-run ID = %q", newID)
+	}
+	rerun := waitForRecent(t, state, func(v JobView) bool { return v.ID == newID })
+	if rerun.Status != "success" || rerun.Source != LocalJobSource || rerun.RayID != "ray-1" || rerun.Result["text"] != "ok" {
+		t.Errorf("re-run = %+v, want a local success", rerun)
+	}
+
+	// The re-run is not settled at the source: only the original Nack.
+	if len(source.AckedJobs()) != 0 || len(source.NackedJobs()) != 1 {
+		t.Errorf("acked %d, nacked %d; want the re-run kept off the source", len(source.AckedJobs()), len(source.NackedJobs()))
+	}
+	if _, err := runner.RerunJob(newID); err == nil {
+		t.Error("re-running a successful job succeeded")
+	}
+}
+
+func TestRedactPayload(t *testing.T) {
+	in := map[string]any{
+		"model":         "llama",
+		"max_tokens":    float64(256),
+		"hf_token":      "hf_abc",
+		"Authorization": "Bearer x",
+		"env": map[string]any{
+			"DB_PASSWORD": "pw",
+			"PATH":        "/bin",
+		},
+		"steps": []any{map[string]any{"client-secret": "s"}},
+	}
+	got := RedactPayload(in)
+
+	if got["model"] != "llama" || got["max_tokens"] != float64(256) {
+		t.Errorf("non-secrets changed: %v", got)
+	}
+	if got["hf_token"] != redactedValue || got["Authorization"] != redactedValue {
+		t.Errorf("top-level secrets kept: %v", got)
+	}
+	env := got["env"].(map[string]any)
+	if env["DB_PASSWORD"] != redactedValue || env["PATH"] != "/bin" {
+		t.Errorf("nested env = %v", env)
+	}
+	if step := got["steps"].([]any)[0].(map[string]any); step["client-secret"] != redactedValue {
+		t.Errorf("secret in list kept: %v", step)
+	}
+	if in["hf_token"] != "hf_abc" || in["env"].(map[string]any)["DB_PASSWORD"] != "pw" {
+		t.Error("RedactPayload modified its input")
+	}
+}
+
+func TestRedactPayload_StringEncodedEnv(t *testing.T) {
+	// SHELL_COMMAND takes env as a JSON-encoded string too.
+	in := map[string]any{
+		"command": "./migrate.sh",
+		"env":     `{"DATABASE_URL":"postgres://u:pw@db/app","SIGNING_KEY":"k","X_AUTH":"a","SENTRY_DSN":"https://x@sentry","PATH":"/bin"}`,
+		"note":    "{not json",
+	}
+	got := RedactPayload(in)
+
+	var env map[string]any
+	if err := json.Unmarshal([]byte(got["env"].(string)), &env); err != nil {
+		t.Fatalf("env is no longer a JSON string: %v (%v)", err, got["env"])
+	}
+	for _, k := range []string{"DATABASE_URL", "SIGNING_KEY", "X_AUTH", "SENTRY_DSN"} {
+		if env[k] != redactedValue {
+			t.Errorf("%s kept: %v", k, env[k])
+		}
+	}
+	if env["PATH"] != "/bin" {
+		t.Errorf("PATH = %v, want it kept", env["PATH"])
+	}
+	if got["command"] != "./migrate.sh" || got["note"] != "{not json" {
+		t.Errorf("plain strings changed: %v", got)
+	}
+}
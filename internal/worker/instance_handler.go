@@ -2,9 +2,10 @@
 //
 // INSTANCE_* job handlers (aceteam#5963): EC2-style instance provisioning as a
 // fabric capability. The platform dispatches org-scoped INSTANCE_PROVISION /
-// INSTANCE_START / INSTANCE_STOP / INSTANCE_DESTROY / INSTANCE_STATUS jobs to
-// nodes advertising the `hypervisor:proxmox` capability tag; this handler
-// drives the local hypervisor through an injected provider (the live one wraps
+// INSTANCE_START / INSTANCE_STOP / INSTANCE_DESTROY / INSTANCE_STATUS, plus
+// INSTANCE_SNAPSHOT / INSTANCE_ROLLBACK / INSTANCE_BACKUP, jobs to nodes
+// advertising the `hypervisor:proxmox` capability tag; this handler drives the
+// local hypervisor through an injected provider (the live one wraps
 // internal/proxmox.Provisioner).
 //
 // # Queue gating
@@ -38,6 +39,9 @@ type InstanceProvider interface {
 	Stop(ctx context.Context, ref proxmox.InstanceRef) error
 	Destroy(ctx context.Context, ref proxmox.InstanceRef, instanceID string) error
 	Status(ctx context.Context, ref proxmox.InstanceRef) (*proxmox.InstanceStatus, error)
+	Snapshot(ctx context.Context, ref proxmox.InstanceRef, req proxmox.SnapshotRequest) (*proxmox.SnapshotResult, error)
+	Rollback(ctx context.Context, ref proxmox.InstanceRef, name string) error
+	Backup(ctx context.Context, ref proxmox.InstanceRef, opts proxmox.BackupOptions) (*proxmox.BackupResult, error)
 }
 
 // InstanceHandlerConfig configures an InstanceHandler.
@@ -76,14 +80,16 @@ func NewInstanceHandler(cfg InstanceHandlerConfig) *InstanceHandler {
 func (h *InstanceHandler) CanHandle(jobType string) bool {
 	switch jobType {
 	case JobTypeInstanceProvision, JobTypeInstanceStart, JobTypeInstanceStop,
-		JobTypeInstanceDestroy, JobTypeInstanceStatus:
+		JobTypeInstanceDestroy, JobTypeInstanceStatus, JobTypeInstanceSnapshot,
+		JobTypeInstanceRollback, JobTypeInstanceBackup:
 		return true
 	}
 	return false
 }
 
 // instancePayload is the wire payload shared by the INSTANCE_* job family.
-// Provision uses the identity/sizing fields; lifecycle ops use vmid/pve_node.
+// Provision uses the identity/sizing fields; lifecycle ops use vmid/pve_node,
+// and the snapshot/backup ops add their own optional fields.
 type instancePayload struct {
 	InstanceID        string   `json:"instance_id"`
 	Name              string   `json:"name"`
@@ -94,6 +100,11 @@ type instancePayload struct {
 	SSHAuthorizedKeys []string `json:"ssh_authorized_keys"`
 	VMID              int      `json:"vmid"`
 	PVENode           string   `json:"pve_node"`
+	SnapshotName      string   `json:"snapshot_name"`
+	Description       string   `json:"description"`
+	IncludeRAM        bool     `json:"include_ram"`
+	BackupStorage     string   `json:"backup_storage"`
+	BackupMode        string   `json:"backup_mode"`
 }
 
 // Execute dispatches one INSTANCE_* job to the provider.
@@ -122,8 +133,9 @@ func (h *InstanceHandler) Execute(ctx context.Context, job *Job, stream StreamWr
 	switch job.Type {
 	case JobTypeInstanceProvision:
 		return h.provision(ctx, provider, p)
-	case JobTypeInstanceStart, JobTypeInstanceStop, JobTypeInstanceDestroy, JobTypeInstanceStatus:
-		return h.lifecycle(ctx, provider, job.Type, p)
+	case JobTypeInstanceStart, JobTypeInstanceStop, JobTypeInstanceDestroy, JobTypeInstanceStatus,
+		JobTypeInstanceSnapshot, JobTypeInstanceRollback, JobTypeInstanceBackup:
+		return h.lifecycle(ctx, provider, job.ID, job.Type, p)
 	default:
 		return h.failure(fmt.Errorf("unhandled instance job type %q", job.Type)), nil
 	}
@@ -172,10 +184,18 @@ func (h *InstanceHandler) provision(ctx context.Context, provider InstanceProvid
 	}, nil
 }
 
-func (h *InstanceHandler) lifecycle(ctx context.Context, provider InstanceProvider, jobType string, p instancePayload) (*JobResult, error) {
+func (h *InstanceHandler) lifecycle(ctx context.Context, provider InstanceProvider, jobID, jobType string, p instancePayload) (*JobResult, error) {
 	if p.VMID <= 0 {
 		return h.failure(fmt.Errorf("%s: vmid is required", jobType)), nil
 	}
+	if jobType == JobTypeInstanceRollback && p.SnapshotName == "" {
+		return h.failure(fmt.Errorf("%s: snapshot_name is required", jobType)), nil
+	}
+	if p.SnapshotName != "" {
+		if err := proxmox.ValidateSnapshotName(p.SnapshotName); err != nil {
+			return h.failure(fmt.Errorf("%s: %w", jobType, err)), nil
+		}
+	}
 	ref := proxmox.InstanceRef{VMID: p.VMID, PVENode: p.PVENode}
 	h.cfg.Log("%s: instance=%s vmid=%d", jobType, p.InstanceID, p.VMID)
 
@@ -200,11 +220,54 @@ func (h *InstanceHandler) lifecycle(ctx context.Context, provider InstanceProvid
 			out["cpus"] = st.CPUs
 			out["max_mem_bytes"] = st.MaxMemBytes
 		}
+	case JobTypeInstanceSnapshot:
+		var snap *proxmox.SnapshotResult
+		snap, err = provider.Snapshot(ctx, ref, proxmox.SnapshotRequest{
+			Name:        p.SnapshotName,
+			Description: p.Description,
+			IncludeRAM:  p.IncludeRAM,
+			RequestID:   jobID,
+		})
+		if err == nil {
+			out["snapshot"] = snap.Name
+			out["pool"] = snap.Pool
+			out["pruned"] = snap.Pruned
+		}
+	case JobTypeInstanceRollback:
+		err = provider.Rollback(ctx, ref, p.SnapshotName)
+		out["snapshot"] = p.SnapshotName
+	case JobTypeInstanceBackup:
+		var b *proxmox.BackupResult
+		b, err = provider.Backup(ctx, ref, proxmox.BackupOptions{Storage: p.BackupStorage, Mode: p.BackupMode})
+		if err == nil {
+			out["storage"] = b.Storage
+			out["mode"] = b.Mode
+			out["upid"] = b.UPID
+		}
 	}
 	if err != nil {
-		// Lifecycle ops are idempotent on the PVE side; a transient API error is
-		// worth a retry (DLQ-bounded by the runner's MaxAttempts).
-		return h.retry(fmt.Errorf("%s: %w", jobType, err)), nil
+		err = fmt.Errorf("%s: %w", jobType, err)
+		switch jobType {
+		case JobTypeInstanceSnapshot, JobTypeInstanceRollback, JobTypeInstanceBackup:
+			// A rollback converges on the same state when repeated and a named
+			// snapshot is taken at most once (Snapshot recognises one an
+			// earlier attempt of this job took), so a network error or a PVE
+			// 5xx is worth a retry. An auto-named snapshot or a backup is not:
+			// if the first attempt reached PVE, a retry takes a second one.
+			// Anything else, including a request PVE or we refuse, fails the
+			// job.
+			repeatable := jobType != JobTypeInstanceBackup &&
+				(jobType != JobTypeInstanceSnapshot || p.SnapshotName != "")
+			if repeatable && proxmox.IsTransient(err) {
+				return h.retry(err), nil
+			}
+		default:
+			// Start, stop, destroy and status are idempotent on the PVE side;
+			// a failure is worth a retry (DLQ-bounded by the runner's
+			// MaxAttempts).
+			return h.retry(err), nil
+		}
+		return h.failure(err), nil
 	}
 	return &JobResult{Status: JobStatusSuccess, Output: out}, nil
 }
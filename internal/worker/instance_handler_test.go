@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net"
 	"strings"
 	"testing"
 
@@ -51,6 +52,27 @@ func (f *fakeInstanceProvider) Status(ctx context.Context, ref proxmox.InstanceR
 	return &proxmox.InstanceStatus{VMID: ref.VMID, Status: "running", UptimeSeconds: 42}, nil
 }
 
+func (f *fakeInstanceProvider) Snapshot(ctx context.Context, ref proxmox.InstanceRef, req proxmox.SnapshotRequest) (*proxmox.SnapshotResult, error) {
+	f.calls = append(f.calls, fmt.Sprintf("snapshot:%d:%s", ref.VMID, req.Name))
+	if f.failWith != nil {
+		return nil, f.failWith
+	}
+	return &proxmox.SnapshotResult{Name: req.Name, Pool: "aceteam-org-x", Pruned: []string{"old"}}, nil
+}
+
+func (f *fakeInstanceProvider) Rollback(ctx context.Context, ref proxmox.InstanceRef, name string) error {
+	f.calls = append(f.calls, fmt.Sprintf("rollback:%d:%s", ref.VMID, name))
+	return f.failWith
+}
+
+func (f *fakeInstanceProvider) Backup(ctx context.Context, ref proxmox.InstanceRef, opts proxmox.BackupOptions) (*proxmox.BackupResult, error) {
+	f.calls = append(f.calls, fmt.Sprintf("backup:%d:%s", ref.VMID, opts.Storage))
+	if f.failWith != nil {
+		return nil, f.failWith
+	}
+	return &proxmox.BackupResult{Storage: opts.Storage, Mode: "snapshot", UPID: "UPID:pve1:vzdump:"}, nil
+}
+
 // perNodeQueue is declared in agent_update_test.go.
 const (
 	hypervisorQueue = "jobs:v1:tag:hypervisor:proxmox"
@@ -76,7 +98,8 @@ func TestInstanceHandlerCanHandle(t *testing.T) {
 	h := newTestInstanceHandler(&fakeInstanceProvider{}, nil)
 	for _, jt := range []string{
 		JobTypeInstanceProvision, JobTypeInstanceStart, JobTypeInstanceStop,
-		JobTypeInstanceDestroy, JobTypeInstanceStatus,
+		JobTypeInstanceDestroy, JobTypeInstanceStatus, JobTypeInstanceSnapshot,
+		JobTypeInstanceRollback, JobTypeInstanceBackup,
 	} {
 		if !h.CanHandle(jt) {
 			t.Errorf("expected CanHandle(%s)", jt)
@@ -195,6 +218,9 @@ func TestInstanceHandlerLifecycleOps(t *testing.T) {
 		{JobTypeInstanceStop, "stop:105"},
 		{JobTypeInstanceDestroy, "destroy:105:i-1"},
 		{JobTypeInstanceStatus, "status:105"},
+		{JobTypeInstanceSnapshot, "snapshot:105:before-upgrade"},
+		{JobTypeInstanceRollback, "rollback:105:before-upgrade"},
+		{JobTypeInstanceBackup, "backup:105:pbs"},
 	}
 	for _, tc := range cases {
 		t.Run(tc.jobType, func(t *testing.T) {
@@ -202,6 +228,7 @@ func TestInstanceHandlerLifecycleOps(t *testing.T) {
 			h := newTestInstanceHandler(fake, nil)
 			res, err := h.Execute(context.Background(), instanceJob(tc.jobType, perNodeQueue, map[string]any{
 				"instance_id": "i-1", "vmid": 105, "pve_node": "pve1",
+				"snapshot_name": "before-upgrade", "backup_storage": "pbs",
 			}), &NoOpStreamWriter{})
 			if err != nil {
 				t.Fatalf("Execute: %v", err)
@@ -226,6 +253,38 @@ func TestInstanceHandlerLifecycleRequiresVMID(t *testing.T) {
 	}
 }
 
+func TestInstanceHandlerRollbackRequiresValidSnapshotName(t *testing.T) {
+	for name, payload := range map[string]map[string]any{
+		"missing": {"instance_id": "i-1", "vmid": 105},
+		"invalid": {"instance_id": "i-1", "vmid": 105, "snapshot_name": "../etc"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			fake := &fakeInstanceProvider{}
+			h := newTestInstanceHandler(fake, nil)
+			res, _ := h.Execute(context.Background(), instanceJob(JobTypeInstanceRollback, perNodeQueue, payload), &NoOpStreamWriter{})
+			if res.Status != JobStatusFailure {
+				t.Fatalf("expected terminal failure, got %s", res.Status)
+			}
+			if len(fake.calls) != 0 {
+				t.Errorf("provider must not be called: %v", fake.calls)
+			}
+		})
+	}
+}
+
+func TestInstanceHandlerSnapshotOutput(t *testing.T) {
+	h := newTestInstanceHandler(&fakeInstanceProvider{}, nil)
+	res, _ := h.Execute(context.Background(), instanceJob(JobTypeInstanceSnapshot, hypervisorQueue, map[string]any{
+		"instance_id": "i-1", "vmid": 105, "snapshot_name": "nightly",
+	}), &NoOpStreamWriter{})
+	if res.Status != JobStatusSuccess {
+		t.Fatalf("expected success, got %s: %v", res.Status, res.Error)
+	}
+	if res.Output["snapshot"] != "nightly" || res.Output["pool"] != "aceteam-org-x" {
+		t.Errorf("unexpected output: %v", res.Output)
+	}
+}
+
 func TestInstanceHandlerLifecycleFailureRetries(t *testing.T) {
 	fake := &fakeInstanceProvider{failWith: errors.New("connection refused")}
 	h := newTestInstanceHandler(fake, nil)
@@ -237,6 +296,37 @@ func TestInstanceHandlerLifecycleFailureRetries(t *testing.T) {
 	}
 }
 
+func TestInstanceHandlerNonRepeatableFailuresDoNotRetry(t *testing.T) {
+	transient := &proxmox.APIError{StatusCode: 503, Body: "proxy unavailable"}
+	refused := &proxmox.APIError{StatusCode: 400, Body: `{"errors":{"vmid":"bad"}}`}
+	for _, tc := range []struct {
+		name    string
+		jobType string
+		payload map[string]any
+		err     error
+		want    JobStatus
+	}{
+		{"named snapshot, transient", JobTypeInstanceSnapshot, map[string]any{"snapshot_name": "nightly"}, transient, JobStatusRetry},
+		{"named snapshot, refused", JobTypeInstanceSnapshot, map[string]any{"snapshot_name": "nightly"}, refused, JobStatusFailure},
+		{"named snapshot, already exists", JobTypeInstanceSnapshot, map[string]any{"snapshot_name": "nightly"}, errors.New(`snapshot "nightly" already exists on VM 105`), JobStatusFailure},
+		{"auto-named snapshot", JobTypeInstanceSnapshot, map[string]any{}, transient, JobStatusFailure},
+		{"backup", JobTypeInstanceBackup, map[string]any{}, transient, JobStatusFailure},
+		{"rollback, transient", JobTypeInstanceRollback, map[string]any{"snapshot_name": "nightly"}, &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}, JobStatusRetry},
+		{"rollback, failed task", JobTypeInstanceRollback, map[string]any{"snapshot_name": "nightly"}, &proxmox.TaskError{UPID: "UPID:x", ExitStatus: "boom"}, JobStatusFailure},
+		{"start, failed task", JobTypeInstanceStart, map[string]any{}, &proxmox.TaskError{UPID: "UPID:x", ExitStatus: "boom"}, JobStatusRetry},
+		{"start, task wait timed out", JobTypeInstanceStart, map[string]any{}, errors.New("timed out waiting for task UPID:x"), JobStatusRetry},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newTestInstanceHandler(&fakeInstanceProvider{failWith: tc.err}, nil)
+			tc.payload["instance_id"], tc.payload["vmid"] = "i-1", 105
+			res, _ := h.Execute(context.Background(), instanceJob(tc.jobType, hypervisorQueue, tc.payload), &NoOpStreamWriter{})
+			if res.Status != tc.want {
+				t.Fatalf("status = %s, want %s", res.Status, tc.want)
+			}
+		})
+	}
+}
+
 func TestInstanceHandlerUnconfiguredProviderFailsClearly(t *testing.T) {
 	h := newTestInstanceHandler(nil, errors.New("proxmox is not configured on this node"))
 	res, _ := h.Execute(context.Background(), instanceJob(JobTypeInstanceStatus, hypervisorQueue, map[string]any{
@@ -46,6 +46,10 @@ func (w *recordingStreamWriter) WriteChunk(content string, index int) error {
 	return nil
 }
 
+func (w *recordingStreamWriter) WriteChunkData(content string, index int, data map[string]any) error {
+	return nil
+}
+
 func (w *recordingStreamWriter) WriteEnd(result map[string]any) error {
 	w.mu.Lock()
 	defer w.mu.Unlock()
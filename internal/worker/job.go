@@ -107,6 +107,7 @@ const (
 	JobTypeVLLMInference      = "VLLM_INFERENCE"
 	JobTypeOllamaInference    = "OLLAMA_INFERENCE"
 	JobTypeLLMInference       = "llm_inference"        // Redis worker format
+	JobTypeLLMBatch           = "LLM_BATCH"            // OpenAI Batch-style JSONL inference against the local engine
 	JobTypeEmbedding          = "embedding"            // Redis worker format
 	JobTypeApplyDeviceConfig  = "APPLY_DEVICE_CONFIG"  // Device config from onboarding
 	JobTypeExtraction         = "GLINER_EXTRACTION"    // Entity/relation extraction via GLiNER2
@@ -156,6 +157,9 @@ const (
 	JobTypeInstanceStop      = "INSTANCE_STOP"      // Stop an existing instance VM
 	JobTypeInstanceDestroy   = "INSTANCE_DESTROY"   // Destroy an instance VM and its resources
 	JobTypeInstanceStatus    = "INSTANCE_STATUS"    // Report an instance VM's live status
+	JobTypeInstanceSnapshot  = "INSTANCE_SNAPSHOT"  // Snapshot an instance VM and prune per the pool's retention
+	JobTypeInstanceRollback  = "INSTANCE_ROLLBACK"  // Roll an instance VM back to a snapshot
+	JobTypeInstanceBackup    = "INSTANCE_BACKUP"    // Write a vzdump backup of an instance VM
 )
 
 // allKnownJobTypes enumerates every job type this citadel build knows about.
@@ -172,6 +176,7 @@ var allKnownJobTypes = []string{
 	JobTypeVLLMInference,
 	JobTypeOllamaInference,
 	JobTypeLLMInference,
+	JobTypeLLMBatch,
 	JobTypeEmbedding,
 	JobTypeApplyDeviceConfig,
 	JobTypeExtraction,
@@ -217,4 +222,7 @@ var allKnownJobTypes = []string{
 	JobTypeInstanceStop,
 	JobTypeInstanceDestroy,
 	JobTypeInstanceStatus,
+	JobTypeInstanceSnapshot,
+	JobTypeInstanceRollback,
+	JobTypeInstanceBackup,
 }
@@ -0,0 +1,764 @@
+// internal/worker/llm_batch.go
+//
+// LLM_BATCH job handler: OpenAI Batch API-style bulk inference against the
+// node-local engine.
+//
+// llm_inference carries one prompt per job, so 50k classification prompts cost
+// 50k queue round trips and the engine only ever sees one request at a time.
+// A batch job instead names a JSONL file in the workspace, and the handler
+// keeps up to `concurrency` requests in flight against the engine so vLLM's
+// continuous batching can actually batch.
+//
+// # Contract
+//
+// Request payload:
+//
+//	input_file   string  workspace JSONL, one request per line (required)
+//	output_file  string  results JSONL (default <input>.results.jsonl)
+//	error_file   string  errors JSONL (default <input>.errors.jsonl)
+//	backend      string  engine, as for llm_inference (default vllm)
+//	model        string  model for request bodies that name none
+//	concurrency  int     requests in flight (default 16, max 256)
+//
+// Input lines use the OpenAI Batch request shape:
+//
+//	{"custom_id": "req-1", "method": "POST", "url": "/v1/chat/completions", "body": {...}}
+//
+// url is /v1/chat/completions, /v1/completions or /v1/embeddings. Results and
+// errors use the OpenAI Batch output shape, in completion order:
+//
+//	{"id": "batch_req_1", "custom_id": "req-1", "response": {"status_code": 200, "body": {...}}, "error": null}
+//
+// A request the engine answers with a non-200 status, that is malformed, or
+// whose HTTP exchange fails, goes to the error file; the batch carries on. An
+// engine that stops serving altogether fails the job instead, since every
+// remaining request would fail the same way.
+//
+// # Checkpointing
+//
+// The output files are the checkpoint: every finished request is appended as
+// one line the moment it completes. A re-run with the same payload (after a
+// worker restart, a deadline, or an engine outage) reads the custom_ids already
+// present, drops a torn final line, and sends only the rest. Failures that may
+// succeed on a retry (a failed exchange, or an engine 429 or 5xx) are not
+// final: a re-run removes them from the error file and sends those requests
+// again. A small <output>.checkpoint.json records which input the files belong
+// to (by SHA-256), so a changed input starts over instead of mixing two
+// batches.
+//
+// # Progress
+//
+// Progress counts are streamed as JSON chunks through the StreamWriter
+// ({"total", "completed", "failed", "resumed"}) at most every
+// batchProgressInterval and once at the end.
+package worker
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aceteam-ai/citadel-cli/internal/jobs"
+)
+
+const (
+	// batchDefaultConcurrency keeps enough requests in flight for an engine to
+	// batch without queueing a whole file's worth of HTTP connections.
+	batchDefaultConcurrency = 16
+	batchMaxConcurrency     = 256
+
+	// batchMaxLineBytes bounds one input line. Requests are prompts, not
+	// documents; a longer line fails the job rather than the node.
+	batchMaxLineBytes = 16 << 20
+
+	// batchMaxResponseBytes bounds one engine response body.
+	batchMaxResponseBytes = 32 << 20
+
+	// batchProgressInterval rate-limits progress chunks and checkpoint writes.
+	batchProgressInterval = 2 * time.Second
+)
+
+// batchEndpoints are the request URLs a batch line may target.
+var batchEndpoints = map[string]bool{
+	"/v1/chat/completions": true,
+	"/v1/completions":      true,
+	"/v1/embeddings":       true,
+}
+
+// errBatchEngineGone aborts a batch whose engine stopped answering.
+var errBatchEngineGone = errors.New("engine stopped serving")
+
+// LLMBatchConfig configures the LLM_BATCH handler.
+type LLMBatchConfig struct {
+	// Roots are the directories batch files may live in (the node workspace).
+	// Every path is validated through jobs.ValidateWithinRoots.
+	Roots []string
+	// Inference supplies the engine endpoints, readiness probe, hotswap and
+	// HTTP client, so a batch reaches an engine exactly as llm_inference does.
+	Inference *LLMInferenceHandler
+	// Log reports batch start/finish. Optional.
+	Log func(format string, args ...any)
+}
+
+// LLMBatchHandler runs LLM_BATCH jobs.
+type LLMBatchHandler struct {
+	cfg LLMBatchConfig
+}
+
+// NewLLMBatchHandler creates an LLM_BATCH handler.
+func NewLLMBatchHandler(cfg LLMBatchConfig) *LLMBatchHandler {
+	if cfg.Inference == nil {
+		cfg.Inference = NewLLMInferenceHandler()
+	}
+	if cfg.Log == nil {
+		cfg.Log = func(string, ...any) {}
+	}
+	return &LLMBatchHandler{cfg: cfg}
+}
+
+// CanHandle reports whether this handler processes the given job type.
+func (h *LLMBatchHandler) CanHandle(jobType string) bool {
+	return jobType == JobTypeLLMBatch
+}
+
+// batchPayload is the parsed LLM_BATCH payload.
+type batchPayload struct {
+	InputFile   string `json:"input_file"`
+	OutputFile  string `json:"output_file"`
+	ErrorFile   string `json:"error_file"`
+	Backend     string `json:"backend"`
+	Model       string `json:"model"`
+	Concurrency int    `json:"concurrency"`
+}
+
+// batchRequest is one input line.
+type batchRequest struct {
+	CustomID string          `json:"custom_id"`
+	Method   string          `json:"method"`
+	URL      string          `json:"url"`
+	Body     json.RawMessage `json:"body"`
+}
+
+// batchResponse is the response half of an output line.
+type batchResponse struct {
+	StatusCode int             `json:"status_code"`
+	Body       json.RawMessage `json:"body"`
+}
+
+// batchError is the error half of an output line.
+type batchError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// batchLine is one line of the results or errors file.
+type batchLine struct {
+	ID       string         `json:"id"`
+	CustomID string         `json:"custom_id"`
+	Response *batchResponse `json:"response"`
+	Error    *batchError    `json:"error"`
+}
+
+// batchRecord is the part of a results or errors line a re-run needs to tell
+// which requests are finished; the response body is not decoded.
+type batchRecord struct {
+	CustomID string      `json:"custom_id"`
+	Error    *batchError `json:"error"`
+	Response *struct {
+		StatusCode int `json:"status_code"`
+	} `json:"response"`
+}
+
+// batchCheckpoint ties the output files to the input they were produced from.
+type batchCheckpoint struct {
+	InputFile   string    `json:"input_file"`
+	InputSHA256 string    `json:"input_sha256"`
+	Total       int       `json:"total"`
+	Completed   int       `json:"completed"`
+	Failed      int       `json:"failed"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	Done        bool      `json:"done"`
+}
+
+// batchProgress is the streamed progress chunk and the job's counts.
+// Completed and Failed cover the whole batch, including requests finished by
+// an earlier run; Resumed is how many of them that was.
+type batchProgress struct {
+	Total     int `json:"total"`
+	Completed int `json:"completed"`
+	Failed    int `json:"failed"`
+	Resumed   int `json:"resumed"`
+}
+
+// Execute runs the batch and returns its counts and file paths.
+func (h *LLMBatchHandler) Execute(ctx context.Context, job *Job, stream StreamWriter) (*JobResult, error) {
+	start := time.Now()
+	p, err := parseBatchPayload(job.Payload)
+	if err != nil {
+		return h.failure(fmt.Errorf("invalid payload: %w", err)), nil
+	}
+	input, output, errorsPath, err := h.resolvePaths(p)
+	if err != nil {
+		return h.failure(err), nil
+	}
+	inputSum, total, err := scanBatchInput(input)
+	if err != nil {
+		return h.failure(err), nil
+	}
+
+	checkpointPath := output + ".checkpoint.json"
+	done := map[string]bool{}
+	progress := batchProgress{Total: total}
+	cp, _ := loadBatchCheckpoint(checkpointPath)
+	if cp != nil && cp.InputSHA256 == inputSum {
+		if progress.Completed, err = collectBatchDone(output, done, nil); err == nil {
+			progress.Failed, err = collectBatchDone(errorsPath, done, batchRetryable)
+		}
+		if err != nil {
+			return h.failure(fmt.Errorf("cannot resume batch: %w", err)), nil
+		}
+		progress.Resumed = len(done)
+	} else {
+		// A fresh batch, or the input changed since the files were written.
+		for _, path := range []string{output, errorsPath} {
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				return h.failure(err), nil
+			}
+		}
+	}
+	cp = &batchCheckpoint{InputFile: input, InputSHA256: inputSum, Total: total}
+	if err := saveBatchCheckpoint(checkpointPath, cp); err != nil {
+		return h.failure(err), nil
+	}
+
+	inf := h.cfg.Inference
+	if inf.swapper != nil && p.Model != "" {
+		outcome, err := inf.ensureResident(ctx, p.Backend, p.Model)
+		if err != nil {
+			return h.failure(fmt.Errorf("model hotswap failed: %w", err)), nil
+		}
+		if !outcome.Ready {
+			return inf.warming(p.Model, outcome.ETASeconds, outcome.RetryAfterSeconds), nil
+		}
+	}
+	if err := inf.ensureEngineReady(ctx, p.Backend); err != nil {
+		if errors.Is(err, errEngineWarming) {
+			return inf.warming(p.Model, engineWarmETA(p.Backend), 0), nil
+		}
+		return h.failure(err), nil
+	}
+	baseURL := inf.baseURL(p.Backend)
+	if baseURL == "" {
+		return h.failure(fmt.Errorf("unsupported backend: %s", p.Backend)), nil
+	}
+
+	out, err := openBatchAppend(output)
+	if err != nil {
+		return h.failure(err), nil
+	}
+	defer out.Close()
+	errOut, err := openBatchAppend(errorsPath)
+	if err != nil {
+		return h.failure(err), nil
+	}
+	defer errOut.Close()
+
+	h.cfg.Log("LLM_BATCH %s: %d requests (%d already done), concurrency %d", job.ID, total, progress.Resumed, p.Concurrency)
+	run := &batchRun{
+		handler:    h,
+		payload:    p,
+		baseURL:    baseURL,
+		out:        out,
+		errOut:     errOut,
+		stream:     stream,
+		checkpoint: cp,
+		cpPath:     checkpointPath,
+		progress:   progress,
+	}
+	runErr := run.run(ctx, input, done)
+
+	progress = run.finish(runErr == nil)
+	result := map[string]any{
+		"input_file":  input,
+		"output_file": output,
+		"error_file":  errorsPath,
+		"total":       progress.Total,
+		"completed":   progress.Completed,
+		"failed":      progress.Failed,
+		"resumed":     progress.Resumed,
+		"duration_ms": time.Since(start).Milliseconds(),
+	}
+	if runErr != nil {
+		// Partial progress is kept; re-running the same job resumes from it.
+		result["error"] = runErr.Error()
+		return &JobResult{Status: JobStatusFailure, Error: runErr, Output: result}, nil
+	}
+	h.cfg.Log("LLM_BATCH %s: %d completed, %d failed in %s", job.ID,
+		progress.Completed, progress.Failed, time.Since(start).Round(time.Second))
+	result["status"] = "completed"
+	return &JobResult{Status: JobStatusSuccess, Output: result}, nil
+}
+
+// parseBatchPayload decodes and defaults the payload.
+func parseBatchPayload(data map[string]any) (*batchPayload, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	var p batchPayload
+	if err := json.Unmarshal(raw, &p); err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(p.InputFile) == "" {
+		return nil, fmt.Errorf("input_file is required")
+	}
+	if p.Backend == "" {
+		p.Backend = "vllm" // same default as llm_inference
+	}
+	if p.Concurrency <= 0 {
+		p.Concurrency = batchDefaultConcurrency
+	}
+	if p.Concurrency > batchMaxConcurrency {
+		p.Concurrency = batchMaxConcurrency
+	}
+	return &p, nil
+}
+
+// resolvePaths validates the input and output paths against the roots.
+func (h *LLMBatchHandler) resolvePaths(p *batchPayload) (input, output, errorsPath string, err error) {
+	input, err = jobs.ValidateWithinRoots(h.cfg.Roots, p.InputFile)
+	if err != nil {
+		return "", "", "", fmt.Errorf("input_file: %w", err)
+	}
+	base := strings.TrimSuffix(input, ".jsonl")
+	if p.OutputFile == "" {
+		p.OutputFile = base + ".results.jsonl"
+	}
+	if p.ErrorFile == "" {
+		p.ErrorFile = base + ".errors.jsonl"
+	}
+	if output, err = jobs.ValidateWithinRoots(h.cfg.Roots, p.OutputFile); err != nil {
+		return "", "", "", fmt.Errorf("output_file: %w", err)
+	}
+	if errorsPath, err = jobs.ValidateWithinRoots(h.cfg.Roots, p.ErrorFile); err != nil {
+		return "", "", "", fmt.Errorf("error_file: %w", err)
+	}
+	if output == input || errorsPath == input || output == errorsPath {
+		return "", "", "", fmt.Errorf("input_file, output_file and error_file must be distinct")
+	}
+	return input, output, errorsPath, nil
+}
+
+// scanBatchInput hashes the input and counts its requests (non-blank lines).
+func scanBatchInput(path string) (sum string, total int, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", 0, fmt.Errorf("cannot open input_file: %w", err)
+	}
+	defer f.Close()
+	hash := sha256.New()
+	scanner := newBatchScanner(io.TeeReader(f, hash))
+	for scanner.Scan() {
+		if len(bytes.TrimSpace(scanner.Bytes())) > 0 {
+			total++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", 0, fmt.Errorf("cannot read input_file: %w", err)
+	}
+	// Drain anything the scanner did not consume so the hash covers the file.
+	if _, err := io.Copy(hash, f); err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), total, nil
+}
+
+func newBatchScanner(r io.Reader) *bufio.Scanner {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), batchMaxLineBytes)
+	return scanner
+}
+
+// collectBatchDone adds the custom_ids already recorded in path to done and
+// returns how many there were. A torn final line (the worker died mid-write)
+// is truncated away so the file stays valid JSONL and that request is re-sent.
+// Records retry reports true for are removed from the file and not counted,
+// so those requests are re-sent too.
+//
+// The file is read a line at a time and only each record's custom_id, error
+// and status code are decoded, so a results file full of large response
+// bodies is never held in memory.
+func collectBatchDone(path string, done map[string]bool, retry func(*batchRecord) bool) (int, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	tmp := path + ".tmp"
+	var kept *os.File // the rewritten file, opened at the first dropped record
+	defer func() {
+		if kept != nil {
+			kept.Close()
+			os.Remove(tmp)
+		}
+	}()
+
+	r := bufio.NewReader(f)
+	var offset int64
+	n := 0
+	for {
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF {
+			if len(line) > 0 {
+				if err := os.Truncate(path, offset); err != nil {
+					return 0, err
+				}
+			}
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+		var rec batchRecord
+		drop := json.Unmarshal(line, &rec) == nil && rec.CustomID != "" && retry != nil && retry(&rec)
+		if drop && kept == nil {
+			if kept, err = startBatchRewrite(path, tmp, offset); err != nil {
+				return 0, err
+			}
+		}
+		offset += int64(len(line))
+		if drop {
+			continue
+		}
+		if kept != nil {
+			if _, err := kept.Write(line); err != nil {
+				return 0, err
+			}
+		}
+		if rec.CustomID != "" && !done[rec.CustomID] {
+			done[rec.CustomID] = true
+			n++
+		}
+	}
+	if kept != nil {
+		err := kept.Close()
+		kept = nil
+		if err == nil {
+			err = os.Rename(tmp, path)
+		}
+		if err != nil {
+			os.Remove(tmp)
+			return 0, err
+		}
+	}
+	return n, nil
+}
+
+// startBatchRewrite creates tmp holding the first n bytes of path, the lines
+// collectBatchDone kept before it dropped one.
+func startBatchRewrite(path, tmp string, n int64) (*os.File, error) {
+	src, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+	dst, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.CopyN(dst, src, n); err != nil {
+		dst.Close()
+		os.Remove(tmp)
+		return nil, err
+	}
+	return dst, nil
+}
+
+// batchRetryable reports whether a recorded failure may succeed if the
+// request is sent again: the HTTP exchange itself failed, or the engine was
+// overloaded or erred (429, 5xx). Malformed lines and other engine answers
+// are final.
+func batchRetryable(rec *batchRecord) bool {
+	if rec.Error == nil {
+		return false
+	}
+	switch rec.Error.Code {
+	case "request_failed":
+		return true
+	case "engine_error":
+		return rec.Response != nil &&
+			(rec.Response.StatusCode == http.StatusTooManyRequests || rec.Response.StatusCode >= 500)
+	}
+	return false
+}
+
+func loadBatchCheckpoint(path string) (*batchCheckpoint, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cp batchCheckpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return nil, err
+	}
+	return &cp, nil
+}
+
+func saveBatchCheckpoint(path string, cp *batchCheckpoint) error {
+	cp.UpdatedAt = time.Now()
+	data, err := json.MarshalIndent(cp, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func openBatchAppend(path string) (*os.File, error) {
+	return os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+}
+
+// batchRun is one execution of a batch: the worker pool and its shared state.
+type batchRun struct {
+	handler *LLMBatchHandler
+	payload *batchPayload
+	baseURL string
+	out     *os.File
+	errOut  *os.File
+	stream  StreamWriter
+
+	mu           sync.Mutex
+	checkpoint   *batchCheckpoint
+	cpPath       string
+	progress     batchProgress
+	chunkIndex   int
+	lastProgress time.Time
+}
+
+// batchItem is one request handed to a worker.
+type batchItem struct {
+	line int
+	req  batchRequest
+	err  *batchError // set when the line itself is invalid
+}
+
+// run feeds the input to payload.Concurrency workers and waits for them. It
+// returns the first fatal error (engine gone, write failure, cancellation).
+func (r *batchRun) run(ctx context.Context, input string, done map[string]bool) error {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	items := make(chan batchItem)
+	var wg sync.WaitGroup
+	for range r.payload.Concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for item := range items {
+				if err := r.process(ctx, item); err != nil {
+					cancel(err)
+				}
+			}
+		}()
+	}
+
+	feedErr := r.feed(ctx, input, done, items)
+	close(items)
+	wg.Wait()
+
+	if err := context.Cause(ctx); err != nil && !errors.Is(err, context.Canceled) {
+		return err
+	}
+	if feedErr != nil {
+		return feedErr
+	}
+	return ctx.Err()
+}
+
+// feed reads the input and sends every request not yet done to items.
+func (r *batchRun) feed(ctx context.Context, input string, done map[string]bool, items chan<- batchItem) error {
+	f, err := os.Open(input)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	seen := map[string]bool{}
+	scanner := newBatchScanner(f)
+	line := 0
+	for scanner.Scan() {
+		raw := bytes.TrimSpace(scanner.Bytes())
+		if len(raw) == 0 {
+			continue
+		}
+		line++
+		item := batchItem{line: line}
+		if err := json.Unmarshal(raw, &item.req); err != nil {
+			item.err = &batchError{Code: "invalid_json", Message: err.Error()}
+		}
+		if item.req.CustomID == "" {
+			item.req.CustomID = fmt.Sprintf("line-%d", line)
+		}
+		if done[item.req.CustomID] {
+			continue
+		}
+		if item.err == nil {
+			item.err = validateBatchRequest(&item.req)
+		}
+		if item.err == nil && seen[item.req.CustomID] {
+			item.err = &batchError{Code: "duplicate_custom_id", Message: "custom_id already used earlier in this batch"}
+		}
+		seen[item.req.CustomID] = true
+
+		select {
+		case items <- item:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+	return scanner.Err()
+}
+
+// validateBatchRequest checks a request line's method, url and body.
+func validateBatchRequest(req *batchRequest) *batchError {
+	if req.Method != "" && !strings.EqualFold(req.Method, http.MethodPost) {
+		return &batchError{Code: "invalid_method", Message: fmt.Sprintf("method %q is not supported (want POST)", req.Method)}
+	}
+	if !batchEndpoints[req.URL] {
+		return &batchError{Code: "invalid_url", Message: fmt.Sprintf("url %q is not supported (want /v1/chat/completions, /v1/completions or /v1/embeddings)", req.URL)}
+	}
+	var body map[string]any
+	if err := json.Unmarshal(req.Body, &body); err != nil || body == nil {
+		return &batchError{Code: "invalid_body", Message: "body must be a JSON object"}
+	}
+	return nil
+}
+
+// process sends one request and records its outcome. A non-nil error is
+// fatal to the whole batch.
+func (r *batchRun) process(ctx context.Context, item batchItem) error {
+	if ctx.Err() != nil {
+		return nil
+	}
+	rec := batchLine{ID: fmt.Sprintf("batch_req_%d", item.line), CustomID: item.req.CustomID}
+	if item.err != nil {
+		rec.Error = item.err
+		return r.record(&rec)
+	}
+
+	var body map[string]any
+	_ = json.Unmarshal(item.req.Body, &body)
+	if _, ok := body["model"]; !ok && r.payload.Model != "" {
+		body["model"] = r.payload.Model
+	}
+	// Results are written whole; a streamed response has nowhere to go.
+	delete(body, "stream")
+	delete(body, "stream_options")
+
+	resp, err := r.handler.cfg.Inference.postJSON(ctx, r.baseURL+item.req.URL, body)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil
+		}
+		if isEngineNotServing(err) || isConnectionRefused(err) {
+			return fmt.Errorf("%w at request %s: %v", errBatchEngineGone, item.req.CustomID, err)
+		}
+		rec.Error = &batchError{Code: "request_failed", Message: err.Error()}
+		return r.record(&rec)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, batchMaxResponseBytes))
+	resp.Body.Close()
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil
+		}
+		rec.Error = &batchError{Code: "request_failed", Message: err.Error()}
+		return r.record(&rec)
+	}
+	if !json.Valid(data) {
+		data, _ = json.Marshal(string(data))
+	}
+	rec.Response = &batchResponse{StatusCode: resp.StatusCode, Body: data}
+	if resp.StatusCode != http.StatusOK {
+		rec.Error = &batchError{Code: "engine_error", Message: fmt.Sprintf("engine returned status %d", resp.StatusCode)}
+	}
+	return r.record(&rec)
+}
+
+// record appends rec to the results or errors file and updates progress.
+func (r *batchRun) record(rec *batchLine) error {
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	dst := r.out
+	if rec.Error != nil {
+		dst = r.errOut
+		r.progress.Failed++
+	} else {
+		r.progress.Completed++
+	}
+	if _, err := dst.Write(line); err != nil {
+		return fmt.Errorf("cannot write batch results: %w", err)
+	}
+	if time.Since(r.lastProgress) >= batchProgressInterval {
+		r.reportLocked(false)
+	}
+	return nil
+}
+
+// finish writes the final checkpoint and progress chunk and returns the counts.
+func (r *batchRun) finish(done bool) batchProgress {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.reportLocked(done)
+	return r.progress
+}
+
+// reportLocked streams a progress chunk and saves the checkpoint. r.mu held.
+func (r *batchRun) reportLocked(done bool) {
+	r.lastProgress = time.Now()
+	r.checkpoint.Completed = r.progress.Completed
+	r.checkpoint.Failed = r.progress.Failed
+	r.checkpoint.Done = done
+	_ = saveBatchCheckpoint(r.cpPath, r.checkpoint)
+	if r.stream != nil {
+		if chunk, err := json.Marshal(r.progress); err == nil {
+			r.stream.WriteChunk(string(chunk), r.chunkIndex)
+			r.chunkIndex++
+		}
+	}
+}
+
+func (h *LLMBatchHandler) failure(err error) *JobResult {
+	return &JobResult{
+		Status: JobStatusFailure,
+		Error:  err,
+		Output: map[string]any{"error": err.Error()},
+	}
+}
+
+// Ensure LLMBatchHandler implements JobHandler.
+var _ JobHandler = (*LLMBatchHandler)(nil)
@@ -0,0 +1,317 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// newBatchEngine returns a vLLM stand-in that answers chat completions with the
+// request's model and first message, and 400 for any custom "fail" prompt. It
+// counts completion requests.
+func newBatchEngine(t *testing.T, calls *atomic.Int32) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if serveReadinessProbe(w, r) {
+			return
+		}
+		calls.Add(1)
+		var req struct {
+			Model    string `json:"model"`
+			Stream   *bool  `json:"stream"`
+			Messages []struct {
+				Content string `json:"content"`
+			} `json:"messages"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		if req.Stream != nil {
+			http.Error(w, `{"error":"stream not stripped"}`, http.StatusBadRequest)
+			return
+		}
+		if len(req.Messages) > 0 && req.Messages[0].Content == "fail" {
+			http.Error(w, `{"error":"bad prompt"}`, http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"model":   req.Model,
+			"choices": []map[string]any{{"message": map[string]string{"content": "echo:" + req.Messages[0].Content}}},
+		})
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func batchLineFor(id, content string) string {
+	return `{"custom_id":"` + id + `","method":"POST","url":"/v1/chat/completions","body":{"messages":[{"role":"user","content":"` + content + `"}],"stream":true}}`
+}
+
+func newBatchTestHandler(t *testing.T, engineURL string) (*LLMBatchHandler, string) {
+	t.Helper()
+	dir := t.TempDir()
+	inf := NewLLMInferenceHandler()
+	inf.baseURLs["vllm"] = engineURL
+	return NewLLMBatchHandler(LLMBatchConfig{Roots: []string{dir}, Inference: inf}), dir
+}
+
+// readBatchLines parses a results or errors JSONL file, keyed by custom_id.
+func readBatchLines(t *testing.T, path string) map[string]batchLine {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := map[string]batchLine{}
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		if line == "" {
+			continue
+		}
+		var rec batchLine
+		if err := json.Unmarshal([]byte(line), &rec); err != nil {
+			t.Fatalf("invalid JSONL line %q: %v", line, err)
+		}
+		if _, dup := out[rec.CustomID]; dup {
+			t.Errorf("custom_id %q recorded twice", rec.CustomID)
+		}
+		out[rec.CustomID] = rec
+	}
+	return out
+}
+
+// chunkRecorder captures streamed chunks.
+type chunkRecorder struct {
+	NoOpStreamWriter
+	mu     sync.Mutex
+	chunks []string
+}
+
+func (c *chunkRecorder) WriteChunk(content string, index int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.chunks = append(c.chunks, content)
+	return nil
+}
+
+func TestLLMBatch_RunsRequestsAndSplitsErrors(t *testing.T) {
+	var calls atomic.Int32
+	engine := newBatchEngine(t, &calls)
+	h, dir := newBatchTestHandler(t, engine.URL)
+
+	input := strings.Join([]string{
+		batchLineFor("a", "one"),
+		batchLineFor("b", "two"),
+		batchLineFor("c", "fail"),
+		`{"custom_id":"d","method":"POST","url":"/v1/images/generations","body":{}}`,
+		batchLineFor("a", "dup"),
+		"",
+	}, "\n")
+	if err := os.WriteFile(filepath.Join(dir, "prompts.jsonl"), []byte(input), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	stream := &chunkRecorder{}
+	res, err := h.Execute(context.Background(), &Job{ID: "b1", Type: JobTypeLLMBatch, Payload: map[string]any{
+		"input_file": "prompts.jsonl", "model": "qwen", "concurrency": 3,
+	}}, stream)
+	if err != nil || res.Status != JobStatusSuccess {
+		t.Fatalf("Execute = %+v, %v; want success", res, err)
+	}
+	if res.Output["completed"] != 2 || res.Output["failed"] != 3 || res.Output["total"] != 5 {
+		t.Errorf("counts = %v, want 2 completed, 3 failed of 5", res.Output)
+	}
+
+	results := readBatchLines(t, filepath.Join(dir, "prompts.results.jsonl"))
+	if len(results) != 2 {
+		t.Fatalf("results = %v, want a and b", results)
+	}
+	var body struct {
+		Model   string `json:"model"`
+		Choices []struct {
+			Message struct{ Content string } `json:"message"`
+		} `json:"choices"`
+	}
+	_ = json.Unmarshal(results["a"].Response.Body, &body)
+	if results["a"].Response.StatusCode != 200 || body.Model != "qwen" || body.Choices[0].Message.Content != "echo:one" {
+		t.Errorf("result a = %+v (%s), want the engine's reply with the default model", results["a"], results["a"].Response.Body)
+	}
+
+	errs := readBatchLines(t, filepath.Join(dir, "prompts.errors.jsonl"))
+	if errs["c"].Error == nil || errs["c"].Response == nil || errs["c"].Response.StatusCode != 400 {
+		t.Errorf("error c = %+v, want the engine's 400 recorded", errs["c"])
+	}
+	if errs["d"].Error == nil || errs["d"].Error.Code != "invalid_url" {
+		t.Errorf("error d = %+v, want invalid_url", errs["d"])
+	}
+	if calls.Load() != 3 {
+		t.Errorf("engine saw %d requests, want 3 (invalid and duplicate lines are not sent)", calls.Load())
+	}
+
+	if len(stream.chunks) == 0 {
+		t.Fatal("no progress streamed")
+	}
+	var last batchProgress
+	_ = json.Unmarshal([]byte(stream.chunks[len(stream.chunks)-1]), &last)
+	if last != (batchProgress{Total: 5, Completed: 2, Failed: 3}) {
+		t.Errorf("final progress = %+v", last)
+	}
+}
+
+func TestLLMBatch_ResumesFromCheckpoint(t *testing.T) {
+	var calls atomic.Int32
+	engine := newBatchEngine(t, &calls)
+	h, dir := newBatchTestHandler(t, engine.URL)
+
+	var lines []string
+	for _, id := range []string{"a", "b", "c", "d"} {
+		lines = append(lines, batchLineFor(id, id))
+	}
+	inputPath := filepath.Join(dir, "in.jsonl")
+	os.WriteFile(inputPath, []byte(strings.Join(lines, "\n")+"\n"), 0644)
+	job := &Job{ID: "b2", Type: JobTypeLLMBatch, Payload: map[string]any{"input_file": inputPath, "model": "m"}}
+
+	if res, _ := h.Execute(context.Background(), job, nil); res.Status != JobStatusSuccess {
+		t.Fatalf("first run failed: %v", res.Error)
+	}
+
+	// Simulate a crash after two results, the second torn mid-write.
+	resultsPath := filepath.Join(dir, "in.results.jsonl")
+	data, _ := os.ReadFile(resultsPath)
+	kept := strings.SplitAfterN(string(data), "\n", 3)
+	os.WriteFile(resultsPath, []byte(kept[0]+kept[1][:10]), 0644)
+
+	calls.Store(0)
+	res, _ := h.Execute(context.Background(), job, nil)
+	if res.Status != JobStatusSuccess {
+		t.Fatalf("resumed run failed: %v", res.Error)
+	}
+	if calls.Load() != 3 || res.Output["resumed"] != 1 || res.Output["completed"] != 4 {
+		t.Errorf("resume sent %d requests, output %v; want 3 sent, 1 resumed, 4 completed", calls.Load(), res.Output)
+	}
+	if got := readBatchLines(t, resultsPath); len(got) != 4 {
+		t.Errorf("results after resume = %d lines, want 4", len(got))
+	}
+
+	// A changed input starts over rather than mixing batches.
+	os.WriteFile(inputPath, []byte(batchLineFor("z", "z")+"\n"), 0644)
+	calls.Store(0)
+	res, _ = h.Execute(context.Background(), job, nil)
+	if got := readBatchLines(t, resultsPath); len(got) != 1 || calls.Load() != 1 {
+		t.Errorf("changed input: results %v, %d requests; want only z", got, calls.Load())
+	}
+}
+
+func TestLLMBatch_ResumeRetriesTransientFailures(t *testing.T) {
+	var calls atomic.Int32
+	var overloaded atomic.Bool
+	overloaded.Store(true)
+	engine := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if serveReadinessProbe(w, r) {
+			return
+		}
+		calls.Add(1)
+		var req struct {
+			Messages []struct {
+				Content string `json:"content"`
+			} `json:"messages"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		switch content := req.Messages[0].Content; {
+		case content == "fail":
+			http.Error(w, `{"error":"bad prompt"}`, http.StatusBadRequest)
+		case content == "busy" && overloaded.Load():
+			http.Error(w, `{"error":"overloaded"}`, http.StatusServiceUnavailable)
+		default:
+			json.NewEncoder(w).Encode(map[string]any{"choices": []any{}})
+		}
+	}))
+	defer engine.Close()
+	h, dir := newBatchTestHandler(t, engine.URL)
+	input := batchLineFor("ok", "ok") + "\n" + batchLineFor("bad", "fail") + "\n" + batchLineFor("busy", "busy") + "\n"
+	os.WriteFile(filepath.Join(dir, "in.jsonl"), []byte(input), 0644)
+	job := &Job{ID: "b5", Type: JobTypeLLMBatch, Payload: map[string]any{"input_file": "in.jsonl"}}
+
+	if res, _ := h.Execute(context.Background(), job, nil); res.Output["failed"] != 2 {
+		t.Fatalf("first run = %v, want bad and busy failed", res.Output)
+	}
+
+	overloaded.Store(false)
+	calls.Store(0)
+	res, _ := h.Execute(context.Background(), job, nil)
+	if calls.Load() != 1 || res.Output["completed"] != 2 || res.Output["failed"] != 1 || res.Output["resumed"] != 2 {
+		t.Errorf("resume sent %d requests, output %v; want only busy re-sent", calls.Load(), res.Output)
+	}
+	errs := readBatchLines(t, filepath.Join(dir, "in.errors.jsonl"))
+	if _, ok := errs["busy"]; ok || errs["bad"].Error == nil {
+		t.Errorf("errors after resume = %v, want only bad", errs)
+	}
+	if got := readBatchLines(t, filepath.Join(dir, "in.results.jsonl")); len(got) != 2 {
+		t.Errorf("results after resume = %v, want ok and busy", got)
+	}
+}
+
+func TestLLMBatch_EngineGoneFailsJob(t *testing.T) {
+	engine := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if serveReadinessProbe(w, r) {
+			return
+		}
+		conn, _, _ := w.(http.Hijacker).Hijack()
+		conn.Close()
+	}))
+	defer engine.Close()
+	h, dir := newBatchTestHandler(t, engine.URL)
+	os.WriteFile(filepath.Join(dir, "in.jsonl"), []byte(batchLineFor("a", "a")+"\n"+batchLineFor("b", "b")+"\n"), 0644)
+
+	res, _ := h.Execute(context.Background(), &Job{ID: "b3", Type: JobTypeLLMBatch, Payload: map[string]any{"input_file": "in.jsonl"}}, nil)
+	if res.Status != JobStatusFailure || !errors.Is(res.Error, errBatchEngineGone) {
+		t.Errorf("result = %+v, want failure with errBatchEngineGone", res)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "in.errors.jsonl")); len(data) != 0 {
+		t.Errorf("engine outage recorded as per-request errors: %s", data)
+	}
+}
+
+func TestLLMBatch_RejectsPathsOutsideRoots(t *testing.T) {
+	h, dir := newBatchTestHandler(t, "http://127.0.0.1:1")
+	os.WriteFile(filepath.Join(dir, "in.jsonl"), []byte(batchLineFor("a", "a")), 0644)
+
+	for name, payload := range map[string]map[string]any{
+		"input":  {"input_file": "../outside.jsonl"},
+		"output": {"input_file": "in.jsonl", "output_file": "/tmp/elsewhere.jsonl"},
+		"same":   {"input_file": "in.jsonl", "output_file": "in.jsonl"},
+	} {
+		res, _ := h.Execute(context.Background(), &Job{Type: JobTypeLLMBatch, Payload: payload}, nil)
+		if res.Status != JobStatusFailure {
+			t.Errorf("%s: status = %s, want failure", name, res.Status)
+		}
+	}
+}
+
+func TestCollectBatchDone_StreamsLongLinesAndDropsRetries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.jsonl")
+	long := `{"custom_id":"big","response":{"status_code":200,"body":"` + strings.Repeat("x", 1<<20) + `"},"error":null}` + "\n"
+	busy := `{"custom_id":"busy","response":{"status_code":503,"body":{}},"error":{"code":"engine_error","message":"engine returned status 503"}}` + "\n"
+	bad := `{"custom_id":"bad","response":{"status_code":400,"body":{}},"error":{"code":"engine_error","message":"engine returned status 400"}}` + "\n"
+	os.WriteFile(path, []byte(long+busy+bad+`{"custom_id":"torn"`), 0644)
+
+	done := map[string]bool{}
+	n, err := collectBatchDone(path, done, batchRetryable)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 || !done["big"] || !done["bad"] || done["busy"] || done["torn"] {
+		t.Errorf("n = %d, done = %v; want big and bad", n, done)
+	}
+	if data, _ := os.ReadFile(path); string(data) != long+bad {
+		t.Errorf("file kept %d bytes, want the big and bad lines only", len(data))
+	}
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Error("temporary rewrite left behind")
+	}
+}
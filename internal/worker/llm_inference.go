@@ -22,6 +22,10 @@
 // usage} as the JobResult.Output — it must NOT call WriteEnd itself (that would
 // double-publish the terminal event). This mirrors the workflow handler, the
 // closest streaming analog in this package.
+//
+// Tool calls, structured (JSON / JSON-schema) outputs and logprobs are
+// translated per engine in llm_structured.go; when present they add tool_calls
+// and logprobs to the output.
 package worker
 
 import (
@@ -36,6 +40,7 @@ import (
 	"strings"
 
 	"github.com/aceteam-ai/citadel-cli/internal/jobs"
+	"github.com/aceteam-ai/citadel-cli/internal/tracing"
 	"github.com/aceteam-ai/citadel-cli/services"
 )
 
@@ -57,6 +62,10 @@ type LLMInferenceHandler struct {
 	// resident target is swapped in. Injected ONLY when CITADEL_MODEL_HOTSWAP is
 	// on (cmd/nodejobs.go), so a nil swapper == today's behavior exactly.
 	swapper modelSwapper
+
+	// demand, when non-nil, is told about every job as it arrives so the
+	// prewarm predictor (prewarm.go) can learn which engines are wanted.
+	demand demandRecorder
 }
 
 // modelSwapper is the swap surface the handler needs. Satisfied by *SwapManager;
@@ -99,6 +108,13 @@ func (h *LLMInferenceHandler) WithSwapper(s modelSwapper) *LLMInferenceHandler {
 	return h
 }
 
+// WithDemandRecorder attaches the prewarm predictor's demand hook. Called from
+// cmd/nodejobs.go only when prewarming is on.
+func (h *LLMInferenceHandler) WithDemandRecorder(r demandRecorder) *LLMInferenceHandler {
+	h.demand = r
+	return h
+}
+
 // CanHandle reports whether this handler processes the given job type.
 func (h *LLMInferenceHandler) CanHandle(jobType string) bool {
 	return jobType == JobTypeLLMInference
@@ -117,6 +133,9 @@ func (h *LLMInferenceHandler) Execute(ctx context.Context, job *Job, stream Stre
 	if err != nil {
 		return h.failure(fmt.Errorf("invalid payload: %w", err)), nil
 	}
+	if h.demand != nil {
+		h.demand.RecordDemand(job.ID, job.SourceQueue, payload.Backend, payload.Model)
+	}
 
 	// Model hotswap (citadel-cli#632): when enabled (swapper injected), an
 	// installed-but-not-resident target engine is swapped in before routing. If it
@@ -125,7 +144,7 @@ func (h *LLMInferenceHandler) Execute(ctx context.Context, job *Job, stream Stre
 	// success JobResult carrying no content) for the platform to relay + retry. A
 	// nil swapper (flag off) skips this block entirely — unchanged behavior.
 	if h.swapper != nil {
-		outcome, swapErr := h.swapper.EnsureResident(ctx, payload.Backend, payload.Model)
+		outcome, swapErr := h.ensureResident(ctx, payload.Backend, payload.Model)
 		if swapErr != nil {
 			// A node at its swap limit is refusing, not malfunctioning
 			// (citadel-cli#687). It is still a job FAILURE — every consumer that
@@ -158,6 +177,14 @@ func (h *LLMInferenceHandler) Execute(ctx context.Context, job *Job, stream Stre
 		return h.failure(err), nil
 	}
 
+	// A JSON-constrained reply is checked before it is handed back
+	// (llm_structured.go).
+	result, err := h.route(ctx, stream, payload)
+	return h.enforceResponseFormat(payload, result), err
+}
+
+// route sends the job to the backend-specific path.
+func (h *LLMInferenceHandler) route(ctx context.Context, stream StreamWriter, payload *jobs.LLMInferencePayload) (*JobResult, error) {
 	switch payload.Backend {
 	case "vllm":
 		return h.executeVLLM(ctx, stream, payload)
@@ -201,6 +228,9 @@ func parseLLMInferencePayload(data map[string]any) (*jobs.LLMInferencePayload, e
 	if payload.Backend == "" {
 		payload.Backend = "vllm" // Default to vLLM
 	}
+	if err := validateStructuredPayload(&payload); err != nil {
+		return nil, err
+	}
 	return &payload, nil
 }
 
@@ -237,6 +267,7 @@ func (h *LLMInferenceHandler) executeCompletions(ctx context.Context, stream Str
 	if len(payload.Stop) > 0 {
 		reqPayload["stop"] = payload.Stop
 	}
+	applyCompletionsOptions(reqPayload, payload)
 
 	resp, err := h.postJSON(ctx, baseURL+"/v1/completions", reqPayload)
 	if err != nil {
@@ -260,6 +291,7 @@ func (h *LLMInferenceHandler) streamCompletions(stream StreamWriter, body io.Rea
 	scanner := bufio.NewScanner(body)
 	chunkIndex := 0
 	var fullContent strings.Builder
+	var logprobs logprobCollector
 
 	for scanner.Scan() {
 		line := scanner.Text()
@@ -273,8 +305,9 @@ func (h *LLMInferenceHandler) streamCompletions(stream StreamWriter, body io.Rea
 
 		var chunk struct {
 			Choices []struct {
-				Text         string `json:"text"`
-				FinishReason string `json:"finish_reason"`
+				Text         string          `json:"text"`
+				Logprobs     json.RawMessage `json:"logprobs"`
+				FinishReason string          `json:"finish_reason"`
 			} `json:"choices"`
 		}
 		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
@@ -283,7 +316,7 @@ func (h *LLMInferenceHandler) streamCompletions(stream StreamWriter, body io.Rea
 		if len(chunk.Choices) > 0 {
 			text := chunk.Choices[0].Text
 			fullContent.WriteString(text)
-			stream.WriteChunk(text, chunkIndex)
+			writeChunkLogprobs(stream, text, chunkIndex, chunk.Choices[0].Logprobs, &logprobs)
 			chunkIndex++
 			if chunk.Choices[0].FinishReason != "" {
 				break
@@ -293,10 +326,10 @@ func (h *LLMInferenceHandler) streamCompletions(stream StreamWriter, body io.Rea
 	if err := scanner.Err(); err != nil {
 		return h.failure(err), nil
 	}
-	return h.success(map[string]any{
+	return h.success(withExtras(map[string]any{
 		"content":       fullContent.String(),
 		"finish_reason": "stop",
-	}), nil
+	}, &toolCallAccumulator{}, &logprobs)), nil
 }
 
 // bufferedCompletions parses a non-streamed text-completions response.
@@ -307,8 +340,9 @@ func (h *LLMInferenceHandler) bufferedCompletions(stream StreamWriter, body io.R
 	}
 	var response struct {
 		Choices []struct {
-			Text         string `json:"text"`
-			FinishReason string `json:"finish_reason"`
+			Text         string          `json:"text"`
+			Logprobs     json.RawMessage `json:"logprobs"`
+			FinishReason string          `json:"finish_reason"`
 		} `json:"choices"`
 		Usage struct {
 			PromptTokens     int `json:"prompt_tokens"`
@@ -326,7 +360,9 @@ func (h *LLMInferenceHandler) bufferedCompletions(stream StreamWriter, body io.R
 	content := strings.TrimSpace(response.Choices[0].Text)
 	// Emit a single chunk (parity with the streaming path) then the end.
 	writeSingleChunk(stream, content)
-	return h.success(map[string]any{
+	var logprobs logprobCollector
+	logprobs.add(response.Choices[0].Logprobs)
+	return h.success(withExtras(map[string]any{
 		"content":       content,
 		"finish_reason": response.Choices[0].FinishReason,
 		"usage": map[string]any{
@@ -334,7 +370,7 @@ func (h *LLMInferenceHandler) bufferedCompletions(stream StreamWriter, body io.R
 			"completion_tokens": response.Usage.CompletionTokens,
 			"total_tokens":      response.Usage.TotalTokens,
 		},
-	}), nil
+	}, &toolCallAccumulator{}, &logprobs)), nil
 }
 
 // executeOllama handles inference via Ollama's native /api/generate API.
@@ -359,6 +395,7 @@ func (h *LLMInferenceHandler) executeOllama(ctx context.Context, stream StreamWr
 	if payload.MaxTokens > 0 {
 		reqPayload["options"] = map[string]any{"num_predict": payload.MaxTokens}
 	}
+	applyOllamaOptions(reqPayload, payload)
 
 	resp, err := h.postJSON(ctx, h.baseURL("ollama")+"/api/generate", reqPayload)
 	if err != nil {
@@ -382,6 +419,7 @@ func (h *LLMInferenceHandler) streamOllama(stream StreamWriter, body io.Reader)
 	scanner := bufio.NewScanner(body)
 	chunkIndex := 0
 	var fullContent strings.Builder
+	var logprobs logprobCollector
 
 	for scanner.Scan() {
 		line := scanner.Text()
@@ -389,15 +427,16 @@ func (h *LLMInferenceHandler) streamOllama(stream StreamWriter, body io.Reader)
 			continue
 		}
 		var chunk struct {
-			Response string `json:"response"`
-			Done     bool   `json:"done"`
+			Response string          `json:"response"`
+			Logprobs json.RawMessage `json:"logprobs"`
+			Done     bool            `json:"done"`
 		}
 		if err := json.Unmarshal([]byte(line), &chunk); err != nil {
 			continue
 		}
 		if chunk.Response != "" {
 			fullContent.WriteString(chunk.Response)
-			stream.WriteChunk(chunk.Response, chunkIndex)
+			writeChunkLogprobs(stream, chunk.Response, chunkIndex, chunk.Logprobs, &logprobs)
 			chunkIndex++
 		}
 		if chunk.Done {
@@ -407,10 +446,10 @@ func (h *LLMInferenceHandler) streamOllama(stream StreamWriter, body io.Reader)
 	if err := scanner.Err(); err != nil {
 		return h.failure(err), nil
 	}
-	return h.success(map[string]any{
+	return h.success(withExtras(map[string]any{
 		"content":       fullContent.String(),
 		"finish_reason": "stop",
-	}), nil
+	}, &toolCallAccumulator{}, &logprobs)), nil
 }
 
 // bufferedOllama parses a non-streamed Ollama response.
@@ -420,16 +459,19 @@ func (h *LLMInferenceHandler) bufferedOllama(stream StreamWriter, body io.Reader
 		return h.failure(err), nil
 	}
 	var response struct {
-		Response string `json:"response"`
+		Response string          `json:"response"`
+		Logprobs json.RawMessage `json:"logprobs"`
 	}
 	if err := json.Unmarshal(bodyBytes, &response); err != nil {
 		return h.failure(fmt.Errorf("failed to parse Ollama response: %w", err)), nil
 	}
 	writeSingleChunk(stream, response.Response)
-	return h.success(map[string]any{
+	var logprobs logprobCollector
+	logprobs.add(response.Logprobs)
+	return h.success(withExtras(map[string]any{
 		"content":       response.Response,
 		"finish_reason": "stop",
-	}), nil
+	}, &toolCallAccumulator{}, &logprobs)), nil
 }
 
 // executeOllamaChat handles chat-style inference via Ollama's native /api/chat
@@ -441,10 +483,11 @@ func (h *LLMInferenceHandler) executeOllamaChat(ctx context.Context, stream Stre
 	// Ollama's /api/chat takes text content (images ride a separate `images`
 	// field we do not populate), so flatten any multimodal content to its text
 	// parts. OCR/vision fabric models run on vLLM (executeChatCompletionsAt), not
-	// this path.
-	messages := make([]map[string]string, 0, len(payload.Messages))
-	for _, m := range payload.Messages {
-		messages = append(messages, map[string]string{"role": m.Role, "content": m.Text()})
+	// this path. Tool calls and results are translated to Ollama's shape
+	// (llm_structured.go).
+	messages, err := ollamaChatMessages(payload.Messages)
+	if err != nil {
+		return h.failure(err), nil
 	}
 
 	reqPayload := map[string]any{
@@ -471,6 +514,10 @@ func (h *LLMInferenceHandler) executeOllamaChat(ctx context.Context, stream Stre
 	if len(options) > 0 {
 		reqPayload["options"] = options
 	}
+	applyOllamaOptions(reqPayload, payload)
+	if err := applyOllamaTools(reqPayload, payload); err != nil {
+		return h.failure(err), nil
+	}
 
 	resp, err := h.postJSON(ctx, h.baseURL("ollama")+"/api/chat", reqPayload)
 	if err != nil {
@@ -499,6 +546,8 @@ func (h *LLMInferenceHandler) streamOllamaChat(stream StreamWriter, body io.Read
 	chunkIndex := 0
 	var fullContent strings.Builder
 	var promptTokens, completionTokens int
+	var calls toolCallAccumulator
+	var logprobs logprobCollector
 
 	for scanner.Scan() {
 		line := scanner.Text()
@@ -507,18 +556,25 @@ func (h *LLMInferenceHandler) streamOllamaChat(stream StreamWriter, body io.Read
 		}
 		var chunk struct {
 			Message struct {
-				Content string `json:"content"`
+				Content   string           `json:"content"`
+				ToolCalls []ollamaToolCall `json:"tool_calls"`
 			} `json:"message"`
-			Done            bool `json:"done"`
-			PromptEvalCount int  `json:"prompt_eval_count"`
-			EvalCount       int  `json:"eval_count"`
+			Logprobs        json.RawMessage `json:"logprobs"`
+			Done            bool            `json:"done"`
+			PromptEvalCount int             `json:"prompt_eval_count"`
+			EvalCount       int             `json:"eval_count"`
 		}
 		if err := json.Unmarshal([]byte(line), &chunk); err != nil {
 			continue
 		}
 		if chunk.Message.Content != "" {
 			fullContent.WriteString(chunk.Message.Content)
-			stream.WriteChunk(chunk.Message.Content, chunkIndex)
+			writeChunkLogprobs(stream, chunk.Message.Content, chunkIndex, chunk.Logprobs, &logprobs)
+			chunkIndex++
+		}
+		// Ollama sends each tool call whole, in one frame.
+		if len(chunk.Message.ToolCalls) > 0 {
+			stream.WriteChunkData("", chunkIndex, map[string]any{"tool_calls": calls.addOllama(chunk.Message.ToolCalls)})
 			chunkIndex++
 		}
 		if chunk.PromptEvalCount > 0 {
@@ -534,11 +590,11 @@ func (h *LLMInferenceHandler) streamOllamaChat(stream StreamWriter, body io.Read
 	if err := scanner.Err(); err != nil {
 		return h.failure(err), nil
 	}
-	return h.success(map[string]any{
+	return h.success(withExtras(map[string]any{
 		"content":       fullContent.String(),
 		"finish_reason": "stop",
 		"usage":         ollamaUsage(promptTokens, completionTokens),
-	}), nil
+	}, &calls, &logprobs)), nil
 }
 
 // bufferedOllamaChat parses a non-streamed Ollama /api/chat response.
@@ -549,20 +605,26 @@ func (h *LLMInferenceHandler) bufferedOllamaChat(stream StreamWriter, body io.Re
 	}
 	var response struct {
 		Message struct {
-			Content string `json:"content"`
+			Content   string           `json:"content"`
+			ToolCalls []ollamaToolCall `json:"tool_calls"`
 		} `json:"message"`
-		PromptEvalCount int `json:"prompt_eval_count"`
-		EvalCount       int `json:"eval_count"`
+		Logprobs        json.RawMessage `json:"logprobs"`
+		PromptEvalCount int             `json:"prompt_eval_count"`
+		EvalCount       int             `json:"eval_count"`
 	}
 	if err := json.Unmarshal(bodyBytes, &response); err != nil {
 		return h.failure(fmt.Errorf("failed to parse Ollama response: %w", err)), nil
 	}
 	writeSingleChunk(stream, response.Message.Content)
-	return h.success(map[string]any{
+	var calls toolCallAccumulator
+	calls.addOllama(response.Message.ToolCalls)
+	var logprobs logprobCollector
+	logprobs.add(response.Logprobs)
+	return h.success(withExtras(map[string]any{
 		"content":       response.Message.Content,
 		"finish_reason": "stop",
 		"usage":         ollamaUsage(response.PromptEvalCount, response.EvalCount),
-	}), nil
+	}, &calls, &logprobs)), nil
 }
 
 // ollamaUsage maps Ollama's prompt_eval_count/eval_count onto the platform's
@@ -601,6 +663,7 @@ func (h *LLMInferenceHandler) executeLlamaCppAt(ctx context.Context, stream Stre
 	if len(payload.Stop) > 0 {
 		reqPayload["stop"] = payload.Stop
 	}
+	applyLlamaCppOptions(reqPayload, payload)
 
 	resp, err := h.postJSON(ctx, baseURL+"/completion", reqPayload)
 	if err != nil {
@@ -624,6 +687,7 @@ func (h *LLMInferenceHandler) streamLlamaCpp(stream StreamWriter, body io.Reader
 	scanner := bufio.NewScanner(body)
 	chunkIndex := 0
 	var fullContent strings.Builder
+	var logprobs logprobCollector
 
 	for scanner.Scan() {
 		line := scanner.Text()
@@ -633,15 +697,16 @@ func (h *LLMInferenceHandler) streamLlamaCpp(stream StreamWriter, body io.Reader
 		data := strings.TrimPrefix(line, "data: ")
 
 		var chunk struct {
-			Content string `json:"content"`
-			Stop    bool   `json:"stop"`
+			Content       string          `json:"content"`
+			Probabilities json.RawMessage `json:"completion_probabilities"`
+			Stop          bool            `json:"stop"`
 		}
 		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
 			continue
 		}
 		if chunk.Content != "" {
 			fullContent.WriteString(chunk.Content)
-			stream.WriteChunk(chunk.Content, chunkIndex)
+			writeChunkLogprobs(stream, chunk.Content, chunkIndex, chunk.Probabilities, &logprobs)
 			chunkIndex++
 		}
 		if chunk.Stop {
@@ -651,10 +716,10 @@ func (h *LLMInferenceHandler) streamLlamaCpp(stream StreamWriter, body io.Reader
 	if err := scanner.Err(); err != nil {
 		return h.failure(err), nil
 	}
-	return h.success(map[string]any{
+	return h.success(withExtras(map[string]any{
 		"content":       fullContent.String(),
 		"finish_reason": "stop",
-	}), nil
+	}, &toolCallAccumulator{}, &logprobs)), nil
 }
 
 // bufferedLlamaCpp parses a non-streamed llama.cpp /completion response.
@@ -664,16 +729,19 @@ func (h *LLMInferenceHandler) bufferedLlamaCpp(stream StreamWriter, body io.Read
 		return h.failure(err), nil
 	}
 	var response struct {
-		Content string `json:"content"`
+		Content       string          `json:"content"`
+		Probabilities json.RawMessage `json:"completion_probabilities"`
 	}
 	if err := json.Unmarshal(bodyBytes, &response); err != nil {
 		return h.failure(fmt.Errorf("failed to parse llama.cpp response: %w", err)), nil
 	}
 	writeSingleChunk(stream, response.Content)
-	return h.success(map[string]any{
+	var logprobs logprobCollector
+	logprobs.add(response.Probabilities)
+	return h.success(withExtras(map[string]any{
 		"content":       response.Content,
 		"finish_reason": "stop",
-	}), nil
+	}, &toolCallAccumulator{}, &logprobs)), nil
 }
 
 // executeChatCompletionsAt runs a chat-style inference against an OpenAI-
@@ -687,10 +755,21 @@ func (h *LLMInferenceHandler) executeChatCompletionsAt(ctx context.Context, stre
 	// Forward content verbatim (map[string]any, not map[string]string) so the
 	// OpenAI multimodal "content parts" array — e.g. an image_url for a vision/OCR
 	// model like baidu/Unlimited-OCR (#625) — reaches the engine intact. A plain
-	// string content marshals back to a string unchanged.
+	// string content marshals back to a string unchanged. Tool-call turns keep
+	// their tool_calls / tool_call_id / name so a tool loop round-trips.
 	messages := make([]map[string]any, 0, len(payload.Messages))
 	for _, m := range payload.Messages {
-		messages = append(messages, map[string]any{"role": m.Role, "content": m.ContentJSON()})
+		msg := map[string]any{"role": m.Role, "content": m.ContentJSON()}
+		if len(m.ToolCalls) > 0 {
+			msg["tool_calls"] = m.ToolCalls
+		}
+		if m.ToolCallID != "" {
+			msg["tool_call_id"] = m.ToolCallID
+		}
+		if m.Name != "" {
+			msg["name"] = m.Name
+		}
+		messages = append(messages, msg)
 	}
 
 	reqPayload := map[string]any{
@@ -709,6 +788,7 @@ func (h *LLMInferenceHandler) executeChatCompletionsAt(ctx context.Context, stre
 	if len(payload.Stop) > 0 {
 		reqPayload["stop"] = payload.Stop
 	}
+	applyChatOptions(reqPayload, payload)
 
 	resp, err := h.postJSON(ctx, baseURL+"/v1/chat/completions", reqPayload)
 	if err != nil {
@@ -739,11 +819,12 @@ func (h *LLMInferenceHandler) bufferedChatCompletions(stream StreamWriter, body
 		return h.failure(err), nil
 	}
 	writeSingleChunk(stream, content)
-	return h.success(map[string]any{
+	calls, logprobs := parseChatCompletionExtras(bodyBytes)
+	return h.success(withExtras(map[string]any{
 		"content":       content,
 		"finish_reason": finishReason,
 		"usage":         usage,
-	}), nil
+	}, calls, logprobs)), nil
 }
 
 // streamChatCompletions translates an OpenAI chat-completions SSE stream into
@@ -753,7 +834,11 @@ func (h *LLMInferenceHandler) bufferedChatCompletions(stream StreamWriter, body
 // and the answer in delta.content; the reasoning is accumulated but only surfaced
 // if the stream ends with NO answer (token budget spent mid-reasoning), so a
 // reply is never silently blank while staying consistent with the non-stream
-// content-only result.
+// reasoning_content fallback.
+//
+// Tool-call deltas are forwarded as structured chunks (WriteChunkData with the
+// engine's delta.tool_calls, verbatim) and assembled for the result; logprobs
+// ride on the content chunk they belong to.
 func (h *LLMInferenceHandler) streamChatCompletions(stream StreamWriter, body io.Reader) (*JobResult, error) {
 	scanner := bufio.NewScanner(body)
 	// Allow long SSE lines (large deltas) beyond bufio's default 64KiB cap.
@@ -761,6 +846,8 @@ func (h *LLMInferenceHandler) streamChatCompletions(stream StreamWriter, body io
 	chunkIndex := 0
 	var answer strings.Builder
 	var reasoning strings.Builder
+	var calls toolCallAccumulator
+	var logprobs logprobCollector
 
 	for scanner.Scan() {
 		line := scanner.Text()
@@ -775,10 +862,12 @@ func (h *LLMInferenceHandler) streamChatCompletions(stream StreamWriter, body io
 		var chunk struct {
 			Choices []struct {
 				Delta struct {
-					Content          string `json:"content"`
-					ReasoningContent string `json:"reasoning_content"`
+					Content          string           `json:"content"`
+					ReasoningContent string           `json:"reasoning_content"`
+					ToolCalls        []openAIToolCall `json:"tool_calls"`
 				} `json:"delta"`
-				FinishReason string `json:"finish_reason"`
+				Logprobs     json.RawMessage `json:"logprobs"`
+				FinishReason string          `json:"finish_reason"`
 			} `json:"choices"`
 		}
 		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
@@ -790,11 +879,16 @@ func (h *LLMInferenceHandler) streamChatCompletions(stream StreamWriter, body io
 
 		if text := chunk.Choices[0].Delta.Content; text != "" {
 			answer.WriteString(text)
-			stream.WriteChunk(text, chunkIndex)
+			writeChunkLogprobs(stream, text, chunkIndex, chunk.Choices[0].Logprobs, &logprobs)
 			chunkIndex++
 		} else if rc := chunk.Choices[0].Delta.ReasoningContent; rc != "" {
 			reasoning.WriteString(rc)
 		}
+		if deltas := chunk.Choices[0].Delta.ToolCalls; len(deltas) > 0 {
+			calls.addDelta(deltas)
+			stream.WriteChunkData("", chunkIndex, map[string]any{"tool_calls": deltas})
+			chunkIndex++
+		}
 
 		if chunk.Choices[0].FinishReason != "" {
 			break
@@ -805,7 +899,7 @@ func (h *LLMInferenceHandler) streamChatCompletions(stream StreamWriter, body io
 	}
 
 	final := answer.String()
-	if final == "" {
+	if final == "" && len(calls.calls) == 0 {
 		// No answer produced (thinking model ran out of budget mid-reason); surface
 		// the reasoning so the reply is not blank, mirroring the buffered path's
 		// reasoning_content fallback.
@@ -813,10 +907,10 @@ func (h *LLMInferenceHandler) streamChatCompletions(stream StreamWriter, body io
 			stream.WriteChunk(final, chunkIndex)
 		}
 	}
-	return h.success(map[string]any{
+	return h.success(withExtras(map[string]any{
 		"content":       final,
 		"finish_reason": "stop",
-	}), nil
+	}, &calls, &logprobs)), nil
 }
 
 // parseChatCompletionResponse extracts the assistant content, finish reason, and
@@ -863,19 +957,70 @@ func parseChatCompletionResponse(bodyBytes []byte) (content, finishReason string
 	return content, finishReason, usage, nil
 }
 
+// parseChatCompletionExtras extracts the tool calls and logprobs of a buffered
+// OpenAI chat-completions body (first choice). A body without them yields
+// empty collectors.
+func parseChatCompletionExtras(bodyBytes []byte) (*toolCallAccumulator, *logprobCollector) {
+	var response struct {
+		Choices []struct {
+			Message struct {
+				ToolCalls []openAIToolCall `json:"tool_calls"`
+			} `json:"message"`
+			Logprobs json.RawMessage `json:"logprobs"`
+		} `json:"choices"`
+	}
+	calls, logprobs := &toolCallAccumulator{}, &logprobCollector{}
+	if json.Unmarshal(bodyBytes, &response) == nil && len(response.Choices) > 0 {
+		calls.addDelta(response.Choices[0].Message.ToolCalls)
+		logprobs.add(response.Choices[0].Logprobs)
+	}
+	return calls, logprobs
+}
+
+// ensureResident runs the model hotswap under a span, so a job's trace shows
+// how long it waited on residency.
+func (h *LLMInferenceHandler) ensureResident(ctx context.Context, backend, model string) (SwapOutcome, error) {
+	ctx, span := tracing.Start(ctx, "engine.swap",
+		tracing.WithAttr("citadel.engine", backend),
+		tracing.WithAttr("citadel.model", model))
+	outcome, err := h.swapper.EnsureResident(ctx, backend, model)
+	span.SetAttr("citadel.swap.ready", outcome.Ready)
+	if !outcome.Ready && outcome.ETASeconds > 0 {
+		span.SetAttr("citadel.swap.eta_seconds", outcome.ETASeconds)
+	}
+	span.RecordError(err)
+	span.End()
+	return outcome, err
+}
+
 // postJSON issues a ctx-bound POST with a JSON body so a per-job deadline
-// cancels the outbound request (issue #548 watchdog).
+// cancels the outbound request (issue #548 watchdog). The request carries the
+// job's traceparent; its span ends when the engine's response headers arrive,
+// so a streamed generation shows up as time to first byte here and the rest
+// under the publish spans.
 func (h *LLMInferenceHandler) postJSON(ctx context.Context, url string, payload map[string]any) (*http.Response, error) {
 	reqBody, err := json.Marshal(payload)
 	if err != nil {
 		return nil, err
 	}
+	ctx, span := tracing.Start(ctx, "engine.request",
+		tracing.WithKind(tracing.KindClient),
+		tracing.WithAttr("http.request.method", http.MethodPost),
+		tracing.WithAttr("url.full", url))
+	defer span.End()
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(reqBody))
 	if err != nil {
 		return nil, err
 	}
 	req.Header.Set("Content-Type", "application/json")
-	return h.client().Do(req)
+	tracing.Inject(ctx, req.Header)
+	resp, err := h.client().Do(req)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	span.SetAttr("http.response.status_code", resp.StatusCode)
+	return resp, nil
 }
 
 func (h *LLMInferenceHandler) client() *http.Client {
@@ -895,6 +1040,17 @@ func writeSingleChunk(stream StreamWriter, content string) {
 	}
 }
 
+// writeChunkLogprobs emits a content chunk, carrying the engine's logprobs for
+// it when there are any, and collects them for the result.
+func writeChunkLogprobs(stream StreamWriter, content string, index int, logprobs json.RawMessage, collector *logprobCollector) {
+	if lp := strings.TrimSpace(string(logprobs)); lp == "" || lp == "null" {
+		stream.WriteChunk(content, index)
+		return
+	}
+	collector.add(logprobs)
+	stream.WriteChunkData(content, index, map[string]any{"logprobs": logprobs})
+}
+
 func (h *LLMInferenceHandler) success(output map[string]any) *JobResult {
 	return &JobResult{Status: JobStatusSuccess, Output: output}
 }
@@ -45,6 +45,8 @@ import (
 	"strings"
 	"syscall"
 	"time"
+
+	"github.com/aceteam-ai/citadel-cli/internal/tracing"
 )
 
 // Readiness probe budgets. vllm and sglang keep the 60s wait they already had
@@ -160,7 +162,12 @@ func engineWarmETA(backend string) int {
 //
 // A backend with no known readiness endpoint is treated as ready (fail-open), so
 // adding a backend can never silently make it unservable.
-func (h *LLMInferenceHandler) ensureEngineReady(ctx context.Context, backend string) error {
+func (h *LLMInferenceHandler) ensureEngineReady(ctx context.Context, backend string) (err error) {
+	ctx, span := tracing.Start(ctx, "engine.ready", tracing.WithAttr("citadel.engine", backend))
+	defer func() {
+		span.RecordError(err)
+		span.End()
+	}()
 	path, known := engineReadyPath[backend]
 	if !known {
 		return nil
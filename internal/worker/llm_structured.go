@@ -0,0 +1,728 @@
+// internal/worker/llm_structured.go
+//
+// Structured outputs, tool calls and logprobs for llm_inference.
+//
+// The platform sends these in the OpenAI shape (tools, tool_choice,
+// response_format, logprobs/top_logprobs). Each engine spells the constraint
+// differently, so the request side is translated per backend:
+//
+//   - vLLM (and unlimited-ocr, which is vLLM) takes the schema as guided
+//     decoding: guided_json, on both chat and completions.
+//   - SGLang's /v1/completions takes it as a json_schema string.
+//   - llama.cpp (and bonsai) takes json_schema and compiles it to a GBNF
+//     grammar, on both /completion and /v1/chat/completions.
+//   - Ollama takes it as `format`: "json" or the schema object.
+//
+// json_object is the schema {"type":"object"} on every engine, so "any JSON
+// object" is enforced the same way as a schema.
+//
+// Tools are forwarded verbatim to the OpenAI-compatible chat endpoints and
+// translated for Ollama, whose tool calls carry arguments as an object rather
+// than a JSON string and no call id. Whatever the engine, tool calls come back
+// in the OpenAI shape: streamed as structured chunks (WriteChunkData with a
+// tool_calls delta) and returned, assembled, as output.tool_calls.
+//
+// Constrained decoding is only as good as the engine's grammar support, so the
+// final content of a JSON-constrained job is checked before the job succeeds;
+// a reply that does not parse, or does not match the schema, fails the job
+// with reason response_format_violation rather than handing the caller JSON it
+// will choke on.
+package worker
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/aceteam-ai/citadel-cli/internal/jobs"
+)
+
+// maxTopLogprobs is the most alternatives per token a job may ask for (the
+// OpenAI limit; vLLM and llama.cpp accept more, but nothing needs it).
+const maxTopLogprobs = 20
+
+// validateStructuredPayload rejects structured-output and tool options the
+// engines would reject less legibly, or silently ignore.
+func validateStructuredPayload(p *jobs.LLMInferencePayload) error {
+	if f := p.ResponseFormat; f != nil {
+		switch f.Type {
+		case "", jobs.ResponseFormatText, jobs.ResponseFormatJSONObject:
+		case jobs.ResponseFormatJSONSchema:
+			schema := f.Schema()
+			if len(schema) == 0 {
+				return fmt.Errorf("response_format json_schema requires json_schema.schema")
+			}
+			var parsed any
+			if err := json.Unmarshal(schema, &parsed); err != nil {
+				return fmt.Errorf("response_format json_schema: invalid schema: %w", err)
+			}
+			if _, ok := parsed.(map[string]any); !ok {
+				return fmt.Errorf("response_format json_schema: schema must be a JSON object")
+			}
+		default:
+			return fmt.Errorf("unsupported response_format type %q", f.Type)
+		}
+	}
+	if len(p.Tools) > 0 {
+		var tools []json.RawMessage
+		if err := json.Unmarshal(p.Tools, &tools); err != nil {
+			return fmt.Errorf("tools must be a JSON array: %w", err)
+		}
+		if len(tools) > 0 && len(p.Messages) == 0 {
+			return fmt.Errorf("tools require messages")
+		}
+	}
+	if p.TopLogprobs < 0 || p.TopLogprobs > maxTopLogprobs {
+		return fmt.Errorf("top_logprobs must be between 0 and %d", maxTopLogprobs)
+	}
+	if p.TopLogprobs > 0 && !p.Logprobs {
+		return fmt.Errorf("top_logprobs requires logprobs")
+	}
+	return nil
+}
+
+// jsonConstraint returns the schema the output is constrained to: the job's
+// schema for json_schema, {"type":"object"} for json_object, nil otherwise.
+func jsonConstraint(p *jobs.LLMInferencePayload) json.RawMessage {
+	if schema := p.ResponseFormat.Schema(); schema != nil {
+		return schema
+	}
+	if p.ResponseFormat.WantsJSON() {
+		return json.RawMessage(`{"type":"object"}`)
+	}
+	return nil
+}
+
+// hasTools reports whether the job offers the model any tools.
+func hasTools(p *jobs.LLMInferencePayload) bool {
+	t := strings.TrimSpace(string(p.Tools))
+	return t != "" && t != "null" && t != "[]"
+}
+
+// applyChatOptions adds tools, the JSON constraint and logprobs to an OpenAI
+// /v1/chat/completions request for the job's backend.
+func applyChatOptions(req map[string]any, p *jobs.LLMInferencePayload) {
+	if hasTools(p) {
+		req["tools"] = p.Tools
+		if len(p.ToolChoice) > 0 {
+			req["tool_choice"] = p.ToolChoice
+		}
+	}
+	if schema := jsonConstraint(p); schema != nil {
+		switch p.Backend {
+		case "llamacpp", "bonsai":
+			req["json_schema"] = schema
+		case "vllm", "unlimited-ocr":
+			req["guided_json"] = schema
+		default:
+			req["response_format"] = p.ResponseFormat
+		}
+	}
+	if p.Logprobs {
+		req["logprobs"] = true
+		if p.TopLogprobs > 0 {
+			req["top_logprobs"] = p.TopLogprobs
+		}
+	}
+}
+
+// applyCompletionsOptions adds the JSON constraint and logprobs to an OpenAI
+// /v1/completions request (vLLM, SGLang). Completions logprobs is the number
+// of alternatives per token, not a flag.
+func applyCompletionsOptions(req map[string]any, p *jobs.LLMInferencePayload) {
+	if schema := jsonConstraint(p); schema != nil {
+		if p.Backend == "sglang" {
+			req["json_schema"] = string(schema)
+		} else {
+			req["guided_json"] = schema
+		}
+	}
+	if p.Logprobs {
+		req["logprobs"] = p.TopLogprobs
+	}
+}
+
+// applyLlamaCppOptions adds the JSON constraint and token probabilities to a
+// llama.cpp /completion request.
+func applyLlamaCppOptions(req map[string]any, p *jobs.LLMInferencePayload) {
+	if schema := jsonConstraint(p); schema != nil {
+		req["json_schema"] = schema
+	}
+	if p.Logprobs {
+		req["n_probs"] = max(p.TopLogprobs, 1)
+	}
+}
+
+// applyOllamaOptions adds the JSON constraint and logprobs to an Ollama
+// /api/generate or /api/chat request.
+func applyOllamaOptions(req map[string]any, p *jobs.LLMInferencePayload) {
+	if schema := p.ResponseFormat.Schema(); schema != nil {
+		req["format"] = schema
+	} else if p.ResponseFormat.WantsJSON() {
+		req["format"] = "json"
+	}
+	if p.Logprobs {
+		req["logprobs"] = true
+		if p.TopLogprobs > 0 {
+			req["top_logprobs"] = p.TopLogprobs
+		}
+	}
+}
+
+// applyOllamaTools adds the job's tools to an Ollama /api/chat request.
+// Ollama lets the model decide whether to call a tool and has no way to force
+// one, so tool_choice "none" withholds the tools, "auto" passes them, and a
+// forced choice is an error rather than a silently unforced call.
+func applyOllamaTools(req map[string]any, p *jobs.LLMInferencePayload) error {
+	if !hasTools(p) {
+		return nil
+	}
+	choice := "auto"
+	if len(p.ToolChoice) > 0 {
+		if err := json.Unmarshal(p.ToolChoice, &choice); err != nil {
+			return fmt.Errorf("Ollama cannot force a specific tool (tool_choice %s)", p.ToolChoice)
+		}
+	}
+	switch choice {
+	case "none":
+		return nil
+	case "auto":
+		req["tools"] = p.Tools
+		return nil
+	default:
+		return fmt.Errorf("Ollama does not support tool_choice %q", choice)
+	}
+}
+
+// openAIToolCall is one tool call in the OpenAI shape. In a streamed delta
+// only Index is guaranteed; the rest arrive piecemeal, with Arguments split
+// across deltas.
+type openAIToolCall struct {
+	Index    *int   `json:"index,omitempty"`
+	ID       string `json:"id,omitempty"`
+	Type     string `json:"type,omitempty"`
+	Function struct {
+		Name      string `json:"name,omitempty"`
+		Arguments string `json:"arguments,omitempty"`
+	} `json:"function"`
+}
+
+// ollamaToolCall is one tool call in Ollama's shape: no id, arguments as an
+// object.
+type ollamaToolCall struct {
+	Function struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	} `json:"function"`
+}
+
+// ollamaChatMessages converts chat messages to Ollama's /api/chat shape.
+// Content is flattened to text (images ride a separate field this path does
+// not populate), an assistant turn's OpenAI tool calls become Ollama's, and a
+// tool result names the function it came from.
+func ollamaChatMessages(messages []jobs.ChatMessage) ([]map[string]any, error) {
+	out := make([]map[string]any, 0, len(messages))
+	for _, m := range messages {
+		msg := map[string]any{"role": m.Role, "content": m.Text()}
+		if len(m.ToolCalls) > 0 {
+			var calls []openAIToolCall
+			if err := json.Unmarshal(m.ToolCalls, &calls); err != nil {
+				return nil, fmt.Errorf("invalid tool_calls in %s message: %w", m.Role, err)
+			}
+			converted := make([]map[string]any, 0, len(calls))
+			for _, c := range calls {
+				args := json.RawMessage(`{}`)
+				if a := strings.TrimSpace(c.Function.Arguments); a != "" {
+					if !json.Valid([]byte(a)) {
+						return nil, fmt.Errorf("tool call %s: arguments are not valid JSON", c.Function.Name)
+					}
+					args = json.RawMessage(a)
+				}
+				converted = append(converted, map[string]any{
+					"function": map[string]any{"name": c.Function.Name, "arguments": args},
+				})
+			}
+			msg["tool_calls"] = converted
+		}
+		if m.Role == "tool" && m.Name != "" {
+			msg["tool_name"] = m.Name
+		}
+		out = append(out, msg)
+	}
+	return out, nil
+}
+
+// maxToolCalls bounds the tool calls kept from one reply. The delta index
+// comes from the engine, and a bogus one must not allocate millions of calls.
+const maxToolCalls = 128
+
+// toolCallAccumulator assembles tool calls, in the OpenAI shape, from streamed
+// deltas (concatenating each call's argument fragments) or whole calls.
+type toolCallAccumulator struct {
+	calls []*openAIToolCall
+}
+
+// addDelta merges one OpenAI delta.tool_calls array. A delta whose index is
+// negative or at or past maxToolCalls is dropped.
+func (a *toolCallAccumulator) addDelta(deltas []openAIToolCall) {
+	for _, d := range deltas {
+		idx := len(a.calls)
+		if d.Index != nil {
+			idx = *d.Index
+		}
+		if idx < 0 || idx >= maxToolCalls {
+			continue
+		}
+		for len(a.calls) <= idx {
+			a.calls = append(a.calls, &openAIToolCall{})
+		}
+		c := a.calls[idx]
+		if d.ID != "" {
+			c.ID = d.ID
+		}
+		if d.Type != "" {
+			c.Type = d.Type
+		}
+		if d.Function.Name != "" {
+			c.Function.Name = d.Function.Name
+		}
+		c.Function.Arguments += d.Function.Arguments
+	}
+}
+
+// addOllama converts Ollama tool calls to OpenAI ones, numbering ids in the
+// order the calls arrived, and returns them as a delta for streaming. Calls
+// past maxToolCalls are dropped.
+func (a *toolCallAccumulator) addOllama(calls []ollamaToolCall) []map[string]any {
+	delta := make([]map[string]any, 0, len(calls))
+	for _, oc := range calls {
+		idx := len(a.calls)
+		if idx >= maxToolCalls {
+			break
+		}
+		args := strings.TrimSpace(string(oc.Function.Arguments))
+		if args == "" || args == "null" {
+			args = "{}"
+		}
+		c := &openAIToolCall{ID: fmt.Sprintf("call_%d", idx), Type: "function"}
+		c.Function.Name = oc.Function.Name
+		c.Function.Arguments = args
+		a.calls = append(a.calls, c)
+		delta = append(delta, map[string]any{
+			"index": idx,
+			"id":    c.ID,
+			"type":  c.Type,
+			"function": map[string]any{
+				"name":      c.Function.Name,
+				"arguments": c.Function.Arguments,
+			},
+		})
+	}
+	return delta
+}
+
+// output returns the assembled calls for JobResult.Output, nil when none.
+func (a *toolCallAccumulator) output() []map[string]any {
+	if len(a.calls) == 0 {
+		return nil
+	}
+	out := make([]map[string]any, 0, len(a.calls))
+	for i, c := range a.calls {
+		id, typ := c.ID, c.Type
+		if id == "" {
+			id = fmt.Sprintf("call_%d", i)
+		}
+		if typ == "" {
+			typ = "function"
+		}
+		out = append(out, map[string]any{
+			"id":   id,
+			"type": typ,
+			"function": map[string]any{
+				"name":      c.Function.Name,
+				"arguments": c.Function.Arguments,
+			},
+		})
+	}
+	return out
+}
+
+// logprobCollector merges per-chunk logprobs into one value for the output.
+// An object (OpenAI chat {"content": [...]}, or completions' parallel tokens /
+// token_logprobs / top_logprobs arrays) has its array members concatenated; a
+// bare array (Ollama, llama.cpp completion_probabilities) is concatenated
+// under "content", the chat shape it resembles.
+type logprobCollector struct {
+	merged map[string][]any
+}
+
+// add merges one chunk's logprobs. Null and malformed values are ignored.
+func (c *logprobCollector) add(raw json.RawMessage) {
+	if len(raw) == 0 {
+		return
+	}
+	var v any
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return
+	}
+	if c.merged == nil {
+		c.merged = map[string][]any{}
+	}
+	switch v := v.(type) {
+	case []any:
+		c.merged["content"] = append(c.merged["content"], v...)
+	case map[string]any:
+		for k, item := range v {
+			if list, ok := item.([]any); ok {
+				c.merged[k] = append(c.merged[k], list...)
+			}
+		}
+	}
+}
+
+// output returns the merged logprobs, nil when none were collected.
+func (c *logprobCollector) output() map[string]any {
+	if len(c.merged) == 0 {
+		return nil
+	}
+	out := make(map[string]any, len(c.merged))
+	for k, v := range c.merged {
+		out[k] = v
+	}
+	return out
+}
+
+// withExtras adds tool calls and logprobs to a result output when present, and
+// reports finish_reason "tool_calls" for a reply that called a tool.
+func withExtras(output map[string]any, calls *toolCallAccumulator, lp *logprobCollector) map[string]any {
+	if tc := calls.output(); tc != nil {
+		output["tool_calls"] = tc
+		if fr, _ := output["finish_reason"].(string); fr == "" || fr == "stop" {
+			output["finish_reason"] = "tool_calls"
+		}
+	}
+	if l := lp.output(); l != nil {
+		output["logprobs"] = l
+	}
+	return output
+}
+
+// enforceResponseFormat fails a successful JSON-constrained result whose
+// content is not the JSON asked for. A reply that called tools instead of
+// answering is not checked, and neither is a warming signal (no content).
+// On a streamed job the chunks have already gone out; the failure is what
+// tells the caller not to trust them.
+func (h *LLMInferenceHandler) enforceResponseFormat(p *jobs.LLMInferencePayload, result *JobResult) *JobResult {
+	if result == nil || result.Status != JobStatusSuccess || !p.ResponseFormat.WantsJSON() {
+		return result
+	}
+	if _, called := result.Output["tool_calls"]; called {
+		return result
+	}
+	content, ok := result.Output["content"].(string)
+	if !ok {
+		return result
+	}
+	err := checkJSONOutput(content, jsonConstraint(p))
+	if err == nil {
+		return result
+	}
+	err = fmt.Errorf("response does not match response_format: %w", err)
+	return &JobResult{
+		Status: JobStatusFailure,
+		Error:  err,
+		Output: map[string]any{
+			"error":   err.Error(),
+			"reason":  "response_format_violation",
+			"content": content,
+		},
+	}
+}
+
+// checkJSONOutput reports whether content is JSON matching schema.
+func checkJSONOutput(content string, schema json.RawMessage) error {
+	var value any
+	if err := json.Unmarshal([]byte(strings.TrimSpace(content)), &value); err != nil {
+		return fmt.Errorf("not valid JSON: %w", err)
+	}
+	return validateJSONSchema(schema, value)
+}
+
+// validateJSONSchema checks value (as decoded by encoding/json into any)
+// against a JSON schema. It covers the subset structured-output schemas use:
+// type, enum, const, properties, required, additionalProperties, items,
+// length and range bounds, pattern, allOf/anyOf/oneOf and local $refs. Other
+// keywords (format, description, ...) are not checked.
+func validateJSONSchema(schema json.RawMessage, value any) error {
+	var root any
+	if err := json.Unmarshal(schema, &root); err != nil {
+		return fmt.Errorf("invalid schema: %w", err)
+	}
+	v := &schemaValidator{root: root}
+	return v.validate(root, value, "$", 0)
+}
+
+// maxSchemaDepth bounds $ref recursion, so a schema that refers to itself
+// without consuming input cannot loop forever.
+const maxSchemaDepth = 64
+
+type schemaValidator struct {
+	root any
+}
+
+func (v *schemaValidator) validate(schema, value any, path string, depth int) error {
+	if depth > maxSchemaDepth {
+		return fmt.Errorf("%s: schema nests too deeply", path)
+	}
+	switch s := schema.(type) {
+	case bool:
+		if !s {
+			return fmt.Errorf("%s: not allowed", path)
+		}
+		return nil
+	case map[string]any:
+		return v.validateObject(s, value, path, depth)
+	default:
+		return fmt.Errorf("%s: invalid schema", path)
+	}
+}
+
+func (v *schemaValidator) validateObject(s map[string]any, value any, path string, depth int) error {
+	if ref, ok := s["$ref"].(string); ok {
+		target, err := v.resolve(ref)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		if err := v.validate(target, value, path, depth+1); err != nil {
+			return err
+		}
+	}
+
+	if t, ok := s["type"]; ok {
+		if err := checkType(t, value, path); err != nil {
+			return err
+		}
+	}
+	if enum, ok := s["enum"].([]any); ok {
+		found := false
+		for _, e := range enum {
+			if reflect.DeepEqual(e, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("%s: %s is not one of the allowed values", path, jsonString(value))
+		}
+	}
+	if c, ok := s["const"]; ok && !reflect.DeepEqual(c, value) {
+		return fmt.Errorf("%s: must be %s", path, jsonString(c))
+	}
+
+	for _, sub := range schemaList(s["allOf"]) {
+		if err := v.validate(sub, value, path, depth+1); err != nil {
+			return err
+		}
+	}
+	if subs := schemaList(s["anyOf"]); len(subs) > 0 {
+		var firstErr error
+		for _, sub := range subs {
+			if err := v.validate(sub, value, path, depth+1); err == nil {
+				firstErr = nil
+				break
+			} else if firstErr == nil {
+				firstErr = err
+			}
+		}
+		if firstErr != nil {
+			return fmt.Errorf("%s: matches none of anyOf (%v)", path, firstErr)
+		}
+	}
+	if subs := schemaList(s["oneOf"]); len(subs) > 0 {
+		matched := 0
+		for _, sub := range subs {
+			if v.validate(sub, value, path, depth+1) == nil {
+				matched++
+			}
+		}
+		if matched != 1 {
+			return fmt.Errorf("%s: matches %d of oneOf, want exactly 1", path, matched)
+		}
+	}
+
+	switch val := value.(type) {
+	case map[string]any:
+		return v.validateProperties(s, val, path, depth)
+	case []any:
+		if n, ok := schemaNumber(s["minItems"]); ok && float64(len(val)) < n {
+			return fmt.Errorf("%s: has %d items, want at least %v", path, len(val), n)
+		}
+		if n, ok := schemaNumber(s["maxItems"]); ok && float64(len(val)) > n {
+			return fmt.Errorf("%s: has %d items, want at most %v", path, len(val), n)
+		}
+		if items, ok := s["items"]; ok {
+			for i, item := range val {
+				if err := v.validate(items, item, fmt.Sprintf("%s[%d]", path, i), depth+1); err != nil {
+					return err
+				}
+			}
+		}
+	case string:
+		n := utf8.RuneCountInString(val)
+		if m, ok := schemaNumber(s["minLength"]); ok && float64(n) < m {
+			return fmt.Errorf("%s: is %d characters, want at least %v", path, n, m)
+		}
+		if m, ok := schemaNumber(s["maxLength"]); ok && float64(n) > m {
+			return fmt.Errorf("%s: is %d characters, want at most %v", path, n, m)
+		}
+		if p, ok := s["pattern"].(string); ok {
+			re, err := regexp.Compile(p)
+			if err != nil {
+				return fmt.Errorf("%s: invalid pattern %q: %w", path, p, err)
+			}
+			if !re.MatchString(val) {
+				return fmt.Errorf("%s: does not match pattern %q", path, p)
+			}
+		}
+	case float64:
+		if m, ok := schemaNumber(s["minimum"]); ok && val < m {
+			return fmt.Errorf("%s: %v is less than the minimum %v", path, val, m)
+		}
+		if m, ok := schemaNumber(s["maximum"]); ok && val > m {
+			return fmt.Errorf("%s: %v is greater than the maximum %v", path, val, m)
+		}
+		if m, ok := schemaNumber(s["exclusiveMinimum"]); ok && val <= m {
+			return fmt.Errorf("%s: %v must be greater than %v", path, val, m)
+		}
+		if m, ok := schemaNumber(s["exclusiveMaximum"]); ok && val >= m {
+			return fmt.Errorf("%s: %v must be less than %v", path, val, m)
+		}
+	}
+	return nil
+}
+
+func (v *schemaValidator) validateProperties(s map[string]any, obj map[string]any, path string, depth int) error {
+	for _, r := range schemaList(s["required"]) {
+		name, _ := r.(string)
+		if _, ok := obj[name]; !ok {
+			return fmt.Errorf("%s: missing required property %q", path, name)
+		}
+	}
+	props, _ := s["properties"].(map[string]any)
+	// Walk keys in order so the reported violation is deterministic.
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		child := path + "." + k
+		if sub, ok := props[k]; ok {
+			if err := v.validate(sub, obj[k], child, depth+1); err != nil {
+				return err
+			}
+			continue
+		}
+		if extra, ok := s["additionalProperties"]; ok {
+			if allowed, isBool := extra.(bool); isBool && !allowed {
+				return fmt.Errorf("%s: unexpected property", child)
+			}
+			if err := v.validate(extra, obj[k], child, depth+1); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// resolve follows a local $ref ("#", "#/$defs/x", "#/definitions/x", or any
+// JSON pointer into the root schema). Remote refs are not supported.
+func (v *schemaValidator) resolve(ref string) (any, error) {
+	if !strings.HasPrefix(ref, "#") {
+		return nil, fmt.Errorf("unsupported $ref %q (only local refs)", ref)
+	}
+	node := v.root
+	pointer := strings.TrimPrefix(ref, "#")
+	if pointer == "" {
+		return node, nil
+	}
+	for _, tok := range strings.Split(strings.TrimPrefix(pointer, "/"), "/") {
+		tok = strings.ReplaceAll(strings.ReplaceAll(tok, "~1", "/"), "~0", "~")
+		obj, ok := node.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("unresolvable $ref %q", ref)
+		}
+		if node, ok = obj[tok]; !ok {
+			return nil, fmt.Errorf("unresolvable $ref %q", ref)
+		}
+	}
+	return node, nil
+}
+
+// checkType checks value against a schema type, a name or a list of names.
+func checkType(t any, value any, path string) error {
+	var names []string
+	switch t := t.(type) {
+	case string:
+		names = []string{t}
+	case []any:
+		for _, n := range t {
+			if s, ok := n.(string); ok {
+				names = append(names, s)
+			}
+		}
+	}
+	got := jsonType(value)
+	for _, n := range names {
+		if n == got || (n == "number" && got == "integer") {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s: expected %s, got %s", path, strings.Join(names, " or "), got)
+}
+
+// jsonType names a decoded JSON value's type; a number with no fractional
+// part is an integer.
+func jsonType(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case float64:
+		if v == math.Trunc(v) && !math.IsInf(v, 0) {
+			return "integer"
+		}
+		return "number"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func schemaList(v any) []any {
+	list, _ := v.([]any)
+	return list
+}
+
+func schemaNumber(v any) (float64, bool) {
+	n, ok := v.(float64)
+	return n, ok
+}
+
+func jsonString(v any) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
@@ -0,0 +1,360 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newRecordingEngine returns an engine that answers the readiness probe, records
+// the last request's path and decoded body, and replies with body verbatim.
+func newRecordingEngine(t *testing.T, body string, gotPath *string, gotReq *map[string]any) *httptest.Server {
+	t.Helper()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if serveReadinessProbe(w, r) {
+			return
+		}
+		*gotPath = r.URL.Path
+		*gotReq = nil
+		_ = json.NewDecoder(r.Body).Decode(gotReq)
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+const personSchema = `{"type":"object","properties":{"name":{"type":"string"},"age":{"type":"integer","minimum":0}},"required":["name","age"],"additionalProperties":false}`
+
+func structuredJob(backend string, extra map[string]any) *Job {
+	payload := map[string]any{
+		"model":   "m",
+		"backend": backend,
+		"response_format": map[string]any{
+			"type":        "json_schema",
+			"json_schema": map[string]any{"name": "person", "schema": json.RawMessage(personSchema)},
+		},
+	}
+	for k, v := range extra {
+		payload[k] = v
+	}
+	if _, ok := payload["prompt"]; !ok {
+		payload["messages"] = []map[string]any{{"role": "user", "content": "who?"}}
+	}
+	return &Job{ID: "job-" + backend, Type: JobTypeLLMInference, Payload: payload}
+}
+
+// TestLLMInference_ResponseFormatPerBackend asserts the schema reaches each
+// engine in the parameter that engine constrains decoding with.
+func TestLLMInference_ResponseFormatPerBackend(t *testing.T) {
+	const answer = `{"name":"Ada","age":36}`
+	chatBody := `{"choices":[{"message":{"content":` + jsonQuote(answer) + `},"finish_reason":"stop"}]}`
+	cases := []struct {
+		name, backend, body, path, param string
+		extra                            map[string]any
+	}{
+		{"vllm chat", "vllm", chatBody, "/v1/chat/completions", "guided_json", nil},
+		{"vllm completions", "vllm", `{"choices":[{"text":` + jsonQuote(answer) + `}]}`, "/v1/completions", "guided_json", map[string]any{"prompt": "who?"}},
+		{"sglang completions", "sglang", `{"choices":[{"text":` + jsonQuote(answer) + `}]}`, "/v1/completions", "json_schema", map[string]any{"prompt": "who?"}},
+		{"llamacpp chat", "llamacpp", chatBody, "/v1/chat/completions", "json_schema", nil},
+		{"llamacpp completion", "llamacpp", `{"content":` + jsonQuote(answer) + `}`, "/completion", "json_schema", map[string]any{"prompt": "who?"}},
+		{"ollama chat", "ollama", `{"message":{"role":"assistant","content":` + jsonQuote(answer) + `},"done":true}`, "/api/chat", "format", nil},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var gotPath string
+			var gotReq map[string]any
+			ts := newRecordingEngine(t, tc.body, &gotPath, &gotReq)
+			h := NewLLMInferenceHandler()
+			h.baseURLs[tc.backend] = ts.URL
+
+			result, err := h.Execute(context.Background(), structuredJob(tc.backend, tc.extra), &MockStreamWriter{})
+			if err != nil {
+				t.Fatalf("Execute error: %v", err)
+			}
+			if result.Status != JobStatusSuccess {
+				t.Fatalf("result = %+v, want success", result)
+			}
+			if gotPath != tc.path {
+				t.Errorf("path = %q, want %q", gotPath, tc.path)
+			}
+			constraint, ok := gotReq[tc.param]
+			if !ok {
+				t.Fatalf("request %v carried no %s", gotReq, tc.param)
+			}
+			// SGLang takes the schema as a string; everyone else as an object.
+			if s, isString := constraint.(string); isString {
+				_ = json.Unmarshal([]byte(s), &constraint)
+			}
+			if m, _ := constraint.(map[string]any); m["type"] != "object" || m["properties"] == nil {
+				t.Errorf("%s = %v, want the job's schema", tc.param, gotReq[tc.param])
+			}
+			if got, _ := result.Output["content"].(string); got != answer {
+				t.Errorf("content = %q, want %q", got, answer)
+			}
+		})
+	}
+}
+
+func TestLLMInference_ResponseFormatViolation(t *testing.T) {
+	for name, content := range map[string]string{
+		"not json":        `Sure! Here is the person: Ada`,
+		"schema mismatch": `{"name":"Ada","age":"old"}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			var gotPath string
+			var gotReq map[string]any
+			body := `{"choices":[{"message":{"content":` + jsonQuote(content) + `},"finish_reason":"stop"}]}`
+			ts := newRecordingEngine(t, body, &gotPath, &gotReq)
+			h := NewLLMInferenceHandler()
+			h.baseURLs["vllm"] = ts.URL
+
+			result, err := h.Execute(context.Background(), structuredJob("vllm", nil), &MockStreamWriter{})
+			if err != nil {
+				t.Fatalf("Execute error: %v", err)
+			}
+			if result.Status != JobStatusFailure {
+				t.Fatalf("result = %+v, want failure", result)
+			}
+			if result.Output["reason"] != "response_format_violation" || result.Output["content"] != content {
+				t.Errorf("output = %v, want the violation reason and the offending content", result.Output)
+			}
+		})
+	}
+}
+
+// TestLLMInference_ChatToolCallStream asserts tools reach the engine verbatim,
+// tool-call deltas are streamed as structured chunks, and the assembled calls
+// come back in the result.
+func TestLLMInference_ChatToolCallStream(t *testing.T) {
+	frames := []string{
+		`data: {"choices":[{"delta":{"tool_calls":[{"index":0,"id":"call_abc","type":"function","function":{"name":"get_weather","arguments":""}}]}}]}`,
+		`data: {"choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":"{\"city\":"}}]}}]}`,
+		`data: {"choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":"\"Oslo\"}"}}]}}]}`,
+		`data: {"choices":[{"delta":{},"finish_reason":"tool_calls"}]}`,
+		`data: [DONE]`,
+	}
+	var gotReq map[string]any
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if serveReadinessProbe(w, r) {
+			return
+		}
+		_ = json.NewDecoder(r.Body).Decode(&gotReq)
+		for _, f := range frames {
+			_, _ = w.Write([]byte(f + "\n"))
+		}
+	}))
+	defer ts.Close()
+
+	h := NewLLMInferenceHandler()
+	h.baseURLs["vllm"] = ts.URL
+	job := &Job{ID: "job-tools", Type: JobTypeLLMInference, Payload: map[string]any{
+		"model":       "m",
+		"backend":     "vllm",
+		"stream":      true,
+		"messages":    []map[string]any{{"role": "user", "content": "weather in Oslo?"}},
+		"tools":       json.RawMessage(`[{"type":"function","function":{"name":"get_weather","parameters":{"type":"object"}}}]`),
+		"tool_choice": "auto",
+	}}
+	stream := &MockStreamWriter{}
+	result, err := h.Execute(context.Background(), job, stream)
+	if err != nil || result.Status != JobStatusSuccess {
+		t.Fatalf("Execute = %+v, %v, want success", result, err)
+	}
+	if tools, _ := gotReq["tools"].([]any); len(tools) != 1 || gotReq["tool_choice"] != "auto" {
+		t.Errorf("request tools = %v, tool_choice = %v", gotReq["tools"], gotReq["tool_choice"])
+	}
+	if len(stream.chunkData) != 3 || stream.chunkData[0]["tool_calls"] == nil {
+		t.Errorf("structured chunks = %v, want the three tool-call deltas", stream.chunkData)
+	}
+	calls, _ := result.Output["tool_calls"].([]map[string]any)
+	if len(calls) != 1 {
+		t.Fatalf("tool_calls = %v, want one call", result.Output["tool_calls"])
+	}
+	fn := calls[0]["function"].(map[string]any)
+	if calls[0]["id"] != "call_abc" || fn["name"] != "get_weather" || fn["arguments"] != `{"city":"Oslo"}` {
+		t.Errorf("tool call = %v, want get_weather with the joined arguments", calls[0])
+	}
+	if result.Output["finish_reason"] != "tool_calls" {
+		t.Errorf("finish_reason = %v, want tool_calls", result.Output["finish_reason"])
+	}
+}
+
+func TestLLMInference_OllamaToolCalls(t *testing.T) {
+	body := `{"message":{"role":"assistant","content":"","tool_calls":[{"function":{"name":"get_weather","arguments":{"city":"Oslo"}}}]},"done":true}`
+	var gotPath string
+	var gotReq map[string]any
+	ts := newRecordingEngine(t, body, &gotPath, &gotReq)
+	h := NewLLMInferenceHandler()
+	h.baseURLs["ollama"] = ts.URL
+
+	payload := map[string]any{
+		"model":   "llama3.2",
+		"backend": "ollama",
+		"messages": []map[string]any{
+			{"role": "user", "content": "weather?"},
+			{"role": "assistant", "tool_calls": json.RawMessage(`[{"id":"call_0","type":"function","function":{"name":"get_weather","arguments":"{\"city\":\"Bergen\"}"}}]`)},
+			{"role": "tool", "tool_call_id": "call_0", "name": "get_weather", "content": "rain"},
+			{"role": "user", "content": "and Oslo?"},
+		},
+		"tools": json.RawMessage(`[{"type":"function","function":{"name":"get_weather"}}]`),
+	}
+	result, err := h.Execute(context.Background(), &Job{ID: "j", Type: JobTypeLLMInference, Payload: payload}, &MockStreamWriter{})
+	if err != nil || result.Status != JobStatusSuccess {
+		t.Fatalf("Execute = %+v, %v, want success", result, err)
+	}
+
+	msgs, _ := gotReq["messages"].([]any)
+	if len(msgs) != 4 {
+		t.Fatalf("messages = %v", gotReq["messages"])
+	}
+	history := msgs[1].(map[string]any)["tool_calls"].([]any)[0].(map[string]any)["function"].(map[string]any)
+	if args, _ := history["arguments"].(map[string]any); args["city"] != "Bergen" {
+		t.Errorf("history tool call arguments = %v, want an object for Ollama", history["arguments"])
+	}
+	if msgs[2].(map[string]any)["tool_name"] != "get_weather" {
+		t.Errorf("tool result = %v, want tool_name", msgs[2])
+	}
+
+	calls, _ := result.Output["tool_calls"].([]map[string]any)
+	if len(calls) != 1 {
+		t.Fatalf("tool_calls = %v, want one", result.Output["tool_calls"])
+	}
+	fn := calls[0]["function"].(map[string]any)
+	if calls[0]["id"] != "call_0" || fn["arguments"] != `{"city":"Oslo"}` {
+		t.Errorf("tool call = %v, want an OpenAI-shaped call with string arguments", calls[0])
+	}
+
+	t.Run("forced tool_choice fails", func(t *testing.T) {
+		payload["tool_choice"] = json.RawMessage(`{"type":"function","function":{"name":"get_weather"}}`)
+		result, _ := h.Execute(context.Background(), &Job{ID: "j2", Type: JobTypeLLMInference, Payload: payload}, &MockStreamWriter{})
+		if result.Status != JobStatusFailure || !strings.Contains(result.Error.Error(), "Ollama") {
+			t.Errorf("result = %+v, want a failure naming Ollama", result)
+		}
+	})
+}
+
+func TestLLMInference_ChatLogprobsStream(t *testing.T) {
+	frames := []string{
+		`data: {"choices":[{"delta":{"content":"Hi"},"logprobs":{"content":[{"token":"Hi","logprob":-0.1}]}}]}`,
+		`data: {"choices":[{"delta":{"content":"!"},"logprobs":{"content":[{"token":"!","logprob":-0.5}]}}]}`,
+		`data: [DONE]`,
+	}
+	ts := newChatCompletionsServer(t, frames, nil)
+	defer ts.Close()
+	h := NewLLMInferenceHandler()
+	h.baseURLs["bonsai"] = ts.URL
+
+	job := &Job{ID: "job-lp", Type: JobTypeLLMInference, Payload: map[string]any{
+		"model": "m", "backend": "bonsai", "stream": true, "logprobs": true, "top_logprobs": 2,
+		"messages": []map[string]any{{"role": "user", "content": "hi"}},
+	}}
+	stream := &MockStreamWriter{}
+	result, err := h.Execute(context.Background(), job, stream)
+	if err != nil || result.Status != JobStatusSuccess {
+		t.Fatalf("Execute = %+v, %v, want success", result, err)
+	}
+	if strings.Join(stream.chunks, "") != "Hi!" || len(stream.chunkData) != 2 {
+		t.Errorf("chunks = %v, data = %v, want both content chunks with logprobs", stream.chunks, stream.chunkData)
+	}
+	lp, _ := result.Output["logprobs"].(map[string]any)
+	if content, _ := lp["content"].([]any); len(content) != 2 {
+		t.Errorf("logprobs = %v, want both tokens merged", result.Output["logprobs"])
+	}
+}
+
+func TestParseLLMInferencePayload_StructuredValidation(t *testing.T) {
+	base := func(extra map[string]any) map[string]any {
+		p := map[string]any{"model": "m", "messages": []map[string]any{{"role": "user", "content": "hi"}}}
+		for k, v := range extra {
+			p[k] = v
+		}
+		return p
+	}
+	bad := map[string]map[string]any{
+		"unknown format":       base(map[string]any{"response_format": map[string]any{"type": "xml"}}),
+		"schema missing":       base(map[string]any{"response_format": map[string]any{"type": "json_schema"}}),
+		"schema not an object": base(map[string]any{"response_format": map[string]any{"type": "json_schema", "json_schema": map[string]any{"schema": "x"}}}),
+		"tools not an array":   base(map[string]any{"tools": map[string]any{"type": "function"}}),
+		"tools on a prompt":    {"model": "m", "prompt": "hi", "tools": []any{map[string]any{"type": "function"}}},
+		"top_logprobs alone":   base(map[string]any{"top_logprobs": 3}),
+		"top_logprobs too big": base(map[string]any{"logprobs": true, "top_logprobs": 99}),
+	}
+	for name, payload := range bad {
+		if _, err := parseLLMInferencePayload(payload); err == nil {
+			t.Errorf("%s: parsed, want an error", name)
+		}
+	}
+	if _, err := parseLLMInferencePayload(base(map[string]any{"response_format": map[string]any{"type": "json_object"}, "logprobs": true})); err != nil {
+		t.Errorf("valid structured payload rejected: %v", err)
+	}
+}
+
+func TestValidateJSONSchema(t *testing.T) {
+	schema := `{
+		"type": "object",
+		"$defs": {"tag": {"type": "string", "enum": ["a", "b"]}},
+		"properties": {
+			"id":    {"type": "integer", "minimum": 1},
+			"name":  {"type": "string", "minLength": 2, "pattern": "^[A-Z]"},
+			"tags":  {"type": "array", "items": {"$ref": "#/$defs/tag"}, "maxItems": 2},
+			"score": {"type": ["number", "null"]},
+			"kind":  {"oneOf": [{"const": "x"}, {"const": "y"}]},
+			"meta":  {"type": "object", "additionalProperties": {"type": "boolean"}}
+		},
+		"required": ["id", "name"],
+		"additionalProperties": false
+	}`
+	cases := []struct {
+		value string
+		ok    bool
+	}{
+		{`{"id":1,"name":"Ada"}`, true},
+		{`{"id":1,"name":"Ada","tags":["a","b"],"score":null,"kind":"y","meta":{"x":true}}`, true},
+		{`{"id":1.5,"name":"Ada"}`, false},
+		{`{"id":0,"name":"Ada"}`, false},
+		{`{"name":"Ada"}`, false},
+		{`{"id":1,"name":"A"}`, false},
+		{`{"id":1,"name":"ada"}`, false},
+		{`{"id":1,"name":"Ada","tags":["c"]}`, false},
+		{`{"id":1,"name":"Ada","tags":["a","a","a"]}`, false},
+		{`{"id":1,"name":"Ada","score":"high"}`, false},
+		{`{"id":1,"name":"Ada","kind":"z"}`, false},
+		{`{"id":1,"name":"Ada","meta":{"x":1}}`, false},
+		{`{"id":1,"name":"Ada","extra":1}`, false},
+		{`[1,2]`, false},
+	}
+	for _, tc := range cases {
+		var v any
+		if err := json.Unmarshal([]byte(tc.value), &v); err != nil {
+			t.Fatal(err)
+		}
+		err := validateJSONSchema(json.RawMessage(schema), v)
+		if (err == nil) != tc.ok {
+			t.Errorf("validate(%s) = %v, want ok=%v", tc.value, err, tc.ok)
+		}
+	}
+}
+
+func jsonQuote(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b)
+}
+
+func TestToolCallAccumulatorDropsBogusIndexes(t *testing.T) {
+	var deltas []openAIToolCall
+	if err := json.Unmarshal([]byte(`[
+		{"index": 0, "id": "call_a", "function": {"name": "get", "arguments": "{}"}},
+		{"index": -1, "function": {"name": "neg"}},
+		{"index": 2000000000, "function": {"name": "huge"}}
+	]`), &deltas); err != nil {
+		t.Fatal(err)
+	}
+	var a toolCallAccumulator
+	a.addDelta(deltas)
+	if out := a.output(); len(out) != 1 || out[0]["id"] != "call_a" {
+		t.Errorf("calls = %v, want only call_a", out)
+	}
+}
@@ -0,0 +1,361 @@
+// internal/worker/prewarm.go
+//
+// Speculative model prewarming on top of hotswap (swap.go).
+//
+// EnsureResident swaps an engine in only when a job for it arrives, so the
+// first request after an idle stretch pays the whole cold load, and
+// MeasuredLoad shows that can be minutes. The Prewarmer watches llm_inference
+// demand together with the swap ledger, predicts which engine is wanted next,
+// and loads it while VRAM is free. Demand comes from two places: the backlog
+// waiting on the tag queues this node subscribes to, sampled every round
+// without claiming anything (a job is counted while it waits, not only once a
+// handler has it), and the jobs the handler receives from any queue. Each job
+// ID is counted once, so a job that is both seen waiting and later handled,
+// or redelivered after a model_warming reply, does not inflate demand. A
+// source that cannot peek (the API proxy) leaves the handler as the only
+// signal.
+//
+// The prediction is deliberately simple and explainable in a log line. For
+// each engine it estimates how many requests will arrive in the next horizon
+// from two signals, taking whichever is larger:
+//
+//   - recent rate: arrivals decayed with a half-life, so a model that was busy
+//     a few minutes ago is expected to stay busy;
+//   - time of day: arrivals in the same window on previous days, so a model
+//     used every morning is loaded before the morning starts.
+//
+// Expected demand is then weighed by what a miss would cost (the engine's
+// measured load time) and by how often it has cold-missed recently (swaps in
+// the ledger that a request had to wait on). The highest-scoring engine that is
+// not resident is handed to SwapManager.Prewarm, which enforces the safety
+// side: free VRAM only, never an eviction, the shared single-flight slot and
+// the swap rate bound. Speculation never stands in a request's way: a prewarm
+// still loading is cancelled by a request for another engine, and a prewarmed
+// engine nobody has asked for has no residency protection at all.
+//
+// Demand history is in-process like the swap ledger, so time-of-day patterns
+// are learned afresh after a restart.
+package worker
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Prewarm knobs. The interval is how often a speculative load is considered;
+// the horizon is how far ahead demand is predicted; the half-life is how fast
+// recent arrivals stop counting; the history is how far back time-of-day
+// patterns look. minDemand is the expected number of requests in the horizon
+// below which nothing is loaded, and the cooldown stops the predictor retrying
+// one engine every round when its loads keep being wasted or failing.
+const (
+	prewarmInterval    = time.Minute
+	prewarmHorizon     = 15 * time.Minute
+	prewarmHalfLife    = 15 * time.Minute
+	prewarmHistory     = 7 * 24 * time.Hour
+	prewarmMinDemand   = 0.5
+	prewarmCooldown    = 30 * time.Minute
+	prewarmMaxArrivals = 4096
+	// prewarmBacklogSample is how many waiting messages are read per tag queue
+	// each round.
+	prewarmBacklogSample = 64
+)
+
+// demandRecorder is the handler's view of the Prewarmer: it is told about each
+// llm_inference job as it arrives. An interface so the handler does not care
+// whether prewarming is on.
+type demandRecorder interface {
+	RecordDemand(jobID, queue, backend, model string)
+}
+
+// BacklogPeeker is a job source that can show what is waiting on its queues
+// without claiming it. RedisSource implements it.
+type BacklogPeeker interface {
+	PeekBacklog(ctx context.Context, perQueue int) ([]*Job, error)
+}
+
+// demandArrival is one llm_inference job seen by this node.
+type demandArrival struct {
+	backend string
+	model   string
+	queue   string
+	at      time.Time
+}
+
+// PrewarmCandidate is one engine the predictor would load, with the numbers
+// behind the choice.
+type PrewarmCandidate struct {
+	Backend string
+	// Model is the model most recently requested of the engine.
+	Model string
+	// Demand is the expected number of requests in the next horizon.
+	Demand float64
+	// Misses counts recent swaps a request had to wait on for this engine.
+	Misses int
+	// Score ranks candidates: Demand weighed by load time and misses.
+	Score float64
+	// Queues lists the queues its demand arrived on.
+	Queues []string
+}
+
+// Prewarmer predicts the next engine from queue demand and prewarms it.
+type Prewarmer struct {
+	swap *SwapManager
+	logf func(format string, args ...any)
+	now  func() time.Time
+
+	interval  time.Duration
+	horizon   time.Duration
+	halfLife  time.Duration
+	history   time.Duration
+	minDemand float64
+	cooldown  time.Duration
+
+	backlog BacklogPeeker // nil: handler-reported demand only
+
+	mu        sync.Mutex
+	arrivals  []demandArrival      // oldest first
+	seen      map[string]time.Time // job ID -> when its arrival was recorded
+	lastTried map[string]time.Time // per-engine last speculative load
+}
+
+// NewPrewarmer builds a predictor that prewarms through swap. logf may be nil.
+func NewPrewarmer(swap *SwapManager, logf func(format string, args ...any)) *Prewarmer {
+	if logf == nil {
+		logf = func(string, ...any) {}
+	}
+	return &Prewarmer{
+		swap:      swap,
+		logf:      logf,
+		now:       time.Now,
+		interval:  prewarmInterval,
+		horizon:   prewarmHorizon,
+		halfLife:  prewarmHalfLife,
+		history:   prewarmHistory,
+		minDemand: prewarmMinDemand,
+		cooldown:  prewarmCooldown,
+		seen:      map[string]time.Time{},
+		lastTried: map[string]time.Time{},
+	}
+}
+
+// WithBacklog makes the predictor sample src's waiting tag-queue messages each
+// round.
+func (p *Prewarmer) WithBacklog(src BacklogPeeker) *Prewarmer {
+	p.backlog = src
+	return p
+}
+
+// RecordDemand notes that llm_inference job jobID for backend/model arrived on
+// queue. A job ID already recorded is ignored. Satisfies demandRecorder.
+func (p *Prewarmer) RecordDemand(jobID, queue, backend, model string) {
+	if backend == "" {
+		return
+	}
+	now := p.now()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if jobID != "" {
+		if _, dup := p.seen[jobID]; dup {
+			return
+		}
+		p.seen[jobID] = now
+	}
+	p.arrivals = append(p.arrivals, demandArrival{backend: backend, model: model, queue: queue, at: now})
+	p.pruneLocked(now)
+}
+
+// sampleBacklog records the llm_inference jobs waiting on the tag queues.
+func (p *Prewarmer) sampleBacklog(ctx context.Context) {
+	if p.backlog == nil {
+		return
+	}
+	waiting, err := p.backlog.PeekBacklog(ctx, prewarmBacklogSample)
+	if err != nil {
+		p.logf("[prewarm] could not sample the queue backlog: %v", err)
+	}
+	for _, job := range waiting {
+		if job.Type != JobTypeLLMInference {
+			continue
+		}
+		payload, err := parseLLMInferencePayload(job.Payload)
+		if err != nil {
+			continue // the handler will reject it; it is not demand
+		}
+		p.RecordDemand(job.ID, job.SourceQueue, payload.Backend, payload.Model)
+	}
+}
+
+// pruneLocked drops arrivals older than the history, then caps the log, and
+// forgets the job IDs of the arrivals it dropped.
+// Callers hold p.mu.
+func (p *Prewarmer) pruneLocked(now time.Time) {
+	cutoff := now.Add(-p.history)
+	i := 0
+	for i < len(p.arrivals) && p.arrivals[i].at.Before(cutoff) {
+		i++
+	}
+	if n := len(p.arrivals) - i; n > prewarmMaxArrivals {
+		i += n - prewarmMaxArrivals
+	}
+	if i == 0 {
+		return
+	}
+	p.arrivals = append([]demandArrival(nil), p.arrivals[i:]...)
+	// Job IDs are remembered as long as their arrival is.
+	oldest := now
+	if len(p.arrivals) > 0 {
+		oldest = p.arrivals[0].at
+	}
+	for id, at := range p.seen {
+		if at.Before(oldest) {
+			delete(p.seen, id)
+		}
+	}
+}
+
+// Predict ranks the engines worth prewarming now, best first. Engines whose
+// expected demand is below the threshold are left out; residency is not
+// considered here (Prewarm checks it).
+func (p *Prewarmer) Predict() []PrewarmCandidate {
+	now := p.now()
+	p.mu.Lock()
+	p.pruneLocked(now)
+	arrivals := append([]demandArrival(nil), p.arrivals...)
+	p.mu.Unlock()
+	if len(arrivals) == 0 {
+		return nil
+	}
+
+	// Whole previous days the log covers; 0 until the node has a day of history.
+	days := int(now.Sub(arrivals[0].at) / (24 * time.Hour))
+	if limit := int(p.history / (24 * time.Hour)); days > limit {
+		days = limit
+	}
+
+	type tally struct {
+		decayed float64
+		sameTOD int
+		model   string
+		queues  map[string]bool
+	}
+	tallies := map[string]*tally{}
+	for _, a := range arrivals {
+		t := tallies[a.backend]
+		if t == nil {
+			t = &tally{queues: map[string]bool{}}
+			tallies[a.backend] = t
+		}
+		age := now.Sub(a.at)
+		t.decayed += math.Pow(0.5, age.Seconds()/p.halfLife.Seconds())
+		for d := 1; d <= days; d++ {
+			start := now.Add(-time.Duration(d) * 24 * time.Hour)
+			if !a.at.Before(start) && a.at.Before(start.Add(p.horizon)) {
+				t.sameTOD++
+				break
+			}
+		}
+		if a.model != "" {
+			t.model = a.model // arrivals are oldest first: the last one wins
+		}
+		if a.queue != "" {
+			t.queues[a.queue] = true
+		}
+	}
+
+	misses := map[string]int{}
+	for _, rec := range p.swap.SwapStats().Recent {
+		if !rec.Prewarm && (rec.Outcome == swapOutcomeReady || rec.Outcome == swapOutcomeWarming) {
+			misses[rec.Backend]++
+		}
+	}
+
+	var out []PrewarmCandidate
+	for backend, t := range tallies {
+		// A decayed count D under a steady rate r is about r*halfLife/ln2.
+		recent := t.decayed * math.Ln2 / p.halfLife.Seconds() * p.horizon.Seconds()
+		demand := recent
+		if days > 0 {
+			demand = math.Max(recent, float64(t.sameTOD)/float64(days))
+		}
+		if demand < p.minDemand {
+			continue
+		}
+		load, ok := p.swap.MeasuredLoad(backend)
+		if !ok {
+			load = p.swap.loadEstimate(backend)
+		}
+		c := PrewarmCandidate{
+			Backend: backend,
+			Model:   t.model,
+			Demand:  demand,
+			Misses:  misses[backend],
+			Score:   demand * load.Seconds() * float64(1+misses[backend]),
+		}
+		for q := range t.queues {
+			c.Queues = append(c.Queues, q)
+		}
+		sort.Strings(c.Queues)
+		out = append(out, c)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Score != out[j].Score {
+			return out[i].Score > out[j].Score
+		}
+		return out[i].Backend < out[j].Backend
+	})
+	return out
+}
+
+// Run considers a speculative load every interval until ctx is done.
+func (p *Prewarmer) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			p.tick(ctx)
+		}
+	}
+}
+
+// tick starts at most one speculative load: the best candidate that is not
+// resident, fits in free VRAM and is out of its cooldown. It reports the
+// engine it started, or "" when it started none.
+func (p *Prewarmer) tick(ctx context.Context) string {
+	p.sampleBacklog(ctx)
+	now := p.now()
+	for _, c := range p.Predict() {
+		p.mu.Lock()
+		last, tried := p.lastTried[c.Backend]
+		p.mu.Unlock()
+		if tried && now.Sub(last) < p.cooldown {
+			continue
+		}
+
+		err := p.swap.Prewarm(ctx, c.Backend, c.Model)
+		switch {
+		case err == nil:
+			p.mu.Lock()
+			p.lastTried[c.Backend] = now
+			p.mu.Unlock()
+			p.logf("[prewarm] loading %s (model=%s) ahead of demand: %.1f request(s) expected in the next %s, %d recent cold miss(es)",
+				c.Backend, c.Model, c.Demand, p.horizon, c.Misses)
+			return c.Backend
+		case errors.Is(err, errPrewarmResident), errors.Is(err, errPrewarmNoRoom):
+			continue // a smaller or absent engine further down may still fit
+		default:
+			return "" // busy, rate-limited or blind: nothing else will do better this round
+		}
+	}
+	return ""
+}
+
+// Ensure Prewarmer satisfies the handler's demand hook.
+var _ demandRecorder = (*Prewarmer)(nil)
@@ -0,0 +1,287 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aceteam-ai/citadel-cli/internal/status"
+)
+
+// newTestPrewarmer builds a predictor over a fast test manager with a fixed
+// clock the test can move.
+func newTestPrewarmer(ctrl SwapController) (*Prewarmer, *SwapManager, *time.Time) {
+	now := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	m := newTestManager(ctrl)
+	m.now = func() time.Time { return now }
+	p := NewPrewarmer(m, nil)
+	p.now = func() time.Time { return now }
+	return p, m, &now
+}
+
+// recordAt records one arrival, as a job of its own, as if it happened at `at`.
+func recordAt(p *Prewarmer, at time.Time, queue, backend, model string) {
+	saved := p.now
+	p.now = func() time.Time { return at }
+	p.RecordDemand(fmt.Sprintf("job-%d", len(p.seen)), queue, backend, model)
+	p.now = saved
+}
+
+// fakeBacklog is a BacklogPeeker serving a fixed set of waiting jobs.
+type fakeBacklog []*Job
+
+func (f fakeBacklog) PeekBacklog(context.Context, int) ([]*Job, error) { return f, nil }
+
+func TestPrewarmer_CountsEachJobOnce(t *testing.T) {
+	p, _, _ := newTestPrewarmer(newMockController())
+	waiting := fakeBacklog{
+		{ID: "j1", Type: JobTypeLLMInference, SourceQueue: "jobs:v1:tag:llm:qwen",
+			Payload: map[string]any{"backend": "vllm", "model": "qwen-7b", "prompt": "hi"}},
+		{ID: "j2", Type: "SHELL_COMMAND", SourceQueue: "jobs:v1:tag:llm:qwen"},
+	}
+	p.WithBacklog(waiting)
+
+	p.sampleBacklog(context.Background())
+	p.sampleBacklog(context.Background()) // still waiting next round
+	// The handler then receives it, and again after a model_warming reply.
+	p.RecordDemand("j1", "jobs:v1:tag:llm:qwen", "vllm", "qwen-7b")
+	p.RecordDemand("j1", "jobs:v1:tag:llm:qwen", "vllm", "qwen-7b")
+
+	if n := len(p.arrivals); n != 1 {
+		t.Fatalf("arrivals = %d, want the one waiting llm_inference job counted once", n)
+	}
+	if a := p.arrivals[0]; a.backend != "vllm" || a.queue != "jobs:v1:tag:llm:qwen" {
+		t.Errorf("arrival = %+v", a)
+	}
+}
+
+func TestPrewarmer_PredictRanksRecentDemandByLoadCost(t *testing.T) {
+	p, _, now := newTestPrewarmer(newMockController())
+	for i := 0; i < 3; i++ {
+		recordAt(p, now.Add(-time.Duration(i)*time.Minute), "jobs:v1:tag:llm:qwen", "vllm", "qwen-7b")
+	}
+	recordAt(p, now.Add(-time.Minute), "jobs:v1:gpu-general", "ollama", "llama3.2")
+	recordAt(p, now.Add(-4*time.Hour), "jobs:v1:gpu-general", "bonsai", "Bonsai-27B") // long gone
+	recordAt(p, *now, "jobs:v1:tag:llm:qwen", "vllm", "qwen-14b")
+
+	got := p.Predict()
+	if len(got) != 2 {
+		t.Fatalf("candidates = %+v, want vllm and ollama (bonsai's demand has decayed)", got)
+	}
+	if got[0].Backend != "vllm" || got[1].Backend != "ollama" {
+		t.Errorf("order = %s, %s; want vllm (more demand, longer load) first", got[0].Backend, got[1].Backend)
+	}
+	if got[0].Model != "qwen-14b" {
+		t.Errorf("model = %q, want the most recently requested", got[0].Model)
+	}
+	if len(got[0].Queues) != 1 || got[0].Queues[0] != "jobs:v1:tag:llm:qwen" {
+		t.Errorf("queues = %v", got[0].Queues)
+	}
+}
+
+func TestPrewarmer_PredictLearnsTimeOfDay(t *testing.T) {
+	p, _, now := newTestPrewarmer(newMockController())
+	// Oldest first, as they arrive: every morning for three days a request five
+	// minutes after "now", and an afternoon-only model at the wrong time of day.
+	for d := 3; d >= 1; d-- {
+		day := now.Add(-time.Duration(d) * 24 * time.Hour)
+		recordAt(p, day.Add(5*time.Minute), "jobs:v1:tag:llm:qwen", "vllm", "qwen-7b")
+		recordAt(p, day.Add(6*time.Hour), "jobs:v1:gpu-general", "ollama", "llama3.2")
+	}
+
+	got := p.Predict()
+	if len(got) != 1 || got[0].Backend != "vllm" {
+		t.Fatalf("candidates = %+v, want only the morning model", got)
+	}
+	if got[0].Demand < 0.9 {
+		t.Errorf("demand = %.2f, want about one request expected", got[0].Demand)
+	}
+}
+
+func TestPrewarmer_ColdMissesRaiseTheScore(t *testing.T) {
+	p, m, now := newTestPrewarmer(newMockController())
+	recordAt(p, *now, "q", "vllm", "a")
+	recordAt(p, *now, "q", "sglang", "b")
+	// Both engines share a load estimate; sglang has kept cold-missing.
+	m.swaps = append(m.swaps,
+		SwapRecord{Backend: "sglang", StartedAt: now.Add(-10 * time.Minute), Outcome: swapOutcomeReady},
+		SwapRecord{Backend: "vllm", StartedAt: now.Add(-10 * time.Minute), Outcome: swapOutcomeReady, Prewarm: true},
+	)
+
+	got := p.Predict()
+	if len(got) != 2 || got[0].Backend != "sglang" || got[0].Misses != 1 || got[1].Misses != 0 {
+		t.Fatalf("candidates = %+v, want sglang first on its miss (a prewarm is not a miss)", got)
+	}
+}
+
+func TestPrewarmer_TickLoadsIntoFreeVRAM(t *testing.T) {
+	ctrl := newMockController()
+	ctrl.readyAfterStart = true
+	p, m, now := newTestPrewarmer(ctrl)
+	recordAt(p, *now, "jobs:v1:tag:llm:qwen", "vllm", "qwen-7b")
+	recordAt(p, *now, "jobs:v1:tag:llm:qwen", "vllm", "qwen-7b")
+
+	if got := p.tick(context.Background()); got != "vllm" {
+		t.Fatalf("tick started %q, want vllm", got)
+	}
+	waitFor(t, func() bool { return len(m.SwapStats().Recent) == 1 }, "the prewarm swap should finish")
+	rec := m.SwapStats().Recent[0]
+	if !rec.Prewarm || rec.Outcome != swapOutcomeReady || rec.Model != "qwen-7b" {
+		t.Errorf("ledger record = %+v, want a ready prewarm of qwen-7b", rec)
+	}
+
+	// Now resident (and inside its cooldown): nothing more to do.
+	if got := p.tick(context.Background()); got != "" {
+		t.Errorf("second tick started %q, want nothing", got)
+	}
+}
+
+func TestPrewarmer_TickSkipsToACandidateThatFits(t *testing.T) {
+	ctrl := newMockController()
+	ctrl.freeVRAM = 10 << 30
+	p, m, now := newTestPrewarmer(ctrl)
+	m.requiredVRAM = func(b string) uint64 {
+		if b == "vllm" {
+			return 20 << 30
+		}
+		return 4 << 30
+	}
+	for i := 0; i < 3; i++ {
+		recordAt(p, *now, "q", "vllm", "big")
+	}
+	recordAt(p, *now, "q", "ollama", "small")
+
+	if got := p.tick(context.Background()); got != "ollama" {
+		t.Fatalf("tick started %q, want ollama (vllm does not fit in free VRAM)", got)
+	}
+}
+
+func TestSwapManager_PrewarmNeverEvicts(t *testing.T) {
+	ctrl, m := fullGPUWith(t, "vllm") // evicting vllm would fit, but VRAM is not free
+
+	if err := m.Prewarm(context.Background(), "bonsai", "Bonsai-27B"); !errors.Is(err, errPrewarmNoRoom) {
+		t.Fatalf("Prewarm = %v, want errPrewarmNoRoom", err)
+	}
+	if ctrl.startCountVal() != 0 || len(ctrl.stoppedNames()) != 0 {
+		t.Errorf("a refused prewarm started %d engine(s) and stopped %v", ctrl.startCountVal(), ctrl.stoppedNames())
+	}
+}
+
+func TestSwapManager_PrewarmRespectsSwapGuards(t *testing.T) {
+	t.Run("rate limit", func(t *testing.T) {
+		ctrl := newMockController()
+		m := newTestManager(ctrl)
+		fillEvictingSwaps(m, m.maxEvictingPerWindow, time.Minute)
+		var rateErr *SwapRateLimitedError
+		if err := m.Prewarm(context.Background(), "vllm", "m"); !errors.As(err, &rateErr) {
+			t.Fatalf("Prewarm = %v, want the swap rate limit", err)
+		}
+	})
+	t.Run("single flight", func(t *testing.T) {
+		ctrl := newMockController()
+		ctrl.startGate = make(chan struct{})
+		defer close(ctrl.startGate)
+		m := newTestManager(ctrl)
+		_, _ = m.EnsureResident(context.Background(), "sglang", "s")
+		if err := m.Prewarm(context.Background(), "vllm", "m"); !errors.Is(err, errPrewarmBusy) {
+			t.Fatalf("Prewarm = %v, want errPrewarmBusy while a swap is in flight", err)
+		}
+	})
+	t.Run("no VRAM signal", func(t *testing.T) {
+		ctrl := newMockController()
+		ctrl.haveVRAM = false
+		m := newTestManager(ctrl)
+		if err := m.Prewarm(context.Background(), "vllm", "m"); !errors.Is(err, errPrewarmNoSignal) {
+			t.Fatalf("Prewarm = %v, want errPrewarmNoSignal", err)
+		}
+	})
+}
+
+// TestSwap_PrewarmedEngineYieldsToDemand: an unserved engine past the
+// min-residency floor is normally protected until its load-derived ceiling
+// (#687), but a speculative load is not — a guess must not block a request.
+func TestSwap_PrewarmedEngineYieldsToDemand(t *testing.T) {
+	ctrl, m := fullGPUWith(t, "vllm")
+	m.readyAt["vllm"] = m.now().Add(-70 * time.Second)
+	m.prewarmed["vllm"] = true
+
+	if _, err := m.EnsureResident(context.Background(), "bonsai", "Bonsai-27B"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	waitFor(t, func() bool { return len(ctrl.stoppedNames()) > 0 }, "a prewarmed, unserved engine must be evictable past the floor")
+
+	// Nor does the min-residency floor hold for it until it has served.
+	_, m = fullGPUWith(t, "vllm")
+	m.readyAt["vllm"] = m.now().Add(-10 * time.Second)
+	m.prewarmed["vllm"] = true
+	if got := m.filterResidencyProtected([]status.PreemptCandidate{{Name: "vllm"}}); len(got) != 1 {
+		t.Errorf("a prewarmed, unserved engine inside the floor was protected")
+	}
+	m.markServed("vllm")
+	if got := m.filterResidencyProtected([]status.PreemptCandidate{{Name: "vllm"}}); len(got) != 0 {
+		t.Errorf("a prewarmed engine that has served was offered for eviction inside the floor")
+	}
+}
+
+// TestSwap_DemandCancelsInflightPrewarm: a speculative load in flight for one
+// engine must not refuse a request for another; the prewarm is cancelled, its
+// half-started engine stopped, and the demanded swap runs.
+func TestSwap_DemandCancelsInflightPrewarm(t *testing.T) {
+	ctrl := newMockController()
+	ctrl.readyAfterStart = true
+	ctrl.startGate = make(chan struct{}) // the prewarm's start never finishes on its own
+	m := newTestManager(ctrl)
+
+	if err := m.Prewarm(context.Background(), "vllm", "qwen-7b"); err != nil {
+		t.Fatalf("Prewarm = %v", err)
+	}
+	waitFor(t, func() bool { return ctrl.startCountVal() == 1 }, "the prewarm should issue its start")
+
+	ctrl.mu.Lock()
+	ctrl.startGate = nil // the demanded start goes straight through
+	ctrl.mu.Unlock()
+	if _, err := m.EnsureResident(context.Background(), "sglang", "s"); err != nil {
+		t.Fatalf("EnsureResident = %v", err)
+	}
+	waitFor(t, func() bool { return len(m.SwapStats().Recent) == 2 }, "both swaps should finish")
+
+	recs := m.SwapStats().Recent
+	if !recs[0].Prewarm || recs[0].Backend != "vllm" || recs[0].Outcome != swapOutcomeYielded {
+		t.Errorf("first record = %+v, want the vllm prewarm yielded", recs[0])
+	}
+	if recs[1].Prewarm || recs[1].Backend != "sglang" || recs[1].Outcome != swapOutcomeReady {
+		t.Errorf("second record = %+v, want a ready demanded sglang swap", recs[1])
+	}
+	if stopped := ctrl.stoppedNames(); len(stopped) != 1 || stopped[0] != "vllm" {
+		t.Errorf("stopped = %v, want the half-started vllm", stopped)
+	}
+}
+
+// TestSwap_DemandJoiningPrewarmMakesItDemanded: once a request waits on a
+// speculative swap, the swap is no longer a guess: its engine must not be left
+// marked prewarmed (and so evictable before its ceiling).
+func TestSwap_DemandJoiningPrewarmMakesItDemanded(t *testing.T) {
+	ctrl := newMockController()
+	ctrl.readyAfterStart = true
+	ctrl.startGate = make(chan struct{})
+	m := newTestManager(ctrl)
+
+	if err := m.Prewarm(context.Background(), "vllm", "qwen-7b"); err != nil {
+		t.Fatalf("Prewarm = %v", err)
+	}
+	_, _ = m.EnsureResident(context.Background(), "vllm", "qwen-7b")
+	close(ctrl.startGate)
+
+	waitFor(t, func() bool { return len(m.SwapStats().Recent) == 1 }, "the joined swap should finish")
+	if rec := m.SwapStats().Recent[0]; rec.Prewarm || rec.Outcome != swapOutcomeReady {
+		t.Errorf("ledger record = %+v, want a ready demanded swap", rec)
+	}
+	m.mu.Lock()
+	prewarmed := m.prewarmed["vllm"]
+	m.mu.Unlock()
+	if prewarmed {
+		t.Error("engine a request waited on is still marked prewarmed")
+	}
+}
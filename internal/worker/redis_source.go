@@ -2,6 +2,7 @@ package worker
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/url"
 	"strings"
@@ -300,6 +301,12 @@ func (s *RedisSource) convertJob(rj *redisclient.Job) *Job {
 // Ack acknowledges successful job completion.
 func (s *RedisSource) Ack(ctx context.Context, job *Job) error {
 	s.client.SetJobStatus(ctx, job.ID, "completed", nil)
+	return s.ReleaseMessage(ctx, job)
+}
+
+// ReleaseMessage removes the job's message from the consumer group's pending
+// list without touching the job's status. Satisfies messageReleaser.
+func (s *RedisSource) ReleaseMessage(ctx context.Context, job *Job) error {
 	if job.SourceQueue != "" {
 		return s.client.AckJobOnQueue(ctx, job.SourceQueue, job.MessageID)
 	}
@@ -385,6 +392,33 @@ func (s *RedisSource) AddQueue(ctx context.Context, queue string) error {
 	return nil
 }
 
+// PeekBacklog samples up to perQueue undelivered messages from each subscribed
+// tag queue without claiming them. It is what the model prewarm predictor
+// watches to see demand before it reaches a handler. Satisfies BacklogPeeker.
+func (s *RedisSource) PeekBacklog(ctx context.Context, perQueue int) ([]*Job, error) {
+	if s.client == nil {
+		return nil, nil
+	}
+	var out []*Job
+	var errs []error
+	for _, q := range s.snapshotQueues() {
+		if !strings.Contains(q, ":tag:") {
+			continue
+		}
+		rjs, err := s.client.PeekBacklog(ctx, q, int64(perQueue))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for _, rj := range rjs {
+			job := s.convertJob(rj)
+			job.SourceQueue = q
+			out = append(out, job)
+		}
+	}
+	return out, errors.Join(errs...)
+}
+
 // IsJobCancelled checks whether a job has been cancelled by the producer.
 func (s *RedisSource) IsJobCancelled(ctx context.Context, jobID string) bool {
 	cancelled, err := s.client.IsJobCancelled(ctx, jobID)
@@ -397,3 +431,9 @@ func (s *RedisSource) IsJobCancelled(ctx context.Context, jobID string) bool {
 
 // Ensure RedisSource implements JobSource
 var _ JobSource = (*RedisSource)(nil)
+
+// Ensure RedisSource can take a deferred job off its queue.
+var _ messageReleaser = (*RedisSource)(nil)
+
+// Ensure RedisSource can feed the prewarm predictor.
+var _ BacklogPeeker = (*RedisSource)(nil)
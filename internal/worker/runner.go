@@ -14,6 +14,8 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/aceteam-ai/citadel-cli/internal/config"
+	"github.com/aceteam-ai/citadel-cli/internal/tracing"
 	"github.com/aceteam-ai/citadel-cli/internal/usage"
 )
 
@@ -37,6 +39,20 @@ type Runner struct {
 	// counts) for the out-of-band status/control path (issue #236).
 	state *WorkerState
 
+	// localJobs holds failed jobs re-run from the control center (inspect.go),
+	// taken ahead of the source.
+	localJobs chan *Job
+
+	// Deferrable jobs held for a cheap energy window (energy_defer.go): holds
+	// maps job ID to its *jobHold, heldJobs counts live holds, released
+	// hands a job whose hold ended back to the run loop, and spool keeps the
+	// held jobs across restarts.
+	holds    sync.Map
+	heldJobs int64
+	released chan *Job
+	holdWG   sync.WaitGroup
+	spool    *deferSpool
+
 	// Lifecycle observability for safe self-update.
 	// activeJobs counts jobs currently executing in a handler.
 	// draining, when set, stops the run loop from fetching new jobs so
@@ -84,10 +100,46 @@ type RunnerConfig struct {
 	// State, when set, is updated with live introspection metrics so the
 	// status/control path can report consume/job activity (issue #236).
 	State *WorkerState
+
+	// CancelPollInterval is how often a running job's cancellation flag is
+	// checked (0 = 5s; negative disables mid-flight cancellation, leaving
+	// only the check before the handler starts).
+	CancelPollInterval time.Duration
+
+	// CancelGracePeriod is how long a cancelled handler has to stop its child
+	// processes and return before it is abandoned (0 = 30s).
+	CancelGracePeriod time.Duration
+
+	// EnergySchedule, when set, holds deferrable jobs that arrive in a peak
+	// hour until a cheap window opens (energy_defer.go). Nil runs every job
+	// on arrival.
+	EnergySchedule *config.EnergySchedule
+
+	// JobWindowFn is called for every finished job while EnergySchedule is
+	// set, with the energy windows it arrived and ran in (for the footprint
+	// job log).
+	JobWindowFn func(JobWindow)
+
+	// DeferPollInterval is how often a held job checks for a drain or a
+	// producer cancellation (0 = 30s).
+	DeferPollInterval time.Duration
+
+	// MaxDeferredJobs caps how many deferrable jobs may be held at once
+	// (0 = 16; negative disables holding). A deferrable job arriving at the cap
+	// runs immediately.
+	MaxDeferredJobs int
+
+	// DeferSpoolPath is the file held deferrable jobs are kept in while they
+	// wait, off their source. Empty disables holding.
+	DeferSpoolPath string
 }
 
 // NewRunner creates a new job runner.
 func NewRunner(source JobSource, handlers []JobHandler, config RunnerConfig) *Runner {
+	var spool *deferSpool
+	if config.DeferSpoolPath != "" {
+		spool = &deferSpool{path: config.DeferSpoolPath}
+	}
 	return &Runner{
 		source:         source,
 		handlers:       handlers,
@@ -98,6 +150,9 @@ func NewRunner(source JobSource, handlers []JobHandler, config RunnerConfig) *Ru
 		maxConcurrency: config.MaxConcurrency,
 		gpuTracker:     config.GPUTracker,
 		state:          config.State,
+		localJobs:      make(chan *Job, localJobQueueSize),
+		released:       make(chan *Job),
+		spool:          spool,
 	}
 }
 
@@ -160,16 +215,19 @@ func (r *Runner) recordConsumeStatus(pollErr error) {
 
 // recordJob records a job completion for usage tracking
 func (r *Runner) recordJob(record usage.UsageRecord) {
+	r.state.noteOutcome(record)
+	r.recordJobWindow(record)
+	publishJobOutcome(record)
 	if r.jobRecordFn != nil {
 		r.jobRecordFn(record)
 	}
 }
 
-// ActiveJobs returns the number of jobs currently executing in a handler.
-// It is safe to call concurrently and is used by the auto-updater to find an
-// idle moment before swapping the binary.
+// ActiveJobs returns the number of jobs currently executing in a handler or
+// held for a cheap energy window. It is safe to call concurrently and is used
+// by the auto-updater to find an idle moment before swapping the binary.
 func (r *Runner) ActiveJobs() int {
-	return int(atomic.LoadInt64(&r.activeJobs))
+	return int(atomic.LoadInt64(&r.activeJobs) + atomic.LoadInt64(&r.heldJobs))
 }
 
 // Drain signals the run loop to stop fetching new jobs. In-flight jobs are
@@ -230,6 +288,7 @@ func (r *Runner) Run(ctx context.Context) error {
 		fmt.Printf("   - Max Concurrency: %d\n", concurrency)
 	}
 	r.log("success", "Worker started, listening for jobs...")
+	r.resumeSpooledHolds(ctx)
 
 	// Semaphore for concurrent job processing
 	sem := make(chan struct{}, concurrency)
@@ -262,8 +321,14 @@ runLoop:
 			break runLoop
 		default:
 			// Stop fetching new jobs once draining (e.g. an auto-update is
-			// ready to apply). In-flight jobs continue to completion below.
+			// ready to apply). In-flight jobs continue to completion below,
+			// and held deferrable jobs, which the drain releases, still run.
 			if r.isDraining() {
+				if job := r.takeReleasedJob(); job != nil {
+					now := time.Now()
+					r.processJob(ctx, job, fetchTiming{start: now, end: now})
+					continue
+				}
 				select {
 				case <-time.After(200 * time.Millisecond):
 				case <-ctx.Done():
@@ -272,13 +337,27 @@ runLoop:
 				continue
 			}
 
-			// Fetch next job
-			job, err := r.source.Next(ctx)
-			// Record the poll cycle for introspection regardless of outcome,
-			// so the status path can report "last successful poll time" and
-			// whether the worker is actively consuming (issue #236).
-			r.state.RecordPoll()
-			r.recordConsumeStatus(err)
+			// Fetch next job. A deferrable job whose hold ended, then a re-run
+			// queued from the control center, go first; neither touches the
+			// source, so neither is a poll.
+			fetchStart := time.Now()
+			job := r.takeReleasedJob()
+			if job == nil {
+				job = r.takeLocalJob()
+			}
+
+			fromSource := false
+			var err error
+			if job == nil {
+				fromSource = true
+				job, err = r.source.Next(ctx)
+				// Record the poll cycle for introspection regardless of outcome,
+				// so the status path can report "last successful poll time" and
+				// whether the worker is actively consuming (issue #236).
+				r.state.RecordPoll()
+				r.recordConsumeStatus(err)
+			}
+			fetch := fetchTiming{start: fetchStart, end: time.Now()}
 			if err != nil {
 				if ctx.Err() != nil {
 					break runLoop // Context cancelled
@@ -318,6 +397,12 @@ runLoop:
 				continue // No job available, loop again
 			}
 
+			// A deferrable job arriving in a peak energy window is held
+			// off the loop until a cheap one (energy_defer.go).
+			if fromSource && r.holdDeferrable(ctx, job) {
+				continue
+			}
+
 			// Process the job (concurrently if maxConcurrency > 1)
 			if concurrency > 1 {
 				sem <- struct{}{} // Acquire semaphore slot
@@ -325,35 +410,50 @@ runLoop:
 				go func(j *Job) {
 					defer wg.Done()
 					defer func() { <-sem }() // Release semaphore slot
-					r.processJob(ctx, j)
+					r.processJob(ctx, j, fetch)
 				}(job)
 			} else {
-				r.processJob(ctx, job)
+				r.processJob(ctx, job, fetch)
 			}
 		}
 	}
 
-	// Wait for in-flight jobs to complete
+	// Wait for in-flight jobs to complete, and for holds to see the shutdown
 	wg.Wait()
+	r.holdWG.Wait()
 
 	r.log("info", "Worker shutdown complete")
 	return nil
 }
 
 // newStreamWriter builds the per-job stream writer, falling back to a no-op
-// writer when no factory is configured (e.g. Nexus HTTP source).
+// writer when no factory is configured (e.g. Nexus HTTP source) and for a
+// local re-run, which has no producer listening.
 func (r *Runner) newStreamWriter(job *Job) StreamWriter {
-	if r.streamWriterFactory != nil {
+	if r.streamWriterFactory != nil && job.Source != LocalJobSource {
 		return r.streamWriterFactory(job)
 	}
 	return &NoOpStreamWriter{}
 }
 
-// processJob dispatches a job to the appropriate handler.
-func (r *Runner) processJob(ctx context.Context, job *Job) {
+// processJob dispatches a job to the appropriate handler. fetch is when the
+// source call that delivered it ran, for the job's trace.
+func (r *Runner) processJob(ctx context.Context, job *Job, fetch fetchTiming) {
 	atomic.AddInt64(&r.activeJobs, 1)
 	defer atomic.AddInt64(&r.activeJobs, -1)
 
+	// A job released from an energy hold was claimed when the hold began;
+	// from here it counts as executing rather than held.
+	held := r.heldJob(job)
+	if held != nil {
+		atomic.AddInt64(&r.heldJobs, -1)
+	}
+
+	// Every span for this job, including the handler's, hangs off this root
+	// (trace.go). ctx carries it from here on.
+	ctx, root := r.startJobTrace(ctx, job, fetch)
+	defer root.End()
+
 	// Track job in the introspection state. jobOK is flipped to true only on a
 	// clean success; the deferred RecordJobDone classifies the outcome (issue
 	// #236). Covers every return path of this function.
@@ -361,6 +461,9 @@ func (r *Runner) processJob(ctx context.Context, job *Job) {
 	jobOK := false
 	defer func() { r.state.RecordJobDone(jobOK) }()
 
+	// source settles the job; a local re-run has nothing to settle.
+	source := r.sourceFor(job)
+
 	r.log("info", "Received job %s (type: %s)", job.ID, job.Type)
 	startTime := time.Now()
 
@@ -388,7 +491,8 @@ func (r *Runner) processJob(ctx context.Context, job *Job) {
 		} else {
 			r.log("info", "Skipping job %s: target_node=%s (this node=%s)", job.ID, targetNode, r.config.NodeID)
 		}
-		r.source.Ack(ctx, job)
+		root.SetAttr("citadel.job.outcome", "skipped")
+		source.Ack(ctx, job)
 		return
 	}
 
@@ -399,20 +503,30 @@ func (r *Runner) processJob(ctx context.Context, job *Job) {
 	// a wedged or dead-but-heartbeating node never reaches this line, so the
 	// dispatcher fast-fails in ~3s instead of burning the full result budget.
 	// Best-effort: a publish failure must not block execution.
-	stream := r.newStreamWriter(job)
-	if err := stream.WriteClaimed(r.agentVersion); err != nil {
-		r.log("warning", "Failed to publish claimed event for job %s: %v", job.ID, err)
+	// From here the job is on the control center's Jobs page (inspect.go).
+	tracked := r.state.trackJobStart(job)
+	defer r.state.trackJobEnd(tracked)
+
+	stream := countChunks(tracked, traceStream(ctx, root, r.newStreamWriter(job)))
+	_, claimSpan := tracing.Start(ctx, "job.claim")
+	if held == nil {
+		if err := stream.WriteClaimed(r.agentVersion); err != nil {
+			r.log("warning", "Failed to publish claimed event for job %s: %v", job.ID, err)
+		}
+		publishJobClaimed(job)
 	}
 
 	// JQS-Core Section 5.6: Check cancellation before processing
-	if r.source.IsJobCancelled(ctx, job.ID) {
+	cancelledEarly := source.IsJobCancelled(ctx, job.ID)
+	claimSpan.End()
+	if cancelledEarly {
 		r.log("info", "Job %s was cancelled before processing", job.ID)
 		if err := stream.WriteCancelled("Job cancelled before processing"); err != nil {
 			r.log("warning", "Failed to publish cancelled event for job %s: %v", job.ID, err)
 		}
 		r.recordJob(buildUsageRecord(job, "cancelled", startTime, time.Now(), nil, nil))
 		jobOK = true // cleanly acked, not a processing failure
-		r.source.Ack(ctx, job)
+		source.Ack(ctx, job)
 		return
 	}
 
@@ -442,7 +556,7 @@ func (r *Runner) processJob(ctx context.Context, job *Job) {
 					r.log("error", "GPU unavailable: %v", err)
 					r.recordJob(buildUsageRecord(job, "failed", startTime, time.Now(), nil, err))
 					stream.WriteError(err, false)
-					r.source.Nack(ctx, job, err)
+					source.Nack(ctx, job, err)
 					return
 				}
 				gpuIndex = gpuIdx
@@ -455,7 +569,7 @@ func (r *Runner) processJob(ctx context.Context, job *Job) {
 				err := fmt.Errorf("no GPU slots available")
 				r.log("warning", "No GPU slots: %v", err)
 				r.recordJob(buildUsageRecord(job, "retry", startTime, time.Now(), nil, err))
-				r.source.Nack(ctx, job, err)
+				source.Nack(ctx, job, err)
 				return
 			}
 			gpuIndex = idx
@@ -476,19 +590,41 @@ func (r *Runner) processJob(ctx context.Context, job *Job) {
 	// error and the failure path below publishes the terminal error + Nacks on
 	// the live parent ctx, letting the loop advance to the next job. With no
 	// budget present (older backend, or a legitimately unbounded job type like
-	// model download / build / provision) the call stays exactly as before --
-	// synchronous, no timeout, no watchdog goroutine.
+	// model download / build / provision) there is no timeout and the loop
+	// waits for the handler as long as it takes.
+	//
+	// Either way the handler runs under a context the cancellation watcher
+	// cancels if the producer cancels the job mid-flight; it then has a grace
+	// period to clean up before the job is reported cancelled (cancel.go).
 	var result *JobResult
 	var err error
+	execCtx, execSpan := tracing.Start(ctx, "job.execute")
+	jobCtx, stopWatch := r.watchCancellation(execCtx, job, tracked)
 	if timeout, ok := r.resolveJobTimeout(job); ok {
-		result, err = r.executeWithDeadline(ctx, handler, job, stream, timeout)
+		execSpan.SetAttr("citadel.job.timeout_ms", timeout)
+		result, err = r.executeWithDeadline(jobCtx, handler, job, stream, timeout)
 	} else {
-		result, err = handler.Execute(ctx, job, stream)
+		result, err = r.executeCancellable(jobCtx, handler, job, stream)
 	}
+	stopWatch()
+	r.state.noteResult(tracked, result)
+	execSpan.RecordError(err)
+	execSpan.End()
 
 	endTime := time.Now()
 	duration := endTime.Sub(startTime)
 
+	if errors.Is(err, errJobCancelled) {
+		r.log("info", "Job %s cancelled after %v", job.ID, duration)
+		if werr := stream.WriteCancelled("Job cancelled during processing"); werr != nil {
+			r.log("warning", "Failed to publish cancelled event for job %s: %v", job.ID, werr)
+		}
+		r.recordJob(buildUsageRecord(job, "cancelled", startTime, endTime, result, nil))
+		jobOK = true // cleanly acked, not a processing failure
+		source.Ack(ctx, job)
+		return
+	}
+
 	if err != nil || (result != nil && result.Status == JobStatusFailure) {
 		actualErr := err
 		if actualErr == nil && result != nil {
@@ -517,7 +653,7 @@ func (r *Runner) processJob(ctx context.Context, job *Job) {
 		// (which would slowly exhaust GPU capacity across repeated abandons).
 		var deadlineErr *deadlineExceededError
 		if errors.As(actualErr, &deadlineErr) {
-			r.source.Fail(ctx, job, actualErr, map[string]any{
+			source.Fail(ctx, job, actualErr, map[string]any{
 				"deadline_exceeded":  true,
 				"deadline_seconds":   deadlineErr.timeout.Seconds(),
 				"abandoned_by_agent": true,
@@ -525,14 +661,14 @@ func (r *Runner) processJob(ctx context.Context, job *Job) {
 			return
 		}
 
-		r.source.Nack(ctx, job, actualErr)
+		source.Nack(ctx, job, actualErr)
 		return
 	}
 
 	if result != nil && result.Status == JobStatusRetry {
 		r.log("warning", "Job %s needs retry (%v)", job.ID, duration)
 		r.recordJob(buildUsageRecord(job, "retry", startTime, endTime, result, result.Error))
-		r.source.Nack(ctx, job, result.Error)
+		source.Nack(ctx, job, result.Error)
 		return
 	}
 
@@ -545,7 +681,7 @@ func (r *Runner) processJob(ctx context.Context, job *Job) {
 	} else {
 		stream.WriteEnd(nil)
 	}
-	r.source.Ack(ctx, job)
+	source.Ack(ctx, job)
 }
 
 // failUnsupportedJobType terminally fails a job whose type has no registered
@@ -587,18 +723,13 @@ func (r *Runner) failUnsupportedJobType(ctx context.Context, job *Job, startTime
 	// Publish a terminal error event so the streaming dispatch path stops
 	// waiting on a terminal event that would otherwise never arrive. Marked
 	// non-recoverable: retrying an unsupported type on the same node is futile.
-	var stream StreamWriter
-	if r.streamWriterFactory != nil {
-		stream = r.streamWriterFactory(job)
-	} else {
-		stream = &NoOpStreamWriter{}
-	}
+	stream := r.newStreamWriter(job)
 	if werr := stream.WriteError(err, false); werr != nil {
 		r.log("warning", "Failed to publish unsupported-type error for job %s: %v", job.ID, werr)
 	}
 
 	// Fail (failed status + ACK) so the message is not redelivered forever.
-	if ferr := r.source.Fail(ctx, job, err, data); ferr != nil {
+	if ferr := r.sourceFor(job).Fail(ctx, job, err, data); ferr != nil {
 		r.log("warning", "Failed to ack unsupported job %s: %v", job.ID, ferr)
 	}
 }
@@ -20,6 +20,7 @@ type MockJobSource struct {
 	nacked        []*Job
 	failed        []*Job
 	failedData    []map[string]any
+	released      []*Job
 	connected     bool
 	closed        bool
 	mu            sync.Mutex
@@ -75,6 +76,14 @@ func (m *MockJobSource) Nack(ctx context.Context, job *Job, err error) error {
 	return nil
 }
 
+// ReleaseMessage records a job taken off the queue for the deferral spool.
+func (m *MockJobSource) ReleaseMessage(ctx context.Context, job *Job) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.released = append(m.released, job)
+	return nil
+}
+
 func (m *MockJobSource) Fail(ctx context.Context, job *Job, err error, data map[string]any) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -171,6 +180,7 @@ type MockStreamWriter struct {
 	claimedVersion string
 	started        bool
 	chunks         []string
+	chunkData      []map[string]any
 	ended          bool
 	errored        bool
 	erroredErr     error
@@ -194,6 +204,12 @@ func (m *MockStreamWriter) WriteChunk(content string, index int) error {
 	return nil
 }
 
+func (m *MockStreamWriter) WriteChunkData(content string, index int, data map[string]any) error {
+	m.chunks = append(m.chunks, content)
+	m.chunkData = append(m.chunkData, data)
+	return nil
+}
+
 func (m *MockStreamWriter) WriteEnd(result map[string]any) error {
 	m.ended = true
 	return nil
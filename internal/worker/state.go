@@ -47,6 +47,13 @@ type WorkerState struct {
 	inFlight  int64
 	processed int64
 	failed    int64
+
+	// jobsMu guards the per-job records behind the control center's Jobs
+	// page (inspect.go): jobs in flight by ID, and the last recentJobsLimit
+	// finished ones, oldest first.
+	jobsMu     sync.Mutex
+	activeJobs map[string]*trackedJob
+	recentJobs []*trackedJob
 }
 
 // NewWorkerState creates an empty WorkerState stamped with the start time.
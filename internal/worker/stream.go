@@ -43,6 +43,11 @@ func (w *RedisStreamWriter) WriteChunk(content string, index int) error {
 	return w.client.PublishChunk(w.ctx, w.jobID, w.rayID, content, index)
 }
 
+// WriteChunkData sends a chunk with structured fields (tool calls, logprobs).
+func (w *RedisStreamWriter) WriteChunkData(content string, index int, data map[string]any) error {
+	return w.client.PublishChunkData(w.ctx, w.jobID, w.rayID, content, index, data)
+}
+
 // WriteEnd signals successful job completion with final result.
 func (w *RedisStreamWriter) WriteEnd(result map[string]any) error {
 	return w.client.PublishEnd(w.ctx, w.jobID, w.rayID, result)
@@ -106,6 +111,11 @@ func (w *APIStreamWriter) WriteChunk(content string, index int) error {
 	return w.client.PublishChunk(w.ctx, w.jobID, w.rayID, content, index)
 }
 
+// WriteChunkData sends a chunk with structured fields (tool calls, logprobs).
+func (w *APIStreamWriter) WriteChunkData(content string, index int, data map[string]any) error {
+	return w.client.PublishChunkData(w.ctx, w.jobID, w.rayID, content, index, data)
+}
+
 // WriteEnd signals successful job completion with final result.
 func (w *APIStreamWriter) WriteEnd(result map[string]any) error {
 	return w.client.PublishEnd(w.ctx, w.jobID, w.rayID, result)
@@ -30,6 +30,13 @@
 //     load meant a model could become evictable before it finished loading.
 //   - LRU: candidates are pre-sorted least-recently-used first so PlanPreemption's
 //     stable idle-then-largest-VRAM ordering breaks ties by LRU.
+//
+// A swap can also be speculative: Prewarm (driven by the demand predictor in
+// prewarm.go) loads an engine before anyone asks for it, through the same
+// single-flight slot and ledger, but only into VRAM that is already free. A
+// speculative swap never holds the slot against a request: a demanded miss for
+// a different engine cancels it, stops the half-loaded engine, and takes the
+// slot.
 package worker
 
 import (
@@ -40,6 +47,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/aceteam-ai/citadel-cli/internal/events"
 	"github.com/aceteam-ai/citadel-cli/internal/status"
 )
 
@@ -122,6 +130,22 @@ type swapOp struct {
 	// separates a swap that spent the box's time from one refused before it
 	// began.
 	started bool
+	// prewarm marks a speculative swap (Prewarm) that no request is waiting on.
+	// It never evicts: if the VRAM it checked is gone by the time it plans, it
+	// stands down instead. A demand caller joining the op clears it, so the
+	// swap then proceeds and is recorded as demanded. Guarded by
+	// SwapManager.mu; read it through isPrewarm.
+	prewarm bool
+	// yielded marks a speculative swap cancelled so a demanded one could take
+	// the slot. Guarded by SwapManager.mu; read it through hasYielded.
+	yielded bool
+	// after is the yielded swap this one replaced; it waits for that swap to
+	// stop its engine before planning, so the VRAM it frees is counted.
+	after *swapOp
+
+	// ctx bounds the background work and is cancelled when the swap yields.
+	ctx    context.Context
+	cancel context.CancelFunc
 }
 
 // SwapManager serializes and observes model swaps on a node.
@@ -170,6 +194,12 @@ type SwapManager struct {
 	// fails and when an engine is evicted, so a stale entry can never keep an
 	// absent engine reporting "warming".
 	startedAt map[string]time.Time
+	// prewarmed marks engines whose current residency was a speculative load.
+	// Until such an engine serves a request it is protected only by the
+	// min-residency floor, not the served-once invariant: nobody asked for it,
+	// so it must not stand in the way of a swap somebody did ask for. Cleared
+	// on eviction with the rest of the residency.
+	prewarmed map[string]bool
 }
 
 // NewSwapManager builds a swap manager with default timing and VRAM/load
@@ -192,6 +222,7 @@ func NewSwapManager(ctrl SwapController) *SwapManager {
 		startedAt:            map[string]time.Time{},
 		servedAt:             map[string]time.Time{},
 		loadMeasured:         map[string]time.Duration{},
+		prewarmed:            map[string]bool{},
 	}
 }
 
@@ -274,17 +305,39 @@ func (m *SwapManager) EnsureResident(ctx context.Context, backend, model string)
 // existing swap for the SAME backend (joined), or nil when a swap for a DIFFERENT
 // backend is already in flight (the caller must not start a second one — the
 // single-flight guarantee that stops concurrent misses thrashing the GPU).
+//
+// A speculative swap in flight for a different backend does not refuse: it is
+// cancelled and the demanded swap takes the slot, starting once the
+// speculative one has stopped its engine.
 func (m *SwapManager) startOrJoin(backend, model string) *swapOp {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	var prev *swapOp
 	if m.inflight != nil {
 		if m.inflight.backend == backend {
-			return m.inflight // join the same-model swap
+			// Join the same-model swap. A request now waits on it, so a
+			// speculative one becomes a demanded one.
+			m.inflight.prewarm = false
+			return m.inflight
 		}
-		return nil // different model in flight: refuse
+		if !m.inflight.prewarm {
+			return nil // different model in flight: refuse
+		}
+		prev = m.inflight
+		prev.yielded = true
+		prev.cancel()
 	}
 
+	op := m.newSwapOpLocked(backend, model)
+	op.after = prev
+	go m.runSwap(op)
+	return op
+}
+
+// newSwapOpLocked creates a swap and claims the single-flight slot for it.
+// Callers hold m.mu and have checked the slot is free.
+func (m *SwapManager) newSwapOpLocked(backend, model string) *swapOp {
 	op := &swapOp{
 		backend:   backend,
 		model:     model,
@@ -292,27 +345,122 @@ func (m *SwapManager) startOrJoin(backend, model string) *swapOp {
 		loadEst:   m.loadEstimate(backend),
 		done:      make(chan struct{}),
 	}
+	op.ctx, op.cancel = context.WithTimeout(context.Background(), m.backgroundMax)
 	m.inflight = op
-	go m.runSwap(op)
 	return op
 }
 
+// Prewarm errors: why a speculative swap was not started. None is a fault;
+// the predictor moves on to its next candidate or waits for the next round.
+var (
+	errPrewarmBusy     = errors.New("a swap is already in flight")
+	errPrewarmResident = errors.New("engine is already resident")
+	errPrewarmNoSignal = errors.New("free VRAM is unknown")
+	errPrewarmNoRoom   = errors.New("not enough free VRAM")
+)
+
+// Prewarm speculatively swaps in `backend` serving `model`, ahead of demand.
+// Unlike EnsureResident it never evicts: it starts only when the engine fits
+// in VRAM that is free right now, and only when the single-flight slot is idle
+// and the node is under its swap rate bound, so speculation cannot displace a
+// model that is serving or push a thrashing node further. The swap runs in the
+// background like any other; a nil error means it was started.
+func (m *SwapManager) Prewarm(ctx context.Context, backend, model string) error {
+	m.mu.Lock()
+	busy := m.inflight != nil
+	m.mu.Unlock()
+	if busy {
+		return errPrewarmBusy
+	}
+	if m.ctrl.Resident(ctx, backend) {
+		return errPrewarmResident
+	}
+	if err := m.checkSwapRate(backend); err != nil {
+		return err
+	}
+	required := m.requiredVRAM(backend)
+	_, freeVRAM, haveVRAM := m.ctrl.PreemptInputs(ctx, backend)
+	if required == 0 || !haveVRAM {
+		return errPrewarmNoSignal // speculation needs a positive signal, not an absent one
+	}
+	if freeVRAM < required {
+		return errPrewarmNoRoom
+	}
+
+	m.mu.Lock()
+	if m.inflight != nil {
+		m.mu.Unlock()
+		return errPrewarmBusy
+	}
+	op := m.newSwapOpLocked(backend, model)
+	op.prewarm = true
+	m.mu.Unlock()
+	go m.runSwap(op)
+	return nil
+}
+
+// isPrewarm reports whether op is still speculative (see swapOp.prewarm).
+func (m *SwapManager) isPrewarm(op *swapOp) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return op.prewarm
+}
+
+// hasYielded reports whether op was cancelled for a demanded swap.
+func (m *SwapManager) hasYielded(op *swapOp) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return op.yielded
+}
+
 // runSwap performs the actual eviction + start + wait-ready in the background,
 // with its own bounded context (independent of any job context). It closes
 // op.done when finished and clears the single-flight slot.
 func (m *SwapManager) runSwap(op *swapOp) {
 	defer func() {
+		// A yielded swap that got as far as starting its engine stops it
+		// before reporting done, so the demanded swap waiting on it plans
+		// against the VRAM that frees. An engine that made it to ready
+		// stays: it is an ordinary prewarmed, unserved engine, which the
+		// demanded swap may evict like any other.
+		if m.hasYielded(op) && op.started && !op.ready {
+			if err := m.ctrl.StopNonDurable(op.backend); err == nil {
+				m.forget(op.backend)
+			}
+		}
 		close(op.done)
 		m.mu.Lock()
 		if m.inflight == op {
 			m.inflight = nil
 		}
 		m.mu.Unlock()
-		m.recordSwap(m.swapRecord(op))
+		rec := m.swapRecord(op)
+		m.recordSwap(rec)
+		events.Publish(events.TypeSwapFinished, map[string]any{
+			"backend":      rec.Backend,
+			"model":        rec.Model,
+			"outcome":      rec.Outcome,
+			"evicted":      rec.Evicted,
+			"wait_seconds": rec.Wait.Seconds(),
+			"prewarm":      rec.Prewarm,
+		})
 	}()
+	events.Publish(events.TypeSwapStarted, map[string]any{"backend": op.backend, "model": op.model, "prewarm": m.isPrewarm(op)})
 
-	ctx, cancel := context.WithTimeout(context.Background(), m.backgroundMax)
-	defer cancel()
+	ctx := op.ctx
+	defer op.cancel()
+
+	if op.after != nil {
+		select {
+		case <-op.after.done:
+		case <-ctx.Done():
+			op.transient = true
+			return
+		}
+	}
+	if ctx.Err() != nil {
+		return // yielded before it began
+	}
 
 	// Plan and execute non-durable preemption to free VRAM.
 	if err := m.preempt(ctx, op); err != nil {
@@ -346,6 +494,9 @@ func (m *SwapManager) runSwap(op *swapOp) {
 			// (citadel-cli#687) instead of trusting the coarse table.
 			m.recordLoadDuration(op.backend, m.now().Sub(op.startedAt))
 			m.markReady(op.backend)
+			if m.isPrewarm(op) {
+				m.markPrewarmed(op.backend)
+			}
 			return
 		}
 		select {
@@ -392,6 +543,12 @@ func (m *SwapManager) preempt(ctx context.Context, op *swapOp) error {
 		op.transient = true // blocked only by residency protection; retry soon
 		return nil
 	}
+	// A speculative swap checked for free VRAM before it started; if that room
+	// has since been taken, it stands down rather than evict for a guess.
+	if len(plan.Stop) > 0 && m.isPrewarm(op) {
+		op.transient = true
+		return nil
+	}
 
 	// This swap needs to take VRAM away from a resident engine, so it is the kind
 	// the rate bound governs (citadel-cli#687). Checked HERE rather than before
@@ -442,6 +599,10 @@ func (m *SwapManager) checkSwapRate(backend string) error {
 //     It is bounded by unservedResidencyCeiling so an engine nobody ends up
 //     asking for cannot pin VRAM indefinitely.
 //
+// A prewarmed engine that has not served a request is exempt from both: its
+// load was a guess, and a guess must yield to a real request. Once it serves
+// one it is protected like any other engine.
+//
 // An engine with no readyAt entry — one this node never swapped in, e.g. started
 // by an operator or resident since boot — is protected by NEITHER. That is
 // deliberate: it has been up for an unknown-but-long time, and treating "we have
@@ -458,12 +619,17 @@ func (m *SwapManager) filterResidencyProtected(candidates []status.PreemptCandid
 			out = append(out, c)
 			continue
 		}
+		served, everServed := m.servedAt[c.Name]
+		unserved := !everServed || served.Before(ready)
+		if unserved && m.prewarmed[c.Name] {
+			out = append(out, c)
+			continue
+		}
 		age := now.Sub(ready)
 		if age < m.minResidency {
 			continue
 		}
-		served, everServed := m.servedAt[c.Name]
-		if (!everServed || served.Before(ready)) && age < m.unservedResidencyCeilingLocked(c.Name) {
+		if unserved && age < m.unservedResidencyCeilingLocked(c.Name) {
 			continue
 		}
 		out = append(out, c)
@@ -513,6 +679,8 @@ func (m *SwapManager) MeasuredLoad(backend string) (time.Duration, bool) {
 func (m *SwapManager) swapRecord(op *swapOp) SwapRecord {
 	outcome := swapOutcomeWarming
 	switch {
+	case m.hasYielded(op):
+		outcome = swapOutcomeYielded
 	case op.err != nil:
 		outcome = swapOutcomeFailed
 		var rateErr *SwapRateLimitedError
@@ -531,6 +699,7 @@ func (m *SwapManager) swapRecord(op *swapOp) SwapRecord {
 		StartedAt: op.startedAt,
 		Wait:      m.now().Sub(op.startedAt),
 		Outcome:   outcome,
+		Prewarm:   m.isPrewarm(op),
 	}
 }
 
@@ -551,12 +720,21 @@ func (m *SwapManager) touch(backend string) {
 }
 
 // markReady records that `backend` just became ready (starts its min-residency
-// window and refreshes its LRU stamp).
+// window and refreshes its LRU stamp). A fresh residency is a demanded one
+// until markPrewarmed says otherwise.
 func (m *SwapManager) markReady(backend string) {
 	now := m.now()
 	m.mu.Lock()
 	m.readyAt[backend] = now
 	m.lastUsed[backend] = now
+	delete(m.prewarmed, backend)
+	m.mu.Unlock()
+}
+
+// markPrewarmed records that backend's current residency was speculative.
+func (m *SwapManager) markPrewarmed(backend string) {
+	m.mu.Lock()
+	m.prewarmed[backend] = true
 	m.mu.Unlock()
 }
 
@@ -590,6 +768,7 @@ func (m *SwapManager) forget(name string) {
 	delete(m.readyAt, name)
 	delete(m.startedAt, name)
 	delete(m.servedAt, name)
+	delete(m.prewarmed, name)
 	m.mu.Unlock()
 }
 
@@ -55,6 +55,7 @@ const (
 	swapOutcomeFailed      = "failed"       // the start (or an eviction) errored
 	swapOutcomeBlocked     = "blocked"      // could not proceed now (residency protection); nothing started
 	swapOutcomeRateLimited = "rate_limited" // refused by the swap rate bound; nothing started
+	swapOutcomeYielded     = "yielded"      // a prewarm cancelled so a demanded swap could run
 )
 
 // SwapRecord is one swap this node attempted. It records what the manager
@@ -78,6 +79,9 @@ type SwapRecord struct {
 	Wait time.Duration `json:"wait"`
 	// Outcome is one of the swapOutcome* values.
 	Outcome string `json:"outcome"`
+	// Prewarm marks a speculative swap started by the demand predictor
+	// (prewarm.go) rather than by a request.
+	Prewarm bool `json:"prewarm,omitempty"`
 }
 
 // Evicting reports whether this swap took VRAM away from a resident engine.
@@ -70,7 +70,7 @@ func (m *mockSwapController) StopNonDurable(name string) error {
 	return nil
 }
 
-func (m *mockSwapController) Start(_ context.Context, backend, _ string) error {
+func (m *mockSwapController) Start(ctx context.Context, backend, _ string) error {
 	// Count the ATTEMPT before any gate, so an in-flight (blocked) swap is still
 	// observable as a started swap.
 	m.mu.Lock()
@@ -81,7 +81,11 @@ func (m *mockSwapController) Start(_ context.Context, backend, _ string) error {
 	m.mu.Unlock()
 
 	if gate != nil {
-		<-gate
+		select {
+		case <-gate:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
 	}
 
 	m.mu.Lock()
@@ -0,0 +1,135 @@
+package worker
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/aceteam-ai/citadel-cli/internal/tracing"
+)
+
+// Job tracing. Each job gets a root span parented on its RayID (see
+// tracing.ContextWithRayID) with children for the fetch that delivered it,
+// the claim, the handler execution and each stream publish; the LLM handler
+// adds swap, readiness and engine-request spans under execution. All of it is
+// a no-op unless tracing is configured.
+
+// fetchTiming is when the source call that delivered a job started and
+// returned. The job's RayID is unknown until then, so the fetch span and the
+// job's root span are backdated to it.
+type fetchTiming struct {
+	start, end time.Time
+}
+
+// startJobTrace opens a job's root span and records its fetch. The returned
+// context carries the root span for every child.
+func (r *Runner) startJobTrace(ctx context.Context, job *Job, fetch fetchTiming) (context.Context, *tracing.Span) {
+	if !tracing.Enabled() {
+		return ctx, nil
+	}
+	now := time.Now()
+	if fetch.start.IsZero() {
+		fetch = fetchTiming{start: now, end: now}
+	}
+	ctx, root := tracing.Start(tracing.ContextWithRayID(ctx, job.RayID), "job "+job.Type,
+		tracing.WithKind(tracing.KindConsumer),
+		tracing.WithStartTime(fetch.start),
+		tracing.WithAttr("citadel.job.id", job.ID),
+		tracing.WithAttr("citadel.job.type", job.Type),
+		tracing.WithAttr("citadel.job.source", r.source.Name()),
+		tracing.WithAttr("citadel.worker.id", r.config.WorkerID),
+	)
+	if job.RayID != "" {
+		root.SetAttr("citadel.ray_id", job.RayID)
+	}
+	if job.Metadata.Attempts > 0 {
+		root.SetAttr("citadel.job.attempts", job.Metadata.Attempts)
+	}
+	// Queueing is measured from the producer's clock, so it is an attribute
+	// rather than a span that could start before its parent under clock skew.
+	if created := job.Metadata.CreatedAt; !created.IsZero() && fetch.end.After(created) {
+		root.SetAttr("citadel.job.queue_ms", fetch.end.Sub(created))
+	}
+	// Time between the fetch and a free concurrency slot.
+	if wait := now.Sub(fetch.end); wait > time.Millisecond {
+		root.SetAttr("citadel.job.slot_wait_ms", wait)
+	}
+
+	_, fetchSpan := tracing.Start(ctx, "job.fetch", tracing.WithStartTime(fetch.start))
+	fetchSpan.EndAt(fetch.end)
+	return ctx, root
+}
+
+// tracedStreamWriter spans each stream publish and records the job's outcome
+// on its root span from the terminal event. Chunks are counted rather than
+// spanned individually; only the first gets a span, which marks time to first
+// token.
+type tracedStreamWriter struct {
+	StreamWriter
+	ctx    context.Context
+	root   *tracing.Span
+	chunks atomic.Int64
+}
+
+// traceStream wraps stream when the job is being traced.
+func traceStream(ctx context.Context, root *tracing.Span, stream StreamWriter) StreamWriter {
+	if root == nil {
+		return stream
+	}
+	return &tracedStreamWriter{StreamWriter: stream, ctx: ctx, root: root}
+}
+
+func (w *tracedStreamWriter) publish(event string, write func() error) error {
+	_, span := tracing.Start(w.ctx, "stream.publish",
+		tracing.WithKind(tracing.KindProducer),
+		tracing.WithAttr("citadel.stream.event", event))
+	err := write()
+	span.RecordError(err)
+	span.End()
+	return err
+}
+
+func (w *tracedStreamWriter) finish(outcome string) {
+	w.root.SetAttr("citadel.job.outcome", outcome)
+	if n := w.chunks.Load(); n > 0 {
+		w.root.SetAttr("citadel.stream.chunks", n)
+	}
+}
+
+func (w *tracedStreamWriter) WriteClaimed(agentVersion string) error {
+	return w.publish("claimed", func() error { return w.StreamWriter.WriteClaimed(agentVersion) })
+}
+
+func (w *tracedStreamWriter) WriteStart(message string) error {
+	return w.publish("start", func() error { return w.StreamWriter.WriteStart(message) })
+}
+
+func (w *tracedStreamWriter) WriteChunk(content string, index int) error {
+	if w.chunks.Add(1) == 1 {
+		return w.publish("first_chunk", func() error { return w.StreamWriter.WriteChunk(content, index) })
+	}
+	return w.StreamWriter.WriteChunk(content, index)
+}
+
+func (w *tracedStreamWriter) WriteChunkData(content string, index int, data map[string]any) error {
+	if w.chunks.Add(1) == 1 {
+		return w.publish("first_chunk", func() error { return w.StreamWriter.WriteChunkData(content, index, data) })
+	}
+	return w.StreamWriter.WriteChunkData(content, index, data)
+}
+
+func (w *tracedStreamWriter) WriteEnd(result map[string]any) error {
+	w.finish("success")
+	return w.publish("end", func() error { return w.StreamWriter.WriteEnd(result) })
+}
+
+func (w *tracedStreamWriter) WriteError(err error, recoverable bool) error {
+	w.finish("failed")
+	w.root.RecordError(err)
+	return w.publish("error", func() error { return w.StreamWriter.WriteError(err, recoverable) })
+}
+
+func (w *tracedStreamWriter) WriteCancelled(reason string) error {
+	w.finish("cancelled")
+	return w.publish("cancelled", func() error { return w.StreamWriter.WriteCancelled(reason) })
+}
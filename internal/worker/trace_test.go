@@ -0,0 +1,161 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aceteam-ai/citadel-cli/internal/tracing"
+)
+
+// exportedSpan is the part of an OTLP/JSON span the tests check.
+type exportedSpan struct {
+	TraceID      string `json:"traceId"`
+	SpanID       string `json:"spanId"`
+	ParentSpanID string `json:"parentSpanId"`
+	Name         string `json:"name"`
+	Attributes   []struct {
+		Key   string `json:"key"`
+		Value struct {
+			StringValue string `json:"stringValue"`
+			IntValue    string `json:"intValue"`
+		} `json:"value"`
+	} `json:"attributes"`
+}
+
+func (s exportedSpan) attr(key string) string {
+	for _, a := range s.Attributes {
+		if a.Key == key {
+			return a.Value.StringValue + a.Value.IntValue
+		}
+	}
+	return ""
+}
+
+// spanCollector is a fake OTLP/HTTP collector.
+type spanCollector struct {
+	mu    sync.Mutex
+	spans []exportedSpan
+}
+
+func (c *spanCollector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ResourceSpans []struct {
+			ScopeSpans []struct {
+				Spans []exportedSpan `json:"spans"`
+			} `json:"scopeSpans"`
+		} `json:"resourceSpans"`
+	}
+	json.NewDecoder(r.Body).Decode(&req)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, rs := range req.ResourceSpans {
+		for _, ss := range rs.ScopeSpans {
+			c.spans = append(c.spans, ss.Spans...)
+		}
+	}
+}
+
+func (c *spanCollector) named(name string) []exportedSpan {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var out []exportedSpan
+	for _, s := range c.spans {
+		if s.Name == name {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// engineCallHandler streams two chunks and makes one engine request, the
+// shape of an LLM job.
+type engineCallHandler struct {
+	engineURL string
+}
+
+func (h *engineCallHandler) CanHandle(jobType string) bool { return jobType == "TEST_JOB" }
+
+func (h *engineCallHandler) Execute(ctx context.Context, job *Job, stream StreamWriter) (*JobResult, error) {
+	resp, err := (&LLMInferenceHandler{}).postJSON(ctx, h.engineURL, map[string]any{"model": "m"})
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	stream.WriteChunk("a", 0)
+	stream.WriteChunk("b", 1)
+	return &JobResult{Status: JobStatusSuccess}, nil
+}
+
+func TestRunnerTracesJobOnRayID(t *testing.T) {
+	c := &spanCollector{}
+	collector := httptest.NewServer(c)
+	defer collector.Close()
+	shutdown, err := tracing.Setup(tracing.Config{Endpoint: collector.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var traceparent string
+	engine := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get(tracing.TraceparentHeader)
+	}))
+	defer engine.Close()
+
+	jobs := []*Job{{ID: "job-1", Type: "TEST_JOB", RayID: "ray-abc", Payload: map[string]any{}}}
+	source := NewMockJobSource("test", jobs)
+	runner := NewRunner(source, []JobHandler{&engineCallHandler{engineURL: engine.URL}}, RunnerConfig{WorkerID: "test"})
+	runner.WithStreamWriterFactory(newRecordingFactory().factory)
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	runner.Run(ctx)
+
+	flushCtx, flushCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer flushCancel()
+	if err := shutdown(flushCtx); err != nil {
+		t.Fatal(err)
+	}
+
+	roots := c.named("job TEST_JOB")
+	if len(roots) != 1 {
+		t.Fatalf("got %d root spans, want 1", len(roots))
+	}
+	root := roots[0]
+	if want := tracing.TraceIDFromRayID("ray-abc").String(); root.TraceID != want {
+		t.Errorf("root trace = %s, want the RayID's %s", root.TraceID, want)
+	}
+	if root.attr("citadel.job.outcome") != "success" || root.attr("citadel.stream.chunks") != "2" || root.attr("citadel.ray_id") != "ray-abc" {
+		t.Errorf("root attributes = %+v", root.Attributes)
+	}
+
+	for _, name := range []string{"job.fetch", "job.claim", "job.execute"} {
+		spans := c.named(name)
+		if len(spans) != 1 || spans[0].ParentSpanID != root.SpanID {
+			t.Errorf("%s = %+v, want one child of the root", name, spans)
+		}
+	}
+	events := map[string]bool{}
+	for _, s := range c.named("stream.publish") {
+		events[s.attr("citadel.stream.event")] = true
+	}
+	for _, e := range []string{"claimed", "start", "first_chunk", "end"} {
+		if !events[e] {
+			t.Errorf("no stream.publish span for %q (got %v)", e, events)
+		}
+	}
+
+	requests := c.named("engine.request")
+	if len(requests) != 1 {
+		t.Fatalf("got %d engine.request spans, want 1", len(requests))
+	}
+	if exec := c.named("job.execute"); len(exec) == 1 && requests[0].ParentSpanID != exec[0].SpanID {
+		t.Error("engine.request is not a child of job.execute")
+	}
+	if want := "00-" + root.TraceID + "-" + requests[0].SpanID + "-01"; traceparent != want {
+		t.Errorf("engine saw traceparent %q, want %q", traceparent, want)
+	}
+}
@@ -335,7 +335,8 @@ else
       release/citadel_${VERSION}_darwin_arm64.tar.gz \
       release/citadel_${VERSION}_windows_amd64.zip \
       release/citadel_${VERSION}_windows_arm64.zip \
-      release/checksums.txt
+      release/checksums.txt \
+      $(ls release/*.minisig 2>/dev/null)
 fi
 
 if [[ "$DRY_RUN" == true ]]; then
@@ -80,6 +80,9 @@ hook_build() {
   mkdir -p "$release_dir"
 
   local ldflags="-X github.com/aceteam-ai/citadel-cli/cmd.version=${version}"
+  if [[ -n "${CITADEL_RELEASE_PUBLIC_KEY:-}" ]]; then
+    ldflags="$ldflags -X github.com/aceteam-ai/citadel-cli/internal/update.ReleaseSigningKeys=${CITADEL_RELEASE_PUBLIC_KEY}"
+  fi
   local platforms=(
     "linux/amd64"
     "linux/arm64"
@@ -123,6 +126,16 @@ hook_build() {
 
   (cd "$release_dir" && sha256sum * > checksums.txt)
 
+  # Detached signatures let self-updates verify archives against the key baked
+  # in above (internal/update/signature.go), independent of checksums.txt.
+  if [[ -n "${CITADEL_MINISIGN_KEY:-}" ]]; then
+    info "Signing archives with minisign..."
+    for archive in "$release_dir"/*.tar.gz "$release_dir"/*.zip; do
+      [[ -f "$archive" ]] || continue
+      minisign -S -s "$CITADEL_MINISIGN_KEY" -m "$archive"
+    done
+  fi
+
   ok "Built $(ls "$release_dir"/*.tar.gz "$release_dir"/*.zip 2>/dev/null | wc -l) archives"
   ls -lh "$release_dir/"
 }
@@ -130,7 +143,7 @@ hook_build() {
 hook_artifact() {
   local release_dir="$REPO_ROOT/release"
   if [[ -d "$release_dir" ]]; then
-    find "$release_dir" -type f \( -name '*.tar.gz' -o -name '*.zip' -o -name 'checksums.txt' \) | sort
+    find "$release_dir" -type f \( -name '*.tar.gz' -o -name '*.zip' -o -name 'checksums.txt' -o -name '*.minisig' \) | sort
   fi
 }
 